@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"github.com/metalsoft-io/metalcloud-cli/cmd/metalcloud-cli/system"
+	"github.com/metalsoft-io/metalcloud-cli/internal/manifest"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyFlags = struct {
+		configSource  string
+		prune         bool
+		deploy        bool
+		allowDataLoss bool
+	}{}
+
+	applyCmd = &cobra.Command{
+		Use:   "apply",
+		Short: "Create or update an infrastructure from a declarative manifest",
+		Long: `Create or update an infrastructure and its resources from a multi-document manifest.
+
+The manifest is compared with the infrastructure found by label, a plan of the changes is
+printed, and the resources are then created, updated or (with --prune) deleted so that
+the infrastructure matches the manifest. Running apply again with the same manifest
+makes no changes, so manifests can be kept in git and applied from CI.
+
+The manifest is a YAML (or JSON) stream of documents. Each document has a 'kind', a
+'label' and a 'spec' holding the same fields accepted by the corresponding create
+command's --config-source payload. Only the fields present in 'spec' are managed.
+
+Supported kinds:
+  Infrastructure        Exactly one per manifest; 'siteId' is only used on creation
+  ServerInstanceGroup   May list 'networkConnections', keyed by logicalNetworkId
+  VmInstanceGroup
+  Drive
+  FileShare
+  Bucket
+  ExtensionInstance
+
+Example manifest:
+  kind: Infrastructure
+  label: web
+  spec:
+    siteId: 1
+  ---
+  kind: ServerInstanceGroup
+  label: frontend
+  spec:
+    instanceCount: 2
+    defaultServerTypeId: 5
+  networkConnections:
+    - logicalNetworkId: "7"
+      accessMode: l2
+      tagged: true
+  ---
+  kind: Drive
+  label: data
+  spec:
+    sizeMb: 40960
+
+Required Flags:
+  --config-source       Source of the manifest. Can be 'pipe' or path to a YAML/JSON file
+
+Optional Flags:
+  --prune               Delete resources of the infrastructure that are not in the manifest
+  --deploy              Deploy the infrastructure after the changes are applied
+  --allow-data-loss     Allow data loss during the deploy (used with --deploy)
+
+Examples:
+  # Preview the changes first
+  metalcloud-cli diff --config-source infra.yaml
+
+  # Apply a manifest
+  metalcloud-cli apply --config-source infra.yaml
+
+  # Apply, remove resources missing from the manifest and deploy
+  metalcloud-cli apply --config-source infra.yaml --prune --deploy
+
+  # Apply a manifest from stdin
+  cat infra.yaml | metalcloud-cli apply --config-source pipe`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_INFRASTRUCTURES_WRITE},
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content, err := utils.ReadConfigFromPipeOrFile(applyFlags.configSource)
+			if err != nil {
+				return err
+			}
+
+			return manifest.Apply(cmd.Context(), content, applyFlags.prune, applyFlags.deploy, applyFlags.allowDataLoss)
+		},
+	}
+
+	diffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Show the changes apply would make for a manifest",
+		Long: `Compare a manifest with the current state of the infrastructure and print the plan
+that 'apply' would execute, without making any changes.
+
+Each planned change shows the action (create, update or delete), the resource kind and
+label, and for updates the fields that differ. See 'metalcloud-cli apply --help' for the
+manifest format.
+
+Required Flags:
+  --config-source       Source of the manifest. Can be 'pipe' or path to a YAML/JSON file
+
+Optional Flags:
+  --prune               Include the deletions apply --prune would make
+
+Examples:
+  # Show the plan for a manifest
+  metalcloud-cli diff --config-source infra.yaml
+
+  # Include resources that would be pruned, as JSON
+  metalcloud-cli diff --config-source infra.yaml --prune -f json`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_INFRASTRUCTURES_READ},
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content, err := utils.ReadConfigFromPipeOrFile(applyFlags.configSource)
+			if err != nil {
+				return err
+			}
+
+			return manifest.Diff(cmd.Context(), content, applyFlags.prune)
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().StringVar(&applyFlags.configSource, "config-source", "", "Source of the manifest. Can be 'pipe' or path to a YAML/JSON file.")
+	applyCmd.Flags().BoolVar(&applyFlags.prune, "prune", false, "Delete resources that are not in the manifest.")
+	applyCmd.Flags().BoolVar(&applyFlags.deploy, "deploy", false, "Deploy the infrastructure after applying the changes.")
+	applyCmd.Flags().BoolVar(&applyFlags.allowDataLoss, "allow-data-loss", false, "Allow data loss during the deploy.")
+	applyCmd.MarkFlagsOneRequired("config-source")
+
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVar(&applyFlags.configSource, "config-source", "", "Source of the manifest. Can be 'pipe' or path to a YAML/JSON file.")
+	diffCmd.Flags().BoolVar(&applyFlags.prune, "prune", false, "Include deletions of resources that are not in the manifest.")
+	diffCmd.MarkFlagsOneRequired("config-source")
+}
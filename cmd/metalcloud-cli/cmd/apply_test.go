@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// apply_test.go covers:
+//   diff  — prints an update for a changed drive and a create for a new group
+//   apply — requires --config-source
+
+const applyTestManifest = `kind: Infrastructure
+label: test-infra
+spec:
+  siteId: 1
+---
+kind: ServerInstanceGroup
+label: new-group
+spec:
+  instanceCount: 1
+---
+kind: Drive
+label: test-drive
+spec:
+  sizeMb: 20480
+`
+
+func newApplyTestServer() *httptest.Server {
+	mux := newInfraMux(func(mux *http.ServeMux) {
+		for _, collection := range []string{"server-instance-groups", "vm-instance-groups", "file-shares", "buckets", "extension-instances"} {
+			mux.HandleFunc("/api/v2/infrastructures/1/"+collection, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(paginatedList())
+			})
+		}
+		mux.HandleFunc("/api/v2/infrastructures/1/drives", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(paginatedList(driveItem))
+		})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestDiff_ShowsPlan(t *testing.T) {
+	srv := newApplyTestServer()
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "infra.yaml")
+	if err := os.WriteFile(path, []byte(applyTestManifest), 0o600); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	out, err := runCLI(t, srv, "diff", "--config-source", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var changes []map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &changes); err != nil {
+		t.Fatalf("expected JSON plan, got %q: %v", out, err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d: %s", len(changes), out)
+	}
+	if changes[0]["action"] != "create" || changes[0]["label"] != "new-group" {
+		t.Errorf("expected create of new-group first, got %v", changes[0])
+	}
+	if changes[1]["action"] != "update" || changes[1]["details"] != "sizeMb" {
+		t.Errorf("expected sizeMb update of test-drive, got %v", changes[1])
+	}
+}
+
+func TestApply_RequiresConfigSource(t *testing.T) {
+	srv := newApplyTestServer()
+	defer srv.Close()
+
+	_, err := runCLI(t, srv, "apply")
+	if err == nil || !strings.Contains(err.Error(), "config-source") {
+		t.Errorf("expected missing --config-source error, got %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"github.com/metalsoft-io/metalcloud-cli/cmd/metalcloud-cli/system"
+	"github.com/metalsoft-io/metalcloud-cli/internal/config_context"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	contextFlags = struct {
+		site      string
+		overwrite bool
+	}{}
+
+	contextCmd = &cobra.Command{
+		Use:     "context [command]",
+		Aliases: []string{"ctx", "contexts"},
+		Short:   "Manage named connection profiles for multiple MetalSoft endpoints",
+		Long: `Manage named connection profiles (contexts) stored in the configuration file.
+
+A context groups the endpoint, API key, TLS and proxy settings, default output format
+and default site of one MetalSoft controller under a name, so switching between lab,
+staging and production controllers does not require separate configuration files.
+
+Contexts are stored in the configuration file (metalcloud.yaml) under the 'contexts'
+key and the selected context under 'current_context':
+
+  current_context: lab
+  contexts:
+    lab:
+      endpoint: https://lab.metalsoft.example
+      api_key: <key>
+      ca_bundle: /etc/pki/lab-ca.pem
+    production:
+      endpoint: https://metal.example
+      api_key: <key>
+      format: json
+      site: 2
+
+The current context can be overridden for a single command with the global --context
+flag or the METALCLOUD_CONTEXT environment variable. Values given explicitly as flags
+or environment variables always take precedence over the values in the context.
+
+Available Commands:
+  list         List all configured contexts
+  current      Show the context currently in use
+  use          Set the current context
+  add          Add a new context
+  remove       Remove a context
+
+Use "metalcloud-cli context [command] --help" for more information about a specific command.`,
+		Annotations: map[string]string{system.LOCAL_COMMAND: "true"},
+	}
+
+	contextListCmd = &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List all configured contexts",
+		Long: `List all contexts defined in the configuration file.
+
+The current context is marked in the 'Current' column. API keys are never displayed.
+
+Examples:
+  # List all contexts
+  metalcloud-cli context list
+
+  # List contexts in JSON format
+  metalcloud-cli context ls -f json`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.LOCAL_COMMAND: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return config_context.ContextList()
+		},
+	}
+
+	contextCurrentCmd = &cobra.Command{
+		Use:   "current",
+		Short: "Show the context currently in use",
+		Long: `Show the context that commands will use.
+
+This is the context selected with --context or METALCLOUD_CONTEXT when given, or the
+'current_context' from the configuration file otherwise.
+
+Examples:
+  # Show the current context
+  metalcloud-cli context current
+
+  # Show which context a command would use with an override
+  metalcloud-cli --context production context current`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.LOCAL_COMMAND: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return config_context.ContextCurrent(viper.GetString(system.ConfigContext))
+		},
+	}
+
+	contextUseCmd = &cobra.Command{
+		Use:     "use context_name",
+		Aliases: []string{"switch"},
+		Short:   "Set the current context",
+		Long: `Set the context used by all subsequent commands.
+
+Arguments:
+  context_name  The name of an existing context
+
+Examples:
+  # Switch to the production context
+  metalcloud-cli context use production`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.LOCAL_COMMAND: "true"},
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return config_context.ContextUse(args[0])
+		},
+	}
+
+	contextAddCmd = &cobra.Command{
+		Use:     "add context_name",
+		Aliases: []string{"create", "new"},
+		Short:   "Add a new context",
+		Long: `Add a new context to the configuration file.
+
+The context is populated from the global connection settings in effect for this
+command: the --endpoint, --api_key, --insecure_skip_verify, --format and TLS/proxy
+flags (--ca_bundle, --client_cert, --client_key, --tls_min_version, --proxy), their
+METALCLOUD_* environment variables, or the top-level keys of the configuration file.
+This makes it easy to turn an existing single-endpoint configuration into a named context.
+
+The first context added becomes the current context.
+
+Arguments:
+  context_name  The name of the new context
+
+Flags:
+  --site        Default site used by commands that accept an optional site
+  --overwrite   Replace the context if one with the same name already exists
+
+Examples:
+  # Add a context for a lab controller with a certificate from an internal CA
+  metalcloud-cli context add lab -e https://lab.metalsoft.example -k <key> --ca_bundle /etc/pki/lab-ca.pem
+
+  # Add a production context with JSON output and a default site
+  metalcloud-cli context add production -e https://metal.example -k <key> -f json --site 2
+
+  # Store the currently configured endpoint and key as a named context
+  metalcloud-cli context add default`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.LOCAL_COMMAND: "true"},
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format := viper.GetString(formatter.ConfigFormat)
+			if flag := cmd.Flags().Lookup(formatter.ConfigFormat); flag != nil && !flag.Changed && format == flag.DefValue {
+				format = ""
+			}
+
+			return config_context.ContextAdd(config_context.Context{
+				Name:     args[0],
+				Endpoint: viper.GetString(system.ConfigEndpoint),
+				ApiKey:   viper.GetString(system.ConfigApiKey),
+				Insecure: viper.GetBool(system.ConfigInsecure),
+				Format:   format,
+				Site:     contextFlags.site,
+
+				CABundle:      viper.GetString(system.ConfigCABundle),
+				ClientCert:    viper.GetString(system.ConfigClientCert),
+				ClientKey:     viper.GetString(system.ConfigClientKey),
+				TLSMinVersion: viper.GetString(system.ConfigTLSMinVersion),
+				Proxy:         viper.GetString(system.ConfigProxy),
+			}, contextFlags.overwrite)
+		},
+	}
+
+	contextRemoveCmd = &cobra.Command{
+		Use:     "remove context_name",
+		Aliases: []string{"rm", "delete"},
+		Short:   "Remove a context",
+		Long: `Remove a context from the configuration file.
+
+If the removed context is the current context, no context will be current afterwards
+and the top-level configuration keys are used again.
+
+Arguments:
+  context_name  The name of the context to remove
+
+Examples:
+  # Remove the lab context
+  metalcloud-cli context remove lab`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.LOCAL_COMMAND: "true"},
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return config_context.ContextRemove(args[0])
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+
+	contextCmd.AddCommand(contextListCmd)
+
+	contextCmd.AddCommand(contextCurrentCmd)
+
+	contextCmd.AddCommand(contextUseCmd)
+
+	contextCmd.AddCommand(contextAddCmd)
+	contextAddCmd.Flags().StringVar(&contextFlags.site, "site", "", "Default site for the context.")
+	contextAddCmd.Flags().BoolVar(&contextFlags.overwrite, "overwrite", false, "Replace the context if it already exists.")
+
+	contextCmd.AddCommand(contextRemoveCmd)
+}
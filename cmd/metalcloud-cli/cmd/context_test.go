@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"testing"
+)
+
+// context_test.go covers:
+//   context list — runs without an API endpoint or key
+
+func TestContextList_NoEndpointRequired(t *testing.T) {
+	if _, err := runCLI(t, nil, "context", "list"); err != nil {
+		t.Fatalf("expected context list to work without an endpoint, got: %v", err)
+	}
+}
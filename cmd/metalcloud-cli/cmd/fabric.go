@@ -3,9 +3,11 @@ package cmd
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/metalsoft-io/metalcloud-cli/cmd/metalcloud-cli/system"
 	"github.com/metalsoft-io/metalcloud-cli/internal/fabric"
+	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/utils"
 	sdk "github.com/metalsoft-io/metalcloud-sdk-go"
 	"github.com/spf13/cobra"
@@ -24,8 +26,15 @@ var (
 		bgpLinkConfiguration string
 		customVariables      []string
 		dryRun               bool
+		check                bool
+		prune                bool
+		parallelism          int
+		devicesFile          string
+		positions            []string
+		pods                 []string
 		updateLLDP           bool
 		verifyRender         bool
+		renderDir            string
 	}{}
 
 	// configureSwitchesFlags are the per-property alternatives to --config-source
@@ -35,6 +44,9 @@ var (
 		ordering            string
 		enablePhysicalPorts bool
 		descriptionTemplate string
+		portLayout          string
+		addressFamily       string
+		tagKeys             map[string]string
 
 		hostname           bool
 		hostnameLeaf       string
@@ -47,8 +59,9 @@ var (
 		asnSpineStart      int64
 		asnSuperSpineStart int64
 
-		loopback       bool
-		loopbackSubnet string
+		loopback           bool
+		loopbackSubnet     string
+		loopbackSubnetIpv6 string
 
 		topoLeafSpine          bool
 		topoLeafSpineLPP       string
@@ -62,26 +75,30 @@ var (
 		topoLeafHostNicNames    []string
 		topoLeafHostDescription string
 
-		p2p                    bool
-		p2pPoolLeafSpine       string
-		p2pPoolSpineSuperSpine string
-		p2pPoolLeafHost        string
-		p2pMtu                 int32
+		p2p                        bool
+		p2pPoolLeafSpine           string
+		p2pPoolSpineSuperSpine     string
+		p2pPoolLeafHost            string
+		p2pPoolLeafSpineIpv6       string
+		p2pPoolSpineSuperSpineIpv6 string
+		p2pPoolLeafHostIpv6        string
+		p2pMtu                     int32
 	}{}
 
 	// configureSwitchesDetailFlags is every per-property flag; each is marked
 	// mutually exclusive with --config-source.
 	configureSwitchesDetailFlags = []string{
-		"ordering", "enable-physical-ports", "description-template",
+		"ordering", "enable-physical-ports", "description-template", "port-layout", "address-family", "tag-keys",
 		"hostname", "hostname-leaf", "hostname-spine", "hostname-super-spine", "hostname-skip",
 		"asn", "asn-leaf-start", "asn-spine-start", "asn-super-spine-start",
-		"loopback", "loopback-subnet",
+		"loopback", "loopback-subnet", "loopback-subnet-ipv6",
 		"topology-leaf-spine", "topology-leaf-spine-links-per-pair",
 		"topology-spine-super-spine", "topology-spine-super-spine-links-per-pair",
 		"topology-leaf-host", "topology-leaf-host-node-count", "topology-leaf-host-nodes",
 		"topology-leaf-host-port-pattern", "topology-leaf-host-nic-names",
 		"topology-leaf-host-description-template",
-		"p2p", "p2p-pool-leaf-spine", "p2p-pool-spine-super-spine", "p2p-pool-leaf-host", "p2p-mtu",
+		"p2p", "p2p-pool-leaf-spine", "p2p-pool-spine-super-spine", "p2p-pool-leaf-host",
+		"p2p-pool-leaf-spine-ipv6", "p2p-pool-spine-super-spine-ipv6", "p2p-pool-leaf-host-ipv6", "p2p-mtu",
 	}
 
 	fabricCmd = &cobra.Command{
@@ -647,7 +664,7 @@ Examples:
 	}
 
 	fabricConfigureSwitchesCmd = &cobra.Command{
-		Use:     "configure-switches fabric_id",
+		Use:     "configure-switches [fabric_id]",
 		Aliases: []string{"configure-switch"},
 		Short:   "Configure all switches of a fabric from a declarative YAML/JSON",
 		Long: `Configure every network device attached to a fabric from one declarative
@@ -664,7 +681,8 @@ OR built up from the individual per-property flags below. The two are mutually
 exclusive; provide one or the other.
 
 Arguments:
-  fabric_id    The ID or label of the fabric to configure
+  fabric_id    The ID or label of the fabric to configure. Omitted with
+               --devices-file.
 
 Whole-document input:
   --config-source   'pipe' to read from stdin, or a path to a YAML/JSON config file.
@@ -675,19 +693,43 @@ enabled when its toggle flag is set or any of its sub-flags is provided:
   ordering        --ordering
   enable ports    --enable-physical-ports
   descriptions    --description-template
+  port layout     --port-layout (a built-in profile for every position; per-position
+                  layouts need --config-source)
+  address family  --address-family (ipv4 | dual-stack | ipv6)
+  tag keys        --tag-keys (pod=,unit=,rail=,spineIndex=,group= device tag keys;
+                  default the nvidia/* keys)
   hostname        --hostname, --hostname-leaf, --hostname-spine,
                   --hostname-super-spine, --hostname-skip
   asn             --asn, --asn-leaf-start, --asn-spine-start, --asn-super-spine-start
-  loopback        --loopback, --loopback-subnet
+  loopback        --loopback, --loopback-subnet, --loopback-subnet-ipv6
   topology        --topology-leaf-spine[-links-per-pair],
                   --topology-spine-super-spine[-links-per-pair],
                   --topology-leaf-host[-node-count|-nodes|-port-pattern|
                   -nic-names|-description-template]
   p2p             --p2p, --p2p-pool-leaf-spine, --p2p-pool-spine-super-spine,
-                  --p2p-pool-leaf-host, --p2p-mtu
+                  --p2p-pool-leaf-host, --p2p-pool-leaf-spine-ipv6,
+                  --p2p-pool-spine-super-spine-ipv6, --p2p-pool-leaf-host-ipv6,
+                  --p2p-mtu
 
 Always available:
   --dry-run         Compute the plan and report what would change, without writing.
+  --check           Write nothing; print a drift report (device, port, field,
+                    expected, actual) in the selected output format and exit
+                    non-zero when the fabric differs from the plan.
+  --prune           Remove point-to-point links and /31 (/127) subnets created by
+                    this command that are no longer in the plan. Without it they
+                    are only reported. Combine with --dry-run to preview.
+  --parallelism N   Configure up to N devices (then links) at once (default 4);
+                    each device's own writes stay in order.
+
+Offline plan (instead of fabric_id):
+  --devices-file    'pipe' or path to a YAML/JSON device inventory: a list of
+                    devices (position, managementAddress, identifierString,
+                    tagsMap, optional id) or an 'import-devices' document. The
+                    full plan - hostnames, ASNs, loopbacks, cables with their
+                    /31s, port descriptions and, with --config-source, the
+                    freeform/bgp profile variables - is printed without any
+                    API access. Not combinable with --check or --prune.
 
 Examples:
   # Whole-document input from a file or stdin
@@ -704,29 +746,225 @@ Examples:
     --description-template "to_{peerHostname}_{peerPort}" \
     --p2p --p2p-pool-leaf-spine 10.254.0.0/16 --p2p-mtu 9216
 
+  # Dual-stack underlay: IPv6 loopbacks and /127s next to the IPv4 ones
+  metalcloud-cli fabric configure-switches 5 --address-family dual-stack \
+    --loopback --topology-leaf-spine --p2p --p2p-pool-leaf-spine-ipv6 fd00:254::/64 --dry-run
+
   # Override one ASN start and skip naming the spines
   metalcloud-cli fabric configure-switches 5 --asn --asn-leaf-start 4200001000 \
-    --hostname --hostname-skip spine`,
+    --hostname --hostname-skip spine
+
+  # Drift report for monitoring, then reconcile and remove stale links
+  metalcloud-cli fabric configure-switches 5 --config-source fabric-config.yaml --check -f json
+  metalcloud-cli fabric configure-switches 5 --config-source fabric-config.yaml --prune
+
+  # Review the plan of a fabric that is not racked yet
+  metalcloud-cli fabric configure-switches --config-source fabric-config.yaml --devices-file inventory.yaml`,
+		SilenceUsage: true,
+		Annotations: map[string]string{
+			system.REQUIRED_PERMISSION: system.PERMISSION_NETWORK_FABRICS_WRITE,
+			system.LOCAL_WHEN_FLAG:     "devices-file",
+		},
+		Args: cobra.RangeArgs(0, 1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 1) == (fabricFlags.devicesFile != "") {
+				return fmt.Errorf("either a fabric_id or --devices-file is required, but not both")
+			}
+			if fabricFlags.devicesFile == "pipe" && fabricFlags.configSource == "pipe" {
+				return fmt.Errorf("--devices-file and --config-source cannot both be read from the pipe")
+			}
+			var config []byte
+			var err error
+			if fabricFlags.configSource != "" {
+				config, err = utils.ReadConfigFromPipeOrFile(fabricFlags.configSource)
+			} else {
+				config, err = buildSwitchConfigFromFlags(cmd)
+			}
+			if err != nil {
+				return err
+			}
+			if fabricFlags.devicesFile != "" {
+				devices, err := utils.ReadConfigFromPipeOrFile(fabricFlags.devicesFile)
+				if err != nil {
+					return err
+				}
+				return fabric.FabricConfigureSwitchesPlan(cmd.Context(), config, devices)
+			}
+			return fabric.FabricConfigureSwitches(cmd.Context(), args[0], config, fabricFlags.dryRun, fabricFlags.check, fabricFlags.prune, fabricFlags.parallelism)
+		},
+	}
+
+	fabricUnconfigureSwitchesCmd = &cobra.Command{
+		Use:     "unconfigure-switches fabric_id",
+		Aliases: []string{"unconfigure-switch"},
+		Short:   "Undo what configure-switches created on a fabric",
+		Long: `Tear down what 'fabric configure-switches' created on a fabric, for a re-cable
+or a pod rebuild.
+
+The point-to-point links and /31 (/127) IPAM subnets configure-switches created
+are found by their tags and deleted, links first. When the configuration
+configure-switches was run with is supplied, the values it set are reset too:
+hostnames, ASNs and loopback addresses are cleared on the devices, the loopback
+/32 and /128 are removed from the loopback ports and the port descriptions are
+cleared. A value is only reset while it still matches the plan, so anything
+changed by hand since is kept. Ports are never disabled.
+
+Arguments:
+  fabric_id    The ID or label of the fabric
+
+Optional flags:
+  --config-source   'pipe' or path to the YAML/JSON switch configuration that
+                    configure-switches was run with. Without it only links and
+                    subnets are removed.
+  --position        Only tear down devices of these positions (repeatable or
+                    comma-separated), e.g. leaf.
+  --pod             Only tear down devices with these pod ids (repeatable or
+                    comma-separated). Links are removed when either end is in
+                    scope.
+  --dry-run         Report what would be removed or reset, without writing.
+
+Examples:
+  # Preview the teardown of the whole fabric
+  metalcloud-cli fabric unconfigure-switches 5 --config-source fabric-config.yaml --dry-run
+
+  # Remove only the links and subnets of pod 2
+  metalcloud-cli fabric unconfigure-switches 5 --pod 2
+
+  # Reset the super-spines and their uplinks
+  metalcloud-cli fabric unconfigure-switches my-fabric --config-source fabric-config.yaml --position super_spine`,
 		SilenceUsage: true,
 		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_NETWORK_FABRICS_WRITE},
 		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var config []byte
+			if fabricFlags.configSource != "" {
+				var err error
+				config, err = utils.ReadConfigFromPipeOrFile(fabricFlags.configSource)
+				if err != nil {
+					return err
+				}
+			}
+			return fabric.FabricUnconfigureSwitches(cmd.Context(), args[0], config, fabricFlags.dryRun, fabricFlags.positions, fabricFlags.pods)
+		},
+	}
+
+	fabricCablingPlanCmd = &cobra.Command{
+		Use:   "cabling-plan fabric_id",
+		Short: "Export the computed cabling plan as a CSV cut-sheet, DOT diagram or JSON",
+		Long: `Compute the fabric links and leaf->host downlinks of a switch configuration and
+export them as a cabling plan, without making any changes. The fabric devices
+are only read, to group, order and name them exactly as 'configure-switches'
+would.
+
+Every cable gets a stable id (LS-nnnn leaf<->spine, SS-nnnn spine<->superspine,
+LH-nnnn leaf->host) and lists its layer, the A and B device/port and, when the
+configuration has a p2p section, its /31 and /127. Side A is the leaf on
+leaf<->spine and leaf->host cables and the spine on spine<->superspine cables.
+Devices are named by their computed hostname when a hostname section is
+configured, otherwise by their current identifier.
+
+The configuration is the same document accepted by 'configure-switches',
+supplied EITHER via --config-source OR built from the plan flags below (the two
+are mutually exclusive). At least a topology section is required.
+
+Arguments:
+  fabric_id    The ID or label of the fabric
+
+Input (one of):
+  --config-source   'pipe' or path to the YAML/JSON switch configuration.
+  --hostname, --ordering, --port-layout, --address-family, --tag-keys, --topology-*,
+  --p2p-pool-* flags as listed in --help.
+
+Output:
+  --plan-format     csv (technician cut-sheet, default) | dot (Graphviz) | json
+  --output          Write the plan to a file instead of stdout.
+
+Examples:
+  # CSV cut-sheet from the configure-switches document
+  metalcloud-cli fabric cabling-plan 5 --config-source fabric-config.yaml --output pod5-cabling.csv
+
+  # Topology diagram rendered with Graphviz
+  metalcloud-cli fabric cabling-plan 5 --config-source fabric-config.yaml --plan-format dot | dot -Tsvg > pod5.svg
+
+  # JSON plan built from flags, with the reference hostnames and default pools
+  metalcloud-cli fabric cabling-plan my-fabric --hostname --topology-leaf-spine \
+    --topology-leaf-host-node-count 8 --p2p-pool-leaf-spine 10.254.0.0/16 --plan-format json`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_NETWORK_FABRICS_READ},
+		Args:         cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var config []byte
 			var err error
 			if fabricFlags.configSource != "" {
 				config, err = utils.ReadConfigFromPipeOrFile(fabricFlags.configSource)
 			} else {
-				config, err = buildSwitchConfigFromFlags(cmd)
+				config, err = buildCablingPlanConfigFromFlags(cmd)
 			}
 			if err != nil {
 				return err
 			}
-			return fabric.FabricConfigureSwitches(cmd.Context(), args[0], config, fabricFlags.dryRun)
+			return fabric.FabricCablingPlan(cmd.Context(), args[0], config, cablingPlanFlags.planFormat, cablingPlanFlags.outputPath)
+		},
+	}
+
+	fabricVerifyCablingCmd = &cobra.Command{
+		Use:   "verify-cabling fabric_id",
+		Short: "Verify the physical cabling against the computed topology",
+		Long: `Compare the fabric links the controller discovered from LLDP (run
+'fabric rescan-links' first) with the cables planned by a switch configuration,
+and report every miswired, missing and unexpected link per port, with a
+suggested fix. Nothing is changed.
+
+Statuses:
+  miswired     An end of the cable is connected somewhere else. When two cables
+               are crossed, the suggestion names both and the swap that fixes them.
+  missing      Neither end of the cable has a discovered link.
+  unexpected   A discovered link between two ports no planned cable uses.
+
+Leaf->host downlinks are not verified: the hosts are not fabric devices. The
+command exits with an error when there is at least one mismatch, so it can gate
+'configure-freeform' / 'configure-bgp' in scripts.
+
+The configuration is the same document accepted by 'configure-switches' and
+'cabling-plan', supplied EITHER via --config-source OR built from the plan
+flags (the two are mutually exclusive). At least a topology section is required.
+
+Arguments:
+  fabric_id    The ID or label of the fabric
+
+Input (one of):
+  --config-source   'pipe' or path to the YAML/JSON switch configuration.
+  --hostname, --ordering, --port-layout, --topology-* flags as listed in --help.
+
+Flags:
+  --all             Also list the cables that are wired as planned.
+
+Examples:
+  metalcloud-cli fabric rescan-links 5
+  metalcloud-cli fabric verify-cabling 5 --config-source fabric-config.yaml
+
+  # Full per-cable report as JSON
+  metalcloud-cli fabric verify-cabling 5 --config-source fabric-config.yaml --all -f json`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_NETWORK_FABRICS_READ},
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var config []byte
+			var err error
+			if fabricFlags.configSource != "" {
+				config, err = utils.ReadConfigFromPipeOrFile(fabricFlags.configSource)
+			} else {
+				config, err = buildCablingPlanConfigFromFlags(cmd)
+			}
+			if err != nil {
+				return err
+			}
+			return fabric.FabricVerifyCabling(cmd.Context(), args[0], config, cablingPlanFlags.showAll)
 		},
 	}
 
 	fabricConfigureFreeformCmd = &cobra.Command{
-		Use:   "configure-freeform fabric_id",
+		Use:   "configure-freeform [fabric_id]",
 		Short: "Register the base freeform template + per-switch profiles (step 8a)",
 		Long: `Register the Spectrum-X base freeform device-configuration template (hostname,
 RoCE/QoS, adaptive routing, telemetry, BFD; in l3evpn the EVPN/VXLAN data plane)
@@ -741,11 +979,13 @@ The configuration is supplied EITHER as a whole document via --config-source OR
 built from the per-property flags below (the two are mutually exclusive). The
 per-property flags cover the freeform section (--mode, --template-path,
 --template-label, --profile-priority, --apply-mode, --hgx-prefix) plus the plan
-sections it reads (--ordering, --topology-leaf-spine[-links-per-pair],
+sections it reads (--ordering, --port-layout, --topology-leaf-spine[-links-per-pair],
 --topology-leaf-host[-node-count|...], --p2p-pool-leaf-host, ...).
 
+` + localRenderHelp + `
+
 Arguments:
-  fabric_id    The ID or label of the fabric
+  fabric_id    The ID or label of the fabric (omit with --devices-file)
 
 Input (one of):
   --config-source   'pipe' or path to the YAML/JSON config (with a 'freeform' section).
@@ -754,15 +994,29 @@ Input (one of):
 Always available:
   --dry-run         Report the plan without writing.
   --verify-render   Render every device through the engine first; abort on any render error.
+  --parallelism N   Write the profiles of up to N devices at once (default 4).
+  --render-dir DIR  Render locally into DIR instead of registering.
+  --devices-file F  With --render-dir: render for the devices of an inventory
+                    file instead of a fabric ('pipe' or path to YAML/JSON).
 
 Examples:
   metalcloud-cli fabric configure-freeform 5 --config-source fabric-config.l3evpn.yaml --verify-render
   metalcloud-cli fabric configure-freeform 5 --mode l3evpn --template-path ./freeform-device-config.j2 \
-    --topology-leaf-spine --dry-run`,
+    --topology-leaf-spine --dry-run
+
+  # Iterate on the template without a controller; re-run to see the diff
+  metalcloud-cli fabric configure-freeform --config-source fabric-config.l3evpn.yaml \
+    --devices-file inventory.yaml --render-dir ./rendered`,
 		SilenceUsage: true,
-		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_NETWORK_FABRICS_WRITE},
-		Args:         cobra.ExactArgs(1),
+		Annotations: map[string]string{
+			system.REQUIRED_PERMISSION: system.PERMISSION_NETWORK_FABRICS_WRITE,
+			system.LOCAL_WHEN_FLAG:     "devices-file",
+		},
+		Args: cobra.RangeArgs(0, 1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkRenderArgs(args); err != nil {
+				return err
+			}
 			var config []byte
 			var err error
 			if fabricFlags.configSource != "" {
@@ -773,12 +1027,19 @@ Examples:
 			if err != nil {
 				return err
 			}
-			return fabric.FabricConfigureFreeform(cmd.Context(), args[0], config, fabricFlags.dryRun, fabricFlags.verifyRender)
+			if fabricFlags.renderDir != "" {
+				devices, fabricIdOrLabel, err := readRenderDevices(args)
+				if err != nil {
+					return err
+				}
+				return fabric.FabricRenderFreeform(cmd.Context(), fabricIdOrLabel, config, devices, fabricFlags.renderDir)
+			}
+			return fabric.FabricConfigureFreeform(cmd.Context(), args[0], config, fabricFlags.dryRun, fabricFlags.verifyRender, fabricFlags.parallelism)
 		},
 	}
 
 	fabricConfigureBgpCmd = &cobra.Command{
-		Use:   "configure-bgp fabric_id",
+		Use:   "configure-bgp [fabric_id]",
 		Short: "Register the BGP underlay/overlay/PFC templates + profiles (step 8b)",
 		Long: `Register the Spectrum-X BGP underlay template (and, in l3evpn, the EVPN overlay
 RR-mesh, QoS PFC defaults, and the action-bound route-domain VRF template) plus
@@ -795,12 +1056,14 @@ The configuration is supplied EITHER as a whole document via --config-source OR
 built from the per-property flags below (the two are mutually exclusive). The
 per-property flags cover the bgp section (--mode, --apply-mode, --template-path
 /-label/-profile-priority, the --overlay-*, --pfc-*, --vrf-* template flags)
-plus the plan sections it reads (--ordering, --topology-leaf-spine
+plus the plan sections it reads (--ordering, --port-layout, --topology-leaf-spine
 [-links-per-pair], --topology-spine-super-spine[-links-per-pair],
 --topology-leaf-host[...], --p2p-pool-* , --p2p-mtu).
 
+` + localRenderHelp + `
+
 Arguments:
-  fabric_id    The ID or label of the fabric
+  fabric_id    The ID or label of the fabric (omit with --devices-file)
 
 Input (one of):
   --config-source   'pipe' or path to the YAML/JSON config (with a 'bgp' section).
@@ -809,17 +1072,30 @@ Input (one of):
 Always available:
   --dry-run         Report the plan without writing.
   --verify-render   Render every device through the engine first; abort on any render error.
+  --parallelism N   Write the profiles of up to N devices at once (default 4).
+  --render-dir DIR  Render locally into DIR instead of registering.
+  --devices-file F  With --render-dir: render for the devices of an inventory
+                    file instead of a fabric ('pipe' or path to YAML/JSON).
 
 Examples:
   metalcloud-cli fabric configure-bgp 5 --config-source fabric-config.l3evpn.yaml --verify-render
   metalcloud-cli fabric configure-bgp 5 --mode l3evpn \
     --template-path ./freeform-bgp-underlay.j2 --overlay-template-path ./freeform-bgp-overlay.j2 \
     --pfc-template-path ./freeform-qos-pfc.j2 --vrf-template-path ./switch-configure-vrf-create.j2 \
-    --topology-leaf-spine --topology-spine-super-spine --p2p-pool-leaf-spine 10.254.0.0/16 --dry-run`,
+    --topology-leaf-spine --topology-spine-super-spine --p2p-pool-leaf-spine 10.254.0.0/16 --dry-run
+
+  # Render the fabric's underlay/overlay/PFC configs locally and review the diff
+  metalcloud-cli fabric configure-bgp 5 --config-source fabric-config.l3evpn.yaml --render-dir ./rendered`,
 		SilenceUsage: true,
-		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_NETWORK_FABRICS_WRITE},
-		Args:         cobra.ExactArgs(1),
+		Annotations: map[string]string{
+			system.REQUIRED_PERMISSION: system.PERMISSION_NETWORK_FABRICS_WRITE,
+			system.LOCAL_WHEN_FLAG:     "devices-file",
+		},
+		Args: cobra.RangeArgs(0, 1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkRenderArgs(args); err != nil {
+				return err
+			}
 			var config []byte
 			var err error
 			if fabricFlags.configSource != "" {
@@ -830,7 +1106,14 @@ Examples:
 			if err != nil {
 				return err
 			}
-			return fabric.FabricConfigureBgp(cmd.Context(), args[0], config, fabricFlags.dryRun, fabricFlags.verifyRender)
+			if fabricFlags.renderDir != "" {
+				devices, fabricIdOrLabel, err := readRenderDevices(args)
+				if err != nil {
+					return err
+				}
+				return fabric.FabricRenderBgp(cmd.Context(), fabricIdOrLabel, config, devices, fabricFlags.renderDir)
+			}
+			return fabric.FabricConfigureBgp(cmd.Context(), args[0], config, fabricFlags.dryRun, fabricFlags.verifyRender, fabricFlags.parallelism)
 		},
 	}
 
@@ -894,6 +1177,15 @@ func buildSwitchConfigFromFlags(cmd *cobra.Command) ([]byte, error) {
 	if f.Changed("description-template") {
 		doc["descriptionTemplate"] = cs.descriptionTemplate
 	}
+	if f.Changed("port-layout") {
+		doc["portLayouts"] = cs.portLayout
+	}
+	if f.Changed("address-family") {
+		doc["addressFamily"] = cs.addressFamily
+	}
+	if f.Changed("tag-keys") {
+		doc["tags"] = cs.tagKeys
+	}
 
 	// hostname
 	hostname := map[string]interface{}{}
@@ -944,6 +1236,10 @@ func buildSwitchConfigFromFlags(cmd *cobra.Command) ([]byte, error) {
 		loopback["subnet"] = cs.loopbackSubnet
 		loopbackPresent = true
 	}
+	if f.Changed("loopback-subnet-ipv6") {
+		loopback["subnetIpv6"] = cs.loopbackSubnetIpv6
+		loopbackPresent = true
+	}
 	if loopbackPresent {
 		doc["loopback"] = loopback
 	}
@@ -1006,6 +1302,20 @@ func buildSwitchConfigFromFlags(cmd *cobra.Command) ([]byte, error) {
 		p2p["pools"] = pools
 		p2pPresent = true
 	}
+	poolsIpv6 := map[string]interface{}{}
+	if f.Changed("p2p-pool-leaf-spine-ipv6") {
+		poolsIpv6["leafSpine"] = cs.p2pPoolLeafSpineIpv6
+	}
+	if f.Changed("p2p-pool-spine-super-spine-ipv6") {
+		poolsIpv6["spineSuperSpine"] = cs.p2pPoolSpineSuperSpineIpv6
+	}
+	if f.Changed("p2p-pool-leaf-host-ipv6") {
+		poolsIpv6["leafHost"] = cs.p2pPoolLeafHostIpv6
+	}
+	if len(poolsIpv6) > 0 {
+		p2p["poolsIpv6"] = poolsIpv6
+		p2pPresent = true
+	}
 	if f.Changed("p2p-mtu") {
 		p2p["mtu"] = cs.p2pMtu
 		p2pPresent = true
@@ -1049,6 +1359,50 @@ func parseLinksPerPair(s string) (interface{}, error) {
 	return n, nil
 }
 
+// localRenderHelp describes --render-dir in the help of the commands that
+// register templates.
+const localRenderHelp = `Local render: --render-dir renders the templates here instead of registering
+them, one <host>.<profile>.conf file per device, and reports which files were
+added, changed (with a unified diff against the previous render in the
+directory), unchanged or removed, and the variables a template read that the
+device's context lacks. Only the files a previous render recorded in the
+directory's .render-index.json are removed. Nothing is written to the
+controller, and with --devices-file no controller is needed: the devices are
+taken to carry the hostnames, ASNs and loopbacks the plan sets. The render
+implements the Nunjucks subset the Spectrum-X templates use;
+include/extends/import are not supported.`
+
+// checkRenderArgs validates the fabric_id / --render-dir / --devices-file
+// combination of configure-freeform and configure-bgp.
+func checkRenderArgs(args []string) error {
+	if fabricFlags.devicesFile != "" {
+		if fabricFlags.renderDir == "" {
+			return fmt.Errorf("--devices-file requires --render-dir")
+		}
+		if len(args) == 1 {
+			return fmt.Errorf("either a fabric_id or --devices-file is required, but not both")
+		}
+		if fabricFlags.devicesFile == "pipe" && fabricFlags.configSource == "pipe" {
+			return fmt.Errorf("--devices-file and --config-source cannot both be read from the pipe")
+		}
+		return nil
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("a fabric_id is required (or --devices-file with --render-dir)")
+	}
+	return nil
+}
+
+// readRenderDevices returns the --devices-file inventory, if given, and the
+// fabric to render otherwise.
+func readRenderDevices(args []string) ([]byte, string, error) {
+	if fabricFlags.devicesFile == "" {
+		return nil, args[0], nil
+	}
+	devices, err := utils.ReadConfigFromPipeOrFile(fabricFlags.devicesFile)
+	return devices, "", err
+}
+
 func init() {
 	rootCmd.AddCommand(fabricCmd)
 
@@ -1102,11 +1456,21 @@ func init() {
 	csCmd := fabricConfigureSwitchesCmd
 	csCmd.Flags().StringVar(&fabricFlags.configSource, "config-source", "", "Source of the switch configuration. Can be 'pipe' or path to a YAML/JSON file. Mutually exclusive with the per-property flags below.")
 	csCmd.Flags().BoolVar(&fabricFlags.dryRun, "dry-run", false, "Compute and preview the plan without making any changes.")
+	csCmd.Flags().BoolVar(&fabricFlags.check, "check", false, "Report drift from the plan without making any changes; exits non-zero on drift.")
+	csCmd.Flags().BoolVar(&fabricFlags.prune, "prune", false, "Remove p2p links and subnets created by this command that are no longer in the plan.")
+	csCmd.Flags().IntVar(&fabricFlags.parallelism, "parallelism", 4, "Number of devices (then links) configured at once.")
+	csCmd.Flags().StringVar(&fabricFlags.devicesFile, "devices-file", "", "Compute and print the plan offline for the devices of this inventory instead of a fabric. Can be 'pipe' or path to a YAML/JSON file.")
+	csCmd.MarkFlagsMutuallyExclusive("check", "prune")
+	csCmd.MarkFlagsMutuallyExclusive("devices-file", "check")
+	csCmd.MarkFlagsMutuallyExclusive("devices-file", "prune")
 
 	cs := &configureSwitchesFlags
 	csCmd.Flags().StringVar(&cs.ordering, "ordering", "managementAddress", "Device ordering: managementAddress | identifierString | id.")
 	csCmd.Flags().BoolVar(&cs.enablePhysicalPorts, "enable-physical-ports", true, "Enable every physical port's staged config.")
 	csCmd.Flags().StringVar(&cs.descriptionTemplate, "description-template", "", "Interface description template (placeholders {peerHostname}, {peerPort}). Requires a topology section.")
+	csCmd.Flags().StringVar(&cs.portLayout, "port-layout", "", fmt.Sprintf("Switch port layout profile for every position: %s (default %s).", strings.Join(fsc.PortLayoutProfiles(), " | "), fsc.DefaultPortLayoutProfile))
+	csCmd.Flags().StringVar(&cs.addressFamily, "address-family", "", "Underlay address family: ipv4 | dual-stack | ipv6 (default ipv4).")
+	csCmd.Flags().StringToStringVar(&cs.tagKeys, "tag-keys", nil, "Device tag keys of the fabric layout, e.g. pod=site/pod,unit=site/su (keys: pod, unit, rail, spineIndex, group; default the nvidia/* tags).")
 
 	csCmd.Flags().BoolVar(&cs.hostname, "hostname", false, "Enable hostname computation using the built-in reference templates.")
 	csCmd.Flags().StringVar(&cs.hostnameLeaf, "hostname-leaf", "", "Hostname template for leaf devices.")
@@ -1121,6 +1485,7 @@ func init() {
 
 	csCmd.Flags().BoolVar(&cs.loopback, "loopback", false, "Enable loopback IP allocation using the default subnet.")
 	csCmd.Flags().StringVar(&cs.loopbackSubnet, "loopback-subnet", "", "Pool the loopback /32s are carved from.")
+	csCmd.Flags().StringVar(&cs.loopbackSubnetIpv6, "loopback-subnet-ipv6", "", "Pool the loopback /128s are carved from.")
 
 	csCmd.Flags().BoolVar(&cs.topoLeafSpine, "topology-leaf-spine", false, "Enable leaf<->spine pairing.")
 	csCmd.Flags().StringVar(&cs.topoLeafSpineLPP, "topology-leaf-spine-links-per-pair", "", "Leaf<->spine links per pair: 'auto' or an integer.")
@@ -1138,6 +1503,9 @@ func init() {
 	csCmd.Flags().StringVar(&cs.p2pPoolLeafSpine, "p2p-pool-leaf-spine", "", "Leaf<->spine /31 pool.")
 	csCmd.Flags().StringVar(&cs.p2pPoolSpineSuperSpine, "p2p-pool-spine-super-spine", "", "Spine<->superspine /31 pool.")
 	csCmd.Flags().StringVar(&cs.p2pPoolLeafHost, "p2p-pool-leaf-host", "", "Leaf->host /31 pool.")
+	csCmd.Flags().StringVar(&cs.p2pPoolLeafSpineIpv6, "p2p-pool-leaf-spine-ipv6", "", "Leaf<->spine /127 pool.")
+	csCmd.Flags().StringVar(&cs.p2pPoolSpineSuperSpineIpv6, "p2p-pool-spine-super-spine-ipv6", "", "Spine<->superspine /127 pool.")
+	csCmd.Flags().StringVar(&cs.p2pPoolLeafHostIpv6, "p2p-pool-leaf-host-ipv6", "", "Leaf->host /127 pool.")
 	csCmd.Flags().Int32Var(&cs.p2pMtu, "p2p-mtu", 0, "MTU applied to created links.")
 
 	// --config-source is mutually exclusive with each per-property flag; the
@@ -1146,12 +1514,37 @@ func init() {
 		csCmd.MarkFlagsMutuallyExclusive("config-source", name)
 	}
 
+	fabricCmd.AddCommand(fabricUnconfigureSwitchesCmd)
+	fabricUnconfigureSwitchesCmd.Flags().StringVar(&fabricFlags.configSource, "config-source", "", "Source of the switch configuration configure-switches was run with. Can be 'pipe' or path to a YAML/JSON file.")
+	fabricUnconfigureSwitchesCmd.Flags().BoolVar(&fabricFlags.dryRun, "dry-run", false, "Report what would be removed or reset without making any changes.")
+	fabricUnconfigureSwitchesCmd.Flags().StringSliceVar(&fabricFlags.positions, "position", nil, "Only tear down devices of these positions, e.g. leaf.")
+	fabricUnconfigureSwitchesCmd.Flags().StringSliceVar(&fabricFlags.pods, "pod", nil, "Only tear down devices with these pod ids.")
+
 	fabricCmd.AddCommand(fabricConfigureSwitchesExampleCmd)
 
+	fabricCmd.AddCommand(fabricCablingPlanCmd)
+	fabricCablingPlanCmd.Flags().StringVar(&fabricFlags.configSource, "config-source", "", "Source of the switch configuration. Can be 'pipe' or path to a YAML/JSON file. Mutually exclusive with the plan flags.")
+	registerCablingPlanFlags(fabricCablingPlanCmd)
+	for _, name := range cablingPlanDetailFlags {
+		fabricCablingPlanCmd.MarkFlagsMutuallyExclusive("config-source", name)
+	}
+
+	fabricCmd.AddCommand(fabricVerifyCablingCmd)
+	fabricVerifyCablingCmd.Flags().StringVar(&fabricFlags.configSource, "config-source", "", "Source of the switch configuration. Can be 'pipe' or path to a YAML/JSON file. Mutually exclusive with the plan flags.")
+	registerVerifyCablingFlags(fabricVerifyCablingCmd)
+	for _, name := range cablingPlanDetailFlags {
+		fabricVerifyCablingCmd.MarkFlagsMutuallyExclusive("config-source", name)
+	}
+
 	fabricCmd.AddCommand(fabricConfigureFreeformCmd)
 	fabricConfigureFreeformCmd.Flags().StringVar(&fabricFlags.configSource, "config-source", "", "Source of the configuration (with a 'freeform' section). 'pipe' or path to a YAML/JSON file. Mutually exclusive with the per-property flags.")
 	fabricConfigureFreeformCmd.Flags().BoolVar(&fabricFlags.dryRun, "dry-run", false, "Report the plan without making changes.")
 	fabricConfigureFreeformCmd.Flags().BoolVar(&fabricFlags.verifyRender, "verify-render", false, "Render each device through the engine before writing; abort on any render error.")
+	fabricConfigureFreeformCmd.Flags().IntVar(&fabricFlags.parallelism, "parallelism", 4, "Number of devices whose profiles are written at once.")
+	fabricConfigureFreeformCmd.Flags().StringVar(&fabricFlags.renderDir, "render-dir", "", "Render the templates locally into this directory instead of registering them.")
+	fabricConfigureFreeformCmd.Flags().StringVar(&fabricFlags.devicesFile, "devices-file", "", "With --render-dir, render for the devices of this inventory instead of a fabric. Can be 'pipe' or path to a YAML/JSON file.")
+	fabricConfigureFreeformCmd.MarkFlagsMutuallyExclusive("render-dir", "dry-run")
+	fabricConfigureFreeformCmd.MarkFlagsMutuallyExclusive("render-dir", "verify-render")
 	registerConfigureFreeformFlags(fabricConfigureFreeformCmd)
 	for _, name := range freeformDetailFlags {
 		fabricConfigureFreeformCmd.MarkFlagsMutuallyExclusive("config-source", name)
@@ -1161,6 +1554,11 @@ func init() {
 	fabricConfigureBgpCmd.Flags().StringVar(&fabricFlags.configSource, "config-source", "", "Source of the configuration (with a 'bgp' section). 'pipe' or path to a YAML/JSON file. Mutually exclusive with the per-property flags.")
 	fabricConfigureBgpCmd.Flags().BoolVar(&fabricFlags.dryRun, "dry-run", false, "Report the plan without making changes.")
 	fabricConfigureBgpCmd.Flags().BoolVar(&fabricFlags.verifyRender, "verify-render", false, "Render each device through the engine before writing; abort on any render error.")
+	fabricConfigureBgpCmd.Flags().IntVar(&fabricFlags.parallelism, "parallelism", 4, "Number of devices whose profiles are written at once.")
+	fabricConfigureBgpCmd.Flags().StringVar(&fabricFlags.renderDir, "render-dir", "", "Render the templates locally into this directory instead of registering them.")
+	fabricConfigureBgpCmd.Flags().StringVar(&fabricFlags.devicesFile, "devices-file", "", "With --render-dir, render for the devices of this inventory instead of a fabric. Can be 'pipe' or path to a YAML/JSON file.")
+	fabricConfigureBgpCmd.MarkFlagsMutuallyExclusive("render-dir", "dry-run")
+	fabricConfigureBgpCmd.MarkFlagsMutuallyExclusive("render-dir", "verify-render")
 	registerConfigureBgpFlags(fabricConfigureBgpCmd)
 	for _, name := range bgpDetailFlags {
 		fabricConfigureBgpCmd.MarkFlagsMutuallyExclusive("config-source", name)
@@ -17,6 +17,7 @@ func newConfigureSwitchesTestCmd() *cobra.Command {
 		ordering            string
 		enablePhysicalPorts bool
 		descriptionTemplate string
+		portLayout          string
 
 		hostname           bool
 		hostnameLeaf       string
@@ -55,6 +56,8 @@ func newConfigureSwitchesTestCmd() *cobra.Command {
 	f.StringVar(&cs.ordering, "ordering", "managementAddress", "")
 	f.BoolVar(&cs.enablePhysicalPorts, "enable-physical-ports", true, "")
 	f.StringVar(&cs.descriptionTemplate, "description-template", "", "")
+	f.StringVar(&cs.portLayout, "port-layout", "", "")
+	f.StringVar(&cs.addressFamily, "address-family", "", "")
 	f.BoolVar(&cs.hostname, "hostname", false, "")
 	f.StringVar(&cs.hostnameLeaf, "hostname-leaf", "", "")
 	f.StringVar(&cs.hostnameSpine, "hostname-spine", "", "")
@@ -66,6 +69,7 @@ func newConfigureSwitchesTestCmd() *cobra.Command {
 	f.Int64Var(&cs.asnSuperSpineStart, "asn-super-spine-start", 0, "")
 	f.BoolVar(&cs.loopback, "loopback", false, "")
 	f.StringVar(&cs.loopbackSubnet, "loopback-subnet", "", "")
+	f.StringVar(&cs.loopbackSubnetIpv6, "loopback-subnet-ipv6", "", "")
 	f.BoolVar(&cs.topoLeafSpine, "topology-leaf-spine", false, "")
 	f.StringVar(&cs.topoLeafSpineLPP, "topology-leaf-spine-links-per-pair", "", "")
 	f.BoolVar(&cs.topoSpineSuperSpine, "topology-spine-super-spine", false, "")
@@ -80,6 +84,9 @@ func newConfigureSwitchesTestCmd() *cobra.Command {
 	f.StringVar(&cs.p2pPoolLeafSpine, "p2p-pool-leaf-spine", "", "")
 	f.StringVar(&cs.p2pPoolSpineSuperSpine, "p2p-pool-spine-super-spine", "", "")
 	f.StringVar(&cs.p2pPoolLeafHost, "p2p-pool-leaf-host", "", "")
+	f.StringVar(&cs.p2pPoolLeafSpineIpv6, "p2p-pool-leaf-spine-ipv6", "", "")
+	f.StringVar(&cs.p2pPoolSpineSuperSpineIpv6, "p2p-pool-spine-super-spine-ipv6", "", "")
+	f.StringVar(&cs.p2pPoolLeafHostIpv6, "p2p-pool-leaf-host-ipv6", "", "")
 	f.Int32Var(&cs.p2pMtu, "p2p-mtu", 0, "")
 	return cmd
 }
@@ -177,3 +184,49 @@ func TestBuildSwitchConfigHostnameSkipAndAutoLPP(t *testing.T) {
 		t.Errorf("auto linksPerPair should map to nil, got %v", *cfg.Topology.LeafSpine.LinksPerPair)
 	}
 }
+
+func TestBuildSwitchConfigPortLayout(t *testing.T) {
+	cmd := newConfigureSwitchesTestCmd()
+	_ = cmd.Flags().Set("port-layout", "sn5600-800g")
+	_ = cmd.Flags().Set("topology-leaf-spine", "true")
+
+	data, err := buildSwitchConfigFromFlags(cmd)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	cfg, err := fsc.LoadConfig(data)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v\n%s", err, data)
+	}
+	for _, position := range []string{"leaf", "spine", "super_spine"} {
+		if layout := cfg.PortLayouts[position]; layout == nil || layout.Profile != "sn5600-800g" {
+			t.Errorf("%s should use the sn5600-800g port layout, got %+v", position, layout)
+		}
+	}
+}
+
+func TestBuildSwitchConfigAddressFamily(t *testing.T) {
+	cmd := newConfigureSwitchesTestCmd()
+	_ = cmd.Flags().Set("address-family", "dual-stack")
+	_ = cmd.Flags().Set("loopback-subnet-ipv6", "2001:db8:ff::/64")
+	_ = cmd.Flags().Set("topology-leaf-spine", "true")
+	_ = cmd.Flags().Set("p2p-pool-leaf-spine-ipv6", "2001:db8:1::/64")
+
+	data, err := buildSwitchConfigFromFlags(cmd)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	cfg, err := fsc.LoadConfig(data)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v\n%s", err, data)
+	}
+	if cfg.AddressFamily != fsc.AddressFamilyDualStack {
+		t.Errorf("address family = %q, want dual-stack", cfg.AddressFamily)
+	}
+	if cfg.Loopback == nil || cfg.Loopback.SubnetIpv6 != "2001:db8:ff::/64" {
+		t.Errorf("loopback = %+v, want the IPv6 subnet", cfg.Loopback)
+	}
+	if cfg.P2p == nil || cfg.P2p.PoolsIpv6["leafSpine"] != "2001:db8:1::/64" {
+		t.Errorf("p2p = %+v, want the leafSpine IPv6 pool", cfg.P2p)
+	}
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
+	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -11,7 +13,10 @@ import (
 // p2p) that configure-freeform and configure-bgp need to compute per-device
 // variables. They mirror the configure-switches flag names/semantics.
 type templatePlanFlags struct {
-	ordering string
+	ordering      string
+	portLayout    string
+	addressFamily string
+	tagKeys       map[string]string
 
 	topoLeafSpine          bool
 	topoLeafSpineLPP       string
@@ -25,25 +30,32 @@ type templatePlanFlags struct {
 	topoLeafHostNicNames    []string
 	topoLeafHostDescription string
 
-	p2pPoolLeafSpine       string
-	p2pPoolSpineSuperSpine string
-	p2pPoolLeafHost        string
-	p2pMtu                 int32
+	p2pPoolLeafSpine           string
+	p2pPoolSpineSuperSpine     string
+	p2pPoolLeafHost            string
+	p2pPoolLeafSpineIpv6       string
+	p2pPoolSpineSuperSpineIpv6 string
+	p2pPoolLeafHostIpv6        string
+	p2pMtu                     int32
 }
 
 var planFlagNames = []string{
-	"ordering",
+	"ordering", "port-layout", "address-family", "tag-keys",
 	"topology-leaf-spine", "topology-leaf-spine-links-per-pair",
 	"topology-spine-super-spine", "topology-spine-super-spine-links-per-pair",
 	"topology-leaf-host", "topology-leaf-host-node-count", "topology-leaf-host-nodes",
 	"topology-leaf-host-port-pattern", "topology-leaf-host-nic-names",
 	"topology-leaf-host-description-template",
-	"p2p-pool-leaf-spine", "p2p-pool-spine-super-spine", "p2p-pool-leaf-host", "p2p-mtu",
+	"p2p-pool-leaf-spine", "p2p-pool-spine-super-spine", "p2p-pool-leaf-host",
+	"p2p-pool-leaf-spine-ipv6", "p2p-pool-spine-super-spine-ipv6", "p2p-pool-leaf-host-ipv6", "p2p-mtu",
 }
 
 func registerPlanFlags(cmd *cobra.Command, pf *templatePlanFlags) {
 	f := cmd.Flags()
 	f.StringVar(&pf.ordering, "ordering", "managementAddress", "Device ordering: managementAddress | identifierString | id.")
+	f.StringVar(&pf.portLayout, "port-layout", "", fmt.Sprintf("Switch port layout profile for every position: %s (default %s).", strings.Join(fsc.PortLayoutProfiles(), " | "), fsc.DefaultPortLayoutProfile))
+	f.StringVar(&pf.addressFamily, "address-family", "", "Underlay address family: ipv4 | dual-stack | ipv6 (default ipv4).")
+	f.StringToStringVar(&pf.tagKeys, "tag-keys", nil, "Device tag keys of the fabric layout, e.g. pod=site/pod,unit=site/su (keys: pod, unit, rail, spineIndex, group; default the nvidia/* tags).")
 	f.BoolVar(&pf.topoLeafSpine, "topology-leaf-spine", false, "Enable leaf<->spine pairing.")
 	f.StringVar(&pf.topoLeafSpineLPP, "topology-leaf-spine-links-per-pair", "", "Leaf<->spine links per pair: 'auto' or an integer.")
 	f.BoolVar(&pf.topoSpineSuperSpine, "topology-spine-super-spine", false, "Enable spine<->superspine pairing (3-tier only).")
@@ -57,6 +69,9 @@ func registerPlanFlags(cmd *cobra.Command, pf *templatePlanFlags) {
 	f.StringVar(&pf.p2pPoolLeafSpine, "p2p-pool-leaf-spine", "", "Leaf<->spine /31 pool.")
 	f.StringVar(&pf.p2pPoolSpineSuperSpine, "p2p-pool-spine-super-spine", "", "Spine<->superspine /31 pool.")
 	f.StringVar(&pf.p2pPoolLeafHost, "p2p-pool-leaf-host", "", "Leaf->host /31 pool.")
+	f.StringVar(&pf.p2pPoolLeafSpineIpv6, "p2p-pool-leaf-spine-ipv6", "", "Leaf<->spine /127 pool.")
+	f.StringVar(&pf.p2pPoolSpineSuperSpineIpv6, "p2p-pool-spine-super-spine-ipv6", "", "Spine<->superspine /127 pool.")
+	f.StringVar(&pf.p2pPoolLeafHostIpv6, "p2p-pool-leaf-host-ipv6", "", "Leaf->host /127 pool.")
 	f.Int32Var(&pf.p2pMtu, "p2p-mtu", 0, "MTU applied to created links.")
 }
 
@@ -67,6 +82,15 @@ func addPlanSections(cmd *cobra.Command, pf *templatePlanFlags, doc map[string]i
 	if f.Changed("ordering") {
 		doc["ordering"] = pf.ordering
 	}
+	if f.Changed("port-layout") {
+		doc["portLayouts"] = pf.portLayout
+	}
+	if f.Changed("address-family") {
+		doc["addressFamily"] = pf.addressFamily
+	}
+	if f.Changed("tag-keys") {
+		doc["tags"] = pf.tagKeys
+	}
 
 	topology := map[string]interface{}{}
 	if leafSpine, present, err := buildLayerFlags(f, "topology-leaf-spine", pf.topoLeafSpine, "topology-leaf-spine-links-per-pair", pf.topoLeafSpineLPP); err != nil {
@@ -122,6 +146,19 @@ func addPlanSections(cmd *cobra.Command, pf *templatePlanFlags, doc map[string]i
 	if len(pools) > 0 {
 		p2p["pools"] = pools
 	}
+	poolsIpv6 := map[string]interface{}{}
+	if f.Changed("p2p-pool-leaf-spine-ipv6") {
+		poolsIpv6["leafSpine"] = pf.p2pPoolLeafSpineIpv6
+	}
+	if f.Changed("p2p-pool-spine-super-spine-ipv6") {
+		poolsIpv6["spineSuperSpine"] = pf.p2pPoolSpineSuperSpineIpv6
+	}
+	if f.Changed("p2p-pool-leaf-host-ipv6") {
+		poolsIpv6["leafHost"] = pf.p2pPoolLeafHostIpv6
+	}
+	if len(poolsIpv6) > 0 {
+		p2p["poolsIpv6"] = poolsIpv6
+	}
 	if f.Changed("p2p-mtu") {
 		p2p["mtu"] = pf.p2pMtu
 	}
@@ -274,3 +311,54 @@ func buildBgpConfigFromFlags(cmd *cobra.Command) ([]byte, error) {
 	}
 	return yaml.Marshal(doc)
 }
+
+// ---- cabling-plan / verify-cabling ---------------------------------------------
+
+// cablingPlanFlags are shared by cabling-plan and verify-cabling: both compute
+// the cabling from the hostname and plan sections.
+var cablingPlanFlags = struct {
+	planFormat string
+	outputPath string
+	showAll    bool
+	hostname   bool
+	templatePlanFlags
+}{}
+
+var cablingPlanDetailFlags = append([]string{"hostname"}, planFlagNames...)
+
+// registerCablingInputFlags registers the per-property alternatives to
+// --config-source.
+func registerCablingInputFlags(cmd *cobra.Command) {
+	cf := &cablingPlanFlags
+	cmd.Flags().BoolVar(&cf.hostname, "hostname", false, "Name the devices with the built-in reference hostname templates.")
+	registerPlanFlags(cmd, &cf.templatePlanFlags)
+}
+
+func registerCablingPlanFlags(cmd *cobra.Command) {
+	cf := &cablingPlanFlags
+	f := cmd.Flags()
+	f.StringVar(&cf.planFormat, "plan-format", fsc.CablingFormatCsv, fmt.Sprintf("Cabling plan format: %s.", strings.Join(fsc.CablingFormats(), " | ")))
+	f.StringVar(&cf.outputPath, "output", "", "Output file path for the cabling plan (default stdout).")
+	registerCablingInputFlags(cmd)
+}
+
+func registerVerifyCablingFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&cablingPlanFlags.showAll, "all", false, "Also list the cables that are wired as planned.")
+	registerCablingInputFlags(cmd)
+}
+
+func buildCablingPlanConfigFromFlags(cmd *cobra.Command) ([]byte, error) {
+	f := cmd.Flags()
+	cf := &cablingPlanFlags
+	doc := map[string]interface{}{}
+	if f.Changed("hostname") && cf.hostname {
+		doc["hostname"] = map[string]interface{}{}
+	}
+	if err := addPlanSections(cmd, &cf.templatePlanFlags, doc); err != nil {
+		return nil, err
+	}
+	if _, ok := doc["topology"]; !ok {
+		return nil, fmt.Errorf("specify --config-source or at least one topology flag (--topology-leaf-spine, ...)")
+	}
+	return yaml.Marshal(doc)
+}
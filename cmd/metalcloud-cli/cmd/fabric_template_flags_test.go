@@ -107,3 +107,58 @@ func TestBuildTemplateConfigFromFlagsEmpty(t *testing.T) {
 		t.Error("bgp: expected error with no flags set")
 	}
 }
+
+func TestBuildCablingPlanConfigFromFlags(t *testing.T) {
+	cmd := &cobra.Command{Use: "cabling-plan"}
+	registerCablingPlanFlags(cmd)
+	if _, err := buildCablingPlanConfigFromFlags(cmd); err == nil {
+		t.Error("expected error with no topology flag set")
+	}
+	for k, v := range map[string]string{
+		"hostname":            "true",
+		"topology-leaf-spine": "true",
+		"p2p-pool-leaf-spine": "10.254.0.0/16",
+		"plan-format":         "dot",
+		"tag-keys":            "pod=site/pod,rail=site/rail",
+	} {
+		if err := cmd.Flags().Set(k, v); err != nil {
+			t.Fatalf("set %s: %v", k, err)
+		}
+	}
+	data, err := buildCablingPlanConfigFromFlags(cmd)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, data)
+	}
+	if _, ok := doc["hostname"]; !ok {
+		t.Errorf("hostname section missing: %v", doc)
+	}
+	if _, ok := doc["topology"].(map[string]interface{})["leafSpine"]; !ok {
+		t.Errorf("topology.leafSpine missing: %v", doc)
+	}
+	if pools := doc["p2p"].(map[string]interface{})["pools"].(map[string]interface{}); pools["leafSpine"] != "10.254.0.0/16" {
+		t.Errorf("p2p pools wrong: %v", pools)
+	}
+	if tags := doc["tags"].(map[string]interface{}); tags["pod"] != "site/pod" || tags["rail"] != "site/rail" {
+		t.Errorf("tags wrong: %v", tags)
+	}
+	if cablingPlanFlags.planFormat != "dot" {
+		t.Errorf("plan format = %q, want dot", cablingPlanFlags.planFormat)
+	}
+}
+
+func TestVerifyCablingFlags(t *testing.T) {
+	cmd := &cobra.Command{Use: "verify-cabling"}
+	registerVerifyCablingFlags(cmd)
+	for _, name := range append([]string{"all"}, cablingPlanDetailFlags...) {
+		if cmd.Flags().Lookup(name) == nil {
+			t.Errorf("flag --%s not registered", name)
+		}
+	}
+	if cmd.Flags().Lookup("plan-format") != nil {
+		t.Errorf("--plan-format should only exist on cabling-plan")
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/spf13/cobra"
 )
 
 var fabricItem = map[string]interface{}{
@@ -187,3 +190,65 @@ func TestFabricList_Formats(t *testing.T) {
 		})
 	}
 }
+
+// resetFlags restores the named flags of cmd after the test, as the flag
+// values are package state shared by every test that runs the command.
+func resetFlags(t *testing.T, cmd *cobra.Command, names ...string) {
+	t.Cleanup(func() {
+		for _, name := range names {
+			f := cmd.Flags().Lookup(name)
+			_ = f.Value.Set(f.DefValue)
+			f.Changed = false
+		}
+	})
+}
+
+func TestFabricConfigureSwitches_DevicesFileNoEndpointRequired(t *testing.T) {
+	resetFlags(t, fabricConfigureSwitchesCmd, "config-source", "devices-file")
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "fabric-config.yaml")
+	devices := filepath.Join(dir, "inventory.yaml")
+	if err := os.WriteFile(config, []byte("hostname: {}\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := os.WriteFile(devices, []byte("- position: leaf\n  managementAddress: 10.0.0.11\n- position: spine\n  managementAddress: 10.0.0.21\n"), 0o600); err != nil {
+		t.Fatalf("write devices: %v", err)
+	}
+
+	out, err := runCLI(t, nil, "fabric", "configure-switches", "--config-source", config, "--devices-file", devices)
+	if err != nil {
+		t.Fatalf("expected configure-switches --devices-file to work without an endpoint, got: %v", err)
+	}
+	if !json.Valid([]byte(out)) {
+		t.Errorf("invalid JSON plan: %s", out)
+	}
+}
+
+func TestFabricConfigureFreeform_RenderDevicesFileNoEndpointRequired(t *testing.T) {
+	resetFlags(t, fabricConfigureFreeformCmd, "config-source", "devices-file", "render-dir")
+
+	dir := t.TempDir()
+	template := filepath.Join(dir, "freeform.j2")
+	config := filepath.Join(dir, "fabric-config.yaml")
+	devices := filepath.Join(dir, "inventory.yaml")
+	rendered := filepath.Join(dir, "rendered")
+	if err := os.WriteFile(template, []byte("hostname {{ identifierString }}\n"), 0o600); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+	if err := os.WriteFile(config, []byte("hostname: {}\nfreeform:\n  mode: purel3\n  templatePath: "+template+"\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := os.WriteFile(devices, []byte("- position: leaf\n  managementAddress: 10.0.0.11\n- position: spine\n  managementAddress: 10.0.0.21\n"), 0o600); err != nil {
+		t.Fatalf("write devices: %v", err)
+	}
+
+	_, err := runCLI(t, nil, "fabric", "configure-freeform", "--config-source", config, "--devices-file", devices, "--render-dir", rendered)
+	if err != nil {
+		t.Fatalf("expected configure-freeform --render-dir --devices-file to work without an endpoint, got: %v", err)
+	}
+	renders, err := filepath.Glob(filepath.Join(rendered, "*.freeform.conf"))
+	if err != nil || len(renders) != 2 {
+		t.Errorf("renders = %v, %v, want 2", renders, err)
+	}
+}
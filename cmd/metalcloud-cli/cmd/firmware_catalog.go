@@ -29,6 +29,26 @@ var (
 		userPrivateKeyPath      string
 		knownHostsPath          string
 		ignoreHostKeyCheck      bool
+		dryRun                  bool
+		downloadParallelism     int
+		downloadCachePath       string
+		noDownloadCache         bool
+		downloadTimeout         string
+		repoType                string
+		repoLocalPath           string
+		repoUploadUrl           string
+		repoUploadUser          string
+		repoUploadPassword      string
+		repoS3Endpoint          string
+		repoS3Bucket            string
+		repoS3Prefix            string
+		repoS3Region            string
+		repoS3AccessKey         string
+		repoS3SecretKey         string
+		output                  string
+		signingKeyPath          string
+		publicKeyPath           string
+		skipSignatureCheck      bool
 	}{}
 
 	firmwareCatalogCmd = &cobra.Command{
@@ -129,16 +149,58 @@ Optional Flags:
   --download-binaries             Download firmware binaries locally
   --upload-binaries               Upload binaries to offline repository
 
-Offline Repository Configuration (required when --upload-binaries is used):
-  --repo-base-url        Base URL of the offline repository
-  --repo-ssh-host        SSH hostname:port for repository upload
-  --repo-ssh-user        SSH username for repository access
-  --repo-ssh-path        Target directory path on SSH server
+Offline Repository Configuration (used with --upload-binaries):
+  --repo-type            Upload backend: 'sftp' (default), 'local', 'http' or 's3'
+  --repo-base-url        Base URL the repository is served at; binaries are downloaded from
+                         this URL joined with their external ID (default for 'http' and 's3':
+                         the upload URL, or the endpoint, bucket and prefix)
+
+  sftp:  --repo-ssh-host        SSH hostname:port for repository upload (required)
+         --repo-ssh-user        SSH username for repository access (required)
+         --repo-ssh-path        Target directory path on SSH server
+  local: --repo-local-path      Local or NFS mounted directory to copy the binaries to
+  http:  --repo-upload-url      Base URL binaries are uploaded to with HTTP PUT (WebDAV
+                                collections are created as needed)
+         --repo-upload-user     User for HTTP basic authentication
+         --repo-upload-password Password for HTTP basic authentication
+  s3:    --repo-s3-endpoint     URL of the S3-compatible endpoint, e.g. https://s3.eu-central-1.amazonaws.com
+         --repo-s3-bucket       Bucket to upload the binaries to (path-style addressing)
+         --repo-s3-prefix       Key prefix of the binaries in the bucket
+         --repo-s3-region       Region to sign requests for (default: AWS_REGION or us-east-1)
+         --repo-s3-access-key   Access key (default: AWS_ACCESS_KEY_ID)
+         --repo-s3-secret-key   Secret key (default: AWS_SECRET_ACCESS_KEY)
 
 SSH Configuration (mutually exclusive):
   --user-private-key-path    Path to SSH private key (default: ~/.ssh/id_rsa)
   --known-hosts-path         Path to SSH known hosts file (default: ~/.ssh/known_hosts)
-  --ignore-host-key-check    Skip SSH host key verification`,
+  --ignore-host-key-check    Skip SSH host key verification
+
+Download Configuration (used with --download-binaries):
+  --download-parallelism     Number of binaries downloaded at once (default: 4)
+  --download-cache-path      Download cache directory (default: metalcloud-cli/firmware in the user cache directory)
+  --no-download-cache        Do not keep the downloaded binaries in the download cache
+  --download-timeout         Timeout of a single download request, e.g. 30m (default: 1h)
+
+  Binaries with a published checksum are kept in the download cache, keyed by checksum
+  and external ID, so reruns reuse them instead of downloading them again. The cache is
+  never pruned; delete its directory (by default ~/.cache/metalcloud-cli/firmware on
+  Linux) to clear it. Interrupted downloads are kept with a .part suffix, resumed with
+  HTTP range requests and retried up to 3 times. Downloads go through the --proxy of the
+  API connection, or the HTTP_PROXY and HTTPS_PROXY environment variables. A summary of
+  the downloads is printed when they are done.
+
+  All the binaries are downloaded before the first one is uploaded, so the binaries
+  directory (a temporary one unless --vendor-local-binaries-path is given) needs room
+  for the whole catalog, and the download cache as much again unless it is on the same
+  file system, where binaries are hard linked from the cache.
+
+Checksum Verification:
+  Binaries are checked against the MD5 or SHA-256 checksum the vendor catalog publishes
+  (Dell hashMD5 and SHA-256 hashes, Lenovo FileHash, HPE sha256sum, Supermicro md5/sha256)
+  as they are downloaded and, for local binaries, before they are uploaded. Downloads that
+  do not match are deleted. Mismatching binaries are left out of the catalog and reported,
+  and the command then exits with an error. Binaries without a published checksum are not
+  verified.`,
 		Example: `
 Dell example (online):
 metalcloud-cli firmware-catalog create \
@@ -164,6 +226,19 @@ metalcloud-cli firmware-catalog create \
   --repo-ssh-path /var/www/html/dell \
   --update-type offline
 
+Dell example (offline with upload to an S3-compatible object store):
+metalcloud-cli firmware-catalog create \
+  --name "Dell Offline Catalog" \
+  --vendor dell \
+  --vendor-url https://downloads.dell.com/catalog.xml.gz \
+  --download-binaries \
+  --upload-binaries \
+  --repo-type s3 \
+  --repo-s3-endpoint https://minio.mycloud.com \
+  --repo-s3-bucket firmware \
+  --repo-s3-prefix dell \
+  --update-type offline
+
 HP example:
 metalcloud-cli firmware-catalog create \
   --name "HP Gen11 Catalog" \
@@ -238,6 +313,21 @@ server_types_filter:
 					UserPrivateKeyPath:      firmwareCatalogFlags.userPrivateKeyPath,
 					KnownHostsPath:          firmwareCatalogFlags.knownHostsPath,
 					IgnoreHostKeyCheck:      firmwareCatalogFlags.ignoreHostKeyCheck,
+					RepoType:                firmwareCatalogFlags.repoType,
+					RepoLocalPath:           firmwareCatalogFlags.repoLocalPath,
+					RepoUploadUrl:           firmwareCatalogFlags.repoUploadUrl,
+					RepoUploadUser:          firmwareCatalogFlags.repoUploadUser,
+					RepoUploadPassword:      firmwareCatalogFlags.repoUploadPassword,
+					RepoS3Endpoint:          firmwareCatalogFlags.repoS3Endpoint,
+					RepoS3Bucket:            firmwareCatalogFlags.repoS3Bucket,
+					RepoS3Prefix:            firmwareCatalogFlags.repoS3Prefix,
+					RepoS3Region:            firmwareCatalogFlags.repoS3Region,
+					RepoS3AccessKey:         firmwareCatalogFlags.repoS3AccessKey,
+					RepoS3SecretKey:         firmwareCatalogFlags.repoS3SecretKey,
+					DownloadParallelism:     firmwareCatalogFlags.downloadParallelism,
+					DownloadCachePath:       firmwareCatalogFlags.downloadCachePath,
+					NoDownloadCache:         firmwareCatalogFlags.noDownloadCache,
+					DownloadTimeout:         firmwareCatalogFlags.downloadTimeout,
 				}
 			}
 
@@ -294,6 +384,256 @@ Configuration file example (update-config.json):
 		},
 	}
 
+	firmwareCatalogRefreshCmd = &cobra.Command{
+		Use:   "refresh firmware_catalog_id",
+		Short: "Apply vendor catalog changes to an existing firmware catalog",
+		Long: `Apply vendor catalog changes to an existing firmware catalog.
+
+This command re-reads the vendor catalog the firmware catalog was created from and
+compares it with the catalog's binaries, matched by external ID:
+
+  added       The vendor published a binary the catalog does not have yet.
+  changed     The version, download URL or checksum of a binary changed.
+  withdrawn   The vendor no longer publishes a binary of the catalog.
+
+Only these binaries are touched. Added and changed binaries are downloaded, verified
+and uploaded like in create; a changed binary replaces the old record once the new
+one is created. Withdrawn binaries are deleted from the catalog, but their files are
+left in the repository. Only binaries supporting one of the systems refreshed are
+withdrawn, and never those whose vendor file failed checksum verification. The
+catalog's vendor version and release date are then updated.
+
+The vendor, update type and vendor URL are taken from the catalog. When neither
+--server-types nor --vendor-systems is given, the catalog is refreshed for the
+systems it was created or last refreshed for.
+
+Arguments:
+  firmware_catalog_id    The ID of the firmware catalog to refresh
+
+Source Configuration (mutually exclusive):
+  --vendor-url                 URL of the online vendor catalog (default: the catalog's vendor URL)
+  --vendor-local-catalog-path  Path to a local catalog file
+
+Optional Flags:
+  --dry-run                       Only report the differences, change nothing
+  --vendor-token                  Authentication token for vendor API access
+  --server-types                  Comma-separated list of Metalsoft server types to filter
+  --vendor-systems                Comma-separated list of vendor system models to filter
+  --vendor-local-binaries-path    Local directory for downloaded firmware binaries
+  --download-binaries             Download the added and changed binaries
+  --upload-binaries               Upload the added and changed binaries to the offline repository
+
+The offline repository, upload backend, SSH and download flags are the same as for create.`,
+		Example: `
+Preview the changes of a Dell catalog:
+metalcloud-cli firmware-catalog refresh 12345 --dry-run
+
+Refresh an offline catalog, uploading only the new and changed binaries:
+metalcloud-cli firmware-catalog refresh 12345 \
+  --download-binaries \
+  --vendor-local-binaries-path ./downloads \
+  --upload-binaries \
+  --repo-base-url http://repo.mycloud.com/dell \
+  --repo-ssh-host repo.mycloud.com:22 \
+  --repo-ssh-user admin \
+  --repo-ssh-path /var/www/html/dell`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_FIRMWARE_BASELINES_WRITE},
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			firmwareCatalogOptions := firmware_catalog.FirmwareCatalogCreateOptions{
+				VendorUrl:               firmwareCatalogFlags.vendorUrl,
+				VendorToken:             firmwareCatalogFlags.vendorToken,
+				ServerTypesFilter:       firmwareCatalogFlags.serverTypes,
+				VendorSystemsFilter:     firmwareCatalogFlags.vendorSystems,
+				VendorLocalCatalogPath:  firmwareCatalogFlags.vendorLocalCatalogPath,
+				VendorLocalBinariesPath: firmwareCatalogFlags.vendorLocalBinariesPath,
+				DownloadBinaries:        firmwareCatalogFlags.downloadBinaries,
+				UploadBinaries:          firmwareCatalogFlags.uploadBinaries,
+				RepoBaseUrl:             firmwareCatalogFlags.repoBaseUrl,
+				RepoSshHost:             firmwareCatalogFlags.repoSshHost,
+				RepoSshPath:             firmwareCatalogFlags.repoSshPath,
+				RepoSshUser:             firmwareCatalogFlags.repoSshUser,
+				UserPrivateKeyPath:      firmwareCatalogFlags.userPrivateKeyPath,
+				KnownHostsPath:          firmwareCatalogFlags.knownHostsPath,
+				IgnoreHostKeyCheck:      firmwareCatalogFlags.ignoreHostKeyCheck,
+				RepoType:                firmwareCatalogFlags.repoType,
+				RepoLocalPath:           firmwareCatalogFlags.repoLocalPath,
+				RepoUploadUrl:           firmwareCatalogFlags.repoUploadUrl,
+				RepoUploadUser:          firmwareCatalogFlags.repoUploadUser,
+				RepoUploadPassword:      firmwareCatalogFlags.repoUploadPassword,
+				RepoS3Endpoint:          firmwareCatalogFlags.repoS3Endpoint,
+				RepoS3Bucket:            firmwareCatalogFlags.repoS3Bucket,
+				RepoS3Prefix:            firmwareCatalogFlags.repoS3Prefix,
+				RepoS3Region:            firmwareCatalogFlags.repoS3Region,
+				RepoS3AccessKey:         firmwareCatalogFlags.repoS3AccessKey,
+				RepoS3SecretKey:         firmwareCatalogFlags.repoS3SecretKey,
+				DownloadParallelism:     firmwareCatalogFlags.downloadParallelism,
+				DownloadCachePath:       firmwareCatalogFlags.downloadCachePath,
+				NoDownloadCache:         firmwareCatalogFlags.noDownloadCache,
+				DownloadTimeout:         firmwareCatalogFlags.downloadTimeout,
+			}
+
+			return firmware_catalog.FirmwareCatalogRefresh(cmd.Context(), args[0], firmwareCatalogOptions, firmwareCatalogFlags.dryRun)
+		},
+	}
+
+	firmwareCatalogVerifyCmd = &cobra.Command{
+		Use:   "verify firmware_catalog_id",
+		Short: "Re-check the binaries of a firmware catalog in the repository",
+		Long: `Re-check the binaries of a firmware catalog already in the repository.
+
+Each binary of the catalog is downloaded from its cache download URL (or from
+--repo-base-url joined with its external ID) and compared with the MD5 or SHA-256
+checksum the vendor published for it. Nothing is changed.
+
+Statuses:
+  ok           The binary matches its checksum.
+  mismatch     The binary in the repository is corrupt.
+  missing      The repository returned 404 for the binary.
+  error        The binary could not be fetched.
+  unverified   No checksum was published, or the binary is not in a repository.
+
+The command exits with an error when at least one binary is mismatched, missing
+or could not be fetched.
+
+Arguments:
+  firmware_catalog_id    The ID of the firmware catalog to verify
+
+Optional Flags:
+  --repo-base-url    Base URL of the repository to fetch the binaries from
+
+Examples:
+  metalcloud-cli firmware-catalog verify 12345
+  metalcloud-cli fw-catalog verify 12345 --repo-base-url http://repo.mycloud.com/dell -f json`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_FIRMWARE_BASELINES_READ},
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return firmware_catalog.FirmwareCatalogVerify(cmd.Context(), args[0], firmwareCatalogFlags.repoBaseUrl)
+		},
+	}
+
+	firmwareCatalogExportBundleCmd = &cobra.Command{
+		Use:   "export-bundle firmware_catalog_id",
+		Short: "Package a firmware catalog and its binaries for an air-gapped site",
+		Long: `Package a firmware catalog and its binaries into a single signed bundle.
+
+The bundle is a gzip compressed tar archive holding a manifest with the catalog
+definition, the metadata of its binaries and the size and SHA-256 digest of each
+binary file, an Ed25519 signature of the manifest and the binary files. Import it
+with import-bundle on a site without access to the vendor or to this repository.
+
+The binary files are downloaded from the repository the catalog was uploaded to,
+falling back to the vendor download URL, and verified against the vendor checksums.
+With --vendor-local-binaries-path they are taken from a local directory laid out
+as for create instead.
+
+Generate a signing key pair with:
+  openssl genpkey -algorithm ed25519 -out bundle.key
+  openssl pkey -in bundle.key -pubout -out bundle.pub
+
+Arguments:
+  firmware_catalog_id    The ID of the firmware catalog to export
+
+Required Flags:
+  --output              Path of the bundle to write
+  --signing-key         Path to the PEM encoded Ed25519 private key to sign the manifest with
+
+Optional Flags:
+  --vendor-local-binaries-path    Local directory to take the binaries from instead of downloading them
+  --download-parallelism          Number of binaries downloaded at once (default: 4)
+  --download-cache-path           Directory of the download cache
+  --no-download-cache             Do not keep the downloaded binaries in the download cache
+  --download-timeout              Timeout of a single binary download request (default: 1h)`,
+		Example: `
+Export a catalog:
+metalcloud-cli firmware-catalog export-bundle 12345 --output dell-r640.tar.gz --signing-key bundle.key`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_FIRMWARE_BASELINES_READ},
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exportOptions := firmware_catalog.FirmwareCatalogExportOptions{
+				SigningKeyPath:          firmwareCatalogFlags.signingKeyPath,
+				VendorLocalBinariesPath: firmwareCatalogFlags.vendorLocalBinariesPath,
+				DownloadParallelism:     firmwareCatalogFlags.downloadParallelism,
+				DownloadCachePath:       firmwareCatalogFlags.downloadCachePath,
+				NoDownloadCache:         firmwareCatalogFlags.noDownloadCache,
+				DownloadTimeout:         firmwareCatalogFlags.downloadTimeout,
+			}
+
+			return firmware_catalog.FirmwareCatalogExportBundle(cmd.Context(), args[0], firmwareCatalogFlags.output, exportOptions)
+		},
+	}
+
+	firmwareCatalogImportBundleCmd = &cobra.Command{
+		Use:   "import-bundle archive",
+		Short: "Recreate a firmware catalog from a bundle",
+		Long: `Recreate a firmware catalog from a bundle written by export-bundle.
+
+The manifest signature is checked with the exporter's Ed25519 public key before
+anything is extracted, and every binary file is checked against the SHA-256 digest
+in the manifest. The catalog is then created like with create from local binaries:
+the extracted files are verified against the vendor checksums and, with
+--upload-binaries, uploaded to the repository.
+
+Arguments:
+  archive    Path of the bundle to import
+
+Signature Verification (one required):
+  --public-key              Path to the PEM encoded Ed25519 public key of the exporter
+  --skip-signature-check    Import without a public key, checking only the file digests
+
+Optional Flags:
+  --name                          Name of the catalog (default: the exported catalog's name)
+  --description                   Description of the catalog (default: the exported catalog's description)
+  --vendor-local-binaries-path    Directory to extract the binaries to and keep them (default: a temporary directory)
+  --upload-binaries               Upload the binaries to the offline repository
+  --repo-base-url                 Base URL of the offline repository
+
+The upload backend and SSH flags are the same as for create.`,
+		Example: `
+Import a bundle into a local web server directory:
+metalcloud-cli firmware-catalog import-bundle dell-r640.tar.gz \
+  --public-key bundle.pub \
+  --upload-binaries \
+  --repo-type local \
+  --repo-local-path /var/www/html/dell \
+  --repo-base-url http://repo.mycloud.com/dell`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_FIRMWARE_BASELINES_WRITE},
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			firmwareCatalogOptions := firmware_catalog.FirmwareCatalogCreateOptions{
+				Name:                    firmwareCatalogFlags.name,
+				Description:             firmwareCatalogFlags.description,
+				VendorLocalBinariesPath: firmwareCatalogFlags.vendorLocalBinariesPath,
+				UploadBinaries:          firmwareCatalogFlags.uploadBinaries,
+				RepoBaseUrl:             firmwareCatalogFlags.repoBaseUrl,
+				RepoSshHost:             firmwareCatalogFlags.repoSshHost,
+				RepoSshPath:             firmwareCatalogFlags.repoSshPath,
+				RepoSshUser:             firmwareCatalogFlags.repoSshUser,
+				UserPrivateKeyPath:      firmwareCatalogFlags.userPrivateKeyPath,
+				KnownHostsPath:          firmwareCatalogFlags.knownHostsPath,
+				IgnoreHostKeyCheck:      firmwareCatalogFlags.ignoreHostKeyCheck,
+				RepoType:                firmwareCatalogFlags.repoType,
+				RepoLocalPath:           firmwareCatalogFlags.repoLocalPath,
+				RepoUploadUrl:           firmwareCatalogFlags.repoUploadUrl,
+				RepoUploadUser:          firmwareCatalogFlags.repoUploadUser,
+				RepoUploadPassword:      firmwareCatalogFlags.repoUploadPassword,
+				RepoS3Endpoint:          firmwareCatalogFlags.repoS3Endpoint,
+				RepoS3Bucket:            firmwareCatalogFlags.repoS3Bucket,
+				RepoS3Prefix:            firmwareCatalogFlags.repoS3Prefix,
+				RepoS3Region:            firmwareCatalogFlags.repoS3Region,
+				RepoS3AccessKey:         firmwareCatalogFlags.repoS3AccessKey,
+				RepoS3SecretKey:         firmwareCatalogFlags.repoS3SecretKey,
+			}
+
+			return firmware_catalog.FirmwareCatalogImportBundle(cmd.Context(), args[0], firmwareCatalogOptions,
+				firmwareCatalogFlags.publicKeyPath, firmwareCatalogFlags.skipSignatureCheck)
+		},
+	}
+
 	firmwareCatalogDeleteCmd = &cobra.Command{
 		Use:     "delete firmware_catalog_id",
 		Aliases: []string{"rm"},
@@ -338,22 +678,85 @@ func init() {
 	firmwareCatalogCreateCmd.Flags().StringSliceVar(&firmwareCatalogFlags.vendorSystems, "vendor-systems", []string{}, "List of supported vendor systems (comma-separated)")
 	firmwareCatalogCreateCmd.Flags().BoolVar(&firmwareCatalogFlags.downloadBinaries, "download-binaries", false, "Download binaries from the vendor catalog")
 	firmwareCatalogCreateCmd.Flags().BoolVar(&firmwareCatalogFlags.uploadBinaries, "upload-binaries", false, "Upload binaries to the offline repository")
-	firmwareCatalogCreateCmd.Flags().StringVar(&firmwareCatalogFlags.repoBaseUrl, "repo-base-url", "", "Base URL of the offline repository")
-	firmwareCatalogCreateCmd.Flags().StringVar(&firmwareCatalogFlags.repoSshHost, "repo-ssh-host", "", "SSH host with port of the offline repository")
-	firmwareCatalogCreateCmd.Flags().StringVar(&firmwareCatalogFlags.repoSshPath, "repo-ssh-path", "", "The path to the target folder in the SSH repository")
-	firmwareCatalogCreateCmd.Flags().StringVar(&firmwareCatalogFlags.repoSshUser, "repo-ssh-user", "", "SSH user for the offline repository")
-	firmwareCatalogCreateCmd.Flags().StringVar(&firmwareCatalogFlags.userPrivateKeyPath, "user-private-key-path", "~/.ssh/id_rsa", "Path to the user's private SSH key")
-	firmwareCatalogCreateCmd.Flags().StringVar(&firmwareCatalogFlags.knownHostsPath, "known-hosts-path", "~/.ssh/known_hosts", "Path to the known hosts file for SSH connections")
-	firmwareCatalogCreateCmd.Flags().BoolVar(&firmwareCatalogFlags.ignoreHostKeyCheck, "ignore-host-key-check", false, "Ignore host key check for SSH connections")
+	addRepositoryFlags(firmwareCatalogCreateCmd)
+	addDownloadFlags(firmwareCatalogCreateCmd)
 	firmwareCatalogCreateCmd.MarkFlagsMutuallyExclusive("config-source", "name")
 	firmwareCatalogCreateCmd.MarkFlagsRequiredTogether("name", "vendor", "update-type")
 	firmwareCatalogCreateCmd.MarkFlagsMutuallyExclusive("vendor-url", "vendor-local-catalog-path")
-	firmwareCatalogCreateCmd.MarkFlagsRequiredTogether("upload-binaries", "repo-ssh-host", "repo-ssh-user")
-	firmwareCatalogCreateCmd.MarkFlagsMutuallyExclusive("known-hosts-path", "ignore-host-key-check")
 
 	firmwareCatalogCmd.AddCommand(firmwareCatalogUpdateCmd)
 	firmwareCatalogUpdateCmd.Flags().StringVar(&firmwareCatalogFlags.configSource, "config-source", "", "Source of the firmware catalog configuration updates. Can be 'pipe' or path to a JSON file.")
 	firmwareCatalogUpdateCmd.MarkFlagsOneRequired("config-source")
 
+	firmwareCatalogCmd.AddCommand(firmwareCatalogRefreshCmd)
+	firmwareCatalogRefreshCmd.Flags().BoolVar(&firmwareCatalogFlags.dryRun, "dry-run", false, "Only report the binaries that would be added, changed or withdrawn")
+	firmwareCatalogRefreshCmd.Flags().StringVar(&firmwareCatalogFlags.vendorUrl, "vendor-url", "", "URL of the online vendor catalog (default: the catalog's vendor URL)")
+	firmwareCatalogRefreshCmd.Flags().StringVar(&firmwareCatalogFlags.vendorToken, "vendor-token", "", "Token for accessing the online vendor catalog")
+	firmwareCatalogRefreshCmd.Flags().StringVar(&firmwareCatalogFlags.vendorLocalCatalogPath, "vendor-local-catalog-path", "", "Path to the local catalog file")
+	firmwareCatalogRefreshCmd.Flags().StringVar(&firmwareCatalogFlags.vendorLocalBinariesPath, "vendor-local-binaries-path", "", "Path to the local binaries directory")
+	firmwareCatalogRefreshCmd.Flags().StringSliceVar(&firmwareCatalogFlags.serverTypes, "server-types", []string{}, "List of supported Metalsoft server types (comma-separated)")
+	firmwareCatalogRefreshCmd.Flags().StringSliceVar(&firmwareCatalogFlags.vendorSystems, "vendor-systems", []string{}, "List of supported vendor systems (comma-separated)")
+	firmwareCatalogRefreshCmd.Flags().BoolVar(&firmwareCatalogFlags.downloadBinaries, "download-binaries", false, "Download the added and changed binaries from the vendor catalog")
+	firmwareCatalogRefreshCmd.Flags().BoolVar(&firmwareCatalogFlags.uploadBinaries, "upload-binaries", false, "Upload the added and changed binaries to the offline repository")
+	addRepositoryFlags(firmwareCatalogRefreshCmd)
+	addDownloadFlags(firmwareCatalogRefreshCmd)
+	firmwareCatalogRefreshCmd.MarkFlagsMutuallyExclusive("vendor-url", "vendor-local-catalog-path")
+
+	firmwareCatalogCmd.AddCommand(firmwareCatalogVerifyCmd)
+	firmwareCatalogVerifyCmd.Flags().StringVar(&firmwareCatalogFlags.repoBaseUrl, "repo-base-url", "", "Base URL of the repository to fetch the binaries from (default: each binary's cache download URL)")
+
+	firmwareCatalogCmd.AddCommand(firmwareCatalogExportBundleCmd)
+	firmwareCatalogExportBundleCmd.Flags().StringVar(&firmwareCatalogFlags.output, "output", "", "Path of the bundle to write")
+	firmwareCatalogExportBundleCmd.Flags().StringVar(&firmwareCatalogFlags.signingKeyPath, "signing-key", "", "Path to the PEM encoded Ed25519 private key to sign the bundle with")
+	firmwareCatalogExportBundleCmd.Flags().StringVar(&firmwareCatalogFlags.vendorLocalBinariesPath, "vendor-local-binaries-path", "", "Path to a local binaries directory to take the binaries from instead of downloading them")
+	addDownloadFlags(firmwareCatalogExportBundleCmd)
+	firmwareCatalogExportBundleCmd.MarkFlagRequired("output")
+	firmwareCatalogExportBundleCmd.MarkFlagRequired("signing-key")
+
+	firmwareCatalogCmd.AddCommand(firmwareCatalogImportBundleCmd)
+	firmwareCatalogImportBundleCmd.Flags().StringVar(&firmwareCatalogFlags.publicKeyPath, "public-key", "", "Path to the PEM encoded Ed25519 public key to verify the bundle with")
+	firmwareCatalogImportBundleCmd.Flags().BoolVar(&firmwareCatalogFlags.skipSignatureCheck, "skip-signature-check", false, "Import the bundle without verifying its signature")
+	firmwareCatalogImportBundleCmd.Flags().StringVar(&firmwareCatalogFlags.name, "name", "", "Name of the firmware catalog (default: the exported catalog's name)")
+	firmwareCatalogImportBundleCmd.Flags().StringVar(&firmwareCatalogFlags.description, "description", "", "Description of the firmware catalog (default: the exported catalog's description)")
+	firmwareCatalogImportBundleCmd.Flags().StringVar(&firmwareCatalogFlags.vendorLocalBinariesPath, "vendor-local-binaries-path", "", "Path to extract the binaries to (default: a temporary directory)")
+	firmwareCatalogImportBundleCmd.Flags().BoolVar(&firmwareCatalogFlags.uploadBinaries, "upload-binaries", false, "Upload binaries to the offline repository")
+	addRepositoryFlags(firmwareCatalogImportBundleCmd)
+	firmwareCatalogImportBundleCmd.MarkFlagsMutuallyExclusive("public-key", "skip-signature-check")
+	firmwareCatalogImportBundleCmd.MarkFlagsOneRequired("public-key", "skip-signature-check")
+
 	firmwareCatalogCmd.AddCommand(firmwareCatalogDeleteCmd)
 }
+
+// addRepositoryFlags adds the flags selecting and configuring the offline
+// repository the binaries are uploaded to.
+func addRepositoryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoBaseUrl, "repo-base-url", "", "Base URL of the offline repository")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoSshHost, "repo-ssh-host", "", "SSH host with port of the offline repository")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoSshPath, "repo-ssh-path", "", "The path to the target folder in the SSH repository")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoSshUser, "repo-ssh-user", "", "SSH user for the offline repository")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.userPrivateKeyPath, "user-private-key-path", "~/.ssh/id_rsa", "Path to the user's private SSH key")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.knownHostsPath, "known-hosts-path", "~/.ssh/known_hosts", "Path to the known hosts file for SSH connections")
+	cmd.Flags().BoolVar(&firmwareCatalogFlags.ignoreHostKeyCheck, "ignore-host-key-check", false, "Ignore host key check for SSH connections")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoType, "repo-type", "sftp", "Repository upload backend: 'sftp', 'local', 'http' or 's3'")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoLocalPath, "repo-local-path", "", "Directory to copy the binaries to for the 'local' repository type")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoUploadUrl, "repo-upload-url", "", "Base URL to upload the binaries to with HTTP PUT for the 'http' repository type")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoUploadUser, "repo-upload-user", "", "User for HTTP basic authentication to the 'http' repository")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoUploadPassword, "repo-upload-password", "", "Password for HTTP basic authentication to the 'http' repository")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoS3Endpoint, "repo-s3-endpoint", "", "URL of the S3-compatible endpoint for the 's3' repository type")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoS3Bucket, "repo-s3-bucket", "", "Bucket to upload the binaries to for the 's3' repository type")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoS3Prefix, "repo-s3-prefix", "", "Key prefix of the binaries in the S3 bucket")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoS3Region, "repo-s3-region", "", "Region of the S3 bucket (default: AWS_REGION or us-east-1)")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoS3AccessKey, "repo-s3-access-key", "", "S3 access key (default: AWS_ACCESS_KEY_ID)")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.repoS3SecretKey, "repo-s3-secret-key", "", "S3 secret key (default: AWS_SECRET_ACCESS_KEY)")
+	cmd.MarkFlagsMutuallyExclusive("known-hosts-path", "ignore-host-key-check")
+}
+
+// addDownloadFlags adds the flags controlling how the binaries are downloaded
+// from the vendor.
+func addDownloadFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&firmwareCatalogFlags.downloadParallelism, "download-parallelism", 4, "Number of binaries downloaded at once")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.downloadCachePath, "download-cache-path", "", "Path to the download cache directory (default: metalcloud-cli/firmware in the user cache directory)")
+	cmd.Flags().BoolVar(&firmwareCatalogFlags.noDownloadCache, "no-download-cache", false, "Do not keep the downloaded binaries in the download cache")
+	cmd.Flags().StringVar(&firmwareCatalogFlags.downloadTimeout, "download-timeout", "1h", "Timeout of a single binary download request")
+	cmd.MarkFlagsMutuallyExclusive("download-cache-path", "no-download-cache")
+}
@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/metalsoft-io/metalcloud-cli/cmd/metalcloud-cli/system"
 	"github.com/metalsoft-io/metalcloud-cli/internal/infrastructure"
+	"github.com/metalsoft-io/metalcloud-cli/internal/manifest"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
@@ -19,6 +22,7 @@ var (
 		attemptHardShutdown bool
 		softShutdownTimeout int
 		forceShutdown       bool
+		outputPath          string
 	}{}
 
 	infrastructureUtilFlags = struct {
@@ -52,6 +56,7 @@ Available Commands:
   deploy       Deploy infrastructure changes to physical resources
   cancel-deploy Cancel an ongoing infrastructure deployment
   revert       Revert infrastructure to the last deployed state
+  export       Export an infrastructure as a manifest that can be applied again
   users        Manage user access to infrastructures
   statistics   View infrastructure deployment and job statistics
   utilization  Generate resource utilization reports
@@ -126,7 +131,7 @@ Examples:
 	}
 
 	infrastructureCreateCmd = &cobra.Command{
-		Use:     "create site_id label",
+		Use:     "create [site_id] label",
 		Aliases: []string{"new"},
 		Short:   "Create a new infrastructure in a specific site",
 		Long: `Create a new infrastructure with the specified label in the given site.
@@ -136,7 +141,8 @@ actual resources. After creation, you can add compute instances, drives, and net
 to the infrastructure before deploying it.
 
 Arguments:
-  site_id  The numeric ID of the site where the infrastructure will be created
+  site_id  The numeric ID of the site where the infrastructure will be created. Can be
+           omitted when the active configuration context defines a default site.
   label    A unique label (name) for the infrastructure
 
 Examples:
@@ -146,12 +152,24 @@ Examples:
   # Create infrastructure with a descriptive name
   metalcloud-cli infrastructure create 2 "production-database-cluster"
 
+  # Create infrastructure in the default site of the current context
+  metalcloud-cli infrastructure create "staging-cluster"
+
   # Using the alias
   metalcloud-cli infrastructure new 1 "test-environment"`,
 		SilenceUsage: true,
 		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_INFRASTRUCTURES_WRITE},
-		Args:         cobra.ExactArgs(2),
+		Args:         cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				siteId := viper.GetString(system.ConfigSite)
+				if siteId == "" {
+					return fmt.Errorf("site_id is required when the current context does not define a default site")
+				}
+
+				return infrastructure.InfrastructureCreate(cmd.Context(), siteId, args[0])
+			}
+
 			return infrastructure.InfrastructureCreate(cmd.Context(), args[0], args[1])
 		},
 	}
@@ -267,6 +285,9 @@ Examples:
   # Force deployment with immediate shutdown
   metalcloud-cli infrastructure deploy emergency-fix --force-shutdown
 
+  # Deploy and wait up to an hour for the deploy to finish
+  metalcloud-cli infrastructure deploy web-cluster --wait --wait-timeout 1h
+
   # Using the alias
   metalcloud-cli infrastructure apply production-cluster`,
 		SilenceUsage: true,
@@ -346,6 +367,42 @@ Examples:
 		},
 	}
 
+	infrastructureExportCmd = &cobra.Command{
+		Use:     "export infrastructure_id_or_label",
+		Aliases: []string{"dump"},
+		Short:   "Export an infrastructure as a manifest that can be applied again",
+		Long: `Export an infrastructure and all its resources as a multi-document YAML manifest.
+
+The manifest contains the infrastructure, its server and VM instance groups with their
+network connections, drives, file shares, buckets and extension instances. Server-assigned
+fields such as ids, revisions, statuses and timestamps are removed, so the manifest can be
+fed back to 'metalcloud-cli apply' to recreate the environment. To clone an infrastructure
+into another site, change the infrastructure label and 'siteId' before applying it.
+
+Arguments:
+  infrastructure_id_or_label  The ID (numeric) or label (string) of the infrastructure
+
+Flags:
+  --output                    Write the manifest to this file instead of stdout
+
+Examples:
+  # Print the manifest of an infrastructure
+  metalcloud-cli infrastructure export web-cluster
+
+  # Save the manifest to a file
+  metalcloud-cli infrastructure export 123 --output web-cluster.yaml
+
+  # Clone an infrastructure after editing the label and siteId
+  metalcloud-cli infrastructure export web-cluster --output clone.yaml
+  metalcloud-cli apply --config-source clone.yaml`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.REQUIRED_PERMISSION: system.PERMISSION_INFRASTRUCTURES_READ},
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return manifest.Export(cmd.Context(), args[0], infrastructureFlags.outputPath)
+		},
+	}
+
 	infrastructureGetUsersCmd = &cobra.Command{
 		Use:     "users infrastructure_id_or_label",
 		Aliases: []string{"list-users", "get-users"},
@@ -611,6 +668,9 @@ func init() {
 
 	infrastructureCmd.AddCommand(infrastructureCancelDeployCmd)
 
+	infrastructureCmd.AddCommand(infrastructureExportCmd)
+	infrastructureExportCmd.Flags().StringVar(&infrastructureFlags.outputPath, "output", "", "Output file path for the exported manifest.")
+
 	infrastructureCmd.AddCommand(infrastructureGetUsersCmd)
 
 	infrastructureCmd.AddCommand(infrastructureAddUserCmd)
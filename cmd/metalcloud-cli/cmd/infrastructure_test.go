@@ -175,3 +175,21 @@ func TestInfrastructureList_Formats(t *testing.T) {
 		})
 	}
 }
+
+// --- infrastructure export ---
+
+func TestInfrastructureExport(t *testing.T) {
+	srv := newApplyTestServer()
+	defer srv.Close()
+
+	out, err := runCLI(t, srv, "infrastructure", "export", "test-infra")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "kind: Infrastructure") || !strings.Contains(out, "kind: Drive") {
+		t.Errorf("expected infrastructure and drive documents, got: %s", out)
+	}
+	if strings.Contains(out, "revision") || strings.Contains(out, "Timestamp") {
+		t.Errorf("expected server-assigned fields to be stripped, got: %s", out)
+	}
+}
@@ -444,8 +444,9 @@ Permissions:
 		Long: `Wait for a job group to finish executing.
 
 This command first displays the current status of the specified job group. If the
-job group has not finished yet, it polls the job group status every second until
-it finishes, then prints the final status.
+job group has not finished yet, it polls the job group status until it finishes,
+then prints the final status. Polling starts every second and backs off up to every
+30 seconds.
 
 Arguments:
   job_group_id (required)    The numeric ID of the job group to wait for. Must be
@@ -453,13 +454,18 @@ Arguments:
 
 The command exits when:
   - The job group has finished (final status is printed)
-  - An API error occurs while polling
+  - The API returns a client error, e.g. the job group does not exist; connection
+    and server errors are retried
+  - The --wait-timeout elapses, only when given explicitly (waits forever by default)
   - The command is interrupted (e.g., Ctrl+C)
 
 Examples:
   # Wait for job group with ID 15 to finish
   metalcloud-cli job-group wait 15
 
+  # Give up after 10 minutes
+  metalcloud-cli job-group wait 15 --wait-timeout 10m
+
 Permissions:
   Requires job queue read permissions to execute this command.`,
 		SilenceUsage: true,
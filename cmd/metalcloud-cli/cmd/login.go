@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"github.com/metalsoft-io/metalcloud-cli/cmd/metalcloud-cli/system"
+	"github.com/metalsoft-io/metalcloud-cli/internal/credentials"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	loginFlags = struct {
+		apiKeyStdin bool
+	}{}
+
+	loginCmd = &cobra.Command{
+		Use:   "login",
+		Short: "Store the API key of an endpoint in the credential store",
+		Long: `Store the API key of a MetalSoft endpoint so that it does not have to be kept in
+plaintext in the configuration file or in the METALCLOUD_API_KEY environment variable.
+
+The endpoint is taken from the --endpoint flag, the METALCLOUD_ENDPOINT environment
+variable, the current context or the configuration file. The API key is prompted for
+without echo, read from stdin with --api-key-stdin, or taken from --api_key.
+
+The key is stored in the credential store, which is one of:
+  - an external credential helper, when 'credential_helper' is configured. The helper
+    is run with the 'get', 'store' or 'erase' action and speaks the git credential
+    helper protocol over stdin/stdout, e.g. 'git credential-osxkeychain'.
+  - the encrypted credential file otherwise, $HOME/.metalcloud/credentials or the path
+    set with 'credential_file'. The file is encrypted with a passphrase, prompted for
+    or taken from METALCLOUD_CREDENTIAL_PASSPHRASE, or with the contents of the file
+    set with 'credential_keyfile'.
+
+Commands use the stored key of their endpoint when no API key is given directly.
+
+Flags:
+  --api-key-stdin   Read the API key from stdin
+
+Examples:
+  # Store the API key of the configured endpoint, prompting for it
+  metalcloud-cli login
+
+  # Store the API key of another endpoint, reading it from a file
+  metalcloud-cli login -e https://metal.example --api-key-stdin < api-key.txt
+
+  # Store the key with the macOS keychain through the git credential helper
+  METALCLOUD_CREDENTIAL_HELPER="git credential-osxkeychain" metalcloud-cli login`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.LOCAL_COMMAND: "true"},
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyConfigContext(cmd); err != nil {
+				return err
+			}
+
+			return credentials.Login(viper.GetString(system.ConfigEndpoint), viper.GetString(system.ConfigApiKey), loginFlags.apiKeyStdin)
+		},
+	}
+
+	logoutCmd = &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the API key of an endpoint from the credential store",
+		Long: `Remove the API key stored with 'login' for a MetalSoft endpoint.
+
+The endpoint is taken from the --endpoint flag, the METALCLOUD_ENDPOINT environment
+variable, the current context or the configuration file. The encrypted credential file
+is deleted when its last key is removed.
+
+Examples:
+  # Remove the API key of the configured endpoint
+  metalcloud-cli logout
+
+  # Remove the API key of the production context
+  metalcloud-cli --context production logout`,
+		SilenceUsage: true,
+		Annotations:  map[string]string{system.LOCAL_COMMAND: "true"},
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyConfigContext(cmd); err != nil {
+				return err
+			}
+
+			return credentials.Logout(viper.GetString(system.ConfigEndpoint))
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+	loginCmd.Flags().BoolVar(&loginFlags.apiKeyStdin, "api-key-stdin", false, "Read the API key from stdin.")
+
+	rootCmd.AddCommand(logoutCmd)
+}
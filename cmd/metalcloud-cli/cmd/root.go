@@ -1,16 +1,23 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/metalsoft-io/metalcloud-cli/cmd/metalcloud-cli/system"
+	"github.com/metalsoft-io/metalcloud-cli/internal/config_context"
+	"github.com/metalsoft-io/metalcloud-cli/internal/credentials"
+	"github.com/metalsoft-io/metalcloud-cli/internal/job"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/response_inspector"
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
 	"github.com/spf13/viper"
@@ -34,6 +41,8 @@ This CLI requires the correct version of the CLI to be used with the MetalCloud
 		},
 		PersistentPreRunE:  rootPersistentPreRun,
 		PersistentPostRunE: rootPersistentPostRun,
+		// Errors are printed by Execute, as text or as JSON.
+		SilenceErrors: true,
 	}
 )
 
@@ -48,9 +57,21 @@ func init() {
 	rootCmd.PersistentFlags().StringP(system.ConfigApiKey, "k", "", "MetalCloud API key")
 	rootCmd.PersistentFlags().StringP(logger.ConfigVerbosity, "v", "INFO", "Log level verbosity")
 	rootCmd.PersistentFlags().StringP(logger.ConfigLogFile, "l", "", "Log file path")
-	rootCmd.PersistentFlags().StringP(formatter.ConfigFormat, "f", "text", "Output format. Supported values are 'text','csv','md','json','yaml'.")
+	rootCmd.PersistentFlags().StringP(formatter.ConfigFormat, "f", "text", "Output format. Supported values are 'text','csv','md','json','yaml','jsonpath=<template>','jsonpath-file=<path>','go-template=<template>','go-template-file=<path>','custom-columns=<TITLE:.path,...>','wide'.")
+	rootCmd.PersistentFlags().StringP(formatter.ConfigOutputFormat, "o", "", "Alias of --format, e.g. '-o wide' for tables with all the available columns")
+	rootCmd.PersistentFlags().StringSlice(formatter.ConfigColumns, nil, "Table columns to show, in order, by title or JSON path, e.g. 'ID,Label,.config.deployStatus'")
+	rootCmd.PersistentFlags().String(formatter.ConfigSortBy, "", "Sort table rows by a column title or JSON path, e.g. 'Created:DESC'. Commands with their own --sort-by sort on the server instead.")
+	rootCmd.PersistentFlags().Bool(formatter.ConfigNoHeaders, false, "Do not print the table headers")
 	rootCmd.PersistentFlags().BoolP(system.ConfigDebug, "d", false, "Set to enable debug logging")
 	rootCmd.PersistentFlags().BoolP(system.ConfigInsecure, "i", false, "Set to allow insecure transport")
+	rootCmd.PersistentFlags().String(system.ConfigCABundle, "", "Path to a PEM bundle of CA certificates to trust for the API endpoint")
+	rootCmd.PersistentFlags().String(system.ConfigClientCert, "", "Path to a PEM client certificate for mutual TLS")
+	rootCmd.PersistentFlags().String(system.ConfigClientKey, "", "Path to the PEM private key of the client certificate")
+	rootCmd.PersistentFlags().String(system.ConfigTLSMinVersion, "", "Minimum TLS version. Supported values are '1.0', '1.1', '1.2' and '1.3'.")
+	rootCmd.PersistentFlags().String(system.ConfigProxy, "", "URL of the HTTP(S) proxy used to reach the API endpoint")
+	rootCmd.PersistentFlags().String(system.ConfigContext, "", "Name of the configuration context to use instead of the current context")
+	rootCmd.PersistentFlags().Bool(job.ConfigWait, false, "Wait for the jobs started by the command to finish")
+	rootCmd.PersistentFlags().Duration(job.ConfigWaitTimeout, 30*time.Minute, "Maximum time to wait with --wait, 0 waits forever")
 
 	// Add hidden flag to enable development mode
 	rootCmd.PersistentFlags().BoolVarP(&system.AllowDevelop, "allow_develop", "x", false, "Allow development mode")
@@ -91,6 +112,20 @@ func rootPersistentPreRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// -o takes precedence over --format and the format of the context
+	if outputFormat := viper.GetString(formatter.ConfigOutputFormat); outputFormat != "" {
+		viper.Set(formatter.ConfigFormat, outputFormat)
+	}
+
+	if isLocalCommand(cmd) {
+		return nil
+	}
+
+	err = applyConfigContext(cmd)
+	if err != nil {
+		return err
+	}
+
 	endpoint := viper.GetString(system.ConfigEndpoint)
 
 	// Commands that don't require endpoint or API key
@@ -111,20 +146,42 @@ func rootPersistentPreRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid API endpoint URL: %s", endpoint)
 	}
 
+	// Fall back to the API key stored with 'login'
+	if !skipValidation && viper.GetString(system.ConfigApiKey) == "" {
+		apiKey, err := credentials.Resolve(endpoint)
+		if err != nil {
+			return err
+		}
+		if apiKey != "" {
+			viper.Set(system.ConfigApiKey, apiKey)
+		}
+	}
+
 	if !skipValidation && viper.GetString(system.ConfigApiKey) == "" {
-		return fmt.Errorf("API key is required. Use --%s flag or set %s_%s environment variable",
+		return fmt.Errorf("API key is required. Use --%s flag, set %s_%s environment variable or run 'metalcloud-cli login'",
 			system.ConfigApiKey,
 			strings.ToUpper(system.ConfigPrefix),
 			strings.ToUpper(system.ConfigApiKey))
 	}
 
 	// Create API client
-	ctx := api.SetApiClient(cmd.Context(),
+	ctx, err := api.SetApiClient(cmd.Context(),
 		viper.GetString(system.ConfigEndpoint),
 		viper.GetString(system.ConfigApiKey),
 		viper.GetBool(system.ConfigDebug),
-		viper.GetBool(system.ConfigInsecure),
+		api.TransportOptions{
+			InsecureSkipVerify: viper.GetBool(system.ConfigInsecure),
+			CABundle:           viper.GetString(system.ConfigCABundle),
+			ClientCert:         viper.GetString(system.ConfigClientCert),
+			ClientKey:          viper.GetString(system.ConfigClientKey),
+			MinTLSVersion:      viper.GetString(system.ConfigTLSMinVersion),
+			Proxy:              viper.GetString(system.ConfigProxy),
+		},
+		retryOptionsFromConfig(),
 	)
+	if err != nil {
+		return err
+	}
 
 	// Skip version validation for version and completion commands since they may fail with develop versions
 	if !skipValidation {
@@ -160,6 +217,114 @@ func rootPersistentPreRun(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// retryOptionsFromConfig returns the API retry options, taking the defaults
+// for the settings missing from the config file and the environment.
+func retryOptionsFromConfig() api.RetryOptions {
+	options := api.DefaultRetryOptions()
+
+	if viper.IsSet(system.ConfigRetryMaxAttempts) {
+		options.MaxAttempts = viper.GetInt(system.ConfigRetryMaxAttempts)
+	}
+	if viper.IsSet(system.ConfigRetryInitialBackoff) {
+		options.InitialBackoff = viper.GetDuration(system.ConfigRetryInitialBackoff)
+	}
+	if viper.IsSet(system.ConfigRetryMaxBackoff) {
+		options.MaxBackoff = viper.GetDuration(system.ConfigRetryMaxBackoff)
+	}
+	options.RetryPost = viper.GetBool(system.ConfigRetryPost)
+
+	return options
+}
+
+// applyConfigContext copies the settings of the selected context into viper.
+// Values given explicitly as flags or environment variables take precedence
+// over the context, which in turn takes precedence over top-level config keys.
+// A context with its own endpoint also clears the top-level credentials it
+// does not set, as those belong to the top-level endpoint; the API key is then
+// resolved from the credential store of the context's endpoint.
+func applyConfigContext(cmd *cobra.Command) error {
+	configContext, err := config_context.Resolve(viper.GetString(system.ConfigContext))
+	if err != nil {
+		return err
+	}
+	if configContext == nil {
+		return nil
+	}
+
+	settings := map[string]interface{}{
+		system.ConfigEndpoint:      configContext.Endpoint,
+		system.ConfigApiKey:        configContext.ApiKey,
+		system.ConfigInsecure:      configContext.Insecure,
+		system.ConfigCABundle:      configContext.CABundle,
+		system.ConfigClientCert:    configContext.ClientCert,
+		system.ConfigClientKey:     configContext.ClientKey,
+		system.ConfigTLSMinVersion: configContext.TLSMinVersion,
+		system.ConfigProxy:         configContext.Proxy,
+		formatter.ConfigFormat:     configContext.Format,
+		system.ConfigSite:          configContext.Site,
+	}
+
+	for key, value := range settings {
+		if isExplicitSetting(cmd, key) {
+			continue
+		}
+
+		if key == formatter.ConfigFormat && viper.GetString(formatter.ConfigOutputFormat) != "" {
+			continue
+		}
+
+		if !configContext.IsSet(key) {
+			if configContext.IsSet(system.ConfigEndpoint) && slices.Contains(endpointCredentialKeys, key) {
+				viper.Set(key, "")
+			}
+			continue
+		}
+
+		viper.Set(key, value)
+	}
+
+	return nil
+}
+
+// endpointCredentialKeys are the settings that only apply to the endpoint they
+// are configured with.
+var endpointCredentialKeys = []string{
+	system.ConfigApiKey,
+	system.ConfigClientCert,
+	system.ConfigClientKey,
+	system.ConfigCABundle,
+}
+
+// isExplicitSetting reports whether key was given as a flag or an environment
+// variable for this invocation.
+func isExplicitSetting(cmd *cobra.Command, key string) bool {
+	if flag := cmd.Flags().Lookup(key); flag != nil && flag.Changed {
+		return true
+	}
+
+	_, ok := os.LookupEnv(strings.ToUpper(system.ConfigPrefix + "_" + key))
+	return ok
+}
+
+// isLocalCommand reports whether the command, or one of its parents, is
+// annotated as working only with local state, or whether this invocation set
+// the flag that makes the command work only with local state.
+func isLocalCommand(cmd *cobra.Command) bool {
+	if name, ok := cmd.Annotations[system.LOCAL_WHEN_FLAG]; ok {
+		if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+			return true
+		}
+	}
+
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[system.LOCAL_COMMAND] == "true" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func rootPersistentPostRun(cmd *cobra.Command, args []string) error {
 	return nil
 }
@@ -180,7 +345,38 @@ func hideUnavailableCommands(cmd *cobra.Command, userPermissionKeys []string) {
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	if err != nil {
+		printError(err)
+	}
+	return err
+}
+
+// ExitCode returns the process exit code for an error returned by Execute.
+func ExitCode(err error) int {
+	return response_inspector.ExitCode(err)
+}
+
+// printError writes the error to stderr, as a JSON object when the output
+// format is JSON so that scripts can parse it.
+func printError(err error) {
+	if strings.ToLower(viper.GetString(formatter.ConfigFormat)) != "json" {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return
+	}
+
+	var output interface{} = map[string]interface{}{"message": err.Error()}
+	var apiErr *response_inspector.APIError
+	if errors.As(err, &apiErr) {
+		output = apiErr
+	}
+
+	data, marshalErr := json.Marshal(output)
+	if marshalErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(data))
 }
 
 func GenerateDocs() error {
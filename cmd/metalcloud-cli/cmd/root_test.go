@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/metalsoft-io/metalcloud-cli/cmd/metalcloud-cli/system"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// root_test.go covers:
+//   applyConfigContext — the settings a context overrides and clears
+
+// useContextConfig loads a config file with the given content into viper and
+// restores the root flag bindings the other tests rely on afterwards.
+func useContextConfig(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "metalcloud.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	viper.SetConfigFile(path)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	t.Cleanup(func() {
+		viper.Reset()
+		_ = viper.BindPFlags(rootCmd.PersistentFlags())
+		viper.Set(formatter.ConfigFormat, "json")
+	})
+}
+
+const contextEndpointOnlyConfig = `endpoint: https://top.example
+api_key: top-key
+client_cert: /etc/metalcloud/top.crt
+current_context: lab
+contexts:
+  lab:
+    endpoint: https://lab.example
+`
+
+func TestApplyConfigContext_ClearsTopLevelCredentials(t *testing.T) {
+	useContextConfig(t, contextEndpointOnlyConfig)
+
+	if err := applyConfigContext(&cobra.Command{}); err != nil {
+		t.Fatalf("applyConfigContext: %v", err)
+	}
+	if got := viper.GetString(system.ConfigEndpoint); got != "https://lab.example" {
+		t.Errorf("endpoint = %q, want the context's", got)
+	}
+	// The top-level API key belongs to the top-level endpoint; an empty one
+	// lets the credential store of the context's endpoint supply it.
+	if got := viper.GetString(system.ConfigApiKey); got != "" {
+		t.Errorf("api_key = %q, want it cleared", got)
+	}
+	if got := viper.GetString(system.ConfigClientCert); got != "" {
+		t.Errorf("client_cert = %q, want it cleared", got)
+	}
+}
+
+func TestApplyConfigContext_KeepsExplicitCredentials(t *testing.T) {
+	useContextConfig(t, contextEndpointOnlyConfig)
+	t.Setenv("METALCLOUD_API_KEY", "top-key")
+
+	if err := applyConfigContext(&cobra.Command{}); err != nil {
+		t.Fatalf("applyConfigContext: %v", err)
+	}
+	if got := viper.GetString(system.ConfigApiKey); got == "" {
+		t.Errorf("api_key from the environment was cleared")
+	}
+}
@@ -544,7 +544,8 @@ Examples:
 		Long: `Schedule an OS reinstallation for a server instance.
 
 This command marks the server instance for OS reinstallation. The reinstall
-will take effect at the next infrastructure deploy.
+will take effect at the next infrastructure deploy. No job is started until
+then, so use --wait on the infrastructure deploy to follow the reinstall.
 
 Arguments:
   server_instance_id  The numeric ID of the server instance
@@ -445,8 +445,15 @@ func init() {
 
 	if apiKey := v.GetString("api_key"); apiKey != "" {
 		if endpoint := v.GetString(system.ConfigEndpoint); endpoint != "" {
-			ctx := api.SetApiClient(context.Background(), endpoint, apiKey, false, v.GetBool("insecure_skip_verify"))
-			if client, err := api.GetApiClientE(ctx); err == nil {
+			ctx, err := api.SetApiClient(context.Background(), endpoint, apiKey, false, api.TransportOptions{
+				InsecureSkipVerify: v.GetBool(system.ConfigInsecure),
+				CABundle:           v.GetString(system.ConfigCABundle),
+				ClientCert:         v.GetString(system.ConfigClientCert),
+				ClientKey:          v.GetString(system.ConfigClientKey),
+				MinTLSVersion:      v.GetString(system.ConfigTLSMinVersion),
+				Proxy:              v.GetString(system.ConfigProxy),
+			}, api.RetryOptions{MaxAttempts: 1})
+			if client, clientErr := api.GetApiClientE(ctx); err == nil && clientErr == nil {
 				version, _, err := client.SystemAPI.GetVersion(ctx).Execute()
 				if err == nil && version != nil && version.Version != "" {
 					siteOneLinerCmd.Flags().Lookup("images-tag").Usage = "Docker images tag version \x1b[33m(default: " + version.Version + ")\x1b[0m"
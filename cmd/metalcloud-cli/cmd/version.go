@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/metalsoft-io/metalcloud-cli/cmd/metalcloud-cli/system"
+	"github.com/metalsoft-io/metalcloud-cli/internal/config_context"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
 	"github.com/spf13/cobra"
@@ -58,6 +59,9 @@ func runVersionCmd(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Minimum Metalsoft Version: %s\n", minVersion)
 	fmt.Printf("Maximum Metalsoft Version: %s\n", maxVersion)
 
+	if configContext, err := config_context.Resolve(viper.GetString(system.ConfigContext)); err == nil && configContext != nil {
+		fmt.Printf("Context: %s\n", configContext.Name)
+	}
 	fmt.Printf("Metalsoft Endpoint: %s\n", viper.GetString(system.ConfigEndpoint))
 	if cmd.Context() != nil {
 		if apiClient, err := api.GetApiClientE(cmd.Context()); err == nil && apiClient != nil {
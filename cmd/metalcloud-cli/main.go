@@ -53,6 +53,6 @@ func main() {
 
 	err := cmd.Execute()
 	if err != nil {
-		os.Exit(-1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -11,4 +11,25 @@ const (
 	ConfigApiKey   = "api_key"
 	ConfigDebug    = "debug"
 	ConfigInsecure = "insecure_skip_verify"
+	ConfigContext  = "context"
+	ConfigSite     = "site"
+
+	ConfigCABundle      = "ca_bundle"
+	ConfigClientCert    = "client_cert"
+	ConfigClientKey     = "client_key"
+	ConfigTLSMinVersion = "tls_min_version"
+	ConfigProxy         = "proxy"
+
+	ConfigRetryMaxAttempts    = "retry_max_attempts"
+	ConfigRetryInitialBackoff = "retry_initial_backoff"
+	ConfigRetryMaxBackoff     = "retry_max_backoff"
+	ConfigRetryPost           = "retry_post"
 )
+
+// LOCAL_COMMAND marks commands that only work with local state and must not
+// require an API endpoint, an API key or a connection to the server.
+const LOCAL_COMMAND = "localCommand"
+
+// LOCAL_WHEN_FLAG names a flag of the command that, when set, makes the
+// invocation work only with local state, as if it were a LOCAL_COMMAND.
+const LOCAL_WHEN_FLAG = "localWhenFlag"
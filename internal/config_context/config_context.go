@@ -0,0 +1,410 @@
+package config_context
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	currentContextKey = "current_context"
+	contextsKey       = "contexts"
+
+	defaultConfigDir  = ".metalcloud"
+	defaultConfigFile = "metalcloud.yaml"
+)
+
+// Context is a named connection profile stored under the `contexts` key of
+// the configuration file. The YAML keys match the top-level configuration keys
+// so a profile can be copied in and out of a plain configuration file.
+type Context struct {
+	Name     string `yaml:"-" json:"name"`
+	Current  bool   `yaml:"-" json:"current"`
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	ApiKey   string `yaml:"api_key,omitempty" json:"-"`
+	Insecure bool   `yaml:"insecure_skip_verify,omitempty" json:"insecureSkipVerify,omitempty"`
+	Format   string `yaml:"format,omitempty" json:"format,omitempty"`
+	Site     string `yaml:"site,omitempty" json:"site,omitempty"`
+
+	CABundle      string `yaml:"ca_bundle,omitempty" json:"caBundle,omitempty"`
+	ClientCert    string `yaml:"client_cert,omitempty" json:"clientCert,omitempty"`
+	ClientKey     string `yaml:"client_key,omitempty" json:"clientKey,omitempty"`
+	TLSMinVersion string `yaml:"tls_min_version,omitempty" json:"tlsMinVersion,omitempty"`
+	Proxy         string `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+
+	// keys holds the keys set for the context in the configuration file.
+	keys map[string]bool
+}
+
+// IsSet reports whether the context sets key in the configuration file, so an
+// explicit false or empty value can be told apart from an unset one.
+func (c *Context) IsSet(key string) bool {
+	return c.keys[key]
+}
+
+type contextsFile struct {
+	CurrentContext string             `yaml:"current_context,omitempty"`
+	Contexts       map[string]Context `yaml:"contexts,omitempty"`
+}
+
+var contextPrintConfig = formatter.PrintConfig{
+	FieldsConfig: map[string]formatter.RecordFieldConfig{
+		"Current": {
+			Title:       "Current",
+			Transformer: formatter.FormatBooleanValue,
+			Order:       1,
+		},
+		"Name": {
+			Title: "Name",
+			Order: 2,
+		},
+		"Endpoint": {
+			Title:    "Endpoint",
+			MaxWidth: 50,
+			Order:    3,
+		},
+		"Insecure": {
+			Title:       "Insecure",
+			Transformer: formatter.FormatBooleanValue,
+			Order:       4,
+		},
+		"Format": {
+			Title: "Format",
+			Order: 5,
+		},
+		"Site": {
+			Title: "Site",
+			Order: 6,
+		},
+	},
+}
+
+// ConfigFilePath returns the configuration file the contexts are read from and
+// written to: the file viper loaded, or $HOME/.metalcloud/metalcloud.yaml when
+// no configuration file was found.
+func ConfigFilePath() (string, error) {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %v", err)
+	}
+
+	return filepath.Join(home, defaultConfigDir, defaultConfigFile), nil
+}
+
+func readContextsFile(path string) (*contextsFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &contextsFile{Contexts: map[string]Context{}}, nil
+		}
+		return nil, fmt.Errorf("failed to read config file '%s': %v", path, err)
+	}
+
+	file := contextsFile{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %v", path, err)
+	}
+	if file.Contexts == nil {
+		file.Contexts = map[string]Context{}
+	}
+
+	keys := struct {
+		Contexts map[string]map[string]yaml.Node `yaml:"contexts"`
+	}{}
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %v", path, err)
+	}
+	for name, values := range keys.Contexts {
+		context := file.Contexts[name]
+		context.keys = map[string]bool{}
+		for key := range values {
+			context.keys[key] = true
+		}
+		file.Contexts[name] = context
+	}
+
+	return &file, nil
+}
+
+// writeContextsFile replaces the `current_context` and `contexts` keys in the
+// configuration file, leaving every other key and comment untouched.
+func writeContextsFile(path string, file *contextsFile) error {
+	doc := yaml.Node{}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file '%s': %v", path, err)
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file '%s': %v", path, err)
+		}
+	}
+
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("config file '%s' is not a YAML mapping", path)
+	}
+	root := doc.Content[0]
+
+	if file.CurrentContext != "" {
+		if err := setMappingValue(root, currentContextKey, file.CurrentContext); err != nil {
+			return err
+		}
+	} else {
+		removeMappingValue(root, currentContextKey)
+	}
+
+	if len(file.Contexts) > 0 {
+		if err := setMappingValue(root, contextsKey, file.Contexts); err != nil {
+			return err
+		}
+	} else {
+		removeMappingValue(root, contextsKey)
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to serialize config file: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	// The file holds API keys, keep it private to the user.
+	if err := os.WriteFile(path, out, 0o600); err != nil {
+		return fmt.Errorf("failed to write config file '%s': %v", path, err)
+	}
+
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value interface{}) error {
+	valueNode := yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode '%s': %v", key, err)
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = &valueNode
+			return nil
+		}
+	}
+
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&valueNode)
+
+	return nil
+}
+
+func removeMappingValue(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// Resolve returns the context selected by name, or by `current_context` in the
+// loaded configuration file when name is empty. It returns nil when no context
+// is selected.
+func Resolve(name string) (*Context, error) {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		if name != "" {
+			return nil, fmt.Errorf("context '%s' requested but no config file was found", name)
+		}
+		return nil, nil
+	}
+
+	file, err := readContextsFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = file.CurrentContext
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	context, ok := file.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("context '%s' not found in config file '%s'", name, path)
+	}
+	context.Name = name
+	context.Current = true
+
+	return &context, nil
+}
+
+func ContextList() error {
+	logger.Get().Info().Msgf("Listing contexts")
+
+	path, err := ConfigFilePath()
+	if err != nil {
+		return err
+	}
+
+	file, err := readContextsFile(path)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(file.Contexts))
+	for name := range file.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	contexts := make([]Context, 0, len(names))
+	for _, name := range names {
+		context := file.Contexts[name]
+		context.Name = name
+		context.Current = name == file.CurrentContext
+		contexts = append(contexts, context)
+	}
+
+	return formatter.PrintResult(contexts, &contextPrintConfig)
+}
+
+func ContextCurrent(name string) error {
+	logger.Get().Info().Msgf("Get current context")
+
+	context, err := Resolve(name)
+	if err != nil {
+		return err
+	}
+	if context == nil {
+		return fmt.Errorf("no current context is set")
+	}
+
+	return formatter.PrintResult(context, &contextPrintConfig)
+}
+
+func ContextUse(name string) error {
+	logger.Get().Info().Msgf("Switch to context '%s'", name)
+
+	path, err := ConfigFilePath()
+	if err != nil {
+		return err
+	}
+
+	file, err := readContextsFile(path)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := file.Contexts[name]; !ok {
+		err := fmt.Errorf("context '%s' not found in config file '%s'", name, path)
+		logger.Get().Error().Err(err).Msg("")
+		return err
+	}
+
+	file.CurrentContext = name
+
+	if err := writeContextsFile(path, file); err != nil {
+		return err
+	}
+
+	if formatter.IsTextFormat() {
+		fmt.Printf("Switched to context '%s'\n", name)
+	}
+
+	return nil
+}
+
+// ContextAdd creates the named context, or replaces it when it already exists
+// and overwrite is set. The first context added becomes the current one.
+func ContextAdd(context Context, overwrite bool) error {
+	logger.Get().Info().Msgf("Add context '%s'", context.Name)
+
+	if context.Name == "" {
+		return fmt.Errorf("context name is required")
+	}
+	if context.Endpoint == "" {
+		return fmt.Errorf("context endpoint is required")
+	}
+
+	path, err := ConfigFilePath()
+	if err != nil {
+		return err
+	}
+
+	file, err := readContextsFile(path)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := file.Contexts[context.Name]; ok && !overwrite {
+		err := fmt.Errorf("context '%s' already exists, use --overwrite to replace it", context.Name)
+		logger.Get().Error().Err(err).Msg("")
+		return err
+	}
+
+	file.Contexts[context.Name] = context
+	if file.CurrentContext == "" {
+		file.CurrentContext = context.Name
+	}
+
+	if err := writeContextsFile(path, file); err != nil {
+		return err
+	}
+
+	context.Current = file.CurrentContext == context.Name
+
+	return formatter.PrintResult(context, &contextPrintConfig)
+}
+
+func ContextRemove(name string) error {
+	logger.Get().Info().Msgf("Remove context '%s'", name)
+
+	path, err := ConfigFilePath()
+	if err != nil {
+		return err
+	}
+
+	file, err := readContextsFile(path)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := file.Contexts[name]; !ok {
+		err := fmt.Errorf("context '%s' not found in config file '%s'", name, path)
+		logger.Get().Error().Err(err).Msg("")
+		return err
+	}
+
+	delete(file.Contexts, name)
+	if file.CurrentContext == name {
+		file.CurrentContext = ""
+	}
+
+	if err := writeContextsFile(path, file); err != nil {
+		return err
+	}
+
+	if formatter.IsTextFormat() {
+		fmt.Printf("Context '%s' removed\n", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package config_context
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/spf13/viper"
+)
+
+func TestMain(m *testing.M) {
+	viper.Set(formatter.ConfigFormat, "json")
+	m.Run()
+}
+
+// useConfigFile points viper at a config file in a temporary directory with
+// the given content and returns its path.
+func useConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "metalcloud.yaml")
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+	viper.SetConfigFile(path)
+	t.Cleanup(func() {
+		viper.Reset()
+		viper.Set(formatter.ConfigFormat, "json")
+	})
+	return path
+}
+
+func TestContextAdd_PreservesOtherKeys(t *testing.T) {
+	path := useConfigFile(t, "# my settings\nverbosity: DEBUG\n")
+
+	err := ContextAdd(Context{Name: "lab", Endpoint: "https://lab.example", ApiKey: "secret", Insecure: true}, false)
+	if err != nil {
+		t.Fatalf("ContextAdd: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	content := string(data)
+	for _, want := range []string{"# my settings", "verbosity: DEBUG", "current_context: lab", "endpoint: https://lab.example", "api_key: secret"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in config file, got:\n%s", want, content)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected config file mode 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestContextAdd_ExistingRequiresOverwrite(t *testing.T) {
+	useConfigFile(t, "contexts:\n  lab:\n    endpoint: https://old.example\n")
+
+	if err := ContextAdd(Context{Name: "lab", Endpoint: "https://new.example"}, false); err == nil {
+		t.Fatal("expected error when adding an existing context without overwrite")
+	}
+	if err := ContextAdd(Context{Name: "lab", Endpoint: "https://new.example"}, true); err != nil {
+		t.Fatalf("ContextAdd with overwrite: %v", err)
+	}
+
+	context, err := Resolve("lab")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if context.Endpoint != "https://new.example" {
+		t.Errorf("expected endpoint to be replaced, got %q", context.Endpoint)
+	}
+}
+
+func TestContextAdd_RequiresEndpoint(t *testing.T) {
+	useConfigFile(t, "")
+
+	if err := ContextAdd(Context{Name: "lab"}, false); err == nil {
+		t.Fatal("expected error when endpoint is missing")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	useConfigFile(t, `current_context: lab
+contexts:
+  lab:
+    endpoint: https://lab.example
+    api_key: lab-key
+  Production:
+    endpoint: https://prod.example
+    format: json
+    site: "2"
+`)
+
+	context, err := Resolve("")
+	if err != nil {
+		t.Fatalf("Resolve current: %v", err)
+	}
+	if context == nil || context.Name != "lab" || context.ApiKey != "lab-key" {
+		t.Errorf("expected current context 'lab', got %+v", context)
+	}
+
+	// Names are case sensitive and are not lowercased like viper keys.
+	context, err = Resolve("Production")
+	if err != nil {
+		t.Fatalf("Resolve override: %v", err)
+	}
+	if context.Format != "json" || context.Site != "2" {
+		t.Errorf("unexpected context: %+v", context)
+	}
+	if !context.IsSet("format") || context.IsSet("api_key") {
+		t.Errorf("unexpected keys set in context: %+v", context)
+	}
+
+	if _, err := Resolve("missing"); err == nil {
+		t.Error("expected error for unknown context")
+	}
+}
+
+func TestResolve_ExplicitFalse(t *testing.T) {
+	useConfigFile(t, `insecure_skip_verify: true
+current_context: lab
+contexts:
+  lab:
+    endpoint: https://lab.example
+    insecure_skip_verify: false
+`)
+
+	context, err := Resolve("")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if context.Insecure || !context.IsSet("insecure_skip_verify") {
+		t.Errorf("expected insecure_skip_verify explicitly false, got %+v", context)
+	}
+}
+
+func TestResolve_NoContexts(t *testing.T) {
+	useConfigFile(t, "endpoint: https://single.example\n")
+
+	context, err := Resolve("")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if context != nil {
+		t.Errorf("expected no context, got %+v", context)
+	}
+}
+
+func TestContextUseAndRemove(t *testing.T) {
+	useConfigFile(t, `current_context: lab
+contexts:
+  lab:
+    endpoint: https://lab.example
+  prod:
+    endpoint: https://prod.example
+`)
+
+	if err := ContextUse("missing"); err == nil {
+		t.Error("expected error when switching to unknown context")
+	}
+
+	if err := ContextUse("prod"); err != nil {
+		t.Fatalf("ContextUse: %v", err)
+	}
+	context, _ := Resolve("")
+	if context == nil || context.Name != "prod" {
+		t.Fatalf("expected current context 'prod', got %+v", context)
+	}
+
+	if err := ContextRemove("prod"); err != nil {
+		t.Fatalf("ContextRemove: %v", err)
+	}
+	context, _ = Resolve("")
+	if context != nil {
+		t.Errorf("expected no current context after removing it, got %+v", context)
+	}
+
+	if err := ContextRemove("prod"); err == nil {
+		t.Error("expected error when removing unknown context")
+	}
+}
+
+func TestContextList(t *testing.T) {
+	useConfigFile(t, "contexts:\n  b:\n    endpoint: https://b.example\n  a:\n    endpoint: https://a.example\n")
+
+	if err := ContextList(); err != nil {
+		t.Fatalf("ContextList: %v", err)
+	}
+}
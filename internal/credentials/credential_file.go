@@ -0,0 +1,268 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	credentialFileVersion = 1
+	kdfScrypt             = "scrypt"
+
+	// scrypt parameters recommended for interactive logins.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	keyLength      = 32
+	saltLength     = 16
+	minKeyfileSize = 16
+)
+
+// encryptedFile is the on-disk format of the credential file. The API keys
+// are encrypted with AES-256-GCM using a key derived with scrypt from the
+// passphrase or the keyfile contents.
+type encryptedFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// fileStore keeps the API keys, by endpoint, in an encrypted local file.
+type fileStore struct {
+	path    string
+	keyfile string
+}
+
+func newFileStore(path string, keyfile string) *fileStore {
+	return &fileStore{path: path, keyfile: keyfile}
+}
+
+func (s *fileStore) String() string {
+	return fmt.Sprintf("credential file '%s'", s.path)
+}
+
+func (s *fileStore) Get(endpoint string) (string, error) {
+	keys, _, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	return keys[endpoint], nil
+}
+
+func (s *fileStore) Store(endpoint string, apiKey string) error {
+	keys, secret, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if secret == nil {
+		// A new file, ask for the passphrase twice to catch typos.
+		if secret, err = s.secret(true); err != nil {
+			return err
+		}
+	}
+
+	keys[endpoint] = apiKey
+
+	return s.save(keys, secret)
+}
+
+func (s *fileStore) Erase(endpoint string) error {
+	keys, secret, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := keys[endpoint]; !ok {
+		return fmt.Errorf("no API key is stored for %s", endpoint)
+	}
+	delete(keys, endpoint)
+
+	if len(keys) == 0 {
+		if err := os.Remove(s.path); err != nil {
+			return fmt.Errorf("failed to remove credential file: %v", err)
+		}
+		return nil
+	}
+
+	return s.save(keys, secret)
+}
+
+// load decrypts the credential file. It returns no keys and a nil secret,
+// without asking for one, when the file does not exist.
+func (s *fileStore) load() (map[string]string, []byte, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to read credential file: %v", err)
+	}
+
+	file := encryptedFile{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse credential file: %v", err)
+	}
+	if file.Version != credentialFileVersion || file.KDF != kdfScrypt {
+		return nil, nil, fmt.Errorf("unsupported credential file version %d (%s)", file.Version, file.KDF)
+	}
+
+	secret, err := s.secret(false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := newAEAD(secret, file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plaintext, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt credential file, wrong passphrase or keyfile")
+	}
+
+	keys := map[string]string{}
+	if err := json.Unmarshal(plaintext, &keys); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse decrypted credentials: %v", err)
+	}
+
+	return keys, secret, nil
+}
+
+// save encrypts the keys with a new salt and nonce and replaces the file.
+func (s *fileStore) save(keys map[string]string, secret []byte) error {
+	plaintext, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to serialize credentials: %v", err)
+	}
+
+	file := encryptedFile{
+		Version: credentialFileVersion,
+		KDF:     kdfScrypt,
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    make([]byte, saltLength),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %v", err)
+	}
+
+	aead, err := newAEAD(secret, file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return err
+	}
+
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	file.Data = aead.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize credential file: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create credential directory: %v", err)
+	}
+
+	// Write to a temporary file first so an interrupted write does not lose
+	// the stored keys.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write credential file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write credential file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write credential file: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return fmt.Errorf("failed to write credential file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write credential file: %v", err)
+	}
+
+	return nil
+}
+
+// secret returns the keyfile contents when a keyfile is configured, and the
+// passphrase from the environment or the terminal otherwise.
+func (s *fileStore) secret(confirm bool) ([]byte, error) {
+	if s.keyfile != "" {
+		data, err := os.ReadFile(s.keyfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credential keyfile: %v", err)
+		}
+		if len(data) < minKeyfileSize {
+			return nil, fmt.Errorf("credential keyfile '%s' must contain at least %d bytes", s.keyfile, minKeyfileSize)
+		}
+		return data, nil
+	}
+
+	if passphrase := viper.GetString(ConfigCredentialPassphrase); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	if !isInteractive() {
+		return nil, fmt.Errorf("a passphrase is required to unlock the %s, set the METALCLOUD_CREDENTIAL_PASSPHRASE environment variable or the %s setting",
+			s, ConfigCredentialKeyfile)
+	}
+
+	passphrase, err := promptSecret(fmt.Sprintf("Passphrase for %s: ", s.path))
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase is required")
+	}
+
+	if confirm {
+		again, err := promptSecret("Repeat the passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if again != passphrase {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return []byte(passphrase), nil
+}
+
+func newAEAD(secret []byte, salt []byte, n int, r int, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(secret, salt, n, r, p, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %v", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	return cipher.NewGCM(block)
+}
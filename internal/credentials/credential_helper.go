@@ -0,0 +1,124 @@
+package credentials
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// helperUsername is sent as the username of every credential so helpers
+// that index credentials by user find the API key again.
+const helperUsername = "metalcloud-cli"
+
+// helperStore delegates the API keys to an external command speaking the
+// git credential helper protocol: the command is run with the "get", "store"
+// or "erase" action and receives the credential as key=value lines on stdin,
+// terminated by an empty line. For "get" it answers with key=value lines on
+// stdout, the API key being the "password" attribute.
+type helperStore struct {
+	command []string
+}
+
+func newHelperStore(helper string) (*helperStore, error) {
+	command := strings.Fields(helper)
+	if len(command) == 0 {
+		return nil, fmt.Errorf("credential helper command is empty")
+	}
+
+	return &helperStore{command: command}, nil
+}
+
+func (s *helperStore) String() string {
+	return fmt.Sprintf("credential helper '%s'", strings.Join(s.command, " "))
+}
+
+func (s *helperStore) Get(endpoint string) (string, error) {
+	attributes, err := helperAttributes(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := s.run("get", attributes)
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, ok := strings.Cut(strings.TrimRight(line, "\r"), "=")
+		if ok && key == "password" {
+			return value, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (s *helperStore) Store(endpoint string, apiKey string) error {
+	if strings.ContainsAny(apiKey, "\n\x00") {
+		return fmt.Errorf("API key contains invalid characters")
+	}
+
+	attributes, err := helperAttributes(endpoint)
+	if err != nil {
+		return err
+	}
+	attributes = append(attributes, "password="+apiKey)
+
+	_, err = s.run("store", attributes)
+	return err
+}
+
+func (s *helperStore) Erase(endpoint string) error {
+	attributes, err := helperAttributes(endpoint)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.run("erase", attributes)
+	return err
+}
+
+func (s *helperStore) run(action string, attributes []string) ([]byte, error) {
+	input := bytes.Buffer{}
+	for _, attribute := range attributes {
+		input.WriteString(attribute + "\n")
+	}
+	input.WriteString("\n")
+
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	cmd := exec.Command(s.command[0], append(s.command[1:], action)...)
+	cmd.Stdin = &input
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := firstLine(stderr.String()); message != "" {
+			return nil, fmt.Errorf("'%s' failed: %v: %s", action, err, message)
+		}
+		return nil, fmt.Errorf("'%s' failed: %v", action, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// helperAttributes describes the endpoint the way git describes a remote.
+func helperAttributes(endpoint string) ([]string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid API endpoint URL: %s", endpoint)
+	}
+
+	attributes := []string{"protocol=" + u.Scheme, "host=" + u.Host}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		attributes = append(attributes, "path="+path)
+	}
+
+	return append(attributes, "username="+helperUsername), nil
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(line)
+}
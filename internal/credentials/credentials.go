@@ -0,0 +1,162 @@
+package credentials
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ConfigCredentialHelper is the external command API keys are stored with
+	// instead of the encrypted credential file.
+	ConfigCredentialHelper = "credential_helper"
+	// ConfigCredentialFile is the path of the encrypted credential file.
+	ConfigCredentialFile = "credential_file"
+	// ConfigCredentialKeyfile is a file whose contents encrypt the credential
+	// file instead of a passphrase.
+	ConfigCredentialKeyfile = "credential_keyfile"
+	// ConfigCredentialPassphrase is the passphrase of the credential file. It
+	// is meant to be set as the METALCLOUD_CREDENTIAL_PASSPHRASE environment
+	// variable in non-interactive sessions, the passphrase is prompted for otherwise.
+	ConfigCredentialPassphrase = "credential_passphrase"
+
+	defaultCredentialDir  = ".metalcloud"
+	defaultCredentialFile = "credentials"
+)
+
+// Store keeps the API keys of MetalSoft endpoints.
+type Store interface {
+	// Get returns the API key stored for the endpoint, or an empty string
+	// when there is none.
+	Get(endpoint string) (string, error)
+	// Store saves the API key of the endpoint, replacing any previous one.
+	Store(endpoint string, apiKey string) error
+	// Erase removes the API key of the endpoint.
+	Erase(endpoint string) error
+	// String describes where the keys are stored.
+	String() string
+}
+
+// NewStore returns the credential helper store when a helper is configured,
+// and the encrypted credential file store otherwise.
+func NewStore() (Store, error) {
+	if helper := viper.GetString(ConfigCredentialHelper); helper != "" {
+		return newHelperStore(helper)
+	}
+
+	path := viper.GetString(ConfigCredentialFile)
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine home directory: %v", err)
+		}
+		path = filepath.Join(home, defaultCredentialDir, defaultCredentialFile)
+	}
+
+	return newFileStore(path, viper.GetString(ConfigCredentialKeyfile)), nil
+}
+
+// normalizeEndpoint returns the form of the endpoint the keys are stored under,
+// so that "https://metal.example" and "https://metal.example/" share a key.
+func normalizeEndpoint(endpoint string) string {
+	return strings.TrimRight(strings.TrimSpace(endpoint), "/")
+}
+
+// Resolve returns the API key stored for the endpoint, or an empty string when
+// none is stored.
+func Resolve(endpoint string) (string, error) {
+	store, err := NewStore()
+	if err != nil {
+		return "", err
+	}
+
+	apiKey, err := store.Get(normalizeEndpoint(endpoint))
+	if err != nil {
+		return "", fmt.Errorf("failed to get the API key from %s: %w", store, err)
+	}
+	if apiKey != "" {
+		logger.Get().Debug().Msgf("Using the API key for %s from %s", endpoint, store)
+	}
+
+	return apiKey, nil
+}
+
+// Login stores the API key of the endpoint. When apiKey is empty it is read
+// from stdin when fromStdin is set, or prompted for otherwise.
+func Login(endpoint string, apiKey string, fromStdin bool) error {
+	logger.Get().Info().Msgf("Login to %s", endpoint)
+
+	if endpoint == "" {
+		err := fmt.Errorf("API endpoint is required")
+		logger.Get().Error().Err(err).Msg("")
+		return err
+	}
+
+	if apiKey == "" {
+		var err error
+		if fromStdin {
+			apiKey, err = readLine(os.Stdin)
+		} else {
+			apiKey, err = promptSecret(fmt.Sprintf("API key for %s: ", endpoint))
+		}
+		if err != nil {
+			logger.Get().Error().Err(err).Msg("")
+			return err
+		}
+	}
+	if apiKey == "" {
+		err := fmt.Errorf("API key is required")
+		logger.Get().Error().Err(err).Msg("")
+		return err
+	}
+
+	store, err := NewStore()
+	if err != nil {
+		return err
+	}
+
+	if err := store.Store(normalizeEndpoint(endpoint), apiKey); err != nil {
+		err = fmt.Errorf("failed to store the API key in %s: %w", store, err)
+		logger.Get().Error().Err(err).Msg("")
+		return err
+	}
+
+	if formatter.IsTextFormat() {
+		fmt.Printf("API key for %s stored in %s\n", endpoint, store)
+	}
+
+	return nil
+}
+
+// Logout removes the API key stored for the endpoint.
+func Logout(endpoint string) error {
+	logger.Get().Info().Msgf("Logout from %s", endpoint)
+
+	if endpoint == "" {
+		err := fmt.Errorf("API endpoint is required")
+		logger.Get().Error().Err(err).Msg("")
+		return err
+	}
+
+	store, err := NewStore()
+	if err != nil {
+		return err
+	}
+
+	if err := store.Erase(normalizeEndpoint(endpoint)); err != nil {
+		err = fmt.Errorf("failed to remove the API key from %s: %w", store, err)
+		logger.Get().Error().Err(err).Msg("")
+		return err
+	}
+
+	if formatter.IsTextFormat() {
+		fmt.Printf("API key for %s removed from %s\n", endpoint, store)
+	}
+
+	return nil
+}
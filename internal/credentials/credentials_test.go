@@ -0,0 +1,169 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/spf13/viper"
+)
+
+func TestMain(m *testing.M) {
+	viper.Set(formatter.ConfigFormat, "json")
+	m.Run()
+}
+
+// useCredentialFile points the credential store at a file in a temporary
+// directory, protected with passphrase, and returns its path.
+func useCredentialFile(t *testing.T, passphrase string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "credentials")
+	viper.Set(ConfigCredentialFile, path)
+	viper.Set(ConfigCredentialPassphrase, passphrase)
+	t.Cleanup(func() {
+		viper.Reset()
+		viper.Set(formatter.ConfigFormat, "json")
+	})
+
+	return path
+}
+
+func TestLogin_EncryptedFile(t *testing.T) {
+	path := useCredentialFile(t, "correct horse")
+
+	if err := Login("https://metal.example/", "secret-key", false); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read credential file: %v", err)
+	}
+	if strings.Contains(string(data), "secret-key") {
+		t.Errorf("expected the API key to be encrypted, got:\n%s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("expected credential file mode 0600, got %o", info.Mode().Perm())
+	}
+
+	apiKey, err := Resolve("https://metal.example")
+	if err != nil || apiKey != "secret-key" {
+		t.Errorf("expected the stored key, got %q (%v)", apiKey, err)
+	}
+	if apiKey, _ := Resolve("https://other.example"); apiKey != "" {
+		t.Errorf("expected no key for another endpoint, got %q", apiKey)
+	}
+
+	viper.Set(ConfigCredentialPassphrase, "wrong")
+	if _, err := Resolve("https://metal.example"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("expected decryption error with a wrong passphrase, got %v", err)
+	}
+}
+
+func TestLogout_RemovesFileWithLastKey(t *testing.T) {
+	path := useCredentialFile(t, "correct horse")
+
+	for _, endpoint := range []string{"https://a.example", "https://b.example"} {
+		if err := Login(endpoint, "key-"+endpoint, false); err != nil {
+			t.Fatalf("Login %s: %v", endpoint, err)
+		}
+	}
+
+	if err := Logout("https://a.example"); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if apiKey, _ := Resolve("https://b.example"); apiKey != "key-https://b.example" {
+		t.Errorf("expected the other key to be kept, got %q", apiKey)
+	}
+	if err := Logout("https://a.example"); err == nil {
+		t.Error("expected error when no key is stored for the endpoint")
+	}
+
+	if err := Logout("https://b.example"); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the credential file to be removed, got %v", err)
+	}
+}
+
+func TestResolve_NoCredentialFile(t *testing.T) {
+	useCredentialFile(t, "")
+
+	// No passphrase is needed when nothing was stored yet
+	apiKey, err := Resolve("https://metal.example")
+	if err != nil || apiKey != "" {
+		t.Errorf("expected no key and no error, got %q (%v)", apiKey, err)
+	}
+}
+
+func TestFileStore_Keyfile(t *testing.T) {
+	dir := t.TempDir()
+	keyfile := filepath.Join(dir, "keyfile")
+	if err := os.WriteFile(keyfile, []byte("0123456789abcdef0123456789abcdef"), 0o600); err != nil {
+		t.Fatalf("write keyfile: %v", err)
+	}
+
+	store := newFileStore(filepath.Join(dir, "credentials"), keyfile)
+	if err := store.Store("https://metal.example", "secret-key"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if apiKey, err := store.Get("https://metal.example"); err != nil || apiKey != "secret-key" {
+		t.Errorf("expected the stored key, got %q (%v)", apiKey, err)
+	}
+
+	if err := os.WriteFile(keyfile, []byte("short"), 0o600); err != nil {
+		t.Fatalf("write keyfile: %v", err)
+	}
+	if _, err := store.Get("https://metal.example"); err == nil {
+		t.Error("expected error for a keyfile that is too short")
+	}
+}
+
+func TestHelperStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test helper is a shell script")
+	}
+
+	dir := t.TempDir()
+	saved := filepath.Join(dir, "saved")
+	helper := filepath.Join(dir, "helper")
+	script := `#!/bin/sh
+case "$1" in
+  get) [ -f "` + saved + `" ] && grep '^password=' "` + saved + `"; exit 0 ;;
+  store) cat > "` + saved + `" ;;
+  erase) echo "cannot erase" >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(helper, []byte(script), 0o700); err != nil {
+		t.Fatalf("write helper: %v", err)
+	}
+	useCredentialFile(t, "")
+	viper.Set(ConfigCredentialHelper, helper)
+
+	if apiKey, err := Resolve("https://metal.example"); err != nil || apiKey != "" {
+		t.Errorf("expected no key before login, got %q (%v)", apiKey, err)
+	}
+
+	if err := Login("https://metal.example:8443/api", "secret-key", false); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	data, _ := os.ReadFile(saved)
+	expected := "protocol=https\nhost=metal.example:8443\npath=api\nusername=metalcloud-cli\npassword=secret-key\n\n"
+	if string(data) != expected {
+		t.Errorf("expected helper input:\n%s\ngot:\n%s", expected, data)
+	}
+
+	if apiKey, err := Resolve("https://metal.example:8443/api"); err != nil || apiKey != "secret-key" {
+		t.Errorf("expected the stored key, got %q (%v)", apiKey, err)
+	}
+
+	err := Logout("https://metal.example:8443/api")
+	if err == nil || !strings.Contains(err.Error(), "cannot erase") {
+		t.Errorf("expected the helper error message, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package credentials
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// isInteractive reports whether secrets can be prompted for.
+func isInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// promptSecret reads a secret from the terminal without echoing it. The
+// prompt is written to stderr so it does not mix with the command output.
+func promptSecret(prompt string) (string, error) {
+	if !isInteractive() {
+		return "", fmt.Errorf("cannot prompt for a secret, stdin is not a terminal")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from the terminal: %v", err)
+	}
+
+	return strings.TrimSpace(string(secret)), nil
+}
+
+// readLine returns the first line of r without surrounding whitespace.
+func readLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read from stdin: %v", err)
+	}
+
+	return strings.TrimSpace(line), nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/metalsoft-io/metalcloud-cli/internal/job"
 	"github.com/metalsoft-io/metalcloud-cli/internal/server"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
@@ -186,7 +187,7 @@ func CustomIsoBootServer(ctx context.Context, customIsoId string, serverId strin
 	}
 
 	logger.Get().Info().Msgf("Server '%s' boot with custom ISO '%s' initiated", serverId, customIsoId)
-	return formatter.PrintResult(jobInfo, &formatter.PrintConfig{
+	if err := formatter.PrintResult(jobInfo, &formatter.PrintConfig{
 		FieldsConfig: map[string]formatter.RecordFieldConfig{
 			"JobId": {
 				Title: "Job ID",
@@ -197,7 +198,11 @@ func CustomIsoBootServer(ctx context.Context, customIsoId string, serverId strin
 				Order: 2,
 			},
 		},
-	})
+	}); err != nil {
+		return err
+	}
+
+	return job.WaitForResult(ctx, jobInfo)
 }
 
 func CustomIsoConfigExample(ctx context.Context) error {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/metalsoft-io/metalcloud-cli/internal/infrastructure"
+	"github.com/metalsoft-io/metalcloud-cli/internal/job"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
@@ -129,5 +130,5 @@ func DriveSnapshotRestore(ctx context.Context, infrastructureIdOrLabel string, d
 	}
 
 	fmt.Printf("Drive %s restored to snapshot '%s'\n", driveId, snapshotName)
-	return nil
+	return job.WaitForResult(ctx, httpRes)
 }
@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
 	"github.com/metalsoft-io/metalcloud-cli/internal/fabric_template_config"
+	"github.com/metalsoft-io/metalcloud-cli/internal/job"
 	"github.com/metalsoft-io/metalcloud-cli/internal/network_device"
 	"github.com/metalsoft-io/metalcloud-cli/internal/site"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
@@ -301,7 +303,11 @@ func FabricDeploy(ctx context.Context, fabricId string) error {
 		return err
 	}
 
-	return formatter.PrintResult(jobInfo, nil)
+	if err := formatter.PrintResult(jobInfo, nil); err != nil {
+		return err
+	}
+
+	return job.WaitForResult(ctx, jobInfo)
 }
 
 // switchImportConfig is the YAML/JSON shape consumed by FabricImportDevices:
@@ -623,7 +629,11 @@ func FabricRescanLinks(ctx context.Context, fabricId string, updateLLDPInfo bool
 		return err
 	}
 
-	return formatter.PrintResult(jobInfo, nil)
+	if err := formatter.PrintResult(jobInfo, nil); err != nil {
+		return err
+	}
+
+	return job.WaitForResult(ctx, jobInfo)
 }
 
 func FabricDevicesGet(ctx context.Context, fabricId string) error {
@@ -724,10 +734,34 @@ func FabricDevicesRemove(ctx context.Context, fabricId string, deviceId string)
 	return nil
 }
 
+var driftPrintConfig = formatter.PrintConfig{
+	FieldsConfig: map[string]formatter.RecordFieldConfig{
+		"Device": {
+			Order: 1,
+		},
+		"Port": {
+			Order: 2,
+		},
+		"Field": {
+			Order: 3,
+		},
+		"Expected": {
+			Order: 4,
+		},
+		"Actual": {
+			Order: 5,
+		},
+	},
+}
+
 // FabricConfigureSwitches applies a declarative fabric-switch configuration
 // (hostnames, ASNs, loopbacks, port enable/descriptions, point-to-point links)
 // to every device in a fabric. It is idempotent, with a --dry-run preview.
-func FabricConfigureSwitches(ctx context.Context, fabricIdOrLabel string, config []byte, dryRun bool) error {
+// With check set nothing is written either; the differences between the fabric
+// and the plan are printed as a drift report instead, and an error is returned
+// when there are any. With prune set, point-to-point links and subnets this
+// command created but which are no longer in the plan are removed.
+func FabricConfigureSwitches(ctx context.Context, fabricIdOrLabel string, config []byte, dryRun bool, check bool, prune bool, parallelism int) error {
 	fabricInfo, err := GetFabricByIdOrLabel(ctx, fabricIdOrLabel)
 	if err != nil {
 		return err
@@ -745,15 +779,242 @@ func FabricConfigureSwitches(ctx context.Context, fabricIdOrLabel string, config
 	client := api.GetApiClient(ctx)
 	switchClient := fabric_switch_config.NewSDKClient(ctx, client)
 
-	if dryRun {
+	if dryRun || check {
 		logger.Get().Info().Msgf("Dry run: computing the plan for fabric %d without writing", fabricId)
 	}
 
-	result, err := fabric_switch_config.Configure(switchClient, cfg, fabricId, dryRun)
+	result, err := fabric_switch_config.ConfigureWithOptions(switchClient, cfg, fabricId, fabric_switch_config.RunOptions{
+		DryRun:      dryRun || check,
+		Prune:       prune,
+		Parallelism: parallelism,
+		Progress:    printRunProgress,
+	})
 	if err != nil {
 		return err
 	}
 
+	logRunSummary(result, dryRun || check)
+
+	if check {
+		if err := formatter.PrintResult(result.Drift, &driftPrintConfig); err != nil {
+			return err
+		}
+		if formatter.IsTextFormat() {
+			fmt.Printf("Fabric %d: %d difference(s) from the plan\n", fabricId, len(result.Drift))
+		}
+	}
+
+	if result.Failures > 0 {
+		return fmt.Errorf("fabric switch configuration completed with %d failure(s)", result.Failures)
+	}
+	if check && len(result.Drift) > 0 {
+		return fmt.Errorf("fabric %d has drifted from the plan: %d difference(s)", fabricId, len(result.Drift))
+	}
+	return nil
+}
+
+var plannedDevicePrintConfig = formatter.PrintConfig{
+	FieldsConfig: map[string]formatter.RecordFieldConfig{
+		"Id": {
+			Title: "ID",
+			Order: 1,
+		},
+		"Position": {
+			Order: 2,
+		},
+		"ManagementAddress": {
+			Title: "Management Address",
+			Order: 3,
+		},
+		"Hostname": {
+			Order: 4,
+		},
+		"Asn": {
+			Title: "ASN",
+			Order: 5,
+		},
+		"Loopback": {
+			Order: 6,
+		},
+		"LoopbackIpv6": {
+			Title: "Loopback IPv6",
+			Order: 7,
+		},
+	},
+}
+
+var plannedCablePrintConfig = formatter.PrintConfig{
+	FieldsConfig: map[string]formatter.RecordFieldConfig{
+		"Id": {
+			Title: "Cable",
+			Order: 1,
+		},
+		"Layer": {
+			Order: 2,
+		},
+		"DeviceA": {
+			Title: "Device A",
+			Order: 3,
+		},
+		"PortA": {
+			Title: "Port A",
+			Order: 4,
+		},
+		"DeviceB": {
+			Title: "Device B",
+			Order: 5,
+		},
+		"PortB": {
+			Title: "Port B",
+			Order: 6,
+		},
+		"Subnet": {
+			Order: 7,
+		},
+		"SubnetIpv6": {
+			Title: "Subnet IPv6",
+			Order: 8,
+		},
+	},
+}
+
+var plannedPortDescriptionPrintConfig = formatter.PrintConfig{
+	FieldsConfig: map[string]formatter.RecordFieldConfig{
+		"Device": {
+			Order: 1,
+		},
+		"Port": {
+			Order: 2,
+		},
+		"Description": {
+			Order: 3,
+		},
+	},
+}
+
+// FabricConfigureSwitchesPlan computes the plan of a switch configuration for
+// the devices of a devices file instead of those of a fabric, with no API
+// access at all: the hostnames, ASNs and loopbacks of the devices, the cables
+// with their point-to-point subnets, the port descriptions and, when the
+// configuration has freeform or bgp sections, the profile variables those
+// would register.
+func FabricConfigureSwitchesPlan(ctx context.Context, config []byte, devicesFile []byte) error {
+	cfg, err := fabric_switch_config.LoadConfig(config)
+	if err != nil {
+		return err
+	}
+	devices, err := fabric_switch_config.LoadDevicesFile(devicesFile)
+	if err != nil {
+		return err
+	}
+
+	plan, err := fabric_switch_config.ComputePlan(cfg, devices)
+	if err != nil {
+		return err
+	}
+	variables, err := fabric_template_config.ComputePlanVariables(config, plan)
+	if err != nil {
+		return err
+	}
+
+	if formatter.IsNativeFormat() {
+		return formatter.PrintResult(struct {
+			*fabric_switch_config.Plan
+			ProfileVariables []fabric_template_config.ProfileVariables `json:"profileVariables,omitempty"`
+		}{plan, variables}, nil)
+	}
+
+	for _, w := range plan.Warnings {
+		logger.Get().Warn().Msg(w)
+	}
+
+	fmt.Printf("Devices (%d):\n", len(plan.Devices))
+	if err := formatter.PrintResult(plan.Devices, &plannedDevicePrintConfig); err != nil {
+		return err
+	}
+	if len(plan.Cables) > 0 {
+		fmt.Printf("\nCables (%d):\n", len(plan.Cables))
+		if err := formatter.PrintResult(plan.Cables, &plannedCablePrintConfig); err != nil {
+			return err
+		}
+	}
+	if len(plan.PortDescriptions) > 0 {
+		fmt.Printf("\nPort descriptions (%d):\n", len(plan.PortDescriptions))
+		if err := formatter.PrintResult(plan.PortDescriptions, &plannedPortDescriptionPrintConfig); err != nil {
+			return err
+		}
+	}
+	if len(variables) > 0 {
+		fmt.Printf("\nProfile variables (%d profiles):\n", len(variables))
+		if err := formatter.PrintYamlResult(variables); err != nil {
+			return err
+		}
+	}
+	for _, w := range plan.Warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+	return nil
+}
+
+// FabricUnconfigureSwitches is the inverse of FabricConfigureSwitches. It
+// deletes the point-to-point links and subnets configure-switches created for
+// the fabric and, when the configuration it was run with is given, resets the
+// hostnames, ASNs, loopbacks and port descriptions it set. positions and pods
+// narrow the teardown to part of the fabric.
+func FabricUnconfigureSwitches(ctx context.Context, fabricIdOrLabel string, config []byte, dryRun bool, positions []string, pods []string) error {
+	fabricInfo, err := GetFabricByIdOrLabel(ctx, fabricIdOrLabel)
+	if err != nil {
+		return err
+	}
+	fabricId, err := strconv.ParseInt(fabricInfo.Id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid fabric ID %q: %w", fabricInfo.Id, err)
+	}
+
+	var cfg *fabric_switch_config.Config
+	if config != nil {
+		cfg, err = fabric_switch_config.LoadConfig(config)
+		if err != nil {
+			return err
+		}
+	}
+
+	client := api.GetApiClient(ctx)
+	switchClient := fabric_switch_config.NewSDKClient(ctx, client)
+
+	if dryRun {
+		logger.Get().Info().Msgf("Dry run: computing the teardown of fabric %d without writing", fabricId)
+	}
+
+	result, err := fabric_switch_config.Unconfigure(switchClient, cfg, fabricId, fabric_switch_config.TeardownOptions{
+		DryRun:    dryRun,
+		Positions: positions,
+		Pods:      pods,
+	})
+	if err != nil {
+		return err
+	}
+
+	logRunSummary(result, dryRun)
+
+	if result.Failures > 0 {
+		return fmt.Errorf("fabric switch teardown completed with %d failure(s)", result.Failures)
+	}
+	return nil
+}
+
+// printRunProgress reports the progress of a configure run on stderr, in text
+// mode only: every tenth of a step and when the step completes.
+func printRunProgress(step string, done, total int) {
+	if !formatter.IsTextFormat() || (done != total && done%max(total/10, 1) != 0) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Configuring %s: %d/%d\n", step, done, total)
+}
+
+// logRunSummary logs the warnings and the sorted counters of a switch
+// configuration or teardown run.
+func logRunSummary(result *fabric_switch_config.RunResult, dryRun bool) {
 	for _, w := range result.Warnings {
 		logger.Get().Warn().Msg(w)
 	}
@@ -778,37 +1039,280 @@ func FabricConfigureSwitches(ctx context.Context, fabricIdOrLabel string, config
 		suffix = " (dry-run, no changes made)"
 	}
 	logger.Get().Info().Msgf("Summary: %s, failures=%d%s", summary, result.Failures, suffix)
+}
 
-	if result.Failures > 0 {
-		return fmt.Errorf("fabric switch configuration completed with %d failure(s)", result.Failures)
+// FabricCablingPlan computes the cabling plan of a fabric from a switch
+// configuration and writes it as a CSV cut-sheet, a Graphviz DOT diagram or a
+// JSON document. The fabric devices are only read. The plan is written to
+// outputPath, or to stdout when it is empty.
+func FabricCablingPlan(ctx context.Context, fabricIdOrLabel string, config []byte, format string, outputPath string) error {
+	fabricId, err := resolveFabricNumericId(ctx, fabricIdOrLabel)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := fabric_switch_config.LoadConfig(config)
+	if err != nil {
+		return err
+	}
+	if cfg.Topology == nil {
+		return fmt.Errorf("the configuration has no topology section, there is no cabling to plan")
+	}
+
+	switchClient := fabric_switch_config.NewSDKClient(ctx, api.GetApiClient(ctx))
+	plan, err := fabric_switch_config.ComputeCablingPlan(switchClient, cfg, fabricId)
+	if err != nil {
+		return err
+	}
+
+	content, err := fabric_switch_config.RenderCablingPlan(plan, format)
+	if err != nil {
+		return err
+	}
+
+	if outputPath == "" {
+		fmt.Print(string(content))
+		return nil
+	}
+
+	if err := os.WriteFile(outputPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write cabling plan to '%s': %w", outputPath, err)
+	}
+
+	if formatter.IsTextFormat() {
+		fmt.Printf("Cabling plan of fabric %d written to %s (%d cables)\n", fabricId, outputPath, len(plan.Cables))
+	}
+
+	return nil
+}
+
+var cablingCheckPrintConfig = formatter.PrintConfig{
+	FieldsConfig: map[string]formatter.RecordFieldConfig{
+		"CableId": {
+			Title: "Cable",
+			Order: 1,
+		},
+		"Status": {
+			Order: 2,
+		},
+		"Layer": {
+			Order: 3,
+		},
+		"DeviceA": {
+			Title: "Device A",
+			Order: 4,
+		},
+		"PortA": {
+			Title: "Port A",
+			Order: 5,
+		},
+		"DeviceB": {
+			Title: "Device B",
+			Order: 6,
+		},
+		"PortB": {
+			Title: "Port B",
+			Order: 7,
+		},
+		"ActualA": {
+			Title: "A Connected To",
+			Order: 8,
+		},
+		"ActualB": {
+			Title: "B Connected To",
+			Order: 9,
+		},
+		"Suggestion": {
+			Order: 10,
+		},
+	},
+}
+
+// FabricVerifyCabling compares the fabric links discovered by a link rescan
+// with the cables planned by a switch configuration and reports the miswired,
+// missing and unexpected ones. Matching cables are only listed with showAll.
+// It returns an error when there is at least one mismatch.
+func FabricVerifyCabling(ctx context.Context, fabricIdOrLabel string, config []byte, showAll bool) error {
+	fabricId, err := resolveFabricNumericId(ctx, fabricIdOrLabel)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := fabric_switch_config.LoadConfig(config)
+	if err != nil {
+		return err
+	}
+	if cfg.Topology == nil {
+		return fmt.Errorf("the configuration has no topology section, there is no cabling to verify")
+	}
+
+	switchClient := fabric_switch_config.NewSDKClient(ctx, api.GetApiClient(ctx))
+	report, err := fabric_switch_config.VerifyCabling(switchClient, cfg, fabricId)
+	if err != nil {
+		return err
+	}
+
+	checks := report.Checks
+	if !showAll {
+		checks = make([]fabric_switch_config.CablingCheck, 0, report.Mismatches())
+		for _, c := range report.Checks {
+			if c.Status != fabric_switch_config.CablingOk {
+				checks = append(checks, c)
+			}
+		}
+	}
+	if err := formatter.PrintResult(checks, &cablingCheckPrintConfig); err != nil {
+		return err
+	}
+
+	if formatter.IsTextFormat() {
+		fmt.Printf("Cabling of fabric %d: %d ok, %d miswired, %d missing, %d unexpected",
+			fabricId,
+			report.Counts[fabric_switch_config.CablingOk],
+			report.Counts[fabric_switch_config.CablingMiswired],
+			report.Counts[fabric_switch_config.CablingMissing],
+			report.Counts[fabric_switch_config.CablingUnexpected])
+		if report.Unverified > 0 {
+			fmt.Printf(" (%d host downlink(s) not verified)", report.Unverified)
+		}
+		fmt.Println()
+	}
+
+	if mismatches := report.Mismatches(); mismatches > 0 {
+		return fmt.Errorf("cabling verification found %d mismatch(es)", mismatches)
 	}
 	return nil
 }
 
 // FabricConfigureFreeform registers the base freeform device-configuration
 // template + one profile per switch.
-func FabricConfigureFreeform(ctx context.Context, fabricIdOrLabel string, config []byte, dryRun bool, verify bool) error {
+func FabricConfigureFreeform(ctx context.Context, fabricIdOrLabel string, config []byte, dryRun bool, verify bool, parallelism int) error {
 	fabricId, err := resolveFabricNumericId(ctx, fabricIdOrLabel)
 	if err != nil {
 		return err
 	}
 	client := fabric_template_config.NewSDKClient(ctx, api.GetApiClient(ctx))
-	_, err = fabric_template_config.RunFreeform(client, config, fabricId, dryRun, verify)
+	_, err = fabric_template_config.RunFreeformWithOptions(client, config, fabricId, fabric_template_config.RunOptions{
+		DryRun:      dryRun,
+		Verify:      verify,
+		Parallelism: parallelism,
+		Progress:    printRunProgress,
+	})
 	return err
 }
 
 // FabricConfigureBgp registers the BGP underlay (+ l3evpn overlay/PFC/VRF)
 // templates and per-switch profiles, and reconciles device customVariables.
-func FabricConfigureBgp(ctx context.Context, fabricIdOrLabel string, config []byte, dryRun bool, verify bool) error {
+func FabricConfigureBgp(ctx context.Context, fabricIdOrLabel string, config []byte, dryRun bool, verify bool, parallelism int) error {
 	fabricId, err := resolveFabricNumericId(ctx, fabricIdOrLabel)
 	if err != nil {
 		return err
 	}
 	client := fabric_template_config.NewSDKClient(ctx, api.GetApiClient(ctx))
-	_, err = fabric_template_config.RunBgp(client, config, fabricId, dryRun, verify)
+	_, err = fabric_template_config.RunBgpWithOptions(client, config, fabricId, fabric_template_config.RunOptions{
+		DryRun:      dryRun,
+		Verify:      verify,
+		Parallelism: parallelism,
+		Progress:    printRunProgress,
+	})
 	return err
 }
 
+var renderedFilePrintConfig = formatter.PrintConfig{
+	FieldsConfig: map[string]formatter.RecordFieldConfig{
+		"Device": {
+			Order: 1,
+		},
+		"Profile": {
+			Order: 2,
+		},
+		"Status": {
+			Order: 3,
+		},
+		"Path": {
+			Order: 4,
+		},
+		"Undefined": {
+			Title: "Undefined Variables",
+			Order: 5,
+		},
+	},
+}
+
+// FabricRenderFreeform renders the freeform template for every switch of the
+// fabric, or of the devices file when one is given, into outputDir without
+// writing to the controller.
+func FabricRenderFreeform(ctx context.Context, fabricIdOrLabel string, config []byte, devicesFile []byte, outputDir string) error {
+	return renderTemplatesLocal(ctx, fabricIdOrLabel, config, devicesFile, outputDir, fabric_template_config.RenderFreeformLocal)
+}
+
+// FabricRenderBgp renders the BGP underlay (+ l3evpn overlay/PFC) templates for
+// every switch of the fabric, or of the devices file when one is given, into
+// outputDir without writing to the controller.
+func FabricRenderBgp(ctx context.Context, fabricIdOrLabel string, config []byte, devicesFile []byte, outputDir string) error {
+	return renderTemplatesLocal(ctx, fabricIdOrLabel, config, devicesFile, outputDir, fabric_template_config.RenderBgpLocal)
+}
+
+type localRenderFunc func(client fabric_template_config.TemplateClient, data []byte, fabricId int64, options fabric_template_config.RenderOptions) (*fabric_template_config.RenderResult, error)
+
+// renderTemplatesLocal runs render against the fabric, or against the offline
+// plan of devicesFile, and reports the rendered files and their diffs against
+// the previous render.
+func renderTemplatesLocal(ctx context.Context, fabricIdOrLabel string, config []byte, devicesFile []byte, outputDir string, render localRenderFunc) error {
+	options := fabric_template_config.RenderOptions{OutputDir: outputDir}
+	var client fabric_template_config.TemplateClient
+	var fabricId int64
+	if devicesFile != nil {
+		cfg, err := fabric_switch_config.LoadConfig(config)
+		if err != nil {
+			return err
+		}
+		devices, err := fabric_switch_config.LoadDevicesFile(devicesFile)
+		if err != nil {
+			return err
+		}
+		options.Plan, err = fabric_switch_config.ComputePlan(cfg, devices)
+		if err != nil {
+			return err
+		}
+	} else {
+		var err error
+		fabricId, err = resolveFabricNumericId(ctx, fabricIdOrLabel)
+		if err != nil {
+			return err
+		}
+		client = fabric_template_config.NewSDKClient(ctx, api.GetApiClient(ctx))
+	}
+
+	result, err := render(client, config, fabricId, options)
+	if err != nil {
+		return err
+	}
+	if formatter.IsNativeFormat() {
+		return formatter.PrintResult(result, nil)
+	}
+
+	if err := formatter.PrintResult(result.Files, &renderedFilePrintConfig); err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	for _, file := range result.Files {
+		counts[file.Status]++
+		if file.Diff != "" {
+			fmt.Printf("\n%s", file.Diff)
+		}
+	}
+	fmt.Printf("\nRendered into %s: %d added, %d changed, %d unchanged, %d removed\n", outputDir,
+		counts[fabric_template_config.RenderAdded],
+		counts[fabric_template_config.RenderChanged],
+		counts[fabric_template_config.RenderUnchanged],
+		counts[fabric_template_config.RenderRemoved])
+	for _, w := range result.Warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+	return nil
+}
+
 // FabricConfigureFreeformExample prints a ready-to-edit freeform config example.
 func FabricConfigureFreeformExample(ctx context.Context) error {
 	fmt.Print(fabric_template_config.ExampleFreeformYAML())
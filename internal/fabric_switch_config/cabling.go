@@ -0,0 +1,242 @@
+package fabric_switch_config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Cabling plan output formats.
+const (
+	CablingFormatCsv  = "csv"
+	CablingFormatDot  = "dot"
+	CablingFormatJson = "json"
+)
+
+// CablingFormats lists the formats RenderCablingPlan accepts.
+func CablingFormats() []string {
+	return []string{CablingFormatCsv, CablingFormatDot, CablingFormatJson}
+}
+
+// cableIdPrefix is the per-layer prefix of the cable ids printed on the
+// cut-sheet; cables are numbered per layer in plan order.
+var cableIdPrefix = map[string]string{
+	"leafSpine":       "LS",
+	"spineSuperSpine": "SS",
+	"leafHost":        "LH",
+}
+
+// hostPosition is the position reported for the host end of leaf downlinks.
+const hostPosition = "host"
+
+// CablingPlan is the physical view of a DesiredState: every switch of the
+// fabric plus one entry per cable to pull.
+type CablingPlan struct {
+	FabricId   int64           `json:"fabricId"`
+	FabricName string          `json:"fabricName"`
+	Devices    []CablingDevice `json:"devices"`
+	Cables     []Cable         `json:"cables"`
+}
+
+// CablingDevice is a switch of the fabric, named as it will be configured.
+type CablingDevice struct {
+	Id                int64  `json:"id"`
+	Name              string `json:"name"`
+	Position          string `json:"position"`
+	ManagementAddress string `json:"managementAddress,omitempty"`
+}
+
+// Cable is one planned cable. Side A is the gateway side of fabric links and
+// the leaf of host downlinks, whose B side is a host NIC rather than a switch
+// (DeviceBId is nil).
+type Cable struct {
+	Id         string `json:"id"`
+	Layer      string `json:"layer"`
+	DeviceAId  int64  `json:"deviceAId"`
+	DeviceA    string `json:"deviceA"`
+	PortA      string `json:"portA"`
+	DeviceBId  *int64 `json:"deviceBId,omitempty"`
+	DeviceB    string `json:"deviceB"`
+	PortB      string `json:"portB"`
+	Subnet     string `json:"subnet,omitempty"`
+	SubnetIpv6 string `json:"subnetIpv6,omitempty"`
+}
+
+// ComputeCablingPlan reads the fabric devices through client and computes the
+// cabling plan for config. It never writes.
+func ComputeCablingPlan(client Client, config *Config, fabricId int64) (*CablingPlan, error) {
+	plan, err := loadPlan(client, config, fabricId)
+	if err != nil {
+		return nil, err
+	}
+	return buildCablingPlan(plan.fabric, plan.groups, plan.state), nil
+}
+
+func buildCablingPlan(fabric *FabricInfo, groups map[string][]*Device, state *DesiredState) *CablingPlan {
+	plan := &CablingPlan{FabricId: fabric.Id, FabricName: fabric.Name, Devices: []CablingDevice{}, Cables: []Cable{}}
+
+	for _, position := range sortedGroupKeys(groups) {
+		for _, dev := range groups[position] {
+			plan.Devices = append(plan.Devices, CablingDevice{
+				Id:                dev.Id,
+				Name:              endpointName(state, dev),
+				Position:          dev.Position,
+				ManagementAddress: dev.ManagementAddress,
+			})
+		}
+	}
+
+	counters := map[string]int{}
+	nextId := func(layer string) string {
+		counters[layer]++
+		return fmt.Sprintf("%s-%04d", cableIdPrefix[layer], counters[layer])
+	}
+
+	for _, link := range state.Links {
+		deviceBId := link.DeviceB.Id
+		plan.Cables = append(plan.Cables, Cable{
+			Id:         nextId(link.Layer),
+			Layer:      layerTagValue[link.Layer],
+			DeviceAId:  link.DeviceA.Id,
+			DeviceA:    endpointName(state, link.DeviceA),
+			PortA:      link.PortA,
+			DeviceBId:  &deviceBId,
+			DeviceB:    endpointName(state, link.DeviceB),
+			PortB:      link.PortB,
+			Subnet:     subnetOrEmpty(link.Subnet),
+			SubnetIpv6: subnetOrEmpty(link.SubnetIpv6),
+		})
+	}
+	for _, link := range state.HostLinks {
+		plan.Cables = append(plan.Cables, Cable{
+			Id:         nextId("leafHost"),
+			Layer:      layerTagValue["leafHost"],
+			DeviceAId:  link.Leaf.Id,
+			DeviceA:    endpointName(state, link.Leaf),
+			PortA:      link.LeafPort,
+			DeviceB:    link.HostName,
+			PortB:      link.Nic,
+			Subnet:     subnetOrEmpty(link.Subnet),
+			SubnetIpv6: subnetOrEmpty(link.SubnetIpv6),
+		})
+	}
+	return plan
+}
+
+func subnetOrEmpty(s *Subnet) string {
+	if s == nil {
+		return ""
+	}
+	return s.String()
+}
+
+// RenderCablingPlan renders plan in one of CablingFormats.
+func RenderCablingPlan(plan *CablingPlan, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case CablingFormatCsv:
+		return renderCablingCsv(plan)
+	case CablingFormatDot:
+		return renderCablingDot(plan), nil
+	case CablingFormatJson:
+		out, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unsupported cabling plan format %q, expected one of: %s", format, strings.Join(CablingFormats(), ", "))
+	}
+}
+
+// renderCablingCsv writes the technician cut-sheet: one row per cable.
+func renderCablingCsv(plan *CablingPlan) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{{"Cable ID", "Layer", "A Device", "A Port", "B Device", "B Port", "Subnet", "Subnet IPv6"}}
+	for _, c := range plan.Cables {
+		rows = append(rows, []string{c.Id, c.Layer, c.DeviceA, c.PortA, c.DeviceB, c.PortB, c.Subnet, c.SubnetIpv6})
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// dotRankOrder lays the tiers out top to bottom, hosts last.
+var dotRankOrder = []string{"super_spine", "spine", "leaf", hostPosition}
+
+// renderCablingDot draws the topology as an undirected Graphviz graph with one
+// rank per position and one edge per cable, labelled with its ports. Switches
+// are keyed by id so that devices sharing a label stay distinct nodes.
+func renderCablingDot(plan *CablingPlan) []byte {
+	type dotNode struct{ id, label string }
+	nodesByPosition := map[string][]dotNode{}
+	for _, d := range plan.Devices {
+		nodesByPosition[d.Position] = append(nodesByPosition[d.Position], dotNode{switchNodeId(d.Id), d.Name})
+	}
+	seenHosts := map[string]bool{}
+	for _, c := range plan.Cables {
+		if c.DeviceBId == nil && !seenHosts[c.DeviceB] {
+			seenHosts[c.DeviceB] = true
+			nodesByPosition[hostPosition] = append(nodesByPosition[hostPosition], dotNode{hostNodeId(c.DeviceB), c.DeviceB})
+		}
+	}
+
+	positions := append([]string{}, dotRankOrder...)
+	var extra []string
+	for position := range nodesByPosition {
+		if !slices.Contains(dotRankOrder, position) {
+			extra = append(extra, position)
+		}
+	}
+	sort.Strings(extra)
+	positions = append(positions, extra...)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "graph %s {\n", dotQuote(fmt.Sprintf("fabric-%d", plan.FabricId)))
+	fmt.Fprintf(&b, "  label=%s;\n", dotQuote(plan.FabricName))
+	b.WriteString("  rankdir=TB;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, position := range positions {
+		nodes := nodesByPosition[position]
+		if len(nodes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "  subgraph %s {\n", dotQuote(position))
+		b.WriteString("    rank=same;\n")
+		for _, n := range nodes {
+			if position == hostPosition {
+				fmt.Fprintf(&b, "    %s [label=%s, shape=ellipse];\n", dotQuote(n.id), dotQuote(n.label))
+			} else {
+				fmt.Fprintf(&b, "    %s [label=%s];\n", dotQuote(n.id), dotQuote(n.label))
+			}
+		}
+		b.WriteString("  }\n")
+	}
+	// dot ranks an edge's tail above its head, so the upper tier goes first:
+	// side B of fabric links, the leaf (side A) of host downlinks.
+	for _, c := range plan.Cables {
+		upper, upperPort := switchNodeId(c.DeviceAId), c.PortA
+		lower, lowerPort := hostNodeId(c.DeviceB), c.PortB
+		if c.DeviceBId != nil {
+			upper, upperPort = switchNodeId(*c.DeviceBId), c.PortB
+			lower, lowerPort = switchNodeId(c.DeviceAId), c.PortA
+		}
+		fmt.Fprintf(&b, "  %s -- %s [label=%s, taillabel=%s, headlabel=%s];\n",
+			dotQuote(upper), dotQuote(lower), dotQuote(c.Id), dotQuote(upperPort), dotQuote(lowerPort))
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+func switchNodeId(id int64) string { return "sw" + itoa(id) }
+
+func hostNodeId(name string) string { return "host:" + name }
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
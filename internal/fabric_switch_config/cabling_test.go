@@ -0,0 +1,110 @@
+package fabric_switch_config
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCablingPlan(t *testing.T) {
+	f, state := newFakeFromFixture(t, fixtureDevices(), fixtureConfig())
+
+	plan, err := ComputeCablingPlan(f, fixtureConfig(), 5)
+	if err != nil {
+		t.Fatalf("ComputeCablingPlan: %v", err)
+	}
+	if f.devicePatches != 0 || f.portPatches != 0 || len(f.linksCreated) != 0 || len(f.subnetsCreated) != 0 {
+		t.Fatalf("cabling plan made writes")
+	}
+	if len(plan.Devices) != 8 {
+		t.Errorf("devices = %d, want 8", len(plan.Devices))
+	}
+	if got, want := len(plan.Cables), len(state.Links)+len(state.HostLinks); got != want {
+		t.Fatalf("cables = %d, want %d", got, want)
+	}
+
+	var leafSpine, hostLink *Cable
+	ids := map[string]bool{}
+	for i := range plan.Cables {
+		c := &plan.Cables[i]
+		if ids[c.Id] {
+			t.Errorf("duplicate cable id %q", c.Id)
+		}
+		ids[c.Id] = true
+		if c.Subnet == "10.254.0.0/31" {
+			leafSpine = c
+		}
+		if hostLink == nil && c.Layer == "leaf-server" {
+			hostLink = c
+		}
+	}
+	if leafSpine == nil {
+		t.Fatalf("no cable for 10.254.0.0/31")
+	}
+	if leafSpine.Id != "LS-0001" || leafSpine.Layer != "leaf-spine" || leafSpine.DeviceA != "leaf-pod5-su1-r1" ||
+		leafSpine.PortA != "swp33s0" || leafSpine.DeviceBId == nil {
+		t.Errorf("leaf-spine cable = %+v", *leafSpine)
+	}
+	if hostLink == nil {
+		t.Fatalf("no leaf-server cable")
+	}
+	if hostLink.Id != "LH-0001" || hostLink.DeviceBId != nil || hostLink.DeviceB == "" || hostLink.PortB == "" {
+		t.Errorf("host cable = %+v", *hostLink)
+	}
+}
+
+func TestRenderCablingPlan(t *testing.T) {
+	groups, err := GroupAndOrder(fixtureDevices(), OrderingManagementAddress)
+	if err != nil {
+		t.Fatalf("GroupAndOrder: %v", err)
+	}
+	state := computeFixture(t)
+	plan := buildCablingPlan(&FabricInfo{Id: 5, Name: `Test "Fabric"`}, groups, state)
+
+	out, err := RenderCablingPlan(plan, "CSV")
+	if err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(out))).ReadAll()
+	if err != nil {
+		t.Fatalf("csv parse: %v", err)
+	}
+	if len(rows) != len(plan.Cables)+1 {
+		t.Errorf("csv rows = %d, want %d", len(rows), len(plan.Cables)+1)
+	}
+	if strings.Join(rows[0], ",") != "Cable ID,Layer,A Device,A Port,B Device,B Port,Subnet,Subnet IPv6" {
+		t.Errorf("csv header = %v", rows[0])
+	}
+
+	out, err = RenderCablingPlan(plan, CablingFormatJson)
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	var decoded CablingPlan
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("json parse: %v", err)
+	}
+	if len(decoded.Cables) != len(plan.Cables) || decoded.FabricId != 5 {
+		t.Errorf("json round-trip: %d cables, fabric %d", len(decoded.Cables), decoded.FabricId)
+	}
+
+	out, err = RenderCablingPlan(plan, CablingFormatDot)
+	if err != nil {
+		t.Fatalf("dot: %v", err)
+	}
+	dot := string(out)
+	if !strings.HasPrefix(dot, `graph "fabric-5" {`) || !strings.Contains(dot, `label="Test \"Fabric\""`) {
+		t.Errorf("dot header:\n%s", dot)
+	}
+	if got := strings.Count(dot, " -- "); got != len(plan.Cables) {
+		t.Errorf("dot edges = %d, want %d", got, len(plan.Cables))
+	}
+	if !strings.Contains(dot, `[label="ssp-group1-s1"]`) || !strings.Contains(dot, "shape=ellipse") {
+		t.Errorf("dot nodes missing:\n%s", dot)
+	}
+
+	if _, err := RenderCablingPlan(plan, "xml"); err == nil {
+		t.Errorf("expected an error for an unsupported format")
+	}
+}
@@ -11,11 +11,18 @@ type Client interface {
 	ListPorts(deviceId int64) ([]*PortRecord, error)
 	UpdatePortConfig(deviceId, portId int64, enabled *bool, description *string, configRevision int64) error
 	AddPortIpv4(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error
+	AddPortIpv6(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error
+	RemovePortIpv4(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error
+	RemovePortIpv6(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error
+	ListFabricLinks(fabricId int64) ([]*FabricLinkRecord, error)
 	ListP2pLinks() ([]*P2pLinkRecord, error)
 	CreateP2pLink(payload P2pLinkCreate) (*P2pLinkRecord, error)
 	CreateP2pIpv4Strategy(linkId, subnetId int64, binding string, linkRevision int64) error
+	CreateP2pIpv6Strategy(linkId, subnetId int64, binding string, linkRevision int64) error
+	DeleteP2pLink(linkId, linkRevision int64) error
 	ListSubnetsByFabricTag(fabricId int64) ([]*SubnetRecord, error)
 	CreateSubnet(payload SubnetCreate) (*SubnetRecord, error)
+	DeleteSubnet(subnetId, revision int64) error
 }
 
 // FabricInfo is the subset of a fabric the runner reads.
@@ -31,21 +38,30 @@ type DeviceRecord struct {
 	Device
 	Asn                                   int64
 	LoopbackAddressIpv4                   *string
+	LoopbackAddressIpv6                   *string
 	ApplyIdentifierAsHostnameOnNextDeploy bool
 	Revision                              int64
 }
 
-// DeviceUpdate is a sparse device patch: only the non-nil fields are sent.
+// DeviceUpdate is a sparse device patch: only the non-nil fields are sent. The
+// Clear fields unset a field by sending an explicit null.
 type DeviceUpdate struct {
 	IdentifierString                      *string
 	ApplyIdentifierAsHostnameOnNextDeploy *bool
 	Asn                                   *int64
 	LoopbackAddress                       *string
+	LoopbackAddressIpv6                   *string
+
+	ClearIdentifierString    bool
+	ClearAsn                 bool
+	ClearLoopbackAddress     bool
+	ClearLoopbackAddressIpv6 bool
 }
 
 func (u DeviceUpdate) empty() bool {
 	return u.IdentifierString == nil && u.ApplyIdentifierAsHostnameOnNextDeploy == nil &&
-		u.Asn == nil && u.LoopbackAddress == nil
+		u.Asn == nil && u.LoopbackAddress == nil && u.LoopbackAddressIpv6 == nil &&
+		!u.ClearIdentifierString && !u.ClearAsn && !u.ClearLoopbackAddress && !u.ClearLoopbackAddressIpv6
 }
 
 // PortRecord is a device interface and its staged config.
@@ -57,6 +73,7 @@ type PortRecord struct {
 	Description    *string
 	ConfigRevision int64
 	Ipv4Addresses  []IpAddress
+	Ipv6Addresses  []IpAddress
 }
 
 type IpAddress struct {
@@ -64,38 +81,52 @@ type IpAddress struct {
 	PrefixLength int32
 }
 
+// FabricLinkRecord is a physical link known to the fabric - discovered from
+// LLDP by a link rescan, or added by hand - between two switch interfaces.
+type FabricLinkRecord struct {
+	Id           int64
+	InterfaceAId int64
+	InterfaceBId int64
+}
+
 // P2pLinkRecord is the subset of an existing point-to-point link the runner
 // uses for idempotency. InterfaceAId/InterfaceBId are set only for sides of
-// type network_equipment_interface.
+// type network_equipment_interface. SubnetIds are the subnets its manual
+// strategies allocate from, in both address families.
 type P2pLinkRecord struct {
 	Id              int64
 	Revision        int64
 	InterfaceAId    *int64
 	InterfaceBId    *int64
 	HasIpv4Strategy bool
+	HasIpv6Strategy bool
+	SubnetIds       []int64
 }
 
 // P2pLinkCreate is a link to create. InterfaceBId nil => half-connected link.
-// When StagedSubnetId is non-nil, a manual ipv4 strategy is staged on create.
+// When StagedSubnetId (StagedSubnetIdIpv6) is non-nil, a manual ipv4 (ipv6)
+// strategy is staged on create; both use StagedBinding.
 type P2pLinkCreate struct {
-	InterfaceAId      int64
-	InterfaceBId      *int64
-	Description       *string
-	Mtu               *int32
-	RoutingActivation string
-	StagedSubnetId    *int64
-	StagedBinding     string
+	InterfaceAId       int64
+	InterfaceBId       *int64
+	Description        *string
+	Mtu                *int32
+	RoutingActivation  string
+	StagedSubnetId     *int64
+	StagedSubnetIdIpv6 *int64
+	StagedBinding      string
 }
 
 // SubnetRecord is an existing IPAM subnet.
 type SubnetRecord struct {
 	Id             int64
+	Revision       int64
 	NetworkAddress string
 	PrefixLength   int32
 	Tags           map[string]string
 }
 
-// SubnetCreate is a /31 IPAM subnet to create.
+// SubnetCreate is a /31 or /127 IPAM subnet to create.
 type SubnetCreate struct {
 	NetworkAddress string
 	PrefixLength   int32
@@ -56,7 +56,7 @@ func computeHostnames(config *Config, groups map[string][]*Device, threeTier boo
 				template = *t
 			}
 		} else {
-			template = defaults[p]
+			template = config.TagKeys().rewrite(defaults[p])
 		}
 		if template != "" {
 			hostnameTemplates[p] = template
@@ -121,6 +121,7 @@ func computeAsns(config *Config, groups map[string][]*Device, threeTier bool, st
 	if config.Asn == nil {
 		return nil
 	}
+	keys := config.TagKeys()
 	leafStart, spineStart, superSpineStart := defaultLeafStart, defaultSpineStart, defaultSuperSpineStart
 	if config.Asn.LeafStart != nil {
 		leafStart = *config.Asn.LeafStart
@@ -142,9 +143,9 @@ func computeAsns(config *Config, groups map[string][]*Device, threeTier bool, st
 	}
 
 	// Leaves: unique ASNs by (pod,su,rail) sort (3-tier) or (su,rail) (2-tier).
-	leafSortTags := []string{tagSu, tagRail}
+	leafSortTags := []string{keys.Unit, keys.Rail}
 	if threeTier {
-		leafSortTags = []string{tagPod, tagSu, tagRail}
+		leafSortTags = []string{keys.Pod, keys.Unit, keys.Rail}
 	}
 	leaves, err := sortByNumericTags(groups["leaf"], leafSortTags)
 	if err != nil {
@@ -158,14 +159,14 @@ func computeAsns(config *Config, groups map[string][]*Device, threeTier bool, st
 
 	// Spines.
 	if threeTier {
-		spines, err := sortByNumericTags(groups["spine"], []string{tagPod, tagRail})
+		spines, err := sortByNumericTags(groups["spine"], []string{keys.Pod, keys.Rail})
 		if err != nil {
 			return err
 		}
 		groupIndex := -1
 		var previousKey []int
 		for _, dev := range spines {
-			key, _ := numericTags(dev, tagPod, tagRail)
+			key, _ := numericTags(dev, keys.Pod, keys.Rail)
 			if previousKey == nil || !equalIntSlice(key, previousKey) {
 				groupIndex++
 				previousKey = key
@@ -197,20 +198,12 @@ func computeLoopbacks(config *Config, groups map[string][]*Device, threeTier boo
 	if config.Loopback == nil {
 		return nil
 	}
-	subnetStr := config.Loopback.Subnet
-	if subnetStr == "" {
-		subnetStr = defaultLoopbackSubnet
-	}
-	network, err := parseIpv4Network(subnetStr)
-	if err != nil {
-		return configErrorf("loopback.subnet: invalid network %q: %s", subnetStr, err.Error())
-	}
-
-	leafKeys := []string{tagSu, tagRail}
-	spineKeys := []string{tagSpineIndex}
+	keys := config.TagKeys()
+	leafKeys := []string{keys.Unit, keys.Rail}
+	spineKeys := []string{keys.SpineIndex}
 	if threeTier {
-		leafKeys = []string{tagPod, tagSu, tagRail}
-		spineKeys = []string{tagPod, tagRail, tagSpineIndex}
+		leafKeys = []string{keys.Pod, keys.Unit, keys.Rail}
+		spineKeys = []string{keys.Pod, keys.Rail, keys.SpineIndex}
 	}
 
 	var ordered []*Device
@@ -225,12 +218,34 @@ func computeLoopbacks(config *Config, groups map[string][]*Device, threeTier boo
 	}
 	ordered = append(ordered, spines...)
 	// Stable sort by ssp-group: within a group the fabric ordering holds.
-	ssps, err := sortByNumericTags(groups["super_spine"], []string{tagSspGroup})
+	ssps, err := sortByNumericTags(groups["super_spine"], []string{keys.Group})
 	if err != nil {
 		return err
 	}
 	ordered = append(ordered, ssps...)
 
+	if config.ipv4() {
+		if err := assignLoopbacksIpv4(config.Loopback.Subnet, ordered, state); err != nil {
+			return err
+		}
+	}
+	if config.ipv6() {
+		if err := assignLoopbacksIpv6(config.Loopback.SubnetIpv6, ordered, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func assignLoopbacksIpv4(subnetStr string, ordered []*Device, state *DesiredState) error {
+	if subnetStr == "" {
+		subnetStr = defaultLoopbackSubnet
+	}
+	network, err := parseIpv4Network(subnetStr)
+	if err != nil {
+		return configErrorf("loopback.subnet: invalid network %q: %s", subnetStr, err.Error())
+	}
+
 	broadcast := network.lastAddress()
 	for offset, dev := range ordered {
 		address := network.base + 1 + uint32(offset)
@@ -245,6 +260,30 @@ func computeLoopbacks(config *Config, groups map[string][]*Device, threeTier boo
 	return nil
 }
 
+// assignLoopbacksIpv6 carves the /128s in the same order as the /32s, starting
+// at the pool's ::1.
+func assignLoopbacksIpv6(subnetStr string, ordered []*Device, state *DesiredState) error {
+	if subnetStr == "" {
+		subnetStr = defaultLoopbackSubnetIpv6
+	}
+	network, err := parseIpv6Network(subnetStr)
+	if err != nil {
+		return configErrorf("loopback.subnetIpv6: invalid network %q: %s", subnetStr, err.Error())
+	}
+
+	for offset, dev := range ordered {
+		address := network.addressAt(uint64(offset) + 1)
+		if !network.containsSubnet(address, 128) {
+			return configErrorf(
+				"loopback.subnetIpv6 %s exhausted at %s (device %d of %d)",
+				subnetStr, dev.Label(), offset+1, len(ordered))
+		}
+		ip := address.String()
+		state.desiredFor(dev.Id).LoopbackIpv6 = &ip
+	}
+	return nil
+}
+
 // ---- Topology + descriptions ------------------------------------------------
 
 func computeTopology(config *Config, groups map[string][]*Device, threeTier bool, state *DesiredState) error {
@@ -252,15 +291,16 @@ func computeTopology(config *Config, groups map[string][]*Device, threeTier bool
 	if topo == nil {
 		return nil
 	}
+	keys := config.TagKeys()
 
 	// Global spine index = ordinal in the numeric sort by (pod,rail,spine-index)
 	// (just (spine-index) in 2-tier). Needed by both fabric layers.
 	var spinesSorted []*Device
 	spineGlobal := map[int64]int{}
 	if topo.LeafSpine != nil || topo.SpineSuperSpine != nil {
-		spineSortTags := []string{tagSpineIndex}
+		spineSortTags := []string{keys.SpineIndex}
 		if threeTier {
-			spineSortTags = []string{tagPod, tagRail, tagSpineIndex}
+			spineSortTags = []string{keys.Pod, keys.Rail, keys.SpineIndex}
 		}
 		var err error
 		spinesSorted, err = sortByNumericTags(groups["spine"], spineSortTags)
@@ -283,26 +323,27 @@ func computeTopology(config *Config, groups map[string][]*Device, threeTier bool
 		}
 	}
 
-	if err := computeLeafSpine(topo, groups, threeTier, spinesSorted, spineGlobal, state); err != nil {
+	if err := computeLeafSpine(config, groups, threeTier, spinesSorted, spineGlobal, state); err != nil {
 		return err
 	}
-	if err := computeSpineSuperSpine(topo, groups, threeTier, spinesSorted, spineGlobal, state); err != nil {
+	if err := computeSpineSuperSpine(config, groups, threeTier, spinesSorted, spineGlobal, state); err != nil {
 		return err
 	}
 	if err := computeDescriptions(config, state); err != nil {
 		return err
 	}
-	if err := computeLeafHost(topo, groups, threeTier, state); err != nil {
+	if err := computeLeafHost(config, groups, threeTier, state); err != nil {
 		return err
 	}
 	return nil
 }
 
-func computeLeafSpine(topo *TopologyConfig, groups map[string][]*Device, threeTier bool, spinesSorted []*Device, spineGlobal map[int64]int, state *DesiredState) error {
-	leafSpine := topo.LeafSpine
+func computeLeafSpine(config *Config, groups map[string][]*Device, threeTier bool, spinesSorted []*Device, spineGlobal map[int64]int, state *DesiredState) error {
+	leafSpine := config.Topology.LeafSpine
 	if leafSpine == nil {
 		return nil
 	}
+	keys := config.TagKeys()
 	leaves := groups["leaf"]
 	if len(leaves) == 0 {
 		return configErrorf("topology.leafSpine configured but the fabric has no leaf devices")
@@ -311,11 +352,11 @@ func computeLeafSpine(topo *TopologyConfig, groups map[string][]*Device, threeTi
 		return configErrorf("topology.leafSpine configured but the fabric has no spine devices")
 	}
 
-	leafUplinkBudget := splitsPerSwitch - (leafUplinkLogicalStart - 1) // 64
-	spineDownlinkBudget := splitsPerSwitch                             // 2-tier
-	if threeTier {
-		spineDownlinkBudget = spineUplinkLogicalStart - spineDownlinkLogicalStart // 32
-	}
+	// Reference layout: 64 leaf uplinks; 128 spine downlinks (32 in 3-tier,
+	// where the spine's uplinks start at split 33).
+	leafLayout, spineLayout := config.portLayout("leaf"), config.portLayout("spine")
+	leafUplinkBudget := leafLayout.uplinkCount()
+	spineDownlinkBudget := spineLayout.downlinkCount(threeTier)
 
 	type block struct {
 		label  string
@@ -327,11 +368,11 @@ func computeLeafSpine(topo *TopologyConfig, groups map[string][]*Device, threeTi
 
 	if threeTier {
 		// Group leaves by (pod, rail), ordered within block by su.
-		leavesSorted, err := sortByNumericTags(leaves, []string{tagPod, tagRail, tagSu})
+		leavesSorted, err := sortByNumericTags(leaves, []string{keys.Pod, keys.Rail, keys.Unit})
 		if err != nil {
 			return err
 		}
-		leavesByRail, railOrder, err := groupByTagPair(leavesSorted, tagPod, tagRail)
+		leavesByRail, railOrder, err := groupByTagPair(leavesSorted, keys.Pod, keys.Rail)
 		if err != nil {
 			return err
 		}
@@ -344,11 +385,11 @@ func computeLeafSpine(topo *TopologyConfig, groups map[string][]*Device, threeTi
 			var sus []int
 			seenSu := map[int]bool{}
 			for _, leaf := range railLeaves {
-				su, _ := numericTag(leaf, tagSu)
+				su, _ := numericTag(leaf, keys.Unit)
 				if seenSu[su] {
 					return configErrorf(
-						"duplicate nvidia/scalability-unit-id among the leaves of pod %d rail-group %d: %v",
-						key.a, key.b, intsOf(railLeaves, tagSu))
+						"duplicate %s among the leaves of pod %d rail-group %d: %v",
+						keys.Unit, key.a, key.b, intsOf(railLeaves, keys.Unit))
 				}
 				seenSu[su] = true
 				sus = append(sus, su)
@@ -376,12 +417,12 @@ func computeLeafSpine(topo *TopologyConfig, groups map[string][]*Device, threeTi
 		}
 		if !allEqual(counts) {
 			return configErrorf(
-				"pods have different SU counts %v; the reference addressing (L = 32 // susPerPod) needs a uniform SU count",
+				"pods have different SU counts %v; the reference addressing (L = spine downlinks // susPerPod) needs a uniform SU count",
 				podSuCount)
 		}
 		autoBlockSize = counts[0] // susPerPod
 
-		spinesByRail, spineRailOrder, err := groupByTagPair(spinesSorted, tagPod, tagRail)
+		spinesByRail, spineRailOrder, err := groupByTagPair(spinesSorted, keys.Pod, keys.Rail)
 		if err != nil {
 			return err
 		}
@@ -405,13 +446,13 @@ func computeLeafSpine(topo *TopologyConfig, groups map[string][]*Device, threeTi
 			}
 		}
 	} else {
-		leavesSorted, err := sortByNumericTags(leaves, []string{tagSu, tagRail})
+		leavesSorted, err := sortByNumericTags(leaves, []string{keys.Unit, keys.Rail})
 		if err != nil {
 			return err
 		}
 		seen := map[string]*Device{}
 		for _, leaf := range leavesSorted {
-			key, _ := numericTags(leaf, tagSu, tagRail)
+			key, _ := numericTags(leaf, keys.Unit, keys.Rail)
 			ks := fmt.Sprint(key)
 			if other, ok := seen[ks]; ok {
 				return configErrorf(
@@ -457,9 +498,9 @@ func computeLeafSpine(topo *TopologyConfig, groups map[string][]*Device, threeTi
 					state.Links = append(state.Links, &LinkPlan{
 						Layer:      "leafSpine",
 						DeviceA:    leaf,
-						PortA:      logicalToSwp(leafUplinkLogicalStart + spineBlock*L + u),
+						PortA:      leafLayout.portName(leafLayout.Uplinks.Start + spineBlock*L + u),
 						DeviceB:    spine,
-						PortB:      logicalToSwp(spineDownlinkLogicalStart + leafBlock*L + u),
+						PortB:      spineLayout.portName(spineLayout.Downlinks.Start + leafBlock*L + u),
 						PoolOffset: base + 2*u,
 					})
 				}
@@ -469,11 +510,12 @@ func computeLeafSpine(topo *TopologyConfig, groups map[string][]*Device, threeTi
 	return nil
 }
 
-func computeSpineSuperSpine(topo *TopologyConfig, groups map[string][]*Device, threeTier bool, spinesSorted []*Device, spineGlobal map[int64]int, state *DesiredState) error {
-	spineSsp := topo.SpineSuperSpine
+func computeSpineSuperSpine(config *Config, groups map[string][]*Device, threeTier bool, spinesSorted []*Device, spineGlobal map[int64]int, state *DesiredState) error {
+	spineSsp := config.Topology.SpineSuperSpine
 	if spineSsp == nil {
 		return nil
 	}
+	keys := config.TagKeys()
 	if !threeTier {
 		return configErrorf(
 			"topology.spineSuperSpine configured but the fabric has no super_spine devices (2-tier fabrics have no superspine layer)")
@@ -486,7 +528,7 @@ func computeSpineSuperSpine(topo *TopologyConfig, groups map[string][]*Device, t
 	sspsByGroup := map[int][]*Device{}
 	var groupOrder []int
 	for _, ssp := range groups["super_spine"] {
-		g, err := numericTag(ssp, tagSspGroup)
+		g, err := numericTag(ssp, keys.Group)
 		if err != nil {
 			return err
 		}
@@ -498,16 +540,21 @@ func computeSpineSuperSpine(topo *TopologyConfig, groups map[string][]*Device, t
 
 	spineIndexValues := map[int]bool{}
 	for _, s := range spinesSorted {
-		idx, _ := numericTag(s, tagSpineIndex)
+		idx, _ := numericTag(s, keys.SpineIndex)
 		spineIndexValues[idx] = true
 	}
 	if !equalIntSets(spineIndexValues, keySet(sspsByGroup)) {
 		return configErrorf(
-			"nvidia/spine-index values %v and nvidia/ssp-group-id values %v must match exactly (a spine with spine-index S connects to ssp-group S)",
-			sortedSet(spineIndexValues), sortedKeysInt(sspsByGroup))
+			"%s values %v and %s values %v must match exactly (a spine with spine-index S connects to ssp-group S)",
+			keys.SpineIndex, sortedSet(spineIndexValues), keys.Group, sortedKeysInt(sspsByGroup))
 	}
 
-	spineUplinkBudget := spineSspRunAddresses / 2 // 32
+	spineLayout, sspLayout := config.portLayout("spine"), config.portLayout("super_spine")
+	spineUplinkBudget := spineLayout.uplinkCount() // 32 in the reference layout
+	if spineUplinkBudget == 0 {
+		return configErrorf("topology.spineSuperSpine configured but the spine port layout %s has no uplinks", spineLayout.describe())
+	}
+	sspDownlinkBudget := sspLayout.downlinkCount(false)
 	Lss := 0
 	if spineSsp.LinksPerPair == nil { // "auto"
 		sizes := map[int]bool{}
@@ -516,7 +563,7 @@ func computeSpineSuperSpine(topo *TopologyConfig, groups map[string][]*Device, t
 		}
 		if len(sizes) != 1 {
 			return configErrorf(
-				"ssp groups have different sizes; linksPerPair 'auto' (= 32 // sspsPerGroup) needs uniform groups - set topology.spineSuperSpine.linksPerPair explicitly")
+				"ssp groups have different sizes; linksPerPair 'auto' (= spine uplinks // sspsPerGroup) needs uniform groups - set topology.spineSuperSpine.linksPerPair explicitly")
 		}
 		var sspsPerGroup int
 		for s := range sizes {
@@ -533,7 +580,7 @@ func computeSpineSuperSpine(topo *TopologyConfig, groups map[string][]*Device, t
 	}
 
 	for _, spine := range spinesSorted {
-		idx, _ := numericTag(spine, tagSpineIndex)
+		idx, _ := numericTag(spine, keys.SpineIndex)
 		groupSsps := sspsByGroup[idx]
 		if len(groupSsps)*Lss > spineUplinkBudget {
 			return configErrorf(
@@ -541,19 +588,19 @@ func computeSpineSuperSpine(topo *TopologyConfig, groups map[string][]*Device, t
 				len(groupSsps), Lss, spineUplinkBudget, spine.Label())
 		}
 		sg := spineGlobal[spine.Id]
-		if sspDownlinkLogicalStart-1+(sg+1)*Lss > splitsPerSwitch {
+		if (sg+1)*Lss > sspDownlinkBudget {
 			return configErrorf(
-				"spine %s (global index %d) x %d link(s) exceed the superspine's %d splits",
-				spine.Label(), sg, Lss, splitsPerSwitch)
+				"spine %s (global index %d) x %d link(s) exceed the superspine's %d downlink splits",
+				spine.Label(), sg, Lss, sspDownlinkBudget)
 		}
 		for inGroup, ssp := range groupSsps {
 			for u := 0; u < Lss; u++ {
 				state.Links = append(state.Links, &LinkPlan{
 					Layer:      "spineSuperSpine",
 					DeviceA:    spine,
-					PortA:      logicalToSwp(spineUplinkLogicalStart + inGroup*Lss + u),
+					PortA:      spineLayout.portName(spineLayout.Uplinks.Start + inGroup*Lss + u),
 					DeviceB:    ssp,
-					PortB:      logicalToSwp(sspDownlinkLogicalStart + sg*Lss + u),
+					PortB:      sspLayout.portName(sspLayout.Downlinks.Start + sg*Lss + u),
 					PoolOffset: sg*spineSspRunAddresses + inGroup*2*Lss + 2*u,
 				})
 			}
@@ -595,17 +642,19 @@ func computeDescriptions(config *Config, state *DesiredState) error {
 	return nil
 }
 
-func computeLeafHost(topo *TopologyConfig, groups map[string][]*Device, threeTier bool, state *DesiredState) error {
-	leafHost := topo.LeafHost
+func computeLeafHost(config *Config, groups map[string][]*Device, threeTier bool, state *DesiredState) error {
+	leafHost := config.Topology.LeafHost
 	if leafHost == nil {
 		return nil
 	}
+	keys := config.TagKeys()
+	leafLayout := config.portLayout("leaf")
 
 	var nodes []int
 	if leafHost.Nodes != nil {
 		nodes = leafHost.Nodes
 	} else {
-		nodeCount := defaultHostNodeCount
+		nodeCount := leafLayout.hostNodeCapacity() // 32 in the reference layout
 		if leafHost.NodeCount != nil {
 			nodeCount = *leafHost.NodeCount
 		}
@@ -613,9 +662,12 @@ func computeLeafHost(topo *TopologyConfig, groups map[string][]*Device, threeTie
 			nodes = append(nodes, i)
 		}
 	}
-	portPattern := leafHost.PortPattern
-	if portPattern == "" {
-		portPattern = defaultHostPortPattern
+	for _, node := range nodes {
+		if node >= leafLayout.hostNodeCapacity() {
+			return configErrorf(
+				"host node %d does not fit the leaf port layout %s (at most %d nodes)",
+				node, leafLayout.describe(), leafLayout.hostNodeCapacity())
+		}
 	}
 	nics := leafHost.NicNames
 	if nics == nil {
@@ -635,13 +687,13 @@ func computeLeafHost(topo *TopologyConfig, groups map[string][]*Device, threeTie
 	suKeySet := map[string][]int{}
 	var suKeys [][]int
 	for _, leaf := range groups["leaf"] {
-		su, err := numericTag(leaf, tagSu)
+		su, err := numericTag(leaf, keys.Unit)
 		if err != nil {
 			return err
 		}
 		var key []int
 		if threeTier {
-			pod, err := numericTag(leaf, tagPod)
+			pod, err := numericTag(leaf, keys.Pod)
 			if err != nil {
 				return err
 			}
@@ -667,8 +719,8 @@ func computeLeafHost(topo *TopologyConfig, groups map[string][]*Device, threeTie
 	}
 
 	for _, leaf := range groups["leaf"] {
-		su, _ := numericTag(leaf, tagSu)
-		railGroup, err := numericTag(leaf, tagRail)
+		su, _ := numericTag(leaf, keys.Unit)
+		railGroup, err := numericTag(leaf, keys.Rail)
 		if err != nil {
 			return err
 		}
@@ -676,11 +728,11 @@ func computeLeafHost(topo *TopologyConfig, groups map[string][]*Device, threeTie
 		var pod int
 		havePod := false
 		if threeTier {
-			pod, _ = numericTag(leaf, tagPod)
+			pod, _ = numericTag(leaf, keys.Pod)
 			values["pod"] = pod
 			havePod = true
-		} else if _, ok := leaf.TagsMap[tagPod]; ok {
-			pod, _ = numericTag(leaf, tagPod)
+		} else if _, ok := leaf.TagsMap[keys.Pod]; ok {
+			pod, _ = numericTag(leaf, keys.Pod)
 			values["pod"] = pod
 			havePod = true
 		}
@@ -705,11 +757,17 @@ func computeLeafHost(topo *TopologyConfig, groups map[string][]*Device, threeTie
 				hostName = fmt.Sprintf("hgx-su%02d-h%02d", su, node)
 			}
 			for _, sub := range []int{0, 1} {
-				portName, err := expandTemplate(portPattern, nil, nil, map[string]any{
-					"port": node + 1, "sub": sub, "node": node,
-				})
-				if err != nil {
-					return err
+				// An explicit pattern keeps its {port} = node + 1 meaning;
+				// otherwise the leaf layout names the node's downlinks.
+				portName := leafLayout.hostPortName(node, sub)
+				if leafHost.PortPattern != "" {
+					var err error
+					portName, err = expandTemplate(leafHost.PortPattern, nil, nil, map[string]any{
+						"port": node + 1, "sub": sub, "node": node,
+					})
+					if err != nil {
+						return err
+					}
 				}
 				key := PortKey{leaf.Id, portName}
 				if _, exists := state.PortDescriptions[key]; exists {
@@ -742,7 +800,7 @@ func computeLeafHost(topo *TopologyConfig, groups map[string][]*Device, threeTie
 	return nil
 }
 
-// ---- P2P /31 assignment -----------------------------------------------------
+// ---- P2P /31 + /127 assignment ----------------------------------------------
 
 func assignP2pSubnets(config *Config, state *DesiredState) error {
 	if config.P2p == nil {
@@ -752,56 +810,98 @@ func assignP2pSubnets(config *Config, state *DesiredState) error {
 		return configErrorf("'p2p' configured but 'topology' produced no link pairs")
 	}
 
-	pools := map[string]ipv4Network{}
-	for layer, def := range defaultPools {
-		raw := def
-		if config.P2p.Pools != nil {
-			if v, ok := config.P2p.Pools[layer]; ok && v != "" {
-				raw = v
-			}
-		}
-		pool, err := parseIpv4Network(raw)
-		if err != nil {
-			return configErrorf("p2p.pools.%s: invalid network %q: %s", layer, raw, err.Error())
-		}
-		pools[layer] = pool
-	}
-
 	assigned := map[string]string{}
-	assign := func(layer string, offset int, label string) (*Subnet, error) {
-		pool := pools[layer]
-		base := pool.base + uint32(offset)
-		if !pool.containsSubnet(base, 31) {
-			return nil, configErrorf(
-				"computed /31 %s/31 for %s falls outside p2p.pools.%s; size the pool to the fabric",
-				uintToIpv4(base), label, layer)
-		}
-		subnet := &Subnet{NetworkAddress: uintToIpv4(base), PrefixLength: 31}
+	claim := func(subnet *Subnet, label string) error {
 		key := subnet.String()
 		if other, ok := assigned[key]; ok {
-			return nil, configErrorf(
-				"computed /31 %s for %s collides with %s (overlapping p2p.pools?)",
-				key, label, other)
+			return configErrorf(
+				"computed /%d %s for %s collides with %s (overlapping p2p pools?)",
+				subnet.PrefixLength, key, label, other)
 		}
 		assigned[key] = label
-		return subnet, nil
+		return nil
+	}
+
+	var assign, assignIpv6 func(layer string, offset int, label string) (*Subnet, error)
+	if config.ipv4() {
+		pools := map[string]ipv4Network{}
+		for layer, def := range defaultPools {
+			raw := def
+			if config.P2p.Pools != nil {
+				if v, ok := config.P2p.Pools[layer]; ok && v != "" {
+					raw = v
+				}
+			}
+			pool, err := parseIpv4Network(raw)
+			if err != nil {
+				return configErrorf("p2p.pools.%s: invalid network %q: %s", layer, raw, err.Error())
+			}
+			pools[layer] = pool
+		}
+		assign = func(layer string, offset int, label string) (*Subnet, error) {
+			pool := pools[layer]
+			base := pool.base + uint32(offset)
+			if !pool.containsSubnet(base, 31) {
+				return nil, configErrorf(
+					"computed /31 %s/31 for %s falls outside p2p.pools.%s; size the pool to the fabric",
+					uintToIpv4(base), label, layer)
+			}
+			subnet := &Subnet{NetworkAddress: uintToIpv4(base), PrefixLength: 31}
+			return subnet, claim(subnet, label)
+		}
+	}
+	if config.ipv6() {
+		pools := map[string]ipv6Network{}
+		for layer, def := range defaultPoolsIpv6 {
+			raw := def
+			if v, ok := config.P2p.PoolsIpv6[layer]; ok && v != "" {
+				raw = v
+			}
+			pool, err := parseIpv6Network(raw)
+			if err != nil {
+				return configErrorf("p2p.poolsIpv6.%s: invalid network %q: %s", layer, raw, err.Error())
+			}
+			pools[layer] = pool
+		}
+		assignIpv6 = func(layer string, offset int, label string) (*Subnet, error) {
+			pool := pools[layer]
+			base := pool.addressAt(uint64(offset))
+			if !pool.containsSubnet(base, 127) {
+				return nil, configErrorf(
+					"computed /127 %s/127 for %s falls outside p2p.poolsIpv6.%s; size the pool to the fabric",
+					base, label, layer)
+			}
+			subnet := &Subnet{NetworkAddress: base.String(), PrefixLength: 127}
+			return subnet, claim(subnet, label)
+		}
 	}
 
+	var err error
 	for _, plan := range state.Links {
 		label := fmt.Sprintf("%s:%s<->%s:%s", plan.DeviceA.Label(), plan.PortA, plan.DeviceB.Label(), plan.PortB)
-		subnet, err := assign(plan.Layer, plan.PoolOffset, label)
-		if err != nil {
-			return err
+		if assign != nil {
+			if plan.Subnet, err = assign(plan.Layer, plan.PoolOffset, label); err != nil {
+				return err
+			}
+		}
+		if assignIpv6 != nil {
+			if plan.SubnetIpv6, err = assignIpv6(plan.Layer, plan.PoolOffset, label); err != nil {
+				return err
+			}
 		}
-		plan.Subnet = subnet
 	}
 	for _, plan := range state.HostLinks {
 		label := fmt.Sprintf("%s:%s->%s", plan.Leaf.Label(), plan.LeafPort, plan.HostName)
-		subnet, err := assign("leafHost", plan.PoolOffset, label)
-		if err != nil {
-			return err
+		if assign != nil {
+			if plan.Subnet, err = assign("leafHost", plan.PoolOffset, label); err != nil {
+				return err
+			}
+		}
+		if assignIpv6 != nil {
+			if plan.SubnetIpv6, err = assignIpv6("leafHost", plan.PoolOffset, label); err != nil {
+				return err
+			}
 		}
-		plan.Subnet = subnet
 	}
 	return nil
 }
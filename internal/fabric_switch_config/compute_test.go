@@ -1,6 +1,7 @@
 package fabric_switch_config
 
 import (
+	"net/netip"
 	"testing"
 )
 
@@ -101,6 +102,71 @@ func TestAsns(t *testing.T) {
 	}
 }
 
+func TestDualStackUnderlay(t *testing.T) {
+	config := fixtureConfig()
+	config.AddressFamily = AddressFamilyDualStack
+	groups, err := GroupAndOrder(fixtureDevices(), OrderingManagementAddress)
+	if err != nil {
+		t.Fatalf("GroupAndOrder: %v", err)
+	}
+	s, err := ComputeDesired(config, groups)
+	if err != nil {
+		t.Fatalf("ComputeDesired: %v", err)
+	}
+
+	// Same device order as the /32s: first leaf ::1, first super-spine ::6.
+	for id, want := range map[int64]string{4: "fd00:253::1", 1: "fd00:253::5", 6: "fd00:253::6"} {
+		if d := s.ByDevice[id]; d.LoopbackIp == nil || d.LoopbackIpv6 == nil || *d.LoopbackIpv6 != want {
+			t.Errorf("device %d loopbacks = %s / %s, want an IPv4 one and %s",
+				id, strOrDash(d.LoopbackIp), strOrDash(d.LoopbackIpv6), want)
+		}
+	}
+	// Each /127 sits at the /31's offset in its IPv6 pool.
+	for _, l := range s.Links {
+		if l.Subnet == nil || l.SubnetIpv6 == nil || l.SubnetIpv6.PrefixLength != 127 {
+			t.Fatalf("%s link %s: subnets %s / %s", l.Layer, l.PortA, subnetOrDash(l.Subnet), subnetOrDash(l.SubnetIpv6))
+		}
+	}
+	if l := findLink(s, "leafSpine", 4, 2, "swp33s0"); l == nil || l.SubnetIpv6.String() != "fd00:254::/127" {
+		t.Errorf("first leaf-spine /127 = %+v, want fd00:254::/127", l)
+	}
+	if h := s.HostLinks[len(s.HostLinks)-1]; h.SubnetIpv6 == nil ||
+		addIpv6(netip.MustParseAddr("fd00:172:16::"), uint64(h.PoolOffset)).String() != h.SubnetIpv6.NetworkAddress {
+		t.Errorf("host /127 %s does not match offset %d", subnetOrDash(h.SubnetIpv6), h.PoolOffset)
+	}
+}
+
+func TestIpv6OnlyUnderlay(t *testing.T) {
+	config := fixtureConfig()
+	config.AddressFamily = AddressFamilyIpv6
+	config.Loopback.SubnetIpv6 = "2001:db8:ff::/120"
+	config.P2p.PoolsIpv6 = map[string]string{"leafSpine": "2001:db8:1::/64"}
+	groups, err := GroupAndOrder(fixtureDevices(), OrderingManagementAddress)
+	if err != nil {
+		t.Fatalf("GroupAndOrder: %v", err)
+	}
+	s, err := ComputeDesired(config, groups)
+	if err != nil {
+		t.Fatalf("ComputeDesired: %v", err)
+	}
+	if d := s.ByDevice[3]; d.LoopbackIp != nil || d.LoopbackIpv6 == nil || *d.LoopbackIpv6 != "2001:db8:ff::2" {
+		t.Errorf("device 3 loopbacks = %s / %s, want - / 2001:db8:ff::2", strOrDash(d.LoopbackIp), strOrDash(d.LoopbackIpv6))
+	}
+	for _, l := range s.Links {
+		if l.Subnet != nil || l.SubnetIpv6 == nil {
+			t.Fatalf("%s link %s: subnets %s / %s, want IPv6 only", l.Layer, l.PortA, subnetOrDash(l.Subnet), subnetOrDash(l.SubnetIpv6))
+		}
+	}
+	if l := findLink(s, "leafSpine", 4, 2, "swp33s0"); l == nil || l.SubnetIpv6.String() != "2001:db8:1::/127" {
+		t.Errorf("first leaf-spine /127 = %+v, want 2001:db8:1::/127", l)
+	}
+
+	config.Loopback.SubnetIpv6 = "2001:db8:ff::/126"
+	if _, err := ComputeDesired(config, groups); err == nil || !contains(err.Error(), "exhausted") {
+		t.Errorf("expected loopback.subnetIpv6 exhaustion, got %v", err)
+	}
+}
+
 func TestLoopbacks(t *testing.T) {
 	s := computeFixture(t)
 	// leaves r1,r2,r3 then spines s1,s2 then ssp g1#1,g1#2,g2#1
@@ -291,3 +357,63 @@ func TestDescriptions(t *testing.T) {
 		}
 	}
 }
+
+func TestCustomTagKeys(t *testing.T) {
+	config := func() *Config {
+		c := fixtureConfig()
+		c.Hostname = &HostnameConfig{Templates: map[string]*string{}} // built-in templates
+		c.Loopback = &LoopbackConfig{}
+		return c
+	}
+	rename := map[string]string{
+		tagPod: "site/pod", tagSu: "site/unit", tagRail: "site/rail",
+		tagSpineIndex: "site/spine", tagSspGroup: "site/group",
+	}
+	devices := fixtureDevices()
+	for _, d := range devices {
+		tags := map[string]string{}
+		for k, v := range d.TagsMap {
+			if renamed, ok := rename[k]; ok {
+				k = renamed
+			}
+			tags[k] = v
+		}
+		d.TagsMap = tags
+	}
+
+	groups, err := GroupAndOrder(devices, OrderingManagementAddress)
+	if err != nil {
+		t.Fatalf("GroupAndOrder: %v", err)
+	}
+	if _, err := ComputeDesired(config(), groups); err == nil {
+		t.Fatalf("expected an error with the default keys missing from the devices")
+	}
+
+	custom := config()
+	custom.Tags = &TagKeysConfig{Pod: "site/pod", Unit: "site/unit", Rail: "site/rail", SpineIndex: "site/spine", Group: "site/group"}
+	got, err := ComputeDesired(custom, groups)
+	if err != nil {
+		t.Fatalf("ComputeDesired with custom keys: %v", err)
+	}
+	baseGroups, _ := GroupAndOrder(fixtureDevices(), OrderingManagementAddress)
+	want, err := ComputeDesired(config(), baseGroups)
+	if err != nil {
+		t.Fatalf("ComputeDesired with default keys: %v", err)
+	}
+
+	for id, w := range want.ByDevice {
+		g := got.ByDevice[id]
+		if g == nil || strOrDash(g.Hostname) != strOrDash(w.Hostname) || int64OrDash(g.Asn) != int64OrDash(w.Asn) ||
+			strOrDash(g.LoopbackIp) != strOrDash(w.LoopbackIp) {
+			t.Errorf("device %d: got %+v, want %+v", id, g, w)
+		}
+	}
+	if len(got.Links) != len(want.Links) || len(got.HostLinks) != len(want.HostLinks) {
+		t.Fatalf("links = %d/%d, want %d/%d", len(got.Links), len(got.HostLinks), len(want.Links), len(want.HostLinks))
+	}
+	for i := range want.Links {
+		if got.Links[i].PortA != want.Links[i].PortA || got.Links[i].DeviceB.Id != want.Links[i].DeviceB.Id {
+			t.Errorf("link %d differs", i)
+		}
+	}
+}
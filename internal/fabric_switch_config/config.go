@@ -1,17 +1,35 @@
 package fabric_switch_config
 
+import "strings"
+
 // Config is the parsed, validated fabric-switch configuration. Presence (a
 // non-nil pointer), not truthiness, enables a feature: an empty Asn{} means
 // "ASNs with default starts". The runner builds this from YAML via LoadConfig.
 type Config struct {
 	Ordering            string
+	AddressFamily       string // "" => ipv4
 	Hostname            *HostnameConfig
 	Asn                 *AsnConfig
 	Loopback            *LoopbackConfig
 	Topology            *TopologyConfig
 	P2p                 *P2pConfig
 	DescriptionTemplate *string
-	EnablePhysicalPorts *bool // nil => default true
+	EnablePhysicalPorts *bool                  // nil => default true
+	PortLayouts         map[string]*PortLayout // position -> layout (absent => default profile)
+	Tags                *TagKeysConfig         // nil => the NVIDIA keys
+}
+
+// TagKeysConfig names the device tags that carry the fabric layout: the pod,
+// the scalability unit, the rail group, the spine index and the superspine
+// group. Grouping, ASNs, loopbacks, topology and the built-in hostname
+// templates read them through these keys. Empty fields fall back to the NVIDIA
+// Spectrum-X keys.
+type TagKeysConfig struct {
+	Pod        string
+	Unit       string
+	Rail       string
+	SpineIndex string
+	Group      string
 }
 
 // HostnameConfig holds per-position templates. A position present with a nil
@@ -28,9 +46,10 @@ type AsnConfig struct {
 	SuperSpineStart *int64
 }
 
-// LoopbackConfig sets the pool the loopback /32s are carved from.
+// LoopbackConfig sets the pools the loopback /32s and /128s are carved from.
 type LoopbackConfig struct {
-	Subnet string // "" => defaultLoopbackSubnet
+	Subnet     string // "" => defaultLoopbackSubnet
+	SubnetIpv6 string // "" => defaultLoopbackSubnetIpv6
 }
 
 // TopologyConfig: each non-nil layer enables that layer's pairing.
@@ -57,8 +76,9 @@ type LeafHostConfig struct {
 
 // P2pConfig enables point-to-point link creation over the topology pairs.
 type P2pConfig struct {
-	Pools map[string]string // layer -> CIDR ("" entries fall back to defaults)
-	Mtu   *int32
+	Pools     map[string]string // layer -> CIDR ("" entries fall back to defaults)
+	PoolsIpv6 map[string]string // layer -> IPv6 CIDR for the /127s
+	Mtu       *int32
 }
 
 func (c *Config) ordering() string {
@@ -68,9 +88,66 @@ func (c *Config) ordering() string {
 	return c.Ordering
 }
 
+func (c *Config) addressFamily() string {
+	if c.AddressFamily == "" {
+		return AddressFamilyIpv4
+	}
+	return c.AddressFamily
+}
+
+// ipv4 reports whether the underlay carries IPv4 loopbacks and /31s.
+func (c *Config) ipv4() bool { return c.addressFamily() != AddressFamilyIpv6 }
+
+// ipv6 reports whether the underlay carries IPv6 loopbacks and /127s.
+func (c *Config) ipv6() bool { return c.addressFamily() != AddressFamilyIpv4 }
+
+// TagKeys returns the tag key mapping with the defaults filled in.
+func (c *Config) TagKeys() TagKeysConfig {
+	keys := defaultTagKeys
+	if c.Tags != nil {
+		for _, field := range []struct{ value, target *string }{
+			{&c.Tags.Pod, &keys.Pod},
+			{&c.Tags.Unit, &keys.Unit},
+			{&c.Tags.Rail, &keys.Rail},
+			{&c.Tags.SpineIndex, &keys.SpineIndex},
+			{&c.Tags.Group, &keys.Group},
+		} {
+			if *field.value != "" {
+				*field.target = *field.value
+			}
+		}
+	}
+	return keys
+}
+
+// rewrite points a template written against the default keys (the built-in
+// hostname templates) at the configured ones.
+func (k TagKeysConfig) rewrite(template string) string {
+	if k == defaultTagKeys {
+		return template
+	}
+	return strings.NewReplacer(
+		":"+defaultTagKeys.Pod, ":"+k.Pod,
+		":"+defaultTagKeys.Unit, ":"+k.Unit,
+		":"+defaultTagKeys.Rail, ":"+k.Rail,
+		":"+defaultTagKeys.SpineIndex, ":"+k.SpineIndex,
+		":"+defaultTagKeys.Group, ":"+k.Group,
+	).Replace(template)
+}
+
 func (c *Config) enablePhysicalPorts() bool {
 	if c.EnablePhysicalPorts == nil {
 		return true
 	}
 	return *c.EnablePhysicalPorts
 }
+
+// portLayout returns the port layout of position, falling back to the default
+// profile.
+func (c *Config) portLayout(position string) *PortLayout {
+	if layout, ok := c.PortLayouts[position]; ok && layout != nil {
+		return layout
+	}
+	layout, _ := builtinPortLayout(DefaultPortLayoutProfile, position)
+	return layout
+}
@@ -1,6 +1,7 @@
 package fabric_switch_config
 
-// NVIDIA tags that drive the ASN / loopback / topology sort and grouping.
+// NVIDIA tags that drive the ASN / loopback / topology sort and grouping by
+// default; a config's tags section maps them to other keys.
 const (
 	tagPod        = "nvidia/pod-id"
 	tagSu         = "nvidia/scalability-unit-id"
@@ -9,6 +10,24 @@ const (
 	tagSspGroup   = "nvidia/ssp-group-id"
 )
 
+// Tags the runner writes on the point-to-point subnets it creates. They are
+// not part of the device layout, so a config's tags section does not map them.
+const (
+	tagLinkLayer = "nvidia/link-layer"
+	tagEndpointA = "nvidia/endpoint-a"
+	tagPortA     = "nvidia/port-a"
+	tagEndpointB = "nvidia/endpoint-b"
+	tagPortB     = "nvidia/port-b"
+)
+
+var defaultTagKeys = TagKeysConfig{
+	Pod:        tagPod,
+	Unit:       tagSu,
+	Rail:       tagRail,
+	SpineIndex: tagSpineIndex,
+	Group:      tagSspGroup,
+}
+
 // ASN starting points per role (private 32-bit ASN space), overridable in the
 // config's asn section.
 const (
@@ -20,18 +39,23 @@ const (
 
 const defaultLoopbackSubnet = "10.253.128.0/18"
 
-// Reference port layout. All fabric ports are 2x-breakout sub-ports of a 64-OSFP
-// switch: logical split l in 1..128 maps to swp{(l+1)//2}s{1 - l%2}.
+// Address families of the underlay (loopbacks and p2p subnets).
 const (
-	splitsPerSwitch           = 128
-	leafUplinkLogicalStart    = 65 // swp33s0; leaf splits 1..64 face the hosts
-	spineDownlinkLogicalStart = 1  // swp1s0
-	spineUplinkLogicalStart   = 33 // swp17s0 (3-tier; downlinks get logicals 1..32)
-	sspDownlinkLogicalStart   = 1  // swp1s0
-	// Each spine owns a fixed 64-address run of the spine<->superspine pool.
-	spineSspRunAddresses = 64
+	AddressFamilyIpv4      = "ipv4"
+	AddressFamilyDualStack = "dual-stack"
+	AddressFamilyIpv6      = "ipv6"
 )
 
+var validAddressFamilies = []string{AddressFamilyIpv4, AddressFamilyDualStack, AddressFamilyIpv6}
+
+// IPv6 underlay defaults (ULA space). Loopbacks are /128s carved in the same
+// device order as the IPv4 /32s.
+const defaultLoopbackSubnetIpv6 = "fd00:253::/64"
+
+// Each spine owns a fixed 64-address run of the spine<->superspine pool. The
+// port layouts themselves live in port_layout.go.
+const spineSspRunAddresses = 64
+
 // /31 pool defaults per link layer (reference IPAM; overridable via p2p.pools).
 var defaultPools = map[string]string{
 	"leafSpine":       "10.254.0.0/16",
@@ -47,7 +71,16 @@ var poolMaxPrefixLen = map[string]int{
 	"leafHost":        16,
 }
 
-// nvidia/link-layer tag value per topology layer.
+// /127 pool defaults per link layer (overridable via p2p.poolsIpv6). A /127 sits
+// at the same offset from its pool as the link's /31, so an IPv6 pool needs as
+// many host bits as the IPv4 one.
+var defaultPoolsIpv6 = map[string]string{
+	"leafSpine":       "fd00:254::/64",
+	"spineSuperSpine": "fd00:100:64::/64",
+	"leafHost":        "fd00:172:16::/64",
+}
+
+// tagLinkLayer value per topology layer.
 var layerTagValue = map[string]string{
 	"leafSpine":       "leaf-spine",
 	"spineSuperSpine": "spine-superspine",
@@ -61,10 +94,8 @@ var defaultHostNicNames = []string{
 }
 
 const (
-	defaultHostPortPattern       = "swp{port}s{sub}"
 	defaultHostDescTemplate      = "to_hgx-su{su:02d}-h{node:02d}_{nic}"
 	defaultHostDescTemplate3Tier = "to_hgx-pod{pod:02d}-su{su:02d}-h{node:02d}_{nic}"
-	defaultHostNodeCount         = 32
 )
 
 // PendingDescription is stamped on physical ports for which no description rule
@@ -12,13 +12,31 @@ const exampleConfigYAML = `# Fabric switch configuration for 'metalcloud-cli fab
 #
 # Device roles are taken from each device's 'position' (leaf | spine |
 # super_spine). A fabric is 3-tier if it has super_spine devices. Most steps are
-# driven by NVIDIA tags on the devices: nvidia/pod-id, nvidia/scalability-unit-id,
-# nvidia/rail-group-id, nvidia/spine-index, nvidia/ssp-group-id.
+# driven by tags on the devices, by default the NVIDIA ones: nvidia/pod-id,
+# nvidia/scalability-unit-id, nvidia/rail-group-id, nvidia/spine-index,
+# nvidia/ssp-group-id.
+
+# Device tag keys of the fabric layout (optional; defaults shown). Map them to
+# your own tagging convention to use the engine on any leaf/spine Clos fabric.
+# The built-in hostname templates follow the mapping.
+#   pod         pod id (3-tier)             unit   scalability unit id
+#   rail        rail group id               spineIndex  spine index
+#   group       superspine group id (3-tier; matches the spine index)
+tags:
+  pod: nvidia/pod-id
+  unit: nvidia/scalability-unit-id
+  rail: nvidia/rail-group-id
+  spineIndex: nvidia/spine-index
+  group: nvidia/ssp-group-id
 
 # Stable order defining each device's 1-based ordinal within its position group.
 # One of: managementAddress (default) | identifierString | id.
 ordering: managementAddress
 
+# Underlay address family: ipv4 (default) | dual-stack | ipv6. It selects which
+# loopbacks (/32, /128) and p2p subnets (/31, /127) are allocated and written.
+addressFamily: ipv4
+
 # Hostname (identifierString) computation. The presence of this section enables
 # it; positions not set here use the built-in reference templates for the
 # detected tier. Set a position to null to skip it. Placeholders:
@@ -44,34 +62,60 @@ asn:
   spineStart: 4201000000
   superSpineStart: 4202000000
 
-# Loopback IPv4 allocation (presence enables it; subnet optional). Addresses are
-# allocated incrementally from the start (first device gets .1): leaves, then
-# spines, then superspines. Written both as a /32 on the loopback interface and
-# into the device-level loopbackAddress field.
+# Loopback allocation (presence enables it; subnets optional). Addresses are
+# allocated incrementally from the start (first device gets .1 / ::1): leaves,
+# then spines, then superspines. Written both as a /32 (/128) on the loopback
+# interface and into the device-level loopbackAddress (loopbackAddressIpv6)
+# field. subnetIpv6 is used by the dual-stack and ipv6 address families.
 loopback:
   subnet: 10.253.128.0/18
+  subnetIpv6: fd00:253::/64
 
 # Set enabled: true on the staged config of every physical port (default true).
 enablePhysicalPorts: true
 
+# Switch port model. Either one built-in profile for every position
+# (sn5600-2x400g - the reference default -, sn5600-800g, sn4700-400g) or, per
+# position (leaf | spine | super_spine), a profile name or a layout overriding
+# the profile's fields. Ports are addressed by logical split: 1-based across all
+# breakout sub-ports. Downlinks face the tier below (hosts for leaves), uplinks
+# the tier above; an open range (no end) runs up to the uplinks or the last
+# split. portPattern placeholders: {port}, {sub}, {logical}.
+portLayouts: sn5600-2x400g
+# portLayouts:
+#   leaf: sn5600-800g
+#   spine:
+#     profile: sn5600-2x400g      # base the overrides apply to (default shown)
+#     ports: 64
+#     breakout: 2
+#     portPattern: "swp{port}s{sub}"
+#     firstSub: 0                 # number of the first sub-port
+#     downlinks: {start: 1}       # 3-tier: up to the uplinks; 2-tier: all splits
+#     uplinks: {start: 33, end: 64}
+
 # Cabling topology - the shared input for both interface descriptions and
-# point-to-point link creation. Ports are derived from the reference block-port
-# model; the only per-layer knob is linksPerPair ('auto' or a positive integer).
+# point-to-point link creation. Ports are derived from the block-port model of
+# the port layouts; the only per-layer knob is linksPerPair ('auto' or a
+# positive integer).
 topology:
   # Leaf <-> spine. 3-tier: a leaf uplinks only to its own (pod, rail) spines;
-  # 2-tier: full mesh. auto L = 32 // susPerPod (3-tier) or 128 // leafCount.
+  # 2-tier: full mesh. auto L = spine downlinks // susPerPod (3-tier)
+  # or // leafCount (2-tier): 32 // susPerPod or 128 // leafCount by default.
   leafSpine:
     linksPerPair: auto
   # Spine <-> superspine (3-TIER ONLY). A spine with spine-index S connects to
-  # all superspines of ssp-group S. auto L = 32 // sspsPerGroup (uniform groups).
+  # all superspines of ssp-group S. auto L = spine uplinks // sspsPerGroup
+  # (32 // sspsPerGroup by default; uniform groups).
   spineSuperSpine:
     linksPerPair: auto
   # Leaf -> host (HGX node) downlinks. All keys optional; defaults shown.
   leafHost:
-    nodeCount: 32                 # number of host port-pairs per leaf
+    nodeCount: 32                 # number of host port-pairs per leaf (default:
+    #                             # all the leaf layout's downlink pairs)
     # nodes: [0, 8, 16, 24]       # OR the exact 0-based node indices (mutually
     #                             # exclusive with nodeCount)
-    portPattern: "swp{port}s{sub}"
+    # portPattern: "swp{port}s{sub}"  # overrides the leaf layout's names
+    #                                 # ({port} = node + 1, {sub} = 0 | 1)
     nicNames: [enp26s0f0np0, enp60s0f0np0, enp77s0f0np0, enp94s0f0np0,
                enp156s0f0np0, enp188s0f0np0, enp204s0f0np0, enp220s0f0np0]
     # descriptionTemplate: "to_hgx-su{su:02d}-h{node:02d}_{nic}"   # tier-aware default
@@ -84,12 +128,18 @@ descriptionTemplate: "to_{peerHostname}_{peerPort}"
 
 # Point-to-point link creation over the topology pairs (requires topology). Each
 # link gets a deterministic /31 from its layer's pool, registered as a tagged
-# IPAM subnet and attached as a manual allocation strategy.
+# IPAM subnet and attached as a manual allocation strategy. With IPv6 enabled
+# (addressFamily) each link also gets the /127 at the same offset of its layer's
+# IPv6 pool.
 p2p:
   pools:                          # all optional; reference defaults shown
     leafSpine: 10.254.0.0/16
     spineSuperSpine: 100.64.0.0/10
     leafHost: 172.16.0.0/12
+  poolsIpv6:                      # all optional; at least /120, /122, /112
+    leafSpine: fd00:254::/64
+    spineSuperSpine: fd00:100:64::/64
+    leafHost: fd00:172:16::/64
   mtu: 9216                       # optional; applied to created links
 `
 
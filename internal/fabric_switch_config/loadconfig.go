@@ -4,15 +4,13 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// maxHostNodes is the number of leaf swpNs0/s1 pairs below the uplink block.
-const maxHostNodes = (leafUplinkLogicalStart - 1) / 2 // 32
-
 // rawConfig is the YAML shape. Pointer / map fields let us distinguish an absent
 // section (nil) from a present-but-empty one (non-nil), which is what enables a
 // feature ("asn: {}" => ASNs with defaults). Unknown top-level keys (api,
 // freeform, bgp, ...) used by the sibling scripts are ignored.
 type rawConfig struct {
 	Ordering            *string                `yaml:"ordering"`
+	AddressFamily       *string                `yaml:"addressFamily"`
 	Hostname            map[string]*string     `yaml:"hostname"`
 	Asn                 map[string]*int64      `yaml:"asn"`
 	Loopback            map[string]interface{} `yaml:"loopback"`
@@ -20,6 +18,8 @@ type rawConfig struct {
 	P2p                 *rawP2p                `yaml:"p2p"`
 	DescriptionTemplate *string                `yaml:"descriptionTemplate"`
 	EnablePhysicalPorts *bool                  `yaml:"enablePhysicalPorts"`
+	PortLayouts         yaml.Node              `yaml:"portLayouts"`
+	Tags                map[string]*string     `yaml:"tags"`
 }
 
 type rawTopology struct {
@@ -44,9 +44,10 @@ type rawLeafHost struct {
 }
 
 type rawP2p struct {
-	Pools    map[string]string `yaml:"pools"`
-	Mtu      *int32            `yaml:"mtu"`
-	Supernet interface{}       `yaml:"supernet"`
+	Pools     map[string]string `yaml:"pools"`
+	PoolsIpv6 map[string]string `yaml:"poolsIpv6"`
+	Mtu       *int32            `yaml:"mtu"`
+	Supernet  interface{}       `yaml:"supernet"`
 }
 
 // LoadConfig parses and validates a fabric-switch configuration from YAML/JSON
@@ -72,6 +73,22 @@ func LoadConfig(data []byte) (*Config, error) {
 		return nil, configErrorf("ordering must be one of %v, got %q", validOrderings, config.Ordering)
 	}
 
+	config.AddressFamily = AddressFamilyIpv4
+	if raw.AddressFamily != nil {
+		config.AddressFamily = *raw.AddressFamily
+	}
+	if !containsString(validAddressFamilies, config.AddressFamily) {
+		return nil, configErrorf("addressFamily must be one of %v, got %q", validAddressFamilies, config.AddressFamily)
+	}
+
+	if raw.Tags != nil {
+		tags, err := buildTagKeys(raw.Tags)
+		if err != nil {
+			return nil, err
+		}
+		config.Tags = tags
+	}
+
 	if raw.Hostname != nil {
 		config.Hostname = &HostnameConfig{Templates: raw.Hostname}
 	}
@@ -92,8 +109,16 @@ func LoadConfig(data []byte) (*Config, error) {
 		config.Loopback = loopback
 	}
 
+	if raw.PortLayouts.Kind != 0 && raw.PortLayouts.Tag != "!!null" {
+		layouts, err := buildPortLayouts(&raw.PortLayouts)
+		if err != nil {
+			return nil, err
+		}
+		config.PortLayouts = layouts
+	}
+
 	if raw.Topology != nil {
-		topo, err := buildTopology(raw.Topology)
+		topo, err := buildTopology(raw.Topology, config.portLayout("leaf"))
 		if err != nil {
 			return nil, err
 		}
@@ -144,19 +169,51 @@ func buildAsn(raw map[string]*int64) (*AsnConfig, error) {
 	return asn, nil
 }
 
+func buildTagKeys(raw map[string]*string) (*TagKeysConfig, error) {
+	tags := &TagKeysConfig{}
+	targets := map[string]*string{
+		"pod": &tags.Pod, "unit": &tags.Unit, "rail": &tags.Rail,
+		"spineIndex": &tags.SpineIndex, "group": &tags.Group,
+	}
+	for key, value := range raw {
+		target, ok := targets[key]
+		if !ok {
+			return nil, configErrorf("unknown tags key %q; allowed: [pod unit rail spineIndex group]", key)
+		}
+		if value == nil || *value == "" {
+			return nil, configErrorf("tags.%s must be a non-empty tag key", key)
+		}
+		*target = *value
+	}
+	seen := map[string]string{}
+	for _, key := range []string{"pod", "unit", "rail", "spineIndex", "group"} {
+		value := *targets[key]
+		if value == "" {
+			continue
+		}
+		if other, ok := seen[value]; ok {
+			return nil, configErrorf("tags.%s and tags.%s both map to %q", other, key, value)
+		}
+		seen[value] = key
+	}
+	return tags, nil
+}
+
 func buildLoopback(raw map[string]interface{}) (*LoopbackConfig, error) {
 	for key := range raw {
-		if key != "subnet" {
-			return nil, configErrorf("unknown loopback key %q; allowed: [subnet]", key)
+		if key != "subnet" && key != "subnetIpv6" {
+			return nil, configErrorf("unknown loopback key %q; allowed: [subnet subnetIpv6]", key)
 		}
 	}
 	loopback := &LoopbackConfig{}
-	if v, ok := raw["subnet"]; ok && v != nil {
-		s, ok := v.(string)
-		if !ok {
-			return nil, configErrorf("loopback.subnet must be a string")
+	for key, target := range map[string]*string{"subnet": &loopback.Subnet, "subnetIpv6": &loopback.SubnetIpv6} {
+		if v, ok := raw[key]; ok && v != nil {
+			s, ok := v.(string)
+			if !ok {
+				return nil, configErrorf("loopback.%s must be a string", key)
+			}
+			*target = s
 		}
-		loopback.Subnet = s
 	}
 	subnet := loopback.Subnet
 	if subnet == "" {
@@ -169,10 +226,21 @@ func buildLoopback(raw map[string]interface{}) (*LoopbackConfig, error) {
 	if network.prefixLen > 30 {
 		return nil, configErrorf("loopback.subnet %s is too small to allocate addresses from", subnet)
 	}
+	subnetIpv6 := loopback.SubnetIpv6
+	if subnetIpv6 == "" {
+		subnetIpv6 = defaultLoopbackSubnetIpv6
+	}
+	networkIpv6, err := parseIpv6Network(subnetIpv6)
+	if err != nil {
+		return nil, configErrorf("loopback.subnetIpv6: invalid network %q: %s", subnetIpv6, err.Error())
+	}
+	if networkIpv6.prefixLen() > 120 {
+		return nil, configErrorf("loopback.subnetIpv6 %s is too small to allocate addresses from", subnetIpv6)
+	}
 	return loopback, nil
 }
 
-func buildTopology(raw *rawTopology) (*TopologyConfig, error) {
+func buildTopology(raw *rawTopology, leafLayout *PortLayout) (*TopologyConfig, error) {
 	topo := &TopologyConfig{}
 	var err error
 	if raw.LeafSpine != nil {
@@ -186,7 +254,7 @@ func buildTopology(raw *rawTopology) (*TopologyConfig, error) {
 		}
 	}
 	if raw.LeafHost != nil {
-		if topo.LeafHost, err = buildLeafHost(raw.LeafHost); err != nil {
+		if topo.LeafHost, err = buildLeafHost(raw.LeafHost, leafLayout.hostNodeCapacity()); err != nil {
 			return nil, err
 		}
 	}
@@ -220,7 +288,9 @@ func buildLayer(raw *rawLayer, name string) (*LayerConfig, error) {
 	return layer, nil
 }
 
-func buildLeafHost(raw *rawLeafHost) (*LeafHostConfig, error) {
+// buildLeafHost validates the leafHost section; maxHostNodes is the number of
+// host port pairs of the leaf port layout.
+func buildLeafHost(raw *rawLeafHost, maxHostNodes int) (*LeafHostConfig, error) {
 	lh := &LeafHostConfig{
 		PortPattern:         raw.PortPattern,
 		DescriptionTemplate: raw.DescriptionTemplate,
@@ -279,6 +349,14 @@ func buildP2p(raw *rawP2p, topo *TopologyConfig) (*P2pConfig, error) {
 		}
 		p2p.Pools = raw.Pools
 	}
+	if raw.PoolsIpv6 != nil {
+		for layer := range raw.PoolsIpv6 {
+			if _, ok := defaultPoolsIpv6[layer]; !ok {
+				return nil, configErrorf("unknown p2p.poolsIpv6 key %q; allowed: [leafHost leafSpine spineSuperSpine]", layer)
+			}
+		}
+		p2p.PoolsIpv6 = raw.PoolsIpv6
+	}
 	// Validate each pool (configured or default) is an aligned IPv4 network.
 	for layer, def := range defaultPools {
 		raw := def
@@ -295,6 +373,22 @@ func buildP2p(raw *rawP2p, topo *TopologyConfig) (*P2pConfig, error) {
 				layer, poolMaxPrefixLen[layer], layerTagValue[layer], raw)
 		}
 	}
+	// The /127s use the /31 offsets, so the IPv6 pools need the same host bits.
+	for layer, def := range defaultPoolsIpv6 {
+		raw := def
+		if v, ok := p2p.PoolsIpv6[layer]; ok && v != "" {
+			raw = v
+		}
+		pool, err := parseIpv6Network(raw)
+		if err != nil {
+			return nil, configErrorf("p2p.poolsIpv6.%s: invalid network %q: %s", layer, raw, err.Error())
+		}
+		if maxPrefixLen := 128 - (32 - poolMaxPrefixLen[layer]); pool.prefixLen() > maxPrefixLen {
+			return nil, configErrorf(
+				"p2p.poolsIpv6.%s must be /%d or larger (the %s /127s use the /31 offsets), got %q",
+				layer, maxPrefixLen, layerTagValue[layer], raw)
+		}
+	}
 	return p2p, nil
 }
 
@@ -72,6 +72,14 @@ func TestLoadConfigValidationErrors(t *testing.T) {
 		{"p2p without topology", "p2p:\n  mtu: 9000\n", "requires a 'topology'"},
 		{"nodes and nodeCount", "topology:\n  leafHost:\n    nodeCount: 2\n    nodes: [0,1]\n", "mutually exclusive"},
 		{"nothing to do", "enablePhysicalPorts: false\n", "nothing to do"},
+		{"bad address family", "addressFamily: ipv5\nasn: {}\n", "addressFamily must be one of"},
+		{"ipv4 loopback for ipv6", "loopback:\n  subnetIpv6: 10.0.0.0/24\n", "not an IPv6 network"},
+		{"ipv6 loopback too small", "loopback:\n  subnetIpv6: fd00::/124\n", "too small"},
+		{"unknown ipv6 pool", "topology:\n  leafSpine: {}\np2p:\n  poolsIpv6:\n    border: fd00::/64\n", "unknown p2p.poolsIpv6 key"},
+		{"unknown tags key", "tags:\n  row: site/row\nasn: {}\n", "unknown tags key"},
+		{"empty tag key", "tags:\n  pod: \"\"\nasn: {}\n", "non-empty tag key"},
+		{"duplicate tag key", "tags:\n  pod: site/x\n  unit: site/x\nasn: {}\n", "both map to"},
+		{"ipv6 pool too small", "topology:\n  leafSpine: {}\np2p:\n  poolsIpv6:\n    leafHost: fd00::/120\n", "must be /112 or larger"},
 	}
 	for _, c := range cases {
 		_, err := LoadConfig([]byte(c.yaml))
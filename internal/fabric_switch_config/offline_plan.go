@@ -0,0 +1,208 @@
+package fabric_switch_config
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rawInventoryDevice is one device of a devices file. The keys are those of an
+// import-devices switch entry, so the same file can describe a fabric before
+// it is racked and import it afterwards; the other keys of an entry are ignored.
+type rawInventoryDevice struct {
+	Id                *int64                 `yaml:"id"`
+	Position          string                 `yaml:"position"`
+	ManagementAddress string                 `yaml:"managementAddress"`
+	IdentifierString  string                 `yaml:"identifierString"`
+	Driver            string                 `yaml:"driver"`
+	TagsMap           map[string]interface{} `yaml:"tagsMap"`
+}
+
+// rawInventory is the import-devices document shape: defaults merged into each
+// of the switches (per-switch keys win, tagsMap merged key by key).
+type rawInventory struct {
+	Defaults rawInventoryDevice   `yaml:"defaults"`
+	Switches []rawInventoryDevice `yaml:"switches"`
+}
+
+// LoadDevicesFile parses a device inventory from YAML/JSON bytes: either a
+// list of devices or an import-devices document ({defaults, switches}). Every
+// device needs a position; devices without an id are numbered from 1 in file
+// order. Scalar tag values are taken as strings.
+func LoadDevicesFile(data []byte) ([]*Device, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, configErrorf("invalid devices file: %s", err.Error())
+	}
+	if len(node.Content) == 0 {
+		return nil, configErrorf("the devices file is empty")
+	}
+
+	var entries []rawInventoryDevice
+	if node.Content[0].Kind == yaml.SequenceNode {
+		if err := node.Decode(&entries); err != nil {
+			return nil, configErrorf("invalid devices file: %s", err.Error())
+		}
+	} else {
+		var doc rawInventory
+		if err := node.Decode(&doc); err != nil {
+			return nil, configErrorf("invalid devices file: %s", err.Error())
+		}
+		for _, entry := range doc.Switches {
+			entries = append(entries, doc.Defaults.mergedInto(entry))
+		}
+	}
+	if len(entries) == 0 {
+		return nil, configErrorf("the devices file lists no devices; expected a list or a 'switches' list")
+	}
+
+	devices := make([]*Device, 0, len(entries))
+	seen := map[int64]int{}
+	for i, entry := range entries {
+		if entry.Position == "" {
+			return nil, configErrorf("devices[%d]: position is required", i)
+		}
+		id := int64(i + 1)
+		if entry.Id != nil {
+			id = *entry.Id
+		}
+		if prev, ok := seen[id]; ok {
+			return nil, configErrorf("devices[%d] and devices[%d] both have id %d", prev, i, id)
+		}
+		seen[id] = i
+
+		tags := map[string]string{}
+		for key, value := range entry.TagsMap {
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				return nil, configErrorf("devices[%d]: tagsMap.%s must be a scalar value", i, key)
+			case nil:
+				tags[key] = ""
+			default:
+				tags[key] = fmt.Sprint(value)
+			}
+		}
+		devices = append(devices, &Device{
+			Id:                id,
+			Position:          entry.Position,
+			ManagementAddress: entry.ManagementAddress,
+			IdentifierString:  entry.IdentifierString,
+			Driver:            entry.Driver,
+			TagsMap:           tags,
+		})
+	}
+	return devices, nil
+}
+
+func (d rawInventoryDevice) mergedInto(entry rawInventoryDevice) rawInventoryDevice {
+	if entry.Position == "" {
+		entry.Position = d.Position
+	}
+	if entry.ManagementAddress == "" {
+		entry.ManagementAddress = d.ManagementAddress
+	}
+	if entry.IdentifierString == "" {
+		entry.IdentifierString = d.IdentifierString
+	}
+	if entry.Driver == "" {
+		entry.Driver = d.Driver
+	}
+	tags := map[string]interface{}{}
+	for key, value := range d.TagsMap {
+		tags[key] = value
+	}
+	for key, value := range entry.TagsMap {
+		tags[key] = value
+	}
+	entry.TagsMap = tags
+	return entry
+}
+
+// Plan is the full desired state for a set of devices, laid out for review:
+// what configure-switches would set on every device and port, and every cable
+// with its /31 (/127). Groups and State are the computed plan itself, for the
+// layers built on it.
+type Plan struct {
+	Devices          []PlannedDevice          `json:"devices"`
+	Cables           []Cable                  `json:"cables"`
+	PortDescriptions []PlannedPortDescription `json:"portDescriptions"`
+	Warnings         []string                 `json:"warnings,omitempty"`
+
+	Groups map[string][]*Device `json:"-"`
+	State  *DesiredState        `json:"-"`
+}
+
+// PlannedDevice is a device and the fields the plan sets on it; a field the
+// configuration does not enable is empty.
+type PlannedDevice struct {
+	Id                int64  `json:"id"`
+	Position          string `json:"position"`
+	ManagementAddress string `json:"managementAddress,omitempty"`
+	IdentifierString  string `json:"identifierString,omitempty"`
+	Hostname          string `json:"hostname,omitempty"`
+	Asn               int64  `json:"asn,omitempty"`
+	Loopback          string `json:"loopback,omitempty"`
+	LoopbackIpv6      string `json:"loopbackIpv6,omitempty"`
+}
+
+// PlannedPortDescription is the description the plan sets on a port.
+type PlannedPortDescription struct {
+	Device      string `json:"device"`
+	Port        string `json:"port"`
+	Description string `json:"description"`
+}
+
+// ComputePlan computes the plan of config for devices without any API access:
+// nothing is read from, or written to, a fabric.
+func ComputePlan(config *Config, devices []*Device) (*Plan, error) {
+	groups, err := GroupAndOrder(devices, config.ordering())
+	if err != nil {
+		return nil, err
+	}
+	state, err := ComputeDesired(config, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Cables:           buildCablingPlan(&FabricInfo{}, groups, state).Cables,
+		PortDescriptions: []PlannedPortDescription{},
+		Warnings:         state.Warnings,
+		Groups:           groups,
+		State:            state,
+	}
+	byId := map[int64]*Device{}
+	for _, position := range sortedGroupKeys(groups) {
+		for _, dev := range groups[position] {
+			byId[dev.Id] = dev
+			planned := PlannedDevice{Id: dev.Id, Position: dev.Position,
+				ManagementAddress: dev.ManagementAddress, IdentifierString: dev.IdentifierString}
+			if desired := state.ByDevice[dev.Id]; desired != nil {
+				planned.Hostname = derefString(desired.Hostname)
+				planned.Loopback = derefString(desired.LoopbackIp)
+				planned.LoopbackIpv6 = derefString(desired.LoopbackIpv6)
+				if desired.Asn != nil {
+					planned.Asn = *desired.Asn
+				}
+			}
+			plan.Devices = append(plan.Devices, planned)
+		}
+	}
+
+	for key, description := range state.PortDescriptions {
+		plan.PortDescriptions = append(plan.PortDescriptions, PlannedPortDescription{
+			Device:      endpointName(state, byId[key.DeviceId]),
+			Port:        key.PortName,
+			Description: description,
+		})
+	}
+	sort.Slice(plan.PortDescriptions, func(i, j int) bool {
+		a, b := plan.PortDescriptions[i], plan.PortDescriptions[j]
+		if a.Device != b.Device {
+			return a.Device < b.Device
+		}
+		return a.Port < b.Port
+	})
+	return plan, nil
+}
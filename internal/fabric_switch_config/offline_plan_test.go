@@ -0,0 +1,91 @@
+package fabric_switch_config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadDevicesFile(t *testing.T) {
+	list := []byte(`
+- position: leaf
+  managementAddress: 10.0.0.11
+  identifierString: leaf-a
+  tagsMap: {nvidia/pod-id: 5, nvidia/rail-group-id: "1"}
+- id: 40
+  position: spine
+  managementAddress: 10.0.0.21
+`)
+	devices, err := LoadDevicesFile(list)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	want := &Device{Id: 1, Position: "leaf", ManagementAddress: "10.0.0.11", IdentifierString: "leaf-a",
+		TagsMap: map[string]string{"nvidia/pod-id": "5", "nvidia/rail-group-id": "1"}}
+	if len(devices) != 2 || !reflect.DeepEqual(devices[0], want) || devices[1].Id != 40 {
+		t.Errorf("list devices = %+v, %+v", devices[0], devices[1])
+	}
+
+	// The import-devices shape: defaults merged under each switch.
+	imported := []byte(`{
+  "defaults": {"driver": "cumulus_linux", "username": "admin", "tagsMap": {"nvidia/pod-id": "5"}},
+  "switches": [
+    {"position": "leaf", "managementAddress": "10.0.0.11", "tagsMap": {"nvidia/rail-group-id": "2"}},
+    {"position": "spine", "managementAddress": "10.0.0.21", "driver": "sonic_enterprise"}
+  ]
+}`)
+	devices, err = LoadDevicesFile(imported)
+	if err != nil {
+		t.Fatalf("import document: %v", err)
+	}
+	if len(devices) != 2 || devices[0].Driver != "cumulus_linux" || devices[1].Driver != "sonic_enterprise" {
+		t.Fatalf("import devices = %+v", devices)
+	}
+	if tags := devices[0].TagsMap; tags["nvidia/pod-id"] != "5" || tags["nvidia/rail-group-id"] != "2" {
+		t.Errorf("merged tags = %v", tags)
+	}
+
+	for name, tc := range map[string]struct{ data, want string }{
+		"empty":        {"", "empty"},
+		"no devices":   {"switches: []", "no devices"},
+		"no position":  {"- managementAddress: 10.0.0.1", "position is required"},
+		"duplicate id": {"- {id: 3, position: leaf}\n- {id: 3, position: spine}", "both have id 3"},
+		"nested tag":   {"- {position: leaf, tagsMap: {a: {b: c}}}", "tagsMap.a"},
+	} {
+		if _, err := LoadDevicesFile([]byte(tc.data)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want it to mention %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestComputePlan(t *testing.T) {
+	plan, err := ComputePlan(fixtureConfig(), fixtureDevices())
+	if err != nil {
+		t.Fatalf("ComputePlan: %v", err)
+	}
+	state := computeFixture(t)
+
+	if len(plan.Devices) != 8 {
+		t.Fatalf("devices = %d, want 8", len(plan.Devices))
+	}
+	for _, d := range plan.Devices {
+		desired := state.ByDevice[d.Id]
+		if d.Hostname != *desired.Hostname || d.Asn != *desired.Asn || d.Loopback != *desired.LoopbackIp {
+			t.Errorf("device %d = %+v, want the computed hostname, asn and loopback", d.Id, d)
+		}
+	}
+	if got, want := len(plan.Cables), len(state.Links)+len(state.HostLinks); got != want {
+		t.Errorf("cables = %d, want %d", got, want)
+	}
+	for _, c := range plan.Cables {
+		if c.Subnet == "" {
+			t.Errorf("cable %s has no /31", c.Id)
+		}
+	}
+	if len(plan.PortDescriptions) != len(state.PortDescriptions) {
+		t.Errorf("port descriptions = %d, want %d", len(plan.PortDescriptions), len(state.PortDescriptions))
+	}
+	if d := plan.PortDescriptions[0]; d.Description == "" || d.Device == "" || d.Port == "" {
+		t.Errorf("first port description = %+v", d)
+	}
+}
@@ -0,0 +1,336 @@
+package fabric_switch_config
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PortLayout is the port model of the switches of one position. Fabric ports
+// are addressed by logical split: 1-based across all breakout sub-ports, so
+// split l lives on port (l-1)/Breakout+1, sub-port (l-1)%Breakout+FirstSub.
+// Downlinks face the tier below (hosts for leaves), uplinks the tier above.
+type PortLayout struct {
+	Profile     string // built-in profile the layout derives from ("" => custom)
+	Ports       int
+	Breakout    int
+	PortPattern string // placeholders {port}, {sub}, {logical}
+	FirstSub    int
+	Downlinks   PortRange
+	Uplinks     *PortRange // nil => the position has no uplinks
+}
+
+// PortRange is a run of logical splits. End 0 means open: up to the start of
+// the uplinks when they are in use, otherwise up to the last split.
+type PortRange struct {
+	Start int
+	End   int
+}
+
+// DefaultPortLayoutProfile is the reference port model used for positions
+// without a configured layout.
+const DefaultPortLayoutProfile = "sn5600-2x400g"
+
+// portLayoutPositions are the positions a port layout can be configured for.
+var portLayoutPositions = []string{"leaf", "spine", "super_spine"}
+
+// builtinPortLayouts are the named profiles, keyed by profile -> position.
+var builtinPortLayouts = map[string]map[string]PortLayout{
+	// The reference model: 64-OSFP switches, every port split 2x. Leaf splits
+	// 1..64 face the hosts (swp1s0..swp32s1) and 65..128 the spines; 3-tier
+	// spines use 1..32 towards the leaves and 33..64 towards the superspines.
+	"sn5600-2x400g": {
+		"leaf":        {Ports: 64, Breakout: 2, PortPattern: "swp{port}s{sub}", Downlinks: PortRange{1, 64}, Uplinks: &PortRange{65, 0}},
+		"spine":       {Ports: 64, Breakout: 2, PortPattern: "swp{port}s{sub}", Downlinks: PortRange{1, 0}, Uplinks: &PortRange{33, 64}},
+		"super_spine": {Ports: 64, Breakout: 2, PortPattern: "swp{port}s{sub}", Downlinks: PortRange{1, 0}},
+	},
+	// The same switches without breakout.
+	"sn5600-800g": {
+		"leaf":        {Ports: 64, Breakout: 1, PortPattern: "swp{port}", Downlinks: PortRange{1, 32}, Uplinks: &PortRange{33, 0}},
+		"spine":       {Ports: 64, Breakout: 1, PortPattern: "swp{port}", Downlinks: PortRange{1, 0}, Uplinks: &PortRange{33, 64}},
+		"super_spine": {Ports: 64, Breakout: 1, PortPattern: "swp{port}", Downlinks: PortRange{1, 0}},
+	},
+	// 32-port QSFP switches without breakout.
+	"sn4700-400g": {
+		"leaf":        {Ports: 32, Breakout: 1, PortPattern: "swp{port}", Downlinks: PortRange{1, 16}, Uplinks: &PortRange{17, 0}},
+		"spine":       {Ports: 32, Breakout: 1, PortPattern: "swp{port}", Downlinks: PortRange{1, 0}, Uplinks: &PortRange{17, 32}},
+		"super_spine": {Ports: 32, Breakout: 1, PortPattern: "swp{port}", Downlinks: PortRange{1, 0}},
+	},
+}
+
+// PortLayoutProfiles returns the names of the built-in port layout profiles.
+func PortLayoutProfiles() []string {
+	names := make([]string, 0, len(builtinPortLayouts))
+	for name := range builtinPortLayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// builtinPortLayout returns a copy of a built-in profile's layout for position.
+func builtinPortLayout(profile, position string) (*PortLayout, error) {
+	layouts, ok := builtinPortLayouts[profile]
+	if !ok {
+		return nil, configErrorf("unknown port layout profile %q; built-in profiles: %v", profile, PortLayoutProfiles())
+	}
+	layout := layouts[position]
+	layout.Profile = profile
+	if layout.Uplinks != nil {
+		uplinks := *layout.Uplinks
+		layout.Uplinks = &uplinks
+	}
+	return &layout, nil
+}
+
+// splits returns the number of logical splits of the switch.
+func (l *PortLayout) splits() int {
+	return l.Ports * l.Breakout
+}
+
+// portName returns the port name of a logical split (1-based).
+func (l *PortLayout) portName(logical int) string {
+	name, _ := expandTemplate(l.PortPattern, nil, nil, map[string]any{
+		"port":    (logical-1)/l.Breakout + 1,
+		"sub":     (logical-1)%l.Breakout + l.FirstSub,
+		"logical": logical,
+	})
+	return name
+}
+
+// downlinkEnd returns the last downlink split. An open range stops below the
+// uplinks when they are in use (e.g. the spines of a 3-tier fabric).
+func (l *PortLayout) downlinkEnd(uplinksInUse bool) int {
+	if l.Downlinks.End != 0 {
+		return l.Downlinks.End
+	}
+	if uplinksInUse && l.Uplinks != nil && l.Uplinks.Start > l.Downlinks.Start {
+		return l.Uplinks.Start - 1
+	}
+	return l.splits()
+}
+
+// downlinkCount returns the number of downlink splits.
+func (l *PortLayout) downlinkCount(uplinksInUse bool) int {
+	return l.downlinkEnd(uplinksInUse) - l.Downlinks.Start + 1
+}
+
+// uplinkCount returns the number of uplink splits (0 without uplinks).
+func (l *PortLayout) uplinkCount() int {
+	if l.Uplinks == nil {
+		return 0
+	}
+	end := l.Uplinks.End
+	if end == 0 {
+		end = l.splits()
+	}
+	return end - l.Uplinks.Start + 1
+}
+
+// hostNodeCapacity returns the number of host nodes a leaf can serve: each node
+// takes two consecutive downlink splits (s0/s1 of the reference model).
+func (l *PortLayout) hostNodeCapacity() int {
+	return l.downlinkCount(true) / 2
+}
+
+// hostPortName returns the name of the leaf port serving sub-link sub (0 or 1)
+// of host node (0-based).
+func (l *PortLayout) hostPortName(node, sub int) string {
+	return l.portName(l.Downlinks.Start + 2*node + sub)
+}
+
+// describe returns a short summary of the layout for messages.
+func (l *PortLayout) describe() string {
+	name := l.Profile
+	if name == "" {
+		name = "custom"
+	}
+	return name + " (" + itoa(int64(l.Ports)) + " ports x" + itoa(int64(l.Breakout)) + ", " + l.PortPattern + ")"
+}
+
+// validate checks the layout of position against the model and the /31
+// formulas, which address a fixed number of links per device.
+func (l *PortLayout) validate(position string) error {
+	prefix := "portLayouts." + position
+	if l.Ports < 1 {
+		return configErrorf("%s.ports must be a positive integer", prefix)
+	}
+	if l.Breakout < 1 {
+		return configErrorf("%s.breakout must be a positive integer", prefix)
+	}
+	if l.PortPattern == "" {
+		return configErrorf("%s.portPattern must not be empty", prefix)
+	}
+	values := map[string]any{"port": 1, "sub": l.FirstSub, "logical": 1}
+	if _, err := expandTemplate(l.PortPattern, nil, nil, values); err != nil {
+		return configErrorf("%s.portPattern: %s", prefix, err.Error())
+	}
+	if l.Breakout > 1 && !strings.Contains(l.PortPattern, "{sub") && !strings.Contains(l.PortPattern, "{logical") {
+		return configErrorf("%s.portPattern must contain {sub} or {logical} with a breakout of %d", prefix, l.Breakout)
+	}
+
+	splits := l.splits()
+	checkRange := func(name string, r PortRange) error {
+		if r.Start < 1 || r.Start > splits || r.End < 0 || r.End > splits || (r.End != 0 && r.End < r.Start) {
+			return configErrorf("%s.%s must be a range of logical splits within [1, %d]", prefix, name, splits)
+		}
+		return nil
+	}
+	if err := checkRange("downlinks", l.Downlinks); err != nil {
+		return err
+	}
+	if l.Uplinks != nil {
+		if err := checkRange("uplinks", *l.Uplinks); err != nil {
+			return err
+		}
+		downStart, downEnd := l.Downlinks.Start, l.downlinkEnd(true)
+		upStart, upEnd := l.Uplinks.Start, l.Uplinks.Start+l.uplinkCount()-1
+		if downStart <= upEnd && upStart <= downEnd {
+			return configErrorf("%s.downlinks and %s.uplinks overlap", prefix, prefix)
+		}
+	}
+
+	switch position {
+	case "leaf":
+		if l.Uplinks == nil {
+			return configErrorf("%s.uplinks is required (leaves uplink to the spines)", prefix)
+		}
+		if l.hostNodeCapacity() < 1 {
+			return configErrorf("%s.downlinks must have at least 2 splits (one host node)", prefix)
+		}
+	case "spine":
+		// leafSpine /31s: each spine owns a 256-address run.
+		if l.downlinkCount(false) > 128 {
+			return configErrorf("%s.downlinks has %d splits; the leaf-spine /31 formula supports at most 128 per spine", prefix, l.downlinkCount(false))
+		}
+		if l.uplinkCount() > spineSspRunAddresses/2 {
+			return configErrorf("%s.uplinks has %d splits; the spine-superspine /31 formula supports at most %d per spine", prefix, l.uplinkCount(), spineSspRunAddresses/2)
+		}
+	}
+	return nil
+}
+
+// rawPortRange is the YAML shape of a PortRange.
+type rawPortRange struct {
+	Start *int `yaml:"start"`
+	End   *int `yaml:"end"`
+}
+
+// rawPortLayout is the YAML shape of a per-position layout. Every field is an
+// override of the profile's value (the default profile if none is named).
+type rawPortLayout struct {
+	Profile     string        `yaml:"profile"`
+	Ports       *int          `yaml:"ports"`
+	Breakout    *int          `yaml:"breakout"`
+	PortPattern *string       `yaml:"portPattern"`
+	FirstSub    *int          `yaml:"firstSub"`
+	Downlinks   *rawPortRange `yaml:"downlinks"`
+	Uplinks     *rawPortRange `yaml:"uplinks"`
+}
+
+var portLayoutKeys = []string{"breakout", "downlinks", "firstSub", "portPattern", "ports", "profile", "uplinks"}
+
+// buildPortLayouts parses the portLayouts section: either the name of a
+// profile for every position, or a map of position -> profile name or layout.
+func buildPortLayouts(node *yaml.Node) (map[string]*PortLayout, error) {
+	layouts := map[string]*PortLayout{}
+
+	if node.Kind == yaml.ScalarNode {
+		for _, position := range portLayoutPositions {
+			layout, err := builtinPortLayout(node.Value, position)
+			if err != nil {
+				return nil, err
+			}
+			layouts[position] = layout
+		}
+		return layouts, nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, configErrorf("portLayouts must be a profile name or a map of position -> layout")
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		position, value := node.Content[i].Value, node.Content[i+1]
+		if !containsString(portLayoutPositions, position) {
+			return nil, configErrorf("unknown portLayouts position %q; allowed: %v", position, portLayoutPositions)
+		}
+		layout, err := buildPortLayout(position, value)
+		if err != nil {
+			return nil, err
+		}
+		if err := layout.validate(position); err != nil {
+			return nil, err
+		}
+		layouts[position] = layout
+	}
+	return layouts, nil
+}
+
+func buildPortLayout(position string, node *yaml.Node) (*PortLayout, error) {
+	if node.Kind == yaml.ScalarNode {
+		return builtinPortLayout(node.Value, position)
+	}
+
+	var keys map[string]interface{}
+	if err := node.Decode(&keys); err != nil {
+		return nil, configErrorf("portLayouts.%s must be a profile name or a layout: %s", position, err.Error())
+	}
+	for key := range keys {
+		if !containsString(portLayoutKeys, key) {
+			return nil, configErrorf("unknown portLayouts.%s key %q; allowed: %v", position, key, portLayoutKeys)
+		}
+	}
+	var raw rawPortLayout
+	if err := node.Decode(&raw); err != nil {
+		return nil, configErrorf("portLayouts.%s: %s", position, err.Error())
+	}
+
+	profile := raw.Profile
+	if profile == "" {
+		profile = DefaultPortLayoutProfile
+	}
+	layout, err := builtinPortLayout(profile, position)
+	if err != nil {
+		return nil, err
+	}
+	if raw.Ports != nil {
+		layout.Ports = *raw.Ports
+	}
+	if raw.Breakout != nil {
+		layout.Breakout = *raw.Breakout
+	}
+	if raw.PortPattern != nil {
+		layout.PortPattern = *raw.PortPattern
+	}
+	if raw.FirstSub != nil {
+		layout.FirstSub = *raw.FirstSub
+	}
+	if raw.Downlinks != nil {
+		layout.Downlinks = PortRange{Start: intOr(raw.Downlinks.Start, 1), End: intOr(raw.Downlinks.End, 0)}
+	}
+	if raw.Uplinks != nil {
+		layout.Uplinks = &PortRange{Start: intOr(raw.Uplinks.Start, 1), End: intOr(raw.Uplinks.End, 0)}
+	}
+	if raw.Ports != nil || raw.Breakout != nil || raw.PortPattern != nil || raw.FirstSub != nil ||
+		raw.Downlinks != nil || raw.Uplinks != nil {
+		layout.Profile = ""
+	}
+	return layout, nil
+}
+
+func intOr(v *int, def int) int {
+	if v == nil {
+		return def
+	}
+	return *v
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package fabric_switch_config
+
+import "testing"
+
+func TestReferencePortLayoutNames(t *testing.T) {
+	layout := (&Config{}).portLayout("leaf")
+	cases := map[int]string{1: "swp1s0", 2: "swp1s1", 65: "swp33s0", 128: "swp64s1"}
+	for logical, want := range cases {
+		if got := layout.portName(logical); got != want {
+			t.Errorf("portName(%d) = %q, want %q", logical, got, want)
+		}
+	}
+	if layout.uplinkCount() != 64 || layout.hostNodeCapacity() != 32 {
+		t.Errorf("reference leaf: %d uplinks / %d host nodes, want 64 / 32", layout.uplinkCount(), layout.hostNodeCapacity())
+	}
+	spine := (&Config{}).portLayout("spine")
+	if spine.downlinkCount(false) != 128 || spine.downlinkCount(true) != 32 || spine.uplinkCount() != 32 {
+		t.Errorf("reference spine: %d/%d downlinks, %d uplinks, want 128/32, 32",
+			spine.downlinkCount(false), spine.downlinkCount(true), spine.uplinkCount())
+	}
+}
+
+func TestLoadConfigPortLayouts(t *testing.T) {
+	cfg, err := LoadConfig([]byte(`
+portLayouts:
+  leaf: sn5600-800g
+  spine:
+    ports: 32
+    breakout: 4
+    portPattern: "Ethernet1/{port}/{sub}"
+    firstSub: 1
+    downlinks: {start: 1}
+    uplinks: {start: 97, end: 128}
+topology:
+  leafSpine: {}
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if got := cfg.portLayout("leaf"); got.Profile != "sn5600-800g" || got.portName(33) != "swp33" {
+		t.Errorf("leaf layout = %+v", got)
+	}
+	spine := cfg.portLayout("spine")
+	if spine.Profile != "" || spine.portName(1) != "Ethernet1/1/1" || spine.portName(8) != "Ethernet1/2/4" {
+		t.Errorf("custom spine layout = %+v, port 8 = %q", spine, spine.portName(8))
+	}
+	if got := cfg.portLayout("super_spine"); got.Profile != DefaultPortLayoutProfile {
+		t.Errorf("unconfigured position should use the default profile, got %q", got.Profile)
+	}
+
+	all, err := LoadConfig([]byte("portLayouts: sn4700-400g\nasn: {}\n"))
+	if err != nil {
+		t.Fatalf("LoadConfig profile: %v", err)
+	}
+	for _, position := range portLayoutPositions {
+		if all.portLayout(position).Profile != "sn4700-400g" {
+			t.Errorf("%s should use the sn4700-400g profile", position)
+		}
+	}
+}
+
+func TestLoadConfigPortLayoutErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"unknown profile", "portLayouts: nope\nasn: {}\n", "unknown port layout profile"},
+		{"unknown position", "portLayouts:\n  border: sn5600-800g\nasn: {}\n", "unknown portLayouts position"},
+		{"unknown key", "portLayouts:\n  leaf:\n    speed: 400\nasn: {}\n", "unknown portLayouts.leaf key"},
+		{"overlap", "portLayouts:\n  leaf:\n    uplinks: {start: 60}\nasn: {}\n", "overlap"},
+		{"out of range", "portLayouts:\n  leaf:\n    ports: 32\nasn: {}\n", "within [1, 64]"},
+		{"breakout without sub", "portLayouts:\n  spine:\n    portPattern: \"swp{port}\"\nasn: {}\n", "must contain {sub}"},
+		{"too many spine uplinks", "portLayouts:\n  spine:\n    uplinks: {start: 33, end: 128}\nasn: {}\n", "at most 32"},
+		{"nodes beyond layout", "portLayouts: sn5600-800g\ntopology:\n  leafHost:\n    nodeCount: 17\n", "[1, 16]"},
+	}
+	for _, c := range cases {
+		_, err := LoadConfig([]byte(c.yaml))
+		if err == nil {
+			t.Errorf("%s: expected error", c.name)
+			continue
+		}
+		if !contains(err.Error(), c.want) {
+			t.Errorf("%s: error %q does not contain %q", c.name, err.Error(), c.want)
+		}
+	}
+}
+
+func TestComputeWithPortLayout(t *testing.T) {
+	cfg, err := LoadConfig([]byte(`
+portLayouts: sn5600-800g
+topology:
+  leafSpine: {}
+  leafHost:
+    nodeCount: 2
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	s := compute(t, twoTierDevices(), cfg)
+
+	// 4 leaves x 2 spines x L = 64 // 4 = 16, from leaf uplink swp33.
+	if len(s.Links) != 128 {
+		t.Errorf("leafSpine links = %d, want 128", len(s.Links))
+	}
+	if l := findLink(s, "leafSpine", 23, 22, "swp33"); l == nil || l.PortB != "swp17" {
+		t.Errorf("first link of the second leaf to spine 22 = %+v, want swp33 <-> swp17", l)
+	}
+	// Each host node takes two unsplit leaf ports.
+	hostPorts := map[string]bool{}
+	for _, h := range s.HostLinks {
+		if h.Leaf.Id == 24 {
+			hostPorts[h.LeafPort] = true
+		}
+	}
+	for _, port := range []string{"swp1", "swp2", "swp3", "swp4"} {
+		if !hostPorts[port] {
+			t.Errorf("leaf 24 host ports %v miss %s", hostPorts, port)
+		}
+	}
+}
@@ -5,19 +5,52 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/utils"
 )
 
 // cumulusDrivers are the drivers that support the one-shot
 // applyIdentifierAsHostnameOnNextDeploy flag.
 var cumulusDrivers = map[string]bool{"cumulus_linux": true, "cumulus42": true}
 
-// RunResult summarizes a Configure run.
+// RunOptions selects how ConfigureWithOptions applies the plan.
+type RunOptions struct {
+	// DryRun computes the plan and reports what would change, without writing.
+	DryRun bool
+	// Prune also removes the p2p links and subnets the tool created for the
+	// fabric that are no longer part of the plan.
+	Prune bool
+	// Parallelism is how many devices (and then links) are configured at once;
+	// below 2 the run is sequential. The writes for one device always happen
+	// in order.
+	Parallelism int
+	// Progress, when set, is called as the devices and links steps advance.
+	Progress ProgressFunc
+}
+
+// ProgressFunc receives the progress of a run: done of total items of step
+// ("devices", "links") are finished.
+type ProgressFunc func(step string, done, total int)
+
+// RunResult summarizes a Configure run. Drift lists every difference between
+// the plan and the state the run started from; a dry run only reports it.
 type RunResult struct {
 	Counters map[string]int
 	Failures int
 	Warnings []string
+	Drift    []DriftItem
+}
+
+// DriftItem is one difference between the plan and the current state. Device
+// and Port are empty for IPAM subnets; "-" stands for an unset or absent value.
+type DriftItem struct {
+	Device   string `json:"device,omitempty"`
+	Port     string `json:"port,omitempty"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
 }
 
 func (r *RunResult) count(key string)         { r.Counters[key]++ }
@@ -28,15 +61,22 @@ type runner struct {
 	config   *Config
 	fabricId int64
 	dryRun   bool
+	prune    bool
 	state    *DesiredState
 	result   *RunResult
 
+	parallelism int
+	progress    ProgressFunc
+	// mu guards the maps below, which the tasks of a parallel step share.
+	mu *sync.Mutex
+
 	recByID map[int64]*DeviceRecord
 	ports   map[int64]map[string]*PortRecord // device id -> port name -> port
 
 	existingLinks map[string]*P2pLinkRecord // unordered iface pair sig -> link
 	linksByIface  map[int64]*P2pLinkRecord  // iface id -> link (covers half-connected)
 	subnetIds     map[string]int64          // "netaddr/prefix" -> subnet id
+	plannedLinks  map[int64]bool            // existing link ids the plan accounts for
 }
 
 func (r *runner) fail(format string, args ...any) {
@@ -48,10 +88,56 @@ func (r *runner) info(format string, args ...any) {
 	logger.Get().Info().Msgf(format, args...)
 }
 
-// Configure executes the full configure flow against client. It returns a
-// RunResult (counters + failure count); a non-nil error is only returned for
-// fatal setup problems (fabric/device fetch, config computation).
-func Configure(client Client, config *Config, fabricId int64, dryRun bool) (*RunResult, error) {
+func (r *runner) drift(device, port, field, expected, actual string) {
+	r.result.Drift = append(r.result.Drift, DriftItem{Device: device, Port: port, Field: field, Expected: expected, Actual: actual})
+}
+
+// parallel runs task for every i in [0, n) on up to r.parallelism goroutines.
+// Each task gets a fork of the runner with its own result; the results are
+// merged in index order, so counters, drift and failures come out the same
+// whatever the parallelism.
+func (r *runner) parallel(step string, n int, task func(r *runner, i int)) {
+	forks := make([]*runner, n)
+	var progress func(int)
+	if r.progress != nil {
+		progress = func(done int) { r.progress(step, done, n) }
+	}
+	utils.ForEachParallel(n, r.parallelism, func(i int) {
+		forks[i] = r.fork()
+		task(forks[i], i)
+	}, progress)
+	for _, f := range forks {
+		r.join(f)
+	}
+}
+
+func (r *runner) fork() *runner {
+	f := *r
+	f.result = &RunResult{Counters: map[string]int{}}
+	return &f
+}
+
+func (r *runner) join(f *runner) {
+	for key, n := range f.result.Counters {
+		r.result.countN(key, n)
+	}
+	r.result.Failures += f.result.Failures
+	r.result.Warnings = append(r.result.Warnings, f.result.Warnings...)
+	r.result.Drift = append(r.result.Drift, f.result.Drift...)
+}
+
+// fabricPlan is the read-only part of a run: the fabric, its devices and the
+// state computed for them. No writes happen while building it.
+type fabricPlan struct {
+	fabric  *FabricInfo
+	recByID map[int64]*DeviceRecord
+	groups  map[string][]*Device
+	state   *DesiredState
+}
+
+// loadPlan fetches the fabric and its devices (backfilling tags from the site
+// listing) and computes the desired state for them.
+func loadPlan(client Client, config *Config, fabricId int64) (*fabricPlan, error) {
 	fabric, err := client.GetFabric(fabricId)
 	if err != nil {
 		return nil, err
@@ -86,17 +172,40 @@ func Configure(client Client, config *Config, fabricId int64, dryRun bool) (*Run
 	if err != nil {
 		return nil, err
 	}
+	return &fabricPlan{fabric: fabric, recByID: recByID, groups: groups, state: state}, nil
+}
+
+// Configure executes the full configure flow against client. It returns a
+// RunResult (counters + failure count); a non-nil error is only returned for
+// fatal setup problems (fabric/device fetch, config computation).
+func Configure(client Client, config *Config, fabricId int64, dryRun bool) (*RunResult, error) {
+	return ConfigureWithOptions(client, config, fabricId, RunOptions{DryRun: dryRun})
+}
+
+// ConfigureWithOptions is Configure with the full set of RunOptions.
+func ConfigureWithOptions(client Client, config *Config, fabricId int64, options RunOptions) (*RunResult, error) {
+	plan, err := loadPlan(client, config, fabricId)
+	if err != nil {
+		return nil, err
+	}
+	state, groups, recByID := plan.state, plan.groups, plan.recByID
 
 	r := &runner{
 		client:    client,
 		config:    config,
 		fabricId:  fabricId,
-		dryRun:    dryRun,
+		dryRun:    options.DryRun,
+		prune:     options.Prune,
 		state:     state,
 		result:    &RunResult{Counters: map[string]int{}, Warnings: state.Warnings},
 		recByID:   recByID,
 		ports:     map[int64]map[string]*PortRecord{},
 		subnetIds: map[string]int64{},
+
+		parallelism:  options.Parallelism,
+		progress:     options.Progress,
+		mu:           &sync.Mutex{},
+		plannedLinks: map[int64]bool{},
 	}
 
 	logger.Get().Debug().Msgf(
@@ -125,21 +234,26 @@ func Configure(client Client, config *Config, fabricId int64, dryRun bool) (*Run
 	}
 
 	processAllPorts := config.enablePhysicalPorts()
+	var devices []*Device
 	for _, position := range sortedGroupKeys(groups) {
 		for _, dev := range groups[position] {
-			rec := recByID[dev.Id]
-			desired := state.ByDevice[dev.Id]
-			if desired == nil {
-				desired = &DeviceDesired{}
-			}
-			if targeted[position] {
-				r.configureDevice(rec, desired)
-			}
 			if targeted[position] || processAllPorts {
-				r.configurePorts(rec)
+				devices = append(devices, dev)
 			}
 		}
 	}
+	r.parallel("devices", len(devices), func(r *runner, i int) {
+		dev := devices[i]
+		rec := recByID[dev.Id]
+		desired := state.ByDevice[dev.Id]
+		if desired == nil {
+			desired = &DeviceDesired{}
+		}
+		if targeted[dev.Position] {
+			r.configureDevice(rec, desired)
+		}
+		r.configurePorts(rec)
+	})
 
 	r.configureLinks()
 	return r.result, nil
@@ -238,11 +352,29 @@ func (r *runner) configureDevice(dev *DeviceRecord, desired *DeviceDesired) {
 			body.LoopbackAddress = desired.LoopbackIp
 		}
 	}
+	if desired.LoopbackIpv6 != nil {
+		if dev.LoopbackAddressIpv6 == nil || normalizeAddress(*dev.LoopbackAddressIpv6) != *desired.LoopbackIpv6 {
+			body.LoopbackAddressIpv6 = desired.LoopbackIpv6
+		}
+	}
+
+	if body.IdentifierString != nil {
+		r.drift(label, "", "hostname", *body.IdentifierString, dev.IdentifierString)
+	}
+	if body.Asn != nil {
+		r.drift(label, "", "asn", itoa(*body.Asn), itoa(dev.Asn))
+	}
+	if body.LoopbackAddress != nil {
+		r.drift(label, "", "loopback", *body.LoopbackAddress, strOrDash(dev.LoopbackAddressIpv4))
+	}
+	if body.LoopbackAddressIpv6 != nil {
+		r.drift(label, "", "loopbackIpv6", *body.LoopbackAddressIpv6, strOrDash(dev.LoopbackAddressIpv6))
+	}
 
 	logger.Get().Debug().Msgf(
-		"[%s] device desired: hostname=%s asn=%s loopback=%s (current: hostname=%q asn=%d loopback=%s); patch={%s}",
-		label, strOrDash(desired.Hostname), int64OrDash(desired.Asn), strOrDash(desired.LoopbackIp),
-		dev.IdentifierString, dev.Asn, strOrDash(dev.LoopbackAddressIpv4), describeDeviceUpdate(body))
+		"[%s] device desired: hostname=%s asn=%s loopback=%s loopbackIpv6=%s (current: hostname=%q asn=%d loopback=%s loopbackIpv6=%s); patch={%s}",
+		label, strOrDash(desired.Hostname), int64OrDash(desired.Asn), strOrDash(desired.LoopbackIp), strOrDash(desired.LoopbackIpv6),
+		dev.IdentifierString, dev.Asn, strOrDash(dev.LoopbackAddressIpv4), strOrDash(dev.LoopbackAddressIpv6), describeDeviceUpdate(body))
 
 	if body.empty() {
 		r.result.count("devices unchanged")
@@ -283,11 +415,14 @@ func (r *runner) configurePorts(dev *DeviceRecord) {
 			loopback++
 		}
 	}
+	r.mu.Lock()
 	r.ports[dev.Id] = byName
+	r.mu.Unlock()
 	logger.Get().Debug().Msgf("[%s] %d port(s) discovered: %d physical, %d loopback, %d other",
 		label, len(ports), physical, loopback, len(ports)-physical-loopback)
-	if loopback == 0 && r.state.ByDevice[dev.Id] != nil && r.state.ByDevice[dev.Id].LoopbackIp != nil {
-		logger.Get().Debug().Msgf("[%s] no loopback port among the discovered interfaces; the /32 step will be skipped (have the switch interfaces been discovered yet?)", label)
+	r.checkPortLayout(&dev.Device, byName)
+	if d := r.state.ByDevice[dev.Id]; loopback == 0 && d != nil && (d.LoopbackIp != nil || d.LoopbackIpv6 != nil) {
+		logger.Get().Debug().Msgf("[%s] no loopback port among the discovered interfaces; the /32 and /128 steps will be skipped (have the switch interfaces been discovered yet?)", label)
 	}
 
 	enablePhysical := r.config.enablePhysicalPorts()
@@ -314,6 +449,12 @@ func (r *runner) configurePorts(dev *DeviceRecord) {
 		if enabled == nil && description == nil {
 			continue
 		}
+		if enabled != nil {
+			r.drift(label, port.InterfaceName, "enabled", "true", boolOrDash(port.Enabled))
+		}
+		if description != nil {
+			r.drift(label, port.InterfaceName, "description", *description, strOrDash(port.Description))
+		}
 		logger.Get().Debug().Msgf("[%s:%s] port config patch: enabled=%s description=%s",
 			label, port.InterfaceName, boolOrDash(enabled), strOrDash(description))
 		if r.dryRun {
@@ -332,50 +473,83 @@ func (r *runner) configurePorts(dev *DeviceRecord) {
 
 func (r *runner) configureLoopbackIp(dev *DeviceRecord, ports []*PortRecord) {
 	desired := r.state.ByDevice[dev.Id]
-	if desired == nil || desired.LoopbackIp == nil {
+	if desired == nil || (desired.LoopbackIp == nil && desired.LoopbackIpv6 == nil) {
 		return
 	}
 	label := dev.Label()
 
-	var loopbacks []*PortRecord
-	for _, p := range ports {
-		if p.Kind == "loopback" {
-			loopbacks = append(loopbacks, p)
+	loopback := loopbackPort(ports)
+	if loopback == nil {
+		for _, target := range []string{addressWithPrefix(desired.LoopbackIp, 32), addressWithPrefix(desired.LoopbackIpv6, 128)} {
+			if target != "" {
+				r.fail("[%s] no loopback interface found; cannot set %s", label, target)
+			}
 		}
-	}
-	if len(loopbacks) == 0 {
-		r.fail("[%s] no loopback interface found; cannot set %s/32", label, *desired.LoopbackIp)
 		return
 	}
-	loopback := loopbacks[0]
-	for _, p := range loopbacks {
+
+	if desired.LoopbackIp != nil {
+		r.addLoopbackAddress(dev, loopback, *desired.LoopbackIp, 32, loopback.Ipv4Addresses, r.client.AddPortIpv4)
+	}
+	if desired.LoopbackIpv6 != nil {
+		// The IPv4 POST (if any) bumped the port's config revision.
+		r.addLoopbackAddress(dev, loopback, *desired.LoopbackIpv6, 128, loopback.Ipv6Addresses, r.client.AddPortIpv6)
+	}
+}
+
+// loopbackPort is the port that carries the loopback addresses: "lo" when the
+// device has it, else its first loopback interface, else nil.
+func loopbackPort(ports []*PortRecord) *PortRecord {
+	var loopback *PortRecord
+	for _, p := range ports {
+		if p.Kind != "loopback" {
+			continue
+		}
 		if p.InterfaceName == "lo" {
+			return p
+		}
+		if loopback == nil {
 			loopback = p
-			break
 		}
 	}
-	logger.Get().Debug().Msgf("[%s] loopback interface %q (id=%d) has %d existing address(es); target %s/32",
-		label, loopback.InterfaceName, loopback.InterfaceId, len(loopback.Ipv4Addresses), *desired.LoopbackIp)
+	return loopback
+}
+
+// addLoopbackAddress adds one address family's loopback address unless the
+// loopback port already has it.
+func (r *runner) addLoopbackAddress(dev *DeviceRecord, loopback *PortRecord, address string, prefixLength int32,
+	existing []IpAddress, add func(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error) {
+	label := dev.Label()
+	target := fmt.Sprintf("%s/%d", address, prefixLength)
+	logger.Get().Debug().Msgf("[%s] loopback interface %q (id=%d) has %d existing address(es); target %s",
+		label, loopback.InterfaceName, loopback.InterfaceId, len(existing), target)
 
-	for _, addr := range loopback.Ipv4Addresses {
-		if addr.Address == *desired.LoopbackIp && addr.PrefixLength == 32 {
+	for _, addr := range existing {
+		if normalizeAddress(addr.Address) == normalizeAddress(address) && addr.PrefixLength == prefixLength {
 			r.result.count("loopback IPs already present")
 			return
 		}
 	}
-	if len(loopback.Ipv4Addresses) > 0 {
-		r.fail("[%s] loopback %s already has different IP(s); not adding %s/32",
-			label, loopback.InterfaceName, *desired.LoopbackIp)
+	if len(existing) > 0 {
+		current := make([]string, 0, len(existing))
+		for _, addr := range existing {
+			current = append(current, fmt.Sprintf("%s/%d", addr.Address, addr.PrefixLength))
+		}
+		r.drift(label, loopback.InterfaceName, "address", target, strings.Join(current, ", "))
+		r.fail("[%s] loopback %s already has different IP(s); not adding %s",
+			label, loopback.InterfaceName, target)
 		return
 	}
+	r.drift(label, loopback.InterfaceName, "address", target, "-")
 	if r.dryRun {
 		r.result.count("loopback IPs added")
 		return
 	}
-	if err := r.client.AddPortIpv4(dev.Id, loopback.InterfaceId, *desired.LoopbackIp, 32, loopback.ConfigRevision); err != nil {
+	if err := add(dev.Id, loopback.InterfaceId, address, prefixLength, loopback.ConfigRevision); err != nil {
 		r.fail("[%s] loopback IP POST failed: %s", label, err.Error())
 		return
 	}
+	loopback.ConfigRevision++
 	r.result.count("loopback IPs added")
 }
 
@@ -385,7 +559,7 @@ func (r *runner) configureLinks() {
 	if r.config.P2p == nil {
 		return
 	}
-	if len(r.state.Links) == 0 && len(r.state.HostLinks) == 0 {
+	if len(r.state.Links) == 0 && len(r.state.HostLinks) == 0 && !r.prune {
 		return
 	}
 
@@ -423,12 +597,16 @@ func (r *runner) configureLinks() {
 		r.subnetIds[subnetKey(s.NetworkAddress, int(s.PrefixLength))] = s.Id
 	}
 
-	for _, plan := range r.state.Links {
-		r.configureLink(plan)
-	}
-	for _, plan := range r.state.HostLinks {
-		r.configureHostLink(plan)
-	}
+	failuresBefore := r.result.Failures
+	fabricLinks := len(r.state.Links)
+	r.parallel("links", fabricLinks+len(r.state.HostLinks), func(r *runner, i int) {
+		if i < fabricLinks {
+			r.configureLink(r.state.Links[i])
+		} else {
+			r.configureHostLink(r.state.HostLinks[i-fabricLinks])
+		}
+	})
+	r.pruneStale(links, subnets, r.result.Failures > failuresBefore)
 }
 
 func (r *runner) configureLink(plan *LinkPlan) {
@@ -439,18 +617,21 @@ func (r *runner) configureLink(plan *LinkPlan) {
 	}
 	label := fmt.Sprintf("%s:%s<->%s:%s", plan.DeviceA.Label(), plan.PortA, plan.DeviceB.Label(), plan.PortB)
 	tags := r.subnetTags(plan.Layer, r.endpointName(plan.DeviceA), plan.PortA, r.endpointName(plan.DeviceB), plan.PortB)
-	name := r.subnetName(plan.DeviceA.Id, plan.PortA, plan.DeviceB.Id, plan.PortB)
-	logger.Get().Debug().Msgf("[%s] %s link: ifaceA=%d ifaceB=%d /31=%s binding=%s",
-		label, plan.Layer, portA.InterfaceId, portB.InterfaceId, subnetOrDash(plan.Subnet), gatewayBinding)
+	subnets := []linkSubnet{
+		{plan.Subnet, r.subnetName(false, plan.DeviceA.Id, plan.PortA, plan.DeviceB.Id, plan.PortB)},
+		{plan.SubnetIpv6, r.subnetName(true, plan.DeviceA.Id, plan.PortA, plan.DeviceB.Id, plan.PortB)},
+	}
+	logger.Get().Debug().Msgf("[%s] %s link: ifaceA=%d ifaceB=%d /31=%s /127=%s binding=%s",
+		label, plan.Layer, portA.InterfaceId, portB.InterfaceId, subnetOrDash(plan.Subnet), subnetOrDash(plan.SubnetIpv6), gatewayBinding)
 
 	if existing, ok := r.existingLinks[ifacePairKey(portA.InterfaceId, portB.InterfaceId)]; ok {
+		r.planLink(existing)
 		r.result.count("links existing")
-		if plan.Subnet != nil {
-			r.ensureLinkStrategy(existing, label, plan.Subnet, tags, gatewayBinding, true, name)
-		}
+		r.ensureLinkStrategies(existing, label, subnets, tags, gatewayBinding, true)
 		return
 	}
 
+	r.drift(plan.DeviceA.Label(), plan.PortA, "p2p link", plan.DeviceB.Label()+":"+plan.PortB, "-")
 	payload := P2pLinkCreate{
 		InterfaceAId:      portA.InterfaceId,
 		InterfaceBId:      &portB.InterfaceId,
@@ -461,26 +642,17 @@ func (r *runner) configureLink(plan *LinkPlan) {
 
 	if r.dryRun {
 		r.result.count("links created")
-		if plan.Subnet != nil {
-			r.ensureLinkStrategy(nil, label, plan.Subnet, tags, gatewayBinding, false, name)
-		}
+		r.ensureLinkStrategies(nil, label, subnets, tags, gatewayBinding, false)
 		return
 	}
 
-	if plan.Subnet != nil {
-		if subnetId, ok := r.ensureSubnet(plan.Subnet, tags, name); ok {
-			payload.StagedSubnetId = &subnetId
-			payload.StagedBinding = gatewayBinding
-		}
-	}
+	r.stageSubnets(&payload, subnets, tags, gatewayBinding)
 	if _, err := r.client.CreateP2pLink(payload); err != nil {
 		r.fail("[%s] link create failed: %s", label, err.Error())
 		return
 	}
 	r.result.count("links created")
-	if payload.StagedSubnetId != nil {
-		r.result.count("/31 strategies added")
-	}
+	r.countStagedStrategies(payload)
 }
 
 func (r *runner) configureHostLink(plan *HostLinkPlan) {
@@ -490,16 +662,19 @@ func (r *runner) configureHostLink(plan *HostLinkPlan) {
 	}
 	label := fmt.Sprintf("%s:%s (host downlink)", plan.Leaf.Label(), plan.LeafPort)
 	tags := r.subnetTags("leafHost", r.endpointName(plan.Leaf), plan.LeafPort, plan.HostName, plan.Nic)
-	name := r.hostSubnetName(plan.Leaf.Id, plan.LeafPort, plan.HostName)
+	subnets := []linkSubnet{
+		{plan.Subnet, r.hostSubnetName(false, plan.Leaf.Id, plan.LeafPort, plan.HostName)},
+		{plan.SubnetIpv6, r.hostSubnetName(true, plan.Leaf.Id, plan.LeafPort, plan.HostName)},
+	}
 
 	if existing, ok := r.linksByIface[port.InterfaceId]; ok {
+		r.planLink(existing)
 		r.result.count("host links existing")
-		if plan.Subnet != nil {
-			r.ensureLinkStrategy(existing, label, plan.Subnet, tags, hostBinding, true, name)
-		}
+		r.ensureLinkStrategies(existing, label, subnets, tags, hostBinding, true)
 		return
 	}
 
+	r.drift(plan.Leaf.Label(), plan.LeafPort, "p2p link", "host downlink to "+plan.HostName, "-")
 	payload := P2pLinkCreate{
 		InterfaceAId:      port.InterfaceId,
 		Description:       &plan.Description,
@@ -509,45 +684,117 @@ func (r *runner) configureHostLink(plan *HostLinkPlan) {
 
 	if r.dryRun {
 		r.result.count("host links created")
-		if plan.Subnet != nil {
-			r.ensureLinkStrategy(nil, label, plan.Subnet, tags, hostBinding, false, name)
-		}
+		r.ensureLinkStrategies(nil, label, subnets, tags, hostBinding, false)
 		return
 	}
 
-	if plan.Subnet != nil {
-		if subnetId, ok := r.ensureSubnet(plan.Subnet, tags, name); ok {
-			payload.StagedSubnetId = &subnetId
-			payload.StagedBinding = hostBinding
-		}
-	}
+	r.stageSubnets(&payload, subnets, tags, hostBinding)
 	if _, err := r.client.CreateP2pLink(payload); err != nil {
 		r.fail("[%s] link create failed: %s", label, err.Error())
 		return
 	}
 	r.result.count("host links created")
+	r.countStagedStrategies(payload)
+}
+
+// linkSubnet is one address family's subnet of a link plan (nil when that
+// family is not enabled) and the IPAM name it is created with.
+type linkSubnet struct {
+	subnet *Subnet
+	name   string
+}
+
+// stageSubnets ensures the link's subnets exist and stages a manual strategy
+// for each on the create payload.
+func (r *runner) stageSubnets(payload *P2pLinkCreate, subnets []linkSubnet, tags map[string]string, binding string) {
+	for _, s := range subnets {
+		if s.subnet == nil {
+			continue
+		}
+		subnetId, ok := r.ensureSubnet(s.subnet, tags, s.name)
+		if !ok {
+			continue
+		}
+		if s.subnet.IsIpv6() {
+			payload.StagedSubnetIdIpv6 = &subnetId
+		} else {
+			payload.StagedSubnetId = &subnetId
+		}
+		payload.StagedBinding = binding
+	}
+}
+
+func (r *runner) countStagedStrategies(payload P2pLinkCreate) {
 	if payload.StagedSubnetId != nil {
 		r.result.count("/31 strategies added")
 	}
+	if payload.StagedSubnetIdIpv6 != nil {
+		r.result.count("/127 strategies added")
+	}
 }
 
+func (r *runner) ensureLinkStrategies(link *P2pLinkRecord, label string, subnets []linkSubnet, tags map[string]string, binding string, checkExisting bool) {
+	for _, s := range subnets {
+		if s.subnet != nil {
+			r.ensureLinkStrategy(link, label, s.subnet, tags, binding, checkExisting, s.name)
+		}
+	}
+}
+
+// resolvePort finds the device port a plan names. The name comes from the port
+// layout of the device's position; drivers that report the same name in a
+// different case still match.
 func (r *runner) resolvePort(dev *Device, portName string) *PortRecord {
 	if byName, ok := r.ports[dev.Id]; ok {
 		if port, ok := byName[portName]; ok {
 			return port
 		}
+		for name, port := range byName {
+			if strings.EqualFold(name, portName) {
+				return port
+			}
+		}
 	}
-	r.fail("[%s] port %q not found; skipping its link", dev.Label(), portName)
+	r.fail("[%s] port %q not found (port layout %s); skipping its link",
+		dev.Label(), portName, r.config.portLayout(dev.Position).describe())
 	return nil
 }
 
+// checkPortLayout warns when a fabric device has none of the downlink ports its
+// position's layout names, which usually means the wrong layout is selected.
+func (r *runner) checkPortLayout(dev *Device, byName map[string]*PortRecord) {
+	if r.config.Topology == nil || len(byName) == 0 || !containsString(portLayoutPositions, dev.Position) {
+		return
+	}
+	layout := r.config.portLayout(dev.Position)
+	first, last := layout.Downlinks.Start, layout.downlinkEnd(false)
+	for logical := first; logical <= last; logical++ {
+		if _, ok := byName[layout.portName(logical)]; ok {
+			return
+		}
+	}
+	r.result.Warnings = append(r.result.Warnings, fmt.Sprintf(
+		"%s has none of the ports %s..%s of its port layout %s; select the matching layout in portLayouts",
+		dev.Label(), layout.portName(first), layout.portName(last), layout.describe()))
+}
+
+func (r *runner) planLink(link *P2pLinkRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.plannedLinks[link.Id] = true
+}
+
 func (r *runner) ensureSubnet(subnet *Subnet, tags map[string]string, name string) (int64, bool) {
 	key := subnetKey(subnet.NetworkAddress, subnet.PrefixLength)
-	if id, ok := r.subnetIds[key]; ok {
+	r.mu.Lock()
+	id, ok := r.subnetIds[key]
+	r.mu.Unlock()
+	if ok {
 		logger.Get().Debug().Msgf("subnet %s already exists (id=%d, name=%q)", subnet, id, name)
 		return id, true
 	}
-	logger.Get().Debug().Msgf("subnet %s not found; would create (name=%q, link-layer=%s)", subnet, name, tags["nvidia/link-layer"])
+	logger.Get().Debug().Msgf("subnet %s not found; would create (name=%q, link-layer=%s)", subnet, name, tags[tagLinkLayer])
+	r.drift("", "", "subnet", fmt.Sprintf("%s (%s)", subnet, name), "-")
 	if r.dryRun {
 		r.result.count("subnets created")
 		return 0, false
@@ -562,29 +809,171 @@ func (r *runner) ensureSubnet(subnet *Subnet, tags map[string]string, name strin
 		r.fail("creating subnet %s failed: %s", subnet, err.Error())
 		return 0, false
 	}
+	r.mu.Lock()
 	r.subnetIds[key] = created.Id
+	r.mu.Unlock()
 	r.result.count("subnets created")
 	return created.Id, true
 }
 
 func (r *runner) ensureLinkStrategy(link *P2pLinkRecord, label string, subnet *Subnet, tags map[string]string, binding string, checkExisting bool, name string) {
-	if checkExisting && link != nil && link.HasIpv4Strategy {
-		r.result.count("links with existing /31 strategy")
+	kind := fmt.Sprintf("/%d", subnet.PrefixLength)
+	hasStrategy, create := link != nil && link.HasIpv4Strategy, r.client.CreateP2pIpv4Strategy
+	if subnet.IsIpv6() {
+		hasStrategy, create = link != nil && link.HasIpv6Strategy, r.client.CreateP2pIpv6Strategy
+	}
+	if checkExisting && hasStrategy {
+		r.result.count("links with existing " + kind + " strategy")
 		return
 	}
+	if link != nil {
+		r.drift(label, "", kind+" strategy", subnet.String(), "-")
+	}
 	subnetId, ok := r.ensureSubnet(subnet, tags, name)
 	if r.dryRun {
-		r.result.count("/31 strategies added")
+		r.result.count(kind + " strategies added")
 		return
 	}
 	if !ok {
 		return
 	}
-	if err := r.client.CreateP2pIpv4Strategy(link.Id, subnetId, binding, link.Revision); err != nil {
-		r.fail("[%s] creating /31 strategy failed: %s", label, err.Error())
+	if err := create(link.Id, subnetId, binding, link.Revision); err != nil {
+		r.fail("[%s] creating %s strategy failed: %s", label, kind, err.Error())
 		return
 	}
-	r.result.count("/31 strategies added")
+	// Both families' strategies are added under the link's If-Match lock.
+	link.Revision++
+	r.result.count(kind + " strategies added")
+}
+
+// ---- Stale links / subnets --------------------------------------------------
+
+// pruneStale finds the p2p links and subnets the tool created for the fabric
+// that the plan no longer has, reports them as drift and, with prune, deletes
+// them: links first, then the subnets no remaining link allocates from. A
+// subnet is the tool's if it carries the fabric and link-layer tags; a link is
+// if one of its strategies allocates from such a subnet. Pruning is skipped
+// after a failed link step, since an unresolved planned link would look stale.
+func (r *runner) pruneStale(links []*P2pLinkRecord, subnets []*SubnetRecord, linkFailures bool) {
+	planned := map[string]bool{}
+	addPlanned := func(subnet *Subnet) {
+		if subnet != nil {
+			planned[subnetKey(subnet.NetworkAddress, subnet.PrefixLength)] = true
+		}
+	}
+	for _, plan := range r.state.Links {
+		addPlanned(plan.Subnet)
+		addPlanned(plan.SubnetIpv6)
+	}
+	for _, plan := range r.state.HostLinks {
+		addPlanned(plan.Subnet)
+		addPlanned(plan.SubnetIpv6)
+	}
+
+	owned := ownedSubnets(subnets)
+
+	inUse := map[int64]bool{}
+	var staleLinks []*P2pLinkRecord
+	for _, link := range links {
+		if !ownsLink(owned, link) {
+			continue
+		}
+		if r.plannedLinks[link.Id] {
+			for _, id := range link.SubnetIds {
+				inUse[id] = true
+			}
+			continue
+		}
+		staleLinks = append(staleLinks, link)
+	}
+	var staleSubnets []*SubnetRecord
+	for _, s := range subnets {
+		if owned[s.Id] != nil && !inUse[s.Id] && !planned[subnetKey(s.NetworkAddress, int(s.PrefixLength))] {
+			staleSubnets = append(staleSubnets, s)
+		}
+	}
+	if len(staleLinks) == 0 && len(staleSubnets) == 0 {
+		return
+	}
+
+	ifaceNames := r.interfaceNames()
+	for _, link := range staleLinks {
+		device, port := "-", "-"
+		if link.InterfaceAId != nil {
+			device, port = ifaceNames.describe(*link.InterfaceAId)
+		}
+		r.drift(device, port, "p2p link", "-", fmt.Sprintf("link %d (not in the plan)", link.Id))
+	}
+	for _, s := range staleSubnets {
+		r.drift("", "", "subnet", "-", fmt.Sprintf("%s/%d (id %d, not in the plan)", s.NetworkAddress, s.PrefixLength, s.Id))
+	}
+
+	if !r.prune {
+		r.result.Warnings = append(r.result.Warnings, fmt.Sprintf(
+			"%d p2p link(s) and %d subnet(s) created for this fabric are no longer in the plan; use --prune to remove them",
+			len(staleLinks), len(staleSubnets)))
+		return
+	}
+	if linkFailures {
+		r.fail("not pruning %d stale link(s) and %d subnet(s): the link step had failures", len(staleLinks), len(staleSubnets))
+		return
+	}
+
+	for _, link := range staleLinks {
+		if r.dryRun {
+			r.result.count("links pruned")
+			continue
+		}
+		if err := r.client.DeleteP2pLink(link.Id, link.Revision); err != nil {
+			r.fail("deleting stale link %d failed: %s", link.Id, err.Error())
+			for _, id := range link.SubnetIds {
+				inUse[id] = true
+			}
+			continue
+		}
+		r.result.count("links pruned")
+	}
+	for _, s := range staleSubnets {
+		label := fmt.Sprintf("%s/%d", s.NetworkAddress, s.PrefixLength)
+		if inUse[s.Id] {
+			logger.Get().Debug().Msgf("stale subnet %s kept: its link could not be deleted", label)
+			continue
+		}
+		if r.dryRun {
+			r.result.count("subnets pruned")
+			continue
+		}
+		if err := r.client.DeleteSubnet(s.Id, s.Revision); err != nil {
+			r.fail("deleting stale subnet %s failed: %s", label, err.Error())
+			continue
+		}
+		r.result.count("subnets pruned")
+	}
+}
+
+// interfaceIndex maps the interface ids of the ports read so far back to
+// their device and port names.
+type interfaceIndex map[int64][2]string
+
+func (r *runner) interfaceNames() interfaceIndex {
+	index := interfaceIndex{}
+	for deviceId, byName := range r.ports {
+		label := "id=" + itoa(deviceId)
+		if rec, ok := r.recByID[deviceId]; ok {
+			label = rec.Label()
+		}
+		for name, port := range byName {
+			index[port.InterfaceId] = [2]string{label, name}
+		}
+	}
+	return index
+}
+
+func (x interfaceIndex) describe(interfaceId int64) (string, string) {
+	if names, ok := x[interfaceId]; ok {
+		return names[0], names[1]
+	}
+	return "-", fmt.Sprintf("interface %d", interfaceId)
 }
 
 // ---- subnet tags / names ----------------------------------------------------
@@ -595,7 +984,13 @@ const (
 )
 
 func (r *runner) endpointName(dev *Device) string {
-	if d, ok := r.state.ByDevice[dev.Id]; ok && d.Hostname != nil {
+	return endpointName(r.state, dev)
+}
+
+// endpointName is the device's computed hostname, falling back to its label
+// when hostnames are not part of the plan.
+func endpointName(state *DesiredState, dev *Device) string {
+	if d, ok := state.ByDevice[dev.Id]; ok && d.Hostname != nil {
 		return *d.Hostname
 	}
 	return dev.Label()
@@ -603,21 +998,29 @@ func (r *runner) endpointName(dev *Device) string {
 
 func (r *runner) subnetTags(layer, endpointA, portA, endpointB, portB string) map[string]string {
 	return map[string]string{
-		FabricTag:           fmt.Sprintf("%d", r.fabricId),
-		"nvidia/link-layer": layerTagValue[layer],
-		"nvidia/endpoint-a": endpointA,
-		"nvidia/port-a":     portA,
-		"nvidia/endpoint-b": endpointB,
-		"nvidia/port-b":     portB,
+		FabricTag:    fmt.Sprintf("%d", r.fabricId),
+		tagLinkLayer: layerTagValue[layer],
+		tagEndpointA: endpointA,
+		tagPortA:     portA,
+		tagEndpointB: endpointB,
+		tagPortB:     portB,
 	}
 }
 
-func (r *runner) subnetName(swA int64, portA string, swB int64, portB string) string {
-	return truncateName(fmt.Sprintf("fab%d-sw%d-%s-to-sw%d-%s", r.fabricId, swA, portA, swB, portB))
+func (r *runner) subnetName(ipv6 bool, swA int64, portA string, swB int64, portB string) string {
+	return truncateName(fmt.Sprintf("%s-sw%d-%s-to-sw%d-%s", r.subnetNamePrefix(ipv6), swA, portA, swB, portB))
 }
 
-func (r *runner) hostSubnetName(leafId int64, leafPort, hostName string) string {
-	return truncateName(fmt.Sprintf("fab%d-sw%d-%s-to-%s", r.fabricId, leafId, leafPort, hostName))
+func (r *runner) hostSubnetName(ipv6 bool, leafId int64, leafPort, hostName string) string {
+	return truncateName(fmt.Sprintf("%s-sw%d-%s-to-%s", r.subnetNamePrefix(ipv6), leafId, leafPort, hostName))
+}
+
+// subnetNamePrefix keeps the /31 and /127 of a link apart in IPAM names.
+func (r *runner) subnetNamePrefix(ipv6 bool) string {
+	if ipv6 {
+		return fmt.Sprintf("fab%d-v6", r.fabricId)
+	}
+	return fmt.Sprintf("fab%d", r.fabricId)
 }
 
 func truncateName(name string) string {
@@ -678,6 +1081,21 @@ func describeDeviceUpdate(body DeviceUpdate) string {
 	if body.LoopbackAddress != nil {
 		parts = append(parts, "loopbackAddress="+*body.LoopbackAddress)
 	}
+	if body.LoopbackAddressIpv6 != nil {
+		parts = append(parts, "loopbackAddressIpv6="+*body.LoopbackAddressIpv6)
+	}
+	if body.ClearIdentifierString {
+		parts = append(parts, "identifierString=null")
+	}
+	if body.ClearAsn {
+		parts = append(parts, "asn=null")
+	}
+	if body.ClearLoopbackAddress {
+		parts = append(parts, "loopbackAddress=null")
+	}
+	if body.ClearLoopbackAddressIpv6 {
+		parts = append(parts, "loopbackAddressIpv6=null")
+	}
 	if len(parts) == 0 {
 		return "no changes"
 	}
@@ -693,10 +1111,22 @@ func ifacePairKey(a, b int64) string {
 	return fmt.Sprintf("%d-%d", a, b)
 }
 
+// subnetKey identifies a subnet by its canonical network address, so IPv6
+// subnets read back from IPAM match the computed ones.
 func subnetKey(networkAddress string, prefixLength int) string {
+	if normalized := normalizeAddress(networkAddress); normalized != "" {
+		networkAddress = normalized
+	}
 	return fmt.Sprintf("%s/%d", networkAddress, prefixLength)
 }
 
+func addressWithPrefix(address *string, prefixLength int) string {
+	if address == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%d", *address, prefixLength)
+}
+
 func sortedGroupKeys(groups map[string][]*Device) []string {
 	out := make([]string, 0, len(groups))
 	for k := range groups {
@@ -1,6 +1,12 @@
 package fabric_switch_config
 
-import "testing"
+import (
+	"net/netip"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
 
 // fakeClient is an in-memory Client that records writes and applies them so a
 // re-run observes the new state.
@@ -9,6 +15,7 @@ type fakeClient struct {
 	devices     map[int64]*DeviceRecord
 	ports       map[int64][]*PortRecord
 	p2pLinks    []*P2pLinkRecord
+	fabricLinks []*FabricLinkRecord
 	subnets     []*SubnetRecord
 	siteDevices []*DeviceRecord
 	nextId      int64
@@ -16,10 +23,13 @@ type fakeClient struct {
 	devicePatches   int
 	portPatches     int
 	portIpAdds      int
+	portIpRemoves   int
 	linksCreated    []P2pLinkCreate
 	subnetsCreated  []SubnetCreate
 	strategyCreates int
 	siteListCalls   int
+	linksDeleted    []int64
+	subnetsDeleted  []int64
 }
 
 func (f *fakeClient) newId() int64 { f.nextId++; return f.nextId }
@@ -54,6 +64,21 @@ func (f *fakeClient) UpdateDevice(deviceId int64, body DeviceUpdate, _ int64) er
 	if body.LoopbackAddress != nil {
 		d.LoopbackAddressIpv4 = body.LoopbackAddress
 	}
+	if body.LoopbackAddressIpv6 != nil {
+		d.LoopbackAddressIpv6 = body.LoopbackAddressIpv6
+	}
+	if body.ClearIdentifierString {
+		d.IdentifierString = ""
+	}
+	if body.ClearAsn {
+		d.Asn = 0
+	}
+	if body.ClearLoopbackAddress {
+		d.LoopbackAddressIpv4 = nil
+	}
+	if body.ClearLoopbackAddressIpv6 {
+		d.LoopbackAddressIpv6 = nil
+	}
 	d.Revision++
 	return nil
 }
@@ -86,7 +111,53 @@ func (f *fakeClient) AddPortIpv4(deviceId, portId int64, address string, prefixL
 	return nil
 }
 
-func (f *fakeClient) ListP2pLinks() ([]*P2pLinkRecord, error) { return f.p2pLinks, nil }
+func (f *fakeClient) AddPortIpv6(deviceId, portId int64, address string, prefixLength int32, _ int64) error {
+	f.portIpAdds++
+	for _, p := range f.ports[deviceId] {
+		if p.InterfaceId == portId {
+			p.Ipv6Addresses = append(p.Ipv6Addresses, IpAddress{Address: address, PrefixLength: prefixLength})
+		}
+	}
+	return nil
+}
+
+func (f *fakeClient) RemovePortIpv4(deviceId, portId int64, address string, prefixLength int32, _ int64) error {
+	f.portIpRemoves++
+	for _, p := range f.ports[deviceId] {
+		if p.InterfaceId == portId {
+			p.Ipv4Addresses = removeIpAddress(p.Ipv4Addresses, address, prefixLength)
+		}
+	}
+	return nil
+}
+
+func (f *fakeClient) RemovePortIpv6(deviceId, portId int64, address string, prefixLength int32, _ int64) error {
+	f.portIpRemoves++
+	for _, p := range f.ports[deviceId] {
+		if p.InterfaceId == portId {
+			p.Ipv6Addresses = removeIpAddress(p.Ipv6Addresses, address, prefixLength)
+		}
+	}
+	return nil
+}
+
+func removeIpAddress(addresses []IpAddress, address string, prefixLength int32) []IpAddress {
+	out := addresses[:0]
+	for _, a := range addresses {
+		if a.Address != address || a.PrefixLength != prefixLength {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func (f *fakeClient) ListFabricLinks(int64) ([]*FabricLinkRecord, error) {
+	return f.fabricLinks, nil
+}
+
+func (f *fakeClient) ListP2pLinks() ([]*P2pLinkRecord, error) {
+	return append([]*P2pLinkRecord(nil), f.p2pLinks...), nil
+}
 
 func (f *fakeClient) CreateP2pLink(payload P2pLinkCreate) (*P2pLinkRecord, error) {
 	f.linksCreated = append(f.linksCreated, payload)
@@ -98,22 +169,55 @@ func (f *fakeClient) CreateP2pLink(payload P2pLinkCreate) (*P2pLinkRecord, error
 		link.InterfaceBId = &b
 	}
 	link.HasIpv4Strategy = payload.StagedSubnetId != nil
+	link.HasIpv6Strategy = payload.StagedSubnetIdIpv6 != nil
+	for _, id := range []*int64{payload.StagedSubnetId, payload.StagedSubnetIdIpv6} {
+		if id != nil {
+			link.SubnetIds = append(link.SubnetIds, *id)
+		}
+	}
 	f.p2pLinks = append(f.p2pLinks, link)
 	return link, nil
 }
 
-func (f *fakeClient) CreateP2pIpv4Strategy(linkId, _ int64, _ string, _ int64) error {
+func (f *fakeClient) CreateP2pIpv4Strategy(linkId, subnetId int64, _ string, _ int64) error {
 	f.strategyCreates++
 	for _, l := range f.p2pLinks {
 		if l.Id == linkId {
 			l.HasIpv4Strategy = true
+			l.SubnetIds = append(l.SubnetIds, subnetId)
 			l.Revision++
 		}
 	}
 	return nil
 }
 
-func (f *fakeClient) ListSubnetsByFabricTag(int64) ([]*SubnetRecord, error) { return f.subnets, nil }
+func (f *fakeClient) CreateP2pIpv6Strategy(linkId, subnetId int64, _ string, _ int64) error {
+	f.strategyCreates++
+	for _, l := range f.p2pLinks {
+		if l.Id == linkId {
+			l.HasIpv6Strategy = true
+			l.SubnetIds = append(l.SubnetIds, subnetId)
+			l.Revision++
+		}
+	}
+	return nil
+}
+
+func (f *fakeClient) DeleteP2pLink(linkId, _ int64) error {
+	f.linksDeleted = append(f.linksDeleted, linkId)
+	for i, l := range f.p2pLinks {
+		if l.Id == linkId {
+			f.p2pLinks = append(f.p2pLinks[:i], f.p2pLinks[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Listings return copies: the deletes below edit the backing slices in place.
+func (f *fakeClient) ListSubnetsByFabricTag(int64) ([]*SubnetRecord, error) {
+	return append([]*SubnetRecord(nil), f.subnets...), nil
+}
 
 func (f *fakeClient) CreateSubnet(payload SubnetCreate) (*SubnetRecord, error) {
 	f.subnetsCreated = append(f.subnetsCreated, payload)
@@ -122,6 +226,17 @@ func (f *fakeClient) CreateSubnet(payload SubnetCreate) (*SubnetRecord, error) {
 	return s, nil
 }
 
+func (f *fakeClient) DeleteSubnet(subnetId, _ int64) error {
+	f.subnetsDeleted = append(f.subnetsDeleted, subnetId)
+	for i, s := range f.subnets {
+		if s.Id == subnetId {
+			f.subnets = append(f.subnets[:i], f.subnets[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
 // newFakeFromFixture builds a fake whose ports cover every port referenced by
 // the computed plan (so links resolve), plus a loopback per device and one
 // spare physical port per device to exercise the placeholder rule.
@@ -228,8 +343,8 @@ func TestRunnerWriteRunIdempotencyAndDryRun(t *testing.T) {
 	// Subnet tags sample (leaf<->spine 10.254.0.0).
 	for _, s := range f.subnetsCreated {
 		if s.NetworkAddress == "10.254.0.0" {
-			if s.Tags["nvidia/link-layer"] != "leaf-spine" || s.Tags[FabricTag] != "5" ||
-				s.Tags["nvidia/endpoint-a"] != "leaf-pod5-su1-r1" || s.Tags["nvidia/port-a"] != "swp33s0" {
+			if s.Tags[tagLinkLayer] != "leaf-spine" || s.Tags[FabricTag] != "5" ||
+				s.Tags[tagEndpointA] != "leaf-pod5-su1-r1" || s.Tags[tagPortA] != "swp33s0" {
 				t.Errorf("leaf-spine subnet tags wrong: %v", s.Tags)
 			}
 			if s.PrefixLength != 31 {
@@ -290,6 +405,61 @@ func TestRunnerStrategyRepair(t *testing.T) {
 	}
 }
 
+func TestRunnerDualStack(t *testing.T) {
+	config := fixtureConfig()
+	config.AddressFamily = AddressFamilyDualStack
+	f, state := newFakeFromFixture(t, fixtureDevices(), config)
+
+	res, err := Configure(f, config, 5, false)
+	if err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if res.Failures != 0 {
+		t.Fatalf("failures = %d, want 0", res.Failures)
+	}
+	links := len(state.Links) + len(state.HostLinks)
+	if len(f.subnetsCreated) != 2*links {
+		t.Errorf("subnets created = %d, want %d", len(f.subnetsCreated), 2*links)
+	}
+	if res.Counters["/31 strategies added"] != links || res.Counters["/127 strategies added"] != links {
+		t.Errorf("strategies staged = %d /31, %d /127, want %d each",
+			res.Counters["/31 strategies added"], res.Counters["/127 strategies added"], links)
+	}
+	if res.Counters["loopback IPs added"] != 16 {
+		t.Errorf("loopback IPs added = %d, want 16", res.Counters["loopback IPs added"])
+	}
+	if d := f.devices[4]; d.LoopbackAddressIpv6 == nil || *d.LoopbackAddressIpv6 != "fd00:253::1" {
+		t.Errorf("device 4 IPv6 loopback = %s, want fd00:253::1", strOrDash(d.LoopbackAddressIpv6))
+	}
+	for _, s := range f.subnetsCreated {
+		if s.NetworkAddress == "fd00:254::" && (s.PrefixLength != 127 || !contains(s.Name, "fab5-v6-")) {
+			t.Errorf("leaf-spine /127 = %+v", s)
+		}
+	}
+
+	// IPAM may hand back the IPv6 subnets in their expanded form.
+	for _, s := range f.subnets {
+		if s.PrefixLength == 127 {
+			s.NetworkAddress = netip.MustParseAddr(s.NetworkAddress).StringExpanded()
+		}
+	}
+	// Drop the IPv6 strategy off one link; a re-run must repair exactly it.
+	f.p2pLinks[0].HasIpv6Strategy = false
+	f.devicePatches, f.portPatches, f.portIpAdds = 0, 0, 0
+	f.linksCreated, f.subnetsCreated, f.strategyCreates = nil, nil, 0
+	res2, err := Configure(f, config, 5, false)
+	if err != nil {
+		t.Fatalf("second Configure: %v", err)
+	}
+	if f.devicePatches != 0 || f.portIpAdds != 0 || len(f.linksCreated) != 0 || len(f.subnetsCreated) != 0 {
+		t.Errorf("second run made writes: devices=%d ips=%d links=%d subnets=%d",
+			f.devicePatches, f.portIpAdds, len(f.linksCreated), len(f.subnetsCreated))
+	}
+	if f.strategyCreates != 1 || res2.Counters["/127 strategies added"] != 1 {
+		t.Errorf("repair strategy POSTs = %d, want 1", f.strategyCreates)
+	}
+}
+
 func TestRunnerTagsHydration(t *testing.T) {
 	devices := fixtureDevices()
 	site := make([]*DeviceRecord, len(devices))
@@ -331,3 +501,241 @@ func portDescription(f *fakeClient, deviceId int64, name string) *string {
 	}
 	return nil
 }
+
+func TestRunnerDrift(t *testing.T) {
+	f, state := newFakeFromFixture(t, fixtureDevices(), fixtureConfig())
+
+	res, err := ConfigureWithOptions(f, fixtureConfig(), 5, RunOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if f.devicePatches != 0 || len(f.linksCreated) != 0 || len(f.subnetsCreated) != 0 {
+		t.Fatalf("check made writes")
+	}
+	fields := map[string]int{}
+	var hostnameDrift *DriftItem
+	for i, d := range res.Drift {
+		fields[d.Field]++
+		if d.Field == "hostname" && d.Expected == "leaf-pod5-su1-r1" {
+			hostnameDrift = &res.Drift[i]
+		}
+	}
+	if hostnameDrift == nil || hostnameDrift.Port != "" {
+		t.Errorf("no hostname drift for device 4: %+v", res.Drift)
+	}
+	if links := len(state.Links) + len(state.HostLinks); fields["p2p link"] != links {
+		t.Errorf("p2p link drift = %d, want %d", fields["p2p link"], links)
+	}
+	if fields["asn"] == 0 || fields["description"] == 0 {
+		t.Errorf("drift fields = %v", fields)
+	}
+
+	if _, err := Configure(f, fixtureConfig(), 5, false); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	res, err = ConfigureWithOptions(f, fixtureConfig(), 5, RunOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("second check: %v", err)
+	}
+	if len(res.Drift) != 0 {
+		t.Errorf("drift after a full run = %+v", res.Drift)
+	}
+}
+
+func TestRunnerPrune(t *testing.T) {
+	f, state := newFakeFromFixture(t, fixtureDevices(), fixtureConfig())
+	if _, err := Configure(f, fixtureConfig(), 5, false); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	// A subnet of another tool shares the fabric tag; it must never be pruned.
+	f.subnets = append(f.subnets, &SubnetRecord{Id: f.newId(), NetworkAddress: "10.9.9.0", PrefixLength: 24,
+		Tags: map[string]string{FabricTag: "5"}})
+
+	config := fixtureConfig()
+	config.Topology.LeafHost.NodeCount = ptrInt(1)
+	groups, err := GroupAndOrder(fixtureDevices(), OrderingManagementAddress)
+	if err != nil {
+		t.Fatalf("GroupAndOrder: %v", err)
+	}
+	shrunk, err := ComputeDesired(config, groups)
+	if err != nil {
+		t.Fatalf("ComputeDesired: %v", err)
+	}
+	stale := len(state.HostLinks) - len(shrunk.HostLinks)
+	if stale <= 0 {
+		t.Fatalf("fixture does not drop any host link")
+	}
+
+	res, err := ConfigureWithOptions(f, config, 5, RunOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	staleDrift := 0
+	for _, d := range res.Drift {
+		if d.Expected == "-" {
+			staleDrift++
+		}
+	}
+	if staleDrift != 2*stale || len(res.Warnings) == 0 || !contains(res.Warnings[len(res.Warnings)-1], "--prune") {
+		t.Errorf("stale drift = %d, want %d; warnings = %v", staleDrift, 2*stale, res.Warnings)
+	}
+
+	res, err = ConfigureWithOptions(f, config, 5, RunOptions{DryRun: true, Prune: true})
+	if err != nil {
+		t.Fatalf("dry-run prune: %v", err)
+	}
+	if res.Counters["links pruned"] != stale || res.Counters["subnets pruned"] != stale {
+		t.Errorf("dry-run prune counters = %v, want %d each", res.Counters, stale)
+	}
+	if len(f.linksDeleted) != 0 || len(f.subnetsDeleted) != 0 {
+		t.Fatalf("dry-run prune deleted")
+	}
+
+	res, err = ConfigureWithOptions(f, config, 5, RunOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if res.Failures != 0 || len(f.linksDeleted) != stale || len(f.subnetsDeleted) != stale {
+		t.Errorf("prune: failures=%d links=%d subnets=%d, want %d each",
+			res.Failures, len(f.linksDeleted), len(f.subnetsDeleted), stale)
+	}
+	kept := false
+	for _, s := range f.subnets {
+		kept = kept || s.NetworkAddress == "10.9.9.0"
+	}
+	if !kept {
+		t.Errorf("prune removed a subnet it does not own")
+	}
+
+	res, err = ConfigureWithOptions(f, config, 5, RunOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("final check: %v", err)
+	}
+	if len(res.Drift) != 0 {
+		t.Errorf("drift after prune = %+v", res.Drift)
+	}
+}
+
+// serialClient lets parallel runs share a fakeClient: it serializes the calls
+// the devices and links steps make.
+type serialClient struct {
+	*fakeClient
+	mu sync.Mutex
+}
+
+func (c *serialClient) ListPorts(deviceId int64) ([]*PortRecord, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fakeClient.ListPorts(deviceId)
+}
+
+func (c *serialClient) UpdateDevice(deviceId int64, body DeviceUpdate, revision int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fakeClient.UpdateDevice(deviceId, body, revision)
+}
+
+func (c *serialClient) UpdatePortConfig(deviceId, portId int64, enabled *bool, description *string, configRevision int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fakeClient.UpdatePortConfig(deviceId, portId, enabled, description, configRevision)
+}
+
+func (c *serialClient) AddPortIpv4(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fakeClient.AddPortIpv4(deviceId, portId, address, prefixLength, configRevision)
+}
+
+func (c *serialClient) AddPortIpv6(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fakeClient.AddPortIpv6(deviceId, portId, address, prefixLength, configRevision)
+}
+
+func (c *serialClient) CreateP2pLink(payload P2pLinkCreate) (*P2pLinkRecord, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fakeClient.CreateP2pLink(payload)
+}
+
+func (c *serialClient) CreateP2pIpv4Strategy(linkId, subnetId int64, binding string, linkRevision int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fakeClient.CreateP2pIpv4Strategy(linkId, subnetId, binding, linkRevision)
+}
+
+func (c *serialClient) CreateP2pIpv6Strategy(linkId, subnetId int64, binding string, linkRevision int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fakeClient.CreateP2pIpv6Strategy(linkId, subnetId, binding, linkRevision)
+}
+
+func (c *serialClient) CreateSubnet(payload SubnetCreate) (*SubnetRecord, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fakeClient.CreateSubnet(payload)
+}
+
+func TestRunnerParallel(t *testing.T) {
+	sequential, _ := newFakeFromFixture(t, fixtureDevices(), fixtureConfig())
+	want, err := Configure(sequential, fixtureConfig(), 5, false)
+	if err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+
+	f, state := newFakeFromFixture(t, fixtureDevices(), fixtureConfig())
+	progress := map[string][2]int{}
+	got, err := ConfigureWithOptions(&serialClient{fakeClient: f}, fixtureConfig(), 5, RunOptions{
+		Parallelism: 4,
+		Progress: func(step string, done, total int) {
+			if done <= progress[step][0] {
+				t.Errorf("%s progress went from %d to %d", step, progress[step][0], done)
+			}
+			progress[step] = [2]int{done, total}
+		},
+	})
+	if err != nil {
+		t.Fatalf("parallel Configure: %v", err)
+	}
+	if got.Failures != 0 || !reflect.DeepEqual(got.Counters, want.Counters) {
+		t.Errorf("parallel run: failures=%d counters=%v, want %v", got.Failures, got.Counters, want.Counters)
+	}
+	// The fakes list the ports of a device in map order, so only the set of
+	// drift items is comparable between them.
+	if !reflect.DeepEqual(sortedDrift(got.Drift), sortedDrift(want.Drift)) {
+		t.Errorf("parallel drift differs from the sequential run")
+	}
+	if progress["devices"] != [2]int{len(f.devices), len(f.devices)} {
+		t.Errorf("devices progress = %v, want %d of %d", progress["devices"], len(f.devices), len(f.devices))
+	}
+	if links := len(state.Links) + len(state.HostLinks); progress["links"] != [2]int{links, links} {
+		t.Errorf("links progress = %v, want %d of %d", progress["links"], links, links)
+	}
+
+	again, err := ConfigureWithOptions(f, fixtureConfig(), 5, RunOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(again.Drift) != 0 {
+		t.Errorf("drift after a parallel run = %+v", again.Drift)
+	}
+}
+
+func sortedDrift(items []DriftItem) []DriftItem {
+	out := append([]DriftItem(nil), items...)
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Device != b.Device {
+			return a.Device < b.Device
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.Expected < b.Expected
+	})
+	return out
+}
@@ -1,6 +1,7 @@
 package fabric_switch_config
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,6 +12,7 @@ import (
 	"strconv"
 
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/response_inspector"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/utils"
 	sdk "github.com/metalsoft-io/metalcloud-sdk-go"
@@ -51,6 +53,7 @@ func deviceRecordFromSDK(d *sdk.NetworkDevice) *DeviceRecord {
 		},
 		Asn:                                   d.Asn,
 		LoopbackAddressIpv4:                   d.LoopbackAddressIpv4,
+		LoopbackAddressIpv6:                   d.LoopbackAddressIpv6,
 		ApplyIdentifierAsHostnameOnNextDeploy: d.ApplyIdentifierAsHostnameOnNextDeploy,
 		Revision:                              d.Revision,
 	}
@@ -97,11 +100,29 @@ func (c *sdkClient) UpdateDevice(deviceId int64, body DeviceUpdate, revision int
 	if body.LoopbackAddress != nil {
 		update.SetLoopbackAddress(*body.LoopbackAddress)
 	}
-	_, httpRes, err := c.api.NetworkDeviceAPI.
-		UpdateNetworkDevice(c.ctx, deviceId).
-		UpdateNetworkDevice(update).
-		IfMatch(strconv.FormatInt(revision, 10)).
-		Execute()
+	if body.LoopbackAddressIpv6 != nil {
+		update.SetLoopbackAddressIpv6(*body.LoopbackAddressIpv6)
+	}
+	if body.ClearIdentifierString {
+		update.SetIdentifierStringNil()
+	}
+	if body.ClearAsn {
+		update.SetAsnNil()
+	}
+	if body.ClearLoopbackAddress {
+		update.SetLoopbackAddressNil()
+	}
+	if body.ClearLoopbackAddressIpv6 {
+		update.SetLoopbackAddressIpv6Nil()
+	}
+	httpRes, err := retryOnRevisionMismatch(strconv.FormatInt(revision, 10), func(revision string) (*http.Response, error) {
+		_, httpRes, err := c.api.NetworkDeviceAPI.
+			UpdateNetworkDevice(c.ctx, deviceId).
+			UpdateNetworkDevice(update).
+			IfMatch(revision).
+			Execute()
+		return httpRes, err
+	})
 	return response_inspector.InspectResponse(httpRes, err)
 }
 
@@ -128,11 +149,34 @@ func (c *sdkClient) ListPorts(deviceId int64) ([]*PortRecord, error) {
 		for _, addr := range p.Ipv4.Addresses {
 			rec.Ipv4Addresses = append(rec.Ipv4Addresses, IpAddress{Address: addr.Address, PrefixLength: addr.PrefixLength})
 		}
+		for _, addr := range p.Ipv6.Addresses {
+			rec.Ipv6Addresses = append(rec.Ipv6Addresses, IpAddress{Address: addr.Address, PrefixLength: addr.PrefixLength})
+		}
 		out = append(out, rec)
 	}
 	return out, nil
 }
 
+func (c *sdkClient) ListFabricLinks(fabricId int64) ([]*FabricLinkRecord, error) {
+	request := c.api.NetworkFabricAPI.GetNetworkFabricLinks(c.ctx, fabricId)
+	links, _, err := utils.FetchAllPages(request)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*FabricLinkRecord, 0, len(links))
+	for _, l := range links {
+		if l.NetworkDeviceAInterfaceId == nil || l.NetworkDeviceBInterfaceId == nil {
+			continue
+		}
+		out = append(out, &FabricLinkRecord{
+			Id:           l.Id,
+			InterfaceAId: *l.NetworkDeviceAInterfaceId,
+			InterfaceBId: *l.NetworkDeviceBInterfaceId,
+		})
+	}
+	return out, nil
+}
+
 func (c *sdkClient) UpdatePortConfig(deviceId, portId int64, enabled *bool, description *string, configRevision int64) error {
 	update := sdk.UpdateNetworkEquipmentInterfaceConfig{}
 	if enabled != nil {
@@ -141,36 +185,88 @@ func (c *sdkClient) UpdatePortConfig(deviceId, portId int64, enabled *bool, desc
 	if description != nil {
 		update.Description = *sdk.NewNullableString(description)
 	}
-	_, httpRes, err := c.api.NetworkDeviceAPI.
-		UpdateNetworkDevicePortConfig(c.ctx, deviceId, portId).
-		UpdateNetworkEquipmentInterfaceConfig(update).
-		IfMatch(strconv.FormatInt(configRevision, 10)).
-		Execute()
+	httpRes, err := retryOnRevisionMismatch(strconv.FormatInt(configRevision, 10), func(revision string) (*http.Response, error) {
+		_, httpRes, err := c.api.NetworkDeviceAPI.
+			UpdateNetworkDevicePortConfig(c.ctx, deviceId, portId).
+			UpdateNetworkEquipmentInterfaceConfig(update).
+			IfMatch(revision).
+			Execute()
+		return httpRes, err
+	})
 	return response_inspector.InspectResponse(httpRes, err)
 }
 
 func (c *sdkClient) AddPortIpv4(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error {
+	return c.addPortIp(deviceId, portId, "ipv4", address, prefixLength, configRevision)
+}
+
+func (c *sdkClient) AddPortIpv6(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error {
+	return c.addPortIp(deviceId, portId, "ipv6", address, prefixLength, configRevision)
+}
+
+func (c *sdkClient) addPortIp(deviceId, portId int64, ipVersion string, address string, prefixLength int32, configRevision int64) error {
 	payload := sdk.AddNetworkEquipmentInterfaceIp{Address: address, PrefixLength: prefixLength}
 	// The lock checks a one-based counter while the single-port GET exposes a
 	// zero-based config.revision: send revision+1, retry once on a 409 mismatch.
-	revision := configRevision + 1
-	_, httpRes, err := c.api.NetworkDeviceAPI.
-		AddNetworkDevicePortIp(c.ctx, deviceId, portId, "ipv4").
-		AddNetworkEquipmentInterfaceIp(payload).
-		IfMatch(strconv.FormatInt(revision, 10)).
-		Execute()
-	if err != nil && httpRes != nil && httpRes.StatusCode == 409 {
-		if expected := expectedRevision(err); expected != "" {
-			_, httpRes, err = c.api.NetworkDeviceAPI.
-				AddNetworkDevicePortIp(c.ctx, deviceId, portId, "ipv4").
-				AddNetworkEquipmentInterfaceIp(payload).
-				IfMatch(expected).
-				Execute()
+	httpRes, err := retryOnRevisionMismatch(strconv.FormatInt(configRevision+1, 10), func(revision string) (*http.Response, error) {
+		_, httpRes, err := c.api.NetworkDeviceAPI.
+			AddNetworkDevicePortIp(c.ctx, deviceId, portId, ipVersion).
+			AddNetworkEquipmentInterfaceIp(payload).
+			IfMatch(revision).
+			Execute()
+		return httpRes, err
+	})
+	return response_inspector.InspectResponse(httpRes, err)
+}
+
+func (c *sdkClient) RemovePortIpv4(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error {
+	return c.removePortIp(deviceId, portId, "ipv4", address, prefixLength, configRevision)
+}
+
+func (c *sdkClient) RemovePortIpv6(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error {
+	return c.removePortIp(deviceId, portId, "ipv6", address, prefixLength, configRevision)
+}
+
+// removePortIp is the inverse of addPortIp. The SDK has no wrapper for it, so
+// the DELETE is sent by hand with the same body and the same revision rules.
+func (c *sdkClient) removePortIp(deviceId, portId int64, ipVersion string, address string, prefixLength int32, configRevision int64) error {
+	path := fmt.Sprintf("/api/v2/network-devices/%d/ports/%d/config/ips/%s", deviceId, portId, ipVersion)
+	body, err := json.Marshal(map[string]any{"address": address, "prefixLength": prefixLength})
+	if err != nil {
+		return err
+	}
+	send := func(revision string) (*http.Response, error) {
+		return api.DoJSONRequestWithHeaders(c.ctx, http.MethodDelete, path, body, map[string]string{"If-Match": revision})
+	}
+
+	httpRes, err := send(strconv.FormatInt(configRevision+1, 10))
+	if err == nil && httpRes != nil && httpRes.StatusCode == http.StatusConflict {
+		payload, _ := io.ReadAll(httpRes.Body)
+		httpRes.Body.Close()
+		if m := revisionMismatchRe.FindSubmatch(payload); m != nil {
+			httpRes, err = send(string(m[1]))
+		} else {
+			httpRes.Body = io.NopCloser(bytes.NewReader(payload))
 		}
 	}
 	return response_inspector.InspectResponse(httpRes, err)
 }
 
+// retryOnRevisionMismatch sends a write under the If-Match revision and, when
+// the server rejects it with a 409 naming the revision it expected, once more
+// under that one. The revision a run read can be stale by the time it writes:
+// another write to the same device (or a parallel task's) may have moved it.
+func retryOnRevisionMismatch(revision string, send func(revision string) (*http.Response, error)) (*http.Response, error) {
+	httpRes, err := send(revision)
+	if err != nil && httpRes != nil && httpRes.StatusCode == http.StatusConflict {
+		if expected := expectedRevision(err); expected != "" {
+			logger.Get().Debug().Msgf("revision %s rejected, retrying with revision %s", revision, expected)
+			return send(expected)
+		}
+	}
+	return httpRes, err
+}
+
 func expectedRevision(err error) string {
 	var apiErr sdk.GenericOpenAPIError
 	if errors.As(err, &apiErr) {
@@ -186,7 +282,7 @@ func expectedRevision(err error) string {
 // strategy is a oneOf whose "unnumbered" variant is just {kind, scope}, so every
 // auto/manual strategy also matches it ("data matches more than one schema in
 // oneOf"). We only need the link id/revision, the switch-interface ids, and
-// whether an ipv4 strategy exists, so parse the raw body directly.
+// whether an ipv4/ipv6 strategy exists, so parse the raw body directly.
 type rawP2pInterface struct {
 	Type        string `json:"type"`
 	InterfaceId int64  `json:"interfaceId"`
@@ -198,12 +294,30 @@ type rawP2pLink struct {
 	InterfaceA *rawP2pInterface `json:"interfaceA"`
 	InterfaceB *rawP2pInterface `json:"interfaceB"`
 	Config     struct {
-		Ipv4 *struct {
-			SubnetAllocationStrategies []json.RawMessage `json:"subnetAllocationStrategies"`
-		} `json:"ipv4"`
+		Ipv4 *rawP2pFamilyConfig `json:"ipv4"`
+		Ipv6 *rawP2pFamilyConfig `json:"ipv6"`
 	} `json:"config"`
 }
 
+type rawP2pFamilyConfig struct {
+	SubnetAllocationStrategies []json.RawMessage `json:"subnetAllocationStrategies"`
+}
+
+// subnetIds returns the subnets of the family's manual strategies; strategies
+// of other kinds carry no subnetId and are skipped.
+func (c *rawP2pFamilyConfig) subnetIds() []int64 {
+	var ids []int64
+	for _, raw := range c.SubnetAllocationStrategies {
+		var strategy struct {
+			SubnetId *int64 `json:"subnetId"`
+		}
+		if err := json.Unmarshal(raw, &strategy); err == nil && strategy.SubnetId != nil {
+			ids = append(ids, *strategy.SubnetId)
+		}
+	}
+	return ids
+}
+
 func (c *sdkClient) ListP2pLinks() ([]*P2pLinkRecord, error) {
 	httpRes, err := api.DoJSONRequest(c.ctx, http.MethodGet, "/api/v2/point-to-point-links", nil)
 	if err := response_inspector.InspectResponse(httpRes, err); err != nil {
@@ -238,6 +352,11 @@ func parseP2pLinksBody(body []byte) ([]*P2pLinkRecord, error) {
 		}
 		if link.Config.Ipv4 != nil && len(link.Config.Ipv4.SubnetAllocationStrategies) > 0 {
 			rec.HasIpv4Strategy = true
+			rec.SubnetIds = append(rec.SubnetIds, link.Config.Ipv4.subnetIds()...)
+		}
+		if link.Config.Ipv6 != nil && len(link.Config.Ipv6.SubnetAllocationStrategies) > 0 {
+			rec.HasIpv6Strategy = true
+			rec.SubnetIds = append(rec.SubnetIds, link.Config.Ipv6.subnetIds()...)
 		}
 		out = append(out, rec)
 	}
@@ -271,6 +390,13 @@ func (c *sdkClient) CreateP2pLink(payload P2pLinkCreate) (*P2pLinkRecord, error)
 			},
 		}
 	}
+	if payload.StagedSubnetIdIpv6 != nil {
+		create["ipv6"] = map[string]any{
+			"subnetAllocationStrategies": []map[string]any{
+				manualStrategyBody(*payload.StagedSubnetIdIpv6, payload.StagedBinding),
+			},
+		}
+	}
 
 	body, err := json.Marshal(create)
 	if err != nil {
@@ -294,6 +420,14 @@ func (c *sdkClient) CreateP2pLink(payload P2pLinkCreate) (*P2pLinkRecord, error)
 }
 
 func (c *sdkClient) CreateP2pIpv4Strategy(linkId, subnetId int64, binding string, linkRevision int64) error {
+	return c.createP2pStrategy(linkId, "ipv4", subnetId, binding, linkRevision)
+}
+
+func (c *sdkClient) CreateP2pIpv6Strategy(linkId, subnetId int64, binding string, linkRevision int64) error {
+	return c.createP2pStrategy(linkId, "ipv6", subnetId, binding, linkRevision)
+}
+
+func (c *sdkClient) createP2pStrategy(linkId int64, ipVersion string, subnetId int64, binding string, linkRevision int64) error {
 	// Raw request for the same scope-serialization reason as CreateP2pLink; the
 	// config endpoint additionally requires If-Match with the link's revision.
 	body, err := json.Marshal(manualStrategyBody(subnetId, binding))
@@ -301,13 +435,21 @@ func (c *sdkClient) CreateP2pIpv4Strategy(linkId, subnetId int64, binding string
 		return err
 	}
 
-	path := fmt.Sprintf("/api/v2/point-to-point-links/%d/config/ipv4/subnet-allocation-strategies", linkId)
+	path := fmt.Sprintf("/api/v2/point-to-point-links/%d/config/%s/subnet-allocation-strategies", linkId, ipVersion)
 	headers := map[string]string{"If-Match": strconv.FormatInt(linkRevision, 10)}
 
 	httpRes, err := api.DoJSONRequestWithHeaders(c.ctx, http.MethodPost, path, body, headers)
 	return response_inspector.InspectResponse(httpRes, err)
 }
 
+func (c *sdkClient) DeleteP2pLink(linkId, linkRevision int64) error {
+	path := fmt.Sprintf("/api/v2/point-to-point-links/%d", linkId)
+	headers := map[string]string{"If-Match": strconv.FormatInt(linkRevision, 10)}
+
+	httpRes, err := api.DoJSONRequestWithHeaders(c.ctx, http.MethodDelete, path, nil, headers)
+	return response_inspector.InspectResponse(httpRes, err)
+}
+
 func pointToPointInterface(interfaceId int64) map[string]any {
 	return map[string]any{
 		"type":        string(sdk.POINTTOPOINTINTERFACETYPE_NETWORK_EQUIPMENT_INTERFACE),
@@ -315,7 +457,7 @@ func pointToPointInterface(interfaceId int64) map[string]any {
 	}
 }
 
-// manualStrategyBody is the manual /31 allocation-strategy body. The scope is
+// manualStrategyBody is the manual /31 (/127) allocation-strategy body. The scope is
 // `{"kind":"global"}` with no resourceId (matching the reference implementation);
 // see CreateP2pLink for why this is built by hand rather than via the SDK.
 func manualStrategyBody(subnetId int64, binding string) map[string]any {
@@ -342,6 +484,7 @@ func (c *sdkClient) ListSubnetsByFabricTag(fabricId int64) ([]*SubnetRecord, err
 		}
 		out = append(out, &SubnetRecord{
 			Id:             s.Id,
+			Revision:       int64(s.Revision),
 			NetworkAddress: s.NetworkAddress,
 			PrefixLength:   s.PrefixLength,
 			Tags:           s.Tags,
@@ -366,5 +509,13 @@ func (c *sdkClient) CreateSubnet(payload SubnetCreate) (*SubnetRecord, error) {
 	if err := response_inspector.InspectResponse(httpRes, err); err != nil {
 		return nil, err
 	}
-	return &SubnetRecord{Id: subnet.Id, NetworkAddress: subnet.NetworkAddress, PrefixLength: subnet.PrefixLength, Tags: subnet.Tags}, nil
+	return &SubnetRecord{Id: subnet.Id, Revision: int64(subnet.Revision), NetworkAddress: subnet.NetworkAddress, PrefixLength: subnet.PrefixLength, Tags: subnet.Tags}, nil
+}
+
+func (c *sdkClient) DeleteSubnet(subnetId, revision int64) error {
+	httpRes, err := c.api.SubnetAPI.
+		DeleteSubnet(c.ctx, subnetId).
+		IfMatch(strconv.FormatInt(revision, 10)).
+		Execute()
+	return response_inspector.InspectResponse(httpRes, err)
 }
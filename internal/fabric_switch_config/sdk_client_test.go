@@ -2,8 +2,13 @@ package fabric_switch_config
 
 import (
 	"encoding/json"
+	"io"
+	"net/http"
 	"strings"
 	"testing"
+
+	"github.com/metalsoft-io/metalcloud-cli/internal/testutils"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
 )
 
 // TestManualStrategyBody guards the fix for the API rejecting a global scope with
@@ -55,7 +60,9 @@ func TestParseP2pLinksBody(t *testing.T) {
 	    "id": 43, "revision": 1,
 	    "interfaceA": {"type": "network_equipment_interface", "interfaceId": 3003},
 	    "interfaceB": {"type": "server_interface", "interfaceId": 9},
-	    "config": {"ipv4": {"subnetAllocationStrategies": []}}
+	    "config": {"ipv4": {"subnetAllocationStrategies": []}, "ipv6": {"subnetAllocationStrategies": [
+	      {"kind": "manual", "scope": {"kind": "global"}, "subnetId": 8, "interfaceABinding": "b_first"}
+	    ]}}
 	  }
 	]`)
 
@@ -74,8 +81,11 @@ func TestParseP2pLinksBody(t *testing.T) {
 	if r0.InterfaceAId == nil || *r0.InterfaceAId != 1001 || r0.InterfaceBId == nil || *r0.InterfaceBId != 2002 {
 		t.Errorf("link0 interface ids wrong: %v %v", r0.InterfaceAId, r0.InterfaceBId)
 	}
-	if !r0.HasIpv4Strategy {
-		t.Errorf("link0 should report an existing ipv4 strategy")
+	if !r0.HasIpv4Strategy || r0.HasIpv6Strategy {
+		t.Errorf("link0 should report an existing ipv4 strategy only")
+	}
+	if len(r0.SubnetIds) != 1 || r0.SubnetIds[0] != 7 {
+		t.Errorf("link0 subnet ids = %v, want [7]", r0.SubnetIds)
 	}
 
 	r1 := records[1]
@@ -86,7 +96,84 @@ func TestParseP2pLinksBody(t *testing.T) {
 	if r1.InterfaceBId != nil {
 		t.Errorf("link1 server_interface side should be nil, got %v", *r1.InterfaceBId)
 	}
-	if r1.HasIpv4Strategy {
-		t.Errorf("link1 should have no ipv4 strategy")
+	if r1.HasIpv4Strategy || !r1.HasIpv6Strategy {
+		t.Errorf("link1 should have an ipv6 strategy only")
+	}
+	if len(r1.SubnetIds) != 1 || r1.SubnetIds[0] != 8 {
+		t.Errorf("link1 subnet ids = %v, want [8]", r1.SubnetIds)
+	}
+}
+
+// updatedDevice is a network device as returned by the update endpoint.
+const updatedDevice = `{
+	"id":"5","revision":4,"status":"active","vendorId":1,"siteId":1,
+	"identifierString":"","applyIdentifierAsHostnameOnNextDeploy":false,
+	"description":"","chassisIdentifier":"",
+	"country":"","city":"","datacenterMeta":"","datacenterRoom":"","datacenterRack":"",
+	"rackPositionUpperUnit":0,"rackPositionLowerUnit":0,
+	"managementAddress":"10.0.0.1","managementAddressPrefixLength":24,
+	"managementAddressGateway":"10.0.0.254","managementPort":22,
+	"syslogEnabled":0,"snmpServiceEnabled":false,"snmpMonitoringEnabled":false,
+	"username":"admin","managementMacAddress":"AA:BB:CC:DD:EE:01",
+	"serialNumber":"SN001","driver":"cumulus42","position":"leaf",
+	"driftDetectionSyncStatus":"",
+	"orderIndex":1,"tags":[],"tagsMap":{},"readyForInitialConfiguration":0,
+	"bootstrapReadinessCheckInProgress":0,"subnetOobId":0,"subnetOobIndex":0,
+	"requiresOsInstall":false,"bootstrapExpectedPartnerHostname":"",
+	"vtepAddressIpv6":"",
+	"mlagSystemMac":"","mlagDomainId":0,"quarantineVlan":0,
+	"variablesMaterializedForOSAssets":{},"secretsMaterializedForOSAssets":{},
+	"bootstrapReadinessCheckResult":{},"isGateway":false
+}`
+
+// TestUpdateDeviceClearsWithNull checks the PATCH body the teardown sends:
+// cleared fields must be explicit JSON nulls, not empty strings or ASN 0,
+// and fields left alone must be absent.
+func TestUpdateDeviceClearsWithNull(t *testing.T) {
+	var payload map[string]json.RawMessage
+	var ifMatch string
+	ts := testutils.NewTestServer(map[string]http.HandlerFunc{
+		"/api/v2/network-devices/5": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPatch {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			raw, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				t.Errorf("request body is not a JSON object: %s", raw)
+			}
+			ifMatch = r.Header.Get("If-Match")
+			testutils.RawHandler(http.StatusOK, updatedDevice)(w, r)
+		},
+	})
+	defer ts.Close()
+
+	ctx := testutils.SetupTestContext(ts.URL)
+	client := NewSDKClient(ctx, api.GetApiClient(ctx))
+
+	applyHostname := false
+	err := client.UpdateDevice(5, DeviceUpdate{
+		ApplyIdentifierAsHostnameOnNextDeploy: &applyHostname,
+		ClearIdentifierString:                 true,
+		ClearAsn:                              true,
+		ClearLoopbackAddress:                  true,
+	}, 3)
+	if err != nil {
+		t.Fatalf("UpdateDevice: %v", err)
+	}
+
+	if ifMatch != "3" {
+		t.Errorf("If-Match = %q, want 3", ifMatch)
+	}
+	for _, field := range []string{"identifierString", "asn", "loopbackAddress"} {
+		if value, ok := payload[field]; !ok || string(value) != "null" {
+			t.Errorf("%s = %s (present %v), want null", field, value, ok)
+		}
+	}
+	if value := payload["applyIdentifierAsHostnameOnNextDeploy"]; string(value) != "false" {
+		t.Errorf("applyIdentifierAsHostnameOnNextDeploy = %s, want false", value)
+	}
+	if value, ok := payload["loopbackAddressIpv6"]; ok {
+		t.Errorf("loopbackAddressIpv6 = %s, want it left out", value)
 	}
 }
@@ -0,0 +1,338 @@
+package fabric_switch_config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+)
+
+// TeardownOptions selects what Unconfigure touches.
+type TeardownOptions struct {
+	// DryRun reports what would be removed or reset, without writing.
+	DryRun bool
+	// Positions limits the teardown to the devices of these positions; empty
+	// means every position.
+	Positions []string
+	// Pods limits the teardown to the devices whose pod tag (see
+	// TagKeysConfig) is one of these; empty means every pod.
+	Pods []string
+}
+
+func (o TeardownOptions) scoped() bool { return len(o.Positions) > 0 || len(o.Pods) > 0 }
+
+func (o TeardownOptions) includes(dev *Device, podTag string) bool {
+	if len(o.Positions) > 0 && !slices.Contains(o.Positions, dev.Position) {
+		return false
+	}
+	if len(o.Pods) > 0 && !slices.Contains(o.Pods, dev.TagsMap[podTag]) {
+		return false
+	}
+	return true
+}
+
+// Unconfigure is the inverse of Configure. It deletes the point-to-point links
+// and /31 (/127) subnets the runner created for the fabric - recognised by
+// their tags, so no configuration is needed for them - and, when config is
+// given, resets what the plan for config sets: hostnames, ASNs and loopback
+// addresses on the devices, loopback port IPs and port descriptions. A field
+// is only reset while it still holds the planned value, so anything changed
+// by hand since is left alone. Ports are never disabled.
+//
+// With a position or pod scope, only links touching a device in scope are
+// deleted, with the subnets they (or a device in scope) own.
+func Unconfigure(client Client, config *Config, fabricId int64, options TeardownOptions) (*RunResult, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	plan, err := loadPlan(client, config, fabricId)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &runner{
+		client:   client,
+		config:   config,
+		fabricId: fabricId,
+		dryRun:   options.DryRun,
+		state:    plan.state,
+		result:   &RunResult{Counters: map[string]int{}, Warnings: plan.state.Warnings},
+		recByID:  plan.recByID,
+		ports:    map[int64]map[string]*PortRecord{},
+		mu:       &sync.Mutex{},
+	}
+
+	for _, position := range options.Positions {
+		if _, ok := plan.groups[position]; !ok {
+			r.result.Warnings = append(r.result.Warnings,
+				fmt.Sprintf("scope names position %q but the fabric has no such devices", position))
+		}
+	}
+	podTag := config.TagKeys().Pod
+	var devices []*DeviceRecord
+	for _, position := range sortedGroupKeys(plan.groups) {
+		for _, dev := range plan.groups[position] {
+			if options.includes(dev, podTag) {
+				devices = append(devices, plan.recByID[dev.Id])
+			}
+		}
+	}
+	if len(devices) == 0 {
+		r.result.Warnings = append(r.result.Warnings, "no device of the fabric is in scope; nothing to do")
+		return r.result, nil
+	}
+	logger.Get().Info().Msgf("Tearing down %d device(s)", len(devices))
+
+	// Links are scoped by the interfaces they use, so every port is read first.
+	var listed []*DeviceRecord
+	for _, dev := range devices {
+		list, err := client.ListPorts(dev.Id)
+		if err != nil {
+			r.fail("[%s] listing ports failed: %s", dev.Label(), err.Error())
+			continue
+		}
+		byName := map[string]*PortRecord{}
+		for _, p := range list {
+			if p.InterfaceName != "" {
+				byName[p.InterfaceName] = p
+			}
+		}
+		r.ports[dev.Id] = byName
+		listed = append(listed, dev)
+	}
+
+	r.unconfigureLinks(devices, options.scoped())
+	for _, dev := range listed {
+		r.unconfigurePorts(dev)
+	}
+	for _, dev := range devices {
+		r.unconfigureDevice(dev)
+	}
+	return r.result, nil
+}
+
+// ownedSubnets are the subnets of the fabric the runner created: they carry
+// the link-layer tag next to the fabric tag.
+func ownedSubnets(subnets []*SubnetRecord) map[int64]*SubnetRecord {
+	owned := map[int64]*SubnetRecord{}
+	for _, s := range subnets {
+		if s.Tags[tagLinkLayer] != "" {
+			owned[s.Id] = s
+		}
+	}
+	return owned
+}
+
+// ownsLink reports whether link allocates from a subnet the runner created.
+func ownsLink(owned map[int64]*SubnetRecord, link *P2pLinkRecord) bool {
+	for _, id := range link.SubnetIds {
+		if owned[id] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *runner) unconfigureLinks(devices []*DeviceRecord, scoped bool) {
+	links, err := r.client.ListP2pLinks()
+	if err != nil {
+		r.fail("listing point-to-point links failed: %s", err.Error())
+		return
+	}
+	subnets, err := r.client.ListSubnetsByFabricTag(r.fabricId)
+	if err != nil {
+		r.fail("listing subnets failed: %s", err.Error())
+		return
+	}
+	owned := ownedSubnets(subnets)
+
+	scopeIfaces := map[int64]bool{}
+	scopeNames := map[string]bool{}
+	for _, dev := range devices {
+		for _, p := range r.ports[dev.Id] {
+			scopeIfaces[p.InterfaceId] = true
+		}
+		scopeNames[dev.Label()] = true
+		scopeNames[r.endpointName(&dev.Device)] = true
+	}
+	touchesScope := func(link *P2pLinkRecord) bool {
+		return (link.InterfaceAId != nil && scopeIfaces[*link.InterfaceAId]) ||
+			(link.InterfaceBId != nil && scopeIfaces[*link.InterfaceBId])
+	}
+
+	kept := map[int64]bool{}    // subnets still allocated from by a link that stays
+	removed := map[int64]bool{} // subnets of the links deleted here
+	var doomed []*P2pLinkRecord
+	for _, link := range links {
+		if !ownsLink(owned, link) {
+			continue
+		}
+		if scoped && !touchesScope(link) {
+			for _, id := range link.SubnetIds {
+				kept[id] = true
+			}
+			continue
+		}
+		doomed = append(doomed, link)
+	}
+
+	ifaceNames := r.interfaceNames()
+	for _, link := range doomed {
+		label := fmt.Sprintf("link %d", link.Id)
+		if link.InterfaceAId != nil {
+			device, port := ifaceNames.describe(*link.InterfaceAId)
+			label = fmt.Sprintf("link %d (%s:%s)", link.Id, device, port)
+		}
+		if r.dryRun {
+			r.info("would delete %s", label)
+		} else if err := r.client.DeleteP2pLink(link.Id, link.Revision); err != nil {
+			r.fail("deleting %s failed: %s", label, err.Error())
+			for _, id := range link.SubnetIds {
+				kept[id] = true
+			}
+			continue
+		}
+		r.result.count("links deleted")
+		for _, id := range link.SubnetIds {
+			removed[id] = true
+		}
+	}
+
+	for _, s := range subnets {
+		if owned[s.Id] == nil || kept[s.Id] {
+			continue
+		}
+		if scoped && !removed[s.Id] && !scopeNames[s.Tags[tagEndpointA]] && !scopeNames[s.Tags[tagEndpointB]] {
+			continue
+		}
+		label := fmt.Sprintf("%s/%d", s.NetworkAddress, s.PrefixLength)
+		if r.dryRun {
+			r.info("would delete subnet %s", label)
+		} else if err := r.client.DeleteSubnet(s.Id, s.Revision); err != nil {
+			r.fail("deleting subnet %s failed: %s", label, err.Error())
+			continue
+		}
+		r.result.count("subnets deleted")
+	}
+}
+
+// unconfigurePorts clears the descriptions the plan sets and removes the
+// planned loopback addresses from the loopback port.
+func (r *runner) unconfigurePorts(dev *DeviceRecord) {
+	label := dev.Label()
+	byName := r.ports[dev.Id]
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	haveDescriptionTemplate := r.config.DescriptionTemplate != nil && *r.config.DescriptionTemplate != ""
+	list := make([]*PortRecord, 0, len(names))
+	for _, name := range names {
+		port := byName[name]
+		list = append(list, port)
+
+		planned, ok := r.state.PortDescriptions[PortKey{dev.Id, name}]
+		if !ok && haveDescriptionTemplate && port.Kind == "physical" {
+			planned, ok = PendingDescription, true
+		}
+		if !ok || planned == "" || port.Description == nil || *port.Description != planned {
+			continue
+		}
+		if r.dryRun {
+			r.result.count("port descriptions cleared")
+			continue
+		}
+		empty := ""
+		if err := r.client.UpdatePortConfig(dev.Id, port.InterfaceId, nil, &empty, port.ConfigRevision); err != nil {
+			r.fail("[%s:%s] clearing the description failed: %s", label, name, err.Error())
+			continue
+		}
+		r.result.count("port descriptions cleared")
+	}
+
+	desired := r.state.ByDevice[dev.Id]
+	if desired == nil || (desired.LoopbackIp == nil && desired.LoopbackIpv6 == nil) {
+		return
+	}
+	loopback := loopbackPort(list)
+	if loopback == nil {
+		return
+	}
+	if desired.LoopbackIp != nil {
+		r.removeLoopbackAddress(dev, loopback, *desired.LoopbackIp, 32, loopback.Ipv4Addresses, r.client.RemovePortIpv4)
+	}
+	if desired.LoopbackIpv6 != nil {
+		r.removeLoopbackAddress(dev, loopback, *desired.LoopbackIpv6, 128, loopback.Ipv6Addresses, r.client.RemovePortIpv6)
+	}
+}
+
+// removeLoopbackAddress is the inverse of addLoopbackAddress: it removes the
+// address if the loopback port has it.
+func (r *runner) removeLoopbackAddress(dev *DeviceRecord, loopback *PortRecord, address string, prefixLength int32,
+	existing []IpAddress, remove func(deviceId, portId int64, address string, prefixLength int32, configRevision int64) error) {
+	for _, addr := range existing {
+		if normalizeAddress(addr.Address) != normalizeAddress(address) || addr.PrefixLength != prefixLength {
+			continue
+		}
+		if r.dryRun {
+			r.result.count("loopback IPs removed")
+			return
+		}
+		if err := remove(dev.Id, loopback.InterfaceId, addr.Address, prefixLength, loopback.ConfigRevision); err != nil {
+			r.fail("[%s] removing loopback IP %s/%d failed: %s", dev.Label(), address, prefixLength, err.Error())
+			return
+		}
+		loopback.ConfigRevision++
+		r.result.count("loopback IPs removed")
+		return
+	}
+}
+
+// unconfigureDevice clears the device fields that still hold their planned
+// value.
+func (r *runner) unconfigureDevice(dev *DeviceRecord) {
+	desired := r.state.ByDevice[dev.Id]
+	if desired == nil {
+		return
+	}
+	label := dev.Label()
+	body := DeviceUpdate{}
+
+	if desired.Hostname != nil && dev.IdentifierString == *desired.Hostname {
+		body.ClearIdentifierString = true
+		if cumulusDrivers[dev.Driver] && dev.ApplyIdentifierAsHostnameOnNextDeploy {
+			f := false
+			body.ApplyIdentifierAsHostnameOnNextDeploy = &f
+		}
+	}
+	if desired.Asn != nil && dev.Asn == *desired.Asn {
+		body.ClearAsn = true
+	}
+	if desired.LoopbackIp != nil && dev.LoopbackAddressIpv4 != nil && *dev.LoopbackAddressIpv4 == *desired.LoopbackIp {
+		body.ClearLoopbackAddress = true
+	}
+	if desired.LoopbackIpv6 != nil && dev.LoopbackAddressIpv6 != nil && normalizeAddress(*dev.LoopbackAddressIpv6) == *desired.LoopbackIpv6 {
+		body.ClearLoopbackAddressIpv6 = true
+	}
+
+	logger.Get().Debug().Msgf("[%s] device reset: patch={%s}", label, describeDeviceUpdate(body))
+	if body.empty() {
+		r.result.count("devices unchanged")
+		return
+	}
+	if r.dryRun {
+		r.result.count("devices reset")
+		r.info("[%s] would reset device", label)
+		return
+	}
+	if err := r.client.UpdateDevice(dev.Id, body, dev.Revision); err != nil {
+		r.fail("[%s] device reset failed: %s", label, err.Error())
+		return
+	}
+	r.result.count("devices reset")
+	r.info("[%s] device reset", label)
+}
@@ -0,0 +1,118 @@
+package fabric_switch_config
+
+import "testing"
+
+func TestUnconfigure(t *testing.T) {
+	f, state := newFakeFromFixture(t, fixtureDevices(), fixtureConfig())
+	if _, err := Configure(f, fixtureConfig(), 5, false); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	links, subnets := len(f.p2pLinks), len(f.subnets)
+	// Renamed by hand since: the teardown must keep it.
+	f.devices[4].IdentifierString = "leaf-renamed"
+
+	dry, err := Unconfigure(f, fixtureConfig(), 5, TeardownOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry-run Unconfigure: %v", err)
+	}
+	if len(f.linksDeleted) != 0 || len(f.subnetsDeleted) != 0 || f.portIpRemoves != 0 {
+		t.Fatalf("dry-run made writes")
+	}
+	if dry.Counters["links deleted"] != links || dry.Counters["subnets deleted"] != subnets {
+		t.Errorf("dry-run counters = %v, want %d links and %d subnets", dry.Counters, links, subnets)
+	}
+
+	f.devicePatches, f.portPatches = 0, 0
+	res, err := Unconfigure(f, fixtureConfig(), 5, TeardownOptions{})
+	if err != nil {
+		t.Fatalf("Unconfigure: %v", err)
+	}
+	if res.Failures != 0 {
+		t.Fatalf("failures = %d, want 0", res.Failures)
+	}
+	for key, want := range dry.Counters {
+		if res.Counters[key] != want {
+			t.Errorf("%s = %d, dry-run said %d", key, res.Counters[key], want)
+		}
+	}
+	if len(f.p2pLinks) != 0 || len(f.subnets) != 0 {
+		t.Errorf("left %d link(s) and %d subnet(s)", len(f.p2pLinks), len(f.subnets))
+	}
+	if res.Counters["loopback IPs removed"] != len(f.devices) || f.portIpRemoves != len(f.devices) {
+		t.Errorf("loopback IPs removed = %d, want %d", res.Counters["loopback IPs removed"], len(f.devices))
+	}
+	if res.Counters["devices reset"] != len(f.devices) {
+		t.Errorf("devices reset = %d, want %d", res.Counters["devices reset"], len(f.devices))
+	}
+	if d := f.devices[4]; d.IdentifierString != "leaf-renamed" || d.Asn != 0 || d.LoopbackAddressIpv4 != nil {
+		t.Errorf("device 4 = hostname %q asn %d loopback %s", d.IdentifierString, d.Asn, strOrDash(d.LoopbackAddressIpv4))
+	}
+	if d := f.devices[2]; d.IdentifierString != "" {
+		t.Errorf("device 2 hostname = %q, want cleared", d.IdentifierString)
+	}
+	if res.Counters["port descriptions cleared"] < len(state.PortDescriptions) {
+		t.Errorf("port descriptions cleared = %d, want at least %d", res.Counters["port descriptions cleared"], len(state.PortDescriptions))
+	}
+	for _, p := range f.ports[4] {
+		if p.Description != nil && *p.Description != "" {
+			t.Errorf("device 4 port %s description = %q", p.InterfaceName, *p.Description)
+		}
+	}
+
+	// A second teardown finds nothing left to do.
+	again, err := Unconfigure(f, fixtureConfig(), 5, TeardownOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("second Unconfigure: %v", err)
+	}
+	for key, n := range again.Counters {
+		if key != "devices unchanged" && n != 0 {
+			t.Errorf("second teardown: %s = %d", key, n)
+		}
+	}
+}
+
+func TestUnconfigureScoped(t *testing.T) {
+	f, state := newFakeFromFixture(t, fixtureDevices(), fixtureConfig())
+	if _, err := Configure(f, fixtureConfig(), 5, false); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	sspLinks := 0
+	for _, l := range state.Links {
+		if l.Layer == "spineSuperSpine" {
+			sspLinks++
+		}
+	}
+	total := len(f.p2pLinks)
+	f.devicePatches = 0
+
+	// Without a configuration only the links and subnets are torn down.
+	res, err := Unconfigure(f, nil, 5, TeardownOptions{Positions: []string{"super_spine", "border"}})
+	if err != nil {
+		t.Fatalf("Unconfigure: %v", err)
+	}
+	if res.Counters["links deleted"] != sspLinks || len(f.p2pLinks) != total-sspLinks {
+		t.Errorf("links deleted = %d, want %d", res.Counters["links deleted"], sspLinks)
+	}
+	if res.Counters["subnets deleted"] != sspLinks {
+		t.Errorf("subnets deleted = %d, want %d", res.Counters["subnets deleted"], sspLinks)
+	}
+	for _, s := range f.subnets {
+		if s.Tags[tagLinkLayer] == layerTagValue["spineSuperSpine"] {
+			t.Errorf("spine-superspine subnet %s left behind", s.NetworkAddress)
+		}
+	}
+	if f.devicePatches != 0 || f.portIpRemoves != 0 || res.Counters["devices reset"] != 0 {
+		t.Errorf("teardown without a configuration touched devices")
+	}
+	if len(res.Warnings) == 0 || !contains(res.Warnings[len(res.Warnings)-1], `"border"`) {
+		t.Errorf("warnings = %v, want one for the unknown position", res.Warnings)
+	}
+
+	res, err = Unconfigure(f, nil, 5, TeardownOptions{Pods: []string{"99"}})
+	if err != nil {
+		t.Fatalf("Unconfigure: %v", err)
+	}
+	if len(res.Counters) != 0 || len(f.p2pLinks) != total-sspLinks {
+		t.Errorf("out-of-scope pod: counters = %v", res.Counters)
+	}
+}
@@ -44,12 +44,3 @@ func TestExpandTemplateErrors(t *testing.T) {
 		t.Error("ordinalBy without callback should error")
 	}
 }
-
-func TestLogicalToSwp(t *testing.T) {
-	cases := map[int]string{1: "swp1s0", 2: "swp1s1", 65: "swp33s0", 128: "swp64s1"}
-	for logical, want := range cases {
-		if got := logicalToSwp(logical); got != want {
-			t.Errorf("logicalToSwp(%d) = %q, want %q", logical, got, want)
-		}
-	}
-}
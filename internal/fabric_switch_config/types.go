@@ -8,6 +8,8 @@
 // nothing in this package performs network calls.
 package fabric_switch_config
 
+import "strings"
+
 // Device is the minimal, SDK-independent view of a fabric network device the
 // compute engine needs. The runner builds these from sdk.NetworkDevice.
 type Device struct {
@@ -34,12 +36,13 @@ func (d *Device) Label() string {
 // DeviceDesired holds the computed per-device target fields. A nil pointer means
 // "this feature was not configured for this device" (leave it untouched).
 type DeviceDesired struct {
-	Hostname   *string
-	Asn        *int64
-	LoopbackIp *string
+	Hostname     *string
+	Asn          *int64
+	LoopbackIp   *string
+	LoopbackIpv6 *string
 }
 
-// Subnet is a computed IPv4 subnet (always a /31 for p2p links).
+// Subnet is a computed p2p subnet: a /31, or a /127 for the IPv6 underlay.
 type Subnet struct {
 	NetworkAddress string
 	PrefixLength   int
@@ -49,11 +52,17 @@ func (s Subnet) String() string {
 	return s.NetworkAddress + "/" + itoa(int64(s.PrefixLength))
 }
 
+// IsIpv6 reports whether the subnet is an IPv6 one.
+func (s Subnet) IsIpv6() bool {
+	return strings.Contains(s.NetworkAddress, ":")
+}
+
 // LinkPlan is a fully-connected fabric link. DeviceA is the gateway side
 // (interfaceA, smaller/even IP, binding a_first): the leaf on leafSpine links,
 // the spine on spineSuperSpine links. PoolOffset is the /31's base-address
-// offset from the layer's pool (pool-independent); Subnet is set only once a
-// p2p pool has been assigned.
+// offset from the layer's pool (pool-independent); Subnet and SubnetIpv6 (the
+// /127 at the same offset) are set only once the p2p pools have been assigned,
+// each only when its address family is enabled.
 type LinkPlan struct {
 	Layer      string // "leafSpine" | "spineSuperSpine"
 	DeviceA    *Device
@@ -62,6 +71,7 @@ type LinkPlan struct {
 	PortB      string
 	PoolOffset int
 	Subnet     *Subnet
+	SubnetIpv6 *Subnet
 }
 
 // HostLinkPlan is a leaf->host downlink: a half-connected p2p link (the host
@@ -76,6 +86,7 @@ type HostLinkPlan struct {
 	Nic         string // remote NIC netdev name (for subnet tags)
 	PoolOffset  int
 	Subnet      *Subnet
+	SubnetIpv6  *Subnet
 }
 
 // PortKey identifies a (device, port-name) pair.
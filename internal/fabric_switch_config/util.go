@@ -1,8 +1,10 @@
 package fabric_switch_config
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
+	"net/netip"
 	"strconv"
 )
 
@@ -18,10 +20,11 @@ func configErrorf(format string, args ...any) error {
 
 func itoa(n int64) string { return strconv.FormatInt(n, 10) }
 
-// logicalToSwp returns the breakout sub-port name for a logical split index
-// (1=swp1s0, 2=swp1s1, ..., 128=swp64s1).
-func logicalToSwp(logical int) string {
-	return fmt.Sprintf("swp%ds%d", (logical+1)/2, 1-(logical%2))
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
 
 // ipv4ToUint parses a dotted IPv4 string into a uint32. Returns ok=false for
@@ -80,3 +83,59 @@ func (n ipv4Network) containsSubnet(addr uint32, prefixLen int) bool {
 	last := addr | (uint32(1)<<uint(hostBits) - 1)
 	return addr >= n.base && last <= n.lastAddress()
 }
+
+// ipv6Network is a parsed IPv6 CIDR (masked prefix).
+type ipv6Network struct {
+	prefix netip.Prefix
+}
+
+func parseIpv6Network(cidr string) (ipv6Network, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return ipv6Network{}, err
+	}
+	if !prefix.Addr().Is6() || prefix.Addr().Is4In6() {
+		return ipv6Network{}, fmt.Errorf("not an IPv6 network: %s", cidr)
+	}
+	return ipv6Network{prefix: prefix.Masked()}, nil
+}
+
+func (n ipv6Network) prefixLen() int { return n.prefix.Bits() }
+
+// addressAt returns the network address plus offset.
+func (n ipv6Network) addressAt(offset uint64) netip.Addr {
+	return addIpv6(n.prefix.Addr(), offset)
+}
+
+// containsSubnet reports whether the subnet at base 'addr' fits entirely within n.
+func (n ipv6Network) containsSubnet(addr netip.Addr, prefixLen int) bool {
+	if prefixLen < n.prefixLen() || prefixLen <= 64 {
+		return false
+	}
+	last := addIpv6(addr, uint64(1)<<uint(128-prefixLen)-1)
+	return n.prefix.Contains(addr) && n.prefix.Contains(last) && !last.Less(addr)
+}
+
+// addIpv6 adds offset to an IPv6 address, wrapping at the top of the space.
+func addIpv6(addr netip.Addr, offset uint64) netip.Addr {
+	b := addr.As16()
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	sum := lo + offset
+	if sum < lo {
+		hi++
+	}
+	binary.BigEndian.PutUint64(b[:8], hi)
+	binary.BigEndian.PutUint64(b[8:], sum)
+	return netip.AddrFrom16(b)
+}
+
+// normalizeAddress returns the canonical text of an IP address ("" if invalid),
+// so IPv6 addresses compare equal regardless of zero compression.
+func normalizeAddress(s string) string {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return ""
+	}
+	return addr.Unmap().String()
+}
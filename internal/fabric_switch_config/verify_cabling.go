@@ -0,0 +1,225 @@
+package fabric_switch_config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Cabling check statuses.
+const (
+	CablingOk         = "ok"
+	CablingMiswired   = "miswired"
+	CablingMissing    = "missing"
+	CablingUnexpected = "unexpected"
+)
+
+// CablingCheck is the verdict for one planned cable, or for one known link no
+// cable of the plan accounts for (CablingUnexpected, without a CableId).
+// ActualA/ActualB are the "device:port" the two ends are really connected to,
+// empty when nothing was discovered on that end.
+type CablingCheck struct {
+	CableId    string `json:"cableId,omitempty"`
+	Status     string `json:"status"`
+	Layer      string `json:"layer,omitempty"`
+	DeviceA    string `json:"deviceA"`
+	PortA      string `json:"portA"`
+	DeviceB    string `json:"deviceB"`
+	PortB      string `json:"portB"`
+	ActualA    string `json:"actualA,omitempty"`
+	ActualB    string `json:"actualB,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// CablingReport is the outcome of VerifyCabling. Leaf->host downlinks cannot
+// be verified from fabric links (the hosts are not fabric devices); they are
+// only counted in Unverified.
+type CablingReport struct {
+	Checks     []CablingCheck
+	Counts     map[string]int
+	Unverified int
+}
+
+// Mismatches is the number of miswired, missing and unexpected links.
+func (r *CablingReport) Mismatches() int {
+	return r.Counts[CablingMiswired] + r.Counts[CablingMissing] + r.Counts[CablingUnexpected]
+}
+
+// portRef is one end of a cable: a device port, by name.
+type portRef struct {
+	DeviceId int64
+	Port     string
+}
+
+// VerifyCabling compares the fabric links the controller knows about (after a
+// link rescan, the LLDP-discovered neighbours) with the fabric cables planned
+// for config. It only reads.
+func VerifyCabling(client Client, config *Config, fabricId int64) (*CablingReport, error) {
+	plan, err := loadPlan(client, config, fabricId)
+	if err != nil {
+		return nil, err
+	}
+	cabling := buildCablingPlan(plan.fabric, plan.groups, plan.state)
+
+	names := map[int64]string{}
+	for _, d := range cabling.Devices {
+		names[d.Id] = d.Name
+	}
+
+	deviceIds := make([]int64, 0, len(plan.recByID))
+	for id := range plan.recByID {
+		deviceIds = append(deviceIds, id)
+	}
+	sort.Slice(deviceIds, func(i, j int) bool { return deviceIds[i] < deviceIds[j] })
+	ifaces := map[int64]portRef{}
+	for _, id := range deviceIds {
+		ports, err := client.ListPorts(id)
+		if err != nil {
+			return nil, fmt.Errorf("listing ports of device %d: %w", id, err)
+		}
+		for _, p := range ports {
+			ifaces[p.InterfaceId] = portRef{DeviceId: id, Port: p.InterfaceName}
+		}
+	}
+
+	links, err := client.ListFabricLinks(fabricId)
+	if err != nil {
+		return nil, err
+	}
+	return checkCabling(cabling, names, ifaces, links), nil
+}
+
+// checkCabling is the pure comparison behind VerifyCabling. ifaces resolves
+// interface ids to device ports; links on interfaces outside the fabric are
+// reported by interface id.
+func checkCabling(cabling *CablingPlan, names map[int64]string, ifaces map[int64]portRef, links []*FabricLinkRecord) *CablingReport {
+	report := &CablingReport{Checks: []CablingCheck{}, Counts: map[string]int{}}
+
+	resolve := func(ifaceId int64) portRef {
+		if ref, ok := ifaces[ifaceId]; ok {
+			return ref
+		}
+		return portRef{Port: fmt.Sprintf("interface %d", ifaceId)}
+	}
+	deviceName := func(ref portRef) string {
+		if ref.DeviceId == 0 {
+			return "unknown"
+		}
+		if name, ok := names[ref.DeviceId]; ok {
+			return name
+		}
+		return "id=" + itoa(ref.DeviceId)
+	}
+	describe := func(ref portRef) string { return deviceName(ref) + ":" + ref.Port }
+
+	// Discovered adjacency, both directions. A port carries one neighbour, so
+	// only the first link seen on a port counts.
+	actual := map[portRef]portRef{}
+	for _, l := range links {
+		a, b := resolve(l.InterfaceAId), resolve(l.InterfaceBId)
+		if _, ok := actual[a]; !ok {
+			actual[a] = b
+		}
+		if _, ok := actual[b]; !ok {
+			actual[b] = a
+		}
+	}
+
+	// Planned adjacency of the fabric cables, both directions.
+	expected := map[portRef]portRef{}
+	cableByPort := map[portRef]string{}
+	for _, c := range cabling.Cables {
+		if c.DeviceBId == nil {
+			report.Unverified++
+			continue
+		}
+		a, b := portRef{c.DeviceAId, c.PortA}, portRef{*c.DeviceBId, c.PortB}
+		expected[a], expected[b] = b, a
+		cableByPort[a], cableByPort[b] = c.Id, c.Id
+	}
+
+	for _, c := range cabling.Cables {
+		if c.DeviceBId == nil {
+			continue
+		}
+		a, b := portRef{c.DeviceAId, c.PortA}, portRef{*c.DeviceBId, c.PortB}
+		check := CablingCheck{
+			CableId: c.Id,
+			Layer:   c.Layer,
+			DeviceA: c.DeviceA,
+			PortA:   c.PortA,
+			DeviceB: c.DeviceB,
+			PortB:   c.PortB,
+		}
+		peerA, okA := actual[a]
+		peerB, okB := actual[b]
+		if okA {
+			check.ActualA = describe(peerA)
+		}
+		if okB {
+			check.ActualB = describe(peerB)
+		}
+
+		switch {
+		case okA && peerA == b:
+			check.Status = CablingOk
+		case !okA && !okB:
+			check.Status = CablingMissing
+			check.Suggestion = fmt.Sprintf("connect %s to %s", describe(a), describe(b))
+		default:
+			check.Status = CablingMiswired
+			check.Suggestion = suggestRepatch(a, b, peerA, okA, peerB, okB, actual, expected, cableByPort, describe)
+		}
+		report.Checks = append(report.Checks, check)
+		report.Counts[check.Status]++
+	}
+
+	// Known links with neither end on a planned fabric port. Links touching a
+	// planned port already show up in that cable's check.
+	for _, l := range links {
+		a, b := resolve(l.InterfaceAId), resolve(l.InterfaceBId)
+		_, plannedA := expected[a]
+		_, plannedB := expected[b]
+		if plannedA || plannedB {
+			continue
+		}
+		check := CablingCheck{
+			Status:     CablingUnexpected,
+			DeviceA:    deviceName(a),
+			PortA:      a.Port,
+			DeviceB:    deviceName(b),
+			PortB:      b.Port,
+			ActualA:    describe(b),
+			ActualB:    describe(a),
+			Suggestion: "not in the cabling plan, remove the cable or fix the topology configuration",
+		}
+		report.Checks = append(report.Checks, check)
+		report.Counts[check.Status]++
+	}
+	return report
+}
+
+// suggestRepatch proposes the fix for a miswired cable a<->b. When the port a
+// is really connected to belongs to another planned cable whose other end is
+// connected to b, the two cables are crossed and one swap fixes both;
+// otherwise the wrong end is moved to where it belongs.
+func suggestRepatch(a, b, peerA portRef, okA bool, peerB portRef, okB bool,
+	actual, expected map[portRef]portRef, cableByPort map[portRef]string, describe func(portRef) string) string {
+	if okA {
+		if other, planned := expected[peerA]; planned && other != a {
+			if back, ok := actual[other]; ok && back == b {
+				return fmt.Sprintf("crossed with %s: swap the cables on %s and %s", cableByPort[peerA], describe(b), describe(peerA))
+			}
+		}
+	}
+	if okB {
+		if other, planned := expected[peerB]; planned && other != b {
+			if back, ok := actual[other]; ok && back == a {
+				return fmt.Sprintf("crossed with %s: swap the cables on %s and %s", cableByPort[peerB], describe(a), describe(peerB))
+			}
+		}
+	}
+	if okA {
+		return fmt.Sprintf("move the cable on %s from %s to %s", describe(a), describe(peerA), describe(b))
+	}
+	return fmt.Sprintf("move the cable on %s from %s to %s", describe(b), describe(peerB), describe(a))
+}
@@ -0,0 +1,103 @@
+package fabric_switch_config
+
+import (
+	"strings"
+	"testing"
+)
+
+func ifaceIdOf(t *testing.T, f *fakeClient, deviceId int64, name string) int64 {
+	t.Helper()
+	for _, p := range f.ports[deviceId] {
+		if p.InterfaceName == name {
+			return p.InterfaceId
+		}
+	}
+	t.Fatalf("device %d has no port %q", deviceId, name)
+	return 0
+}
+
+func TestVerifyCabling(t *testing.T) {
+	f, state := newFakeFromFixture(t, fixtureDevices(), fixtureConfig())
+	for i, l := range state.Links {
+		f.fabricLinks = append(f.fabricLinks, &FabricLinkRecord{
+			Id:           int64(i + 1),
+			InterfaceAId: ifaceIdOf(t, f, l.DeviceA.Id, l.PortA),
+			InterfaceBId: ifaceIdOf(t, f, l.DeviceB.Id, l.PortB),
+		})
+	}
+
+	report, err := VerifyCabling(f, fixtureConfig(), 5)
+	if err != nil {
+		t.Fatalf("VerifyCabling: %v", err)
+	}
+	if report.Mismatches() != 0 || report.Counts[CablingOk] != len(state.Links) {
+		t.Fatalf("clean fabric: counts = %v", report.Counts)
+	}
+	if report.Unverified != len(state.HostLinks) {
+		t.Errorf("unverified = %d, want %d", report.Unverified, len(state.HostLinks))
+	}
+
+	// Cross the B ends of the first two cables, unplug the third, and add a
+	// link between two spare ports.
+	f.fabricLinks[0].InterfaceBId, f.fabricLinks[1].InterfaceBId = f.fabricLinks[1].InterfaceBId, f.fabricLinks[0].InterfaceBId
+	f.fabricLinks = append(f.fabricLinks[:2], f.fabricLinks[3:]...)
+	f.fabricLinks = append(f.fabricLinks, &FabricLinkRecord{
+		Id:           999,
+		InterfaceAId: ifaceIdOf(t, f, 1, "swp64s1-spare"),
+		InterfaceBId: ifaceIdOf(t, f, 2, "swp64s1-spare"),
+	})
+
+	report, err = VerifyCabling(f, fixtureConfig(), 5)
+	if err != nil {
+		t.Fatalf("VerifyCabling: %v", err)
+	}
+	if report.Counts[CablingMiswired] != 2 || report.Counts[CablingMissing] != 1 || report.Counts[CablingUnexpected] != 1 {
+		t.Fatalf("counts = %v, want 2 miswired, 1 missing, 1 unexpected", report.Counts)
+	}
+	if report.Mismatches() != 4 {
+		t.Errorf("mismatches = %d, want 4", report.Mismatches())
+	}
+
+	byCable := map[string]CablingCheck{}
+	for _, c := range report.Checks {
+		byCable[c.CableId] = c
+	}
+	first := byCable["LS-0001"]
+	if first.Status != CablingMiswired || !strings.HasPrefix(first.Suggestion, "crossed with LS-0002: swap the cables on ") {
+		t.Errorf("LS-0001 = %+v", first)
+	}
+	if first.ActualA != "spine-pod5-r1-s1:swp1s1" {
+		t.Errorf("LS-0001 actual A = %q, want spine-pod5-r1-s1:swp1s1", first.ActualA)
+	}
+	if second := byCable["LS-0002"]; second.Status != CablingMiswired || !strings.Contains(second.Suggestion, "LS-0001") {
+		t.Errorf("LS-0002 = %+v", second)
+	}
+	if third := byCable["LS-0003"]; third.Status != CablingMissing || !strings.HasPrefix(third.Suggestion, "connect leaf-pod5-su1-r1:swp34s0 to ") {
+		t.Errorf("LS-0003 = %+v", third)
+	}
+	unexpected := byCable[""]
+	if unexpected.Status != CablingUnexpected || unexpected.PortA != "swp64s1-spare" || unexpected.DeviceB != "spine-pod5-r1-s1" {
+		t.Errorf("unexpected = %+v", unexpected)
+	}
+}
+
+func TestVerifyCablingMovedEnd(t *testing.T) {
+	cabling := &CablingPlan{Cables: []Cable{
+		{Id: "LS-0001", DeviceAId: 1, PortA: "swp1", DeviceBId: ptrInt64(2), PortB: "swp1"},
+	}}
+	names := map[int64]string{1: "leaf1", 2: "spine1"}
+	ifaces := map[int64]portRef{11: {1, "swp1"}, 21: {2, "swp1"}, 22: {2, "swp9"}}
+	links := []*FabricLinkRecord{{Id: 1, InterfaceAId: 22, InterfaceBId: 11}}
+
+	report := checkCabling(cabling, names, ifaces, links)
+	if len(report.Checks) != 1 {
+		t.Fatalf("checks = %+v", report.Checks)
+	}
+	c := report.Checks[0]
+	if c.Status != CablingMiswired || c.ActualA != "spine1:swp9" || c.ActualB != "" {
+		t.Errorf("check = %+v", c)
+	}
+	if c.Suggestion != "move the cable on leaf1:swp1 from spine1:swp9 to spine1:swp1" {
+		t.Errorf("suggestion = %q", c.Suggestion)
+	}
+}
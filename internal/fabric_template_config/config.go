@@ -32,11 +32,12 @@ const (
 var vrfTemplateAnnotations = map[string]string{"action": "switch-configure-vrf-create", "position": "leaf"}
 
 // templateSpec is a registered template: its find-or-create label, the profile
-// priority, and the (decoded) template body read from its .j2 file.
+// priority, and the (decoded) template body read from its .j2 file at Path.
 type templateSpec struct {
 	Label    string
 	Priority float32
 	Text     string
+	Path     string
 }
 
 // FreeformConfig is the resolved `freeform:` section.
@@ -166,7 +167,7 @@ func LoadFreeformConfig(data []byte) (*FreeformConfig, error) {
 		Mode:      raw.Mode,
 		HgxPrefix: raw.HgxPrefix,
 		ApplyMode: applyMode,
-		Template:  templateSpec{Label: resolveLabel(raw.TemplateLabel, defaultFreeformLabel), Priority: priority, Text: text},
+		Template:  templateSpec{Label: resolveLabel(raw.TemplateLabel, defaultFreeformLabel), Priority: priority, Text: text, Path: raw.TemplatePath},
 	}, nil
 }
 
@@ -220,9 +221,9 @@ func LoadBgpConfig(data []byte) (*BgpConfig, error) {
 	return &BgpConfig{
 		Mode:      raw.Mode,
 		ApplyMode: applyMode,
-		Underlay:  templateSpec{Label: resolveLabel(raw.TemplateLabel, defaultUnderlayLabel), Priority: underlayPriority, Text: underlayText},
-		Overlay:   templateSpec{Label: resolveLabel(raw.OverlayTemplateLabel, defaultOverlayLabel), Priority: overlayPriority, Text: overlayText},
-		Pfc:       templateSpec{Label: resolveLabel(raw.PfcTemplateLabel, defaultPfcLabel), Priority: pfcPriority, Text: pfcText},
-		Vrf:       templateSpec{Label: resolveLabel(raw.VrfTemplateLabel, defaultVrfLabel), Text: vrfText},
+		Underlay:  templateSpec{Label: resolveLabel(raw.TemplateLabel, defaultUnderlayLabel), Priority: underlayPriority, Text: underlayText, Path: raw.TemplatePath},
+		Overlay:   templateSpec{Label: resolveLabel(raw.OverlayTemplateLabel, defaultOverlayLabel), Priority: overlayPriority, Text: overlayText, Path: raw.OverlayTemplatePath},
+		Pfc:       templateSpec{Label: resolveLabel(raw.PfcTemplateLabel, defaultPfcLabel), Priority: pfcPriority, Text: pfcText, Path: raw.PfcTemplatePath},
+		Vrf:       templateSpec{Label: resolveLabel(raw.VrfTemplateLabel, defaultVrfLabel), Text: vrfText, Path: raw.VrfTemplatePath},
 	}, nil
 }
@@ -0,0 +1,932 @@
+package fabric_template_config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// jinjaTemplate is a parsed .j2 template body, for rendering without the
+// engine. It covers the Jinja2 syntax the engine's Nunjucks renderer shares and
+// device-configuration templates use: {{ }} expressions with filters and tests,
+// {% if/elif/else %}, {% for %} (with loop.* and else), {% set %} (also as a
+// block), {% macro %}, {% raw %}, {# #} comments and "-" whitespace control.
+// include/extends/import are not supported: templates are uploaded standalone.
+//
+// Values evaluate the way Nunjucks evaluates them, so the output matches the
+// engine's: numbers are floats, undefined and null print as nothing, lists
+// print comma-joined, booleans print as true/false and empty lists and maps
+// are true.
+type jinjaTemplate struct {
+	name string
+	body []jinjaNode
+}
+
+const (
+	jinjaText = iota
+	jinjaOutput
+	jinjaBlock
+)
+
+// jinjaToken is a piece of a template: literal text, the expression of a
+// {{ }} or the content of a {% %}.
+type jinjaToken struct {
+	kind int
+	text string
+	line int
+}
+
+var jinjaEndRaw = regexp.MustCompile(`\{%(-?)\s*endraw\s*(-?)%\}`)
+
+// parseJinja parses a template body; name is used in error messages.
+func parseJinja(name, text string) (*jinjaTemplate, error) {
+	tokens, err := splitJinja(name, text)
+	if err != nil {
+		return nil, err
+	}
+	p := &jinjaParser{name: name, tokens: tokens}
+	body, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil {
+		return nil, p.errorf(end.line, "unexpected {%% %s %%}", end.text)
+	}
+	return &jinjaTemplate{name: name, body: body}, nil
+}
+
+// splitJinja cuts a template into tokens, applying the whitespace control of
+// the tags and keeping {% raw %} sections as text.
+func splitJinja(name, text string) ([]jinjaToken, error) {
+	var tokens []jinjaToken
+	pos, line := 0, 1
+	trimNext := false
+	addText := func(s string, trimEnd bool) {
+		if trimNext {
+			s = strings.TrimLeft(s, " \t\r\n")
+		}
+		if trimEnd {
+			s = strings.TrimRight(s, " \t\r\n")
+		}
+		trimNext = false
+		if s != "" {
+			tokens = append(tokens, jinjaToken{kind: jinjaText, text: s, line: line})
+		}
+	}
+
+	for pos < len(text) {
+		start := nextJinjaTag(text, pos)
+		if start < 0 {
+			addText(text[pos:], false)
+			break
+		}
+		tagLine := line + strings.Count(text[pos:start], "\n")
+		open := text[start+1]
+		inner := start + 2
+		trimBefore := inner < len(text) && text[inner] == '-'
+		if trimBefore {
+			inner++
+		}
+		addText(text[pos:start], trimBefore)
+		line = tagLine
+
+		if open == '#' {
+			end := strings.Index(text[inner:], "#}")
+			if end < 0 {
+				return nil, fmt.Errorf("%s:%d: unclosed comment", name, tagLine)
+			}
+			end += inner
+			trimNext = end > inner && text[end-1] == '-'
+			line += strings.Count(text[start:end], "\n")
+			pos = end + 2
+			continue
+		}
+
+		closer := "}}"
+		kind := jinjaOutput
+		if open == '%' {
+			closer, kind = "%}", jinjaBlock
+		}
+		end := findJinjaCloser(text, inner, closer)
+		if end < 0 {
+			return nil, fmt.Errorf("%s:%d: unclosed %s", name, tagLine, text[start:start+2])
+		}
+		content := text[inner:end]
+		trimNext = strings.HasSuffix(content, "-")
+		content = strings.TrimSpace(strings.TrimSuffix(content, "-"))
+		line += strings.Count(text[start:end], "\n")
+		pos = end + 2
+
+		if kind == jinjaBlock && content == "raw" {
+			m := jinjaEndRaw.FindStringSubmatchIndex(text[pos:])
+			if m == nil {
+				return nil, fmt.Errorf("%s:%d: unclosed {%% raw %%}", name, tagLine)
+			}
+			raw := text[pos : pos+m[0]]
+			if trimNext {
+				raw = strings.TrimLeft(raw, " \t\r\n")
+			}
+			if m[3] > m[2] {
+				raw = strings.TrimRight(raw, " \t\r\n")
+			}
+			if raw != "" {
+				tokens = append(tokens, jinjaToken{kind: jinjaText, text: raw, line: line})
+			}
+			trimNext = m[5] > m[4]
+			line += strings.Count(text[pos:pos+m[1]], "\n")
+			pos += m[1]
+			continue
+		}
+		tokens = append(tokens, jinjaToken{kind: kind, text: content, line: tagLine})
+	}
+	return tokens, nil
+}
+
+// nextJinjaTag returns the index of the next "{{", "{%" or "{#" from pos, or -1.
+func nextJinjaTag(text string, pos int) int {
+	for {
+		i := strings.IndexByte(text[pos:], '{')
+		if i < 0 || pos+i+1 >= len(text) {
+			return -1
+		}
+		pos += i
+		switch text[pos+1] {
+		case '{', '%', '#':
+			return pos
+		}
+		pos++
+	}
+}
+
+// findJinjaCloser returns the index of closer from pos, skipping quoted strings.
+func findJinjaCloser(text string, pos int, closer string) int {
+	var quote byte
+	for i := pos; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case strings.HasPrefix(text[i:], closer):
+			return i
+		}
+	}
+	return -1
+}
+
+// jinjaNode is a node of a parsed template.
+type jinjaNode interface {
+	render(s *jinjaState, out *strings.Builder) error
+}
+
+type jinjaTextNode struct{ text string }
+
+type jinjaOutputNode struct {
+	expr jinjaExpr
+	line int
+}
+
+type jinjaIfBranch struct {
+	cond jinjaExpr
+	body []jinjaNode
+	line int
+}
+
+type jinjaIfNode struct {
+	branches []jinjaIfBranch
+	orElse   []jinjaNode
+}
+
+type jinjaForNode struct {
+	targets []string
+	iter    jinjaExpr
+	body    []jinjaNode
+	orElse  []jinjaNode
+	line    int
+}
+
+// jinjaSetNode assigns expr, or the rendered body of a block set, to targets.
+type jinjaSetNode struct {
+	targets []string
+	expr    jinjaExpr
+	body    []jinjaNode
+	line    int
+}
+
+type jinjaMacroNode struct {
+	name     string
+	params   []string
+	defaults map[string]jinjaExpr
+	body     []jinjaNode
+}
+
+type jinjaParser struct {
+	name   string
+	tokens []jinjaToken
+	pos    int
+}
+
+func (p *jinjaParser) errorf(line int, format string, args ...any) error {
+	return fmt.Errorf("%s:%d: %s", p.name, line, fmt.Sprintf(format, args...))
+}
+
+// parseBody parses nodes up to the end of the template or up to a block tag
+// it does not open itself (endif, else, endfor, ...), which it consumes and
+// returns for the caller to check.
+func (p *jinjaParser) parseBody() ([]jinjaNode, *jinjaToken, error) {
+	var nodes []jinjaNode
+	for p.pos < len(p.tokens) {
+		tok := &p.tokens[p.pos]
+		p.pos++
+		switch tok.kind {
+		case jinjaText:
+			nodes = append(nodes, &jinjaTextNode{text: tok.text})
+		case jinjaOutput:
+			expr, err := p.parseExpression(tok, tok.text)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, &jinjaOutputNode{expr: expr, line: tok.line})
+		case jinjaBlock:
+			keyword, rest := splitJinjaKeyword(tok.text)
+			var node jinjaNode
+			var err error
+			switch keyword {
+			case "if":
+				node, err = p.parseIf(tok, rest)
+			case "for":
+				node, err = p.parseFor(tok, rest)
+			case "set":
+				node, err = p.parseSet(tok, rest)
+			case "macro":
+				node, err = p.parseMacro(tok, rest)
+			case "include", "extends", "import", "from", "block", "call", "filter":
+				return nil, nil, p.errorf(tok.line, "{%% %s %%} is not supported by the local render", keyword)
+			default:
+				return nodes, tok, nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil, nil
+}
+
+// parseUntil parses a body that must be closed by one of the end keywords.
+func (p *jinjaParser) parseUntil(open *jinjaToken, ends ...string) ([]jinjaNode, *jinjaToken, string, error) {
+	body, end, err := p.parseBody()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if end == nil {
+		return nil, nil, "", p.errorf(open.line, "unclosed {%% %s %%}", open.text)
+	}
+	keyword, rest := splitJinjaKeyword(end.text)
+	for _, e := range ends {
+		if keyword == e {
+			return body, end, rest, nil
+		}
+	}
+	return nil, nil, "", p.errorf(end.line, "unexpected {%% %s %%} in {%% %s %%}", end.text, open.text)
+}
+
+func (p *jinjaParser) parseIf(tok *jinjaToken, rest string) (jinjaNode, error) {
+	node := &jinjaIfNode{}
+	open, condText := tok, rest
+	for {
+		cond, err := p.parseExpression(open, condText)
+		if err != nil {
+			return nil, err
+		}
+		body, end, endRest, err := p.parseUntil(tok, "elif", "else", "endif")
+		if err != nil {
+			return nil, err
+		}
+		node.branches = append(node.branches, jinjaIfBranch{cond: cond, body: body, line: open.line})
+		switch keyword, _ := splitJinjaKeyword(end.text); keyword {
+		case "elif":
+			open, condText = end, endRest
+			continue
+		case "else":
+			node.orElse, _, _, err = p.parseUntil(tok, "endif")
+			if err != nil {
+				return nil, err
+			}
+		}
+		return node, nil
+	}
+}
+
+func (p *jinjaParser) parseFor(tok *jinjaToken, rest string) (jinjaNode, error) {
+	targets, iterText, ok := strings.Cut(rest, " in ")
+	if !ok {
+		return nil, p.errorf(tok.line, "expected {%% for x in items %%}, got {%% %s %%}", tok.text)
+	}
+	node := &jinjaForNode{line: tok.line}
+	for _, target := range strings.Split(targets, ",") {
+		target = strings.TrimSpace(target)
+		if !isJinjaName(target) {
+			return nil, p.errorf(tok.line, "invalid loop variable %q", target)
+		}
+		node.targets = append(node.targets, target)
+	}
+	iter, err := p.parseExpression(tok, iterText)
+	if err != nil {
+		return nil, err
+	}
+	node.iter = iter
+	body, end, _, err := p.parseUntil(tok, "else", "endfor")
+	if err != nil {
+		return nil, err
+	}
+	node.body = body
+	if keyword, _ := splitJinjaKeyword(end.text); keyword == "else" {
+		node.orElse, _, _, err = p.parseUntil(tok, "endfor")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func (p *jinjaParser) parseSet(tok *jinjaToken, rest string) (jinjaNode, error) {
+	node := &jinjaSetNode{line: tok.line}
+	targets, value, hasValue := strings.Cut(rest, "=")
+	for _, target := range strings.Split(targets, ",") {
+		target = strings.TrimSpace(target)
+		if !isJinjaName(target) {
+			return nil, p.errorf(tok.line, "invalid variable name %q", target)
+		}
+		node.targets = append(node.targets, target)
+	}
+	if hasValue {
+		expr, err := p.parseExpression(tok, value)
+		if err != nil {
+			return nil, err
+		}
+		node.expr = expr
+		return node, nil
+	}
+	body, _, _, err := p.parseUntil(tok, "endset")
+	if err != nil {
+		return nil, err
+	}
+	node.body = body
+	return node, nil
+}
+
+func (p *jinjaParser) parseMacro(tok *jinjaToken, rest string) (jinjaNode, error) {
+	ep := &jinjaExprParser{}
+	if err := ep.tokenize(rest); err != nil {
+		return nil, p.errorf(tok.line, "%s", err.Error())
+	}
+	name := ep.next()
+	if name.kind != exprName || !ep.accept("(") {
+		return nil, p.errorf(tok.line, "expected {%% macro name(params) %%}, got {%% %s %%}", tok.text)
+	}
+	node := &jinjaMacroNode{name: name.text, defaults: map[string]jinjaExpr{}}
+	for !ep.accept(")") {
+		param := ep.next()
+		if param.kind != exprName {
+			return nil, p.errorf(tok.line, "invalid macro parameter %q", param.text)
+		}
+		node.params = append(node.params, param.text)
+		if ep.accept("=") {
+			def, err := ep.parseConditional()
+			if err != nil {
+				return nil, p.errorf(tok.line, "%s", err.Error())
+			}
+			node.defaults[param.text] = def
+		}
+		if !ep.accept(",") && ep.peek().text != ")" {
+			return nil, p.errorf(tok.line, "expected ',' or ')' in the macro parameters")
+		}
+	}
+	body, _, _, err := p.parseUntil(tok, "endmacro")
+	if err != nil {
+		return nil, err
+	}
+	node.body = body
+	return node, nil
+}
+
+func (p *jinjaParser) parseExpression(tok *jinjaToken, text string) (jinjaExpr, error) {
+	ep := &jinjaExprParser{}
+	if err := ep.tokenize(text); err != nil {
+		return nil, p.errorf(tok.line, "%s", err.Error())
+	}
+	expr, err := ep.parseConditional()
+	if err == nil && ep.peek().kind != exprEnd {
+		err = fmt.Errorf("unexpected %q", ep.peek().text)
+	}
+	if err != nil {
+		return nil, p.errorf(tok.line, "%s in %q", err.Error(), text)
+	}
+	return expr, nil
+}
+
+func splitJinjaKeyword(text string) (string, string) {
+	keyword, rest, _ := strings.Cut(text, " ")
+	return keyword, strings.TrimSpace(rest)
+}
+
+func isJinjaName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	exprEnd = iota
+	exprName
+	exprNumber
+	exprString
+	exprOp
+)
+
+type exprToken struct {
+	kind int
+	text string
+	num  float64
+}
+
+// jinjaExprParser parses one expression by recursive descent, lowest
+// precedence first: conditional, or, and, not, comparisons/in/is, ~, + -,
+// * / // %, **, unary minus and |filters, then primaries with their .attr,
+// [index] and (call) suffixes.
+type jinjaExprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+var exprOps = []string{"==", "!=", "<=", ">=", "//", "**", "+", "-", "*", "/", "%", "~", "|", ".", ",", ":", "(", ")", "[", "]", "{", "}", "<", ">", "="}
+
+func (ep *jinjaExprParser) tokenize(text string) error {
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(text) && (text[j] == '_' || text[j] >= 'a' && text[j] <= 'z' || text[j] >= 'A' && text[j] <= 'Z' || text[j] >= '0' && text[j] <= '9') {
+				j++
+			}
+			ep.tokens = append(ep.tokens, exprToken{kind: exprName, text: text[i:j]})
+			i = j
+		case c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(text) && (text[j] >= '0' && text[j] <= '9' || text[j] == '_' ||
+				text[j] == '.' && j+1 < len(text) && text[j+1] >= '0' && text[j+1] <= '9') {
+				j++
+			}
+			n, err := strconv.ParseFloat(strings.ReplaceAll(text[i:j], "_", ""), 64)
+			if err != nil {
+				return fmt.Errorf("invalid number %q", text[i:j])
+			}
+			ep.tokens = append(ep.tokens, exprToken{kind: exprNumber, text: text[i:j], num: n})
+			i = j
+		case c == '"' || c == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(text) && text[j] != c; j++ {
+				if text[j] == '\\' && j+1 < len(text) {
+					j++
+					switch text[j] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					case 'r':
+						b.WriteByte('\r')
+					default:
+						b.WriteByte(text[j])
+					}
+					continue
+				}
+				b.WriteByte(text[j])
+			}
+			if j >= len(text) {
+				return fmt.Errorf("unterminated string")
+			}
+			ep.tokens = append(ep.tokens, exprToken{kind: exprString, text: b.String()})
+			i = j + 1
+		default:
+			op := ""
+			for _, o := range exprOps {
+				if strings.HasPrefix(text[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return fmt.Errorf("unexpected character %q", c)
+			}
+			ep.tokens = append(ep.tokens, exprToken{kind: exprOp, text: op})
+			i += len(op)
+		}
+	}
+	return nil
+}
+
+func (ep *jinjaExprParser) peek() exprToken {
+	if ep.pos >= len(ep.tokens) {
+		return exprToken{kind: exprEnd, text: "end of expression"}
+	}
+	return ep.tokens[ep.pos]
+}
+
+func (ep *jinjaExprParser) next() exprToken {
+	tok := ep.peek()
+	if ep.pos < len(ep.tokens) {
+		ep.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token when it is the operator or keyword text.
+func (ep *jinjaExprParser) accept(text string) bool {
+	if tok := ep.peek(); (tok.kind == exprOp || tok.kind == exprName) && tok.text == text {
+		ep.pos++
+		return true
+	}
+	return false
+}
+
+func (ep *jinjaExprParser) expect(text string) error {
+	if !ep.accept(text) {
+		return fmt.Errorf("expected %q, got %q", text, ep.peek().text)
+	}
+	return nil
+}
+
+func (ep *jinjaExprParser) parseConditional() (jinjaExpr, error) {
+	expr, err := ep.parseOr()
+	if err != nil || !ep.accept("if") {
+		return expr, err
+	}
+	cond, err := ep.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	node := &jinjaCondExpr{cond: cond, then: expr}
+	if ep.accept("else") {
+		if node.orElse, err = ep.parseConditional(); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func (ep *jinjaExprParser) parseOr() (jinjaExpr, error) {
+	left, err := ep.parseAnd()
+	for err == nil && ep.accept("or") {
+		var right jinjaExpr
+		right, err = ep.parseAnd()
+		left = &jinjaBinaryExpr{op: "or", left: left, right: right}
+	}
+	return left, err
+}
+
+func (ep *jinjaExprParser) parseAnd() (jinjaExpr, error) {
+	left, err := ep.parseNot()
+	for err == nil && ep.accept("and") {
+		var right jinjaExpr
+		right, err = ep.parseNot()
+		left = &jinjaBinaryExpr{op: "and", left: left, right: right}
+	}
+	return left, err
+}
+
+func (ep *jinjaExprParser) parseNot() (jinjaExpr, error) {
+	if ep.accept("not") {
+		x, err := ep.parseNot()
+		return &jinjaUnaryExpr{op: "not", x: x}, err
+	}
+	return ep.parseCompare()
+}
+
+func (ep *jinjaExprParser) parseCompare() (jinjaExpr, error) {
+	left, err := ep.parseConcat()
+	for err == nil {
+		tok := ep.peek()
+		switch {
+		case tok.kind == exprOp && (tok.text == "==" || tok.text == "!=" || tok.text == "<" || tok.text == "<=" || tok.text == ">" || tok.text == ">="):
+			ep.pos++
+			var right jinjaExpr
+			right, err = ep.parseConcat()
+			left = &jinjaBinaryExpr{op: tok.text, left: left, right: right}
+		case tok.kind == exprName && tok.text == "in":
+			ep.pos++
+			var right jinjaExpr
+			right, err = ep.parseConcat()
+			left = &jinjaBinaryExpr{op: "in", left: left, right: right}
+		case tok.kind == exprName && tok.text == "not" && ep.pos+1 < len(ep.tokens) && ep.tokens[ep.pos+1].text == "in":
+			ep.pos += 2
+			var right jinjaExpr
+			right, err = ep.parseConcat()
+			left = &jinjaUnaryExpr{op: "not", x: &jinjaBinaryExpr{op: "in", left: left, right: right}}
+		case tok.kind == exprName && tok.text == "is":
+			ep.pos++
+			left, err = ep.parseTest(left)
+		default:
+			return left, nil
+		}
+	}
+	return nil, err
+}
+
+// parseTest parses the test of an "x is [not] test[(args)]" expression; a
+// single argument may also follow without parentheses ("is divisibleby 3").
+func (ep *jinjaExprParser) parseTest(input jinjaExpr) (jinjaExpr, error) {
+	negate := ep.accept("not")
+	name := ep.next()
+	if name.kind != exprName {
+		return nil, fmt.Errorf("expected a test name after 'is', got %q", name.text)
+	}
+	if _, ok := jinjaTests[name.text]; !ok {
+		return nil, fmt.Errorf("unknown test %q", name.text)
+	}
+	node := &jinjaTestExpr{name: name.text, input: input, negate: negate}
+	var err error
+	if ep.peek().text == "(" {
+		ep.pos++
+		node.args, _, err = ep.parseArgs()
+	} else if tok := ep.peek(); tok.kind == exprNumber || tok.kind == exprString || tok.kind == exprName && !jinjaKeywords[tok.text] {
+		var arg jinjaExpr
+		arg, err = ep.parsePostfix()
+		node.args = []jinjaExpr{arg}
+	}
+	return node, err
+}
+
+var jinjaKeywords = map[string]bool{"and": true, "or": true, "not": true, "in": true, "is": true, "if": true, "else": true}
+
+func (ep *jinjaExprParser) parseConcat() (jinjaExpr, error) {
+	left, err := ep.parseAdd()
+	for err == nil && ep.accept("~") {
+		var right jinjaExpr
+		right, err = ep.parseAdd()
+		left = &jinjaBinaryExpr{op: "~", left: left, right: right}
+	}
+	return left, err
+}
+
+func (ep *jinjaExprParser) parseAdd() (jinjaExpr, error) {
+	left, err := ep.parseMul()
+	for err == nil {
+		tok := ep.peek()
+		if tok.kind != exprOp || (tok.text != "+" && tok.text != "-") {
+			return left, nil
+		}
+		ep.pos++
+		var right jinjaExpr
+		right, err = ep.parseMul()
+		left = &jinjaBinaryExpr{op: tok.text, left: left, right: right}
+	}
+	return nil, err
+}
+
+func (ep *jinjaExprParser) parseMul() (jinjaExpr, error) {
+	left, err := ep.parsePow()
+	for err == nil {
+		tok := ep.peek()
+		if tok.kind != exprOp || (tok.text != "*" && tok.text != "/" && tok.text != "//" && tok.text != "%") {
+			return left, nil
+		}
+		ep.pos++
+		var right jinjaExpr
+		right, err = ep.parsePow()
+		left = &jinjaBinaryExpr{op: tok.text, left: left, right: right}
+	}
+	return nil, err
+}
+
+func (ep *jinjaExprParser) parsePow() (jinjaExpr, error) {
+	left, err := ep.parseUnary()
+	for err == nil && ep.accept("**") {
+		var right jinjaExpr
+		right, err = ep.parseUnary()
+		left = &jinjaBinaryExpr{op: "**", left: left, right: right}
+	}
+	return left, err
+}
+
+// parseUnary parses a unary minus or plus and the filters that follow; as in
+// Nunjucks the filters apply to the negated value, so -3|abs is 3.
+func (ep *jinjaExprParser) parseUnary() (jinjaExpr, error) {
+	var expr jinjaExpr
+	var err error
+	if tok := ep.peek(); tok.kind == exprOp && (tok.text == "-" || tok.text == "+") {
+		ep.pos++
+		var x jinjaExpr
+		if x, err = ep.parseUnaryOperand(); err != nil {
+			return nil, err
+		}
+		expr = &jinjaUnaryExpr{op: tok.text, x: x}
+	} else if expr, err = ep.parsePostfix(); err != nil {
+		return nil, err
+	}
+
+	for ep.accept("|") {
+		name := ep.next()
+		if name.kind != exprName {
+			return nil, fmt.Errorf("expected a filter name after '|', got %q", name.text)
+		}
+		if _, ok := jinjaFilters[name.text]; !ok {
+			return nil, fmt.Errorf("unknown filter %q", name.text)
+		}
+		filter := &jinjaFilterExpr{name: name.text, input: expr}
+		if ep.accept("(") {
+			if filter.args, filter.kwargs, err = ep.parseArgs(); err != nil {
+				return nil, err
+			}
+		}
+		expr = filter
+	}
+	return expr, nil
+}
+
+// parseUnaryOperand parses the operand of a unary operator, without filters.
+func (ep *jinjaExprParser) parseUnaryOperand() (jinjaExpr, error) {
+	if tok := ep.peek(); tok.kind == exprOp && (tok.text == "-" || tok.text == "+") {
+		ep.pos++
+		x, err := ep.parseUnaryOperand()
+		return &jinjaUnaryExpr{op: tok.text, x: x}, err
+	}
+	return ep.parsePostfix()
+}
+
+func (ep *jinjaExprParser) parsePostfix() (jinjaExpr, error) {
+	expr, err := ep.parsePrimary()
+	for err == nil {
+		switch {
+		case ep.accept("."):
+			name := ep.next()
+			if name.kind != exprName && name.kind != exprNumber {
+				return nil, fmt.Errorf("expected an attribute name after '.', got %q", name.text)
+			}
+			expr = &jinjaAttrExpr{x: expr, name: name.text}
+		case ep.accept("["):
+			expr, err = ep.parseSubscript(expr)
+		case ep.accept("("):
+			call := &jinjaCallExpr{fn: expr}
+			call.args, call.kwargs, err = ep.parseArgs()
+			expr = call
+		default:
+			return expr, nil
+		}
+	}
+	return nil, err
+}
+
+// parseSubscript parses what follows "[": an index or a [start:stop] slice.
+func (ep *jinjaExprParser) parseSubscript(x jinjaExpr) (jinjaExpr, error) {
+	var start, stop jinjaExpr
+	var err error
+	if ep.peek().text != ":" {
+		if start, err = ep.parseConditional(); err != nil {
+			return nil, err
+		}
+		if ep.accept("]") {
+			return &jinjaIndexExpr{x: x, index: start}, nil
+		}
+	}
+	if err := ep.expect(":"); err != nil {
+		return nil, err
+	}
+	if ep.peek().text != "]" {
+		if stop, err = ep.parseConditional(); err != nil {
+			return nil, err
+		}
+	}
+	if err := ep.expect("]"); err != nil {
+		return nil, err
+	}
+	return &jinjaSliceExpr{x: x, start: start, stop: stop}, nil
+}
+
+// parseArgs parses call arguments up to and including the closing ")".
+func (ep *jinjaExprParser) parseArgs() ([]jinjaExpr, map[string]jinjaExpr, error) {
+	var args []jinjaExpr
+	kwargs := map[string]jinjaExpr{}
+	for !ep.accept(")") {
+		if tok := ep.peek(); tok.kind == exprName && ep.pos+1 < len(ep.tokens) && ep.tokens[ep.pos+1].text == "=" {
+			ep.pos += 2
+			value, err := ep.parseConditional()
+			if err != nil {
+				return nil, nil, err
+			}
+			kwargs[tok.text] = value
+		} else {
+			arg, err := ep.parseConditional()
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, arg)
+		}
+		if !ep.accept(",") && ep.peek().text != ")" {
+			return nil, nil, fmt.Errorf("expected ',' or ')', got %q", ep.peek().text)
+		}
+	}
+	return args, kwargs, nil
+}
+
+func (ep *jinjaExprParser) parsePrimary() (jinjaExpr, error) {
+	tok := ep.next()
+	switch tok.kind {
+	case exprNumber:
+		return &jinjaLiteral{value: tok.num}, nil
+	case exprString:
+		return &jinjaLiteral{value: tok.text}, nil
+	case exprName:
+		switch tok.text {
+		case "true", "True":
+			return &jinjaLiteral{value: true}, nil
+		case "false", "False":
+			return &jinjaLiteral{value: false}, nil
+		case "none", "None", "null":
+			return &jinjaLiteral{value: nil}, nil
+		}
+		if jinjaKeywords[tok.text] {
+			return nil, fmt.Errorf("unexpected %q", tok.text)
+		}
+		return &jinjaNameExpr{name: tok.text}, nil
+	case exprOp:
+		switch tok.text {
+		case "(":
+			expr, err := ep.parseConditional()
+			if err != nil {
+				return nil, err
+			}
+			if ep.peek().text != "," {
+				return expr, ep.expect(")")
+			}
+			list := &jinjaListExpr{items: []jinjaExpr{expr}}
+			for ep.accept(",") && ep.peek().text != ")" {
+				item, err := ep.parseConditional()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+			}
+			return list, ep.expect(")")
+		case "[":
+			list := &jinjaListExpr{}
+			for !ep.accept("]") {
+				item, err := ep.parseConditional()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if !ep.accept(",") && ep.peek().text != "]" {
+					return nil, fmt.Errorf("expected ',' or ']', got %q", ep.peek().text)
+				}
+			}
+			return list, nil
+		case "{":
+			dict := &jinjaDictExpr{}
+			for !ep.accept("}") {
+				key := ep.peek()
+				var keyExpr jinjaExpr
+				if key.kind == exprName {
+					ep.pos++
+					keyExpr = &jinjaLiteral{value: key.text}
+				} else {
+					var err error
+					if keyExpr, err = ep.parseConditional(); err != nil {
+						return nil, err
+					}
+				}
+				if err := ep.expect(":"); err != nil {
+					return nil, err
+				}
+				value, err := ep.parseConditional()
+				if err != nil {
+					return nil, err
+				}
+				dict.keys = append(dict.keys, keyExpr)
+				dict.values = append(dict.values, value)
+				if !ep.accept(",") && ep.peek().text != "}" {
+					return nil, fmt.Errorf("expected ',' or '}', got %q", ep.peek().text)
+				}
+			}
+			return dict, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
@@ -0,0 +1,907 @@
+package fabric_template_config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jinjaUndefined is the value of a name or attribute that does not exist.
+type jinjaUndefined struct{ name string }
+
+// jinjaFunc is a callable value: a macro, a global such as range or a method.
+type jinjaFunc func(args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+// jinjaScope holds the variables of a template, loop or macro frame. A set
+// updates the frame the variable was found in, up to the first isolated frame
+// (a macro), as Nunjucks does; the render context itself is never written.
+type jinjaScope struct {
+	vars     map[string]interface{}
+	parent   *jinjaScope
+	isolated bool
+	readOnly bool
+}
+
+func (sc *jinjaScope) lookup(name string) (interface{}, bool) {
+	for ; sc != nil; sc = sc.parent {
+		if v, ok := sc.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (sc *jinjaScope) assign(name string, value interface{}) {
+	for frame := sc; frame != nil && !frame.readOnly; frame = frame.parent {
+		if _, ok := frame.vars[name]; ok {
+			frame.vars[name] = value
+			return
+		}
+		if frame.isolated {
+			break
+		}
+	}
+	sc.vars[name] = value
+}
+
+func (sc *jinjaScope) push(isolated bool) *jinjaScope {
+	return &jinjaScope{vars: map[string]interface{}{}, parent: sc, isolated: isolated}
+}
+
+// jinjaState is the state of one render.
+type jinjaState struct {
+	scope     *jinjaScope
+	undefined map[string]bool
+	depth     int
+}
+
+// render renders the template against context, whose values are first
+// normalized through JSON as they are for the engine. It returns the output
+// and the sorted names of the undefined values that were printed.
+func (t *jinjaTemplate) render(context map[string]interface{}) (string, []string, error) {
+	normalized, err := jinjaNormalize(context)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", t.name, err)
+	}
+	globals := &jinjaScope{vars: map[string]interface{}{"range": jinjaFunc(jinjaRange)}, readOnly: true}
+	root := &jinjaScope{vars: normalized, parent: globals, readOnly: true}
+	s := &jinjaState{scope: root.push(false), undefined: map[string]bool{}}
+
+	var out strings.Builder
+	if err := renderJinjaNodes(s, t.body, &out); err != nil {
+		return "", nil, fmt.Errorf("%s:%w", t.name, err)
+	}
+	undefined := make([]string, 0, len(s.undefined))
+	for name := range s.undefined {
+		undefined = append(undefined, name)
+	}
+	sort.Strings(undefined)
+	return out.String(), undefined, nil
+}
+
+func jinjaNormalize(context map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(context)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode the render context: %w", err)
+	}
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("cannot decode the render context: %w", err)
+	}
+	return normalized, nil
+}
+
+func renderJinjaNodes(s *jinjaState, nodes []jinjaNode, out *strings.Builder) error {
+	for _, node := range nodes {
+		if err := node.render(s, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *jinjaTextNode) render(s *jinjaState, out *strings.Builder) error {
+	out.WriteString(n.text)
+	return nil
+}
+
+func (n *jinjaOutputNode) render(s *jinjaState, out *strings.Builder) error {
+	v, err := n.expr.eval(s)
+	if err != nil {
+		return fmt.Errorf("%d: %w", n.line, err)
+	}
+	if u, ok := v.(jinjaUndefined); ok {
+		s.undefined[u.name] = true
+	}
+	out.WriteString(jinjaString(v))
+	return nil
+}
+
+func (n *jinjaIfNode) render(s *jinjaState, out *strings.Builder) error {
+	for _, branch := range n.branches {
+		cond, err := branch.cond.eval(s)
+		if err != nil {
+			return fmt.Errorf("%d: %w", branch.line, err)
+		}
+		if jinjaTruthy(cond) {
+			return renderJinjaNodes(s, branch.body, out)
+		}
+	}
+	return renderJinjaNodes(s, n.orElse, out)
+}
+
+func (n *jinjaForNode) render(s *jinjaState, out *strings.Builder) error {
+	iter, err := n.iter.eval(s)
+	if err != nil {
+		return fmt.Errorf("%d: %w", n.line, err)
+	}
+	var items []interface{}
+	switch v := iter.(type) {
+	case []interface{}:
+		items = v
+	case string:
+		for _, r := range v {
+			items = append(items, string(r))
+		}
+	case map[string]interface{}:
+		for _, key := range sortedJinjaKeys(v) {
+			if len(n.targets) == 2 {
+				items = append(items, []interface{}{key, v[key]})
+			} else {
+				items = append(items, key)
+			}
+		}
+	}
+	if len(items) == 0 {
+		return renderJinjaNodes(s, n.orElse, out)
+	}
+
+	outer := s.scope
+	defer func() { s.scope = outer }()
+	for i, item := range items {
+		s.scope = outer.push(false)
+		if len(n.targets) == 1 {
+			s.scope.vars[n.targets[0]] = item
+		} else {
+			values, ok := item.([]interface{})
+			if !ok || len(values) != len(n.targets) {
+				return fmt.Errorf("%d: cannot unpack %s into %d loop variables", n.line, jinjaDump(item), len(n.targets))
+			}
+			for j, target := range n.targets {
+				s.scope.vars[target] = values[j]
+			}
+		}
+		s.scope.vars["loop"] = map[string]interface{}{
+			"index": float64(i + 1), "index0": float64(i),
+			"revindex": float64(len(items) - i), "revindex0": float64(len(items) - i - 1),
+			"first": i == 0, "last": i == len(items)-1, "length": float64(len(items)),
+		}
+		if err := renderJinjaNodes(s, n.body, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *jinjaSetNode) render(s *jinjaState, out *strings.Builder) error {
+	var value interface{}
+	if n.expr != nil {
+		v, err := n.expr.eval(s)
+		if err != nil {
+			return fmt.Errorf("%d: %w", n.line, err)
+		}
+		value = v
+	} else {
+		var body strings.Builder
+		if err := renderJinjaNodes(s, n.body, &body); err != nil {
+			return err
+		}
+		value = body.String()
+	}
+	for _, target := range n.targets {
+		s.scope.assign(target, value)
+	}
+	return nil
+}
+
+func (n *jinjaMacroNode) render(s *jinjaState, out *strings.Builder) error {
+	defined := s.scope
+	s.scope.assign(n.name, jinjaFunc(func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		if s.depth > 100 {
+			return nil, fmt.Errorf("macro %s: recursion too deep", n.name)
+		}
+		if len(args) > len(n.params) {
+			return nil, fmt.Errorf("macro %s takes %d argument(s), got %d", n.name, len(n.params), len(args))
+		}
+		caller := s.scope
+		s.scope = defined.push(true)
+		s.depth++
+		defer func() { s.scope, s.depth = caller, s.depth-1 }()
+
+		for i, param := range n.params {
+			switch v, ok := kwargs[param]; {
+			case i < len(args):
+				s.scope.vars[param] = args[i]
+			case ok:
+				s.scope.vars[param] = v
+			case n.defaults[param] != nil:
+				def, err := n.defaults[param].eval(s)
+				if err != nil {
+					return nil, err
+				}
+				s.scope.vars[param] = def
+			default:
+				s.scope.vars[param] = jinjaUndefined{name: param}
+			}
+		}
+		var body strings.Builder
+		if err := renderJinjaNodes(s, n.body, &body); err != nil {
+			return nil, err
+		}
+		return body.String(), nil
+	}))
+	return nil
+}
+
+// jinjaExpr is a node of a parsed expression.
+type jinjaExpr interface {
+	eval(s *jinjaState) (interface{}, error)
+}
+
+type jinjaLiteral struct{ value interface{} }
+
+type jinjaNameExpr struct{ name string }
+
+type jinjaAttrExpr struct {
+	x    jinjaExpr
+	name string
+}
+
+type jinjaIndexExpr struct{ x, index jinjaExpr }
+
+type jinjaSliceExpr struct{ x, start, stop jinjaExpr }
+
+type jinjaCallExpr struct {
+	fn     jinjaExpr
+	args   []jinjaExpr
+	kwargs map[string]jinjaExpr
+}
+
+type jinjaFilterExpr struct {
+	name   string
+	input  jinjaExpr
+	args   []jinjaExpr
+	kwargs map[string]jinjaExpr
+}
+
+type jinjaTestExpr struct {
+	name   string
+	input  jinjaExpr
+	args   []jinjaExpr
+	negate bool
+}
+
+type jinjaUnaryExpr struct {
+	op string
+	x  jinjaExpr
+}
+
+type jinjaBinaryExpr struct {
+	op          string
+	left, right jinjaExpr
+}
+
+type jinjaCondExpr struct{ cond, then, orElse jinjaExpr }
+
+type jinjaListExpr struct{ items []jinjaExpr }
+
+type jinjaDictExpr struct{ keys, values []jinjaExpr }
+
+func (e *jinjaLiteral) eval(s *jinjaState) (interface{}, error) { return e.value, nil }
+
+func (e *jinjaNameExpr) eval(s *jinjaState) (interface{}, error) {
+	if v, ok := s.scope.lookup(e.name); ok {
+		return v, nil
+	}
+	return jinjaUndefined{name: e.name}, nil
+}
+
+func (e *jinjaAttrExpr) eval(s *jinjaState) (interface{}, error) {
+	x, err := e.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	return jinjaMember(x, e.name, jinjaExprName(e.x)+"."+e.name), nil
+}
+
+func (e *jinjaIndexExpr) eval(s *jinjaState) (interface{}, error) {
+	x, err := e.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	index, err := e.index.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := index.(float64); ok {
+		if list, ok := x.([]interface{}); ok {
+			if i := int(n); float64(i) == n && i >= 0 && i < len(list) {
+				return list[i], nil
+			}
+			return jinjaUndefined{name: fmt.Sprintf("%s[%s]", jinjaExprName(e.x), jinjaString(n))}, nil
+		}
+	}
+	key := jinjaString(index)
+	return jinjaMember(x, key, fmt.Sprintf("%s[%q]", jinjaExprName(e.x), key)), nil
+}
+
+func (e *jinjaSliceExpr) eval(s *jinjaState) (interface{}, error) {
+	x, err := e.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	var length int
+	switch v := x.(type) {
+	case []interface{}:
+		length = len(v)
+	case string:
+		length = len([]rune(v))
+	default:
+		return jinjaUndefined{name: jinjaExprName(e.x) + "[:]"}, nil
+	}
+	bound := func(expr jinjaExpr, def int) (int, error) {
+		if expr == nil {
+			return def, nil
+		}
+		v, err := expr.eval(s)
+		if err != nil {
+			return 0, err
+		}
+		i := int(jinjaNumber(v))
+		if i < 0 {
+			i += length
+		}
+		return min(max(i, 0), length), nil
+	}
+	start, err := bound(e.start, 0)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := bound(e.stop, length)
+	if err != nil {
+		return nil, err
+	}
+	stop = max(stop, start)
+	if list, ok := x.([]interface{}); ok {
+		return append([]interface{}{}, list[start:stop]...), nil
+	}
+	return string([]rune(x.(string))[start:stop]), nil
+}
+
+func (e *jinjaCallExpr) eval(s *jinjaState) (interface{}, error) {
+	fn, err := e.fn.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := fn.(jinjaFunc)
+	if !ok {
+		return nil, fmt.Errorf("%s is not callable", jinjaExprName(e.fn))
+	}
+	args, kwargs, err := evalJinjaArgs(s, e.args, e.kwargs)
+	if err != nil {
+		return nil, err
+	}
+	return f(args, kwargs)
+}
+
+func (e *jinjaFilterExpr) eval(s *jinjaState) (interface{}, error) {
+	input, err := e.input.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	args, kwargs, err := evalJinjaArgs(s, e.args, e.kwargs)
+	if err != nil {
+		return nil, err
+	}
+	v, err := jinjaFilters[e.name](input, args, kwargs)
+	if err != nil {
+		return nil, fmt.Errorf("filter %s: %w", e.name, err)
+	}
+	return v, nil
+}
+
+func (e *jinjaTestExpr) eval(s *jinjaState) (interface{}, error) {
+	input, err := e.input.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	args, _, err := evalJinjaArgs(s, e.args, nil)
+	if err != nil {
+		return nil, err
+	}
+	return jinjaTests[e.name](input, args) != e.negate, nil
+}
+
+func (e *jinjaUnaryExpr) eval(s *jinjaState) (interface{}, error) {
+	x, err := e.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "not":
+		return !jinjaTruthy(x), nil
+	case "-":
+		return -jinjaNumber(x), nil
+	}
+	return jinjaNumber(x), nil
+}
+
+func (e *jinjaBinaryExpr) eval(s *jinjaState) (interface{}, error) {
+	left, err := e.left.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	// and/or short-circuit and yield an operand, as in JavaScript.
+	switch e.op {
+	case "and":
+		if !jinjaTruthy(left) {
+			return left, nil
+		}
+		return e.right.eval(s)
+	case "or":
+		if jinjaTruthy(left) {
+			return left, nil
+		}
+		return e.right.eval(s)
+	}
+	right, err := e.right.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "==":
+		return jinjaEqual(left, right), nil
+	case "!=":
+		return !jinjaEqual(left, right), nil
+	case "<", "<=", ">", ">=":
+		c, ok := jinjaCompare(left, right)
+		if !ok {
+			return false, nil
+		}
+		switch e.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	case "in":
+		return jinjaContains(right, left), nil
+	case "~":
+		return jinjaString(left) + jinjaString(right), nil
+	case "+":
+		_, ls := left.(string)
+		_, rs := right.(string)
+		if ls || rs {
+			return jinjaString(left) + jinjaString(right), nil
+		}
+		return jinjaNumber(left) + jinjaNumber(right), nil
+	case "-":
+		return jinjaNumber(left) - jinjaNumber(right), nil
+	case "*":
+		return jinjaNumber(left) * jinjaNumber(right), nil
+	case "/":
+		return jinjaNumber(left) / jinjaNumber(right), nil
+	case "//":
+		return math.Floor(jinjaNumber(left) / jinjaNumber(right)), nil
+	case "%":
+		return math.Mod(jinjaNumber(left), jinjaNumber(right)), nil
+	case "**":
+		return math.Pow(jinjaNumber(left), jinjaNumber(right)), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", e.op)
+}
+
+func (e *jinjaCondExpr) eval(s *jinjaState) (interface{}, error) {
+	cond, err := e.cond.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if jinjaTruthy(cond) {
+		return e.then.eval(s)
+	}
+	if e.orElse == nil {
+		return jinjaUndefined{name: "else"}, nil
+	}
+	return e.orElse.eval(s)
+}
+
+func (e *jinjaListExpr) eval(s *jinjaState) (interface{}, error) {
+	list := make([]interface{}, 0, len(e.items))
+	for _, item := range e.items {
+		v, err := item.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (e *jinjaDictExpr) eval(s *jinjaState) (interface{}, error) {
+	dict := make(map[string]interface{}, len(e.keys))
+	for i := range e.keys {
+		key, err := e.keys[i].eval(s)
+		if err != nil {
+			return nil, err
+		}
+		value, err := e.values[i].eval(s)
+		if err != nil {
+			return nil, err
+		}
+		dict[jinjaString(key)] = value
+	}
+	return dict, nil
+}
+
+func evalJinjaArgs(s *jinjaState, exprs []jinjaExpr, kwexprs map[string]jinjaExpr) ([]interface{}, map[string]interface{}, error) {
+	args := make([]interface{}, 0, len(exprs))
+	for _, expr := range exprs {
+		v, err := expr.eval(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, v)
+	}
+	kwargs := make(map[string]interface{}, len(kwexprs))
+	for name, expr := range kwexprs {
+		v, err := expr.eval(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		kwargs[name] = v
+	}
+	return args, kwargs, nil
+}
+
+// jinjaExprName is the source-like name of an expression, for undefined
+// values and error messages.
+func jinjaExprName(e jinjaExpr) string {
+	switch v := e.(type) {
+	case *jinjaNameExpr:
+		return v.name
+	case *jinjaAttrExpr:
+		return jinjaExprName(v.x) + "." + v.name
+	case *jinjaIndexExpr:
+		return jinjaExprName(v.x) + "[]"
+	case *jinjaCallExpr:
+		return jinjaExprName(v.fn) + "()"
+	}
+	return "value"
+}
+
+// jinjaMember looks name up in x: a map key, a list index or length, or one of
+// the Python-style methods templates call (items, keys, get, split, ...).
+// Anything else, including any member of undefined or null, is undefined.
+func jinjaMember(x interface{}, name, fullName string) interface{} {
+	switch v := x.(type) {
+	case map[string]interface{}:
+		if value, ok := v[name]; ok {
+			return value
+		}
+	case []interface{}:
+		if name == "length" {
+			return float64(len(v))
+		}
+		if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(v) {
+			return v[i]
+		}
+	case string:
+		if name == "length" {
+			return float64(len([]rune(v)))
+		}
+	}
+	if method := jinjaMethod(x, name); method != nil {
+		return method
+	}
+	return jinjaUndefined{name: fullName}
+}
+
+func jinjaMethod(x interface{}, name string) jinjaFunc {
+	arg := func(args []interface{}, i int) string {
+		if i < len(args) {
+			return jinjaString(args[i])
+		}
+		return ""
+	}
+	switch v := x.(type) {
+	case map[string]interface{}:
+		switch name {
+		case "items", "keys", "values":
+			return func([]interface{}, map[string]interface{}) (interface{}, error) {
+				list := []interface{}{}
+				for _, key := range sortedJinjaKeys(v) {
+					switch name {
+					case "items":
+						list = append(list, []interface{}{key, v[key]})
+					case "keys":
+						list = append(list, key)
+					default:
+						list = append(list, v[key])
+					}
+				}
+				return list, nil
+			}
+		case "get":
+			return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if value, ok := v[arg(args, 0)]; ok {
+					return value, nil
+				}
+				if len(args) > 1 {
+					return args[1], nil
+				}
+				return nil, nil
+			}
+		}
+	case string:
+		switch name {
+		case "upper", "lower", "strip", "lstrip", "rstrip", "title", "capitalize":
+			return func([]interface{}, map[string]interface{}) (interface{}, error) {
+				switch name {
+				case "upper":
+					return strings.ToUpper(v), nil
+				case "lower":
+					return strings.ToLower(v), nil
+				case "strip":
+					return strings.TrimSpace(v), nil
+				case "lstrip":
+					return strings.TrimLeft(v, " \t\r\n"), nil
+				case "rstrip":
+					return strings.TrimRight(v, " \t\r\n"), nil
+				case "title":
+					return jinjaTitle(v), nil
+				}
+				return jinjaCapitalize(v), nil
+			}
+		case "startswith", "endswith":
+			return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if name == "startswith" {
+					return strings.HasPrefix(v, arg(args, 0)), nil
+				}
+				return strings.HasSuffix(v, arg(args, 0)), nil
+			}
+		case "split":
+			return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				var parts []string
+				if len(args) == 0 || args[0] == nil {
+					parts = strings.Fields(v)
+				} else {
+					parts = strings.Split(v, arg(args, 0))
+				}
+				list := make([]interface{}, len(parts))
+				for i, part := range parts {
+					list[i] = part
+				}
+				return list, nil
+			}
+		case "replace":
+			return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				return strings.ReplaceAll(v, arg(args, 0), arg(args, 1)), nil
+			}
+		case "join":
+			return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) == 0 {
+					return nil, fmt.Errorf("join takes a list")
+				}
+				return jinjaJoin(args[0], v), nil
+			}
+		}
+	}
+	return nil
+}
+
+// jinjaString prints a value the way Nunjucks prints it.
+func jinjaString(v interface{}) string {
+	switch x := v.(type) {
+	case nil, jinjaUndefined:
+		return ""
+	case string:
+		return x
+	case bool:
+		if x {
+			return "true"
+		}
+		return "false"
+	case float64:
+		if math.IsInf(x, 0) {
+			if x > 0 {
+				return "Infinity"
+			}
+			return "-Infinity"
+		}
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case []interface{}:
+		return jinjaJoin(x, ",")
+	case map[string]interface{}:
+		return "[object Object]"
+	}
+	return fmt.Sprint(v)
+}
+
+func jinjaJoin(list interface{}, separator string) string {
+	items, _ := list.([]interface{})
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = jinjaString(item)
+	}
+	return strings.Join(parts, separator)
+}
+
+// jinjaTruthy is JavaScript truthiness: empty lists and maps are true.
+func jinjaTruthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil, jinjaUndefined:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0 && !math.IsNaN(x)
+	case string:
+		return x != ""
+	}
+	return true
+}
+
+// jinjaNumber converts a value to a number as JavaScript does, NaN when it
+// has none.
+func jinjaNumber(v interface{}) float64 {
+	switch x := v.(type) {
+	case nil:
+		return 0
+	case float64:
+		return x
+	case bool:
+		if x {
+			return 1
+		}
+		return 0
+	case string:
+		s := strings.TrimSpace(x)
+		if s == "" {
+			return 0
+		}
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	}
+	return math.NaN()
+}
+
+// jinjaEqual is the loose equality of Nunjucks' ==.
+func jinjaEqual(a, b interface{}) bool {
+	nullish := func(v interface{}) bool {
+		switch v.(type) {
+		case nil, jinjaUndefined:
+			return true
+		}
+		return false
+	}
+	if nullish(a) || nullish(b) {
+		return nullish(a) && nullish(b)
+	}
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return x == y
+		}
+	case float64, bool:
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+	switch b.(type) {
+	case string, float64, bool:
+		return jinjaNumber(a) == jinjaNumber(b)
+	}
+	return false
+}
+
+// jinjaCompare orders strings by text and anything else by number; ok is
+// false when the values are not comparable (a NaN).
+func jinjaCompare(a, b interface{}) (int, bool) {
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	}
+	x, y := jinjaNumber(a), jinjaNumber(b)
+	switch {
+	case math.IsNaN(x) || math.IsNaN(y):
+		return 0, false
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
+
+func jinjaContains(container, item interface{}) bool {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, v := range c {
+			if jinjaStrictEqual(v, item) {
+				return true
+			}
+		}
+	case string:
+		return strings.Contains(c, jinjaString(item))
+	case map[string]interface{}:
+		_, ok := c[jinjaString(item)]
+		return ok
+	}
+	return false
+}
+
+func jinjaStrictEqual(a, b interface{}) bool {
+	if _, ok := a.(jinjaUndefined); ok {
+		_, ok := b.(jinjaUndefined)
+		return ok
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func jinjaDump(v interface{}) string {
+	if _, ok := v.(jinjaUndefined); ok {
+		return "undefined"
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return jinjaString(v)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func sortedJinjaKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jinjaRange(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+	start, stop, step := 0.0, 0.0, 1.0
+	switch len(args) {
+	case 1:
+		stop = jinjaNumber(args[0])
+	case 2, 3:
+		start, stop = jinjaNumber(args[0]), jinjaNumber(args[1])
+		if len(args) == 3 {
+			step = jinjaNumber(args[2])
+		}
+	default:
+		return nil, fmt.Errorf("range takes 1 to 3 arguments, got %d", len(args))
+	}
+	if step == 0 || math.IsNaN(start+stop+step) {
+		return nil, fmt.Errorf("range: invalid arguments")
+	}
+	list := []interface{}{}
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		if len(list) >= 1_000_000 {
+			return nil, fmt.Errorf("range: too many values")
+		}
+		list = append(list, i)
+	}
+	return list, nil
+}
@@ -0,0 +1,441 @@
+package fabric_template_config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type jinjaFilter func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+// jinjaFilters are the Nunjucks built-in filters the local render supports.
+var jinjaFilters = map[string]jinjaFilter{
+	"abs": func(input interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+		return math.Abs(jinjaNumber(input)), nil
+	},
+	"capitalize": stringFilter(jinjaCapitalize),
+	"default":    jinjaDefault,
+	"d":          jinjaDefault,
+	"dictsort": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		m, ok := input.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("dictsort takes a mapping")
+		}
+		items := []interface{}{}
+		for _, key := range sortedJinjaKeys(m) {
+			items = append(items, []interface{}{key, m[key]})
+		}
+		if jinjaString(jinjaArg(args, kwargs, 1, "by", "key")) == "value" {
+			sort.SliceStable(items, func(i, j int) bool {
+				c, _ := jinjaCompare(items[i].([]interface{})[1], items[j].([]interface{})[1])
+				return c < 0
+			})
+		}
+		return items, nil
+	},
+	"dump": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		if spaces := jinjaArg(args, kwargs, 0, "spaces", nil); spaces != nil {
+			data, err := json.MarshalIndent(input, "", strings.Repeat(" ", int(jinjaNumber(spaces))))
+			return string(data), err
+		}
+		return jinjaDump(input), nil
+	},
+	"escape": stringFilter(jinjaEscape),
+	"e":      stringFilter(jinjaEscape),
+	"first": func(input interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+		switch v := input.(type) {
+		case []interface{}:
+			if len(v) > 0 {
+				return v[0], nil
+			}
+		case string:
+			if r := []rune(v); len(r) > 0 {
+				return string(r[0]), nil
+			}
+		}
+		return jinjaUndefined{name: "first"}, nil
+	},
+	"float": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		if n := jinjaNumber(input); !math.IsNaN(n) {
+			return n, nil
+		}
+		return jinjaArg(args, kwargs, 0, "default", 0.0), nil
+	},
+	"indent": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		width := jinjaNumber(jinjaArg(args, kwargs, 0, "width", 4.0))
+		first := jinjaTruthy(jinjaArg(args, kwargs, 1, "indentfirst", false))
+		pad := strings.Repeat(" ", int(width))
+		lines := strings.Split(jinjaString(input), "\n")
+		for i, line := range lines {
+			if (i > 0 || first) && line != "" {
+				lines[i] = pad + line
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	},
+	"int": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		if n := jinjaNumber(input); !math.IsNaN(n) {
+			return math.Trunc(n), nil
+		}
+		// parseInt takes the leading digits of a string, "12abc" is 12.
+		s := strings.TrimSpace(jinjaString(input))
+		end := 0
+		for end < len(s) && (s[end] >= '0' && s[end] <= '9' || end == 0 && (s[end] == '-' || s[end] == '+')) {
+			end++
+		}
+		if n, err := strconv.ParseFloat(s[:end], 64); err == nil {
+			return n, nil
+		}
+		return jinjaArg(args, kwargs, 0, "default", 0.0), nil
+	},
+	"join": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		items, _ := input.([]interface{})
+		if attr := jinjaArg(args, kwargs, 1, "attribute", nil); attr != nil {
+			items = jinjaAttributes(items, jinjaString(attr))
+		}
+		return jinjaJoin(items, jinjaString(jinjaArg(args, kwargs, 0, "d", ""))), nil
+	},
+	"last": func(input interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+		switch v := input.(type) {
+		case []interface{}:
+			if len(v) > 0 {
+				return v[len(v)-1], nil
+			}
+		case string:
+			if r := []rune(v); len(r) > 0 {
+				return string(r[len(r)-1]), nil
+			}
+		}
+		return jinjaUndefined{name: "last"}, nil
+	},
+	"length": jinjaLength,
+	"count":  jinjaLength,
+	"list": func(input interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+		switch v := input.(type) {
+		case []interface{}:
+			return v, nil
+		case string:
+			list := []interface{}{}
+			for _, r := range v {
+				list = append(list, string(r))
+			}
+			return list, nil
+		case map[string]interface{}:
+			list := []interface{}{}
+			for _, key := range sortedJinjaKeys(v) {
+				list = append(list, map[string]interface{}{"key": key, "value": v[key]})
+			}
+			return list, nil
+		}
+		return nil, fmt.Errorf("list takes a list, a string or a mapping")
+	},
+	"lower": stringFilter(strings.ToLower),
+	"replace": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		old := jinjaString(jinjaArg(args, kwargs, 0, "old", ""))
+		replacement := jinjaString(jinjaArg(args, kwargs, 1, "new", ""))
+		count := -1
+		if n := jinjaArg(args, kwargs, 2, "count", nil); n != nil {
+			count = int(jinjaNumber(n))
+		}
+		return strings.Replace(jinjaString(input), old, replacement, count), nil
+	},
+	"reverse": func(input interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+		switch v := input.(type) {
+		case []interface{}:
+			list := make([]interface{}, len(v))
+			for i, item := range v {
+				list[len(v)-1-i] = item
+			}
+			return list, nil
+		case string:
+			r := []rune(v)
+			for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+				r[i], r[j] = r[j], r[i]
+			}
+			return string(r), nil
+		}
+		return input, nil
+	},
+	"round": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		factor := math.Pow(10, jinjaNumber(jinjaArg(args, kwargs, 0, "precision", 0.0)))
+		n := jinjaNumber(input) * factor
+		switch jinjaString(jinjaArg(args, kwargs, 1, "method", "common")) {
+		case "ceil":
+			n = math.Ceil(n)
+		case "floor":
+			n = math.Floor(n)
+		default:
+			n = math.Round(n)
+		}
+		return n / factor, nil
+	},
+	"safe": func(input interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+		return input, nil
+	},
+	"rejectattr": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		return jinjaSelect(input, args, true, true)
+	},
+	"reject": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		return jinjaSelect(input, args, false, true)
+	},
+	"selectattr": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		return jinjaSelect(input, args, true, false)
+	},
+	"select": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		return jinjaSelect(input, args, false, false)
+	},
+	"sort": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		items, ok := input.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("sort takes a list")
+		}
+		reverse := jinjaTruthy(jinjaArg(args, kwargs, 0, "reverse", false))
+		caseSensitive := jinjaTruthy(jinjaArg(args, kwargs, 1, "case_sensitive", false))
+		attr := jinjaArg(args, kwargs, 2, "attribute", nil)
+		keys := items
+		if attr != nil {
+			keys = jinjaAttributes(items, jinjaString(attr))
+		}
+		order := make([]int, len(items))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			a, b := keys[order[i]], keys[order[j]]
+			if !caseSensitive {
+				if s, ok := a.(string); ok {
+					a = strings.ToLower(s)
+				}
+				if s, ok := b.(string); ok {
+					b = strings.ToLower(s)
+				}
+			}
+			c, _ := jinjaCompare(a, b)
+			if reverse {
+				return c > 0
+			}
+			return c < 0
+		})
+		sorted := make([]interface{}, len(items))
+		for i, k := range order {
+			sorted[i] = items[k]
+		}
+		return sorted, nil
+	},
+	"string": func(input interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+		return jinjaString(input), nil
+	},
+	"sum": func(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		items, _ := input.([]interface{})
+		if attr := jinjaArg(args, kwargs, 0, "attribute", nil); attr != nil {
+			items = jinjaAttributes(items, jinjaString(attr))
+		}
+		total := jinjaNumber(jinjaArg(args, kwargs, 1, "start", 0.0))
+		for _, item := range items {
+			total += jinjaNumber(item)
+		}
+		return total, nil
+	},
+	"title": stringFilter(jinjaTitle),
+	"trim":  stringFilter(strings.TrimSpace),
+	"upper": stringFilter(strings.ToUpper),
+}
+
+func stringFilter(f func(string) string) jinjaFilter {
+	return func(input interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+		return f(jinjaString(input)), nil
+	}
+}
+
+// jinjaArg returns the filter argument at position i or named name, or def.
+func jinjaArg(args []interface{}, kwargs map[string]interface{}, i int, name string, def interface{}) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	if v, ok := kwargs[name]; ok {
+		return v
+	}
+	return def
+}
+
+// jinjaDefault replaces an undefined value, or with its boolean argument set
+// any false one; null is kept, as in Nunjucks.
+func jinjaDefault(input interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	def := jinjaArg(args, kwargs, 0, "default_value", "")
+	if jinjaTruthy(jinjaArg(args, kwargs, 1, "boolean", false)) {
+		if !jinjaTruthy(input) {
+			return def, nil
+		}
+		return input, nil
+	}
+	if _, ok := input.(jinjaUndefined); ok {
+		return def, nil
+	}
+	return input, nil
+}
+
+func jinjaLength(input interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	switch v := input.(type) {
+	case []interface{}:
+		return float64(len(v)), nil
+	case map[string]interface{}:
+		return float64(len(v)), nil
+	case string:
+		return float64(len([]rune(v))), nil
+	}
+	return 0.0, nil
+}
+
+func jinjaAttributes(items []interface{}, attr string) []interface{} {
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		for _, part := range strings.Split(attr, ".") {
+			item = jinjaMember(item, part, attr)
+		}
+		values[i] = item
+	}
+	return values
+}
+
+// jinjaSelect implements select, reject, selectattr and rejectattr: the items
+// (or their attribute) passing a test, truthiness when no test is named.
+func jinjaSelect(input interface{}, args []interface{}, byAttr, reject bool) (interface{}, error) {
+	items, _ := input.([]interface{})
+	values := items
+	if byAttr {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("an attribute name is required")
+		}
+		values = jinjaAttributes(items, jinjaString(args[0]))
+		args = args[1:]
+	}
+	test := func(v interface{}, _ []interface{}) bool { return jinjaTruthy(v) }
+	if len(args) > 0 {
+		name := jinjaString(args[0])
+		t, ok := jinjaTests[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown test %q", name)
+		}
+		test, args = t, args[1:]
+	}
+	selected := []interface{}{}
+	for i, item := range items {
+		if test(values[i], args) != reject {
+			selected = append(selected, item)
+		}
+	}
+	return selected, nil
+}
+
+var jinjaEscaper = strings.NewReplacer("&", "&amp;", `"`, "&quot;", "'", "&#39;", "<", "&lt;", ">", "&gt;")
+
+func jinjaEscape(s string) string { return jinjaEscaper.Replace(s) }
+
+func jinjaCapitalize(s string) string {
+	r := []rune(strings.ToLower(s))
+	if len(r) > 0 {
+		r[0] = unicode.ToUpper(r[0])
+	}
+	return string(r)
+}
+
+func jinjaTitle(s string) string {
+	words := strings.Split(s, " ")
+	for i, word := range words {
+		words[i] = jinjaCapitalize(word)
+	}
+	return strings.Join(words, " ")
+}
+
+type jinjaTest func(input interface{}, args []interface{}) bool
+
+// jinjaTests are the Nunjucks built-in tests, with the Jinja2 aliases.
+var jinjaTests = map[string]jinjaTest{
+	"defined": func(input interface{}, _ []interface{}) bool {
+		_, undefined := input.(jinjaUndefined)
+		return !undefined
+	},
+	"undefined": func(input interface{}, _ []interface{}) bool {
+		_, undefined := input.(jinjaUndefined)
+		return undefined
+	},
+	"none": isJinjaNull,
+	"null": isJinjaNull,
+	"number": func(input interface{}, _ []interface{}) bool {
+		_, ok := input.(float64)
+		return ok
+	},
+	"string": func(input interface{}, _ []interface{}) bool {
+		_, ok := input.(string)
+		return ok
+	},
+	"mapping": func(input interface{}, _ []interface{}) bool {
+		_, ok := input.(map[string]interface{})
+		return ok
+	},
+	"iterable": isJinjaIterable,
+	"sequence": isJinjaIterable,
+	"callable": func(input interface{}, _ []interface{}) bool {
+		_, ok := input.(jinjaFunc)
+		return ok
+	},
+	"truthy": func(input interface{}, _ []interface{}) bool { return jinjaTruthy(input) },
+	"falsy":  func(input interface{}, _ []interface{}) bool { return !jinjaTruthy(input) },
+	"even": func(input interface{}, _ []interface{}) bool {
+		return math.Mod(jinjaNumber(input), 2) == 0
+	},
+	"odd": func(input interface{}, _ []interface{}) bool {
+		return math.Abs(math.Mod(jinjaNumber(input), 2)) == 1
+	},
+	"divisibleby": func(input interface{}, args []interface{}) bool {
+		return len(args) > 0 && math.Mod(jinjaNumber(input), jinjaNumber(args[0])) == 0
+	},
+	"equalto": jinjaSameAs,
+	"eq":      jinjaSameAs,
+	"sameas":  jinjaSameAs,
+	"ne": func(input interface{}, args []interface{}) bool {
+		return !jinjaSameAs(input, args)
+	},
+	"lt":          jinjaOrderTest(func(c int) bool { return c < 0 }),
+	"lessthan":    jinjaOrderTest(func(c int) bool { return c < 0 }),
+	"le":          jinjaOrderTest(func(c int) bool { return c <= 0 }),
+	"gt":          jinjaOrderTest(func(c int) bool { return c > 0 }),
+	"greaterthan": jinjaOrderTest(func(c int) bool { return c > 0 }),
+	"ge":          jinjaOrderTest(func(c int) bool { return c >= 0 }),
+	"lower": func(input interface{}, _ []interface{}) bool {
+		s, ok := input.(string)
+		return ok && strings.ToLower(s) == s
+	},
+	"upper": func(input interface{}, _ []interface{}) bool {
+		s, ok := input.(string)
+		return ok && strings.ToUpper(s) == s
+	},
+}
+
+func isJinjaNull(input interface{}, _ []interface{}) bool { return input == nil }
+
+func isJinjaIterable(input interface{}, _ []interface{}) bool {
+	switch input.(type) {
+	case []interface{}, string, map[string]interface{}:
+		return true
+	}
+	return false
+}
+
+func jinjaSameAs(input interface{}, args []interface{}) bool {
+	return len(args) > 0 && jinjaStrictEqual(input, args[0])
+}
+
+func jinjaOrderTest(accept func(int) bool) jinjaTest {
+	return func(input interface{}, args []interface{}) bool {
+		if len(args) == 0 {
+			return false
+		}
+		c, ok := jinjaCompare(input, args[0])
+		return ok && accept(c)
+	}
+}
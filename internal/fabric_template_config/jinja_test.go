@@ -0,0 +1,111 @@
+package fabric_template_config
+
+import (
+	"strings"
+	"testing"
+)
+
+func renderJinjaString(t *testing.T, text string, context map[string]interface{}) (string, []string) {
+	t.Helper()
+	tpl, err := parseJinja("test.j2", text)
+	if err != nil {
+		t.Fatalf("parse %q: %v", text, err)
+	}
+	out, undefined, err := tpl.render(context)
+	if err != nil {
+		t.Fatalf("render %q: %v", text, err)
+	}
+	return out, undefined
+}
+
+func TestJinjaRender(t *testing.T) {
+	context := map[string]interface{}{
+		"hostname":   "leaf-1",
+		"asn":        int64(4200000001),
+		"mode":       "l3evpn",
+		"is_rr":      true,
+		"mtu":        9216,
+		"empty":      []string{},
+		"nothing":    nil,
+		"aggregates": []string{"10.0.0.0/26", "10.0.0.64/26"},
+		"bgp_neighbors": []map[string]interface{}{
+			{"ip": "10.254.0.1", "asn": 4200000100, "port": "swp1"},
+			{"ip": "10.254.0.3", "asn": 4200000101, "port": "swp2"},
+		},
+		"tags": map[string]interface{}{"pod": "5", "rail": "1"},
+	}
+
+	cases := map[string]string{
+		`hostname {{ hostname }}`: "hostname leaf-1",
+		`{{ asn }} {{ mtu / 2 }} {{ 7 // 2 }} {{ 7 % 4 }} {{ 2 ** 10 }}`:   "4200000001 4608 3 3 1024",
+		`{{ is_rr }} [{{ nothing }}] [{{ missing }}] [{{ missing.attr }}]`: "true [] [] []",
+		`{{ aggregates }}`:                                  "10.0.0.0/26,10.0.0.64/26",
+		`{{ tags.pod }}{{ tags["rail"] }}`:                  "51",
+		`{{ "a" ~ 1 ~ true }} {{ "a" + 1 }} {{ 1 + 2 }}`:    "a1true a1 3",
+		`{{ hostname | upper | replace("LEAF", "spine") }}`: "spine-1",
+		`{{ missing | default("x") }} [{{ nothing | default("x") }}] {{ "" | default("y", true) }}`: "x [] y",
+		`{{ aggregates | join(", ") }} {{ aggregates | length }} {{ aggregates | first }}`:          "10.0.0.0/26, 10.0.0.64/26 2 10.0.0.0/26",
+		`{{ bgp_neighbors | selectattr("port", "equalto", "swp2") | join(",", "ip") }}`:             "10.254.0.3",
+		`{{ bgp_neighbors | sort(true, false, "ip") | join(" ", "port") }}`:                         "swp2 swp1",
+		`{{ "10" | int + 1 }} {{ "x" | int(7) }} {{ 2.6 | round }} {{ -3 | abs }}`:                  "11 7 3 3",
+		`{{ tags | dump }}`:                                              `{"pod":"5","rail":"1"}`,
+		`{{ "a\nb" | indent(2) }}`:                                       "a\n  b",
+		`{{ aggregates[1:] }} {{ hostname[:4] }}`:                        "10.0.0.64/26 leaf",
+		`{{ hostname.split("-")[1] }} {{ hostname.startswith("leaf") }}`: "1 true",
+		`{% if mode == "l3evpn" and is_rr %}rr{% elif mode == "purel3" %}p{% else %}x{% endif %}`:                                             "rr",
+		`{% if empty %}empty lists are true{% endif %}`:                                                                                       "empty lists are true",
+		`{% if not missing and "10.0.0.0/26" in aggregates and "pod" in tags %}in{% endif %}`:                                                 "in",
+		`{% if asn is defined and missing is not defined and mtu is divisibleby 3 %}t{% endif %}`:                                             "t",
+		`{% for n in bgp_neighbors %}{{ loop.index }}/{{ loop.length }}:{{ n.ip }}{% if not loop.last %},{% endif %}{% endfor %}`:             "1/2:10.254.0.1,2/2:10.254.0.3",
+		`{% for k, v in tags %}{{ k }}={{ v }};{% endfor %}`:                                                                                  "pod=5;rail=1;",
+		`{% for k, v in tags.items() %}{{ k }}={{ v }};{% endfor %}`:                                                                          "pod=5;rail=1;",
+		`{% for x in empty %}x{% else %}none{% endfor %}`:                                                                                     "none",
+		`{% for i in range(3) %}{{ i }}{% endfor %}`:                                                                                          "012",
+		`{% set total = 0 %}{% for i in range(4) %}{% set total = total + i %}{% endfor %}{{ total }}`:                                        "6",
+		`{% set greeting %}hi {{ hostname }}{% endset %}{{ greeting }}`:                                                                       "hi leaf-1",
+		`{% macro peer(ip, port="swp0") %}neighbor {{ ip }} {{ port }}{% endmacro %}{{ peer("1.1.1.1") }}|{{ peer("2.2.2.2", port="swp9") }}`: "neighbor 1.1.1.1 swp0|neighbor 2.2.2.2 swp9",
+		`{{ "yes" if is_rr else "no" }} {{ 1 if false }}`:                                                                                     "yes ",
+		`{# comment #}a {%- raw %} {{ b }} {% endraw -%} c`:                                                                                   "a {{ b }} c",
+		"line\n  {%- if true %}\n  x\n  {%- endif %}\nend":                                                                                    "line\n  x\nend",
+	}
+	for text, want := range cases {
+		if got, _ := renderJinjaString(t, text, context); got != want {
+			t.Errorf("%s\n got %q\nwant %q", text, got, want)
+		}
+	}
+}
+
+func TestJinjaUndefined(t *testing.T) {
+	_, undefined := renderJinjaString(t, "{{ a }} {{ b.c }} {{ a | default('x') }} {% if d %}{% endif %}", nil)
+	if strings.Join(undefined, ",") != "a,b.c" {
+		t.Errorf("undefined = %v, want [a b.c]", undefined)
+	}
+}
+
+func TestJinjaErrors(t *testing.T) {
+	cases := map[string]string{
+		"{% if x %}":                   "test.j2:1: unclosed {% if x %}",
+		"a\n{{ x | nosuchfilter }}":    "test.j2:2: unknown filter",
+		"{% for x of y %}{% endfor %}": "expected {% for x in items %}",
+		"{% endif %}":                  "unexpected {% endif %}",
+		"{% include 'x.j2' %}":         "not supported",
+		"{{ x ":                        "unclosed {{",
+		"{{ 'abc }}":                   "unclosed {{",
+		"{{ x is nosuchtest }}":        "unknown test",
+		"{% if x %}{% endfor %}":       "unexpected {% endfor %} in {% if x %}",
+	}
+	for text, want := range cases {
+		_, err := parseJinja("test.j2", text)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err = %v, want it to contain %q", text, err, want)
+		}
+	}
+
+	tpl, err := parseJinja("test.j2", "ok\n{{ hostname() }}")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if _, _, err := tpl.render(map[string]interface{}{"hostname": "x"}); err == nil || !strings.Contains(err.Error(), "test.j2:2: hostname is not callable") {
+		t.Errorf("render error = %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package fabric_template_config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/utils"
+)
+
+// ProfileVariables are the variables one device's profile of one template
+// ("freeform", "underlay", "overlay", "pfc") would carry.
+type ProfileVariables struct {
+	Device    string                 `json:"device"`
+	Profile   string                 `json:"profile"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// ComputePlanVariables computes, without any API access, the profile
+// variables the freeform and bgp sections of data would register for the
+// devices of plan. The devices are taken to carry the hostnames, ASNs and
+// loopbacks the plan sets, as they would after configure-switches. The
+// template files are not read. It returns nothing when data has neither
+// section.
+func ComputePlanVariables(data []byte, plan *fsc.Plan) ([]ProfileVariables, error) {
+	var doc rawTemplateDoc
+	if err := utils.UnmarshalContent(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Freeform == nil && doc.Bgp == nil {
+		return nil, nil
+	}
+	planConfig, err := fsc.LoadConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	offline := offlinePlanContext(plan)
+	records := offline.records
+	switches := make([]*fsc.Device, len(offline.devices))
+	for i, rec := range offline.devices {
+		switches[i] = &rec.Device
+	}
+
+	var out []ProfileVariables
+	add := func(profile string, variables map[int64]map[string]interface{}, applies func(*fsc.Device) bool) {
+		for _, dev := range switches {
+			vars, ok := variables[dev.Id]
+			if !ok || (applies != nil && !applies(dev)) {
+				continue
+			}
+			out = append(out, ProfileVariables{Device: hostOf(dev, plan.State, records), Profile: profile, Variables: vars})
+		}
+	}
+
+	if raw := doc.Freeform; raw != nil {
+		if !validModes[raw.Mode] {
+			return nil, fmt.Errorf("freeform.mode must be one of [purel3 l3evpn], got %q", raw.Mode)
+		}
+		if raw.HgxPrefix != "" {
+			if _, _, err := net.ParseCIDR(raw.HgxPrefix); err != nil {
+				return nil, fmt.Errorf("freeform.hgxPrefix: invalid network %q: %w", raw.HgxPrefix, err)
+			}
+		}
+		hgx := hgxPrefix(planConfig, threeTier(plan.Groups), raw.HgxPrefix)
+		variables, err := computeFreeformVariables(plan.Groups, plan.State, records, raw.Mode, hgx)
+		if err != nil {
+			return nil, err
+		}
+		add("freeform", variables, nil)
+	}
+
+	if raw := doc.Bgp; raw != nil {
+		if !validModes[raw.Mode] {
+			return nil, fmt.Errorf("bgp.mode must be one of [purel3 l3evpn], got %q", raw.Mode)
+		}
+		if planConfig.Topology == nil || planConfig.Topology.LeafSpine == nil {
+			return nil, fmt.Errorf("'bgp' requires 'topology.leafSpine' (the neighbor set is the link plan)")
+		}
+		if planConfig.P2p == nil {
+			return nil, fmt.Errorf("'bgp' requires 'p2p' (neighbor IPs are the link /31s and /127s)")
+		}
+		var unplanned []string
+		for _, dev := range switches {
+			if records[dev.Id].Asn == nil || routerAddressOf(dev, plan.State, records) == "" {
+				unplanned = append(unplanned, dev.Label())
+			}
+		}
+		if len(unplanned) > 0 {
+			return nil, fmt.Errorf("'bgp' requires the 'asn' and 'loopback' sections; no asn/loopback planned for: %s", strings.Join(unplanned, ", "))
+		}
+
+		variables, err := computeBgpVariables(plan.Groups, plan.State, records, raw.Mode)
+		if err != nil {
+			return nil, err
+		}
+		add("underlay", variables, nil)
+
+		if raw.Mode == "l3evpn" {
+			overlay, err := computeOverlayVariables(plan.Groups, plan.State, records, raw.Mode, planConfig.TagKeys().Group)
+			if err != nil {
+				return nil, err
+			}
+			pfc := computePfcVariables(plan.Groups, raw.Mode)
+			add("overlay", overlay, func(dev *fsc.Device) bool { return overlayApplies(dev, overlay[dev.Id]) })
+			add("pfc", pfc, func(dev *fsc.Device) bool { return pfcApplies(pfc[dev.Id]) })
+		}
+	}
+	return out, nil
+}
+
+// offlinePlanContext is the planContext of an offline plan, its devices
+// carrying the hostnames, ASNs and loopbacks the plan sets.
+func offlinePlanContext(plan *fsc.Plan) *planContext {
+	records := map[int64]*deviceRecord{}
+	var ordered []*deviceRecord
+	for _, position := range switchPositions {
+		for _, dev := range plan.Groups[position] {
+			rec := &deviceRecord{Device: *dev}
+			if desired := plan.State.ByDevice[dev.Id]; desired != nil {
+				rec.Asn = desired.Asn
+				rec.LoopbackAddressIpv4 = desired.LoopbackIp
+				rec.LoopbackAddressIpv6 = desired.LoopbackIpv6
+			}
+			records[dev.Id] = rec
+			ordered = append(ordered, rec)
+		}
+	}
+	return &planContext{groups: plan.Groups, state: plan.State, records: records, devices: ordered}
+}
@@ -0,0 +1,45 @@
+package fabric_template_config
+
+import (
+	"testing"
+
+	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
+)
+
+func TestComputePlanVariables(t *testing.T) {
+	data := bgpL3evpnConfig(t)
+	config, err := fsc.LoadConfig(data)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	var devices []*fsc.Device
+	for _, rec := range fixtureDeviceRecords() {
+		devices = append(devices, &rec.Device)
+	}
+	plan, err := fsc.ComputePlan(config, devices)
+	if err != nil {
+		t.Fatalf("ComputePlan: %v", err)
+	}
+
+	// The devices carry no ASN: the planned ones stand in for them.
+	variables, err := ComputePlanVariables(data, plan)
+	if err != nil {
+		t.Fatalf("ComputePlanVariables: %v", err)
+	}
+	count := map[string]int{}
+	for _, v := range variables {
+		count[v.Profile]++
+		if v.Device == "" || len(v.Variables) == 0 {
+			t.Errorf("%s profile of %q has no variables", v.Profile, v.Device)
+		}
+	}
+	if count["underlay"] != 8 || count["overlay"] != 5 || count["pfc"] != 8 {
+		t.Errorf("profiles = %v, want underlay 8, overlay 5, pfc 8", count)
+	}
+
+	// No freeform or bgp section: nothing to compute.
+	variables, err = ComputePlanVariables([]byte("loopback:\n  subnet: 10.253.128.0/18\n"), plan)
+	if err != nil || variables != nil {
+		t.Errorf("without a template section: %v, %v", variables, err)
+	}
+}
@@ -0,0 +1,285 @@
+package fabric_template_config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/utils"
+)
+
+// Statuses of a RenderedFile against the previous render in the directory.
+const (
+	RenderAdded     = "added"
+	RenderChanged   = "changed"
+	RenderUnchanged = "unchanged"
+	RenderRemoved   = "removed"
+)
+
+// RenderOptions selects where RenderFreeformLocal and RenderBgpLocal take the
+// devices from and write the renders to.
+type RenderOptions struct {
+	// OutputDir receives one <host>.<profile>.conf file per device and profile.
+	OutputDir string
+	// Plan, when set, is an offline plan whose devices are rendered instead of
+	// the fabric's; the client is not used.
+	Plan *fsc.Plan
+}
+
+// RenderedFile is one device's render of one template.
+type RenderedFile struct {
+	Device  string `json:"device"`
+	Profile string `json:"profile"`
+	Path    string `json:"path"`
+	Status  string `json:"status"`
+	// Undefined lists the variables the template read that the context lacks.
+	Undefined []string `json:"undefined,omitempty"`
+	// Diff is the unified diff against the previous render, for changed files.
+	Diff string `json:"diff,omitempty"`
+}
+
+// RenderResult is the outcome of a local render.
+type RenderResult struct {
+	Files    []RenderedFile `json:"files"`
+	Warnings []string       `json:"warnings,omitempty"`
+}
+
+// renderIndexFile lists, per profile, the files the last render wrote into the
+// output directory, so the next one removes only its own stale files.
+const renderIndexFile = ".render-index.json"
+
+// renderJob is one template rendered for a set of devices.
+type renderJob struct {
+	profile    string
+	spec       templateSpec
+	devices    []*deviceRecord
+	contextFor func(*deviceRecord) map[string]interface{}
+}
+
+// RenderFreeformLocal renders the freeform template for every switch locally,
+// with the variables RunFreeform would register, into options.OutputDir.
+func RenderFreeformLocal(client TemplateClient, data []byte, fabricId int64, options RenderOptions) (*RenderResult, error) {
+	freeform, err := LoadFreeformConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	plan, planConfig, warnings, err := renderPlan(client, data, fabricId, options.Plan)
+	if err != nil {
+		return nil, err
+	}
+	hgx := hgxPrefix(planConfig, threeTier(plan.groups), freeform.HgxPrefix)
+	variables, err := computeFreeformVariables(plan.groups, plan.state, plan.records, freeform.Mode, hgx)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []renderJob{{profile: "freeform", spec: freeform.Template, devices: plan.devices,
+		contextFor: func(rec *deviceRecord) map[string]interface{} {
+			return renderContextFreeform(&rec.Device, variables[rec.Id], plan.state, plan.records)
+		}}}
+	files, err := renderLocal(plan, jobs, []string{"freeform"}, options.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	return &RenderResult{Files: files, Warnings: warnings}, nil
+}
+
+// RenderBgpLocal renders the BGP underlay template (and, in l3evpn, the overlay
+// and PFC templates) locally, with the variables RunBgp would register, into
+// options.OutputDir. The action-bound VRF template has no profile and is not
+// rendered.
+func RenderBgpLocal(client TemplateClient, data []byte, fabricId int64, options RenderOptions) (*RenderResult, error) {
+	bgp, err := LoadBgpConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	plan, planConfig, warnings, err := renderPlan(client, data, fabricId, options.Plan)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := computeBgpLayers(plan, planConfig, bgp.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []renderJob{{profile: "underlay", spec: bgp.Underlay, devices: plan.devices, contextFor: bgpCtx(layers.underlay, plan)}}
+	if bgp.Mode == "l3evpn" {
+		jobs = append(jobs,
+			renderJob{profile: "overlay", spec: bgp.Overlay, devices: layers.overlayTargets, contextFor: bgpCtx(layers.overlay, plan)},
+			renderJob{profile: "pfc", spec: bgp.Pfc, devices: layers.pfcTargets, contextFor: bgpCtx(layers.pfc, plan)})
+	}
+	files, err := renderLocal(plan, jobs, []string{"underlay", "overlay", "pfc"}, options.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	return &RenderResult{Files: files, Warnings: warnings}, nil
+}
+
+// renderPlan is the fabric's plan, or the offline one when given.
+func renderPlan(client TemplateClient, data []byte, fabricId int64, offline *fsc.Plan) (*planContext, *fsc.Config, []string, error) {
+	if offline == nil {
+		return buildPlan(client, data, fabricId)
+	}
+	planConfig, err := fsc.LoadConfig(data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return offlinePlanContext(offline), planConfig, offline.Warnings, nil
+}
+
+// renderLocal renders jobs into dir and compares each file with the one the
+// previous render left there. Files of the given profiles that the previous
+// render recorded in the directory's index and no device rendered this time
+// are removed; files the index does not list are left alone. The result is
+// sorted by path.
+func renderLocal(plan *planContext, jobs []renderJob, profiles []string, dir string) ([]RenderedFile, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("an output directory is required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create output directory %q: %w", dir, err)
+	}
+	index, err := readRenderIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	bases := renderFileBases(plan)
+	var files []RenderedFile
+	written := map[string]string{}
+	for _, job := range jobs {
+		name := job.spec.Path
+		if name == "" {
+			name = job.spec.Label
+		}
+		tpl, err := parseJinja(name, job.spec.Text)
+		if err != nil {
+			return nil, err
+		}
+		for _, dev := range job.devices {
+			host := hostOf(&dev.Device, plan.state, plan.records)
+			text, undefined, err := tpl.render(job.contextFor(dev))
+			if err != nil {
+				return nil, fmt.Errorf("[%s] %s render failed: %w", dev.Label(), job.profile, err)
+			}
+			fileName := bases[dev.Id] + "." + job.profile + ".conf"
+			written[fileName] = job.profile
+
+			file := RenderedFile{Device: host, Profile: job.profile, Path: filepath.Join(dir, fileName), Undefined: undefined}
+			previous, err := os.ReadFile(file.Path)
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				file.Status = RenderAdded
+			case err != nil:
+				return nil, fmt.Errorf("cannot read previous render %q: %w", file.Path, err)
+			case string(previous) == text:
+				file.Status = RenderUnchanged
+			default:
+				file.Status = RenderChanged
+				file.Diff = utils.UnifiedDiff("a/"+fileName, "b/"+fileName, string(previous), text)
+			}
+			if file.Status != RenderUnchanged {
+				if err := os.WriteFile(file.Path, []byte(text), 0644); err != nil {
+					return nil, fmt.Errorf("cannot write %q: %w", file.Path, err)
+				}
+			}
+			if len(undefined) > 0 {
+				logger.Get().Debug().Msgf("[%s] %s render read undefined variable(s): %s", dev.Label(), job.profile, strings.Join(undefined, ", "))
+			}
+			files = append(files, file)
+		}
+	}
+
+	for _, profile := range profiles {
+		suffix := "." + profile + ".conf"
+		for _, fileName := range index[profile] {
+			if written[fileName] != "" || fileName != filepath.Base(fileName) || !strings.HasSuffix(fileName, suffix) {
+				continue
+			}
+			path := filepath.Join(dir, fileName)
+			if err := os.Remove(path); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return nil, fmt.Errorf("cannot remove stale render %q: %w", path, err)
+			}
+			files = append(files, RenderedFile{Device: strings.TrimSuffix(fileName, suffix), Profile: profile, Path: path, Status: RenderRemoved})
+		}
+		delete(index, profile)
+	}
+	for fileName, profile := range written {
+		index[profile] = append(index[profile], fileName)
+	}
+	if err := writeRenderIndex(dir, index); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// readRenderIndex reads the index of the files the previous render wrote into
+// dir, by profile. A directory with no index has none.
+func readRenderIndex(dir string) (map[string][]string, error) {
+	index := map[string][]string{}
+	data, err := os.ReadFile(filepath.Join(dir, renderIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read render index: %w", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid render index %q: %w", filepath.Join(dir, renderIndexFile), err)
+	}
+	return index, nil
+}
+
+// writeRenderIndex records the files of the render in dir, by profile.
+func writeRenderIndex(dir string, index map[string][]string) error {
+	for _, fileNames := range index {
+		sort.Strings(fileNames)
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, renderIndexFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write render index: %w", err)
+	}
+	return nil
+}
+
+// renderFileBases names each device's render files after its host, reduced
+// to characters safe in a file name. Devices whose names would collide (e.g.
+// before configure-switches has set distinct hostnames) get their id appended.
+func renderFileBases(plan *planContext) map[int64]string {
+	bases := map[int64]string{}
+	devices := map[string]int{}
+	for _, dev := range plan.devices {
+		base := strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+				return r
+			}
+			return '_'
+		}, hostOf(&dev.Device, plan.state, plan.records))
+		if strings.Trim(base, ".") == "" {
+			base = "device"
+		}
+		bases[dev.Id] = base
+		devices[base]++
+	}
+	for id, base := range bases {
+		if devices[base] > 1 {
+			bases[id] = fmt.Sprintf("%s-%d", base, id)
+		}
+	}
+	return bases
+}
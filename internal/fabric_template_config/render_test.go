@@ -0,0 +1,136 @@
+package fabric_template_config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
+)
+
+func renderStatuses(files []RenderedFile) map[string]int {
+	count := map[string]int{}
+	for _, f := range files {
+		count[f.Status]++
+	}
+	return count
+}
+
+func TestRenderFreeformLocal(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "rendered")
+	tmpl := writeTemplate(t, dir, "freeform.j2", "hostname {{ identifierString }}\nmode {{ mode }}\n")
+	config := []byte(fmt.Sprintf(`
+ordering: managementAddress
+loopback:
+  subnet: 10.253.128.0/18
+topology:
+  leafSpine:
+    linksPerPair: auto
+  spineSuperSpine:
+    linksPerPair: 4
+  leafHost:
+    nodeCount: 2
+p2p:
+  mtu: 9216
+freeform:
+  mode: l3evpn
+  templatePath: %s
+`, tmpl))
+
+	f := newFakeClient()
+	res, err := RenderFreeformLocal(f, config, 5, RenderOptions{OutputDir: out})
+	if err != nil {
+		t.Fatalf("RenderFreeformLocal: %v", err)
+	}
+	if got := renderStatuses(res.Files); got[RenderAdded] != 8 || len(res.Files) != 8 {
+		t.Fatalf("first render statuses = %v, want 8 added", got)
+	}
+	body, err := os.ReadFile(res.Files[0].Path)
+	if err != nil || !strings.HasPrefix(string(body), "hostname ") || !strings.HasSuffix(string(body), "mode l3evpn\n") {
+		t.Errorf("rendered %s = %q, %v", res.Files[0].Path, body, err)
+	}
+	if f.templatesCreated != 0 || f.profilesCreated != 0 || f.renders != 0 {
+		t.Errorf("local render wrote to or rendered on the controller")
+	}
+
+	res, err = RenderFreeformLocal(f, config, 5, RenderOptions{OutputDir: out})
+	if err != nil {
+		t.Fatalf("second RenderFreeformLocal: %v", err)
+	}
+	if got := renderStatuses(res.Files); got[RenderUnchanged] != 8 {
+		t.Errorf("second render statuses = %v, want 8 unchanged", got)
+	}
+
+	writeTemplate(t, dir, "freeform.j2", "hostname {{ identifierString }}\nmode {{ mode | upper }}\n")
+	// A render of a device no longer in the fabric is removed; files the
+	// previous render did not write stay, even with a render's name.
+	writeTemplate(t, out, "gone.freeform.conf", "hostname gone\n")
+	writeTemplate(t, out, "mine.freeform.conf", "kept\n")
+	writeTemplate(t, out, "notes.txt", "kept\n")
+	index, err := readRenderIndex(out)
+	if err != nil || len(index["freeform"]) != 8 {
+		t.Fatalf("render index = %v, %v, want 8 freeform files", index, err)
+	}
+	index["freeform"] = append(index["freeform"], "gone.freeform.conf")
+	if err := writeRenderIndex(out, index); err != nil {
+		t.Fatalf("writeRenderIndex: %v", err)
+	}
+	res, err = RenderFreeformLocal(f, config, 5, RenderOptions{OutputDir: out})
+	if err != nil {
+		t.Fatalf("third RenderFreeformLocal: %v", err)
+	}
+	if got := renderStatuses(res.Files); got[RenderChanged] != 8 || got[RenderRemoved] != 1 {
+		t.Fatalf("third render statuses = %v, want 8 changed, 1 removed", got)
+	}
+	for _, file := range res.Files {
+		switch file.Status {
+		case RenderChanged:
+			if !strings.Contains(file.Diff, "-mode l3evpn\n+mode L3EVPN\n") {
+				t.Errorf("%s diff = %q", file.Path, file.Diff)
+			}
+		case RenderRemoved:
+			if _, err := os.Stat(file.Path); !os.IsNotExist(err) {
+				t.Errorf("removed render %s still exists", file.Path)
+			}
+		}
+	}
+	for _, name := range []string{"notes.txt", "mine.freeform.conf"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Errorf("unrelated file %s removed: %v", name, err)
+		}
+	}
+}
+
+func TestRenderBgpLocalOffline(t *testing.T) {
+	data := bgpL3evpnConfig(t)
+	config, err := fsc.LoadConfig(data)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	var devices []*fsc.Device
+	for _, rec := range fixtureDeviceRecords() {
+		devices = append(devices, &rec.Device)
+	}
+	plan, err := fsc.ComputePlan(config, devices)
+	if err != nil {
+		t.Fatalf("ComputePlan: %v", err)
+	}
+
+	res, err := RenderBgpLocal(nil, data, 0, RenderOptions{OutputDir: t.TempDir(), Plan: plan})
+	if err != nil {
+		t.Fatalf("RenderBgpLocal: %v", err)
+	}
+	count := map[string]int{}
+	for _, file := range res.Files {
+		count[file.Profile]++
+		if file.Status != RenderAdded || len(file.Undefined) > 0 {
+			t.Errorf("%s: status %s, undefined %v", file.Path, file.Status, file.Undefined)
+		}
+	}
+	if count["underlay"] != 8 || count["overlay"] != 5 || count["pfc"] != 8 {
+		t.Errorf("renders = %v, want underlay 8, overlay 5, pfc 8", count)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
 )
 
@@ -15,9 +16,30 @@ const (
 	vrfDescription      = "Spectrum-X route-domain tenant VRF (EVPN VNI/RD + leaf /26 aggregate-routes from device customVariables)"
 )
 
+// RunOptions selects how RunFreeformWithOptions and RunBgpWithOptions apply
+// the plan.
+type RunOptions struct {
+	// DryRun reports what would change, without writing.
+	DryRun bool
+	// Verify pushes each device's render through the engine before any write.
+	Verify bool
+	// Parallelism is how many devices' profiles (and customVariables) are
+	// written at once; below 2 the run is sequential.
+	Parallelism int
+	// Progress, when set, is called as the "profiles" and "custom variables"
+	// steps advance.
+	Progress fsc.ProgressFunc
+}
+
 // RunFreeform registers the base freeform template + one profile per switch.
 // verify pushes each device's render through the engine first.
 func RunFreeform(client TemplateClient, data []byte, fabricId int64, dryRun, verify bool) (*Result, error) {
+	return RunFreeformWithOptions(client, data, fabricId, RunOptions{DryRun: dryRun, Verify: verify})
+}
+
+// RunFreeformWithOptions is RunFreeform with the full set of RunOptions.
+func RunFreeformWithOptions(client TemplateClient, data []byte, fabricId int64, options RunOptions) (*Result, error) {
+	dryRun, verify := options.DryRun, options.Verify
 	freeform, err := LoadFreeformConfig(data)
 	if err != nil {
 		return nil, err
@@ -40,7 +62,8 @@ func RunFreeform(client TemplateClient, data []byte, fabricId int64, dryRun, ver
 	}
 
 	r := &runner{client: client, fabricId: fabricId, dryRun: dryRun, apply: freeform.ApplyMode,
-		result: &Result{Counters: map[string]int{}, Warnings: warnings}}
+		result:      &Result{Counters: map[string]int{}, Warnings: warnings},
+		parallelism: options.Parallelism, progress: options.Progress}
 
 	if verify {
 		m := r.verifyRender(freeform.Template, plan.devices, func(rec *deviceRecord) map[string]interface{} {
@@ -66,6 +89,12 @@ func RunFreeform(client TemplateClient, data []byte, fabricId int64, dryRun, ver
 // RunBgp registers the BGP underlay (+ l3evpn overlay/PFC/VRF) templates and
 // per-switch profiles, and reconciles device customVariables.
 func RunBgp(client TemplateClient, data []byte, fabricId int64, dryRun, verify bool) (*Result, error) {
+	return RunBgpWithOptions(client, data, fabricId, RunOptions{DryRun: dryRun, Verify: verify})
+}
+
+// RunBgpWithOptions is RunBgp with the full set of RunOptions.
+func RunBgpWithOptions(client TemplateClient, data []byte, fabricId int64, options RunOptions) (*Result, error) {
+	dryRun, verify := options.DryRun, options.Verify
 	bgp, err := LoadBgpConfig(data)
 	if err != nil {
 		return nil, err
@@ -74,44 +103,12 @@ func RunBgp(client TemplateClient, data []byte, fabricId int64, dryRun, verify b
 	if err != nil {
 		return nil, err
 	}
-	if planConfig.Topology == nil || planConfig.Topology.LeafSpine == nil {
-		return nil, fmt.Errorf("'bgp' requires 'topology.leafSpine' (the neighbor set is the link plan)")
-	}
-	if planConfig.P2p == nil {
-		return nil, fmt.Errorf("'bgp' requires 'p2p' (neighbor IPs are the link /31s)")
-	}
-
-	variables, err := computeBgpVariables(plan.groups, plan.state, plan.records, bgp.Mode)
+	layers, err := computeBgpLayers(plan, planConfig, bgp.Mode)
 	if err != nil {
 		return nil, err
 	}
-
-	// asn/loopback must already be on the device records (configure-switches first).
-	var unconfigured []string
-	for _, dev := range plan.devices {
-		if dev.Asn == nil || loopbackOf(&dev.Device, plan.state, plan.records) == "" {
-			unconfigured = append(unconfigured, dev.Label())
-		}
-	}
-	if len(unconfigured) > 0 {
-		return nil, fmt.Errorf("device(s) missing asn/loopbackAddress (run 'fabric configure-switches' first): %s", strings.Join(unconfigured, ", "))
-	}
-
-	overlay, err := computeOverlayVariables(plan.groups, plan.state, plan.records, bgp.Mode)
-	if err != nil {
-		return nil, err
-	}
-	pfc := computePfcVariables(plan.groups, bgp.Mode)
-
-	var overlayTargets, pfcTargets []*deviceRecord
-	for _, dev := range plan.devices {
-		if overlayApplies(&dev.Device, overlay[dev.Id]) {
-			overlayTargets = append(overlayTargets, dev)
-		}
-		if pfcApplies(pfc[dev.Id]) {
-			pfcTargets = append(pfcTargets, dev)
-		}
-	}
+	variables, overlay, pfc := layers.underlay, layers.overlay, layers.pfc
+	overlayTargets, pfcTargets := layers.overlayTargets, layers.pfcTargets
 
 	logger.Get().Debug().Msgf("bgp: mode=%s, %d switch(es); overlay targets=%d, pfc targets=%d",
 		bgp.Mode, len(plan.devices), len(overlayTargets), len(pfcTargets))
@@ -121,7 +118,8 @@ func RunBgp(client TemplateClient, data []byte, fabricId int64, dryRun, verify b
 	}
 
 	r := &runner{client: client, fabricId: fabricId, dryRun: dryRun, apply: bgp.ApplyMode,
-		result: &Result{Counters: map[string]int{}, Warnings: warnings}}
+		result:      &Result{Counters: map[string]int{}, Warnings: warnings},
+		parallelism: options.Parallelism, progress: options.Progress}
 
 	if verify {
 		m := r.verifyRender(bgp.Underlay, plan.devices, bgpCtx(variables, plan))
@@ -157,6 +155,54 @@ func RunBgp(client TemplateClient, data []byte, fabricId int64, dryRun, verify b
 	return r.result, nil
 }
 
+// bgpLayers are the per-device variables of the bgp layers and the devices
+// the overlay and pfc layers apply to.
+type bgpLayers struct {
+	underlay, overlay, pfc     map[int64]map[string]interface{}
+	overlayTargets, pfcTargets []*deviceRecord
+}
+
+// computeBgpLayers checks that the plan can carry bgp and computes its layers.
+func computeBgpLayers(plan *planContext, planConfig *fsc.Config, mode string) (*bgpLayers, error) {
+	if planConfig.Topology == nil || planConfig.Topology.LeafSpine == nil {
+		return nil, fmt.Errorf("'bgp' requires 'topology.leafSpine' (the neighbor set is the link plan)")
+	}
+	if planConfig.P2p == nil {
+		return nil, fmt.Errorf("'bgp' requires 'p2p' (neighbor IPs are the link /31s and /127s)")
+	}
+
+	variables, err := computeBgpVariables(plan.groups, plan.state, plan.records, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	// asn/loopback must already be on the device records (configure-switches first).
+	var unconfigured []string
+	for _, dev := range plan.devices {
+		if dev.Asn == nil || routerAddressOf(&dev.Device, plan.state, plan.records) == "" {
+			unconfigured = append(unconfigured, dev.Label())
+		}
+	}
+	if len(unconfigured) > 0 {
+		return nil, fmt.Errorf("device(s) missing asn/loopbackAddress (run 'fabric configure-switches' first): %s", strings.Join(unconfigured, ", "))
+	}
+
+	overlay, err := computeOverlayVariables(plan.groups, plan.state, plan.records, mode, planConfig.TagKeys().Group)
+	if err != nil {
+		return nil, err
+	}
+	layers := &bgpLayers{underlay: variables, overlay: overlay, pfc: computePfcVariables(plan.groups, mode)}
+	for _, dev := range plan.devices {
+		if overlayApplies(&dev.Device, overlay[dev.Id]) {
+			layers.overlayTargets = append(layers.overlayTargets, dev)
+		}
+		if pfcApplies(layers.pfc[dev.Id]) {
+			layers.pfcTargets = append(layers.pfcTargets, dev)
+		}
+	}
+	return layers, nil
+}
+
 func bgpCtx(variables map[int64]map[string]interface{}, plan *planContext) func(*deviceRecord) map[string]interface{} {
 	return func(rec *deviceRecord) map[string]interface{} {
 		return renderContextBgp(&rec.Device, variables[rec.Id], plan.records)
@@ -166,7 +212,7 @@ func bgpCtx(variables map[int64]map[string]interface{}, plan *planContext) func(
 // reconcileCustomVariables writes each device's customVariables = {aggregates,
 // is_evpn_rr} (strings), which the engine's route-domain tenant-VRF render reads.
 func (r *runner) reconcileCustomVariables(devices []*deviceRecord, variables, overlay map[int64]map[string]interface{}) {
-	for _, dev := range devices {
+	r.parallel("custom variables", devices, func(r *runner, dev *deviceRecord) {
 		var aggregates []string
 		if a, ok := variables[dev.Id]["aggregates"].([]string); ok {
 			aggregates = a
@@ -183,7 +229,7 @@ func (r *runner) reconcileCustomVariables(devices []*deviceRecord, variables, ov
 		current, revision, err := r.client.GetDeviceCustomVariables(dev.Id)
 		if err != nil {
 			r.fail("[%s] device GET failed: %s", dev.Label(), err.Error())
-			continue
+			return
 		}
 		merged := map[string]interface{}{}
 		for k, v := range current {
@@ -194,18 +240,18 @@ func (r *runner) reconcileCustomVariables(devices []*deviceRecord, variables, ov
 		}
 		if canonicalJSON(merged) == canonicalJSON(current) {
 			r.count("device custom variables unchanged")
-			continue
+			return
 		}
 		if r.dryRun {
 			r.count("device custom variables set")
-			continue
+			return
 		}
 		if err := r.client.UpdateDeviceCustomVariables(dev.Id, merged, revision); err != nil {
 			r.fail("[%s] customVariables PATCH failed: %s", dev.Label(), err.Error())
-			continue
+			return
 		}
 		r.count("device custom variables set")
-	}
+	})
 }
 
 func boolStr(b bool) string {
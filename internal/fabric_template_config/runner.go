@@ -8,6 +8,7 @@ import (
 
 	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/utils"
 )
 
 const profileLifecycleStage = "configuration"
@@ -25,6 +26,9 @@ type runner struct {
 	dryRun   bool
 	apply    string
 	result   *Result
+
+	parallelism int
+	progress    fsc.ProgressFunc
 }
 
 func (r *runner) count(key string) { r.result.Counters[key]++ }
@@ -33,6 +37,29 @@ func (r *runner) fail(format string, args ...any) {
 	logger.Get().Error().Msgf(format, args...)
 }
 
+// parallel runs task for every device on up to r.parallelism goroutines, each
+// on a fork of the runner whose result is merged back once all are done.
+func (r *runner) parallel(step string, devices []*deviceRecord, task func(r *runner, dev *deviceRecord)) {
+	forks := make([]*runner, len(devices))
+	var progress func(int)
+	if r.progress != nil {
+		progress = func(done int) { r.progress(step, done, len(devices)) }
+	}
+	utils.ForEachParallel(len(devices), r.parallelism, func(i int) {
+		f := *r
+		f.result = &Result{Counters: map[string]int{}}
+		forks[i] = &f
+		task(forks[i], devices[i])
+	}, progress)
+	for _, f := range forks {
+		for key, n := range f.result.Counters {
+			r.result.Counters[key] += n
+		}
+		r.result.Failures += f.result.Failures
+		r.result.Warnings = append(r.result.Warnings, f.result.Warnings...)
+	}
+}
+
 // varSummary is a compact one-line rendering of a profile's variables for debug
 // logging: list-valued variables show their length, scalars show their value.
 func varSummary(vars map[string]interface{}) string {
@@ -238,59 +265,66 @@ func (r *runner) ensureProfiles(templateId int64, usable bool, devices []*device
 		}
 	}
 
+	var targets []*deviceRecord
 	for _, dev := range devices {
-		vars, ok := variables[dev.Id]
-		if !ok {
-			continue
-		}
-		existing := existingByDevice[dev.Id]
-		if existing == nil {
-			logger.Get().Debug().Msgf("[%s] profile (template %d): create {%s}", dev.Label(), templateId, varSummary(vars))
-			if r.dryRun {
-				r.count("profiles created")
-				continue
-			}
-			err := r.client.CreateProfile(profileCreate{
-				TemplateId: templateId, DeviceId: dev.Id, FabricId: r.fabricId,
-				LifecycleStage: profileLifecycleStage, Variables: vars,
-				IsEnabled: true, Priority: priority, ApplyMode: r.apply,
-			})
-			if err != nil {
-				r.fail("[%s] profile POST failed: %s", dev.Label(), err.Error())
-				continue
-			}
-			r.count("profiles created")
-			continue
+		if _, ok := variables[dev.Id]; ok {
+			targets = append(targets, dev)
 		}
+	}
+	r.parallel("profiles", targets, func(r *runner, dev *deviceRecord) {
+		r.ensureProfile(templateId, dev, variables[dev.Id], existingByDevice[dev.Id], priority)
+	})
+}
 
-		varsDrift := canonicalJSON(existing.Variables) != canonicalJSON(vars)
-		priorityDrift := existing.Priority == nil || *existing.Priority != priority
-		applyDrift := existing.ApplyMode != r.apply
-		enabledDrift := existing.IsEnabled == nil || !*existing.IsEnabled
-		if !varsDrift && !priorityDrift && !applyDrift && !enabledDrift {
-			logger.Get().Debug().Msgf("[%s] profile id=%s (template %d): unchanged", dev.Label(), existing.Id, templateId)
-			r.count("profiles unchanged")
-			continue
-		}
-		logger.Get().Debug().Msgf("[%s] profile id=%s: update (vars=%v priority=%v applyMode=%v enabled=%v)",
-			dev.Label(), existing.Id, varsDrift, priorityDrift, applyDrift, enabledDrift)
+// ensureProfile reconciles the profile of one device: POST when there is none,
+// PATCH when it drifted.
+func (r *runner) ensureProfile(templateId int64, dev *deviceRecord, vars map[string]interface{}, existing *profileRecord, priority float32) {
+	if existing == nil {
+		logger.Get().Debug().Msgf("[%s] profile (template %d): create {%s}", dev.Label(), templateId, varSummary(vars))
 		if r.dryRun {
-			r.count("profiles updated")
-			continue
+			r.count("profiles created")
+			return
 		}
-		id, _ := parseInt64(existing.Id)
-		err := r.client.UpdateProfile(id, profileUpdate{Variables: vars, IsEnabled: true, Priority: priority, ApplyMode: r.apply}, existing.Revision)
+		err := r.client.CreateProfile(profileCreate{
+			TemplateId: templateId, DeviceId: dev.Id, FabricId: r.fabricId,
+			LifecycleStage: profileLifecycleStage, Variables: vars,
+			IsEnabled: true, Priority: priority, ApplyMode: r.apply,
+		})
 		if err != nil {
-			r.fail("[%s] profile PATCH failed: %s", dev.Label(), err.Error())
-			continue
+			r.fail("[%s] profile POST failed: %s", dev.Label(), err.Error())
+			return
 		}
+		r.count("profiles created")
+		return
+	}
+
+	varsDrift := canonicalJSON(existing.Variables) != canonicalJSON(vars)
+	priorityDrift := existing.Priority == nil || *existing.Priority != priority
+	applyDrift := existing.ApplyMode != r.apply
+	enabledDrift := existing.IsEnabled == nil || !*existing.IsEnabled
+	if !varsDrift && !priorityDrift && !applyDrift && !enabledDrift {
+		logger.Get().Debug().Msgf("[%s] profile id=%s (template %d): unchanged", dev.Label(), existing.Id, templateId)
+		r.count("profiles unchanged")
+		return
+	}
+	logger.Get().Debug().Msgf("[%s] profile id=%s: update (vars=%v priority=%v applyMode=%v enabled=%v)",
+		dev.Label(), existing.Id, varsDrift, priorityDrift, applyDrift, enabledDrift)
+	if r.dryRun {
 		r.count("profiles updated")
+		return
+	}
+	id, _ := parseInt64(existing.Id)
+	err := r.client.UpdateProfile(id, profileUpdate{Variables: vars, IsEnabled: true, Priority: priority, ApplyMode: r.apply}, existing.Revision)
+	if err != nil {
+		r.fail("[%s] profile PATCH failed: %s", dev.Label(), err.Error())
+		return
 	}
+	r.count("profiles updated")
 }
 
 // verifyRender pushes each device's render context through the engine's
 // stateless render endpoint; a render error counts as a verification failure.
-// (Unlike the Python gate, there is no local Jinja2 render to compare against.)
+// (RenderFreeformLocal / RenderBgpLocal render without the engine.)
 func (r *runner) verifyRender(spec templateSpec, devices []*deviceRecord, contextFor func(*deviceRecord) map[string]interface{}) int {
 	contentB64 := base64Encode(spec.Text)
 	mismatches := 0
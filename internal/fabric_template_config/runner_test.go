@@ -4,14 +4,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 
 	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
 )
 
 // fakeTemplateClient is an in-memory TemplateClient that records and applies
-// writes so a re-run observes the new state.
+// writes so a re-run observes the new state. mu serializes the writes a
+// parallel run makes.
 type fakeTemplateClient struct {
+	mu        sync.Mutex
 	siteId    int64
 	devices   []*deviceRecord
 	nextId    int64
@@ -70,6 +74,8 @@ func (f *fakeTemplateClient) ListProfiles() ([]*profileRecord, error) {
 	return out, nil
 }
 func (f *fakeTemplateClient) CreateProfile(p profileCreate) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.profilesCreated++
 	id := f.newId()
 	prio := p.Priority
@@ -81,6 +87,8 @@ func (f *fakeTemplateClient) CreateProfile(p profileCreate) error {
 	return nil
 }
 func (f *fakeTemplateClient) UpdateProfile(id int64, p profileUpdate, revision string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.profilesUpdated++
 	return nil
 }
@@ -92,6 +100,8 @@ func (f *fakeTemplateClient) GetDeviceCustomVariables(int64) (map[string]interfa
 	return map[string]interface{}{}, "1", nil
 }
 func (f *fakeTemplateClient) UpdateDeviceCustomVariables(int64, map[string]interface{}, string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.customVarsSet++
 	return nil
 }
@@ -183,10 +193,11 @@ freeform:
 	}
 }
 
-func TestRunBgpL3evpn(t *testing.T) {
+func bgpL3evpnConfig(t *testing.T) []byte {
+	t.Helper()
 	dir := t.TempDir()
 	body := "{{ mode }} {{ position }}\n"
-	config := []byte(fmt.Sprintf(`
+	return []byte(fmt.Sprintf(`
 ordering: managementAddress
 loopback:
   subnet: 10.253.128.0/18
@@ -211,13 +222,22 @@ bgp:
 		writeTemplate(t, dir, "overlay.j2", body),
 		writeTemplate(t, dir, "pfc.j2", body),
 		writeTemplate(t, dir, "vrf.j2", body)))
+}
 
+// newConfiguredFakeClient is newFakeClient with the asn configure-switches
+// would have set on every record.
+func newConfiguredFakeClient() *fakeTemplateClient {
 	f := newFakeClient()
-	// asn must be present on records (configure-switches first); set it.
 	for _, d := range f.devices {
 		asn := int64(4200000000 + d.Id)
 		d.Asn = &asn
 	}
+	return f
+}
+
+func TestRunBgpL3evpn(t *testing.T) {
+	config := bgpL3evpnConfig(t)
+	f := newConfiguredFakeClient()
 	res, err := RunBgp(f, config, 5, false, false)
 	if err != nil {
 		t.Fatalf("RunBgp: %v", err)
@@ -239,6 +259,38 @@ bgp:
 	}
 }
 
+func TestRunBgpParallel(t *testing.T) {
+	config := bgpL3evpnConfig(t)
+	want, err := RunBgp(newConfiguredFakeClient(), config, 5, false, false)
+	if err != nil {
+		t.Fatalf("RunBgp: %v", err)
+	}
+
+	f := newConfiguredFakeClient()
+	finished := map[string]int{}
+	got, err := RunBgpWithOptions(f, config, 5, RunOptions{
+		Parallelism: 3,
+		Progress: func(step string, done, total int) {
+			if done == total {
+				finished[step]++
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("parallel RunBgp: %v", err)
+	}
+	if got.Failures != 0 || !reflect.DeepEqual(got.Counters, want.Counters) {
+		t.Errorf("parallel run: failures=%d counters=%v, want %v", got.Failures, got.Counters, want.Counters)
+	}
+	if f.profilesCreated != 8+5+8 || f.customVarsSet != 8 {
+		t.Errorf("profiles created = %d, customVariables set = %d", f.profilesCreated, f.customVarsSet)
+	}
+	// underlay, overlay and pfc profiles, then the customVariables.
+	if finished["profiles"] != 3 || finished["custom variables"] != 1 {
+		t.Errorf("finished steps = %v", finished)
+	}
+}
+
 func TestRunBgpRequiresAsnLoopback(t *testing.T) {
 	dir := t.TempDir()
 	body := "x\n"
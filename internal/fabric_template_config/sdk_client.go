@@ -41,6 +41,7 @@ func deviceRecordFromSDK(d *sdk.NetworkDevice) *deviceRecord {
 			TagsMap:           d.TagsMap,
 		},
 		LoopbackAddressIpv4: d.LoopbackAddressIpv4,
+		LoopbackAddressIpv6: d.LoopbackAddressIpv6,
 		CustomVariables:     d.CustomVariables,
 		Revision:            strconv.FormatInt(d.Revision, 10),
 	}
@@ -3,12 +3,14 @@
 // fabric_switch_config engine produces, then registers the device-configuration
 // templates (from .j2 bodies) and one variables-carrying profile per switch,
 // idempotently. Template rendering happens server-side (Nunjucks); the optional
-// verification uses the engine's stateless render endpoint (there is no local
-// Jinja2 render, unlike the Python --verify-render parity gate).
+// verification uses the engine's stateless render endpoint. For iterating on
+// templates without a controller, the Nunjucks subset in jinja.go renders them
+// locally into a directory (render.go).
 package fabric_template_config
 
 import (
 	"net"
+	"net/netip"
 
 	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
 )
@@ -24,6 +26,7 @@ type deviceRecord struct {
 	fsc.Device
 	Asn                 *int64
 	LoopbackAddressIpv4 *string
+	LoopbackAddressIpv6 *string
 	CustomVariables     map[string]interface{}
 	Revision            string
 }
@@ -51,6 +54,33 @@ func loopbackOf(dev *fsc.Device, state *fsc.DesiredState, records map[int64]*dev
 	return ""
 }
 
+// loopbackIpv6Of returns a device's loopback /128, like loopbackOf.
+func loopbackIpv6Of(dev *fsc.Device, state *fsc.DesiredState, records map[int64]*deviceRecord) string {
+	if d, ok := state.ByDevice[dev.Id]; ok && d.LoopbackIpv6 != nil && *d.LoopbackIpv6 != "" {
+		return *d.LoopbackIpv6
+	}
+	if rec, ok := records[dev.Id]; ok && rec.LoopbackAddressIpv6 != nil {
+		return *rec.LoopbackAddressIpv6
+	}
+	return ""
+}
+
+// routerAddressOf returns the address a device is peered and identified by: its
+// IPv4 loopback, or its IPv6 one on an IPv6-only underlay.
+func routerAddressOf(dev *fsc.Device, state *fsc.DesiredState, records map[int64]*deviceRecord) string {
+	if lb := loopbackOf(dev, state, records); lb != "" {
+		return lb
+	}
+	return loopbackIpv6Of(dev, state, records)
+}
+
+// compareIP orders addresses numerically, IPv4 before IPv6.
+func compareIP(a, b string) int {
+	addrA, _ := netip.ParseAddr(a)
+	addrB, _ := netip.ParseAddr(b)
+	return addrA.Unmap().Compare(addrB.Unmap())
+}
+
 func ipToUint(s string) uint32 {
 	ip := net.ParseIP(s).To4()
 	if ip == nil {
@@ -63,9 +93,12 @@ func uintToIP(v uint32) string {
 	return net.IPv4(byte(v>>24), byte(v>>16), byte(v>>8), byte(v)).String()
 }
 
-// subnetHostAddrs returns the two addresses of a /31 (interfaceA = the gateway =
-// the smaller/even address = [0]; interfaceB = [1]).
+// subnetHostAddrs returns the two addresses of a /31 or /127 (interfaceA = the
+// gateway = the smaller/even address = [0]; interfaceB = [1]).
 func subnetHostAddrs(s *fsc.Subnet) (string, string) {
-	base := ipToUint(s.NetworkAddress)
-	return uintToIP(base), uintToIP(base + 1)
+	base, err := netip.ParseAddr(s.NetworkAddress)
+	if err != nil {
+		return "", ""
+	}
+	return base.String(), base.Next().String()
 }
@@ -2,6 +2,7 @@ package fabric_template_config
 
 import (
 	"fmt"
+	"net/netip"
 	"sort"
 	"strconv"
 	"strings"
@@ -9,8 +10,6 @@ import (
 	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
 )
 
-const tagSspGroup = "nvidia/ssp-group-id"
-
 func threeTier(groups map[string][]*fsc.Device) bool {
 	return len(groups["super_spine"]) > 0
 }
@@ -62,15 +61,20 @@ func leafInterfaces(dev *fsc.Device, state *fsc.DesiredState) []map[string]inter
 	return out
 }
 
-// computeFreeformVariables: every switch gets mode + hgx_prefix; an l3evpn leaf
-// also gets nve_source (its loopback). Returns nil error only via ConfigError.
+// computeFreeformVariables: every switch gets mode + hgx_prefix (+ loopback_ipv6
+// when it has an IPv6 loopback); an l3evpn leaf also gets nve_source (its
+// loopback, the IPv6 one on an IPv6-only underlay). Returns nil error only via
+// ConfigError.
 func computeFreeformVariables(groups map[string][]*fsc.Device, state *fsc.DesiredState, records map[int64]*deviceRecord, mode, hgx string) (map[int64]map[string]interface{}, error) {
 	variables := map[int64]map[string]interface{}{}
 	for _, position := range switchPositions {
 		for _, dev := range groups[position] {
 			vars := map[string]interface{}{"mode": mode, "hgx_prefix": hgx}
+			if loopback := loopbackIpv6Of(dev, state, records); loopback != "" {
+				vars["loopback_ipv6"] = loopback
+			}
 			if position == "leaf" && mode == "l3evpn" {
-				loopback := loopbackOf(dev, state, records)
+				loopback := routerAddressOf(dev, state, records)
 				if loopback == "" {
 					return nil, fmt.Errorf("%s has no loopback; the l3evpn NVE source is the leaf loopback", dev.Label())
 				}
@@ -83,27 +87,47 @@ func computeFreeformVariables(groups map[string][]*fsc.Device, state *fsc.Desire
 }
 
 // computeBgpVariables: one bgp_neighbors entry per fabric link on BOTH endpoints
-// (neighbor IP = far end's /31 address), plus the leaf's per-rail /26 aggregates.
+// (neighbor IP = far end's /31 address, or its /127 address on an IPv6-only
+// underlay; ipv6 = far end's /127 address when IPv6 is enabled), plus the leaf's
+// per-rail /26 (aggregates) and /122 (aggregates_ipv6) aggregates.
 func computeBgpVariables(groups map[string][]*fsc.Device, state *fsc.DesiredState, records map[int64]*deviceRecord, mode string) (map[int64]map[string]interface{}, error) {
 	isThreeTier := threeTier(groups)
 
 	neighbors := map[int64][]map[string]interface{}{}
 	for _, plan := range state.Links {
-		if plan.Subnet == nil {
-			return nil, fmt.Errorf("link plan has no /31 (is 'p2p' missing?); bgp needs the link subnets")
+		if plan.Subnet == nil && plan.SubnetIpv6 == nil {
+			return nil, fmt.Errorf("link plan has no /31 or /127 (is 'p2p' missing?); bgp needs the link subnets")
 		}
-		ipA, ipB := subnetHostAddrs(plan.Subnet)
-		neighbors[plan.DeviceA.Id] = append(neighbors[plan.DeviceA.Id], map[string]interface{}{
-			"ip": ipB, "host": hostOf(plan.DeviceB, state, records), "port": plan.PortB, "role": plan.DeviceB.Position,
-		})
-		neighbors[plan.DeviceB.Id] = append(neighbors[plan.DeviceB.Id], map[string]interface{}{
-			"ip": ipA, "host": hostOf(plan.DeviceA, state, records), "port": plan.PortA, "role": plan.DeviceA.Position,
-		})
+		neighborA := map[string]interface{}{"host": hostOf(plan.DeviceB, state, records), "port": plan.PortB, "role": plan.DeviceB.Position}
+		neighborB := map[string]interface{}{"host": hostOf(plan.DeviceA, state, records), "port": plan.PortA, "role": plan.DeviceA.Position}
+		if plan.SubnetIpv6 != nil {
+			ipA, ipB := subnetHostAddrs(plan.SubnetIpv6)
+			neighborA["ip"], neighborA["ipv6"] = ipB, ipB
+			neighborB["ip"], neighborB["ipv6"] = ipA, ipA
+		}
+		if plan.Subnet != nil {
+			ipA, ipB := subnetHostAddrs(plan.Subnet)
+			neighborA["ip"], neighborB["ip"] = ipB, ipA
+		}
+		neighbors[plan.DeviceA.Id] = append(neighbors[plan.DeviceA.Id], neighborA)
+		neighbors[plan.DeviceB.Id] = append(neighbors[plan.DeviceB.Id], neighborB)
 	}
 
-	// /26 aggregate = the leaf's host-downlink /31 run with the host-walk octet cleared.
+	// /26 aggregate = the leaf's host-downlink /31 run with the host-walk octet
+	// cleared; the /127s sit at the same offsets, so the IPv6 run is a /122.
 	aggregateBases := map[int64]map[uint32]bool{}
+	aggregateBasesIpv6 := map[int64]map[netip.Addr]bool{}
 	for _, hp := range state.HostLinks {
+		if hp.SubnetIpv6 != nil {
+			if addr, err := netip.ParseAddr(hp.SubnetIpv6.NetworkAddress); err == nil {
+				base := addr.As16()
+				base[15] = 0
+				if aggregateBasesIpv6[hp.Leaf.Id] == nil {
+					aggregateBasesIpv6[hp.Leaf.Id] = map[netip.Addr]bool{}
+				}
+				aggregateBasesIpv6[hp.Leaf.Id][netip.AddrFrom16(base)] = true
+			}
+		}
 		if hp.Subnet == nil {
 			continue
 		}
@@ -119,7 +143,7 @@ func computeBgpVariables(groups map[string][]*fsc.Device, state *fsc.DesiredStat
 		for _, dev := range groups[position] {
 			devNeighbors := append([]map[string]interface{}{}, neighbors[dev.Id]...)
 			sort.SliceStable(devNeighbors, func(i, j int) bool {
-				return ipToUint(devNeighbors[i]["ip"].(string)) < ipToUint(devNeighbors[j]["ip"].(string))
+				return compareIP(devNeighbors[i]["ip"].(string), devNeighbors[j]["ip"].(string)) < 0
 			})
 			var bases []uint32
 			for b := range aggregateBases[dev.Id] {
@@ -136,6 +160,18 @@ func computeBgpVariables(groups map[string][]*fsc.Device, state *fsc.DesiredStat
 				"aggregates":    aggregates,
 				"bgp_neighbors": devNeighbors,
 			}
+			if len(aggregateBasesIpv6[dev.Id]) > 0 {
+				var basesIpv6 []netip.Addr
+				for b := range aggregateBasesIpv6[dev.Id] {
+					basesIpv6 = append(basesIpv6, b)
+				}
+				sort.Slice(basesIpv6, func(i, j int) bool { return basesIpv6[i].Less(basesIpv6[j]) })
+				aggregatesIpv6 := make([]string, 0, len(basesIpv6))
+				for _, b := range basesIpv6 {
+					aggregatesIpv6 = append(aggregatesIpv6, b.String()+"/122")
+				}
+				variables[dev.Id]["aggregates_ipv6"] = aggregatesIpv6
+			}
 		}
 	}
 	return variables, nil
@@ -143,14 +179,16 @@ func computeBgpVariables(groups map[string][]*fsc.Device, state *fsc.DesiredStat
 
 // evpnRouteReflectors selects the EVPN overlay route reflectors (PDF 7.3.2):
 // 2-tier: the 2 lowest-router-id spines; 3-tier single group: that group's 2
-// lowest; 3-tier multiple groups: the lowest of each group. Router-id = loopback.
-func evpnRouteReflectors(groups map[string][]*fsc.Device, state *fsc.DesiredState, records map[int64]*deviceRecord) ([]*fsc.Device, error) {
-	rid := func(dev *fsc.Device) (uint32, error) {
-		lb := loopbackOf(dev, state, records)
+// lowest; 3-tier multiple groups: the lowest of each group. Router-id = loopback
+// (the IPv6 one on an IPv6-only underlay). groupTag is the superspine group tag
+// key of the plan.
+func evpnRouteReflectors(groups map[string][]*fsc.Device, state *fsc.DesiredState, records map[int64]*deviceRecord, groupTag string) ([]*fsc.Device, error) {
+	rid := func(dev *fsc.Device) (string, error) {
+		lb := routerAddressOf(dev, state, records)
 		if lb == "" {
-			return 0, fmt.Errorf("%s has no loopback; overlay RR selection needs router-ids", dev.Label())
+			return "", fmt.Errorf("%s has no loopback; overlay RR selection needs router-ids", dev.Label())
 		}
-		return ipToUint(lb), nil
+		return lb, nil
 	}
 	sortByRid := func(devs []*fsc.Device) ([]*fsc.Device, error) {
 		out := append([]*fsc.Device{}, devs...)
@@ -164,7 +202,7 @@ func evpnRouteReflectors(groups map[string][]*fsc.Device, state *fsc.DesiredStat
 			if err2 != nil {
 				sortErr = err2
 			}
-			return a < b
+			return compareIP(a, b) < 0
 		})
 		return out, sortErr
 	}
@@ -184,9 +222,9 @@ func evpnRouteReflectors(groups map[string][]*fsc.Device, state *fsc.DesiredStat
 	perGroup := map[int][]*fsc.Device{}
 	var groupOrder []int
 	for _, dev := range ssps {
-		g, ok := numericTag(dev, tagSspGroup)
+		g, ok := numericTag(dev, groupTag)
 		if !ok {
-			return nil, fmt.Errorf("%s missing %s tag", dev.Label(), tagSspGroup)
+			return nil, fmt.Errorf("%s missing %s tag", dev.Label(), groupTag)
 		}
 		if _, seen := perGroup[g]; !seen {
 			groupOrder = append(groupOrder, g)
@@ -217,9 +255,10 @@ func evpnRouteReflectors(groups map[string][]*fsc.Device, state *fsc.DesiredStat
 
 // computeOverlayVariables: loopback-to-loopback overlay mesh. Leaves peer with
 // the RR loopbacks; RRs peer with every leaf loopback; everything else gets none.
-func computeOverlayVariables(groups map[string][]*fsc.Device, state *fsc.DesiredState, records map[int64]*deviceRecord, mode string) (map[int64]map[string]interface{}, error) {
+// Each neighbor also carries its IPv6 loopback (ipv6) when it has one.
+func computeOverlayVariables(groups map[string][]*fsc.Device, state *fsc.DesiredState, records map[int64]*deviceRecord, mode, groupTag string) (map[int64]map[string]interface{}, error) {
 	isThreeTier := threeTier(groups)
-	rrs, err := evpnRouteReflectors(groups, state, records)
+	rrs, err := evpnRouteReflectors(groups, state, records, groupTag)
 	if err != nil {
 		return nil, err
 	}
@@ -229,15 +268,19 @@ func computeOverlayVariables(groups map[string][]*fsc.Device, state *fsc.Desired
 	}
 
 	neighbor := func(dev *fsc.Device) (map[string]interface{}, error) {
-		lb := loopbackOf(dev, state, records)
+		lb := routerAddressOf(dev, state, records)
 		if lb == "" {
 			return nil, fmt.Errorf("%s has no loopback; overlay neighbors are loopback peerings", dev.Label())
 		}
-		return map[string]interface{}{"ip": lb, "host": hostOf(dev, state, records)}, nil
+		n := map[string]interface{}{"ip": lb, "host": hostOf(dev, state, records)}
+		if lbIpv6 := loopbackIpv6Of(dev, state, records); lbIpv6 != "" {
+			n["ipv6"] = lbIpv6
+		}
+		return n, nil
 	}
 	byIP := func(entries []map[string]interface{}) []map[string]interface{} {
 		sort.SliceStable(entries, func(i, j int) bool {
-			return ipToUint(entries[i]["ip"].(string)) < ipToUint(entries[j]["ip"].(string))
+			return compareIP(entries[i]["ip"].(string), entries[j]["ip"].(string)) < 0
 		})
 		return entries
 	}
@@ -333,6 +376,9 @@ func renderContextBgp(dev *fsc.Device, vars map[string]interface{}, records map[
 		if rec.LoopbackAddressIpv4 != nil {
 			ctx["loopbackAddress"] = *rec.LoopbackAddressIpv4
 		}
+		if rec.LoopbackAddressIpv6 != nil {
+			ctx["loopbackAddressIpv6"] = *rec.LoopbackAddressIpv6
+		}
 	}
 	for k, v := range vars {
 		ctx[k] = v
@@ -6,9 +6,19 @@ import (
 	fsc "github.com/metalsoft-io/metalcloud-cli/internal/fabric_switch_config"
 )
 
+// defaultGroupTag is the superspine group tag key of a plan without a tags
+// section.
+var defaultGroupTag = (&fsc.Config{}).TagKeys().Group
+
 // fixturePlan builds the 3-tier reference fixture plan (matching the
 // fabric_switch_config gold fixture) and the device records.
 func fixturePlan(t *testing.T) (map[string][]*fsc.Device, *fsc.DesiredState, map[int64]*deviceRecord) {
+	t.Helper()
+	return fixturePlanFamily(t, fsc.AddressFamilyIpv4)
+}
+
+// fixturePlanFamily is fixturePlan with the given underlay address family.
+func fixturePlanFamily(t *testing.T, addressFamily string) (map[string][]*fsc.Device, *fsc.DesiredState, map[int64]*deviceRecord) {
 	t.Helper()
 	dev := func(id int64, position, mgmt string, tags map[string]string) *fsc.Device {
 		return &fsc.Device{Id: id, Position: position, ManagementAddress: mgmt,
@@ -26,6 +36,7 @@ func fixturePlan(t *testing.T) (map[string][]*fsc.Device, *fsc.DesiredState, map
 	}
 	yaml := `
 ordering: managementAddress
+addressFamily: ` + addressFamily + `
 loopback:
   subnet: 10.253.128.0/18
 topology:
@@ -93,7 +104,7 @@ func TestComputeBgpVariables(t *testing.T) {
 
 func TestEvpnRouteReflectors(t *testing.T) {
 	groups, state, records := fixturePlan(t)
-	rrs, err := evpnRouteReflectors(groups, state, records)
+	rrs, err := evpnRouteReflectors(groups, state, records, defaultGroupTag)
 	if err != nil {
 		t.Fatalf("evpnRouteReflectors: %v", err)
 	}
@@ -110,7 +121,7 @@ func TestEvpnRouteReflectors(t *testing.T) {
 
 func TestComputeOverlayVariables(t *testing.T) {
 	groups, state, records := fixturePlan(t)
-	overlay, err := computeOverlayVariables(groups, state, records, "l3evpn")
+	overlay, err := computeOverlayVariables(groups, state, records, "l3evpn", defaultGroupTag)
 	if err != nil {
 		t.Fatalf("computeOverlayVariables: %v", err)
 	}
@@ -136,7 +147,7 @@ func TestComputeOverlayVariables(t *testing.T) {
 
 func TestOverlayAndPfcApplies(t *testing.T) {
 	groups, state, records := fixturePlan(t)
-	overlay, _ := computeOverlayVariables(groups, state, records, "l3evpn")
+	overlay, _ := computeOverlayVariables(groups, state, records, "l3evpn", defaultGroupTag)
 	// leaf applies; non-RR spine/ssp does not.
 	leaf := groups["leaf"][0]
 	if !overlayApplies(leaf, overlay[leaf.Id]) {
@@ -191,3 +202,74 @@ func TestHgxPrefix(t *testing.T) {
 		t.Errorf("override hgxPrefix = %q, want 10.0.0.0/8", got)
 	}
 }
+
+func TestDualStackVariables(t *testing.T) {
+	groups, state, records := fixturePlanFamily(t, fsc.AddressFamilyDualStack)
+	vars, err := computeBgpVariables(groups, state, records, "l3evpn")
+	if err != nil {
+		t.Fatalf("computeBgpVariables: %v", err)
+	}
+	// The IPv4 neighbor set is unchanged; the /127 far end rides along.
+	found := false
+	for _, n := range vars[4]["bgp_neighbors"].([]map[string]interface{}) {
+		if n["ip"] == "10.254.0.1" && n["ipv6"] == "fd00:254::1" && n["port"] == "swp1s0" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("dev4 missing neighbor {10.254.0.1, fd00:254::1, swp1s0}: %v", vars[4]["bgp_neighbors"])
+	}
+	agg := vars[4]["aggregates_ipv6"].([]string)
+	if len(agg) != 2 || agg[0] != "fd00:172:16::2:0/122" || agg[1] != "fd00:172:16::a:0/122" {
+		t.Errorf("dev4 aggregates_ipv6 = %v", agg)
+	}
+
+	freeform, err := computeFreeformVariables(groups, state, records, "l3evpn", "172.0.0.0/8")
+	if err != nil {
+		t.Fatalf("computeFreeformVariables: %v", err)
+	}
+	if freeform[4]["nve_source"] != "10.253.128.1" || freeform[4]["loopback_ipv6"] != "fd00:253::1" {
+		t.Errorf("dev4 freeform vars = %v", freeform[4])
+	}
+
+	overlay, err := computeOverlayVariables(groups, state, records, "l3evpn", defaultGroupTag)
+	if err != nil {
+		t.Fatalf("computeOverlayVariables: %v", err)
+	}
+	leafNbrs := overlay[4]["overlay_neighbors"].([]map[string]interface{})
+	if len(leafNbrs) != 2 || leafNbrs[0]["ip"] != "10.253.128.6" || leafNbrs[0]["ipv6"] != "fd00:253::6" {
+		t.Errorf("dev4 overlay_neighbors = %v", leafNbrs)
+	}
+}
+
+func TestIpv6OnlyVariables(t *testing.T) {
+	groups, state, records := fixturePlanFamily(t, fsc.AddressFamilyIpv6)
+	vars, err := computeBgpVariables(groups, state, records, "l3evpn")
+	if err != nil {
+		t.Fatalf("computeBgpVariables: %v", err)
+	}
+	neighbors := vars[4]["bgp_neighbors"].([]map[string]interface{})
+	if len(neighbors) != 20 || neighbors[0]["ip"] != "fd00:254::1" {
+		t.Errorf("dev4 bgp_neighbors = %v, want 20 starting at fd00:254::1", neighbors)
+	}
+	if agg := vars[4]["aggregates"].([]string); len(agg) != 0 {
+		t.Errorf("IPv6-only aggregates = %v, want none", agg)
+	}
+
+	freeform, err := computeFreeformVariables(groups, state, records, "l3evpn", "172.0.0.0/8")
+	if err != nil {
+		t.Fatalf("computeFreeformVariables: %v", err)
+	}
+	if freeform[4]["nve_source"] != "fd00:253::1" {
+		t.Errorf("dev4 nve_source = %v, want fd00:253::1", freeform[4]["nve_source"])
+	}
+
+	// Router-ids fall back to the IPv6 loopbacks: dev6=::6, dev7=::8.
+	rrs, err := evpnRouteReflectors(groups, state, records, defaultGroupTag)
+	if err != nil {
+		t.Fatalf("evpnRouteReflectors: %v", err)
+	}
+	if len(rrs) != 2 || rrs[0].Id != 6 || rrs[1].Id != 7 {
+		t.Errorf("route reflectors = %v, want [6 7]", rrs)
+	}
+}
@@ -0,0 +1,338 @@
+package firmware_catalog
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A firmware bundle is a gzip compressed tar archive holding, in order, the
+// manifest, its Ed25519 signature and the binary files listed in the manifest.
+const (
+	bundleFormatVersion = 1
+	bundleManifestName  = "manifest.json"
+	bundleSignatureName = "manifest.json.sig"
+	bundleBinariesDir   = "binaries"
+
+	maxBundleManifestSize = 64 << 20
+)
+
+// errBundleSignature is wrapped by the errors of bundles whose manifest is not
+// signed by the expected key.
+var errBundleSignature = errors.New("bundle signature verification failed")
+
+// bundleFile is a binary file in a bundle. Path is the slash separated path
+// of the file in the archive, below bundleBinariesDir.
+type bundleFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+
+	// source is the local file the entry is written from on export.
+	source string
+}
+
+// bundleIndex is the part of a manifest needed to read a bundle.
+type bundleIndex struct {
+	FormatVersion int          `json:"formatVersion"`
+	Files         []bundleFile `json:"files"`
+}
+
+// newBundleFile describes the local file at source, stored in the bundle as
+// bundleBinariesDir/relativePath.
+func newBundleFile(source string, relativePath string) (bundleFile, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return bundleFile{}, err
+	}
+
+	digest, err := fileChecksum(source, ChecksumSHA256)
+	if err != nil {
+		return bundleFile{}, err
+	}
+
+	return bundleFile{
+		Path:   bundleBinariesDir + "/" + cleanRelativePath(relativePath),
+		Size:   info.Size(),
+		SHA256: digest,
+		source: source,
+	}, nil
+}
+
+// loadBundleSigningKey reads a PEM encoded PKCS #8 Ed25519 private key, as
+// generated by "openssl genpkey -algorithm ed25519".
+func loadBundleSigningKey(keyPath string) (ed25519.PrivateKey, error) {
+	block, err := readPemFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key '%s': %v", keyPath, err)
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key '%s' is not an Ed25519 key", keyPath)
+	}
+
+	return privateKey, nil
+}
+
+// loadBundleVerifyKey reads a PEM encoded PKIX Ed25519 public key, as
+// extracted by "openssl pkey -pubout".
+func loadBundleVerifyKey(keyPath string) (ed25519.PublicKey, error) {
+	block, err := readPemFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key '%s': %v", keyPath, err)
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key '%s' is not an Ed25519 key", keyPath)
+	}
+
+	return publicKey, nil
+}
+
+func readPemFile(keyPath string) (*pem.Block, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in '%s'", keyPath)
+	}
+
+	return block, nil
+}
+
+// writeBundle writes the manifest, signed with key, and the files to a bundle
+// at archivePath. The archive is written next to archivePath and renamed into
+// place once complete.
+func writeBundle(archivePath string, manifest []byte, key ed25519.PrivateKey, files []bundleFile) error {
+	tempPath := archivePath + ".tmp"
+	archive, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %v", err)
+	}
+	defer os.Remove(tempPath)
+	defer archive.Close()
+
+	gzWriter := gzip.NewWriter(archive)
+	tarWriter := tar.NewWriter(gzWriter)
+	modTime := time.Now()
+
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, manifest))
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{bundleManifestName, manifest},
+		{bundleSignatureName, []byte(signature + "\n")},
+	} {
+		err = tarWriter.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.data)), ModTime: modTime})
+		if err != nil {
+			return err
+		}
+		if _, err := tarWriter.Write(entry.data); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range files {
+		err = writeBundleFile(tarWriter, file, modTime)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to bundle: %v", file.source, err)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	if err := gzWriter.Close(); err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, archivePath)
+}
+
+func writeBundleFile(tarWriter *tar.Writer, file bundleFile, modTime time.Time) error {
+	source, err := os.Open(file.source)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	err = tarWriter.WriteHeader(&tar.Header{Name: file.Path, Mode: 0644, Size: file.Size, ModTime: modTime})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tarWriter, source)
+	return err
+}
+
+// readBundle verifies the bundle at archivePath and extracts its files below
+// destDir, returning the manifest. The manifest signature is checked against
+// publicKey before anything is extracted, unless publicKey is nil. Every file
+// must match the size and SHA-256 digest listed in the manifest; entries not
+// listed are rejected.
+func readBundle(archivePath string, destDir string, publicKey ed25519.PublicKey) ([]byte, error) {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	gzReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a firmware bundle: %v", archivePath, err)
+	}
+	defer gzReader.Close()
+
+	tarReader := tar.NewReader(gzReader)
+
+	header, err := tarReader.Next()
+	if err != nil || header.Name != bundleManifestName {
+		return nil, fmt.Errorf("%s is not a firmware bundle: the manifest must be its first entry", archivePath)
+	}
+	manifest, err := io.ReadAll(io.LimitReader(tarReader, maxBundleManifestSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle manifest: %v", err)
+	}
+
+	var signature []byte
+	header, err = tarReader.Next()
+	if err == nil && header.Name == bundleSignatureName {
+		encoded, err := io.ReadAll(io.LimitReader(tarReader, 1024))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle signature: %v", err)
+		}
+		signature, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed signature: %v", errBundleSignature, err)
+		}
+		header, err = tarReader.Next()
+	}
+
+	if publicKey != nil {
+		if signature == nil {
+			return nil, fmt.Errorf("%w: the bundle is not signed", errBundleSignature)
+		}
+		if !ed25519.Verify(publicKey, manifest, signature) {
+			return nil, fmt.Errorf("%w: the manifest was not signed by the given key or was modified", errBundleSignature)
+		}
+	}
+
+	var index bundleIndex
+	if err := json.Unmarshal(manifest, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle manifest: %v", err)
+	}
+	if index.FormatVersion != bundleFormatVersion {
+		return nil, fmt.Errorf("unsupported bundle format version %d", index.FormatVersion)
+	}
+
+	expected := map[string]bundleFile{}
+	for _, file := range index.Files {
+		if !strings.HasPrefix(file.Path, bundleBinariesDir+"/") || cleanRelativePath(file.Path) != file.Path {
+			return nil, fmt.Errorf("invalid file path %q in bundle manifest", file.Path)
+		}
+		expected[file.Path] = file
+	}
+
+	for ; err == nil; header, err = tarReader.Next() {
+		file, ok := expected[header.Name]
+		if !ok || header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("unexpected entry %q in bundle", header.Name)
+		}
+
+		err = extractBundleFile(tarReader, file, filepath.Join(destDir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			return nil, err
+		}
+		delete(expected, header.Name)
+	}
+	if err != io.EOF {
+		return nil, fmt.Errorf("failed to read bundle: %v", err)
+	}
+
+	if len(expected) > 0 {
+		missing := []string{}
+		for path := range expected {
+			missing = append(missing, path)
+		}
+		return nil, fmt.Errorf("%d files listed in the bundle manifest are missing: %s", len(missing), strings.Join(missing, ", "))
+	}
+
+	return manifest, nil
+}
+
+// extractBundleFile writes one file of a bundle to targetPath and checks it
+// against its manifest entry. A mismatching file is deleted.
+func extractBundleFile(reader io.Reader, file bundleFile, targetPath string) error {
+	err := os.MkdirAll(filepath.Dir(targetPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for binary: %v", err)
+	}
+
+	target, err := os.Create(targetPath)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer target.Close()
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(target, hash), io.LimitReader(reader, file.Size+1))
+	if err == nil {
+		err = target.Close()
+	}
+	if err != nil {
+		os.Remove(targetPath)
+		return fmt.Errorf("failed to extract %s: %v", file.Path, err)
+	}
+
+	if digest := hex.EncodeToString(hash.Sum(nil)); written != file.Size || digest != file.SHA256 {
+		os.Remove(targetPath)
+		return fmt.Errorf("%w: %s in the bundle is %d bytes with sha256 %s, expected %d bytes with sha256 %s",
+			errChecksumMismatch, file.Path, written, digest, file.Size, file.SHA256)
+	}
+
+	return nil
+}
+
+// marshalBundleManifest encodes a manifest in the form it is signed in.
+func marshalBundleManifest(manifest any) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,212 @@
+package firmware_catalog
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeBundleKeys writes a new Ed25519 key pair as PEM files and returns their
+// paths.
+func writeBundleKeys(t *testing.T) (string, string) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() returned error: %v", err)
+	}
+
+	privateDer, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() returned error: %v", err)
+	}
+	publicDer, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() returned error: %v", err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "bundle.key")
+	publicPath := filepath.Join(dir, "bundle.pub")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDer}), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer}), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	return privatePath, publicPath
+}
+
+// buildTestBundle writes a bundle holding the test binary as two files and
+// returns its path. mutate may alter the manifest, not the files archived.
+func buildTestBundle(t *testing.T, privatePath string, mutate func(*bundleIndex)) string {
+	t.Helper()
+	key, err := loadBundleSigningKey(privatePath)
+	if err != nil {
+		t.Fatalf("loadBundleSigningKey() returned error: %v", err)
+	}
+
+	source := writeBinary(t)
+	index := bundleIndex{FormatVersion: bundleFormatVersion}
+	for _, relativePath := range []string{"FOLDER1/binary.bin", ".extracted/BIOS.bin"} {
+		file, err := newBundleFile(source, relativePath)
+		if err != nil {
+			t.Fatalf("newBundleFile() returned error: %v", err)
+		}
+		index.Files = append(index.Files, file)
+	}
+	files := append([]bundleFile{}, index.Files...)
+	if mutate != nil {
+		mutate(&index)
+	}
+
+	manifest, err := marshalBundleManifest(index)
+	if err != nil {
+		t.Fatalf("marshalBundleManifest() returned error: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if err := writeBundle(archivePath, manifest, key, files); err != nil {
+		t.Fatalf("writeBundle() returned error: %v", err)
+	}
+	return archivePath
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	privatePath, publicPath := writeBundleKeys(t)
+	archivePath := buildTestBundle(t, privatePath, nil)
+
+	publicKey, err := loadBundleVerifyKey(publicPath)
+	if err != nil {
+		t.Fatalf("loadBundleVerifyKey() returned error: %v", err)
+	}
+
+	dest := t.TempDir()
+	manifest, err := readBundle(archivePath, dest, publicKey)
+	if err != nil {
+		t.Fatalf("readBundle() returned error: %v", err)
+	}
+	if !strings.Contains(string(manifest), `"binaries/FOLDER1/binary.bin"`) {
+		t.Errorf("manifest = %s", manifest)
+	}
+
+	for _, relativePath := range []string{"binaries/FOLDER1/binary.bin", "binaries/.extracted/BIOS.bin"} {
+		content, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(relativePath)))
+		if err != nil || string(content) != testBinaryContent {
+			t.Errorf("extracted %s = %q, %v", relativePath, content, err)
+		}
+	}
+
+	// The signature is optional when no key is given
+	if _, err := readBundle(archivePath, t.TempDir(), nil); err != nil {
+		t.Errorf("readBundle() without key returned error: %v", err)
+	}
+}
+
+func TestBundleSignature(t *testing.T) {
+	privatePath, _ := writeBundleKeys(t)
+	_, otherPublicPath := writeBundleKeys(t)
+	archivePath := buildTestBundle(t, privatePath, nil)
+
+	otherKey, err := loadBundleVerifyKey(otherPublicPath)
+	if err != nil {
+		t.Fatalf("loadBundleVerifyKey() returned error: %v", err)
+	}
+
+	dest := t.TempDir()
+	_, err = readBundle(archivePath, dest, otherKey)
+	if !errors.Is(err, errBundleSignature) {
+		t.Errorf("readBundle() with another key = %v, want a signature error", err)
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Errorf("files extracted from a bundle with a bad signature: %v", entries)
+	}
+
+	if _, err := loadBundleSigningKey(otherPublicPath); err == nil {
+		t.Error("Expected an error loading a public key as signing key")
+	}
+}
+
+func TestBundleCorruptFile(t *testing.T) {
+	privatePath, publicPath := writeBundleKeys(t)
+	publicKey, err := loadBundleVerifyKey(publicPath)
+	if err != nil {
+		t.Fatalf("loadBundleVerifyKey() returned error: %v", err)
+	}
+
+	// The manifest is signed but lists a digest the file does not match
+	archivePath := buildTestBundle(t, privatePath, func(index *bundleIndex) {
+		index.Files[1].SHA256 = otherSHA256
+	})
+	dest := t.TempDir()
+	_, err = readBundle(archivePath, dest, publicKey)
+	if !errors.Is(err, errChecksumMismatch) {
+		t.Errorf("readBundle() with a corrupt file = %v, want checksum mismatch", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "binaries", ".extracted", "BIOS.bin")); !os.IsNotExist(err) {
+		t.Errorf("corrupt file kept: %v", err)
+	}
+
+	// Files listed in the manifest must be in the archive
+	archivePath = buildTestBundle(t, privatePath, func(index *bundleIndex) {
+		index.Files = append(index.Files, bundleFile{Path: "binaries/missing.bin", Size: 1, SHA256: otherSHA256})
+	})
+	_, err = readBundle(archivePath, t.TempDir(), publicKey)
+	if err == nil || !strings.Contains(err.Error(), "binaries/missing.bin") {
+		t.Errorf("readBundle() with a missing file = %v", err)
+	}
+}
+
+func TestBundleRejectsUnsafePaths(t *testing.T) {
+	privatePath, _ := writeBundleKeys(t)
+	archivePath := buildTestBundle(t, privatePath, func(index *bundleIndex) {
+		index.Files[0].Path = "binaries/../../evil.bin"
+	})
+
+	dest := t.TempDir()
+	_, err := readBundle(archivePath, filepath.Join(dest, "extract"), nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid file path") {
+		t.Errorf("readBundle() with an unsafe path = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "evil.bin")); !os.IsNotExist(err) {
+		t.Errorf("file written outside the extract directory: %v", err)
+	}
+}
+
+func TestCheckBundledBinary(t *testing.T) {
+	files := []bundleFile{
+		{Path: bundleBinariesDir + "/FOLDER1/binary.bin"},
+		{Path: bundleBinariesDir + "/.extracted/BIOS.bin"},
+	}
+
+	for _, tc := range []struct {
+		vendor     string
+		externalId string
+		valid      bool
+	}{
+		{VendorDell, "FOLDER1/binary.bin", true},
+		{VendorSupermicro, "BIOS.bin", true},
+		{VendorDell, "BIOS.bin", false},
+		{VendorDell, "../../etc/passwd", false},
+		{VendorDell, "FOLDER1/../FOLDER1/binary.bin", false},
+		{VendorSupermicro, "../FOLDER1/binary.bin", false},
+	} {
+		err := checkBundledBinary(tc.vendor, tc.externalId, files)
+		if (err == nil) != tc.valid {
+			t.Errorf("checkBundledBinary(%s, %s) = %v, want valid %v", tc.vendor, tc.externalId, err, tc.valid)
+		}
+	}
+}
+
+func TestReadBundleNotABundle(t *testing.T) {
+	archivePath := writeBinary(t)
+	if _, err := readBundle(archivePath, t.TempDir(), nil); err == nil || !strings.Contains(err.Error(), "not a firmware bundle") {
+		t.Errorf("readBundle() of a plain file = %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package firmware_catalog
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	ChecksumMD5    = "md5"
+	ChecksumSHA256 = "sha256"
+)
+
+// errChecksumMismatch is wrapped by the errors of binaries whose content does
+// not match the checksum the vendor published.
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// parseChecksum normalizes a vendor published checksum to its algorithm and
+// lowercase hex digest. The algorithm is taken from an "algorithm:" prefix
+// when present, else from the digest length. ok is false when value is empty
+// or not a recognizable MD5 or SHA-256 digest.
+func parseChecksum(value string) (algorithm string, digest string, ok bool) {
+	value = strings.TrimSpace(value)
+	if prefix, rest, found := strings.Cut(value, ":"); found {
+		value = strings.TrimSpace(rest)
+		switch strings.ToLower(strings.ReplaceAll(prefix, "-", "")) {
+		case ChecksumMD5:
+			algorithm = ChecksumMD5
+		case ChecksumSHA256:
+			algorithm = ChecksumSHA256
+		default:
+			return "", "", false
+		}
+	}
+
+	digest = strings.ToLower(value)
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", "", false
+	}
+	switch {
+	case len(digest) == 2*md5.Size && (algorithm == "" || algorithm == ChecksumMD5):
+		return ChecksumMD5, digest, true
+	case len(digest) == 2*sha256.Size && (algorithm == "" || algorithm == ChecksumSHA256):
+		return ChecksumSHA256, digest, true
+	}
+	return "", "", false
+}
+
+// setBinaryChecksum records a vendor published checksum in the binary's vendor
+// information, where it is stored with the binary and read back by verify.
+// Values that are not a recognizable digest are ignored.
+func setBinaryChecksum(vendor map[string]any, value string) {
+	if algorithm, digest, ok := parseChecksum(value); ok {
+		vendor[algorithm] = digest
+	}
+}
+
+// vendorChecksum returns the checksum recorded in a binary's vendor
+// information, preferring SHA-256, or empty strings when the vendor published
+// none.
+func vendorChecksum(vendor map[string]any) (algorithm string, digest string) {
+	for _, key := range []string{ChecksumSHA256, ChecksumMD5} {
+		if value, ok := vendor[key].(string); ok {
+			if algorithm, digest, ok := parseChecksum(key + ":" + value); ok {
+				return algorithm, digest
+			}
+		}
+	}
+	return "", ""
+}
+
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case ChecksumMD5:
+		return md5.New(), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+}
+
+// readerChecksum returns the hex digest of everything read from r.
+func readerChecksum(r io.Reader, algorithm string) (string, error) {
+	h, err := newChecksumHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fileChecksum returns the hex digest of the file at filePath.
+func fileChecksum(filePath string, algorithm string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return readerChecksum(file, algorithm)
+}
+
+// verifyFileChecksum checks the file at filePath against the expected digest.
+// A mismatch is reported with an error wrapping errChecksumMismatch.
+func verifyFileChecksum(filePath string, algorithm string, expected string) error {
+	actual, err := fileChecksum(filePath, algorithm)
+	if err != nil {
+		return fmt.Errorf("failed to compute %s checksum of %s: %v", algorithm, filePath, err)
+	}
+	if actual != expected {
+		return fmt.Errorf("%w: %s of %s is %s, expected %s", errChecksumMismatch, algorithm, filePath, actual, expected)
+	}
+	return nil
+}
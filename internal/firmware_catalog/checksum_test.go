@@ -0,0 +1,127 @@
+package firmware_catalog
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testBinaryContent = "firmware image"
+	// otherSHA256 is a well-formed digest of some other content
+	otherSHA256 = "5c5b1b7bbb4de3ad2a1b0f0c3e3a1e2e0d1f3c9a7b7e5d8c6f4a2b0e9d8c7b6a"
+)
+
+func TestParseChecksum(t *testing.T) {
+	md5Digest := "0123456789abcdef0123456789ABCDEF"
+	sha256Digest := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		value     string
+		algorithm string
+		digest    string
+		ok        bool
+	}{
+		{md5Digest, ChecksumMD5, "0123456789abcdef0123456789abcdef", true},
+		{sha256Digest, ChecksumSHA256, sha256Digest, true},
+		{"SHA256:" + sha256Digest, ChecksumSHA256, sha256Digest, true},
+		{"sha-256: " + sha256Digest, ChecksumSHA256, sha256Digest, true},
+		{"MD5:" + md5Digest, ChecksumMD5, "0123456789abcdef0123456789abcdef", true},
+		{"md5:" + sha256Digest, "", "", false},
+		{"SHA1:0123456789abcdef0123456789abcdef01234567", "", "", false},
+		{"not-a-digest", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tc := range tests {
+		algorithm, digest, ok := parseChecksum(tc.value)
+		if algorithm != tc.algorithm || digest != tc.digest || ok != tc.ok {
+			t.Errorf("parseChecksum(%q) = %q, %q, %v; want %q, %q, %v", tc.value, algorithm, digest, ok, tc.algorithm, tc.digest, tc.ok)
+		}
+	}
+}
+
+func TestVendorChecksum(t *testing.T) {
+	vendor := map[string]any{}
+	setBinaryChecksum(vendor, "bogus")
+	if algorithm, _ := vendorChecksum(vendor); algorithm != "" {
+		t.Errorf("expected no checksum, got %s", algorithm)
+	}
+
+	setBinaryChecksum(vendor, "0123456789abcdef0123456789abcdef")
+	if algorithm, _ := vendorChecksum(vendor); algorithm != ChecksumMD5 {
+		t.Errorf("expected md5, got %q", algorithm)
+	}
+
+	// SHA-256 is preferred when both are published
+	setBinaryChecksum(vendor, "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	if algorithm, _ := vendorChecksum(vendor); algorithm != ChecksumSHA256 {
+		t.Errorf("expected sha256, got %q", algorithm)
+	}
+}
+
+func TestVerifyFileChecksum(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "binary.bin")
+	if err := os.WriteFile(filePath, []byte(testBinaryContent), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	md5Digest, err := fileChecksum(filePath, ChecksumMD5)
+	if err != nil {
+		t.Fatalf("fileChecksum() returned error: %v", err)
+	}
+	if err := verifyFileChecksum(filePath, ChecksumMD5, md5Digest); err != nil {
+		t.Errorf("Expected matching checksum, got: %v", err)
+	}
+
+	err = verifyFileChecksum(filePath, ChecksumSHA256, otherSHA256)
+	if !errors.Is(err, errChecksumMismatch) {
+		t.Errorf("Expected checksum mismatch, got: %v", err)
+	}
+}
+
+func TestCheckRepositoryFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/good.bin", "/corrupt.bin":
+			w.Write([]byte(testBinaryContent))
+		case "/broken.bin":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	filePath := filepath.Join(t.TempDir(), "binary.bin")
+	if err := os.WriteFile(filePath, []byte(testBinaryContent), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	digest, err := fileChecksum(filePath, ChecksumSHA256)
+	if err != nil {
+		t.Fatalf("fileChecksum() returned error: %v", err)
+	}
+
+	tests := map[string]struct {
+		path   string
+		digest string
+		status string
+	}{
+		"Ok":       {"/good.bin", digest, BinaryVerifyOk},
+		"Mismatch": {"/corrupt.bin", otherSHA256, BinaryVerifyMismatch},
+		"Missing":  {"/gone.bin", digest, BinaryVerifyMissing},
+		"Error":    {"/broken.bin", digest, BinaryVerifyError},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			status, detail := checkRepositoryFile(server.Client(), server.URL+tc.path, ChecksumSHA256, tc.digest)
+			if status != tc.status {
+				t.Errorf("status = %s (%s), want %s", status, detail, tc.status)
+			}
+		})
+	}
+}
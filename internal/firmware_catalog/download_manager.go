@@ -0,0 +1,381 @@
+package firmware_catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+)
+
+// Outcomes of a binary download.
+const (
+	downloadFetched  = "downloaded"
+	downloadResumed  = "resumed"
+	downloadCached   = "cached"
+	downloadNotFound = "not found"
+	downloadFailed   = "failed"
+)
+
+const (
+	DefaultDownloadParallelism = 4
+	DefaultDownloadTimeout     = time.Hour
+	defaultDownloadRetries     = 3
+)
+
+// downloadRetryDelay is multiplied by the attempt number between retries.
+var downloadRetryDelay = 2 * time.Second
+
+// errRangeIgnored is returned when a server answers a resumed request with a
+// part that does not start where the partial download ends.
+var errRangeIgnored = errors.New("server did not resume at the requested offset")
+
+// downloadManager downloads vendor binaries. Binaries with a published checksum
+// are downloaded into a content-addressed cache, keyed by checksum and external
+// ID, so later runs reuse them. Interrupted downloads are kept next to their
+// target with a .part suffix and resumed with an HTTP range request. Its
+// methods are safe for concurrent use.
+type downloadManager struct {
+	client   *http.Client
+	cacheDir string
+	retries  int
+
+	mu    sync.Mutex
+	stats downloadStats
+}
+
+// downloadStats counts the outcomes of the downloads of a manager.
+type downloadStats struct {
+	Downloaded int
+	Resumed    int
+	Cached     int
+	NotFound   int
+	Failed     int
+	Bytes      int64
+	Started    time.Time
+}
+
+// newDownloadManager returns a manager using transport, or when nil the
+// default one, which honours the HTTP(S)_PROXY environment variables. timeout
+// bounds each request, including reading the body; an interrupted body is
+// resumed on the next attempt.
+func newDownloadManager(cacheDir string, timeout time.Duration, retries int, transport http.RoundTripper) *downloadManager {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &downloadManager{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+		cacheDir: cacheDir,
+		retries:  retries,
+		stats:    downloadStats{Started: time.Now()},
+	}
+}
+
+// newDownloadTransport returns the transport of the vendor downloads, going
+// through the proxy of the API connection and trusting its CA bundle. The
+// client certificate and the insecure setting of the API connection are only
+// meant for the API endpoint and are not used.
+func newDownloadTransport(ctx context.Context) (http.RoundTripper, error) {
+	options := api.GetTransportOptions(ctx)
+	transport, err := api.NewTransport(api.TransportOptions{
+		CABundle:      options.CABundle,
+		MinTLSVersion: options.MinTLSVersion,
+		Proxy:         options.Proxy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transport, nil
+}
+
+// defaultDownloadCachePath returns the download cache directory under the
+// user's cache directory, or an empty string (no cache) when there is none.
+func defaultDownloadCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		logger.Get().Debug().Msgf("No user cache directory, firmware downloads are not cached: %v", err)
+		return ""
+	}
+	return filepath.Join(dir, "metalcloud-cli", "firmware")
+}
+
+// cachePath returns where a binary is kept in the cache, or an empty string
+// when it cannot be cached: there is no cache or no published checksum.
+func (dm *downloadManager) cachePath(externalId string, algorithm string, digest string) string {
+	if dm.cacheDir == "" || algorithm == "" {
+		return ""
+	}
+	return filepath.Join(dm.cacheDir, algorithm, digest, filepath.FromSlash(path.Clean("/"+externalId)))
+}
+
+// fetch downloads sourceUrl to destPath, or places it there from the cache,
+// and returns the outcome. A download that does not match the checksum is
+// deleted and reported with an error wrapping errChecksumMismatch. A missing
+// binary is reported with the downloadNotFound outcome and no error.
+func (dm *downloadManager) fetch(externalId string, sourceUrl string, destPath string, algorithm string, digest string) (string, error) {
+	outcome, err := dm.fetchFile(externalId, sourceUrl, destPath, algorithm, digest)
+	if err != nil {
+		outcome = downloadFailed
+	}
+
+	dm.mu.Lock()
+	switch outcome {
+	case downloadFetched:
+		dm.stats.Downloaded++
+	case downloadResumed:
+		dm.stats.Resumed++
+	case downloadCached:
+		dm.stats.Cached++
+	case downloadNotFound:
+		dm.stats.NotFound++
+	case downloadFailed:
+		dm.stats.Failed++
+	}
+	dm.mu.Unlock()
+
+	return outcome, err
+}
+
+func (dm *downloadManager) fetchFile(externalId string, sourceUrl string, destPath string, algorithm string, digest string) (string, error) {
+	cachePath := dm.cachePath(externalId, algorithm, digest)
+	if cachePath == "" {
+		return dm.download(sourceUrl, destPath, algorithm, digest)
+	}
+
+	if _, err := os.Stat(cachePath); err == nil {
+		// Reuse the cached file only if it is still intact
+		if err := verifyFileChecksum(cachePath, algorithm, digest); err == nil {
+			logger.Get().Debug().Msgf("Binary %s found in the download cache at %s", externalId, cachePath)
+			return downloadCached, placeFile(cachePath, destPath)
+		}
+		logger.Get().Warn().Msgf("Cached binary %s is corrupt - downloading it again", cachePath)
+		os.Remove(cachePath)
+	}
+
+	outcome, err := dm.download(sourceUrl, cachePath, algorithm, digest)
+	if err != nil || outcome == downloadNotFound {
+		return outcome, err
+	}
+
+	return outcome, placeFile(cachePath, destPath)
+}
+
+// download fetches sourceUrl to targetPath through targetPath.part. Only
+// downloads with a published checksum are resumed from an existing .part file;
+// others restart, since a stale partial download could not be detected.
+func (dm *downloadManager) download(sourceUrl string, targetPath string, algorithm string, digest string) (string, error) {
+	err := os.MkdirAll(filepath.Dir(targetPath), 0755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create directory for binary: %v", err)
+	}
+
+	partPath := targetPath + ".part"
+	if algorithm == "" {
+		os.Remove(partPath)
+	}
+
+	outcome, err := dm.downloadVerified(sourceUrl, partPath, algorithm, digest)
+	if errors.Is(err, errChecksumMismatch) && outcome == downloadResumed {
+		// The partial download may have come from a different file; start over once
+		logger.Get().Warn().Msgf("Resumed download of %s does not match its checksum - downloading it again", sourceUrl)
+		outcome, err = dm.downloadVerified(sourceUrl, partPath, algorithm, digest)
+	}
+	if err != nil || outcome == downloadNotFound {
+		return outcome, err
+	}
+
+	err = os.Rename(partPath, targetPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to save binary to file: %v", err)
+	}
+
+	return outcome, nil
+}
+
+// downloadVerified completes partPath, retrying failed attempts, and checks it
+// against the checksum. A mismatching file is deleted.
+func (dm *downloadManager) downloadVerified(sourceUrl string, partPath string, algorithm string, digest string) (string, error) {
+	resumed := false
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * downloadRetryDelay)
+		}
+
+		partResumed, retry, err := dm.downloadPart(sourceUrl, partPath)
+		resumed = resumed || partResumed
+		if err == nil {
+			break
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return downloadNotFound, nil
+		}
+		if !retry || attempt >= dm.retries {
+			return "", fmt.Errorf("error downloading binary from vendor URL: %w", err)
+		}
+		logger.Get().Warn().Msgf("Download of %s failed (attempt %d of %d), retrying: %v", sourceUrl, attempt+1, dm.retries+1, err)
+	}
+
+	outcome := downloadFetched
+	if resumed {
+		outcome = downloadResumed
+	}
+
+	if algorithm != "" {
+		if err := verifyFileChecksum(partPath, algorithm, digest); err != nil {
+			os.Remove(partPath)
+			return outcome, err
+		}
+	}
+
+	return outcome, nil
+}
+
+// downloadPart makes one attempt to download sourceUrl into partPath,
+// continuing from the end of partPath when it exists. It reports whether the
+// server resumed the download and whether a failure is worth retrying. A
+// missing binary is reported with an error wrapping os.ErrNotExist.
+func (dm *downloadManager) downloadPart(sourceUrl string, partPath string) (resumed bool, retry bool, err error) {
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, sourceUrl, nil)
+	if err != nil {
+		return false, false, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := dm.client.Do(req)
+	if err != nil {
+		return false, true, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		flags |= os.O_TRUNC
+	case resp.StatusCode == http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			os.Remove(partPath)
+			return false, true, errRangeIgnored
+		}
+		logger.Get().Debug().Msgf("Resuming download of %s at byte %d", sourceUrl, offset)
+		flags |= os.O_APPEND
+		resumed = true
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		os.Remove(partPath)
+		return false, true, fmt.Errorf("cannot resume at byte %d, restarting", offset)
+	case resp.StatusCode == http.StatusNotFound:
+		return false, false, fmt.Errorf("%s: %w", sourceUrl, os.ErrNotExist)
+	default:
+		retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return false, retry, fmt.Errorf("received non-OK response when downloading binary: %d", resp.StatusCode)
+	}
+
+	outFile, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer outFile.Close()
+
+	written, err := io.Copy(outFile, resp.Body)
+
+	dm.mu.Lock()
+	dm.stats.Bytes += written
+	dm.mu.Unlock()
+
+	if err != nil {
+		return resumed, true, fmt.Errorf("failed to save binary to file: %v", err)
+	}
+
+	return resumed, false, outFile.Close()
+}
+
+// contentRangeStart returns the first byte of a "bytes first-last/size"
+// Content-Range header.
+func contentRangeStart(contentRange string) (int64, bool) {
+	rangeSpec, found := strings.CutPrefix(contentRange, "bytes ")
+	if !found {
+		return 0, false
+	}
+	first, _, found := strings.Cut(rangeSpec, "-")
+	if !found {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	return start, err == nil
+}
+
+// placeFile makes the file at srcPath available at destPath, hard linking it
+// when possible and copying it otherwise.
+func placeFile(srcPath string, destPath string) error {
+	if srcPath == destPath {
+		return nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(destPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for binary: %v", err)
+	}
+
+	os.Remove(destPath)
+	if err := os.Link(srcPath, destPath); err == nil {
+		return nil
+	}
+
+	srcFile, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	destFile, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer destFile.Close()
+
+	if _, err := io.Copy(destFile, srcFile); err != nil {
+		return fmt.Errorf("failed to copy binary from the download cache: %v", err)
+	}
+
+	return destFile.Close()
+}
+
+// summary returns a one line summary of the downloads so far.
+func (dm *downloadManager) summary() string {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	s := dm.stats
+	return fmt.Sprintf("Downloaded %d binaries (%d resumed, %.1f MiB in %s), %d from cache, %d not found, %d failed",
+		s.Downloaded+s.Resumed, s.Resumed, float64(s.Bytes)/(1<<20), time.Since(s.Started).Round(time.Second),
+		s.Cached, s.NotFound, s.Failed)
+}
+
+// printDownloadProgress reports the progress of the downloads on stderr, in
+// text mode only: every tenth of them and when all are done.
+func printDownloadProgress(done, total int) {
+	if !formatter.IsTextFormat() || (done != total && done%max(total/10, 1) != 0) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Downloading binaries: %d/%d\n", done, total)
+}
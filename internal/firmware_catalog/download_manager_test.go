@@ -0,0 +1,180 @@
+package firmware_catalog
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// rangeServer serves content at /binary.bin, honouring range requests, and
+// fails the first failures requests with a 503.
+func rangeServer(t *testing.T, content string, failures int32) (*httptest.Server, *atomic.Int32, *atomic.Value) {
+	var requests atomic.Int32
+	var lastRange atomic.Value
+	lastRange.Store("")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if r.URL.Path != "/binary.bin" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if n <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		lastRange.Store(r.Header.Get("Range"))
+		http.ServeContent(w, r, "binary.bin", time.Time{}, strings.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests, &lastRange
+}
+
+func contentSHA256(t *testing.T, content string) string {
+	t.Helper()
+	digest, err := readerChecksum(strings.NewReader(content), ChecksumSHA256)
+	if err != nil {
+		t.Fatalf("readerChecksum() returned error: %v", err)
+	}
+	return digest
+}
+
+func TestDownloadManagerCache(t *testing.T) {
+	server, requests, _ := rangeServer(t, testBinaryContent, 0)
+	digest := contentSHA256(t, testBinaryContent)
+	dir := t.TempDir()
+
+	dm := newDownloadManager(filepath.Join(dir, "cache"), time.Minute, 0, nil)
+	for i, want := range []string{downloadFetched, downloadCached} {
+		dest := filepath.Join(dir, "out", want, "binary.bin")
+		outcome, err := dm.fetch("dir/binary.bin", server.URL+"/binary.bin", dest, ChecksumSHA256, digest)
+		if err != nil || outcome != want {
+			t.Fatalf("fetch #%d = %s, %v; want %s", i+1, outcome, err, want)
+		}
+		if content, err := os.ReadFile(dest); err != nil || string(content) != testBinaryContent {
+			t.Errorf("fetch #%d wrote %q, %v", i+1, content, err)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("server got %d requests, want 1", requests.Load())
+	}
+
+	cached := filepath.Join(dir, "cache", ChecksumSHA256, digest, "dir", "binary.bin")
+	if _, err := os.Stat(cached); err != nil {
+		t.Errorf("binary not cached at %s: %v", cached, err)
+	}
+
+	// A corrupt cache entry is downloaded again
+	if err := os.WriteFile(cached, []byte("corrupt"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	outcome, err := dm.fetch("dir/binary.bin", server.URL+"/binary.bin", filepath.Join(dir, "out", "binary.bin"), ChecksumSHA256, digest)
+	if err != nil || outcome != downloadFetched {
+		t.Errorf("fetch with corrupt cache = %s, %v; want %s", outcome, err, downloadFetched)
+	}
+}
+
+func TestDownloadManagerResume(t *testing.T) {
+	server, _, lastRange := rangeServer(t, testBinaryContent, 0)
+	digest := contentSHA256(t, testBinaryContent)
+	dest := filepath.Join(t.TempDir(), "binary.bin")
+
+	if err := os.WriteFile(dest+".part", []byte(testBinaryContent[:5]), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	dm := newDownloadManager("", time.Minute, 0, nil)
+	outcome, err := dm.fetch("binary.bin", server.URL+"/binary.bin", dest, ChecksumSHA256, digest)
+	if err != nil || outcome != downloadResumed {
+		t.Fatalf("fetch = %s, %v; want %s", outcome, err, downloadResumed)
+	}
+	if got := lastRange.Load(); got != "bytes=5-" {
+		t.Errorf("Range = %q, want bytes=5-", got)
+	}
+	if content, err := os.ReadFile(dest); err != nil || string(content) != testBinaryContent {
+		t.Errorf("resumed download = %q, %v", content, err)
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Errorf("partial download left behind: %v", err)
+	}
+
+	// A stale partial download of another file is detected and replaced
+	if err := os.WriteFile(dest+".part", []byte("stale"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	outcome, err = dm.fetch("binary.bin", server.URL+"/binary.bin", dest, ChecksumSHA256, digest)
+	if err != nil || outcome != downloadFetched {
+		t.Errorf("fetch over stale part = %s, %v; want %s", outcome, err, downloadFetched)
+	}
+
+	// Without a checksum a partial download is not trusted
+	if err := os.WriteFile(dest+".part", []byte("stale"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	outcome, err = dm.fetch("binary.bin", server.URL+"/binary.bin", dest, "", "")
+	if err != nil || outcome != downloadFetched || lastRange.Load() != "" {
+		t.Errorf("fetch without checksum = %s, %v (Range %q); want %s", outcome, err, lastRange.Load(), downloadFetched)
+	}
+}
+
+func TestDownloadManagerFailures(t *testing.T) {
+	originalDelay := downloadRetryDelay
+	downloadRetryDelay = time.Millisecond
+	defer func() { downloadRetryDelay = originalDelay }()
+
+	server, _, _ := rangeServer(t, testBinaryContent, 2)
+	dir := t.TempDir()
+
+	dm := newDownloadManager("", time.Minute, 1, nil)
+	_, err := dm.fetch("binary.bin", server.URL+"/binary.bin", filepath.Join(dir, "a.bin"), "", "")
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("fetch with too few retries = %v, want a 503 error", err)
+	}
+
+	outcome, err := dm.fetch("binary.bin", server.URL+"/binary.bin", filepath.Join(dir, "b.bin"), "", "")
+	if err != nil || outcome != downloadFetched {
+		t.Errorf("fetch after retry = %s, %v; want %s", outcome, err, downloadFetched)
+	}
+
+	outcome, err = dm.fetch("gone.bin", server.URL+"/gone.bin", filepath.Join(dir, "c.bin"), "", "")
+	if err != nil || outcome != downloadNotFound {
+		t.Errorf("fetch of missing binary = %s, %v; want %s", outcome, err, downloadNotFound)
+	}
+
+	dest := filepath.Join(dir, "d.bin")
+	_, err = dm.fetch("binary.bin", server.URL+"/binary.bin", dest, ChecksumSHA256, otherSHA256)
+	if !errors.Is(err, errChecksumMismatch) {
+		t.Errorf("fetch of corrupt binary = %v, want checksum mismatch", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("corrupt download kept: %v", err)
+	}
+
+	if got := dm.stats; got.Downloaded != 1 || got.NotFound != 1 || got.Failed != 2 {
+		t.Errorf("stats = %+v", got)
+	}
+}
+
+func TestContentRangeStart(t *testing.T) {
+	tests := map[string]struct {
+		start int64
+		ok    bool
+	}{
+		"bytes 5-13/14": {5, true},
+		"bytes */14":    {0, false},
+		"items 5-13/14": {0, false},
+		"":              {0, false},
+	}
+	for header, want := range tests {
+		if start, ok := contentRangeStart(header); start != want.start || ok != want.ok {
+			t.Errorf("contentRangeStart(%q) = %d, %v; want %d, %v", header, start, ok, want.start, want.ok)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
@@ -109,6 +110,21 @@ type FirmwareCatalogCreateOptions struct {
 	UserPrivateKeyPath      string   `json:"user_private_key_path,omitempty"`
 	KnownHostsPath          string   `json:"known_hosts_path,omitempty"`
 	IgnoreHostKeyCheck      bool     `json:"ignore_host_key_check,omitempty"`
+	RepoType                string   `json:"repo_type,omitempty"`
+	RepoLocalPath           string   `json:"repo_local_path,omitempty"`
+	RepoUploadUrl           string   `json:"repo_upload_url,omitempty"`
+	RepoUploadUser          string   `json:"repo_upload_user,omitempty"`
+	RepoUploadPassword      string   `json:"repo_upload_password,omitempty"`
+	RepoS3Endpoint          string   `json:"repo_s3_endpoint,omitempty"`
+	RepoS3Bucket            string   `json:"repo_s3_bucket,omitempty"`
+	RepoS3Prefix            string   `json:"repo_s3_prefix,omitempty"`
+	RepoS3Region            string   `json:"repo_s3_region,omitempty"`
+	RepoS3AccessKey         string   `json:"repo_s3_access_key,omitempty"`
+	RepoS3SecretKey         string   `json:"repo_s3_secret_key,omitempty"`
+	DownloadParallelism     int      `json:"download_parallelism,omitempty"`
+	DownloadCachePath       string   `json:"download_cache_path,omitempty"`
+	NoDownloadCache         bool     `json:"no_download_cache,omitempty"`
+	DownloadTimeout         string   `json:"download_timeout,omitempty"`
 }
 
 func FirmwareCatalogCreate(ctx context.Context, firmwareCatalogOptions FirmwareCatalogCreateOptions) error {
@@ -118,6 +134,10 @@ func FirmwareCatalogCreate(ctx context.Context, firmwareCatalogOptions FirmwareC
 	if err != nil {
 		return err
 	}
+	vendorCatalog.DownloadTransport, err = newDownloadTransport(ctx)
+	if err != nil {
+		return err
+	}
 
 	logger.Get().Debug().Msgf("Processing firmware catalog")
 
@@ -127,6 +147,10 @@ func FirmwareCatalogCreate(ctx context.Context, firmwareCatalogOptions FirmwareC
 	}
 
 	if len(vendorCatalog.Binaries) == 0 {
+		if len(vendorCatalog.ChecksumFailures) > 0 {
+			return fmt.Errorf("no binaries left in the catalog: %d failed checksum verification: %s",
+				len(vendorCatalog.ChecksumFailures), strings.Join(vendorCatalog.ChecksumFailures, ", "))
+		}
 		logger.Get().Warn().Msg("No binaries found in the catalog")
 		return fmt.Errorf("no binaries found in the catalog")
 	}
@@ -243,3 +267,20 @@ func getFirmwareCatalogId(firmwareCatalogId string) (int64, error) {
 
 	return firmwareCatalogIdNumeric, nil
 }
+
+// listCatalogBinaries returns the binaries of a firmware catalog, by ID.
+func listCatalogBinaries(ctx context.Context, client *sdk.APIClient, firmwareCatalogId int64) ([]sdk.FirmwareBinary, error) {
+	binaries, _, err := utils.FetchAllPages(client.FirmwareBinaryAPI.GetFirmwareBinaries(ctx).SortBy([]string{"id:ASC"}))
+	if err != nil {
+		return nil, err
+	}
+
+	catalogBinaries := []sdk.FirmwareBinary{}
+	for _, binary := range binaries {
+		if int64(binary.CatalogId) == firmwareCatalogId {
+			catalogBinaries = append(catalogBinaries, binary)
+		}
+	}
+
+	return catalogBinaries, nil
+}
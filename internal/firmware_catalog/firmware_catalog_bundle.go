@@ -0,0 +1,320 @@
+package firmware_catalog
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/response_inspector"
+	sdk "github.com/metalsoft-io/metalcloud-sdk-go"
+)
+
+// firmwareBundleManifest describes the catalog packaged in a firmware bundle.
+type firmwareBundleManifest struct {
+	FormatVersion int                  `json:"formatVersion"`
+	CreatedAt     string               `json:"createdAt"`
+	Catalog       sdk.FirmwareCatalog  `json:"catalog"`
+	Binaries      []sdk.FirmwareBinary `json:"binaries"`
+	Files         []bundleFile         `json:"files"`
+}
+
+type FirmwareCatalogExportOptions struct {
+	SigningKeyPath          string `json:"signing_key_path"`
+	VendorLocalBinariesPath string `json:"vendor_local_binaries_path,omitempty"`
+	DownloadParallelism     int    `json:"download_parallelism,omitempty"`
+	DownloadCachePath       string `json:"download_cache_path,omitempty"`
+	NoDownloadCache         bool   `json:"no_download_cache,omitempty"`
+	DownloadTimeout         string `json:"download_timeout,omitempty"`
+}
+
+// FirmwareCatalogExportBundle packages a firmware catalog, its binaries and
+// their files into a bundle at archivePath, for import on a site without
+// access to the vendor or to the repository. The manifest is signed with the
+// Ed25519 key at options.SigningKeyPath.
+//
+// The binary files are taken from VendorLocalBinariesPath, laid out as for
+// catalog creation, or else downloaded from the repository the catalog was
+// uploaded to, falling back to the vendor download URL.
+func FirmwareCatalogExportBundle(ctx context.Context, firmwareCatalogId string, archivePath string, options FirmwareCatalogExportOptions) error {
+	logger.Get().Info().Msgf("Exporting firmware catalog '%s' to bundle %s", firmwareCatalogId, archivePath)
+
+	firmwareCatalogIdNumeric, err := getFirmwareCatalogId(firmwareCatalogId)
+	if err != nil {
+		return err
+	}
+
+	signingKey, err := loadBundleSigningKey(options.SigningKeyPath)
+	if err != nil {
+		return err
+	}
+
+	client := api.GetApiClient(ctx)
+
+	firmwareCatalog, httpRes, err := client.FirmwareCatalogAPI.GetFirmwareCatalog(ctx, firmwareCatalogIdNumeric).Execute()
+	if err := response_inspector.InspectResponse(httpRes, err); err != nil {
+		return err
+	}
+
+	binaries, err := listCatalogBinaries(ctx, client, firmwareCatalogIdNumeric)
+	if err != nil {
+		return err
+	}
+
+	catalogBinaries := []sdk.FirmwareBinary{}
+	for _, binary := range binaries {
+		if binary.ExternalId != nil && *binary.ExternalId != "" {
+			catalogBinaries = append(catalogBinaries, binary)
+		}
+	}
+	if len(catalogBinaries) == 0 {
+		return fmt.Errorf("firmware catalog '%s' has no binaries to export", firmwareCatalogId)
+	}
+
+	vendorCatalog, err := NewVendorCatalogFromCreateOptions(FirmwareCatalogCreateOptions{
+		Name:                    firmwareCatalog.Name,
+		Vendor:                  firmwareCatalog.Vendor,
+		UpdateType:              firmwareCatalog.UpdateType,
+		VendorLocalBinariesPath: options.VendorLocalBinariesPath,
+		DownloadBinaries:        options.VendorLocalBinariesPath == "",
+		DownloadParallelism:     options.DownloadParallelism,
+		DownloadCachePath:       options.DownloadCachePath,
+		NoDownloadCache:         options.NoDownloadCache,
+		DownloadTimeout:         options.DownloadTimeout,
+	})
+	if err != nil {
+		return err
+	}
+	vendorCatalog.DownloadTransport, err = newDownloadTransport(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer vendorCatalog.removeTempFiles()
+
+	localPaths, err := vendorCatalog.bundleBinaryFiles(catalogBinaries)
+	if err != nil {
+		return err
+	}
+
+	manifest := firmwareBundleManifest{
+		FormatVersion: bundleFormatVersion,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		Catalog:       *firmwareCatalog,
+		Binaries:      catalogBinaries,
+	}
+
+	files := []bundleFile{}
+	seen := map[string]bool{}
+	var totalSize int64
+	for i, binary := range catalogBinaries {
+		relativePath := filepath.ToSlash(localBinaryRelativePath(firmwareCatalog.Vendor, *binary.ExternalId))
+		if seen[relativePath] {
+			continue
+		}
+		seen[relativePath] = true
+
+		file, err := newBundleFile(localPaths[i], relativePath)
+		if err != nil {
+			return fmt.Errorf("failed to read binary %s: %v", *binary.ExternalId, err)
+		}
+		files = append(files, file)
+		totalSize += file.Size
+	}
+	manifest.Files = files
+
+	manifestData, err := marshalBundleManifest(manifest)
+	if err != nil {
+		return err
+	}
+
+	err = writeBundle(archivePath, manifestData, signingKey, files)
+	if err != nil {
+		return err
+	}
+
+	if formatter.IsTextFormat() {
+		fmt.Printf("Exported firmware catalog '%s' with %d binaries (%d files, %d bytes) to %s\n",
+			firmwareCatalogId, len(catalogBinaries), len(files), totalSize, archivePath)
+	}
+
+	return nil
+}
+
+// bundleBinaryFiles returns the local path of the file of each binary, taken
+// from VendorLocalBinariesPath and verified, or downloaded.
+func (vc *VendorCatalog) bundleBinaryFiles(binaries []sdk.FirmwareBinary) ([]string, error) {
+	localPaths := make([]string, len(binaries))
+
+	if !vc.DownloadBinaries {
+		for i := range binaries {
+			localPath, err := vc.localBinaryPath(&binaries[i])
+			if err != nil {
+				return nil, err
+			}
+
+			err = vc.verifyLocalBinary(&binaries[i], localPath)
+			if err != nil {
+				return nil, fmt.Errorf("binary %s: %v", *binaries[i].ExternalId, err)
+			}
+			localPaths[i] = localPath
+		}
+		return localPaths, nil
+	}
+
+	// Download the copies kept in the repository where there are some: they
+	// are what the catalog serves. Supermicro repository copies are extracted
+	// from the archive the vendor checksum covers, so they are not verified.
+	sources := make([]*sdk.FirmwareBinary, len(binaries))
+	for i, binary := range binaries {
+		source := binary
+		if binary.CacheDownloadUrl != nil && *binary.CacheDownloadUrl != "" {
+			source.VendorDownloadUrl = *binary.CacheDownloadUrl
+			if vc.CatalogInfo.Vendor == VendorSupermicro {
+				source.Vendor = nil
+			}
+		} else if vc.CatalogInfo.Vendor == VendorSupermicro {
+			return nil, fmt.Errorf("binary %s has no repository URL - Supermicro binaries can only be exported from the repository or from a local binaries path", *binary.ExternalId)
+		}
+		sources[i] = &source
+	}
+
+	vc.downloadBinaries(sources)
+
+	failures := []string{}
+	for i, source := range sources {
+		downloaded := vc.downloads[source]
+		switch {
+		case downloaded.err != nil:
+			logger.Get().Error().Msgf("Failed to download binary %s: %v", *source.ExternalId, downloaded.err)
+			failures = append(failures, *source.ExternalId)
+		case downloaded.localPath == "":
+			logger.Get().Error().Msgf("Binary %s not found at %s", *source.ExternalId, source.VendorDownloadUrl)
+			failures = append(failures, *source.ExternalId)
+		default:
+			localPaths[i] = downloaded.localPath
+		}
+	}
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("%d binaries could not be downloaded: %s", len(failures), strings.Join(failures, ", "))
+	}
+
+	return localPaths, nil
+}
+
+// FirmwareCatalogImportBundle recreates the firmware catalog packaged in the
+// bundle at archivePath. The bundle is verified against the Ed25519 public key
+// at publicKeyPath, unless skipSignatureCheck is set, and its files against
+// the digests in the manifest.
+//
+// The files are extracted to options.VendorLocalBinariesPath, or a temporary
+// directory, and processed as local binaries: with options.UploadBinaries they
+// are uploaded to the configured repository. The name, description and vendor
+// URL default to those of the exported catalog.
+func FirmwareCatalogImportBundle(ctx context.Context, archivePath string, options FirmwareCatalogCreateOptions, publicKeyPath string, skipSignatureCheck bool) error {
+	logger.Get().Info().Msgf("Importing firmware catalog bundle %s", archivePath)
+
+	var publicKey ed25519.PublicKey
+	if publicKeyPath != "" {
+		var err error
+		publicKey, err = loadBundleVerifyKey(publicKeyPath)
+		if err != nil {
+			return err
+		}
+	} else if !skipSignatureCheck {
+		return fmt.Errorf("a public key is required to verify the bundle signature")
+	} else {
+		logger.Get().Warn().Msgf("Importing bundle %s without verifying its signature", archivePath)
+	}
+
+	extractPath := options.VendorLocalBinariesPath
+	if extractPath == "" {
+		tempDir, err := os.MkdirTemp("", "firmware_bundle_*")
+		if err != nil {
+			return fmt.Errorf("failed to create temp directory: %v", err)
+		}
+		defer os.RemoveAll(tempDir)
+
+		extractPath = tempDir
+	}
+
+	manifestData, err := readBundle(archivePath, extractPath, publicKey)
+	if err != nil {
+		return err
+	}
+
+	var manifest firmwareBundleManifest
+	err = json.Unmarshal(manifestData, &manifest)
+	if err != nil {
+		return fmt.Errorf("failed to parse bundle manifest: %v", err)
+	}
+
+	catalog := manifest.Catalog
+	if options.Name == "" {
+		options.Name = catalog.Name
+	}
+	if options.Description == "" && catalog.Description != nil {
+		options.Description = *catalog.Description
+	}
+	if options.VendorUrl == "" && catalog.VendorUrl != nil {
+		options.VendorUrl = *catalog.VendorUrl
+	}
+	options.Vendor = catalog.Vendor
+	options.UpdateType = catalog.UpdateType
+	options.VendorLocalBinariesPath = filepath.Join(extractPath, bundleBinariesDir)
+	options.DownloadBinaries = false
+
+	vendorCatalog, err := NewVendorCatalogFromCreateOptions(options)
+	if err != nil {
+		return err
+	}
+
+	vendorCatalog.CatalogInfo.VendorId = catalog.VendorId
+	vendorCatalog.CatalogInfo.VendorConfiguration = catalog.VendorConfiguration
+	vendorCatalog.CatalogInfo.VendorServerTypesSupported = catalog.VendorServerTypesSupported
+	vendorCatalog.CatalogInfo.VendorReleaseTimestamp = catalog.VendorReleaseTimestamp
+
+	for i := range manifest.Binaries {
+		binary := manifest.Binaries[i]
+		if binary.ExternalId == nil {
+			return fmt.Errorf("bundle binary %s has no external ID", binary.Name)
+		}
+		if err := checkBundledBinary(catalog.Vendor, *binary.ExternalId, manifest.Files); err != nil {
+			return err
+		}
+		binary.CacheDownloadUrl = nil
+		vendorCatalog.Binaries = append(vendorCatalog.Binaries, &binary)
+	}
+
+	logger.Get().Debug().Msgf("Creating MetalSoft firmware catalog from bundle created at %s", manifest.CreatedAt)
+
+	err = vendorCatalog.CreateMetalsoftCatalog(ctx)
+	if err != nil {
+		return err
+	}
+
+	return FirmwareCatalogGet(ctx, fmt.Sprintf("%d", int(vendorCatalog.CatalogInfo.Id)))
+}
+
+// checkBundledBinary verifies that the file of the binary with the given
+// external ID is one of the files of the bundle, whose checksums readBundle
+// verified, so an external ID cannot point the upload outside the extracted
+// binaries.
+func checkBundledBinary(vendor string, externalId string, files []bundleFile) error {
+	relativePath := filepath.ToSlash(localBinaryRelativePath(vendor, externalId))
+	if id := filepath.ToSlash(externalId); id == cleanRelativePath(id) {
+		for _, file := range files {
+			if file.Path == bundleBinariesDir+"/"+relativePath {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("the bundle has no file for binary %s", externalId)
+}
@@ -0,0 +1,561 @@
+package firmware_catalog
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/response_inspector"
+	sdk "github.com/metalsoft-io/metalcloud-sdk-go"
+)
+
+// systemsFilterKey is the catalog vendor configuration key that records the
+// vendor systems the catalog was read for.
+const systemsFilterKey = "vendorSystemsFilter"
+
+// Actions taken on a binary by FirmwareCatalogRefresh.
+const (
+	BinaryRefreshAdded     = "added"
+	BinaryRefreshChanged   = "changed"
+	BinaryRefreshWithdrawn = "withdrawn"
+)
+
+// FirmwareBinaryRefresh describes one binary that differs between a catalog
+// and the current vendor catalog.
+type FirmwareBinaryRefresh struct {
+	Action     string `json:"action"`
+	ExternalId string `json:"externalId"`
+	Name       string `json:"name"`
+	OldVersion string `json:"oldVersion,omitempty"`
+	NewVersion string `json:"newVersion,omitempty"`
+	OldId      int64  `json:"oldId,omitempty"`
+	NewId      int64  `json:"newId,omitempty"`
+	Status     string `json:"status"`
+}
+
+var firmwareBinaryRefreshPrintConfig = formatter.PrintConfig{
+	FieldsConfig: map[string]formatter.RecordFieldConfig{
+		"Action": {
+			Order: 1,
+		},
+		"ExternalId": {
+			Title:    "External ID",
+			MaxWidth: 40,
+			Order:    2,
+		},
+		"Name": {
+			MaxWidth: 30,
+			Order:    3,
+		},
+		"OldVersion": {
+			Title: "Old Version",
+			Order: 4,
+		},
+		"NewVersion": {
+			Title: "New Version",
+			Order: 5,
+		},
+		"OldId": {
+			Title: "Old ID",
+			Order: 6,
+		},
+		"NewId": {
+			Title: "New ID",
+			Order: 7,
+		},
+		"Status": {
+			Order: 8,
+		},
+	},
+}
+
+// catalogBinaryDiff is the difference between the binaries of a catalog and
+// those of the vendor catalog it was created from.
+type catalogBinaryDiff struct {
+	Added     []*sdk.FirmwareBinary
+	Changed   []catalogBinaryChange
+	Withdrawn []sdk.FirmwareBinary
+	Unchanged int
+	// Kept counts the binaries missing from the vendor catalog that are not
+	// withdrawn: outside the systems refreshed, or failing their checksum.
+	Kept int
+}
+
+type catalogBinaryChange struct {
+	Old sdk.FirmwareBinary
+	New *sdk.FirmwareBinary
+}
+
+// diffCatalogBinaries matches the existing binaries of a catalog with the
+// binaries read from the vendor catalog by external ID. A binary is changed
+// when its version, download URL or published checksum differs. Existing
+// binaries without an external ID are not managed by refresh and are ignored.
+//
+// An existing binary missing from the vendor catalog is only withdrawn when it
+// supports one of the systems in systemsFilter, the filter the vendor catalog
+// was read with (any system when empty), and is not in checksumFailures,
+// matched by external or package ID: the vendor catalog leaves those out.
+func diffCatalogBinaries(existing []sdk.FirmwareBinary, vendor []*sdk.FirmwareBinary, systemsFilter []string, checksumFailures []string) catalogBinaryDiff {
+	diff := catalogBinaryDiff{}
+
+	existingByExternalId := map[string]sdk.FirmwareBinary{}
+	for _, binary := range existing {
+		if binary.ExternalId == nil || *binary.ExternalId == "" {
+			continue
+		}
+		existingByExternalId[*binary.ExternalId] = binary
+	}
+
+	seen := map[string]bool{}
+	for _, binary := range vendor {
+		if binary.ExternalId == nil || seen[*binary.ExternalId] {
+			continue
+		}
+		seen[*binary.ExternalId] = true
+
+		old, ok := existingByExternalId[*binary.ExternalId]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, binary)
+		case binaryChanged(old, *binary):
+			diff.Changed = append(diff.Changed, catalogBinaryChange{Old: old, New: binary})
+		default:
+			diff.Unchanged++
+		}
+	}
+
+	for externalId, binary := range existingByExternalId {
+		switch {
+		case seen[externalId]:
+		case !supportsAnySystem(binary, systemsFilter):
+			logger.Get().Debug().Msgf("Keeping binary %s: it supports none of the systems refreshed", externalId)
+			diff.Kept++
+		case slices.Contains(checksumFailures, externalId) || slices.Contains(checksumFailures, stringValue(binary.PackageId)):
+			logger.Get().Debug().Msgf("Keeping binary %s: its vendor file failed checksum verification", externalId)
+			diff.Kept++
+		default:
+			diff.Withdrawn = append(diff.Withdrawn, binary)
+		}
+	}
+	sort.Slice(diff.Withdrawn, func(i, j int) bool {
+		return *diff.Withdrawn[i].ExternalId < *diff.Withdrawn[j].ExternalId
+	})
+
+	return diff
+}
+
+// supportsAnySystem reports whether a binary supports one of the systems, by
+// the IDs of its supported systems and devices or, for Dell, the system model
+// names. Every binary matches an empty list.
+func supportsAnySystem(binary sdk.FirmwareBinary, systems []string) bool {
+	if len(systems) == 0 {
+		return true
+	}
+
+	for _, entries := range [][]map[string]interface{}{binary.VendorSupportedSystems, binary.VendorSupportedDevices} {
+		for _, entry := range entries {
+			id, _ := entry["id"].(string)
+			brandName, _ := entry["brandName"].(string)
+			modelName, _ := entry["modelName"].(string)
+			for _, name := range []string{id, modelName, brandName + " " + modelName} {
+				if strings.TrimSpace(name) != "" && slices.Contains(systems, name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func binaryChanged(old sdk.FirmwareBinary, new sdk.FirmwareBinary) bool {
+	if stringValue(old.PackageVersion) != stringValue(new.PackageVersion) {
+		return true
+	}
+	if old.VendorDownloadUrl != new.VendorDownloadUrl {
+		return true
+	}
+
+	oldAlgorithm, oldDigest := vendorChecksum(old.Vendor)
+	newAlgorithm, newDigest := vendorChecksum(new.Vendor)
+	return oldAlgorithm != newAlgorithm || oldDigest != newDigest
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// FirmwareCatalogRefresh re-reads the vendor catalog a firmware catalog was
+// created from and applies only the differences: new binaries are added,
+// binaries whose version, download URL or checksum changed are replaced and
+// binaries the vendor withdrew are deleted. Only binaries supporting one of the
+// systems refreshed are withdrawn, and never those whose vendor file failed
+// checksum verification. Only added and changed binaries are downloaded and
+// uploaded. Files of withdrawn binaries are left in the repository.
+//
+// The vendor, update type and vendor URL default to those of the catalog. When
+// no server type or vendor system filter is given, the catalog is refreshed
+// for the systems it already covers. With dryRun the differences are reported
+// without changing anything.
+func FirmwareCatalogRefresh(ctx context.Context, firmwareCatalogId string, options FirmwareCatalogCreateOptions, dryRun bool) error {
+	logger.Get().Info().Msgf("Refreshing firmware catalog '%s'", firmwareCatalogId)
+
+	firmwareCatalogIdNumeric, err := getFirmwareCatalogId(firmwareCatalogId)
+	if err != nil {
+		return err
+	}
+
+	client := api.GetApiClient(ctx)
+
+	firmwareCatalog, httpRes, err := client.FirmwareCatalogAPI.GetFirmwareCatalog(ctx, firmwareCatalogIdNumeric).Execute()
+	if err := response_inspector.InspectResponse(httpRes, err); err != nil {
+		return err
+	}
+
+	existing, err := listCatalogBinaries(ctx, client, firmwareCatalogIdNumeric)
+	if err != nil {
+		return err
+	}
+
+	options.Name = firmwareCatalog.Name
+	options.Vendor = firmwareCatalog.Vendor
+	options.UpdateType = firmwareCatalog.UpdateType
+	if options.VendorUrl == "" && firmwareCatalog.VendorUrl != nil {
+		options.VendorUrl = *firmwareCatalog.VendorUrl
+	}
+	if len(options.ServerTypesFilter) == 0 && len(options.VendorSystemsFilter) == 0 {
+		options.VendorSystemsFilter = catalogSystemsFilter(firmwareCatalog, existing)
+		logger.Get().Debug().Msgf("Refreshing for the systems already covered by the catalog: %v", options.VendorSystemsFilter)
+	}
+
+	vendorCatalog, err := NewVendorCatalogFromCreateOptions(options)
+	if err != nil {
+		return err
+	}
+	vendorCatalog.CatalogInfo.Id = firmwareCatalog.Id
+	vendorCatalog.DownloadTransport, err = newDownloadTransport(ctx)
+	if err != nil {
+		return err
+	}
+
+	logger.Get().Debug().Msgf("Processing firmware catalog")
+
+	err = vendorCatalog.ProcessVendorCatalog(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(vendorCatalog.Binaries) == 0 {
+		return fmt.Errorf("no binaries found in the vendor catalog - refusing to withdraw all binaries of firmware catalog '%s'", firmwareCatalogId)
+	}
+
+	diff := diffCatalogBinaries(existing, vendorCatalog.Binaries, vendorCatalog.refreshSystemsFilter(), vendorCatalog.ChecksumFailures)
+
+	var results []FirmwareBinaryRefresh
+	if dryRun {
+		results = refreshResults(diff, "planned")
+	} else {
+		results, err = vendorCatalog.applyCatalogDiff(ctx, diff)
+		if err != nil {
+			return err
+		}
+
+		err = vendorCatalog.updateMetalsoftCatalog(ctx, *firmwareCatalog)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := formatter.PrintResult(results, &firmwareBinaryRefreshPrintConfig); err != nil {
+		return err
+	}
+
+	if formatter.IsTextFormat() {
+		verb := "Refreshed"
+		if dryRun {
+			verb = "Dry run for"
+		}
+		fmt.Printf("%s firmware catalog '%s': %d added, %d changed, %d withdrawn, %d unchanged, %d kept\n",
+			verb, firmwareCatalogId, len(diff.Added), len(diff.Changed), len(diff.Withdrawn), diff.Unchanged, diff.Kept)
+	}
+
+	if len(vendorCatalog.ChecksumFailures) > 0 {
+		return fmt.Errorf("%d binaries failed checksum verification and were not refreshed: %s",
+			len(vendorCatalog.ChecksumFailures), strings.Join(vendorCatalog.ChecksumFailures, ", "))
+	}
+
+	return nil
+}
+
+// catalogSystemsFilter returns the vendor systems a catalog was created for:
+// those recorded in its vendor configuration, or for catalogs created before
+// they were recorded, the ones its binaries support. HPE catalogs are filtered
+// by component UUIDs, which are recorded as the supported devices of their
+// binaries; the other vendors by system model.
+func catalogSystemsFilter(firmwareCatalog *sdk.FirmwareCatalog, binaries []sdk.FirmwareBinary) []string {
+	if systems := recordedSystemsFilter(firmwareCatalog); len(systems) > 0 {
+		return systems
+	}
+
+	if firmwareCatalog.Vendor != VendorHp {
+		if len(firmwareCatalog.VendorServerTypesSupported) > 0 {
+			return firmwareCatalog.VendorServerTypesSupported
+		}
+		return supportedSystemNames(binaries)
+	}
+
+	targets := []string{}
+	for _, binary := range binaries {
+		for _, device := range binary.VendorSupportedDevices {
+			if id, ok := device["id"].(string); ok && id != "" && !slices.Contains(targets, id) {
+				targets = append(targets, id)
+			}
+		}
+	}
+	return targets
+}
+
+// recordedSystemsFilter returns the vendor systems recorded in the vendor
+// configuration of the catalog by recordSystemsFilter.
+func recordedSystemsFilter(firmwareCatalog *sdk.FirmwareCatalog) []string {
+	systems := []string{}
+	switch recorded := firmwareCatalog.VendorConfiguration[systemsFilterKey].(type) {
+	case []string:
+		systems = append(systems, recorded...)
+	case []interface{}:
+		for _, system := range recorded {
+			if name, ok := system.(string); ok && name != "" {
+				systems = append(systems, name)
+			}
+		}
+	}
+	return systems
+}
+
+// supportedSystemNames lists the systems the binaries support, named as the
+// vendor catalog filter takes them: by brand and model for Dell, by machine
+// type for Lenovo. A Dell binary lists every model it fits, not only those the
+// catalog was read for, so the systems all the binaries support are returned
+// when there are any.
+func supportedSystemNames(binaries []sdk.FirmwareBinary) []string {
+	names := []string{}
+	counts := map[string]int{}
+	withSystems := 0
+	for _, binary := range binaries {
+		seen := map[string]bool{}
+		for _, system := range binary.VendorSupportedSystems {
+			id, _ := system["id"].(string)
+			brandName, _ := system["brandName"].(string)
+			modelName, _ := system["modelName"].(string)
+			name := id
+			if modelName != "" {
+				name = strings.TrimSpace(brandName + " " + modelName)
+			}
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			if counts[name] == 0 {
+				names = append(names, name)
+			}
+			counts[name]++
+		}
+		if len(seen) > 0 {
+			withSystems++
+		}
+	}
+
+	common := []string{}
+	for _, name := range names {
+		if counts[name] == withSystems {
+			common = append(common, name)
+		}
+	}
+	if len(common) > 0 {
+		return common
+	}
+	return names
+}
+
+// recordSystemsFilter records the vendor systems the vendor catalog was read
+// for in its vendor configuration, so a refresh reads it for the same ones.
+func (vc *VendorCatalog) recordSystemsFilter() {
+	systems := vc.refreshSystemsFilter()
+	if len(systems) == 0 {
+		return
+	}
+	if vc.CatalogInfo.VendorConfiguration == nil {
+		vc.CatalogInfo.VendorConfiguration = map[string]any{}
+	}
+	vc.CatalogInfo.VendorConfiguration[systemsFilterKey] = systems
+}
+
+// refreshSystemsFilter returns the vendor systems the vendor catalog was read
+// for, or nil when it was not filtered. Supermicro catalogs are never filtered.
+func (vc *VendorCatalog) refreshSystemsFilter() []string {
+	if vc.CatalogInfo.Vendor == VendorSupermicro {
+		return nil
+	}
+
+	systems := append([]string{}, vc.VendorSystemsFilter...)
+	for system := range vc.VendorSystemsFilterEx {
+		if !slices.Contains(systems, system) {
+			systems = append(systems, system)
+		}
+	}
+	return systems
+}
+
+// refreshResults lists the binaries of a diff with the given status.
+func refreshResults(diff catalogBinaryDiff, status string) []FirmwareBinaryRefresh {
+	results := []FirmwareBinaryRefresh{}
+	for _, binary := range diff.Added {
+		results = append(results, FirmwareBinaryRefresh{
+			Action:     BinaryRefreshAdded,
+			ExternalId: *binary.ExternalId,
+			Name:       binary.Name,
+			NewVersion: stringValue(binary.PackageVersion),
+			Status:     status,
+		})
+	}
+	for _, change := range diff.Changed {
+		results = append(results, FirmwareBinaryRefresh{
+			Action:     BinaryRefreshChanged,
+			ExternalId: *change.New.ExternalId,
+			Name:       change.New.Name,
+			OldVersion: stringValue(change.Old.PackageVersion),
+			NewVersion: stringValue(change.New.PackageVersion),
+			OldId:      int64(change.Old.Id),
+			Status:     status,
+		})
+	}
+	for _, binary := range diff.Withdrawn {
+		results = append(results, FirmwareBinaryRefresh{
+			Action:     BinaryRefreshWithdrawn,
+			ExternalId: *binary.ExternalId,
+			Name:       binary.Name,
+			OldVersion: stringValue(binary.PackageVersion),
+			OldId:      int64(binary.Id),
+			Status:     status,
+		})
+	}
+	return results
+}
+
+// applyCatalogDiff stages and registers the added and changed binaries and
+// deletes the withdrawn ones. There is no API to update a binary, so a changed
+// binary is replaced: its old record is deleted once the new one is created,
+// so a failed creation leaves the old binary in the catalog.
+func (vc *VendorCatalog) applyCatalogDiff(ctx context.Context, diff catalogBinaryDiff) ([]FirmwareBinaryRefresh, error) {
+	results := refreshResults(diff, "")
+
+	staging := append([]*sdk.FirmwareBinary{}, diff.Added...)
+	for _, change := range diff.Changed {
+		staging = append(staging, change.New)
+	}
+	vc.downloadBinaries(staging)
+	defer vc.removeTempFiles()
+
+	repo, err := vc.openRepository()
+	if err != nil {
+		return nil, err
+	}
+	if repo != nil {
+		defer repo.Close()
+	}
+
+	repoUrl, err := vc.repositoryUrl(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	client := api.GetApiClient(ctx)
+
+	for i := range results {
+		result := &results[i]
+
+		if result.Action != BinaryRefreshWithdrawn {
+			var binary *sdk.FirmwareBinary
+			if result.Action == BinaryRefreshAdded {
+				binary = diff.Added[i]
+			} else {
+				binary = diff.Changed[i-len(diff.Added)].New
+			}
+
+			staged, err := vc.stageBinary(binary, repo, repoUrl)
+			if err != nil {
+				return nil, err
+			}
+			if !staged {
+				result.Status = "skipped"
+				continue
+			}
+
+			err = vc.createFirmwareBinary(ctx, binary)
+			if err != nil {
+				return nil, err
+			}
+
+			if result.OldId != 0 {
+				err = deleteFirmwareBinary(ctx, client, result.OldId)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			result.NewId = int64(binary.Id)
+			result.Status = "done"
+			continue
+		}
+
+		err = deleteFirmwareBinary(ctx, client, result.OldId)
+		if err != nil {
+			return nil, err
+		}
+		result.Status = "done"
+	}
+
+	return results, nil
+}
+
+func deleteFirmwareBinary(ctx context.Context, client *sdk.APIClient, firmwareBinaryId int64) error {
+	logger.Get().Debug().Msgf("Deleting firmware binary %d", firmwareBinaryId)
+
+	httpRes, err := client.FirmwareBinaryAPI.DeleteFirmwareBinary(ctx, firmwareBinaryId).Execute()
+	return response_inspector.InspectResponse(httpRes, err)
+}
+
+// updateMetalsoftCatalog records the vendor catalog version the catalog was
+// refreshed from.
+func (vc *VendorCatalog) updateMetalsoftCatalog(ctx context.Context, firmwareCatalog sdk.FirmwareCatalog) error {
+	vc.recordSystemsFilter()
+	firmwareCatalogUpdate := sdk.UpdateFirmwareCatalog{
+		Name:                       firmwareCatalog.Name,
+		Description:                firmwareCatalog.Description,
+		Vendor:                     firmwareCatalog.Vendor,
+		UpdateType:                 firmwareCatalog.UpdateType,
+		VendorUrl:                  vc.CatalogInfo.VendorUrl,
+		VendorId:                   vc.CatalogInfo.VendorId,
+		VendorConfiguration:        vc.CatalogInfo.VendorConfiguration,
+		VendorServerTypesSupported: firmwareCatalog.VendorServerTypesSupported,
+		VendorReleaseTimestamp:     vc.CatalogInfo.VendorReleaseTimestamp,
+	}
+	if len(vc.CatalogInfo.VendorServerTypesSupported) > 0 {
+		firmwareCatalogUpdate.VendorServerTypesSupported = vc.CatalogInfo.VendorServerTypesSupported
+	}
+
+	client := api.GetApiClient(ctx)
+
+	_, httpRes, err := client.FirmwareCatalogAPI.
+		UpdateFirmwareCatalog(ctx, int64(firmwareCatalog.Id)).
+		UpdateFirmwareCatalog(firmwareCatalogUpdate).
+		Execute()
+	return response_inspector.InspectResponse(httpRes, err)
+}
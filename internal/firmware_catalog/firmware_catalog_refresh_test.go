@@ -0,0 +1,170 @@
+package firmware_catalog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	sdk "github.com/metalsoft-io/metalcloud-sdk-go"
+)
+
+func refreshBinary(externalId string, version string, checksum string) sdk.FirmwareBinary {
+	binary := sdk.FirmwareBinary{
+		ExternalId:        sdk.PtrString(externalId),
+		Name:              externalId,
+		PackageVersion:    sdk.PtrString(version),
+		VendorDownloadUrl: "https://vendor.example.com/" + externalId,
+		Vendor:            map[string]any{},
+	}
+	setBinaryChecksum(binary.Vendor, checksum)
+	return binary
+}
+
+func TestDiffCatalogBinaries(t *testing.T) {
+	md5A := "0123456789abcdef0123456789abcdef"
+	md5B := "fedcba9876543210fedcba9876543210"
+
+	existing := []sdk.FirmwareBinary{
+		refreshBinary("same.exe", "1.0", md5A),
+		refreshBinary("newer.exe", "1.0", md5A),
+		refreshBinary("repacked.exe", "2.0", md5A),
+		refreshBinary("gone-b.exe", "1.0", ""),
+		refreshBinary("gone-a.exe", "1.0", ""),
+		{Name: "manual", Vendor: map[string]any{}},
+	}
+
+	same := refreshBinary("same.exe", "1.0", md5A)
+	newer := refreshBinary("newer.exe", "1.1", md5A)
+	repacked := refreshBinary("repacked.exe", "2.0", md5B)
+	added := refreshBinary("added.exe", "3.0", md5A)
+	vendor := []*sdk.FirmwareBinary{&same, &newer, &repacked, &added, &added}
+
+	diff := diffCatalogBinaries(existing, vendor, nil, nil)
+
+	if len(diff.Added) != 1 || *diff.Added[0].ExternalId != "added.exe" {
+		t.Errorf("Added = %v, want added.exe", diff.Added)
+	}
+	if len(diff.Changed) != 2 ||
+		*diff.Changed[0].New.ExternalId != "newer.exe" ||
+		*diff.Changed[1].New.ExternalId != "repacked.exe" {
+		t.Errorf("Changed = %v, want newer.exe and repacked.exe", diff.Changed)
+	}
+	if len(diff.Withdrawn) != 2 ||
+		*diff.Withdrawn[0].ExternalId != "gone-a.exe" ||
+		*diff.Withdrawn[1].ExternalId != "gone-b.exe" {
+		t.Errorf("Withdrawn = %v, want gone-a.exe and gone-b.exe", diff.Withdrawn)
+	}
+	if diff.Unchanged != 1 {
+		t.Errorf("Unchanged = %d, want 1", diff.Unchanged)
+	}
+
+	results := refreshResults(diff, "planned")
+	if len(results) != 5 || results[1].OldVersion != "1.0" || results[1].NewVersion != "1.1" {
+		t.Errorf("refreshResults() = %+v", results)
+	}
+}
+
+func TestDiffCatalogBinariesWithdrawal(t *testing.T) {
+	supporting := func(externalId string, systems ...map[string]interface{}) sdk.FirmwareBinary {
+		binary := refreshBinary(externalId, "1.0", "")
+		binary.VendorSupportedSystems = systems
+		return binary
+	}
+
+	archived := supporting("extracted.bin", map[string]interface{}{"id": "R640"})
+	archived.PackageId = sdk.PtrString("archive.zip")
+
+	existing := []sdk.FirmwareBinary{
+		supporting("by-id.exe", map[string]interface{}{"id": "7D2V"}),
+		supporting("by-model.exe", map[string]interface{}{"id": "08FF", "brandName": "PowerEdge", "modelName": "R640"}),
+		supporting("other-system.exe", map[string]interface{}{"id": "7X06"}),
+		supporting("by-device.exe"),
+		supporting("corrupt.exe", map[string]interface{}{"id": "7D2V"}),
+		archived,
+	}
+	existing[3].VendorSupportedDevices = []map[string]interface{}{{"id": "uuid-1"}}
+
+	filter := []string{"7D2V", "PowerEdge R640", "R640", "uuid-1"}
+	diff := diffCatalogBinaries(existing, nil, filter, []string{"corrupt.exe", "archive.zip"})
+
+	withdrawn := []string{}
+	for _, binary := range diff.Withdrawn {
+		withdrawn = append(withdrawn, *binary.ExternalId)
+	}
+	if strings.Join(withdrawn, ",") != "by-device.exe,by-id.exe,by-model.exe" {
+		t.Errorf("Withdrawn = %v, want by-device.exe, by-id.exe and by-model.exe", withdrawn)
+	}
+	if diff.Kept != 3 {
+		t.Errorf("Kept = %d, want 3", diff.Kept)
+	}
+
+	// Without a filter every binary is in scope
+	if diff := diffCatalogBinaries(existing[2:3], nil, nil, nil); len(diff.Withdrawn) != 1 {
+		t.Errorf("Withdrawn without a filter = %v, want other-system.exe", diff.Withdrawn)
+	}
+}
+
+func TestCatalogSystemsFilter(t *testing.T) {
+	dell := &sdk.FirmwareCatalog{Vendor: VendorDell, VendorServerTypesSupported: []string{"PowerEdge R640"}}
+	if got := catalogSystemsFilter(dell, nil); len(got) != 1 || got[0] != "PowerEdge R640" {
+		t.Errorf("catalogSystemsFilter(dell) = %v", got)
+	}
+
+	hpe := &sdk.FirmwareCatalog{Vendor: VendorHp}
+	binaries := []sdk.FirmwareBinary{
+		{VendorSupportedDevices: []map[string]interface{}{{"id": "uuid-1"}, {"id": "uuid-2"}}},
+		{VendorSupportedDevices: []map[string]interface{}{{"id": "uuid-2"}}},
+	}
+	if got := catalogSystemsFilter(hpe, binaries); len(got) != 2 || got[0] != "uuid-1" || got[1] != "uuid-2" {
+		t.Errorf("catalogSystemsFilter(hpe) = %v", got)
+	}
+}
+
+func TestCatalogSystemsFilter_VendorSystemsOnly(t *testing.T) {
+	// A catalog created with --vendor-systems and no --server-types has no
+	// VendorServerTypesSupported; the filter is recorded at create time.
+	created := &VendorCatalog{
+		CatalogInfo:         sdk.FirmwareCatalog{Vendor: VendorDell},
+		VendorSystemsFilter: []string{"PowerEdge R640"},
+	}
+	created.recordSystemsFilter()
+
+	// The catalog read back from the API has the filter as decoded JSON.
+	data, err := json.Marshal(created.CatalogInfo.VendorConfiguration)
+	if err != nil {
+		t.Fatalf("marshal vendor configuration: %v", err)
+	}
+	dell := &sdk.FirmwareCatalog{Vendor: VendorDell}
+	if err := json.Unmarshal(data, &dell.VendorConfiguration); err != nil {
+		t.Fatalf("unmarshal vendor configuration: %v", err)
+	}
+	if got := catalogSystemsFilter(dell, nil); len(got) != 1 || got[0] != "PowerEdge R640" {
+		t.Errorf("catalogSystemsFilter(recorded) = %v", got)
+	}
+
+	// Catalogs created before the filter was recorded fall back to the systems
+	// all their binaries support.
+	dellBinaries := []sdk.FirmwareBinary{
+		{VendorSupportedSystems: []map[string]interface{}{
+			{"id": "0716", "brandName": "PowerEdge", "modelName": "R640"},
+			{"id": "0717", "brandName": "PowerEdge", "modelName": "R740"},
+		}},
+		{VendorSupportedSystems: []map[string]interface{}{
+			{"id": "0716", "brandName": "PowerEdge", "modelName": "R640"},
+		}},
+	}
+	legacy := &sdk.FirmwareCatalog{Vendor: VendorDell}
+	if got := catalogSystemsFilter(legacy, dellBinaries); len(got) != 1 || got[0] != "PowerEdge R640" {
+		t.Errorf("catalogSystemsFilter(dell binaries) = %v", got)
+	}
+
+	lenovoBinaries := []sdk.FirmwareBinary{
+		{VendorSupportedSystems: []map[string]interface{}{{"id": "7X06"}}},
+		{VendorSupportedSystems: []map[string]interface{}{{"id": "7Y51"}}},
+		{VendorSupportedSystems: []map[string]interface{}{{"id": "7X06"}}},
+	}
+	lenovo := &sdk.FirmwareCatalog{Vendor: VendorLenovo}
+	if got := catalogSystemsFilter(lenovo, lenovoBinaries); strings.Join(got, ",") != "7X06,7Y51" {
+		t.Errorf("catalogSystemsFilter(lenovo binaries) = %v", got)
+	}
+}
@@ -0,0 +1,182 @@
+package firmware_catalog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/response_inspector"
+)
+
+// Statuses of a binary checked by FirmwareCatalogVerify.
+const (
+	BinaryVerifyOk         = "ok"
+	BinaryVerifyMismatch   = "mismatch"
+	BinaryVerifyMissing    = "missing"
+	BinaryVerifyUnverified = "unverified"
+	BinaryVerifyError      = "error"
+)
+
+// FirmwareBinaryVerification is the result of re-checking one binary of a
+// catalog in the repository.
+type FirmwareBinaryVerification struct {
+	Id         int64  `json:"id"`
+	Name       string `json:"name"`
+	ExternalId string `json:"externalId"`
+	Algorithm  string `json:"algorithm,omitempty"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+var firmwareBinaryVerificationPrintConfig = formatter.PrintConfig{
+	FieldsConfig: map[string]formatter.RecordFieldConfig{
+		"Id": {
+			Title: "ID",
+			Order: 1,
+		},
+		"Name": {
+			MaxWidth: 30,
+			Order:    2,
+		},
+		"ExternalId": {
+			Title:    "External ID",
+			MaxWidth: 40,
+			Order:    3,
+		},
+		"Algorithm": {
+			Order: 4,
+		},
+		"Status": {
+			Order: 5,
+		},
+		"Detail": {
+			MaxWidth: 60,
+			Order:    6,
+		},
+	},
+}
+
+// FirmwareCatalogVerify downloads every binary of a catalog from the repository
+// and checks it against the checksum the vendor published for it. The binaries
+// are fetched from their cache download URL, or from repoBaseUrl when given. It
+// returns an error when a binary is corrupt, missing or cannot be fetched.
+func FirmwareCatalogVerify(ctx context.Context, firmwareCatalogId string, repoBaseUrl string) error {
+	logger.Get().Info().Msgf("Verifying the binaries of firmware catalog '%s'", firmwareCatalogId)
+
+	firmwareCatalogIdNumeric, err := getFirmwareCatalogId(firmwareCatalogId)
+	if err != nil {
+		return err
+	}
+
+	var repoUrl *url.URL
+	if repoBaseUrl != "" {
+		repoUrl, err = url.Parse(repoBaseUrl)
+		if err != nil {
+			return fmt.Errorf("unable to parse repo base URL: %v", err)
+		}
+	}
+
+	client := api.GetApiClient(ctx)
+
+	firmwareCatalog, httpRes, err := client.FirmwareCatalogAPI.GetFirmwareCatalog(ctx, firmwareCatalogIdNumeric).Execute()
+	if err := response_inspector.InspectResponse(httpRes, err); err != nil {
+		return err
+	}
+
+	binaries, err := listCatalogBinaries(ctx, client, firmwareCatalogIdNumeric)
+	if err != nil {
+		return err
+	}
+
+	transport, err := newDownloadTransport(ctx)
+	if err != nil {
+		return err
+	}
+	downloader := newDownloadManager("", DefaultDownloadTimeout, defaultDownloadRetries, transport)
+
+	results := []FirmwareBinaryVerification{}
+	counts := map[string]int{}
+	for _, binary := range binaries {
+		result := FirmwareBinaryVerification{
+			Id:   int64(binary.Id),
+			Name: binary.Name,
+		}
+		if binary.ExternalId != nil {
+			result.ExternalId = *binary.ExternalId
+		}
+
+		fileUrl := ""
+		if repoUrl != nil && result.ExternalId != "" {
+			fileUrl = repoUrl.JoinPath(result.ExternalId).String()
+		} else if binary.CacheDownloadUrl != nil {
+			fileUrl = *binary.CacheDownloadUrl
+		}
+
+		algorithm, digest := vendorChecksum(binary.Vendor)
+		switch {
+		case firmwareCatalog.Vendor == VendorSupermicro:
+			result.Status = BinaryVerifyUnverified
+			result.Detail = "the vendor checksum covers the archive the binary was extracted from"
+		case algorithm == "":
+			result.Status = BinaryVerifyUnverified
+			result.Detail = "no vendor checksum published"
+		case fileUrl == "":
+			result.Status = BinaryVerifyUnverified
+			result.Detail = "not in a repository (no cache download URL)"
+		default:
+			result.Algorithm = algorithm
+			result.Status, result.Detail = checkRepositoryFile(downloader.client, fileUrl, algorithm, digest)
+		}
+
+		logger.Get().Debug().Msgf("Binary %d (%s): %s %s", result.Id, result.ExternalId, result.Status, result.Detail)
+		counts[result.Status]++
+		results = append(results, result)
+	}
+
+	if err := formatter.PrintResult(results, &firmwareBinaryVerificationPrintConfig); err != nil {
+		return err
+	}
+
+	if formatter.IsTextFormat() {
+		fmt.Printf("Verified %d binaries: %d ok, %d mismatch, %d missing, %d error, %d unverified\n",
+			len(results), counts[BinaryVerifyOk], counts[BinaryVerifyMismatch], counts[BinaryVerifyMissing],
+			counts[BinaryVerifyError], counts[BinaryVerifyUnverified])
+	}
+
+	if failed := counts[BinaryVerifyMismatch] + counts[BinaryVerifyMissing] + counts[BinaryVerifyError]; failed > 0 {
+		return fmt.Errorf("%d binaries of firmware catalog '%s' failed verification", failed, firmwareCatalogId)
+	}
+
+	return nil
+}
+
+// checkRepositoryFile downloads fileUrl with client and compares its checksum
+// with the expected digest.
+func checkRepositoryFile(client *http.Client, fileUrl string, algorithm string, expected string) (status string, detail string) {
+	resp, err := client.Get(fileUrl)
+	if err != nil {
+		return BinaryVerifyError, err.Error()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return BinaryVerifyMissing, fmt.Sprintf("not found at %s", fileUrl)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return BinaryVerifyError, fmt.Sprintf("received non-OK response from %s: %d", fileUrl, resp.StatusCode)
+	}
+
+	actual, err := readerChecksum(resp.Body, algorithm)
+	if err != nil {
+		return BinaryVerifyError, fmt.Sprintf("failed to read %s: %v", fileUrl, err)
+	}
+	if actual != expected {
+		return BinaryVerifyMismatch, fmt.Sprintf("%s is %s, expected %s", algorithm, actual, expected)
+	}
+
+	return BinaryVerifyOk, ""
+}
@@ -0,0 +1,459 @@
+package firmware_catalog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+)
+
+// Repository upload backends.
+const (
+	RepoTypeSftp  = "sftp"
+	RepoTypeLocal = "local"
+	RepoTypeHttp  = "http"
+	RepoTypeS3    = "s3"
+)
+
+// ValidRepoTypes lists the supported repository upload backends.
+var ValidRepoTypes = []string{RepoTypeSftp, RepoTypeLocal, RepoTypeHttp, RepoTypeS3}
+
+// repositoryRequestTimeout bounds a single request to an HTTP or S3
+// repository, including the upload of the binary.
+const repositoryRequestTimeout = time.Hour
+
+// repositoryBackend publishes binaries to the repository the servers download
+// them from. Binaries are stored under a path relative to the repository root,
+// which is also their path relative to the repository base URL.
+type repositoryBackend interface {
+	// Upload stores the file at localPath as relativePath in the repository.
+	Upload(localPath string, relativePath string) error
+	// BaseUrl returns the URL the repository root is served at, used when no
+	// repository base URL is configured, or an empty string when unknown.
+	BaseUrl() string
+	Close() error
+}
+
+// cleanRelativePath turns a binary's external ID into a slash separated path
+// that cannot escape the repository root.
+func cleanRelativePath(relativePath string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(relativePath)), "/")
+}
+
+// localRepository copies binaries into a directory, such as the document root
+// of a web server or an NFS export.
+type localRepository struct {
+	root string
+}
+
+func newLocalRepository(root string) (*localRepository, error) {
+	if root == "" {
+		return nil, fmt.Errorf("a repository local path is required for the %s repository type", RepoTypeLocal)
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository local path: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("repository local path %s is not a directory", root)
+	}
+
+	return &localRepository{root: root}, nil
+}
+
+func (r *localRepository) Upload(localPath string, relativePath string) error {
+	targetPath := filepath.Join(r.root, filepath.FromSlash(cleanRelativePath(relativePath)))
+
+	logger.Get().Debug().Msgf("Copying binary %s to %s", localPath, targetPath)
+
+	err := os.MkdirAll(filepath.Dir(targetPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create repository directory: %v", err)
+	}
+
+	srcFile, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	// Copy to a temporary file first, so the repository never serves a partial binary
+	tempFile, err := os.CreateTemp(filepath.Dir(targetPath), ".upload_*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	if _, err := io.Copy(tempFile, srcFile); err != nil {
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tempFile.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), targetPath)
+}
+
+func (r *localRepository) BaseUrl() string {
+	return ""
+}
+
+func (r *localRepository) Close() error {
+	return nil
+}
+
+// httpRepository uploads binaries with HTTP PUT, to a plain web server or a
+// WebDAV share. Missing WebDAV collections are created with MKCOL.
+type httpRepository struct {
+	client    *http.Client
+	uploadUrl *url.URL
+	user      string
+	password  string
+}
+
+func newHttpRepository(uploadUrl string, user string, password string) (*httpRepository, error) {
+	if uploadUrl == "" {
+		return nil, fmt.Errorf("a repository upload URL is required for the %s repository type", RepoTypeHttp)
+	}
+
+	parsedUrl, err := url.Parse(uploadUrl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse repository upload URL: %v", err)
+	}
+
+	return &httpRepository{
+		client:    &http.Client{Timeout: repositoryRequestTimeout},
+		uploadUrl: parsedUrl,
+		user:      user,
+		password:  password,
+	}, nil
+}
+
+func (r *httpRepository) Upload(localPath string, relativePath string) error {
+	relativePath = cleanRelativePath(relativePath)
+
+	statusCode, err := r.put(localPath, relativePath)
+	if err != nil {
+		return err
+	}
+
+	// WebDAV servers answer 409 Conflict when the parent collection is missing
+	if statusCode == http.StatusConflict && path.Dir(relativePath) != "." {
+		err = r.makeCollections(path.Dir(relativePath))
+		if err != nil {
+			return err
+		}
+
+		statusCode, err = r.put(localPath, relativePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if statusCode != http.StatusOK && statusCode != http.StatusCreated && statusCode != http.StatusNoContent {
+		return fmt.Errorf("received non-OK response when uploading %s: %d", relativePath, statusCode)
+	}
+
+	return nil
+}
+
+func (r *httpRepository) put(localPath string, relativePath string) (int, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	targetUrl := r.uploadUrl.JoinPath(relativePath).String()
+	logger.Get().Debug().Msgf("Uploading binary %s to %s", localPath, targetUrl)
+
+	req, err := http.NewRequest(http.MethodPut, targetUrl, file)
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	return r.do(req)
+}
+
+// makeCollections creates the WebDAV collections of collectionPath, from the
+// top down. Collections that already exist are answered with 405.
+func (r *httpRepository) makeCollections(collectionPath string) error {
+	current := ""
+	for _, segment := range strings.Split(collectionPath, "/") {
+		current = path.Join(current, segment)
+
+		req, err := http.NewRequest("MKCOL", r.uploadUrl.JoinPath(current).String()+"/", nil)
+		if err != nil {
+			return err
+		}
+
+		statusCode, err := r.do(req)
+		if err != nil {
+			return err
+		}
+		if statusCode != http.StatusCreated && statusCode != http.StatusMethodNotAllowed {
+			return fmt.Errorf("received non-OK response when creating collection %s: %d", current, statusCode)
+		}
+	}
+
+	return nil
+}
+
+func (r *httpRepository) do(req *http.Request) (int, error) {
+	if r.user != "" {
+		req.SetBasicAuth(r.user, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, nil
+}
+
+func (r *httpRepository) BaseUrl() string {
+	return r.uploadUrl.String()
+}
+
+func (r *httpRepository) Close() error {
+	return nil
+}
+
+// s3RepositoryConfig configures an S3-compatible repository. Missing
+// credentials and region are read from the standard AWS environment variables.
+type s3RepositoryConfig struct {
+	Endpoint     string
+	Bucket       string
+	Prefix       string
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// s3Repository uploads binaries to an S3-compatible object store with
+// path-style PutObject requests signed with AWS Signature Version 4. Objects
+// are limited to the single request maximum of 5 GiB.
+type s3Repository struct {
+	client *http.Client
+	config s3RepositoryConfig
+	bucket *url.URL
+}
+
+func newS3Repository(config s3RepositoryConfig) (*s3Repository, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("a repository S3 endpoint and bucket are required for the %s repository type", RepoTypeS3)
+	}
+
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_REGION")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.AccessKey == "" {
+		config.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		config.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if config.SecretKey == "" {
+		config.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, fmt.Errorf("S3 credentials are required: set the repository S3 access and secret keys or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse repository S3 endpoint: %v", err)
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("repository S3 endpoint %s must be an absolute URL", config.Endpoint)
+	}
+
+	config.Prefix = cleanRelativePath(config.Prefix)
+
+	return &s3Repository{
+		client: &http.Client{Timeout: repositoryRequestTimeout},
+		config: config,
+		bucket: endpoint.JoinPath(config.Bucket),
+	}, nil
+}
+
+func (r *s3Repository) objectKey(relativePath string) string {
+	return cleanRelativePath(path.Join(r.config.Prefix, relativePath))
+}
+
+func (r *s3Repository) Upload(localPath string, relativePath string) error {
+	payloadHash, err := fileChecksum(localPath, ChecksumSHA256)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	key := r.objectKey(relativePath)
+	objectUrl := r.bucket.Scheme + "://" + r.bucket.Host + awsUriEncode(path.Join("/", r.bucket.Path, key), false)
+
+	logger.Get().Debug().Msgf("Uploading binary %s to %s", localPath, objectUrl)
+
+	req, err := http.NewRequest(http.MethodPut, objectUrl, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	signS3Request(req, payloadHash, r.config, time.Now())
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("received non-OK response when uploading %s to S3: %d %s", key, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+func (r *s3Repository) BaseUrl() string {
+	return r.bucket.JoinPath(r.config.Prefix).String()
+}
+
+func (r *s3Repository) Close() error {
+	return nil
+}
+
+// signS3Request adds the AWS Signature Version 4 authorization of req, signing
+// the host and all headers set on it.
+func signS3Request(req *http.Request, payloadHash string, config s3RepositoryConfig, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", config.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + config.Region + "/s3/aws4_request"
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalRequestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+config.SecretKey), date)
+	signingKey = hmacSHA256(signingKey, config.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, awsUriEncode(key, true)+"="+awsUriEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsUriEncode percent-encodes everything but the unreserved characters, and
+// slashes unless encodeSlash is set, as Signature Version 4 requires.
+func awsUriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
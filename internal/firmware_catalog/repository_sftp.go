@@ -0,0 +1,123 @@
+package firmware_catalog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpRepository uploads binaries over SFTP, below root on the SSH server.
+type sftpRepository struct {
+	sshClient  *ssh.Client
+	sftpClient *sftp.Client
+	root       string
+}
+
+func newSftpRepository(host string, user string, root string, privateKeyPath string, knownHostsPath string, ignoreHostKeyCheck bool) (*sftpRepository, error) {
+	if host == "" || user == "" {
+		return nil, fmt.Errorf("a repository SSH host and user are required for the %s repository type", RepoTypeSftp)
+	}
+
+	authMethod, err := privateKeyFile(privateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key '%s': %v", privateKeyPath, err)
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	if ignoreHostKeyCheck {
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	} else {
+		hostKeyCallback, err = knownhosts.New(knownHostsPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not create host key callback: %v", err)
+		}
+	}
+
+	sshConfig := &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			authMethod,
+		},
+		HostKeyCallback: hostKeyCallback,
+	}
+
+	sshClient, err := ssh.Dial("tcp", host, sshConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+
+	return &sftpRepository{
+		sshClient:  sshClient,
+		sftpClient: sftpClient,
+		root:       root,
+	}, nil
+}
+
+func (r *sftpRepository) Upload(localPath string, relativePath string) error {
+	remotePath := cleanRelativePath(relativePath)
+	if r.root != "" {
+		remotePath = path.Join(r.root, remotePath)
+	}
+
+	srcFile, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	logger.Get().Debug().Msgf("Uploading binary %s to remote path %s", localPath, remotePath)
+
+	err = r.sftpClient.MkdirAll(path.Dir(remotePath))
+	if err != nil {
+		return fmt.Errorf("failed to create remote directory: %v", err)
+	}
+
+	dstFile, err := r.sftpClient.Create(remotePath)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	_, err = io.Copy(dstFile, srcFile)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *sftpRepository) BaseUrl() string {
+	return ""
+}
+
+func (r *sftpRepository) Close() error {
+	r.sftpClient.Close()
+	return r.sshClient.Close()
+}
+
+// Reads a PEM private key file
+func privateKeyFile(file string) (ssh.AuthMethod, error) {
+	key, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.PublicKeys(signer), nil
+}
@@ -6,24 +6,25 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"path"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/response_inspector"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/utils"
 	sdk "github.com/metalsoft-io/metalcloud-sdk-go"
-	"github.com/pkg/sftp"
-	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/knownhosts"
 	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/encoding/unicode"
 )
@@ -46,6 +47,41 @@ type VendorCatalog struct {
 	UserPrivateKeyPath      string
 	KnownHostsPath          string
 	IgnoreHostKeyCheck      bool
+	RepoType                string
+	RepoLocalPath           string
+	RepoUploadUrl           string
+	RepoUploadUser          string
+	RepoUploadPassword      string
+	RepoS3Endpoint          string
+	RepoS3Bucket            string
+	RepoS3Prefix            string
+	RepoS3Region            string
+	RepoS3AccessKey         string
+	RepoS3SecretKey         string
+	DownloadParallelism     int
+	DownloadCachePath       string
+	DownloadTimeout         time.Duration
+	DownloadRetries         int
+	// DownloadTransport carries the vendor downloads; nil uses the default
+	// transport.
+	DownloadTransport http.RoundTripper
+	// ChecksumFailures lists the binaries left out of the catalog because they
+	// did not match the checksum the vendor published.
+	ChecksumFailures []string
+
+	downloader *downloadManager
+	downloads  map[*sdk.FirmwareBinary]downloadedBinary
+
+	// tempFiles are the binaries downloaded to temporary files, removed by
+	// removeTempFiles once they are uploaded or bundled.
+	tempFilesMu sync.Mutex
+	tempFiles   []string
+}
+
+// downloadedBinary is the result of downloading a binary ahead of staging it.
+type downloadedBinary struct {
+	localPath string
+	err       error
 }
 
 func NewVendorCatalogFromCreateOptions(options FirmwareCatalogCreateOptions) (*VendorCatalog, error) {
@@ -57,6 +93,14 @@ func NewVendorCatalogFromCreateOptions(options FirmwareCatalogCreateOptions) (*V
 		return nil, fmt.Errorf("invalid update type flag %s - valid options are: %v", options.UpdateType, ValidUpdateTypes)
 	}
 
+	if options.RepoType == "" {
+		options.RepoType = RepoTypeSftp
+	}
+
+	if !slices.Contains(ValidRepoTypes, options.RepoType) {
+		return nil, fmt.Errorf("invalid repository type %s - valid options are: %v", options.RepoType, ValidRepoTypes)
+	}
+
 	vendorCatalog := VendorCatalog{
 		CatalogInfo: sdk.FirmwareCatalog{
 			Name:       options.Name,
@@ -78,6 +122,37 @@ func NewVendorCatalogFromCreateOptions(options FirmwareCatalogCreateOptions) (*V
 		UserPrivateKeyPath:      options.UserPrivateKeyPath,
 		KnownHostsPath:          options.KnownHostsPath,
 		IgnoreHostKeyCheck:      options.IgnoreHostKeyCheck,
+		RepoType:                options.RepoType,
+		RepoLocalPath:           options.RepoLocalPath,
+		RepoUploadUrl:           options.RepoUploadUrl,
+		RepoUploadUser:          options.RepoUploadUser,
+		RepoUploadPassword:      options.RepoUploadPassword,
+		RepoS3Endpoint:          options.RepoS3Endpoint,
+		RepoS3Bucket:            options.RepoS3Bucket,
+		RepoS3Prefix:            options.RepoS3Prefix,
+		RepoS3Region:            options.RepoS3Region,
+		RepoS3AccessKey:         options.RepoS3AccessKey,
+		RepoS3SecretKey:         options.RepoS3SecretKey,
+		DownloadParallelism:     options.DownloadParallelism,
+		DownloadCachePath:       options.DownloadCachePath,
+		DownloadTimeout:         DefaultDownloadTimeout,
+		DownloadRetries:         defaultDownloadRetries,
+	}
+
+	if vendorCatalog.DownloadParallelism == 0 {
+		vendorCatalog.DownloadParallelism = DefaultDownloadParallelism
+	}
+
+	if vendorCatalog.DownloadCachePath == "" && !options.NoDownloadCache {
+		vendorCatalog.DownloadCachePath = defaultDownloadCachePath()
+	}
+
+	if options.DownloadTimeout != "" {
+		timeout, err := time.ParseDuration(options.DownloadTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid download timeout %s: %v", options.DownloadTimeout, err)
+		}
+		vendorCatalog.DownloadTimeout = timeout
 	}
 
 	if options.VendorUrl != "" {
@@ -169,6 +244,7 @@ func (vc *VendorCatalog) ProcessVendorCatalog(ctx context.Context) error {
 }
 
 func (vc *VendorCatalog) CreateMetalsoftCatalog(ctx context.Context) error {
+	vc.recordSystemsFilter()
 	firmwareCatalogCreate := sdk.CreateFirmwareCatalog{
 		Name:                       vc.CatalogInfo.Name,
 		Description:                vc.CatalogInfo.Description,
@@ -193,133 +269,213 @@ func (vc *VendorCatalog) CreateMetalsoftCatalog(ctx context.Context) error {
 
 	vc.CatalogInfo.Id = firmwareCatalog.Id
 
-	var sftpClient *sftp.Client
-	if vc.UploadBinaries {
-		authMethod, err := privateKeyFile(vc.UserPrivateKeyPath)
-		if err != nil {
-			return fmt.Errorf("failed to parse private key '%s': %v", vc.UserPrivateKeyPath, err)
-		}
+	vc.downloadBinaries(vc.Binaries)
+	defer vc.removeTempFiles()
 
-		var hostKeyCallback ssh.HostKeyCallback
-		if vc.IgnoreHostKeyCheck {
-			hostKeyCallback = ssh.InsecureIgnoreHostKey()
-		} else {
-			hostKeyCallback, err = knownhosts.New(vc.KnownHostsPath)
-			if err != nil {
-				return fmt.Errorf("could not create host key callback: %v", err)
-			}
-		}
+	repo, err := vc.openRepository()
+	if err != nil {
+		return err
+	}
+	if repo != nil {
+		defer repo.Close()
+	}
 
-		sshConfig := &ssh.ClientConfig{
-			User: vc.RepoSshUser,
-			Auth: []ssh.AuthMethod{
-				authMethod,
-			},
-			HostKeyCallback: hostKeyCallback,
-		}
+	repoUrl, err := vc.repositoryUrl(repo)
+	if err != nil {
+		return err
+	}
 
-		sshClient, err := ssh.Dial("tcp", vc.RepoSshHost, sshConfig)
+	for _, binary := range vc.Binaries {
+		staged, err := vc.stageBinary(binary, repo, repoUrl)
 		if err != nil {
 			return err
 		}
-		defer sshClient.Close()
+		if !staged {
+			continue
+		}
 
-		sftpClient, err = sftp.NewClient(sshClient)
+		err = vc.createFirmwareBinary(ctx, binary)
 		if err != nil {
 			return err
 		}
-		defer sftpClient.Close()
 	}
 
-	var repoUrl *url.URL
-	if vc.RepoBaseUrl != "" {
-		repoUrl, err = url.Parse(vc.RepoBaseUrl)
-		if err != nil {
-			return fmt.Errorf("unable to parse repo catalog URL: %v", err)
-		}
+	if len(vc.ChecksumFailures) > 0 {
+		return fmt.Errorf("%d binaries failed checksum verification and were not added to the catalog: %s",
+			len(vc.ChecksumFailures), strings.Join(vc.ChecksumFailures, ", "))
 	}
 
-	for _, binary := range vc.Binaries {
-		// Determine the local path to the binary
-		var localPath string
-		if vc.DownloadBinaries {
-			// Download the binary from the vendor catalog
+	return nil
+}
+
+// Opens the repository backend binaries are uploaded to, when uploading.
+func (vc *VendorCatalog) openRepository() (repositoryBackend, error) {
+	if !vc.UploadBinaries {
+		return nil, nil
+	}
+
+	switch vc.RepoType {
+	case RepoTypeSftp, "":
+		return newSftpRepository(vc.RepoSshHost, vc.RepoSshUser, vc.RepoSshPath, vc.UserPrivateKeyPath, vc.KnownHostsPath, vc.IgnoreHostKeyCheck)
+	case RepoTypeLocal:
+		return newLocalRepository(vc.RepoLocalPath)
+	case RepoTypeHttp:
+		return newHttpRepository(vc.RepoUploadUrl, vc.RepoUploadUser, vc.RepoUploadPassword)
+	case RepoTypeS3:
+		return newS3Repository(s3RepositoryConfig{
+			Endpoint:  vc.RepoS3Endpoint,
+			Bucket:    vc.RepoS3Bucket,
+			Prefix:    vc.RepoS3Prefix,
+			Region:    vc.RepoS3Region,
+			AccessKey: vc.RepoS3AccessKey,
+			SecretKey: vc.RepoS3SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("invalid repository type %s - valid options are: %v", vc.RepoType, ValidRepoTypes)
+	}
+}
+
+// Parses the base URL of the repository: the configured one, else the one of
+// the repository backend, if known.
+func (vc *VendorCatalog) repositoryUrl(repo repositoryBackend) (*url.URL, error) {
+	baseUrl := vc.RepoBaseUrl
+	if baseUrl == "" && repo != nil {
+		baseUrl = repo.BaseUrl()
+	}
+	if baseUrl == "" {
+		return nil, nil
+	}
+
+	repoUrl, err := url.Parse(baseUrl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse repo catalog URL: %v", err)
+	}
+
+	return repoUrl, nil
+}
+
+// Downloads or locates a binary, verifies it, sets its repository URL and
+// uploads it when needed. It returns false when the binary must be left out of
+// the catalog: not found at the vendor URL, or corrupt (recorded in
+// ChecksumFailures).
+func (vc *VendorCatalog) stageBinary(binary *sdk.FirmwareBinary, repo repositoryBackend, repoUrl *url.URL) (bool, error) {
+	// Determine the local path to the binary
+	var localPath string
+	var err error
+	if vc.DownloadBinaries {
+		// Download the binary from the vendor catalog, unless it already was
+		if downloaded, ok := vc.downloads[binary]; ok {
+			localPath, err = downloaded.localPath, downloaded.err
+		} else {
 			localPath, err = vc.downloadBinary(binary)
+		}
+		if errors.Is(err, errChecksumMismatch) {
+			logger.Get().Error().Msgf("Binary %s skipped: %v", *binary.ExternalId, err)
+			vc.ChecksumFailures = append(vc.ChecksumFailures, *binary.ExternalId)
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if localPath == "" {
+			logger.Get().Warn().Msgf("Binary %s not found at vendor URL %s - skipping", *binary.ExternalId, binary.VendorDownloadUrl)
+			return false, nil
+		}
+	} else {
+		if vc.VendorLocalBinariesPath != "" {
+			localPath, err = vc.localBinaryPath(binary)
 			if err != nil {
-				return err
-			}
-			if localPath == "" {
-				logger.Get().Warn().Msgf("Binary %s not found at vendor URL %s - skipping", *binary.ExternalId, binary.VendorDownloadUrl)
-				continue
-			}
-		} else {
-			if vc.VendorLocalBinariesPath != "" {
-				// For Supermicro, the ExternalId is the extracted .bin file name in .extracted/ directory
-				if vc.CatalogInfo.Vendor == VendorSupermicro {
-					localPath, err = filepath.Abs(filepath.Join(vc.VendorLocalBinariesPath, ".extracted", *binary.ExternalId))
-				} else {
-					localPath, err = filepath.Abs(filepath.Join(vc.VendorLocalBinariesPath, *binary.ExternalId))
-				}
-				if err != nil {
-					return fmt.Errorf("error getting download binary absolute path: %v", err)
-				}
+				return false, err
 			}
 		}
+	}
 
-		// Set the binary URL in the repo
-		if repoUrl != nil {
-			binary.CacheDownloadUrl = sdk.PtrString(repoUrl.JoinPath(*binary.ExternalId).String())
-		} else {
-			binary.CacheDownloadUrl = nil
-		}
+	// Set the binary URL in the repo
+	if repoUrl != nil {
+		binary.CacheDownloadUrl = sdk.PtrString(repoUrl.JoinPath(*binary.ExternalId).String())
+	} else {
+		binary.CacheDownloadUrl = nil
+	}
 
-		// Upload the binary to the repository if needed
-		if vc.UploadBinaries {
-			remotePath := *binary.ExternalId
-			if vc.RepoSshPath != "" {
-				remotePath = path.Join(vc.RepoSshPath, *binary.ExternalId)
+	// Upload the binary to the repository if needed
+	if vc.UploadBinaries {
+		// Downloads are verified as they are saved; local files are checked here
+		if !vc.DownloadBinaries {
+			err = vc.verifyLocalBinary(binary, localPath)
+			if errors.Is(err, errChecksumMismatch) {
+				logger.Get().Error().Msgf("Binary %s skipped: %v", *binary.ExternalId, err)
+				vc.ChecksumFailures = append(vc.ChecksumFailures, *binary.ExternalId)
+				return false, nil
 			}
-
-			err = vc.uploadBinaryToRepository(sftpClient, localPath, remotePath)
 			if err != nil {
-				return fmt.Errorf("error uploading binary to repository: %v", err)
+				return false, err
 			}
 		}
 
-		binaryCreate := sdk.CreateFirmwareBinary{
-			CatalogId:              firmwareCatalog.Id,
-			ExternalId:             binary.ExternalId,
-			VendorInfoUrl:          binary.VendorInfoUrl,
-			VendorDownloadUrl:      binary.VendorDownloadUrl,
-			CacheDownloadUrl:       binary.CacheDownloadUrl,
-			Name:                   binary.Name,
-			PackageId:              binary.PackageId,
-			PackageVersion:         binary.PackageVersion,
-			RebootRequired:         binary.RebootRequired,
-			UpdateSeverity:         binary.UpdateSeverity,
-			VendorSupportedDevices: binary.VendorSupportedDevices,
-			VendorSupportedSystems: binary.VendorSupportedSystems,
-			VendorReleaseTimestamp: binary.VendorReleaseTimestamp,
-			Vendor:                 binary.Vendor,
+		err = repo.Upload(localPath, *binary.ExternalId)
+		if err != nil {
+			return false, fmt.Errorf("error uploading binary to repository: %v", err)
 		}
+	}
 
-		if len(binaryCreate.Name) > 255 {
-			binaryCreate.Name = binaryCreate.Name[:255]
-			logger.Get().Warn().Msgf("Binary name %s exceeded 255 characters, truncating to %s", binary.Name, binaryCreate.Name)
-		}
+	return true, nil
+}
 
-		logger.Get().Debug().Msgf("Creating firmware binary for catalog %d: %v", int(firmwareCatalog.Id), binaryCreate)
+// Returns the path of a binary below VendorLocalBinariesPath.
+func (vc *VendorCatalog) localBinaryPath(binary *sdk.FirmwareBinary) (string, error) {
+	localPath, err := filepath.Abs(filepath.Join(vc.VendorLocalBinariesPath, localBinaryRelativePath(vc.CatalogInfo.Vendor, *binary.ExternalId)))
+	if err != nil {
+		return "", fmt.Errorf("error getting download binary absolute path: %v", err)
+	}
+	return localPath, nil
+}
 
-		newBinary, httpRes, err := client.FirmwareBinaryAPI.CreateFirmwareBinary(ctx).
-			CreateFirmwareBinary(binaryCreate).
-			Execute()
-		if err := response_inspector.InspectResponse(httpRes, err); err != nil {
-			return err
-		}
+// Returns the path of a binary relative to the local binaries directory.
+func localBinaryRelativePath(vendor string, externalId string) string {
+	// For Supermicro, the ExternalId is the extracted .bin file name in .extracted/ directory
+	if vendor == VendorSupermicro {
+		return filepath.Join(".extracted", externalId)
+	}
+	return externalId
+}
 
-		binary.Id = newBinary.Id
+// Registers a binary in the catalog and records its new ID.
+func (vc *VendorCatalog) createFirmwareBinary(ctx context.Context, binary *sdk.FirmwareBinary) error {
+	binaryCreate := sdk.CreateFirmwareBinary{
+		CatalogId:              vc.CatalogInfo.Id,
+		ExternalId:             binary.ExternalId,
+		VendorInfoUrl:          binary.VendorInfoUrl,
+		VendorDownloadUrl:      binary.VendorDownloadUrl,
+		CacheDownloadUrl:       binary.CacheDownloadUrl,
+		Name:                   binary.Name,
+		PackageId:              binary.PackageId,
+		PackageVersion:         binary.PackageVersion,
+		RebootRequired:         binary.RebootRequired,
+		UpdateSeverity:         binary.UpdateSeverity,
+		VendorSupportedDevices: binary.VendorSupportedDevices,
+		VendorSupportedSystems: binary.VendorSupportedSystems,
+		VendorReleaseTimestamp: binary.VendorReleaseTimestamp,
+		Vendor:                 binary.Vendor,
+	}
+
+	if len(binaryCreate.Name) > 255 {
+		binaryCreate.Name = binaryCreate.Name[:255]
+		logger.Get().Warn().Msgf("Binary name %s exceeded 255 characters, truncating to %s", binary.Name, binaryCreate.Name)
+	}
+
+	logger.Get().Debug().Msgf("Creating firmware binary for catalog %d: %v", int(vc.CatalogInfo.Id), binaryCreate)
+
+	client := api.GetApiClient(ctx)
+
+	newBinary, httpRes, err := client.FirmwareBinaryAPI.CreateFirmwareBinary(ctx).
+		CreateFirmwareBinary(binaryCreate).
+		Execute()
+	if err := response_inspector.InspectResponse(httpRes, err); err != nil {
+		return err
 	}
 
+	binary.Id = newBinary.Id
+
 	return nil
 }
 
@@ -372,125 +528,141 @@ func (vc *VendorCatalog) getFilteredSystemModels(ctx context.Context) ([]string,
 	return systemModels, systemModelsEx, nil
 }
 
-// Downloads a binary from the vendor catalog
+// Downloads binaries from the vendor catalog on up to DownloadParallelism
+// goroutines, ahead of staging them, and reports a summary on stderr.
+func (vc *VendorCatalog) downloadBinaries(binaries []*sdk.FirmwareBinary) {
+	if !vc.DownloadBinaries || len(binaries) == 0 {
+		return
+	}
+
+	downloader := vc.getDownloader()
+	results := make([]downloadedBinary, len(binaries))
+
+	utils.ForEachParallel(len(binaries), vc.DownloadParallelism, func(i int) {
+		localPath, err := vc.downloadBinary(binaries[i])
+		results[i] = downloadedBinary{localPath: localPath, err: err}
+	}, func(done int) {
+		printDownloadProgress(done, len(binaries))
+	})
+
+	if vc.downloads == nil {
+		vc.downloads = map[*sdk.FirmwareBinary]downloadedBinary{}
+	}
+	for i, binary := range binaries {
+		vc.downloads[binary] = results[i]
+	}
+
+	summary := downloader.summary()
+	logger.Get().Info().Msg(summary)
+	if formatter.IsTextFormat() {
+		fmt.Fprintln(os.Stderr, summary)
+	}
+}
+
+func (vc *VendorCatalog) getDownloader() *downloadManager {
+	if vc.downloader == nil {
+		vc.downloader = newDownloadManager(vc.DownloadCachePath, vc.DownloadTimeout, vc.DownloadRetries, vc.DownloadTransport)
+	}
+	return vc.downloader
+}
+
+// Downloads a binary from the vendor catalog, or takes it from the download
+// cache, and returns its local path. The path is empty when the vendor URL
+// returns 404.
 func (vc *VendorCatalog) downloadBinary(binary *sdk.FirmwareBinary) (string, error) {
 	if binary.VendorDownloadUrl == "" {
 		return "", fmt.Errorf("no vendor download URL provided for binary %s", *binary.ExternalId)
 	}
 
+	downloader := vc.getDownloader()
+	algorithm, digest := vendorChecksum(binary.Vendor)
+
 	var err error
 	localPath := ""
 	if vc.VendorLocalBinariesPath != "" {
-		// Check if the local path exists
-		if _, err := os.Stat(vc.VendorLocalBinariesPath); os.IsNotExist(err) {
-			// Create the directory if it doesn't exist
-			logger.Get().Debug().Msgf("Vendor local binaries path %s does not exist, creating it", vc.VendorLocalBinariesPath)
-
-			err = os.MkdirAll(vc.VendorLocalBinariesPath, 0755)
-			if err != nil {
-				return "", fmt.Errorf("failed to create vendor local binaries path: %v", err)
-			}
-		}
-
 		localPath, err = filepath.Abs(filepath.Join(vc.VendorLocalBinariesPath, *binary.ExternalId))
 		if err != nil {
 			return "", fmt.Errorf("error getting download binary absolute path: %v", err)
 		}
-
-		// Ensure the directory exists
-		err = os.MkdirAll(filepath.Dir(localPath), 0755)
-		if err != nil {
-			return "", fmt.Errorf("failed to create directory for binary: %v", err)
-		}
+	} else if cachePath := downloader.cachePath(*binary.ExternalId, algorithm, digest); cachePath != "" {
+		// Upload straight from the cache
+		localPath = cachePath
 	} else {
-		// Create a temporary file to download the binary
+		// Download the binary to a temporary file
 		tempFile, err := os.CreateTemp("", "binary_*.bin")
 		if err != nil {
 			return "", fmt.Errorf("failed to create temp file: %v", err)
 		}
-		defer os.Remove(tempFile.Name())
 		tempFile.Close()
 
 		localPath = tempFile.Name()
+		vc.addTempFile(localPath)
 	}
 
 	logger.Get().Debug().Msgf("Downloading binary %s from %s to %s", *binary.ExternalId, binary.VendorDownloadUrl, localPath)
 
-	// Download binary from vendor
-	resp, err := http.Get(binary.VendorDownloadUrl)
+	outcome, err := downloader.fetch(*binary.ExternalId, binary.VendorDownloadUrl, localPath, algorithm, digest)
 	if err != nil {
-		return "", fmt.Errorf("error downloading binary from vendor URL: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusNotFound {
-			return "", nil // If the binary is not found, we can skip it
-		}
-
-		return "", fmt.Errorf("received non-OK response when downloading binary: %d", resp.StatusCode)
+		return "", err
 	}
-
-	// Create the file
-	outFile, err := os.OpenFile(localPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		return "", fmt.Errorf("failed to open output file: %v", err)
+	if outcome == downloadNotFound {
+		return "", nil // If the binary is not found, we can skip it
 	}
-	defer outFile.Close()
 
-	// Copy the body to the file
-	_, err = io.Copy(outFile, resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to save binary to file: %v", err)
+	if algorithm != "" {
+		logger.Get().Debug().Msgf("Binary %s (%s) matches its vendor %s checksum", *binary.ExternalId, outcome, algorithm)
+	} else {
+		logger.Get().Debug().Msgf("No vendor checksum published for binary %s (%s) - not verified", *binary.ExternalId, outcome)
 	}
 
-	resp.Body.Close()
-	outFile.Close()
-
 	return localPath, nil
 }
 
-func (vc *VendorCatalog) uploadBinaryToRepository(sftpClient *sftp.Client, binaryPath string, remotePath string) error {
-	srcFile, err := os.Open(binaryPath)
-	if err != nil {
-		return err
-	}
-	defer srcFile.Close()
-
-	logger.Get().Debug().Msgf("Uploading binary %s to remote path %s", binaryPath, remotePath)
+func (vc *VendorCatalog) addTempFile(localPath string) {
+	vc.tempFilesMu.Lock()
+	defer vc.tempFilesMu.Unlock()
 
-	err = sftpClient.MkdirAll(path.Dir(remotePath))
-	if err != nil {
-		return fmt.Errorf("failed to create remote directory: %v", err)
-	}
+	vc.tempFiles = append(vc.tempFiles, localPath)
+}
 
-	dstFile, err := sftpClient.Create(remotePath)
-	if err != nil {
-		return err
-	}
-	defer dstFile.Close()
+// removeTempFiles deletes the binaries downloaded to temporary files, with
+// any partial download left next to them.
+func (vc *VendorCatalog) removeTempFiles() {
+	vc.tempFilesMu.Lock()
+	defer vc.tempFilesMu.Unlock()
 
-	_, err = io.Copy(dstFile, srcFile)
-	if err != nil {
-		return err
+	for _, localPath := range vc.tempFiles {
+		for _, filePath := range []string{localPath, localPath + ".part"} {
+			if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+				logger.Get().Warn().Msgf("Failed to remove temporary file %s: %v", filePath, err)
+			}
+		}
 	}
-
-	return nil
+	vc.tempFiles = nil
 }
 
-// Reads a PEM private key file
-func privateKeyFile(file string) (ssh.AuthMethod, error) {
-	key, err := os.ReadFile(file)
-	if err != nil {
-		return nil, err
+// Verifies a local binary against the checksum the vendor published, if any.
+// Supermicro checksums cover the archive the binary was extracted from, which
+// is verified when the catalog is processed.
+func (vc *VendorCatalog) verifyLocalBinary(binary *sdk.FirmwareBinary, localPath string) error {
+	if vc.CatalogInfo.Vendor == VendorSupermicro {
+		return nil
 	}
 
-	signer, err := ssh.ParsePrivateKey(key)
-	if err != nil {
-		return nil, err
+	algorithm, digest := vendorChecksum(binary.Vendor)
+	if algorithm == "" {
+		logger.Get().Debug().Msgf("No vendor checksum published for binary %s - not verified", *binary.ExternalId)
+		return nil
 	}
 
-	return ssh.PublicKeys(signer), nil
+	err := verifyFileChecksum(localPath, algorithm, digest)
+	if errors.Is(err, errChecksumMismatch) {
+		logger.Get().Warn().Msgf("Deleting corrupt binary %s at %s", *binary.ExternalId, localPath)
+		if removeErr := os.Remove(localPath); removeErr != nil {
+			logger.Get().Warn().Msgf("Failed to delete %s: %v", localPath, removeErr)
+		}
+	}
+	return err
 }
 
 func downloadGzipCatalog(url string, filePath string) error {
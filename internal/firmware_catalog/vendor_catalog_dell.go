@@ -67,6 +67,15 @@ type dellSupportedSystems struct {
 	Brands []dellBrand `xml:"Brand"`
 }
 
+type dellHash struct {
+	Algorithm string `xml:"algorithm,attr"`
+	Value     string `xml:",chardata"`
+}
+
+type dellCryptography struct {
+	Hashes []dellHash `xml:"Hash"`
+}
+
 type dellSoftwareComponent struct {
 	XMLName          xml.Name             `xml:"SoftwareComponent"`
 	DateTime         string               `xml:"dateTime,attr"`
@@ -78,6 +87,7 @@ type dellSoftwareComponent struct {
 	ReleaseDate      string               `xml:"releaseDate,attr"`
 	Size             string               `xml:"size,attr"`
 	HashMD5          string               `xml:"hashMD5,attr"`
+	Cryptography     dellCryptography     `xml:"Cryptography"`
 	Category         dellCategory         `xml:"Category"`
 	ReleaseID        string               `xml:"releaseID,attr"`
 	PackageType      string               `xml:"packageType,attr"`
@@ -178,9 +188,9 @@ func (vc *VendorCatalog) processDellCatalog(ctx context.Context) error {
 				systemInfo := map[string]interface{}{
 					"id": model.SystemID,
 					// "idType":      model.SystemIDType,
-					// "brandName":   brand.Display,
+					"brandName": brand.Display,
 					// "brandPrefix": brand.Prefix,
-					// "modelName":   model.Display,
+					"modelName": model.Display,
 				}
 
 				supportedSystems = append(supportedSystems, systemInfo)
@@ -244,6 +254,10 @@ func (vc *VendorCatalog) processDellCatalog(ctx context.Context) error {
 				"packageType": component.PackageType,
 			},
 		}
+		setBinaryChecksum(firmwareBinary.Vendor, component.HashMD5)
+		for _, hash := range component.Cryptography.Hashes {
+			setBinaryChecksum(firmwareBinary.Vendor, hash.Algorithm+":"+hash.Value)
+		}
 
 		vc.Binaries = append(vc.Binaries, &firmwareBinary)
 	}
@@ -43,6 +43,7 @@ type hpCatalogTemplate struct {
 	RebootRequired       string        `json:"reboot_required"`
 	Target               StringOrSlice `json:"target"`
 	Version              string        `json:"version"`
+	Sha256Sum            string        `json:"sha256sum"`
 }
 
 func (vc *VendorCatalog) processHpeCatalog(ctx context.Context) error {
@@ -160,6 +161,7 @@ func (vc *VendorCatalog) processHpeCatalog(ctx context.Context) error {
 			VendorReleaseTimestamp: nil,
 			Vendor:                 map[string]any{},
 		}
+		setBinaryChecksum(firmwareBinary.Vendor, packageInfo.Sha256Sum)
 
 		vc.Binaries = append(vc.Binaries, &firmwareBinary)
 	}
@@ -93,11 +93,13 @@ func (vc *VendorCatalog) processLenovoCatalog(ctx context.Context) error {
 			}
 
 			downloadUrl := ""
+			fileHash := ""
 			description := ""
 			var infoUrl *string
 			for _, file := range softwareUpdate.Files {
 				if file.Type == lenovoSoftwareUpdateTypeFix {
 					downloadUrl = file.URL
+					fileHash = file.FileHash
 					continue
 				}
 				if file.Type == lenovoSoftwareUpdateTypeInstallXML {
@@ -125,6 +127,7 @@ func (vc *VendorCatalog) processLenovoCatalog(ctx context.Context) error {
 			componentVendorConfiguration := map[string]any{
 				"requires": softwareUpdate.RequisitesFixIDs,
 			}
+			setBinaryChecksum(componentVendorConfiguration, fileHash)
 
 			supportedDevices := []map[string]interface{}{
 				{
@@ -122,6 +122,15 @@ func (vc *VendorCatalog) processSupermicroCatalog(ctx context.Context) error {
 			timestamp, _ = time.Parse("2006-01-02", component.ReleaseDate)
 		}
 
+		// The published checksums cover the archive, not the extracted image
+		if algorithm, digest := vendorChecksum(vendorConfiguration); algorithm != "" {
+			if err := verifyFileChecksum(binaryLocalPath, algorithm, digest); err != nil {
+				logger.Get().Warn().Msgf("Skipping component %s: %v", componentId, err)
+				vc.ChecksumFailures = append(vc.ChecksumFailures, component.FileName)
+				continue
+			}
+		}
+
 		// Extract the .bin file from the .zip archive
 		binFileName, err := vc.extractBinFileFromZip(binaryLocalPath, component.FileName)
 		if err != nil {
@@ -1,6 +1,7 @@
 package firmware_catalog
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"os"
@@ -89,6 +90,74 @@ func TestDownloadBinaryWithMockResponses(t *testing.T) {
 			t.Errorf("Expected content 'mocked binary data', got: %s", string(content))
 		}
 	})
+
+	t.Run("MockHTTPChecksumMismatch", func(t *testing.T) {
+		// Mock client that returns content not matching the published checksum
+		http.DefaultClient = &http.Client{
+			Transport: &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader("truncated binary")),
+					}, nil
+				},
+			},
+		}
+
+		vc := &VendorCatalog{
+			VendorLocalBinariesPath: tempDir,
+		}
+		binary := &sdk.FirmwareBinary{
+			ExternalId:        sdk.PtrString("corrupt-binary"),
+			VendorDownloadUrl: "http://example.com/binary",
+			Vendor:            map[string]any{"md5": "0123456789abcdef0123456789abcdef"},
+		}
+
+		_, err := vc.downloadBinary(binary)
+
+		if !errors.Is(err, errChecksumMismatch) {
+			t.Errorf("Expected checksum mismatch, got: %v", err)
+		}
+
+		// The corrupt download is deleted
+		if _, err := os.Stat(filepath.Join(tempDir, "corrupt-binary")); !os.IsNotExist(err) {
+			t.Errorf("Expected corrupt download to be deleted, got: %v", err)
+		}
+	})
+
+	t.Run("MockHTTPTempFile", func(t *testing.T) {
+		http.DefaultClient = &http.Client{
+			Transport: &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader("mocked binary data")),
+					}, nil
+				},
+			},
+		}
+		t.Setenv("TMPDIR", t.TempDir())
+
+		// Without a local binaries path or cache the binary goes to a temp file
+		vc := &VendorCatalog{}
+		binary := &sdk.FirmwareBinary{
+			ExternalId:        sdk.PtrString("test-binary"),
+			VendorDownloadUrl: "http://example.com/binary",
+		}
+
+		localPath, err := vc.downloadBinary(binary)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if _, err := os.Stat(localPath); err != nil {
+			t.Fatalf("Expected downloaded temp file, got: %v", err)
+		}
+
+		vc.removeTempFiles()
+		if _, err := os.Stat(localPath); !os.IsNotExist(err) {
+			t.Errorf("Expected temp file to be removed, got: %v", err)
+		}
+	})
 }
 
 // Helper types for mocking
@@ -115,3 +184,20 @@ func (e *mockNetworkError) Timeout() bool {
 func (e *mockNetworkError) Temporary() bool {
 	return true
 }
+
+func TestVerifyLocalBinaryDeletesCorruptFile(t *testing.T) {
+	vc := &VendorCatalog{CatalogInfo: sdk.FirmwareCatalog{Vendor: VendorDell}}
+	binary := &sdk.FirmwareBinary{
+		ExternalId: sdk.PtrString("test-binary"),
+		Vendor:     map[string]any{ChecksumSHA256: otherSHA256},
+	}
+
+	localPath := writeBinary(t)
+	err := vc.verifyLocalBinary(binary, localPath)
+	if !errors.Is(err, errChecksumMismatch) {
+		t.Fatalf("verifyLocalBinary() = %v, want checksum mismatch", err)
+	}
+	if _, err := os.Stat(localPath); !os.IsNotExist(err) {
+		t.Errorf("corrupt binary kept: %v", err)
+	}
+}
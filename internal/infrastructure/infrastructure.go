@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/metalsoft-io/metalcloud-cli/internal/job"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
@@ -222,6 +223,15 @@ func InfrastructureDeploy(ctx context.Context, infrastructureIdOrLabel string, a
 
 	client := api.GetApiClient(ctx)
 
+	// Jobs above the watermark were started by this deploy
+	var jobWatermark int64
+	if job.WaitRequested() {
+		jobWatermark, err = job.JobWatermark(ctx, infrastructureJobsFilter(int64(infrastructureInfo.Id)))
+		if err != nil {
+			return err
+		}
+	}
+
 	infrastructureInfo, httpRes, err := client.InfrastructureAPI.
 		DeployInfrastructure(ctx, int64(infrastructureInfo.Id)).
 		InfrastructureDeployOptions(infrastructureDeployOptions).
@@ -230,7 +240,46 @@ func InfrastructureDeploy(ctx context.Context, infrastructureIdOrLabel string, a
 		return err
 	}
 
-	return formatter.PrintResult(infrastructureInfo, &infrastructurePrintConfig)
+	if err := formatter.PrintResult(infrastructureInfo, &infrastructurePrintConfig); err != nil {
+		return err
+	}
+
+	if !job.WaitRequested() {
+		return nil
+	}
+
+	return waitForInfrastructureDeploy(ctx, int64(infrastructureInfo.Id), jobWatermark)
+}
+
+func infrastructureJobsFilter(infrastructureId int64) string {
+	return fmt.Sprintf("filter.infrastructureId=$eq:%d", infrastructureId)
+}
+
+// waitForInfrastructureDeploy polls the infrastructure until its deploy is
+// finished. The deploy response carries no job id, so failures are detected
+// from the jobs of the infrastructure above the watermark taken before the
+// deploy started.
+func waitForInfrastructureDeploy(ctx context.Context, infrastructureId int64, jobWatermark int64) error {
+	path := fmt.Sprintf("/api/v2/infrastructures/%d", infrastructureId)
+	filter := infrastructureJobsFilter(infrastructureId)
+
+	return job.Poll(ctx, fmt.Sprintf("deploy of infrastructure %d", infrastructureId), func(ctx context.Context) (bool, string, error) {
+		var infrastructure struct {
+			Config struct {
+				DeployStatus string `json:"deployStatus"`
+			} `json:"config"`
+		}
+		if err := job.GetForWait(ctx, path, &infrastructure); err != nil {
+			return false, "", err
+		}
+
+		status := infrastructure.Config.DeployStatus
+		if err := job.FailedJobsError(ctx, filter, jobWatermark); err != nil {
+			return false, status, err
+		}
+
+		return status == "finished", status, nil
+	})
 }
 
 func InfrastructureRevert(ctx context.Context, infrastructureIdOrLabel string) error {
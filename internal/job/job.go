@@ -243,7 +243,7 @@ func jobRequest(ctx context.Context, method string, path string, body interface{
 	}
 
 	// Read and re-wrap the body so that response_inspector.InspectResponse
-	// can format it properly (it uses %s on httpRes.Body).
+	// can still parse the error details from it.
 	respBody, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	resp.Body = io.NopCloser(bytes.NewBuffer(respBody))
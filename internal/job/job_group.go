@@ -7,6 +7,7 @@ import (
 	"time"
 
 	sdk "github.com/metalsoft-io/metalcloud-sdk-go"
+	"github.com/spf13/viper"
 
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
@@ -132,23 +133,27 @@ func JobGroupWait(ctx context.Context, groupId string) error {
 
 	fmt.Printf("Waiting for job group %d to finish...\n", id)
 
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-		}
+	// Unlike --wait, this command only gives up when --wait-timeout is given
+	timeout := time.Duration(0)
+	if viper.IsSet(ConfigWaitTimeout) {
+		timeout = viper.GetDuration(ConfigWaitTimeout)
+	}
 
+	err = pollWithTimeout(ctx, fmt.Sprintf("job group %d", id), timeout, func(ctx context.Context) (bool, string, error) {
 		group, httpRes, err = client.JobAPI.GetJobGroup(ctx, id).Execute()
 		if err := response_inspector.InspectResponse(httpRes, err); err != nil {
-			fmt.Printf("Error while waiting for job group to finish: %s", err.Error())
-		} else if jobGroupFinished(group) {
-			return formatter.PrintResult(group, &jobGroupPrintConfig)
+			if httpRes != nil && !isRetryableStatus(httpRes.StatusCode) {
+				return false, "", err
+			}
+			return false, "", retryableError{err}
 		}
+		return jobGroupFinished(group), "", nil
+	})
+	if err != nil {
+		return err
 	}
+
+	return formatter.PrintResult(group, &jobGroupPrintConfig)
 }
 
 func jobGroupFinished(group *sdk.JobGroup) bool {
@@ -0,0 +1,469 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/response_inspector"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/utils"
+)
+
+const (
+	ConfigWait        = "wait"
+	ConfigWaitTimeout = "wait-timeout"
+)
+
+// Polling starts fast so short jobs return quickly and backs off so that long
+// deploys do not hammer the API.
+var (
+	waitInitialInterval = time.Second
+	waitMaxInterval     = 30 * time.Second
+)
+
+const waitBackoffFactor = 1.5
+
+var (
+	jobSucceededStatuses = map[string]bool{"returned_success": true, "completed": true, "finished": true, "skipped": true}
+	jobFailedStatuses    = map[string]bool{"thrown_error": true, "failed": true, "killed": true, "cancelled": true}
+)
+
+type jobExceptionRaw struct {
+	JobId     interface{} `json:"jobId"`
+	Exception string      `json:"exception"`
+}
+
+// retryableError marks a polling error that does not mean the job failed,
+// such as a dropped connection or a 5xx from the API.
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string {
+	return e.err.Error()
+}
+
+// WaitRequested reports whether --wait was given.
+func WaitRequested() bool {
+	return viper.GetBool(ConfigWait)
+}
+
+// WaitForResult follows the job or job group referenced by the result of an
+// asynchronous operation when --wait was given. The result may be an SDK model
+// (e.g. JobInfo or a registration response) or the *http.Response of a call
+// without a typed body.
+func WaitForResult(ctx context.Context, result interface{}) error {
+	if !WaitRequested() {
+		return nil
+	}
+
+	jobId, jobGroupId := findJobReference(result)
+	switch {
+	case jobGroupId != "":
+		return WaitForJobGroup(ctx, jobGroupId)
+	case jobId != "":
+		return WaitForJob(ctx, jobId)
+	}
+
+	logger.Get().Warn().Msg("The operation did not return a job to wait for")
+	printWaitProgress("Nothing to wait for: the operation did not return a job.")
+	return nil
+}
+
+// WaitForJob polls the job until it reaches a final status. A failed job is
+// returned as an error carrying the job exceptions.
+func WaitForJob(ctx context.Context, jobId string) error {
+	if err := validateJobId(jobId); err != nil {
+		return err
+	}
+
+	logger.Get().Info().Msgf("Waiting for job '%s' to finish", jobId)
+
+	return Poll(ctx, fmt.Sprintf("job %s", jobId), func(ctx context.Context) (bool, string, error) {
+		var job jobRaw
+		if err := getForWait(ctx, fmt.Sprintf("/api/v2/jobs/%s", jobId), &job); err != nil {
+			return false, "", err
+		}
+
+		status := ""
+		if job.Status != nil {
+			status = *job.Status
+		}
+
+		switch {
+		case jobSucceededStatuses[status]:
+			return true, status, nil
+		case jobFailedStatuses[status]:
+			return true, status, jobFailedError(ctx, []jobRaw{job})
+		}
+
+		return false, status, nil
+	})
+}
+
+// WaitForJobGroup polls the job group until it is finished and then checks
+// that none of its jobs failed.
+func WaitForJobGroup(ctx context.Context, groupId string) error {
+	if _, err := getJobGroupId(groupId); err != nil {
+		return err
+	}
+
+	logger.Get().Info().Msgf("Waiting for job group '%s' to finish", groupId)
+
+	return Poll(ctx, fmt.Sprintf("job group %s", groupId), func(ctx context.Context) (bool, string, error) {
+		var group struct {
+			FinishedTimestamp *string `json:"finishedTimestamp"`
+		}
+		if err := getForWait(ctx, fmt.Sprintf("/api/v2/job-groups/%s", groupId), &group); err != nil {
+			return false, "", err
+		}
+
+		finished := group.FinishedTimestamp != nil && *group.FinishedTimestamp != ""
+
+		// The jobs are only needed for progress while the group runs, so a
+		// failure to list them is fatal only once the group has finished.
+		jobs, err := listJobsForWait(ctx, "filter.jobGroupId=$eq:"+groupId)
+		if err != nil {
+			if finished {
+				return true, "", err
+			}
+			logger.Get().Warn().Err(err).Msgf("Failed to list the jobs of job group '%s'", groupId)
+			return false, "", nil
+		}
+
+		failed := []jobRaw{}
+		pending := 0
+		for _, job := range jobs {
+			status := ""
+			if job.Status != nil {
+				status = *job.Status
+			}
+			switch {
+			case jobFailedStatuses[status]:
+				failed = append(failed, job)
+			case !jobSucceededStatuses[status]:
+				pending++
+			}
+		}
+
+		status := fmt.Sprintf("%d of %d jobs done", len(jobs)-pending, len(jobs))
+		if !finished {
+			return false, status, nil
+		}
+		if len(failed) > 0 {
+			return true, status, jobFailedError(ctx, failed)
+		}
+
+		return true, status, nil
+	})
+}
+
+// Poll calls check with exponential backoff until it reports done, returns a
+// non-retryable error, or the --wait-timeout elapses. The status returned by
+// check is printed as progress whenever it changes.
+func Poll(ctx context.Context, what string, check func(ctx context.Context) (done bool, status string, err error)) error {
+	return pollWithTimeout(ctx, what, viper.GetDuration(ConfigWaitTimeout), check)
+}
+
+// pollWithTimeout is Poll with an explicit timeout, 0 waiting forever.
+func pollWithTimeout(ctx context.Context, what string, timeout time.Duration, check func(ctx context.Context) (done bool, status string, err error)) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	interval := waitInitialInterval
+	lastStatus := ""
+
+	for {
+		done, status, err := check(ctx)
+
+		var retryable retryableError
+		switch {
+		case err != nil && errors.As(err, &retryable) && ctx.Err() == nil:
+			logger.Get().Warn().Err(err).Msgf("Error while waiting for %s, retrying", what)
+		case err != nil && ctx.Err() == nil:
+			return err
+		case status != "" && status != lastStatus:
+			printWaitProgress(fmt.Sprintf("Waiting for %s: %s (%s elapsed)", what, status, time.Since(start).Round(time.Second)))
+			lastStatus = status
+		}
+
+		if done && err == nil {
+			printWaitProgress(fmt.Sprintf("Finished waiting for %s after %s", what, time.Since(start).Round(time.Second)))
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err := fmt.Errorf("timed out after %s waiting for %s", timeout, what)
+				logger.Get().Error().Err(err).Msg("")
+				return err
+			}
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		interval = time.Duration(float64(interval) * waitBackoffFactor)
+		if interval > waitMaxInterval {
+			interval = waitMaxInterval
+		}
+	}
+}
+
+// findJobReference extracts the job and job group ids from an operation
+// result, looking at the top level and at an embedded "jobInfo" object.
+func findJobReference(result interface{}) (jobId string, jobGroupId string) {
+	var data []byte
+	switch r := result.(type) {
+	case nil:
+		return "", ""
+	case *http.Response:
+		if r == nil || r.Body == nil {
+			return "", ""
+		}
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return "", ""
+		}
+		data = body
+	default:
+		body, err := json.Marshal(result)
+		if err != nil {
+			return "", ""
+		}
+		data = body
+	}
+
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return "", ""
+	}
+
+	candidates := []map[string]interface{}{values}
+	if jobInfo, ok := values["jobInfo"].(map[string]interface{}); ok {
+		candidates = append(candidates, jobInfo)
+	}
+
+	for _, candidate := range candidates {
+		if jobGroupId == "" {
+			jobGroupId = idToString(candidate["jobGroupId"])
+		}
+		if jobId == "" {
+			jobId = idToString(candidate["jobId"])
+		}
+	}
+
+	return jobId, jobGroupId
+}
+
+func idToString(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		if v <= 0 {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
+
+// jobFailedError builds the error returned for failed jobs, including the
+// exceptions recorded for each of them.
+func jobFailedError(ctx context.Context, jobs []jobRaw) error {
+	messages := []string{}
+	for _, job := range jobs {
+		jobId := idToString(job.JobId)
+		status := ""
+		if job.Status != nil {
+			status = *job.Status
+		}
+
+		message := fmt.Sprintf("job %s failed with status '%s'", jobId, status)
+
+		exceptions, err := listJobExceptionsForWait(ctx, jobId)
+		if err != nil {
+			logger.Get().Warn().Err(err).Msgf("Failed to get exceptions of job '%s'", jobId)
+		}
+		for _, exception := range exceptions {
+			message += "\n  " + strings.TrimSpace(exception.Exception)
+		}
+
+		messages = append(messages, message)
+	}
+
+	err := errors.New(strings.Join(messages, "\n"))
+	logger.Get().Error().Err(err).Msg("")
+	return err
+}
+
+func listJobsForWait(ctx context.Context, filter string) ([]jobRaw, error) {
+	rawItems, _, err := utils.FetchAllPagesRaw(func(page float32) (*http.Response, error) {
+		return api.DoJSONRequest(ctx, http.MethodGet, fmt.Sprintf("/api/v2/jobs?%s&page=%d&limit=100", filter, int(page)), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.UnmarshalRawItems[jobRaw](rawItems)
+}
+
+func listJobExceptionsForWait(ctx context.Context, jobId string) ([]jobExceptionRaw, error) {
+	rawItems, _, err := utils.FetchAllPagesRaw(func(page float32) (*http.Response, error) {
+		return api.DoJSONRequest(ctx, http.MethodGet, fmt.Sprintf("/api/v2/jobs/%s/exceptions?page=%d&limit=100", url.PathEscape(jobId), int(page)), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.UnmarshalRawItems[jobExceptionRaw](rawItems)
+}
+
+// getForWait reads a resource while polling. Connection errors and server
+// side errors are retryable, client errors (e.g. a wrong id) are not.
+func getForWait(ctx context.Context, path string, result interface{}) error {
+	httpRes, err := api.DoJSONRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return retryableError{err}
+	}
+	defer httpRes.Body.Close()
+
+	if isRetryableStatus(httpRes.StatusCode) {
+		return retryableError{response_inspector.InspectResponse(httpRes, nil)}
+	}
+	if err := response_inspector.InspectResponse(httpRes, nil); err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(httpRes.Body)
+	if err != nil {
+		return retryableError{fmt.Errorf("failed to read response body: %w", err)}
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to parse response of '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests
+}
+
+// GetForWait is getForWait for the wait helpers of other packages, e.g. to
+// poll a resource status inside Poll.
+func GetForWait(ctx context.Context, path string, result interface{}) error {
+	return getForWait(ctx, path, result)
+}
+
+// JobWatermark returns the id of the newest job matching filter (e.g.
+// "filter.infrastructureId=$eq:12"), or 0 when there is none. Job ids are
+// assigned by the server in increasing order, so a watermark taken before an
+// operation identifies the jobs it started without comparing clocks.
+func JobWatermark(ctx context.Context, filter string) (int64, error) {
+	jobs, _, err := listNewestJobsForWait(ctx, filter, 1, 1)
+	if err != nil {
+		return 0, err
+	}
+	if len(jobs) == 0 {
+		return 0, nil
+	}
+
+	return jobNumericId(jobs[0]), nil
+}
+
+// FailedJobsError returns an error carrying the exceptions of the jobs that
+// match filter, have an id above watermark and failed, or nil when there are
+// none. Jobs are listed newest first and only down to the watermark.
+func FailedJobsError(ctx context.Context, filter string, watermark int64) error {
+	failed := []jobRaw{}
+
+	for page := 1; ; page++ {
+		jobs, more, err := listNewestJobsForWait(ctx, filter, page, 100)
+		if err != nil {
+			return retryableError{err}
+		}
+
+		reachedWatermark := false
+		for _, job := range jobs {
+			if jobNumericId(job) <= watermark {
+				reachedWatermark = true
+				break
+			}
+			if job.Status != nil && jobFailedStatuses[*job.Status] {
+				failed = append(failed, job)
+			}
+		}
+
+		if reachedWatermark || !more {
+			break
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return jobFailedError(ctx, failed)
+}
+
+// listNewestJobsForWait returns one page of the jobs matching filter, newest
+// first, and whether there are more pages.
+func listNewestJobsForWait(ctx context.Context, filter string, page int, limit int) ([]jobRaw, bool, error) {
+	var result struct {
+		Data []jobRaw `json:"data"`
+		Meta struct {
+			TotalPages *int `json:"totalPages"`
+		} `json:"meta"`
+	}
+	err := getForWait(ctx, fmt.Sprintf("/api/v2/jobs?%s&sortBy=jobId:DESC&page=%d&limit=%d", filter, page, limit), &result)
+	if err != nil {
+		return nil, false, err
+	}
+
+	more := len(result.Data) == limit
+	if result.Meta.TotalPages != nil {
+		more = *result.Meta.TotalPages > page
+	}
+
+	return result.Data, more, nil
+}
+
+func jobNumericId(job jobRaw) int64 {
+	id, err := strconv.ParseInt(idToString(job.JobId), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// printWaitProgress writes progress to stderr so that it does not mix with
+// the command output, and only in text mode.
+func printWaitProgress(message string) {
+	if formatter.IsTextFormat() {
+		fmt.Fprintln(os.Stderr, message)
+	}
+}
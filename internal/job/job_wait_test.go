@@ -0,0 +1,272 @@
+package job
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/metalsoft-io/metalcloud-cli/internal/testutils"
+)
+
+// setupWait enables --wait with a short polling interval for the test.
+func setupWait(t *testing.T, timeout time.Duration) {
+	t.Helper()
+
+	initialInterval, maxInterval := waitInitialInterval, waitMaxInterval
+	waitInitialInterval, waitMaxInterval = 10*time.Millisecond, 20*time.Millisecond
+	viper.Set(ConfigWait, true)
+	viper.Set(ConfigWaitTimeout, timeout)
+
+	t.Cleanup(func() {
+		waitInitialInterval, waitMaxInterval = initialInterval, maxInterval
+		viper.Set(ConfigWait, false)
+		viper.Set(ConfigWaitTimeout, time.Duration(0))
+	})
+}
+
+func TestWaitForResult_NotRequested(t *testing.T) {
+	var calls int32
+	ts := testutils.NewTestServer(map[string]http.HandlerFunc{
+		"/api/v2/jobs/7": func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+		},
+	})
+	defer ts.Close()
+
+	ctx := testutils.SetupTestContext(ts.URL)
+	if err := WaitForResult(ctx, map[string]interface{}{"jobId": 7}); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Errorf("expected no API calls without --wait, got %d", got)
+	}
+}
+
+func TestWaitForResult_Job(t *testing.T) {
+	setupWait(t, time.Minute)
+
+	var calls int32
+	ts := testutils.NewTestServer(map[string]http.HandlerFunc{
+		"/api/v2/jobs/7": func(w http.ResponseWriter, r *http.Request) {
+			status := "running"
+			if atomic.AddInt32(&calls, 1) >= 3 {
+				status = "returned_success"
+			}
+			testutils.RawHandler(http.StatusOK, `{"jobId":7,"status":"`+status+`"}`)(w, r)
+		},
+	})
+	defer ts.Close()
+
+	ctx := testutils.SetupTestContext(ts.URL)
+	result := map[string]interface{}{"serverId": 1, "jobInfo": map[string]interface{}{"jobId": 7}}
+	if err := WaitForResult(ctx, result); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("expected 3 polls, got %d", got)
+	}
+}
+
+func TestWaitForJob_FailedWithExceptions(t *testing.T) {
+	setupWait(t, time.Minute)
+
+	ts := testutils.NewTestServer(map[string]http.HandlerFunc{
+		"/api/v2/jobs/7": testutils.RawHandler(http.StatusOK, `{"jobId":7,"status":"thrown_error"}`),
+		"/api/v2/jobs/7/exceptions": testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse([]interface{}{
+			map[string]interface{}{"exceptionId": 1, "jobId": 7, "exception": "switch unreachable"},
+		}, 1, 1)),
+	})
+	defer ts.Close()
+
+	ctx := testutils.SetupTestContext(ts.URL)
+	err := WaitForJob(ctx, "7")
+	if err == nil {
+		t.Fatal("expected error for failed job, got nil")
+	}
+	if !strings.Contains(err.Error(), "thrown_error") || !strings.Contains(err.Error(), "switch unreachable") {
+		t.Errorf("expected status and exception in error, got: %v", err)
+	}
+}
+
+func TestWaitForJobGroup(t *testing.T) {
+	t.Run("Succeeded", func(t *testing.T) {
+		setupWait(t, time.Minute)
+
+		var calls int32
+		ts := testutils.NewTestServer(map[string]http.HandlerFunc{
+			"/api/v2/job-groups/3": func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) >= 2 {
+					testutils.RawHandler(http.StatusOK, `{"id":3,"finishedTimestamp":"2024-01-01T01:00:00Z"}`)(w, r)
+					return
+				}
+				testutils.RawHandler(http.StatusOK, `{"id":3}`)(w, r)
+			},
+			"/api/v2/jobs": func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query().Get("filter.jobGroupId"); got != "$eq:3" {
+					t.Errorf("expected job group filter, got %q", got)
+				}
+				testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse([]interface{}{
+					map[string]interface{}{"jobId": 1, "status": "returned_success"},
+				}, 1, 1))(w, r)
+			},
+		})
+		defer ts.Close()
+
+		ctx := testutils.SetupTestContext(ts.URL)
+		if err := WaitForResult(ctx, map[string]interface{}{"jobId": 1, "jobGroupId": 3}); err != nil {
+			t.Fatalf("expected nil error, got: %v", err)
+		}
+	})
+
+	t.Run("FailedJob", func(t *testing.T) {
+		setupWait(t, time.Minute)
+
+		ts := testutils.NewTestServer(map[string]http.HandlerFunc{
+			"/api/v2/job-groups/3": testutils.RawHandler(http.StatusOK, `{"id":3,"finishedTimestamp":"2024-01-01T01:00:00Z"}`),
+			"/api/v2/jobs": testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse([]interface{}{
+				map[string]interface{}{"jobId": 1, "status": "returned_success"},
+				map[string]interface{}{"jobId": 2, "status": "killed"},
+			}, 1, 1)),
+			"/api/v2/jobs/2/exceptions": testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse([]interface{}{}, 1, 1)),
+		})
+		defer ts.Close()
+
+		ctx := testutils.SetupTestContext(ts.URL)
+		err := WaitForJobGroup(ctx, "3")
+		if err == nil || !strings.Contains(err.Error(), "job 2 failed") {
+			t.Errorf("expected failure of job 2, got: %v", err)
+		}
+	})
+}
+
+func TestFailedJobsError(t *testing.T) {
+	var pages []string
+	ts := testutils.NewTestServer(map[string]http.HandlerFunc{
+		"/api/v2/jobs": func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			if query.Get("sortBy") != "jobId:DESC" || query.Get("filter.infrastructureId") != "$eq:12" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			pages = append(pages, query.Get("page"))
+
+			// Newest first: jobs 5 and 4 were started by the operation, the
+			// failed job 3 predates the watermark
+			jobs := []interface{}{
+				map[string]interface{}{"jobId": 5, "status": "running"},
+				map[string]interface{}{"jobId": 4, "status": "thrown_error"},
+				map[string]interface{}{"jobId": 3, "status": "thrown_error"},
+			}
+			if query.Get("limit") == "1" {
+				jobs = jobs[:1]
+			}
+			testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse(jobs, 1, 2))(w, r)
+		},
+		"/api/v2/jobs/4/exceptions": testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse([]interface{}{}, 1, 1)),
+	})
+	defer ts.Close()
+
+	ctx := testutils.SetupTestContext(ts.URL)
+
+	watermark, err := JobWatermark(ctx, "filter.infrastructureId=$eq:12")
+	if err != nil || watermark != 5 {
+		t.Fatalf("JobWatermark() = %d, %v; want 5", watermark, err)
+	}
+
+	if err := FailedJobsError(ctx, "filter.infrastructureId=$eq:12", 5); err != nil {
+		t.Errorf("expected no failed jobs above the watermark, got: %v", err)
+	}
+
+	pages = nil
+	err = FailedJobsError(ctx, "filter.infrastructureId=$eq:12", 3)
+	if err == nil || !strings.Contains(err.Error(), "job 4 failed") || strings.Contains(err.Error(), "job 3") {
+		t.Errorf("expected only job 4 to fail, got: %v", err)
+	}
+	if len(pages) != 1 {
+		t.Errorf("expected listing to stop at the watermark, got pages %v", pages)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	t.Run("Timeout", func(t *testing.T) {
+		setupWait(t, 50*time.Millisecond)
+
+		err := Poll(context.Background(), "test", func(ctx context.Context) (bool, string, error) {
+			return false, "running", nil
+		})
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("expected timeout error, got: %v", err)
+		}
+	})
+
+	t.Run("RetriesTransientErrors", func(t *testing.T) {
+		setupWait(t, time.Minute)
+
+		calls := 0
+		err := Poll(context.Background(), "test", func(ctx context.Context) (bool, string, error) {
+			calls++
+			if calls < 3 {
+				return false, "", retryableError{io.ErrUnexpectedEOF}
+			}
+			return true, "done", nil
+		})
+		if err != nil {
+			t.Fatalf("expected nil error, got: %v", err)
+		}
+		if calls != 3 {
+			t.Errorf("expected 3 calls, got %d", calls)
+		}
+	})
+
+	t.Run("BackoffIsCapped", func(t *testing.T) {
+		setupWait(t, time.Minute)
+
+		var last time.Time
+		gaps := []time.Duration{}
+		err := Poll(context.Background(), "test", func(ctx context.Context) (bool, string, error) {
+			if !last.IsZero() {
+				gaps = append(gaps, time.Since(last))
+			}
+			last = time.Now()
+			return len(gaps) == 4, "", nil
+		})
+		if err != nil {
+			t.Fatalf("expected nil error, got: %v", err)
+		}
+		if gaps[0] < waitInitialInterval || gaps[3] < waitMaxInterval {
+			t.Errorf("expected intervals to grow up to %s, got %v", waitMaxInterval, gaps)
+		}
+	})
+}
+
+func TestFindJobReference(t *testing.T) {
+	res := &http.Response{Body: io.NopCloser(strings.NewReader(`{"jobId":12,"jobGroupId":"34"}`))}
+	jobId, jobGroupId := findJobReference(res)
+	if jobId != "12" || jobGroupId != "34" {
+		t.Errorf("expected 12/34, got %q/%q", jobId, jobGroupId)
+	}
+
+	// The body must still be readable by the caller.
+	if body, _ := io.ReadAll(res.Body); len(body) == 0 {
+		t.Error("expected response body to be preserved")
+	}
+
+	jobId, jobGroupId = findJobReference(struct {
+		JobInfo struct {
+			JobId float32 `json:"jobId"`
+		} `json:"jobInfo"`
+	}{})
+	if jobId != "" || jobGroupId != "" {
+		t.Errorf("expected no reference for zero ids, got %q/%q", jobId, jobGroupId)
+	}
+
+	jobId, _ = findJobReference(&http.Response{Body: http.NoBody})
+	if jobId != "" {
+		t.Errorf("expected no reference for empty body, got %q", jobId)
+	}
+}
@@ -0,0 +1,439 @@
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/metalsoft-io/metalcloud-cli/internal/infrastructure"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/response_inspector"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/utils"
+)
+
+const deletedServiceStatus = "deleted"
+
+// Diff prints the plan that Apply would execute for the manifest, without
+// changing anything.
+func Diff(ctx context.Context, content []byte, prune bool) error {
+	logger.Get().Info().Msgf("Computing manifest diff")
+
+	manifest, err := Parse(content)
+	if err != nil {
+		logger.Get().Error().Err(err).Msg("")
+		return err
+	}
+
+	state, err := fetchState(ctx, manifest)
+	if err != nil {
+		return err
+	}
+
+	return printPlan(manifest, buildPlan(manifest, state, prune))
+}
+
+// Apply converges the infrastructure described by the manifest: it prints the
+// plan, then creates, updates and (with prune) deletes resources, and finally
+// deploys the infrastructure when requested.
+func Apply(ctx context.Context, content []byte, prune bool, deploy bool, allowDataLoss bool) error {
+	logger.Get().Info().Msgf("Applying manifest")
+
+	manifest, err := Parse(content)
+	if err != nil {
+		logger.Get().Error().Err(err).Msg("")
+		return err
+	}
+
+	state, err := fetchState(ctx, manifest)
+	if err != nil {
+		return err
+	}
+
+	steps := buildPlan(manifest, state, prune)
+	if err := printPlan(manifest, steps); err != nil {
+		return err
+	}
+
+	infrastructureId, err := executePlan(ctx, steps, state)
+	if err != nil {
+		return err
+	}
+
+	if formatter.IsTextFormat() && len(steps) > 0 {
+		fmt.Printf("Applied %d change(s) to infrastructure '%s'\n", len(steps), manifest.Infrastructure.Label)
+	}
+
+	if !deploy {
+		return nil
+	}
+
+	return infrastructure.InfrastructureDeploy(ctx, infrastructureId, allowDataLoss, true, true, 180, false)
+}
+
+func printPlan(manifest *Manifest, steps []planStep) error {
+	if len(steps) == 0 && formatter.IsTextFormat() {
+		fmt.Printf("No changes. Infrastructure '%s' is up to date.\n", manifest.Infrastructure.Label)
+		return nil
+	}
+
+	changes := make([]Change, 0, len(steps))
+	for _, step := range steps {
+		changes = append(changes, step.Change)
+	}
+
+	return formatter.PrintResult(changes, &changePrintConfig)
+}
+
+// executePlan runs the plan steps in order and returns the infrastructure id,
+// which is only known after the first step when the infrastructure is new.
+func executePlan(ctx context.Context, steps []planStep, state *remoteState) (string, error) {
+	infrastructureId := ""
+	if state.Infrastructure != nil {
+		infrastructureId = state.Infrastructure.Id
+	}
+
+	groupIds := map[string]string{}
+	for label, group := range state.Resources[KindServerInstanceGroup] {
+		groupIds[label] = group.Id
+	}
+
+	for _, step := range steps {
+		logger.Get().Info().Msgf("Applying %s %s '%s'", step.Action, step.Kind, step.Label)
+
+		switch step.Kind {
+		case KindInfrastructure:
+			if step.Action == ActionCreate {
+				id, err := createResource(ctx, "/api/v2/infrastructures", step.document)
+				if err != nil {
+					return "", err
+				}
+				infrastructureId = id
+			} else {
+				spec := map[string]interface{}{}
+				for key, value := range step.document.Spec {
+					if !infrastructureIgnoredFields[key] {
+						spec[key] = value
+					}
+				}
+				path := fmt.Sprintf("/api/v2/infrastructures/%s/config", infrastructureId)
+				if err := sendResource(ctx, http.MethodPatch, path, spec, step.remote.ConfigRevision); err != nil {
+					return "", err
+				}
+			}
+
+		case KindNetworkConnection:
+			groupId, ok := groupIds[step.group]
+			if !ok {
+				return "", fmt.Errorf("server instance group '%s' not found for network connection '%s'", step.group, step.Label)
+			}
+			path := fmt.Sprintf("/api/v2/server-instance-groups/%s/config/networking/connections", groupId)
+
+			var err error
+			switch step.Action {
+			case ActionCreate:
+				err = sendResource(ctx, http.MethodPost, path, step.connection, "")
+			case ActionUpdate:
+				update := map[string]interface{}{}
+				for key, value := range step.connection {
+					if key != "logicalNetworkId" {
+						update[key] = value
+					}
+				}
+				err = sendResource(ctx, http.MethodPatch, path+"/"+step.remote.Id, update, "")
+			case ActionDelete:
+				err = sendResource(ctx, http.MethodDelete, path+"/"+step.remote.Id, nil, "")
+			}
+			if err != nil {
+				return "", err
+			}
+
+		default:
+			var err error
+			switch step.Action {
+			case ActionCreate:
+				var id string
+				id, err = createResource(ctx, collectionPath(step.Kind, infrastructureId), step.document)
+				if step.Kind == KindServerInstanceGroup {
+					groupIds[step.Label] = id
+				}
+			case ActionUpdate:
+				path := configPath(step.Kind, infrastructureId, step.remote.Id)
+				revision := step.remote.Revision
+				if step.Kind == KindServerInstanceGroup {
+					// Instance group configs carry their own revision.
+					revision, err = configRevision(ctx, path)
+					if err != nil {
+						return "", err
+					}
+				}
+				err = sendResource(ctx, http.MethodPatch, path, step.document.Spec, revision)
+			case ActionDelete:
+				err = sendResource(ctx, http.MethodDelete, itemPath(step.Kind, infrastructureId, step.remote.Id), nil, step.remote.Revision)
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return infrastructureId, nil
+}
+
+// collectionPath returns the REST path used to list and create resources of
+// the given kind inside an infrastructure.
+func collectionPath(kind string, infrastructureId string) string {
+	switch kind {
+	case KindServerInstanceGroup:
+		return fmt.Sprintf("/api/v2/infrastructures/%s/server-instance-groups", infrastructureId)
+	case KindVmInstanceGroup:
+		return fmt.Sprintf("/api/v2/infrastructures/%s/vm-instance-groups", infrastructureId)
+	case KindDrive:
+		return fmt.Sprintf("/api/v2/infrastructures/%s/drives", infrastructureId)
+	case KindFileShare:
+		return fmt.Sprintf("/api/v2/infrastructures/%s/file-shares", infrastructureId)
+	case KindBucket:
+		return fmt.Sprintf("/api/v2/infrastructures/%s/buckets", infrastructureId)
+	case KindExtensionInstance:
+		return fmt.Sprintf("/api/v2/infrastructures/%s/extension-instances", infrastructureId)
+	}
+
+	return ""
+}
+
+// itemPath returns the REST path of a single resource. Server instance groups
+// and extension instances are addressed outside of their infrastructure.
+func itemPath(kind string, infrastructureId string, id string) string {
+	switch kind {
+	case KindServerInstanceGroup:
+		return fmt.Sprintf("/api/v2/server-instance-groups/%s", id)
+	case KindExtensionInstance:
+		return fmt.Sprintf("/api/v2/extension-instances/%s", id)
+	}
+
+	return collectionPath(kind, infrastructureId) + "/" + id
+}
+
+// configPath returns the REST path a resource's configuration is read from
+// and patched at. Extension instances are patched directly.
+func configPath(kind string, infrastructureId string, id string) string {
+	if kind == KindExtensionInstance {
+		return itemPath(kind, infrastructureId, id)
+	}
+
+	return itemPath(kind, infrastructureId, id) + "/config"
+}
+
+func createResource(ctx context.Context, path string, document Document) (string, error) {
+	body := map[string]interface{}{}
+	for key, value := range document.Spec {
+		body[key] = value
+	}
+	body["label"] = document.Label
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize %s '%s': %v", document.Kind, document.Label, err)
+	}
+
+	httpRes, err := api.DoJSONRequest(ctx, http.MethodPost, path, payload)
+	if err := response_inspector.InspectResponse(httpRes, err); err != nil {
+		return "", err
+	}
+
+	created, err := response_inspector.ParseResponseBody(httpRes)
+	if err != nil {
+		return "", err
+	}
+
+	id := valueToString(created["id"])
+	if id == "" {
+		return "", fmt.Errorf("no id returned when creating %s '%s'", document.Kind, document.Label)
+	}
+
+	return id, nil
+}
+
+func configRevision(ctx context.Context, path string) (string, error) {
+	config, err := getValues(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	return valueToString(config["revision"]), nil
+}
+
+func getValues(ctx context.Context, path string) (map[string]interface{}, error) {
+	httpRes, err := api.DoJSONRequest(ctx, http.MethodGet, path, nil)
+	if err := response_inspector.InspectResponse(httpRes, err); err != nil {
+		return nil, err
+	}
+
+	return response_inspector.ParseResponseBody(httpRes)
+}
+
+func sendResource(ctx context.Context, method string, path string, body map[string]interface{}, revision string) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to serialize request body: %v", err)
+		}
+	}
+
+	headers := map[string]string{}
+	if revision != "" {
+		headers["If-Match"] = revision
+	}
+
+	httpRes, err := api.DoJSONRequestWithHeaders(ctx, method, path, payload, headers)
+	if err := response_inspector.InspectResponse(httpRes, err); err != nil {
+		return err
+	}
+	httpRes.Body.Close()
+
+	return nil
+}
+
+// fetchState reads the current infrastructure and the resources of every kind
+// the manifest manages through the raw list endpoints.
+func fetchState(ctx context.Context, manifest *Manifest) (*remoteState, error) {
+	state := remoteState{
+		Resources:   map[string]map[string]*remoteResource{},
+		Connections: map[string]map[string]*remoteResource{},
+	}
+
+	var err error
+	state.Infrastructure, err = findInfrastructure(ctx, manifest.Infrastructure.Label, false)
+	if err != nil {
+		return nil, err
+	}
+	if state.Infrastructure == nil {
+		return &state, nil
+	}
+
+	for _, kind := range resourceKinds {
+		items, err := listResources(ctx, collectionPath(kind, state.Infrastructure.Id))
+		if err != nil {
+			return nil, err
+		}
+
+		state.Resources[kind] = map[string]*remoteResource{}
+		for _, item := range items {
+			state.Resources[kind][item.Label] = item
+		}
+	}
+
+	for _, document := range manifest.resourcesOfKind(KindServerInstanceGroup) {
+		group := state.Resources[KindServerInstanceGroup][document.Label]
+		if group == nil {
+			continue
+		}
+
+		connections, err := listConnections(ctx, group.Id)
+		if err != nil {
+			return nil, err
+		}
+		state.Connections[document.Label] = connections
+	}
+
+	return &state, nil
+}
+
+// findInfrastructure looks up a non-deleted infrastructure by label, or also
+// by id when matchId is set. It returns nil when there is no match.
+func findInfrastructure(ctx context.Context, idOrLabel string, matchId bool) (*remoteResource, error) {
+	infrastructures, err := listResources(ctx, fmt.Sprintf("/api/v2/infrastructures?search=%s", url.QueryEscape(idOrLabel)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range infrastructures {
+		if item.Label == idOrLabel || (matchId && item.Id == idOrLabel) {
+			return item, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// listResources fetches all pages of a list endpoint, skipping deleted
+// resources. The pending configuration is used as the current values when the
+// API returns it, since that is what updates are applied to.
+func listResources(ctx context.Context, path string) ([]*remoteResource, error) {
+	separator := "?"
+	if u, err := url.Parse(path); err == nil && u.RawQuery != "" {
+		separator = "&"
+	}
+
+	rawItems, _, err := utils.FetchAllPagesRaw(func(page float32) (*http.Response, error) {
+		return api.DoJSONRequest(ctx, http.MethodGet, fmt.Sprintf("%s%spage=%.0f&limit=100", path, separator, page), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := utils.UnmarshalRawItems[map[string]interface{}](rawItems)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse '%s': %w", path, err)
+	}
+
+	resources := []*remoteResource{}
+	for _, item := range items {
+		if item["serviceStatus"] == deletedServiceStatus {
+			continue
+		}
+		resources = append(resources, toRemoteResource(item))
+	}
+
+	return resources, nil
+}
+
+func listConnections(ctx context.Context, groupId string) (map[string]*remoteResource, error) {
+	httpRes, err := api.DoJSONRequest(ctx, http.MethodGet,
+		fmt.Sprintf("/api/v2/server-instance-groups/%s/config/networking/connections", groupId), nil)
+	if err := response_inspector.InspectResponse(httpRes, err); err != nil {
+		return nil, err
+	}
+
+	result, err := response_inspector.ParseResponseBody(httpRes)
+	if err != nil {
+		return nil, err
+	}
+
+	connections := map[string]*remoteResource{}
+	data, _ := result["data"].([]interface{})
+	for _, entry := range data {
+		item, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		connection := toRemoteResource(item)
+		connections[valueToString(item["logicalNetworkId"])] = connection
+	}
+
+	return connections, nil
+}
+
+func toRemoteResource(item map[string]interface{}) *remoteResource {
+	resource := remoteResource{
+		Id:             valueToString(item["id"]),
+		Label:          valueToString(item["label"]),
+		Revision:       valueToString(item["revision"]),
+		ConfigRevision: valueToString(item["revision"]),
+		Values:         item,
+	}
+
+	if config, ok := item["config"].(map[string]interface{}); ok && len(config) > 0 {
+		resource.Values = config
+		if revision := valueToString(config["revision"]); revision != "" {
+			resource.ConfigRevision = revision
+		}
+	}
+
+	return &resource
+}
@@ -0,0 +1,158 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// serverAssignedFields are set by the API and rejected or meaningless in
+// create payloads. They are removed from the top level of exported specs.
+var serverAssignedFields = map[string]bool{
+	"label":            true,
+	"links":            true,
+	"infrastructureId": true,
+	"serviceStatus":    true,
+	"deployStatus":     true,
+	"deployType":       true,
+	"userIdOwner":      true,
+	"datacenterName":   true,
+}
+
+// Export writes the infrastructure and all its resources as a manifest that
+// can be fed back to apply, e.g. to clone an environment into another site.
+// The manifest is written to outputPath, or to stdout when it is empty.
+func Export(ctx context.Context, infrastructureIdOrLabel string, outputPath string) error {
+	logger.Get().Info().Msgf("Exporting infrastructure '%s'", infrastructureIdOrLabel)
+
+	infrastructure, err := findInfrastructure(ctx, infrastructureIdOrLabel, true)
+	if err != nil {
+		return err
+	}
+	if infrastructure == nil {
+		err := fmt.Errorf("infrastructure '%s' not found", infrastructureIdOrLabel)
+		logger.Get().Error().Err(err).Msg("")
+		return err
+	}
+
+	documents := []Document{{
+		Kind:  KindInfrastructure,
+		Label: infrastructure.Label,
+		Spec:  stripServerFields(infrastructure.Values),
+	}}
+
+	for _, kind := range resourceKinds {
+		items, err := listResources(ctx, collectionPath(kind, infrastructure.Id))
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			values := item.Values
+			if kind == KindServerInstanceGroup || kind == KindVmInstanceGroup {
+				// Instance group list entries do not embed the configuration.
+				values, err = getValues(ctx, configPath(kind, infrastructure.Id, item.Id))
+				if err != nil {
+					return err
+				}
+			}
+
+			document := Document{
+				Kind:  kind,
+				Label: item.Label,
+				Spec:  stripServerFields(values),
+			}
+
+			if kind == KindServerInstanceGroup {
+				connections, err := listConnections(ctx, item.Id)
+				if err != nil {
+					return err
+				}
+				for _, networkId := range sortedKeys(connections) {
+					document.NetworkConnections = append(document.NetworkConnections, stripServerFields(connections[networkId].Values))
+				}
+			}
+
+			documents = append(documents, document)
+		}
+	}
+
+	content, err := encodeDocuments(documents)
+	if err != nil {
+		return err
+	}
+
+	if outputPath == "" {
+		fmt.Print(string(content))
+		return nil
+	}
+
+	if err := os.WriteFile(outputPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest to '%s': %w", outputPath, err)
+	}
+
+	if formatter.IsTextFormat() {
+		fmt.Printf("Infrastructure '%s' exported to %s (%d resources)\n", infrastructure.Label, outputPath, len(documents)-1)
+	}
+
+	return nil
+}
+
+func encodeDocuments(documents []Document) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return nil, fmt.Errorf("failed to serialize %s '%s': %w", document.Kind, document.Label, err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to serialize manifest: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// stripServerFields returns a copy of values without the server-assigned
+// top-level fields and without ids, revisions, timestamps and empty values at
+// any depth.
+func stripServerFields(values map[string]interface{}) map[string]interface{} {
+	stripped := map[string]interface{}{}
+	for key, value := range stripNested(values).(map[string]interface{}) {
+		if !serverAssignedFields[key] {
+			stripped[key] = value
+		}
+	}
+
+	return stripped
+}
+
+func stripNested(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		stripped := map[string]interface{}{}
+		for key, item := range v {
+			if item == nil || key == "id" || key == "revision" || strings.HasSuffix(key, "Timestamp") {
+				continue
+			}
+			stripped[key] = stripNested(item)
+		}
+		return stripped
+	case []interface{}:
+		stripped := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			stripped = append(stripped, stripNested(item))
+		}
+		return stripped
+	default:
+		return value
+	}
+}
@@ -0,0 +1,99 @@
+package manifest
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/metalsoft-io/metalcloud-cli/internal/testutils"
+)
+
+func TestExport_RoundTrip(t *testing.T) {
+	infrastructure := map[string]interface{}{
+		"id": 123, "label": "web", "revision": 3, "serviceStatus": "active",
+		"createdTimestamp": "2024-01-01T00:00:00Z",
+		"config": map[string]interface{}{
+			"label": "web", "siteId": 1, "revision": 4, "deployStatus": "finished",
+			"datacenterName": "dc1", "customVariables": map[string]interface{}{"env": "prod"},
+			"updatedTimestamp": "2024-01-01T00:00:00Z",
+		},
+	}
+	group := map[string]interface{}{"id": 10, "label": "frontend", "revision": 1, "serviceStatus": "active"}
+	drive := map[string]interface{}{
+		"id": 1, "label": "data", "revision": 1, "serviceStatus": "active",
+		"config": map[string]interface{}{"label": "data", "sizeMb": 40960, "revision": 2},
+	}
+	empty := testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse([]interface{}{}, 1, 1))
+
+	ts := testutils.NewTestServer(map[string]http.HandlerFunc{
+		"/api/v2/infrastructures":                            testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse([]interface{}{infrastructure}, 1, 1)),
+		"/api/v2/infrastructures/123/server-instance-groups": testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse([]interface{}{group}, 1, 1)),
+		"/api/v2/server-instance-groups/10/config": testutils.RawHandler(http.StatusOK,
+			`{"revision":5,"label":"frontend","instanceCount":2,"defaultServerTypeId":5,"updatedTimestamp":"2024-01-01T00:00:00Z"}`),
+		"/api/v2/server-instance-groups/10/config/networking/connections": testutils.RawHandler(http.StatusOK,
+			`{"data":[{"id":100,"logicalNetworkId":"7","accessMode":"l2","tagged":true,"createdTimestamp":"2024-01-01T00:00:00Z"}]}`),
+		"/api/v2/infrastructures/123/vm-instance-groups":  empty,
+		"/api/v2/infrastructures/123/drives":              testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse([]interface{}{drive}, 1, 1)),
+		"/api/v2/infrastructures/123/file-shares":         empty,
+		"/api/v2/infrastructures/123/buckets":             empty,
+		"/api/v2/infrastructures/123/extension-instances": empty,
+	})
+	defer ts.Close()
+
+	outputPath := filepath.Join(t.TempDir(), "web.yaml")
+
+	ctx := testutils.SetupTestContext(ts.URL)
+	if err := Export(ctx, "123", outputPath); err != nil {
+		t.Fatalf("Export: unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read exported manifest: %v", err)
+	}
+
+	// The exported manifest must be accepted by apply.
+	manifest, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse exported manifest: %v\n%s", err, content)
+	}
+
+	expectedInfrastructure := map[string]interface{}{"siteId": float64(1), "customVariables": map[string]interface{}{"env": "prod"}}
+	if !valuesEqual(manifest.Infrastructure.Spec, expectedInfrastructure) {
+		t.Errorf("unexpected infrastructure spec: %v", manifest.Infrastructure.Spec)
+	}
+
+	if len(manifest.Resources) != 2 {
+		t.Fatalf("expected 2 resources, got %d:\n%s", len(manifest.Resources), content)
+	}
+
+	frontend := manifest.Resources[0]
+	if frontend.Kind != KindServerInstanceGroup || frontend.Label != "frontend" {
+		t.Errorf("expected ServerInstanceGroup 'frontend', got %s '%s'", frontend.Kind, frontend.Label)
+	}
+	if !valuesEqual(frontend.Spec, map[string]interface{}{"instanceCount": 2, "defaultServerTypeId": 5}) {
+		t.Errorf("unexpected group spec: %v", frontend.Spec)
+	}
+	if len(frontend.NetworkConnections) != 1 || !valuesEqual(frontend.NetworkConnections[0],
+		map[string]interface{}{"logicalNetworkId": "7", "accessMode": "l2", "tagged": true}) {
+		t.Errorf("unexpected network connections: %v", frontend.NetworkConnections)
+	}
+
+	data := manifest.Resources[1]
+	if data.Kind != KindDrive || !valuesEqual(data.Spec, map[string]interface{}{"sizeMb": 40960}) {
+		t.Errorf("unexpected drive document: %+v", data)
+	}
+}
+
+func TestExport_NotFound(t *testing.T) {
+	ts := testutils.NewTestServer(map[string]http.HandlerFunc{
+		"/api/v2/infrastructures": testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse([]interface{}{}, 1, 1)),
+	})
+	defer ts.Close()
+
+	ctx := testutils.SetupTestContext(ts.URL)
+	if err := Export(ctx, "missing", ""); err == nil {
+		t.Error("Export of missing infrastructure: expected error, got nil")
+	}
+}
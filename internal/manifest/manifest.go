@@ -0,0 +1,146 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Resource kinds supported in a manifest document.
+const (
+	KindInfrastructure      = "Infrastructure"
+	KindServerInstanceGroup = "ServerInstanceGroup"
+	KindVmInstanceGroup     = "VmInstanceGroup"
+	KindDrive               = "Drive"
+	KindFileShare           = "FileShare"
+	KindBucket              = "Bucket"
+	KindExtensionInstance   = "ExtensionInstance"
+	KindNetworkConnection   = "NetworkConnection"
+)
+
+// Document is one YAML document of a manifest. Spec holds the resource fields
+// exactly as the API expects them in create/update bodies (camelCase), so any
+// field accepted by the corresponding `--config-source` payload can be used.
+type Document struct {
+	Kind               string                   `json:"kind" yaml:"kind"`
+	Label              string                   `json:"label" yaml:"label"`
+	Spec               map[string]interface{}   `json:"spec,omitempty" yaml:"spec,omitempty"`
+	NetworkConnections []map[string]interface{} `json:"networkConnections,omitempty" yaml:"networkConnections,omitempty"`
+}
+
+// Manifest is a parsed multi-document manifest describing one infrastructure
+// and the resources it contains.
+type Manifest struct {
+	Infrastructure Document
+	Resources      []Document
+}
+
+// resourceKinds lists the kinds that live inside an infrastructure, in the
+// order they are created. Deletes run in the reverse order.
+var resourceKinds = []string{
+	KindServerInstanceGroup,
+	KindVmInstanceGroup,
+	KindDrive,
+	KindFileShare,
+	KindBucket,
+	KindExtensionInstance,
+}
+
+// Parse reads a multi-document YAML (or JSON) manifest. Exactly one
+// Infrastructure document is required; resource labels must be unique per kind.
+func Parse(content []byte) (*Manifest, error) {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, fmt.Errorf("manifest is empty")
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+
+	manifest := Manifest{}
+	seen := map[string]bool{}
+	index := 0
+
+	for {
+		var raw interface{}
+		err := decoder.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse manifest document %d: %v", index+1, err)
+		}
+		index++
+
+		if raw == nil {
+			continue
+		}
+
+		// Route through JSON so YAML and JSON manifests decode identically.
+		jsonBytes, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse manifest document %d: %v", index, err)
+		}
+
+		document := Document{}
+		if err := json.Unmarshal(jsonBytes, &document); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest document %d: %v", index, err)
+		}
+
+		if document.Label == "" {
+			return nil, fmt.Errorf("manifest document %d (%s) has no label", index, document.Kind)
+		}
+		if document.Spec == nil {
+			document.Spec = map[string]interface{}{}
+		}
+
+		switch document.Kind {
+		case KindInfrastructure:
+			if manifest.Infrastructure.Kind != "" {
+				return nil, fmt.Errorf("manifest contains more than one %s document", KindInfrastructure)
+			}
+			manifest.Infrastructure = document
+		case KindServerInstanceGroup, KindVmInstanceGroup, KindDrive, KindFileShare, KindBucket, KindExtensionInstance:
+			key := document.Kind + "/" + document.Label
+			if seen[key] {
+				return nil, fmt.Errorf("manifest contains %s '%s' more than once", document.Kind, document.Label)
+			}
+			seen[key] = true
+
+			if len(document.NetworkConnections) > 0 && document.Kind != KindServerInstanceGroup {
+				return nil, fmt.Errorf("%s '%s': networkConnections are only supported on %s", document.Kind, document.Label, KindServerInstanceGroup)
+			}
+			for _, connection := range document.NetworkConnections {
+				if connection["logicalNetworkId"] == nil {
+					return nil, fmt.Errorf("%s '%s': every network connection requires a logicalNetworkId", document.Kind, document.Label)
+				}
+			}
+
+			manifest.Resources = append(manifest.Resources, document)
+		default:
+			return nil, fmt.Errorf("manifest document %d has unsupported kind '%s' (supported: %s, %s)",
+				index, document.Kind, KindInfrastructure, strings.Join(resourceKinds, ", "))
+		}
+	}
+
+	if manifest.Infrastructure.Kind == "" {
+		return nil, fmt.Errorf("manifest must contain one %s document", KindInfrastructure)
+	}
+
+	return &manifest, nil
+}
+
+// resourcesOfKind returns the manifest documents of the given kind.
+func (m *Manifest) resourcesOfKind(kind string) []Document {
+	documents := []Document{}
+	for _, document := range m.Resources {
+		if document.Kind == kind {
+			documents = append(documents, document)
+		}
+	}
+
+	return documents
+}
@@ -0,0 +1,241 @@
+package manifest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/metalsoft-io/metalcloud-cli/internal/testutils"
+)
+
+func TestMain(m *testing.M) {
+	testutils.SetupTestFormat()
+	m.Run()
+}
+
+const testManifest = `
+kind: Infrastructure
+label: web
+spec:
+  siteId: 1
+  customVariables:
+    env: prod
+---
+kind: ServerInstanceGroup
+label: frontend
+spec:
+  instanceCount: 2
+  defaultServerTypeId: 5
+networkConnections:
+  - logicalNetworkId: "7"
+    accessMode: l2
+    tagged: true
+---
+kind: Drive
+label: data
+spec:
+  sizeMb: 40960
+`
+
+func TestParse_HappyPath(t *testing.T) {
+	manifest, err := Parse([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+
+	if manifest.Infrastructure.Label != "web" {
+		t.Errorf("expected infrastructure label 'web', got %q", manifest.Infrastructure.Label)
+	}
+	if len(manifest.Resources) != 2 {
+		t.Fatalf("expected 2 resources, got %d", len(manifest.Resources))
+	}
+	if got := manifest.Resources[0].Spec["instanceCount"]; got != float64(2) {
+		t.Errorf("expected instanceCount 2, got %v", got)
+	}
+	if len(manifest.Resources[0].NetworkConnections) != 1 {
+		t.Errorf("expected 1 network connection, got %d", len(manifest.Resources[0].NetworkConnections))
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	cases := map[string]string{
+		"empty":               "",
+		"no infrastructure":   "kind: Drive\nlabel: d\n",
+		"two infrastructures": "kind: Infrastructure\nlabel: a\n---\nkind: Infrastructure\nlabel: b\n",
+		"unknown kind":        "kind: Infrastructure\nlabel: a\n---\nkind: Secret\nlabel: s\n",
+		"missing label":       "kind: Infrastructure\n",
+		"duplicate":           "kind: Infrastructure\nlabel: a\n---\nkind: Drive\nlabel: d\n---\nkind: Drive\nlabel: d\n",
+		"connection on drive": "kind: Infrastructure\nlabel: a\n---\nkind: Drive\nlabel: d\nnetworkConnections:\n  - logicalNetworkId: 1\n",
+		"connection no net":   "kind: Infrastructure\nlabel: a\n---\nkind: ServerInstanceGroup\nlabel: g\nnetworkConnections:\n  - accessMode: l2\n",
+	}
+
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(content)); err == nil {
+				t.Errorf("Parse(%s): expected error, got nil", name)
+			}
+		})
+	}
+}
+
+func TestBuildPlan_NewInfrastructure(t *testing.T) {
+	manifest, err := Parse([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+
+	steps := buildPlan(manifest, &remoteState{}, true)
+
+	expected := []Change{
+		{Action: ActionCreate, Kind: KindInfrastructure, Label: "web"},
+		{Action: ActionCreate, Kind: KindServerInstanceGroup, Label: "frontend"},
+		{Action: ActionCreate, Kind: KindNetworkConnection, Label: "frontend/7"},
+		{Action: ActionCreate, Kind: KindDrive, Label: "data"},
+	}
+	assertChanges(t, steps, expected)
+}
+
+func TestBuildPlan_ExistingInfrastructure(t *testing.T) {
+	manifest, err := Parse([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+
+	state := &remoteState{
+		Infrastructure: &remoteResource{Id: "123", Values: map[string]interface{}{
+			"siteId":          float64(2),
+			"customVariables": map[string]interface{}{"env": "prod", "other": "x"},
+		}},
+		Resources: map[string]map[string]*remoteResource{
+			KindServerInstanceGroup: {
+				"frontend": {Id: "10", Values: map[string]interface{}{"instanceCount": float64(1), "defaultServerTypeId": float64(5)}},
+				"backend":  {Id: "11", Values: map[string]interface{}{}},
+			},
+			KindDrive: {
+				"data": {Id: "1", Values: map[string]interface{}{"sizeMb": float64(40960)}},
+			},
+		},
+		Connections: map[string]map[string]*remoteResource{
+			"frontend": {
+				"7": {Id: "100", Values: map[string]interface{}{"logicalNetworkId": "7", "accessMode": "l2", "tagged": true}},
+				"8": {Id: "101", Values: map[string]interface{}{"logicalNetworkId": "8"}},
+			},
+		},
+	}
+
+	// Without prune nothing is deleted and unchanged resources are skipped.
+	assertChanges(t, buildPlan(manifest, state, false), []Change{
+		{Action: ActionUpdate, Kind: KindServerInstanceGroup, Label: "frontend", Details: "instanceCount"},
+	})
+
+	assertChanges(t, buildPlan(manifest, state, true), []Change{
+		{Action: ActionUpdate, Kind: KindServerInstanceGroup, Label: "frontend", Details: "instanceCount"},
+		{Action: ActionDelete, Kind: KindNetworkConnection, Label: "frontend/8"},
+		{Action: ActionDelete, Kind: KindServerInstanceGroup, Label: "backend"},
+	})
+}
+
+func TestDiffSpec_Nested(t *testing.T) {
+	desired := map[string]interface{}{
+		"a": 1,
+		"b": map[string]interface{}{"c": "x", "d": []interface{}{1, 2}},
+	}
+	current := map[string]interface{}{
+		"a": float64(1),
+		"b": map[string]interface{}{"c": "y", "d": []interface{}{float64(1), float64(2)}, "e": true},
+	}
+
+	fields := diffSpec(desired, current, nil)
+	if len(fields) != 1 || fields[0] != "b.c" {
+		t.Errorf("expected [b.c], got %v", fields)
+	}
+}
+
+func TestApply_CreatesAllResources(t *testing.T) {
+	var mu sync.Mutex
+	requests := []string{}
+	record := func(r *http.Request) map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		body := map[string]interface{}{}
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			_ = json.Unmarshal(data, &body)
+		}
+		return body
+	}
+
+	ts := testutils.NewTestServer(map[string]http.HandlerFunc{
+		"/api/v2/infrastructures": func(w http.ResponseWriter, r *http.Request) {
+			body := record(r)
+			if r.Method == http.MethodPost {
+				if body["label"] != "web" {
+					t.Errorf("expected label 'web' in create body, got %v", body["label"])
+				}
+				testutils.RawHandler(http.StatusCreated, `{"id":123,"label":"web"}`)(w, r)
+				return
+			}
+			testutils.JSONHandler(http.StatusOK, testutils.PaginatedResponse([]interface{}{}, 1, 1))(w, r)
+		},
+		"/api/v2/infrastructures/123/server-instance-groups": func(w http.ResponseWriter, r *http.Request) {
+			record(r)
+			testutils.RawHandler(http.StatusCreated, `{"id":10,"label":"frontend"}`)(w, r)
+		},
+		"/api/v2/server-instance-groups/10/config/networking/connections": func(w http.ResponseWriter, r *http.Request) {
+			body := record(r)
+			if body["logicalNetworkId"] != "7" {
+				t.Errorf("expected logicalNetworkId '7', got %v", body["logicalNetworkId"])
+			}
+			testutils.RawHandler(http.StatusCreated, `{"id":100}`)(w, r)
+		},
+		"/api/v2/infrastructures/123/drives": func(w http.ResponseWriter, r *http.Request) {
+			record(r)
+			testutils.RawHandler(http.StatusCreated, `{"id":1,"label":"data"}`)(w, r)
+		},
+	})
+	defer ts.Close()
+
+	ctx := testutils.SetupTestContext(ts.URL)
+	if err := Apply(ctx, []byte(testManifest), false, false, false); err != nil {
+		t.Fatalf("Apply: unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"GET /api/v2/infrastructures",
+		"POST /api/v2/infrastructures",
+		"POST /api/v2/infrastructures/123/server-instance-groups",
+		"POST /api/v2/server-instance-groups/10/config/networking/connections",
+		"POST /api/v2/infrastructures/123/drives",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected requests:\n%s\nexpected:\n%s", strings.Join(requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestDiff_Error(t *testing.T) {
+	ts := testutils.NewTestServer(map[string]http.HandlerFunc{
+		"/api/v2/infrastructures": testutils.ErrorHandler(http.StatusInternalServerError, "internal error"),
+	})
+	defer ts.Close()
+
+	ctx := testutils.SetupTestContext(ts.URL)
+	if err := Diff(ctx, []byte(testManifest), false); err == nil {
+		t.Error("Diff with 500: expected error, got nil")
+	}
+}
+
+func assertChanges(t *testing.T, steps []planStep, expected []Change) {
+	t.Helper()
+
+	if len(steps) != len(expected) {
+		t.Fatalf("expected %d changes, got %d: %+v", len(expected), len(steps), steps)
+	}
+	for i, step := range steps {
+		if step.Change != expected[i] {
+			t.Errorf("change %d: expected %+v, got %+v", i, expected[i], step.Change)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
+)
+
+// Plan actions.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Change is one entry of an apply plan.
+type Change struct {
+	Action  string `json:"action"`
+	Kind    string `json:"kind"`
+	Label   string `json:"label"`
+	Details string `json:"details,omitempty"`
+}
+
+var changePrintConfig = formatter.PrintConfig{
+	FieldsConfig: map[string]formatter.RecordFieldConfig{
+		"Action": {
+			Title: "Action",
+			Order: 1,
+		},
+		"Kind": {
+			Title: "Kind",
+			Order: 2,
+		},
+		"Label": {
+			Title:    "Label",
+			MaxWidth: 40,
+			Order:    3,
+		},
+		"Details": {
+			Title:    "Details",
+			MaxWidth: 60,
+			Order:    4,
+		},
+	},
+}
+
+// remoteResource is the current server-side state of one resource, decoded
+// from the raw API response.
+type remoteResource struct {
+	Id             string
+	Label          string
+	Revision       string
+	ConfigRevision string
+	Values         map[string]interface{}
+}
+
+// remoteState is the current server-side state of an infrastructure. A nil
+// Infrastructure means the infrastructure does not exist yet.
+type remoteState struct {
+	Infrastructure *remoteResource
+	// Resources is keyed by kind, then by label.
+	Resources map[string]map[string]*remoteResource
+	// Connections is keyed by server instance group label, then by logical network id.
+	Connections map[string]map[string]*remoteResource
+}
+
+// planStep is a Change together with what is needed to execute it.
+type planStep struct {
+	Change
+	document   Document
+	connection map[string]interface{}
+	group      string
+	remote     *remoteResource
+}
+
+// infrastructureIgnoredFields are accepted in the Infrastructure spec for
+// creation but cannot be changed afterwards, so they are not diffed.
+var infrastructureIgnoredFields = map[string]bool{
+	"siteId": true,
+}
+
+// buildPlan compares the manifest with the current state and returns the
+// ordered list of steps needed to converge. Resources present on the server
+// but missing from the manifest are deleted only when prune is set.
+func buildPlan(manifest *Manifest, state *remoteState, prune bool) []planStep {
+	steps := []planStep{}
+
+	infrastructureDocument := manifest.Infrastructure
+	if state.Infrastructure == nil {
+		steps = append(steps, planStep{
+			Change:   Change{Action: ActionCreate, Kind: KindInfrastructure, Label: infrastructureDocument.Label},
+			document: infrastructureDocument,
+		})
+	} else if fields := diffSpec(infrastructureDocument.Spec, state.Infrastructure.Values, infrastructureIgnoredFields); len(fields) > 0 {
+		steps = append(steps, planStep{
+			Change:   Change{Action: ActionUpdate, Kind: KindInfrastructure, Label: infrastructureDocument.Label, Details: strings.Join(fields, ", ")},
+			document: infrastructureDocument,
+			remote:   state.Infrastructure,
+		})
+	}
+
+	// Instance groups first so their network connections can follow, then
+	// storage which may reference the groups.
+	for _, kind := range resourceKinds {
+		for _, document := range manifest.resourcesOfKind(kind) {
+			remote := state.Resources[kind][document.Label]
+			if remote == nil {
+				steps = append(steps, planStep{
+					Change:   Change{Action: ActionCreate, Kind: kind, Label: document.Label},
+					document: document,
+				})
+			} else if fields := diffSpec(document.Spec, remote.Values, nil); len(fields) > 0 {
+				steps = append(steps, planStep{
+					Change:   Change{Action: ActionUpdate, Kind: kind, Label: document.Label, Details: strings.Join(fields, ", ")},
+					document: document,
+					remote:   remote,
+				})
+			}
+
+			if kind == KindServerInstanceGroup {
+				steps = append(steps, planConnections(document, state.Connections[document.Label], prune)...)
+			}
+		}
+	}
+
+	if !prune {
+		return steps
+	}
+
+	for i := len(resourceKinds) - 1; i >= 0; i-- {
+		kind := resourceKinds[i]
+
+		desired := map[string]bool{}
+		for _, document := range manifest.resourcesOfKind(kind) {
+			desired[document.Label] = true
+		}
+
+		for _, label := range sortedKeys(state.Resources[kind]) {
+			if desired[label] {
+				continue
+			}
+			steps = append(steps, planStep{
+				Change: Change{Action: ActionDelete, Kind: kind, Label: label},
+				remote: state.Resources[kind][label],
+			})
+		}
+	}
+
+	return steps
+}
+
+func planConnections(document Document, current map[string]*remoteResource, prune bool) []planStep {
+	steps := []planStep{}
+
+	desired := map[string]bool{}
+	for _, connection := range document.NetworkConnections {
+		networkId := valueToString(connection["logicalNetworkId"])
+		desired[networkId] = true
+		label := document.Label + "/" + networkId
+
+		remote := current[networkId]
+		if remote == nil {
+			steps = append(steps, planStep{
+				Change:     Change{Action: ActionCreate, Kind: KindNetworkConnection, Label: label},
+				connection: connection,
+				group:      document.Label,
+			})
+		} else if fields := diffSpec(connection, remote.Values, nil); len(fields) > 0 {
+			steps = append(steps, planStep{
+				Change:     Change{Action: ActionUpdate, Kind: KindNetworkConnection, Label: label, Details: strings.Join(fields, ", ")},
+				connection: connection,
+				group:      document.Label,
+				remote:     remote,
+			})
+		}
+	}
+
+	if prune {
+		for _, networkId := range sortedKeys(current) {
+			if desired[networkId] {
+				continue
+			}
+			steps = append(steps, planStep{
+				Change: Change{Action: ActionDelete, Kind: KindNetworkConnection, Label: document.Label + "/" + networkId},
+				group:  document.Label,
+				remote: current[networkId],
+			})
+		}
+	}
+
+	return steps
+}
+
+// diffSpec returns the sorted paths of the fields in desired whose value
+// differs from current. Fields not mentioned in desired are left alone, so a
+// manifest only needs to carry the fields it manages.
+func diffSpec(desired map[string]interface{}, current map[string]interface{}, ignored map[string]bool) []string {
+	fields := []string{}
+
+	for key, desiredValue := range desired {
+		if ignored[key] {
+			continue
+		}
+
+		currentValue := current[key]
+
+		desiredMap, desiredIsMap := desiredValue.(map[string]interface{})
+		currentMap, currentIsMap := currentValue.(map[string]interface{})
+		if desiredIsMap && currentIsMap {
+			for _, field := range diffSpec(desiredMap, currentMap, nil) {
+				fields = append(fields, key+"."+field)
+			}
+			continue
+		}
+
+		if !valuesEqual(desiredValue, currentValue) {
+			fields = append(fields, key)
+		}
+	}
+
+	sort.Strings(fields)
+
+	return fields
+}
+
+// valuesEqual compares two values after normalizing them through JSON, so
+// that e.g. an int from a YAML manifest equals a float64 from an API response.
+func valuesEqual(a interface{}, b interface{}) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+func normalize(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+
+	return normalized
+}
+
+// valueToString formats ids and revisions decoded from JSON, which arrive as
+// float64, without an exponent or decimals.
+func valueToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func sortedKeys(resources map[string]*remoteResource) []string {
+	keys := make([]string, 0, len(resources))
+	for key := range resources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
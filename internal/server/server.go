@@ -7,6 +7,7 @@ import (
 	"io"
 	"strconv"
 
+	"github.com/metalsoft-io/metalcloud-cli/internal/job"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
@@ -207,7 +208,7 @@ func ServerRegister(ctx context.Context, serverConfig sdk.RegisterServer) error
 		return err
 	}
 
-	return formatter.PrintResult(registrationInfo, &formatter.PrintConfig{
+	if err := formatter.PrintResult(registrationInfo, &formatter.PrintConfig{
 		FieldsConfig: map[string]formatter.RecordFieldConfig{
 			"ServerId": {
 				Title: "ID",
@@ -235,7 +236,11 @@ func ServerRegister(ctx context.Context, serverConfig sdk.RegisterServer) error
 				},
 			},
 		},
-	})
+	}); err != nil {
+		return err
+	}
+
+	return job.WaitForResult(ctx, registrationInfo)
 }
 
 func ServerReRegister(ctx context.Context, serverId string) error {
@@ -253,7 +258,7 @@ func ServerReRegister(ctx context.Context, serverId string) error {
 		return err
 	}
 
-	return formatter.PrintResult(response, &formatter.PrintConfig{
+	if err := formatter.PrintResult(response, &formatter.PrintConfig{
 		FieldsConfig: map[string]formatter.RecordFieldConfig{
 			"ServerId": {
 				Title: "ID",
@@ -273,7 +278,11 @@ func ServerReRegister(ctx context.Context, serverId string) error {
 				},
 			},
 		},
-	})
+	}); err != nil {
+		return err
+	}
+
+	return job.WaitForResult(ctx, response)
 }
 
 func ServerFactoryReset(ctx context.Context, serverId string) error {
@@ -293,7 +302,7 @@ func ServerFactoryReset(ctx context.Context, serverId string) error {
 
 	logger.Get().Info().Msgf("Factory reset initiated for server '%s'", serverId)
 
-	return nil
+	return job.WaitForResult(ctx, httpRes)
 }
 
 func ServerArchive(ctx context.Context, serverId string) error {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/metalsoft-io/metalcloud-cli/internal/job"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/api"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/formatter"
 	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
@@ -260,7 +261,7 @@ func ServerFirmwareUpgrade(ctx context.Context, serverId string) error {
 	}
 
 	logger.Get().Info().Msgf("Firmware upgrade initiated for server '%s'", serverId)
-	return formatter.PrintResult(jobInfo, &formatter.PrintConfig{
+	if err := formatter.PrintResult(jobInfo, &formatter.PrintConfig{
 		FieldsConfig: map[string]formatter.RecordFieldConfig{
 			"JobId": {
 				Title: "Job ID",
@@ -275,7 +276,11 @@ func ServerFirmwareUpgrade(ctx context.Context, serverId string) error {
 				Order: 3,
 			},
 		},
-	})
+	}); err != nil {
+		return err
+	}
+
+	return job.WaitForResult(ctx, jobInfo)
 }
 
 // ServerFirmwareComponentUpgrade upgrades firmware for a specific component
@@ -306,7 +311,7 @@ func ServerFirmwareComponentUpgrade(ctx context.Context, serverId string, compon
 	}
 
 	logger.Get().Info().Msgf("Firmware upgrade initiated for component '%s' on server '%s'", componentId, serverId)
-	return formatter.PrintResult(jobInfo, &formatter.PrintConfig{
+	if err := formatter.PrintResult(jobInfo, &formatter.PrintConfig{
 		FieldsConfig: map[string]formatter.RecordFieldConfig{
 			"JobId": {
 				Title: "Job ID",
@@ -321,7 +326,11 @@ func ServerFirmwareComponentUpgrade(ctx context.Context, serverId string, compon
 				Order: 3,
 			},
 		},
-	})
+	}); err != nil {
+		return err
+	}
+
+	return job.WaitForResult(ctx, jobInfo)
 }
 
 // ServerFirmwareScheduleUpgrade schedules a firmware upgrade for a server
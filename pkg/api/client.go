@@ -3,7 +3,6 @@ package api
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
@@ -17,9 +16,10 @@ import (
 type ContextKey string
 
 const (
-	ApiClientContextKey       ContextKey = "apiClient"
-	UserIdContextKey          ContextKey = "userId"
-	UserAccessLevelContextKey ContextKey = "userAccessLevel"
+	ApiClientContextKey        ContextKey = "apiClient"
+	UserIdContextKey           ContextKey = "userId"
+	UserAccessLevelContextKey  ContextKey = "userAccessLevel"
+	TransportOptionsContextKey ContextKey = "transportOptions"
 )
 
 func GetApiClient(ctx context.Context) *sdk.APIClient {
@@ -58,7 +58,7 @@ func GetApiClientE(ctx context.Context) (*sdk.APIClient, error) {
 	return apiClient, nil
 }
 
-func SetApiClient(ctx context.Context, apiEndpoint string, apiKey string, debug bool, insecure bool) context.Context {
+func SetApiClient(ctx context.Context, apiEndpoint string, apiKey string, debug bool, transportOptions TransportOptions, retry RetryOptions) (context.Context, error) {
 	// Initialize API client using the arguments from the command line or environment variables
 	cfg := sdk.NewConfiguration()
 	cfg.UserAgent = "metalcloud-cli"
@@ -69,12 +69,15 @@ func SetApiClient(ctx context.Context, apiEndpoint string, apiKey string, debug
 		},
 	}
 
-	if insecure {
-		cfg.HTTPClient = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		}
+	// The SDK and the raw requests of DoJSONRequest share this client
+	transport, err := NewTransport(transportOptions)
+	if err != nil {
+		logger.Get().Error().Err(err).Msg("")
+		return ctx, err
+	}
+
+	cfg.HTTPClient = &http.Client{
+		Transport: NewRetryTransport(transport, retry),
 	}
 
 	// Set debug mode
@@ -84,9 +87,10 @@ func SetApiClient(ctx context.Context, apiEndpoint string, apiKey string, debug
 	apiClient := sdk.NewAPIClient(cfg)
 
 	ctx = context.WithValue(ctx, ApiClientContextKey, apiClient)
+	ctx = context.WithValue(ctx, TransportOptionsContextKey, transportOptions)
 	ctx = context.WithValue(ctx, sdk.ContextAccessToken, apiKey)
 
-	return ctx
+	return ctx, nil
 }
 
 // DoJSONRequest issues an HTTP request against the configured API endpoint using
@@ -184,6 +188,13 @@ func SetUserAccessLevel(ctx context.Context, level string) context.Context {
 	return context.WithValue(ctx, UserAccessLevelContextKey, level)
 }
 
+// GetTransportOptions returns the TLS and proxy settings the API client was
+// set up with, or the zero options when there is no API client.
+func GetTransportOptions(ctx context.Context) TransportOptions {
+	options, _ := ctx.Value(TransportOptionsContextKey).(TransportOptions)
+	return options
+}
+
 // IsAdminAccessLevel reports whether the given user access level grants
 // administrative scope. Values are sourced from the MetalSoft user model.
 func IsAdminAccessLevel(level string) bool {
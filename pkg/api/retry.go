@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
+)
+
+// RetryOptions controls how API requests are retried after transient
+// failures: connection errors and 429, 502, 503 and 504 responses.
+type RetryOptions struct {
+	// MaxAttempts is the total number of attempts, 1 disables retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles on each
+	// retry, up to MaxBackoff, and a random jitter of up to half is removed.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// RetryPost allows retrying POST and PATCH requests. They are not
+	// idempotent, so a retry after a lost response may repeat the operation.
+	RetryPost bool
+}
+
+// maxRetryAfter caps the delay requested by a Retry-After header.
+const maxRetryAfter = 5 * time.Minute
+
+// DefaultRetryOptions returns the options used when none are configured.
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+	}
+}
+
+type retryTransport struct {
+	next    http.RoundTripper
+	options RetryOptions
+}
+
+// NewRetryTransport wraps next so that requests are retried according to
+// options. Idempotent requests are always retried, POST and PATCH only when
+// options.RetryPost is set.
+func NewRetryTransport(next http.RoundTripper, options RetryOptions) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &retryTransport{next: next, options: options}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.options.MaxAttempts <= 1 || !t.canRetry(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	getBody, buffered, err := bodyReplayer(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 || buffered {
+			attemptReq = req.Clone(req.Context())
+			if getBody != nil {
+				if attemptReq.Body, err = getBody(); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		res, err := t.next.RoundTrip(attemptReq)
+		if attempt >= t.options.MaxAttempts || !shouldRetry(req.Context(), res, err) {
+			return res, err
+		}
+
+		delay := t.backoff(attempt, res)
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = res.Status
+			// Drain the body so the connection can be reused.
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		logger.Get().Debug().Msgf("Retrying %s %s in %s (attempt %d of %d): %s",
+			req.Method, req.URL.Redacted(), delay, attempt+1, t.options.MaxAttempts, reason)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (t *retryTransport) canRetry(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost, http.MethodPatch:
+		return t.options.RetryPost
+	}
+
+	return false
+}
+
+// backoff returns the delay before the retry following attempt, honouring a
+// Retry-After header when the server sends one.
+func (t *retryTransport) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if delay, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+			return min(delay, maxRetryAfter)
+		}
+	}
+
+	delay := t.options.InitialBackoff
+	for i := 1; i < attempt && delay < t.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, t.options.MaxBackoff)
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay - rand.N(delay/2+1)
+}
+
+func shouldRetry(ctx context.Context, res *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+// bodyReplayer returns a function producing a fresh copy of the request body
+// for each attempt, or nil when the request has no body. When the request
+// cannot recreate its body, the body is read into memory and buffered is true;
+// the original body is then consumed and every attempt must use a copy.
+func bodyReplayer(req *http.Request) (getBody func() (io.ReadCloser, error), buffered bool, err error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, false, nil
+	}
+	if req.GetBody != nil {
+		return req.GetBody, false, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}, true, nil
+}
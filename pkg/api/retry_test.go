@@ -0,0 +1,138 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newRetryTestClient(options RetryOptions) *http.Client {
+	return &http.Client{Transport: NewRetryTransport(nil, options)}
+}
+
+// flakyServer fails the first `failures` requests with status and then
+// echoes the request body.
+func flakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func TestRetryTransport_RetriesIdempotentRequests(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable, nil)
+
+	client := newRetryTestClient(RetryOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, strings.NewReader(`{"label":"a"}`))
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || string(body) != `{"label":"a"}` {
+		t.Errorf("expected the body to be replayed, got %d %q", res.StatusCode, body)
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestRetryTransport_GivesUpAfterMaxAttempts(t *testing.T) {
+	srv, calls := flakyServer(t, 10, http.StatusBadGateway, nil)
+
+	client := newRetryTestClient(RetryOptions{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected the last response to be returned, got %d", res.StatusCode)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestRetryTransport_PostOnlyWhenOptedIn(t *testing.T) {
+	for _, retryPost := range []bool{false, true} {
+		srv, calls := flakyServer(t, 1, http.StatusServiceUnavailable, nil)
+
+		client := newRetryTestClient(RetryOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, RetryPost: retryPost})
+		res, err := client.Post(srv.URL, "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+
+		expected := int32(1)
+		if retryPost {
+			expected = 2
+		}
+		if got := atomic.LoadInt32(calls); got != expected {
+			t.Errorf("retryPost=%v: expected %d attempts, got %d", retryPost, expected, got)
+		}
+	}
+}
+
+func TestRetryTransport_DoesNotRetryClientErrors(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusNotFound, nil)
+
+	client := newRetryTestClient(RetryOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("expected a single attempt for 404, got %d", got)
+	}
+}
+
+func TestRetryTransport_HonoursRetryAfter(t *testing.T) {
+	srv, _ := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"1"}})
+
+	client := newRetryTestClient(RetryOptions{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	start := time.Now()
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected to wait for Retry-After, retried after %s", elapsed)
+	}
+}
+
+func TestRetryTransport_Backoff(t *testing.T) {
+	transport := &retryTransport{options: RetryOptions{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}}
+
+	for attempt, ceiling := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		delay := transport.backoff(attempt, nil)
+		if delay < ceiling/2 || delay > ceiling {
+			t.Errorf("attempt %d: expected delay between %s and %s, got %s", attempt, ceiling/2, ceiling, delay)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// TransportOptions configures the TLS and proxy settings of the connection to
+// the API endpoint.
+type TransportOptions struct {
+	InsecureSkipVerify bool
+	// CABundle is a PEM file with CA certificates trusted in addition to the
+	// system ones, e.g. the internal CA of an on-prem controller.
+	CABundle string
+	// ClientCert and ClientKey are PEM files used for mutual TLS.
+	ClientCert string
+	ClientKey  string
+	// MinTLSVersion is "1.0", "1.1", "1.2" or "1.3". Empty uses the Go default.
+	MinTLSVersion string
+	// Proxy is the URL of the HTTP(S) proxy. Empty uses the HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy string
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewTransport returns an HTTP transport configured with options.
+func NewTransport(options TransportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify}
+
+	if options.CABundle != "" {
+		pem, err := os.ReadFile(options.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA bundle '%s'", options.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if options.ClientCert != "" || options.ClientKey != "" {
+		if options.ClientCert == "" || options.ClientKey == "" {
+			return nil, fmt.Errorf("both a client certificate and a client key are required for mutual TLS")
+		}
+
+		certificate, err := tls.LoadX509KeyPair(options.ClientCert, options.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if options.MinTLSVersion != "" {
+		version, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(options.MinTLSVersion), "tls")]
+		if !ok {
+			return nil, fmt.Errorf("invalid minimum TLS version '%s', supported values are 1.0, 1.1, 1.2 and 1.3", options.MinTLSVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	if options.Proxy != "" {
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL '%s'", options.Proxy)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme '%s', supported schemes are http, https and socks5", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return transport, nil
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeServerCA writes the certificate of a TLS test server as a CA bundle.
+func writeServerCA(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write CA bundle: %v", err)
+	}
+
+	return path
+}
+
+// writeClientCertificate writes a self-signed client certificate and its key.
+func writeClientCertificate(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "metalcloud-cli"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0o600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	return certPath, keyPath
+}
+
+func getWithTransport(t *testing.T, options TransportOptions, url string) error {
+	t.Helper()
+
+	transport, err := NewTransport(options)
+	if err != nil {
+		t.Fatalf("NewTransport: unexpected error: %v", err)
+	}
+
+	res, err := (&http.Client{Transport: transport}).Get(url)
+	if err == nil {
+		res.Body.Close()
+	}
+	return err
+}
+
+func TestNewTransport_CABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	if err := getWithTransport(t, TransportOptions{}, srv.URL); err == nil {
+		t.Error("expected certificate error without the CA bundle")
+	}
+	if err := getWithTransport(t, TransportOptions{CABundle: writeServerCA(t, srv)}, srv.URL); err != nil {
+		t.Errorf("expected the CA bundle to be trusted, got: %v", err)
+	}
+}
+
+func TestNewTransport_MutualTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caBundle := writeServerCA(t, srv)
+	if err := getWithTransport(t, TransportOptions{CABundle: caBundle}, srv.URL); err == nil {
+		t.Error("expected handshake error without a client certificate")
+	}
+
+	certPath, keyPath := writeClientCertificate(t)
+	options := TransportOptions{CABundle: caBundle, ClientCert: certPath, ClientKey: keyPath, MinTLSVersion: "1.2"}
+	if err := getWithTransport(t, options, srv.URL); err != nil {
+		t.Errorf("expected mutual TLS to succeed, got: %v", err)
+	}
+}
+
+func TestNewTransport_Errors(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	cases := map[string]TransportOptions{
+		"missing CA bundle":   {CABundle: filepath.Join(t.TempDir(), "missing.pem")},
+		"CA bundle not PEM":   {CABundle: notPEM},
+		"cert without key":    {ClientCert: notPEM},
+		"invalid TLS version": {MinTLSVersion: "1.4"},
+		"invalid proxy":       {Proxy: "://proxy"},
+		"unsupported proxy":   {Proxy: "ftp://proxy:21"},
+	}
+
+	for name, options := range cases {
+		if _, err := NewTransport(options); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestNewTransport_Proxy(t *testing.T) {
+	transport, err := NewTransport(TransportOptions{Proxy: "http://proxy.example:3128", MinTLSVersion: "TLS1.3"})
+	if err != nil {
+		t.Fatalf("NewTransport: unexpected error: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://metal.example/api/v2/users", nil)
+	proxyURL, err := transport.Proxy(req)
+	if err != nil || proxyURL == nil || proxyURL.Host != "proxy.example:3128" {
+		t.Errorf("expected requests to use the proxy, got %v (%v)", proxyURL, err)
+	}
+	if transport.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("expected minimum TLS 1.3, got %x", transport.TLSClientConfig.MinVersion)
+	}
+}
@@ -42,20 +42,26 @@ var (
 )
 
 // IsNativeFormat returns true if the configured output format is a native
-// data serialization format (JSON or YAML) rather than a tabular format
-// (text, csv, md). This is useful for determining whether to bypass certain
-// processing steps that are only relevant for table-based output.
+// data serialization format (JSON or YAML), or one of the template formats
+// operating on the JSON shape of the result (jsonpath, go-template,
+// custom-columns), rather than a tabular format (text, csv, md). This is
+// useful for determining whether to bypass certain processing steps that are
+// only relevant for table-based output.
 //
 // Returns:
-//   - true if the format is "json" or "yaml"
+//   - true if the format is "json", "yaml" or a template format
 //   - false otherwise (e.g., "text", "csv", "md", or any other value)
 func IsNativeFormat() bool {
-	return strings.ToLower(viper.GetString(ConfigFormat)) == "json" || strings.ToLower(viper.GetString(ConfigFormat)) == "yaml"
+	format, _ := outputFormat()
+	return format == "json" || format == "yaml" || isTemplateFormat(format)
 }
 
+// IsTextFormat returns true if the configured output format is the text
+// table, including its wide variant, so informational messages can be printed
+// without corrupting machine readable output.
 func IsTextFormat() bool {
 	f := strings.ToLower(viper.GetString(ConfigFormat))
-	return f == "" || f == "text"
+	return f == "" || f == "text" || f == FormatWide
 }
 
 // marshalYAML renders a value to YAML by first marshaling it to JSON. Most
@@ -91,7 +97,7 @@ func PrintYamlResult(result interface{}) error {
 }
 
 func PrintResult(result interface{}, printConfig *PrintConfig) error {
-	format := strings.ToLower(viper.GetString(ConfigFormat))
+	format, argument := outputFormat()
 	disableColor = false
 
 	switch format {
@@ -109,16 +115,10 @@ func PrintResult(result interface{}, printConfig *PrintConfig) error {
 			return fmt.Errorf("failed to convert to YAML: %v", err)
 		}
 		fmt.Printf("%s", string(yamlResult))
-	case "text":
-		generateTable(result, printConfig).Render()
-	case "csv":
-		disableColor = true
-		generateTable(result, printConfig).RenderCSV()
-		disableColor = false
-	case "md":
-		disableColor = true
-		generateTable(result, printConfig).RenderMarkdown()
-		disableColor = false
+	case "text", "csv", "md", FormatWide:
+		return renderTable(result, printConfig, format)
+	case FormatJSONPath, FormatJSONPathFile, FormatGoTemplate, FormatGoTemplateFile, FormatCustomColumns:
+		return printTemplateFormat(os.Stdout, result, format, argument)
 	default:
 		return fmt.Errorf("%s format not supported yet", format)
 	}
@@ -127,8 +127,17 @@ func PrintResult(result interface{}, printConfig *PrintConfig) error {
 }
 
 func generateTable(result interface{}, printConfig *PrintConfig) table.Writer {
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
+	header, rows, configs := tableData(result, printConfig)
+
+	return newTableWriter(header, rows, configs, false)
+}
+
+// tableData returns the header, the rows and the column configs of the table
+// rendered for the result.
+func tableData(result interface{}, printConfig *PrintConfig) (table.Row, []table.Row, []table.ColumnConfig) {
+	var header table.Row
+	var rows []table.Row
+	var configs []table.ColumnConfig
 
 	// Check if the result is a struct with slice field named data
 	paginatedData, ok := getPaginatedData(result)
@@ -136,19 +145,19 @@ func generateTable(result interface{}, printConfig *PrintConfig) table.Writer {
 		// Loop through the paginated data list
 		headerSet := false
 		for i := 0; i < paginatedData.Len(); i++ {
-			names, values, configs := getFieldNamesAndValues(paginatedData.Index(i).Interface(), printConfig)
+			names, values, columnConfigs := getFieldNamesAndValues(paginatedData.Index(i).Interface(), printConfig)
 			if !headerSet {
-				t.AppendHeader(names)
-				t.SetColumnConfigs(configs)
+				header = names
+				configs = columnConfigs
 
 				headerSet = true
 			}
-			t.AppendRows([]table.Row{values})
+			rows = append(rows, values)
 		}
 	} else if reflect.TypeOf(result).Kind() == reflect.String {
 		// Check if the result is a string
-		t.AppendHeader(table.Row{"Result"})
-		t.AppendRows([]table.Row{{result}})
+		header = table.Row{"Result"}
+		rows = []table.Row{{result}}
 	} else if reflect.TypeOf(result).Kind() == reflect.Map {
 		// Check if the result is a map
 		keys := make(table.Row, 0)
@@ -157,15 +166,28 @@ func generateTable(result interface{}, printConfig *PrintConfig) table.Writer {
 			keys = append(keys, k)
 			values = append(values, v)
 		}
-		t.AppendHeader(keys)
-		t.AppendRows([]table.Row{values})
+		header = keys
+		rows = []table.Row{values}
 	} else {
 		// Print the result as a table
-		names, values, configs := getFieldNamesAndValues(result, printConfig)
-		t.AppendHeader(names)
-		t.AppendRows([]table.Row{values})
-		t.SetColumnConfigs(configs)
+		names, values, columnConfigs := getFieldNamesAndValues(result, printConfig)
+		header = names
+		rows = []table.Row{values}
+		configs = columnConfigs
+	}
+
+	return header, rows, configs
+}
+
+func newTableWriter(header table.Row, rows []table.Row, configs []table.ColumnConfig, noHeaders bool) table.Writer {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+
+	if !noHeaders && len(header) > 0 {
+		t.AppendHeader(header)
 	}
+	t.AppendRows(rows)
+	t.SetColumnConfigs(configs)
 
 	t.SetStyle(table.StyleLight)
 
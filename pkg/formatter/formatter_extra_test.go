@@ -40,6 +40,7 @@ func TestIsTextFormat(t *testing.T) {
 		{"text", true},
 		{"TEXT", true},
 		{"", true},
+		{"wide", true},
 		{"json", false},
 		{"yaml", false},
 		{"csv", false},
@@ -0,0 +1,686 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a parsed JSONPath template in the kubectl dialect, e.g.
+// `{.data[*].id}` or `{range .data[*]}{.id}{"\t"}{.label}{"\n"}{end}`. Text
+// outside braces is printed as is. A template without braces is a single
+// expression, so `.data[0].label` is the same as `{.data[0].label}`.
+//
+// Supported expressions: `$` (root), `@` (current item), `.field`,
+// `['field']`, `..field` (recursive descent), `*`, `[n]`, `[-n]`,
+// `[start:end:step]`, `[a,b]` and filters such as `[?(@.status=="active")]`.
+type JSONPath struct {
+	nodes []jsonPathNode
+}
+
+// jsonPathNode is a piece of a template: literal text, an expression or a
+// range over the results of an expression.
+type jsonPathNode struct {
+	text    string
+	path    *pathExpression
+	isRange bool
+	body    []jsonPathNode
+}
+
+type segmentKind int
+
+const (
+	segmentField segmentKind = iota
+	segmentWildcard
+	segmentRecursive
+	segmentIndex
+	segmentSlice
+	segmentFilter
+)
+
+type pathSegment struct {
+	kind    segmentKind
+	names   []string
+	indices []int
+	slice   [3]*int
+	filter  *pathFilter
+}
+
+// pathFilter is a `[?(@.field op value)]` filter. An empty operator only
+// checks that the field exists.
+type pathFilter struct {
+	left     *pathExpression
+	operator string
+	right    interface{}
+}
+
+// pathExpression is a single expression such as `.data[*].id`.
+type pathExpression struct {
+	fromRoot bool
+	segments []pathSegment
+}
+
+// ParseJSONPath parses a JSONPath template.
+func ParseJSONPath(template string) (*JSONPath, error) {
+	if !strings.Contains(template, "{") {
+		template = "{" + template + "}"
+	}
+
+	parser := jsonPathParser{input: template}
+	nodes, err := parser.parseNodes(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONPath{nodes: nodes}, nil
+}
+
+// Execute writes the template evaluated against data, which must have the
+// shape produced by decoding JSON.
+func (j *JSONPath) Execute(w io.Writer, data interface{}) error {
+	return executeJSONPathNodes(w, j.nodes, data, data)
+}
+
+func executeJSONPathNodes(w io.Writer, nodes []jsonPathNode, root interface{}, current interface{}) error {
+	for _, node := range nodes {
+		switch {
+		case node.isRange:
+			for _, item := range node.path.evaluate(root, current) {
+				if err := executeJSONPathNodes(w, node.body, root, item); err != nil {
+					return err
+				}
+			}
+
+		case node.path != nil:
+			values := node.path.evaluate(root, current)
+			texts := make([]string, 0, len(values))
+			for _, value := range values {
+				text, err := formatJSONPathValue(value)
+				if err != nil {
+					return err
+				}
+				texts = append(texts, text)
+			}
+			if _, err := io.WriteString(w, strings.Join(texts, " ")); err != nil {
+				return err
+			}
+
+		default:
+			if _, err := io.WriteString(w, node.text); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// formatJSONPathValue prints strings and numbers as is, and everything else
+// as JSON.
+func formatJSONPathValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert to JSON: %v", err)
+	}
+
+	return string(data), nil
+}
+
+type jsonPathParser struct {
+	input string
+	pos   int
+}
+
+// parseNodes parses the template up to its end or, when inRange is set, up to
+// the {end} closing the range.
+func (p *jsonPathParser) parseNodes(inRange bool) ([]jsonPathNode, error) {
+	nodes := []jsonPathNode{}
+
+	for p.pos < len(p.input) {
+		rest := p.input[p.pos:]
+		open := strings.Index(rest, "{")
+		if open < 0 {
+			nodes = append(nodes, jsonPathNode{text: rest})
+			p.pos = len(p.input)
+			break
+		}
+		if open > 0 {
+			nodes = append(nodes, jsonPathNode{text: rest[:open]})
+		}
+
+		closing := findClosing(rest, open, '{', '}')
+		if closing < 0 {
+			return nil, fmt.Errorf("unclosed action in %q", rest[open:])
+		}
+		action := strings.TrimSpace(rest[open+1 : closing])
+		p.pos += closing + 1
+
+		switch {
+		case action == "end":
+			if !inRange {
+				return nil, fmt.Errorf("{end} without {range}")
+			}
+			return nodes, nil
+
+		case strings.HasPrefix(action, "range "):
+			path, err := parsePathExpression(strings.TrimPrefix(action, "range "))
+			if err != nil {
+				return nil, err
+			}
+			body, err := p.parseNodes(true)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, jsonPathNode{path: path, isRange: true, body: body})
+
+		case strings.HasPrefix(action, `"`):
+			text, err := strconv.Unquote(action)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string literal %s", action)
+			}
+			nodes = append(nodes, jsonPathNode{text: text})
+
+		default:
+			path, err := parsePathExpression(action)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, jsonPathNode{path: path})
+		}
+	}
+
+	if inRange {
+		return nil, fmt.Errorf("{range} without {end}")
+	}
+
+	return nodes, nil
+}
+
+// findClosing returns the index of the close character matching the open
+// character at s[start], ignoring characters inside quotes, or -1.
+func findClosing(s string, start int, open byte, close byte) int {
+	depth := 0
+	var quote byte
+
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == open:
+			depth++
+		case c == close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// parsePathExpression parses a single expression. The leading dot may be
+// omitted, so `data.id` is the same as `.data.id`.
+func parsePathExpression(expression string) (*pathExpression, error) {
+	s := strings.TrimSpace(expression)
+	if s == "" {
+		return nil, fmt.Errorf("empty JSONPath expression")
+	}
+
+	path := &pathExpression{}
+	switch s[0] {
+	case '$':
+		path.fromRoot = true
+		s = s[1:]
+	case '@':
+		s = s[1:]
+	case '.', '[':
+	default:
+		s = "." + s
+	}
+
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			name, rest := splitPathName(s[2:])
+			if name == "" {
+				return nil, fmt.Errorf("missing field name after '..' in %q", expression)
+			}
+			segment := pathSegment{kind: segmentRecursive}
+			if name != "*" {
+				segment.names = []string{name}
+			}
+			path.segments = append(path.segments, segment)
+			s = rest
+
+		case s[0] == '.':
+			name, rest := splitPathName(s[1:])
+			s = rest
+			switch name {
+			case "":
+				// "." alone is the current item, ".[0]" the same as "[0]"
+				if rest != "" && rest[0] != '[' {
+					return nil, fmt.Errorf("missing field name in %q", expression)
+				}
+			case "*":
+				path.segments = append(path.segments, pathSegment{kind: segmentWildcard})
+			default:
+				path.segments = append(path.segments, pathSegment{kind: segmentField, names: []string{name}})
+			}
+
+		case s[0] == '[':
+			closing := findClosing(s, 0, '[', ']')
+			if closing < 0 {
+				return nil, fmt.Errorf("unclosed '[' in %q", expression)
+			}
+			segment, err := parsePathBracket(strings.TrimSpace(s[1:closing]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath expression %q: %v", expression, err)
+			}
+			path.segments = append(path.segments, segment)
+			s = s[closing+1:]
+
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", s[0], expression)
+		}
+	}
+
+	return path, nil
+}
+
+func splitPathName(s string) (string, string) {
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		return strings.TrimSpace(s), ""
+	}
+
+	return strings.TrimSpace(s[:end]), s[end:]
+}
+
+func parsePathBracket(content string) (pathSegment, error) {
+	switch {
+	case content == "*":
+		return pathSegment{kind: segmentWildcard}, nil
+
+	case strings.HasPrefix(content, "?(") && strings.HasSuffix(content, ")"):
+		filter, err := parsePathFilter(content[2 : len(content)-1])
+		if err != nil {
+			return pathSegment{}, err
+		}
+		return pathSegment{kind: segmentFilter, filter: filter}, nil
+
+	case strings.HasPrefix(content, "'") || strings.HasPrefix(content, `"`):
+		names := []string{}
+		for _, part := range splitOutsideQuotes(content, ',') {
+			name, ok := unquotePathString(strings.TrimSpace(part))
+			if !ok {
+				return pathSegment{}, fmt.Errorf("invalid field name %s", part)
+			}
+			names = append(names, name)
+		}
+		return pathSegment{kind: segmentField, names: names}, nil
+
+	case strings.Contains(content, ":"):
+		parts := strings.Split(content, ":")
+		if len(parts) > 3 {
+			return pathSegment{}, fmt.Errorf("invalid slice [%s]", content)
+		}
+		segment := pathSegment{kind: segmentSlice}
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return pathSegment{}, fmt.Errorf("invalid slice [%s]", content)
+			}
+			segment.slice[i] = &value
+		}
+		if step := segment.slice[2]; step != nil && *step <= 0 {
+			return pathSegment{}, fmt.Errorf("slice step must be positive in [%s]", content)
+		}
+		return segment, nil
+
+	default:
+		segment := pathSegment{kind: segmentIndex}
+		for _, part := range strings.Split(content, ",") {
+			index, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return pathSegment{}, fmt.Errorf("invalid index [%s]", content)
+			}
+			segment.indices = append(segment.indices, index)
+		}
+		return segment, nil
+	}
+}
+
+var pathFilterOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func parsePathFilter(expression string) (*pathFilter, error) {
+	var quote byte
+	for i := 0; i < len(expression); i++ {
+		c := expression[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+			continue
+		}
+
+		for _, operator := range pathFilterOperators {
+			if !strings.HasPrefix(expression[i:], operator) {
+				continue
+			}
+
+			left, err := parsePathExpression(expression[:i])
+			if err != nil {
+				return nil, err
+			}
+			right, err := parseFilterLiteral(strings.TrimSpace(expression[i+len(operator):]))
+			if err != nil {
+				return nil, err
+			}
+			return &pathFilter{left: left, operator: operator, right: right}, nil
+		}
+	}
+
+	left, err := parsePathExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pathFilter{left: left}, nil
+}
+
+func parseFilterLiteral(literal string) (interface{}, error) {
+	if value, ok := unquotePathString(literal); ok {
+		return value, nil
+	}
+
+	switch literal {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	if _, err := strconv.ParseFloat(literal, 64); err == nil {
+		return json.Number(literal), nil
+	}
+
+	return nil, fmt.Errorf("invalid filter value %q", literal)
+}
+
+func unquotePathString(s string) (string, bool) {
+	if len(s) < 2 || s[0] != s[len(s)-1] {
+		return "", false
+	}
+
+	switch s[0] {
+	case '"':
+		value, err := strconv.Unquote(s)
+		return value, err == nil
+	case '\'':
+		return s[1 : len(s)-1], true
+	}
+
+	return "", false
+}
+
+// splitOutsideQuotes splits s at each separator that is not inside quotes or
+// brackets.
+func splitOutsideQuotes(s string, separator byte) []string {
+	parts := []string{}
+	depth, start := 0, 0
+	var quote byte
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '(' || c == '{':
+			depth++
+		case c == ']' || c == ')' || c == '}':
+			depth--
+		case c == separator && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// evaluate returns the values matched by the expression, starting from root
+// for `$` expressions and from current otherwise. Missing fields match nothing.
+func (e *pathExpression) evaluate(root interface{}, current interface{}) []interface{} {
+	nodes := []interface{}{current}
+	if e.fromRoot {
+		nodes = []interface{}{root}
+	}
+
+	for _, segment := range e.segments {
+		next := []interface{}{}
+		for _, node := range nodes {
+			next = segment.apply(root, node, next)
+		}
+		nodes = next
+	}
+
+	return nodes
+}
+
+func (s *pathSegment) apply(root interface{}, node interface{}, results []interface{}) []interface{} {
+	switch s.kind {
+	case segmentField:
+		if object, ok := node.(map[string]interface{}); ok {
+			for _, name := range s.names {
+				if value, ok := object[name]; ok {
+					results = append(results, value)
+				}
+			}
+		}
+
+	case segmentWildcard:
+		results = append(results, jsonChildren(node)...)
+
+	case segmentRecursive:
+		results = s.applyRecursive(node, results)
+
+	case segmentIndex:
+		if list, ok := node.([]interface{}); ok {
+			for _, index := range s.indices {
+				if index < 0 {
+					index += len(list)
+				}
+				if index >= 0 && index < len(list) {
+					results = append(results, list[index])
+				}
+			}
+		}
+
+	case segmentSlice:
+		if list, ok := node.([]interface{}); ok {
+			start, end, step := 0, len(list), 1
+			if s.slice[0] != nil {
+				start = sliceBound(*s.slice[0], len(list))
+			}
+			if s.slice[1] != nil {
+				end = sliceBound(*s.slice[1], len(list))
+			}
+			if s.slice[2] != nil {
+				step = *s.slice[2]
+			}
+			for i := start; i < end; i += step {
+				results = append(results, list[i])
+			}
+		}
+
+	case segmentFilter:
+		if list, ok := node.([]interface{}); ok {
+			for _, item := range list {
+				if s.filter.matches(root, item) {
+					results = append(results, item)
+				}
+			}
+		}
+	}
+
+	return results
+}
+
+// applyRecursive collects the named field, or every value when no name is
+// given, from node and all its descendants.
+func (s *pathSegment) applyRecursive(node interface{}, results []interface{}) []interface{} {
+	if len(s.names) > 0 {
+		if object, ok := node.(map[string]interface{}); ok {
+			if value, ok := object[s.names[0]]; ok {
+				results = append(results, value)
+			}
+		}
+	}
+
+	for _, child := range jsonChildren(node) {
+		if len(s.names) == 0 {
+			results = append(results, child)
+		}
+		results = s.applyRecursive(child, results)
+	}
+
+	return results
+}
+
+func sliceBound(index int, length int) int {
+	if index < 0 {
+		index += length
+	}
+
+	return max(0, min(index, length))
+}
+
+// jsonChildren returns the elements of a list or the values of an object,
+// ordered by key.
+func jsonChildren(node interface{}) []interface{} {
+	switch value := node.(type) {
+	case []interface{}:
+		return value
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		children := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			children = append(children, value[key])
+		}
+		return children
+	}
+
+	return nil
+}
+
+func (f *pathFilter) matches(root interface{}, item interface{}) bool {
+	values := f.left.evaluate(root, item)
+	if f.operator == "" {
+		return len(values) > 0
+	}
+
+	for _, value := range values {
+		if compareJSONValues(value, f.operator, f.right) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func compareJSONValues(left interface{}, operator string, right interface{}) bool {
+	var comparison int
+
+	leftNumber, leftIsNumber := jsonNumberValue(left)
+	rightNumber, rightIsNumber := jsonNumberValue(right)
+	leftString, leftIsString := left.(string)
+	rightString, rightIsString := right.(string)
+
+	switch {
+	case leftIsNumber && rightIsNumber:
+		switch {
+		case leftNumber < rightNumber:
+			comparison = -1
+		case leftNumber > rightNumber:
+			comparison = 1
+		}
+	case leftIsString && rightIsString:
+		comparison = strings.Compare(leftString, rightString)
+	default:
+		// Booleans and null can only be compared for equality
+		equal := left == right
+		switch operator {
+		case "==":
+			return equal
+		case "!=":
+			return !equal
+		}
+		return false
+	}
+
+	switch operator {
+	case "==":
+		return comparison == 0
+	case "!=":
+		return comparison != 0
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case ">":
+		return comparison > 0
+	case ">=":
+		return comparison >= 0
+	}
+
+	return false
+}
+
+func jsonNumberValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,92 @@
+package formatter
+
+import (
+	"bytes"
+	"testing"
+)
+
+const jsonPathTestDocument = `{
+	"data": [
+		{"id": 24671856123, "label": "web", "status": "active", "config": {"tags": ["a", "b"]}},
+		{"id": 2, "label": "db", "status": "ordered", "config": {"tags": []}},
+		{"id": 3, "label": "cache", "status": "active"}
+	],
+	"meta": {"totalItems": 3}
+}`
+
+func decodeTestDocument(t *testing.T) interface{} {
+	t.Helper()
+
+	data, err := toJSONValue(rawJSON(jsonPathTestDocument))
+	if err != nil {
+		t.Fatalf("decode test document: %v", err)
+	}
+
+	return data
+}
+
+// rawJSON marshals to itself.
+type rawJSON string
+
+func (r rawJSON) MarshalJSON() ([]byte, error) {
+	return []byte(r), nil
+}
+
+func TestJSONPath_Execute(t *testing.T) {
+	data := decodeTestDocument(t)
+
+	cases := map[string]string{
+		`{.meta.totalItems}`:                                   "3",
+		`.data[0].label`:                                       "web",
+		`{.data[*].id}`:                                        "24671856123 2 3",
+		`{.data[-1].label}`:                                    "cache",
+		`{.data[0:2].label}`:                                   "web db",
+		`{.data[::2].label}`:                                   "web cache",
+		`{.data[0,2].id}`:                                      "24671856123 3",
+		`{.data[0]['label','status']}`:                         "web active",
+		`{.data[?(@.status=="active")].label}`:                 "web cache",
+		`{.data[?(@.id > 2)].label}`:                           "web cache",
+		`{.data[?(@.config)].label}`:                           "web db",
+		`{..tags[0]}`:                                          "a",
+		`{.data[0].config}`:                                    `{"tags":["a","b"]}`,
+		`{.missing}`:                                           "",
+		`id={$.data[1].id}`:                                    "id=2",
+		`{range .data[*]}{.id}{"\t"}{.label}{"\n"}{end}`:       "24671856123\tweb\n2\tdb\n3\tcache\n",
+		`{range .data[?(@.status=="active")]}[{.label}]{end}`:  "[web][cache]",
+		`{range .data[*]}{range .config.tags[*]}{@}{end}{end}`: "ab",
+	}
+
+	for template, expected := range cases {
+		path, err := ParseJSONPath(template)
+		if err != nil {
+			t.Errorf("%s: unexpected parse error: %v", template, err)
+			continue
+		}
+
+		out := bytes.Buffer{}
+		if err := path.Execute(&out, data); err != nil {
+			t.Errorf("%s: unexpected execute error: %v", template, err)
+			continue
+		}
+		if out.String() != expected {
+			t.Errorf("%s: expected %q, got %q", template, expected, out.String())
+		}
+	}
+}
+
+func TestParseJSONPath_Errors(t *testing.T) {
+	for _, template := range []string{
+		`{.data[0}`,
+		`{.data[*].id`,
+		`{range .data[*]}{.id}`,
+		`{.id}{end}`,
+		`{.data[a]}`,
+		`{.data[::0]}`,
+		`{.data[?(@.id == active)]}`,
+		`{"unterminated}`,
+	} {
+		if _, err := ParseJSONPath(template); err == nil {
+			t.Errorf("%s: expected parse error", template)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/spf13/viper"
+)
+
+// Output formats that take an argument after '=', e.g. `jsonpath={.data[*].id}`.
+// They all operate on the JSON shape of the result, as printed by the json format.
+const (
+	FormatJSONPath         = "jsonpath"
+	FormatJSONPathFile     = "jsonpath-file"
+	FormatGoTemplate       = "go-template"
+	FormatGoTemplateFile   = "go-template-file"
+	FormatCustomColumns    = "custom-columns"
+	customColumnsNoneValue = "<none>"
+)
+
+// outputFormat returns the lowercase name of the configured output format and
+// its argument, which keeps its case.
+func outputFormat() (string, string) {
+	name, argument, _ := strings.Cut(viper.GetString(ConfigFormat), "=")
+	return strings.ToLower(strings.TrimSpace(name)), argument
+}
+
+// isTemplateFormat reports whether the format renders the JSON shape of the
+// result through a user supplied expression.
+func isTemplateFormat(format string) bool {
+	switch format {
+	case FormatJSONPath, FormatJSONPathFile, FormatGoTemplate, FormatGoTemplateFile, FormatCustomColumns:
+		return true
+	}
+
+	return false
+}
+
+// toJSONValue converts the result to the generic value decoded from its JSON
+// form. Numbers are kept as json.Number so large IDs are not rounded.
+func toJSONValue(result interface{}) (interface{}, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert to JSON: %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to convert to JSON: %v", err)
+	}
+
+	return value, nil
+}
+
+// printTemplateFormat prints the result with one of the template formats.
+func printTemplateFormat(w io.Writer, result interface{}, format string, argument string) error {
+	if strings.TrimSpace(argument) == "" {
+		return fmt.Errorf("the %s format requires an argument, e.g. --format %s", format, formatExample(format))
+	}
+
+	if format == FormatJSONPathFile || format == FormatGoTemplateFile {
+		content, err := os.ReadFile(argument)
+		if err != nil {
+			return fmt.Errorf("failed to read template file: %v", err)
+		}
+		argument = strings.TrimRight(string(content), "\r\n")
+		format = strings.TrimSuffix(format, "-file")
+	}
+
+	data, err := toJSONValue(result)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatJSONPath:
+		path, err := ParseJSONPath(argument)
+		if err != nil {
+			return fmt.Errorf("invalid jsonpath template: %v", err)
+		}
+		return path.Execute(w, data)
+
+	case FormatGoTemplate:
+		tmpl, err := template.New("output").Funcs(templateFuncs).Option("missingkey=zero").Parse(argument)
+		if err != nil {
+			return fmt.Errorf("invalid go-template: %v", err)
+		}
+		if err := tmpl.Execute(w, data); err != nil {
+			return fmt.Errorf("failed to execute go-template: %v", err)
+		}
+		return nil
+
+	default:
+		return printCustomColumns(w, data, argument)
+	}
+}
+
+func formatExample(format string) string {
+	switch format {
+	case FormatJSONPath:
+		return "'jsonpath={.data[*].id}'"
+	case FormatJSONPathFile, FormatGoTemplateFile:
+		return format + "=template.txt"
+	case FormatGoTemplate:
+		return `'go-template={{range .data}}{{.id}}{{"\n"}}{{end}}'`
+	}
+
+	return "custom-columns=ID:.id,LABEL:.label"
+}
+
+var templateFuncs = template.FuncMap{
+	// json renders a value as JSON, e.g. {{json .tags}}
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	// join joins the elements of a list with a separator, e.g. {{join .tags ","}}
+	"join": func(value interface{}, separator string) (string, error) {
+		if value == nil {
+			return "", nil
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			return formatJSONPathValue(value)
+		}
+		texts := make([]string, 0, len(list))
+		for _, item := range list {
+			text, err := formatJSONPathValue(item)
+			if err != nil {
+				return "", err
+			}
+			texts = append(texts, text)
+		}
+		return strings.Join(texts, separator), nil
+	},
+}
+
+type customColumn struct {
+	title string
+	path  *pathExpression
+}
+
+// parseCustomColumns parses a `TITLE:.path,TITLE:.path` column specification.
+func parseCustomColumns(spec string) ([]customColumn, error) {
+	columns := []customColumn{}
+
+	for _, part := range splitOutsideQuotes(spec, ',') {
+		title, expression, ok := strings.Cut(part, ":")
+		title, expression = strings.TrimSpace(title), strings.TrimSpace(expression)
+		if !ok || title == "" || expression == "" {
+			return nil, fmt.Errorf("invalid custom column %q, expected TITLE:.path", part)
+		}
+
+		// Accept the braces of the jsonpath format, e.g. ID:{.id}
+		if strings.HasPrefix(expression, "{") && strings.HasSuffix(expression, "}") {
+			expression = expression[1 : len(expression)-1]
+		}
+
+		path, err := parsePathExpression(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid custom column %q: %v", part, err)
+		}
+		columns = append(columns, customColumn{title: title, path: path})
+	}
+
+	return columns, nil
+}
+
+// jsonRows returns the records of a result: the elements of a list, the
+// elements of the `data` list of a paginated response, or the result itself.
+func jsonRows(data interface{}) []interface{} {
+	switch value := data.(type) {
+	case []interface{}:
+		return value
+	case map[string]interface{}:
+		if list, ok := value["data"].([]interface{}); ok {
+			return list
+		}
+	}
+
+	return []interface{}{data}
+}
+
+func printCustomColumns(w io.Writer, data interface{}, spec string) error {
+	columns, err := parseCustomColumns(spec)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 10, 4, 3, ' ', 0)
+
+	titles := make([]string, 0, len(columns))
+	for _, column := range columns {
+		titles = append(titles, column.title)
+	}
+	if !viper.GetBool(ConfigNoHeaders) {
+		fmt.Fprintln(tw, strings.Join(titles, "\t"))
+	}
+
+	for _, row := range jsonRows(data) {
+		cells := make([]string, 0, len(columns))
+		for _, column := range columns {
+			values := column.path.evaluate(data, row)
+			texts := make([]string, 0, len(values))
+			for _, value := range values {
+				text, err := formatJSONPathValue(value)
+				if err != nil {
+					return err
+				}
+				texts = append(texts, text)
+			}
+
+			cell := strings.Join(texts, ",")
+			if len(values) == 0 || (len(values) == 1 && values[0] == nil) {
+				cell = customColumnsNoneValue
+			}
+			// A tab or newline in a value would break the alignment
+			cells = append(cells, strings.NewReplacer("\t", " ", "\n", " ").Replace(cell))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,104 @@
+package formatter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+type outputTestRecord struct {
+	Id     int64             `json:"id"`
+	Label  string            `json:"label"`
+	Status string            `json:"status,omitempty"`
+	Tags   []string          `json:"tags,omitempty"`
+	Meta   map[string]string `json:"meta,omitempty"`
+}
+
+type outputTestPage struct {
+	Data []outputTestRecord `json:"data"`
+}
+
+func TestPrintTemplateFormat(t *testing.T) {
+	templateFile := filepath.Join(t.TempDir(), "template.txt")
+	if err := os.WriteFile(templateFile, []byte("{{range .data}}{{.label}};{{end}}\n"), 0o600); err != nil {
+		t.Fatalf("write template file: %v", err)
+	}
+
+	page := outputTestPage{Data: []outputTestRecord{
+		{Id: 24671856123, Label: "web", Status: "active", Tags: []string{"a", "b"}},
+		{Id: 2, Label: "db"},
+	}}
+
+	cases := []struct {
+		format   string
+		argument string
+		expected string
+	}{
+		{FormatJSONPath, `{.data[*].label}`, "web db"},
+		{FormatGoTemplate, `{{range .data}}{{.id}} {{join .tags ","}}{{"\n"}}{{end}}`, "24671856123 a,b\n2 \n"},
+		{FormatGoTemplate, `{{(index .data 0).tags | json}}`, `["a","b"]`},
+		{FormatGoTemplateFile, templateFile, "web;db;"},
+		{FormatCustomColumns, "ID:.id,LABEL:label,STATUS:{.status},TAGS:.tags[*]", "" +
+			"ID            LABEL     STATUS    TAGS\n" +
+			"24671856123   web       active    a,b\n" +
+			"2             db        <none>    <none>\n"},
+	}
+
+	for _, tc := range cases {
+		out := bytes.Buffer{}
+		if err := printTemplateFormat(&out, page, tc.format, tc.argument); err != nil {
+			t.Errorf("%s=%s: unexpected error: %v", tc.format, tc.argument, err)
+			continue
+		}
+		if out.String() != tc.expected {
+			t.Errorf("%s=%s: expected:\n%q\ngot:\n%q", tc.format, tc.argument, tc.expected, out.String())
+		}
+	}
+}
+
+func TestPrintTemplateFormat_SingleRecord(t *testing.T) {
+	out := bytes.Buffer{}
+	record := outputTestRecord{Id: 7, Label: "web"}
+	if err := printTemplateFormat(&out, &record, FormatCustomColumns, "NAME:.label"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(out.String(), "web\n") {
+		t.Errorf("expected a single row, got %q", out.String())
+	}
+}
+
+func TestPrintTemplateFormat_Errors(t *testing.T) {
+	cases := map[string]string{
+		FormatJSONPath:       "{.data[",
+		FormatGoTemplate:     "{{.data",
+		FormatCustomColumns:  "ID",
+		FormatJSONPathFile:   filepath.Join(t.TempDir(), "missing.txt"),
+		FormatGoTemplateFile: "",
+	}
+
+	for format, argument := range cases {
+		if err := printTemplateFormat(&bytes.Buffer{}, outputTestPage{}, format, argument); err == nil {
+			t.Errorf("%s=%s: expected error", format, argument)
+		}
+	}
+}
+
+func TestPrintResult_TemplateFormatKeepsArgumentCase(t *testing.T) {
+	viper.Set(ConfigFormat, "JSONPath={.Label}")
+	defer viper.Set(ConfigFormat, "json")
+
+	format, argument := outputFormat()
+	if format != FormatJSONPath || argument != "{.Label}" {
+		t.Errorf("expected jsonpath and {.Label}, got %q and %q", format, argument)
+	}
+	if !IsNativeFormat() || IsTextFormat() {
+		t.Error("expected jsonpath to be a native, non-text format")
+	}
+	if err := PrintResult(outputTestRecord{Label: "web"}, nil); err != nil {
+		t.Errorf("PrintResult jsonpath: unexpected error: %v", err)
+	}
+}
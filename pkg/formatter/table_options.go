@@ -0,0 +1,487 @@
+package formatter
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ConfigOutputFormat is the -o alias of the format setting, e.g. -o wide.
+	ConfigOutputFormat = "output-format"
+	ConfigColumns      = "columns"
+	ConfigSortBy       = "sort-by"
+	ConfigNoHeaders    = "no-headers"
+
+	// FormatWide is the text table with the hidden fields of the print
+	// configuration revealed.
+	FormatWide = "wide"
+)
+
+// tableOptions are the global settings changing the table formats.
+type tableOptions struct {
+	wide bool
+	// columns are the titles or JSON paths of the columns to show, in order.
+	columns []string
+	// sortBy is the title or JSON path of the column the rows are sorted by.
+	sortBy     string
+	descending bool
+	noHeaders  bool
+}
+
+// sortColumn is the column the table rows are sorted by. A helper column is
+// only added for sorting and removed before rendering.
+type sortColumn struct {
+	index  int
+	helper bool
+}
+
+func tableOptionsFromConfig(format string) tableOptions {
+	options := tableOptions{
+		wide:      format == FormatWide,
+		noHeaders: viper.GetBool(ConfigNoHeaders),
+	}
+
+	// The environment variable is a single comma separated string
+	for _, value := range viper.GetStringSlice(ConfigColumns) {
+		for _, column := range strings.Split(value, ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				options.columns = append(options.columns, column)
+			}
+		}
+	}
+
+	options.sortBy = strings.TrimSpace(viper.GetString(ConfigSortBy))
+	if separator := strings.LastIndex(options.sortBy, ":"); separator >= 0 {
+		switch strings.ToUpper(options.sortBy[separator+1:]) {
+		case "DESC":
+			options.descending = true
+			options.sortBy = options.sortBy[:separator]
+		case "ASC":
+			options.sortBy = options.sortBy[:separator]
+		}
+	}
+
+	return options
+}
+
+// renderTable prints the result as a text, wide, csv or markdown table.
+func renderTable(result interface{}, printConfig *PrintConfig, format string) error {
+	options := tableOptionsFromConfig(format)
+
+	printConfig, sorting, err := options.apply(printConfig)
+	if err != nil {
+		return err
+	}
+
+	header, rows, configs := tableData(result, printConfig)
+
+	if options.sortBy != "" {
+		if sorting == nil {
+			index := headerIndex(header, options.sortBy)
+			if index < 0 {
+				return fmt.Errorf("unknown sort column '%s'", options.sortBy)
+			}
+			sorting = &sortColumn{index: index}
+		}
+
+		sortRows(rows, sorting.index, options.descending)
+
+		if sorting.helper {
+			header, rows, configs = removeColumn(header, rows, configs, sorting.index)
+		}
+	}
+
+	t := newTableWriter(header, rows, configs, options.noHeaders)
+
+	switch format {
+	case "csv":
+		disableColor = true
+		t.RenderCSV()
+		disableColor = false
+	case "md":
+		disableColor = true
+		t.RenderMarkdown()
+		disableColor = false
+	default:
+		t.Render()
+	}
+
+	return nil
+}
+
+// fieldReference locates a field in a FieldsConfig tree.
+type fieldReference struct {
+	fields map[string]RecordFieldConfig
+	key    string
+	// paths are the full Go field paths of the field, one per alternative
+	// name, e.g. "Config.DeployStatus".
+	paths []string
+}
+
+func (r fieldReference) config() RecordFieldConfig {
+	return r.fields[r.key]
+}
+
+func (r fieldReference) update(change func(*RecordFieldConfig)) {
+	config := r.fields[r.key]
+	change(&config)
+	r.fields[r.key] = config
+}
+
+func (r fieldReference) title() string {
+	if title := r.config().Title; title != "" {
+		return title
+	}
+
+	return r.key
+}
+
+// apply returns a copy of the print configuration changed by the options,
+// and the sort column when it can be located in the configuration. The
+// configuration is returned unchanged when no option applies.
+func (o tableOptions) apply(printConfig *PrintConfig) (*PrintConfig, *sortColumn, error) {
+	if !o.wide && len(o.columns) == 0 && o.sortBy == "" {
+		return printConfig, nil, nil
+	}
+
+	// Without a print configuration all fields are shown, and any JSON path
+	// can be selected
+	anyPath := printConfig == nil
+	if anyPath {
+		if len(o.columns) == 0 {
+			return nil, nil, nil
+		}
+		printConfig = &PrintConfig{}
+	}
+
+	fields := copyFieldsConfig(printConfig.FieldsConfig)
+	refs := collectFieldReferences(fields, nil, nil)
+
+	// Visible fields without an order get the next free positions, so the
+	// revealed and added columns cannot collide with them
+	maxOrder := 0
+	for _, ref := range refs {
+		if !ref.config().Hidden {
+			maxOrder = max(maxOrder, ref.config().Order)
+		}
+	}
+	for _, ref := range refs {
+		if config := ref.config(); !config.Hidden && config.Order == 0 {
+			maxOrder++
+			ref.update(func(c *RecordFieldConfig) { c.Order = maxOrder })
+		}
+	}
+
+	// Hidden fields with inner fields only group them, their own value is
+	// not worth a column
+	if o.wide {
+		for _, ref := range refs {
+			if config := ref.config(); config.Hidden && len(config.InnerFields) == 0 {
+				maxOrder++
+				ref.update(func(c *RecordFieldConfig) {
+					c.Hidden = false
+					c.Order = maxOrder
+				})
+			}
+		}
+	}
+
+	if len(o.columns) > 0 {
+		selected := make([]fieldReference, 0, len(o.columns))
+		seen := map[string]bool{}
+
+		for _, column := range o.columns {
+			ref, ok := findFieldReference(refs, column)
+			if !ok {
+				if ref, ok = addPathField(fields, column, anyPath); !ok {
+					return nil, nil, fmt.Errorf("unknown column '%s', available columns are: %s", column, strings.Join(columnTitles(refs), ", "))
+				}
+				refs = append(refs, ref)
+			}
+
+			if seen[ref.paths[0]] {
+				return nil, nil, fmt.Errorf("column '%s' is selected more than once", column)
+			}
+			seen[ref.paths[0]] = true
+			selected = append(selected, ref)
+		}
+
+		for _, ref := range refs {
+			ref.update(func(c *RecordFieldConfig) { c.Hidden = true })
+		}
+		for i, ref := range selected {
+			ref.update(func(c *RecordFieldConfig) {
+				c.Hidden = false
+				c.Order = i + 1
+			})
+		}
+		maxOrder = len(selected)
+	}
+
+	var sorting *sortColumn
+	if o.sortBy != "" {
+		ref, ok := findFieldReference(refs, o.sortBy)
+		if !ok {
+			if ref, ok = addPathField(fields, o.sortBy, anyPath); !ok {
+				return nil, nil, fmt.Errorf("unknown sort column '%s', available columns are: %s", o.sortBy, strings.Join(columnTitles(refs), ", "))
+			}
+		}
+
+		if config := ref.config(); !config.Hidden {
+			sorting = &sortColumn{index: config.Order - 1}
+		} else {
+			// Sort by a column that is not shown
+			maxOrder++
+			ref.update(func(c *RecordFieldConfig) {
+				c.Hidden = false
+				c.Order = maxOrder
+			})
+			sorting = &sortColumn{index: maxOrder - 1, helper: true}
+		}
+	}
+
+	return &PrintConfig{FieldsConfig: fields}, sorting, nil
+}
+
+func copyFieldsConfig(fields map[string]RecordFieldConfig) map[string]RecordFieldConfig {
+	result := make(map[string]RecordFieldConfig, len(fields))
+	for key, config := range fields {
+		if len(config.InnerFields) > 0 {
+			config.InnerFields = copyFieldsConfig(config.InnerFields)
+		}
+		result[key] = config
+	}
+
+	return result
+}
+
+// collectFieldReferences returns references to all the fields of the tree,
+// ordered by name so that lookups are deterministic.
+func collectFieldReferences(fields map[string]RecordFieldConfig, parentPaths []string, refs []fieldReference) []fieldReference {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		paths := []string{}
+		for _, name := range strings.Split(key, "|") {
+			if len(parentPaths) == 0 {
+				paths = append(paths, name)
+			}
+			for _, parent := range parentPaths {
+				paths = append(paths, parent+"."+name)
+			}
+		}
+
+		refs = append(refs, fieldReference{fields: fields, key: key, paths: paths})
+
+		if inner := fields[key].InnerFields; len(inner) > 0 {
+			refs = collectFieldReferences(inner, paths, refs)
+		}
+	}
+
+	return refs
+}
+
+// findFieldReference finds the field by its title, or else by its path given
+// as a Go field path or a JSON path, e.g. "Config.DeployStatus" or
+// ".config.deployStatus".
+func findFieldReference(refs []fieldReference, column string) (fieldReference, bool) {
+	for _, ref := range refs {
+		if strings.EqualFold(ref.title(), column) {
+			return ref, true
+		}
+	}
+
+	path := normalizeColumnPath(column)
+	for _, ref := range refs {
+		for _, refPath := range ref.paths {
+			if strings.EqualFold(refPath, path) {
+				return ref, true
+			}
+		}
+	}
+
+	return fieldReference{}, false
+}
+
+// addPathField adds a top-level field for a column given as a JSON path that
+// is not part of the print configuration. Columns not starting with '.' are
+// only accepted when anyPath is set.
+func addPathField(fields map[string]RecordFieldConfig, column string, anyPath bool) (fieldReference, bool) {
+	column = strings.TrimSpace(column)
+	if !anyPath && !strings.HasPrefix(column, ".") && !strings.HasPrefix(column, "$.") {
+		return fieldReference{}, false
+	}
+
+	key := goFieldPath(column)
+	fields[key] = RecordFieldConfig{Title: normalizeColumnPath(column), Hidden: true}
+
+	return fieldReference{fields: fields, key: key, paths: []string{key}}, true
+}
+
+func normalizeColumnPath(column string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(column), "$"), ".")
+}
+
+// goFieldPath converts a JSON path such as ".config.deployStatus" into the Go
+// field path "Config.DeployStatus".
+func goFieldPath(column string) string {
+	parts := strings.Split(normalizeColumnPath(column), ".")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// columnTitles returns the titles of the fields that can be shown as columns.
+func columnTitles(refs []fieldReference) []string {
+	titles := []string{}
+	for _, ref := range refs {
+		if len(ref.config().InnerFields) == 0 {
+			titles = append(titles, ref.title())
+		}
+	}
+
+	return titles
+}
+
+// headerIndex returns the index of the header named by the column title or
+// path, or -1.
+func headerIndex(header table.Row, column string) int {
+	path := normalizeColumnPath(column)
+	for i, name := range header {
+		if strings.EqualFold(fmt.Sprint(name), column) || strings.EqualFold(fmt.Sprint(name), path) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func removeColumn(header table.Row, rows []table.Row, configs []table.ColumnConfig, index int) (table.Row, []table.Row, []table.ColumnConfig) {
+	if index < len(header) {
+		header = slices.Delete(slices.Clone(header), index, index+1)
+	}
+	for i, row := range rows {
+		if index < len(row) {
+			rows[i] = slices.Delete(row, index, index+1)
+		}
+	}
+
+	result := make([]table.ColumnConfig, 0, len(configs))
+	for _, config := range configs {
+		switch {
+		case config.Number == index+1:
+			continue
+		case config.Number > index+1:
+			config.Number--
+		}
+		result = append(result, config)
+	}
+
+	return header, rows, result
+}
+
+// sortRows sorts the rows by the raw values of the column, numerically for
+// numbers, chronologically for dates and alphabetically otherwise. Empty
+// values are always last.
+func sortRows(rows []table.Row, index int, descending bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rowCell(rows[i], index), rowCell(rows[j], index)
+
+		aEmpty, bEmpty := isEmptyCell(a), isEmptyCell(b)
+		if aEmpty || bEmpty {
+			return !aEmpty && bEmpty
+		}
+
+		if descending {
+			return compareCells(a, b) > 0
+		}
+		return compareCells(a, b) < 0
+	})
+}
+
+func rowCell(row table.Row, index int) interface{} {
+	if index < 0 || index >= len(row) {
+		return nil
+	}
+
+	return row[index]
+}
+
+func isEmptyCell(value interface{}) bool {
+	return value == nil || value == ""
+}
+
+func compareCells(a interface{}, b interface{}) int {
+	if aNumber, ok := numericCell(a); ok {
+		if bNumber, ok := numericCell(b); ok {
+			return cmp.Compare(aNumber, bNumber)
+		}
+	}
+
+	if aTime, ok := timeCell(a); ok {
+		if bTime, ok := timeCell(b); ok {
+			return aTime.Compare(bTime)
+		}
+	}
+
+	aText, bText := fmt.Sprint(a), fmt.Sprint(b)
+	if result := strings.Compare(strings.ToLower(aText), strings.ToLower(bText)); result != 0 {
+		return result
+	}
+
+	return strings.Compare(aText, bText)
+}
+
+func numericCell(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+func timeCell(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(v))
+		return t, err == nil
+	}
+
+	return time.Time{}, false
+}
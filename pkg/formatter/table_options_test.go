@@ -0,0 +1,204 @@
+package formatter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/viper"
+)
+
+type tableTestConfig struct {
+	DeployStatus string
+}
+
+type tableTestRecord struct {
+	Id        int64
+	Label     string
+	Secret    string
+	CreatedAt string
+	Config    tableTestConfig
+}
+
+var tableTestPrintConfig = PrintConfig{
+	FieldsConfig: map[string]RecordFieldConfig{
+		"Id": {
+			Title: "#",
+			Order: 1,
+		},
+		"Label": {
+			Title: "Label",
+			Order: 2,
+		},
+		"Secret": {
+			Hidden: true,
+		},
+		"Config": {
+			Hidden: true,
+			InnerFields: map[string]RecordFieldConfig{
+				"DeployStatus": {
+					Title: "Deploy Status",
+					Order: 3,
+				},
+			},
+		},
+	},
+}
+
+var tableTestRecords = []tableTestRecord{
+	{Id: 10, Label: "web", Secret: "s1", CreatedAt: "2025-03-01T10:00:00Z", Config: tableTestConfig{DeployStatus: "finished"}},
+	{Id: 9, Label: "Db", Secret: "s2", CreatedAt: "2025-01-01T10:00:00Z", Config: tableTestConfig{DeployStatus: "ongoing"}},
+	{Id: 100, Label: "cache", Secret: "s3", CreatedAt: "2025-02-01T10:00:00Z"},
+}
+
+// renderTestTable returns the header and rows of the table for the records
+// with the given options applied.
+func renderTestTable(t *testing.T, options tableOptions, printConfig *PrintConfig) (table.Row, []table.Row) {
+	t.Helper()
+
+	config, sorting, err := options.apply(printConfig)
+	if err != nil {
+		t.Fatalf("apply: unexpected error: %v", err)
+	}
+
+	header, rows, configs := tableData(tableTestRecords, config)
+	if options.sortBy != "" {
+		if sorting == nil {
+			sorting = &sortColumn{index: headerIndex(header, options.sortBy)}
+		}
+		sortRows(rows, sorting.index, options.descending)
+		if sorting.helper {
+			header, rows, _ = removeColumn(header, rows, configs, sorting.index)
+		}
+	}
+
+	return header, rows
+}
+
+func columnValues(rows []table.Row, index int) []interface{} {
+	values := []interface{}{}
+	for _, row := range rows {
+		values = append(values, row[index])
+	}
+	return values
+}
+
+func TestTableOptions_Columns(t *testing.T) {
+	header, rows := renderTestTable(t, tableOptions{columns: []string{"deploy status", ".secret", "#", ".createdAt"}}, &tableTestPrintConfig)
+
+	expected := table.Row{"Deploy Status", "Secret", "#", "createdAt"}
+	if !reflect.DeepEqual(header, expected) {
+		t.Errorf("expected header %v, got %v", expected, header)
+	}
+	if !reflect.DeepEqual(rows[1], table.Row{"ongoing", "s2", int64(9), "2025-01-01T10:00:00Z"}) {
+		t.Errorf("unexpected row %v", rows[1])
+	}
+
+	// The print configuration of the command is not changed
+	if !tableTestPrintConfig.FieldsConfig["Secret"].Hidden || tableTestPrintConfig.FieldsConfig["Label"].Order != 2 {
+		t.Error("expected the original print configuration to be unchanged")
+	}
+}
+
+func TestTableOptions_Wide(t *testing.T) {
+	header, rows := renderTestTable(t, tableOptions{wide: true}, &tableTestPrintConfig)
+
+	expected := table.Row{"#", "Label", "Deploy Status", "Secret"}
+	if !reflect.DeepEqual(header, expected) {
+		t.Errorf("expected hidden leaf fields to be revealed, got %v", header)
+	}
+	if rows[0][3] != "s1" {
+		t.Errorf("expected the hidden value, got %v", rows[0][3])
+	}
+}
+
+func TestTableOptions_SortBy(t *testing.T) {
+	cases := []struct {
+		options  tableOptions
+		index    int
+		expected []interface{}
+	}{
+		// numeric sort
+		{tableOptions{sortBy: "#"}, 0, []interface{}{int64(9), int64(10), int64(100)}},
+		// case-insensitive text sort, descending
+		{tableOptions{sortBy: ".label", descending: true}, 1, []interface{}{"web", "Db", "cache"}},
+		// empty values last
+		{tableOptions{sortBy: "Deploy Status"}, 2, []interface{}{"finished", "ongoing", ""}},
+		// date sort by a column that is not shown
+		{tableOptions{sortBy: ".createdAt"}, 1, []interface{}{"Db", "cache", "web"}},
+	}
+
+	for _, tc := range cases {
+		header, rows := renderTestTable(t, tc.options, &tableTestPrintConfig)
+		if len(header) != 3 {
+			t.Errorf("sort by %s: expected the helper column to be removed, got %v", tc.options.sortBy, header)
+		}
+		if got := columnValues(rows, tc.index); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("sort by %s: expected %v, got %v", tc.options.sortBy, tc.expected, got)
+		}
+	}
+}
+
+func TestTableOptions_WithoutPrintConfig(t *testing.T) {
+	header, rows := renderTestTable(t, tableOptions{columns: []string{"label", ".config.deployStatus"}, sortBy: "Id"}, nil)
+
+	if !reflect.DeepEqual(header, table.Row{"label", "config.deployStatus"}) {
+		t.Errorf("unexpected header %v", header)
+	}
+	if got := columnValues(rows, 0); !reflect.DeepEqual(got, []interface{}{"Db", "web", "cache"}) {
+		t.Errorf("expected rows sorted by id, got %v", got)
+	}
+}
+
+func TestTableOptions_Errors(t *testing.T) {
+	for _, options := range []tableOptions{
+		{columns: []string{"Unknown"}},
+		{columns: []string{"#", ".id"}},
+		{sortBy: "Unknown"},
+	} {
+		if _, _, err := options.apply(&tableTestPrintConfig); err == nil {
+			t.Errorf("%+v: expected error", options)
+		}
+	}
+}
+
+func TestTableOptionsFromConfig(t *testing.T) {
+	viper.Set(ConfigColumns, []string{"ID,Label", " Status "})
+	viper.Set(ConfigSortBy, "Created:desc")
+	viper.Set(ConfigNoHeaders, true)
+	defer func() {
+		viper.Set(ConfigColumns, nil)
+		viper.Set(ConfigSortBy, "")
+		viper.Set(ConfigNoHeaders, false)
+	}()
+
+	options := tableOptionsFromConfig(FormatWide)
+	expected := tableOptions{wide: true, columns: []string{"ID", "Label", "Status"}, sortBy: "Created", descending: true, noHeaders: true}
+	if !reflect.DeepEqual(options, expected) {
+		t.Errorf("expected %+v, got %+v", expected, options)
+	}
+}
+
+func TestCompareCells(t *testing.T) {
+	earlier, later := time.Now(), time.Now().Add(time.Hour)
+
+	cases := []struct {
+		a, b     interface{}
+		expected int
+	}{
+		{int64(9), int64(10), -1},
+		{"9", "10", -1},
+		{1.5, int64(1), 1},
+		{earlier, later, -1},
+		{"2025-02-01T00:00:00Z", "2025-01-01T00:00:00.000Z", 1},
+		{"apple", "Banana", -1},
+		{"b", "b", 0},
+	}
+
+	for _, tc := range cases {
+		if got := compareCells(tc.a, tc.b); got != tc.expected {
+			t.Errorf("compareCells(%v, %v): expected %d, got %d", tc.a, tc.b, tc.expected, got)
+		}
+	}
+}
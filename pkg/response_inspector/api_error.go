@@ -0,0 +1,208 @@
+package response_inspector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Process exit codes, so that scripts can branch on the kind of failure.
+const (
+	ExitCodeError       = 1
+	ExitCodeAuth        = 3
+	ExitCodeNotFound    = 4
+	ExitCodeConflict    = 5
+	ExitCodeValidation  = 6
+	ExitCodeServerError = 7
+)
+
+// maxMessageLength limits the message taken from non-JSON error bodies.
+const maxMessageLength = 1024
+
+// FieldViolation is a validation failure reported by the API for one field.
+// Field is empty when the API does not say which field failed.
+type FieldViolation struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// APIError is an error response of the MetalCloud API.
+type APIError struct {
+	StatusCode int              `json:"statusCode"`
+	Status     string           `json:"status"`
+	Code       string           `json:"code,omitempty"`
+	Message    string           `json:"message"`
+	Violations []FieldViolation `json:"violations,omitempty"`
+	RequestId  string           `json:"requestId,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	var message strings.Builder
+
+	message.WriteString(e.Status)
+	if e.Message != "" {
+		message.WriteString(" - " + e.Message)
+	}
+	if e.Code != "" {
+		message.WriteString(" [" + e.Code + "]")
+	}
+	for _, violation := range e.Violations {
+		if violation.Field != "" {
+			message.WriteString(fmt.Sprintf("\n  %s: %s", violation.Field, violation.Message))
+		} else {
+			message.WriteString("\n  " + violation.Message)
+		}
+	}
+	if e.RequestId != "" {
+		message.WriteString("\n  request id: " + e.RequestId)
+	}
+
+	return message.String()
+}
+
+// ExitCode returns the process exit code matching the HTTP status.
+func (e *APIError) ExitCode() int {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ExitCodeAuth
+	case e.StatusCode == http.StatusNotFound:
+		return ExitCodeNotFound
+	case e.StatusCode == http.StatusConflict || e.StatusCode == http.StatusPreconditionFailed:
+		return ExitCodeConflict
+	case e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity:
+		return ExitCodeValidation
+	case e.StatusCode >= 500:
+		return ExitCodeServerError
+	}
+
+	return ExitCodeError
+}
+
+// ExitCode returns the process exit code for an error returned by a command.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ExitCode()
+	}
+
+	return ExitCodeError
+}
+
+// NewAPIError builds an APIError from an error response. The response body is
+// read and replaced, so it can still be read by the caller.
+func NewAPIError(httpRes *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: httpRes.StatusCode,
+		Status:     httpRes.Status,
+		RequestId:  httpRes.Header.Get("X-Request-Id"),
+	}
+	if apiErr.Status == "" {
+		apiErr.Status = fmt.Sprintf("%d %s", httpRes.StatusCode, http.StatusText(httpRes.StatusCode))
+	}
+	if apiErr.RequestId == "" {
+		apiErr.RequestId = httpRes.Header.Get("X-Correlation-Id")
+	}
+
+	if httpRes.Body == nil {
+		return apiErr
+	}
+
+	body, _ := io.ReadAll(httpRes.Body)
+	httpRes.Body.Close()
+	httpRes.Body = io.NopCloser(bytes.NewReader(body))
+
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(body, &values); err != nil {
+		// Not JSON, e.g. an HTML page from a proxy.
+		apiErr.Message = strings.TrimSpace(string(body))
+		if len(apiErr.Message) > maxMessageLength {
+			apiErr.Message = apiErr.Message[:maxMessageLength] + "..."
+		}
+		return apiErr
+	}
+
+	apiErr.Code = firstString(values, "code", "errorCode", "type")
+	if apiErr.RequestId == "" {
+		apiErr.RequestId = firstString(values, "requestId", "traceId")
+	}
+
+	switch message := values["message"].(type) {
+	case string:
+		apiErr.Message = message
+	case []interface{}:
+		// Validation errors are reported as a list of messages, with the
+		// summary in "error".
+		for _, item := range message {
+			if text, ok := item.(string); ok {
+				apiErr.Violations = append(apiErr.Violations, FieldViolation{Message: text})
+			}
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = firstString(values, "error", "detail", "title")
+	}
+
+	for _, key := range []string{"errors", "violations", "details"} {
+		if items, ok := values[key].([]interface{}); ok {
+			apiErr.Violations = append(apiErr.Violations, parseViolations(items)...)
+		}
+	}
+
+	return apiErr
+}
+
+func parseViolations(items []interface{}) []FieldViolation {
+	violations := []FieldViolation{}
+
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			violations = append(violations, FieldViolation{Message: v})
+		case map[string]interface{}:
+			field := firstString(v, "field", "property", "path")
+
+			if message := firstString(v, "message"); message != "" {
+				violations = append(violations, FieldViolation{Field: field, Message: message})
+				continue
+			}
+
+			// class-validator style: {"property": "label", "constraints": {"isString": "..."}}
+			if constraints, ok := v["constraints"].(map[string]interface{}); ok {
+				keys := make([]string, 0, len(constraints))
+				for key := range constraints {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				for _, key := range keys {
+					violations = append(violations, FieldViolation{Field: field, Message: fmt.Sprint(constraints[key])})
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+func firstString(values map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := values[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return fmt.Sprint(v)
+		}
+	}
+
+	return ""
+}
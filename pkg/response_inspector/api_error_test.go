@@ -0,0 +1,102 @@
+package response_inspector
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	// NestJS style validation error with the request id in a header
+	httpRes := &http.Response{
+		Status:     "400 Bad Request",
+		StatusCode: 400,
+		Header:     http.Header{"X-Request-Id": []string{"req-1"}},
+		Body:       io.NopCloser(bytes.NewBufferString(`{"statusCode":400,"message":["label must be a string"],"error":"Bad Request"}`)),
+	}
+	apiErr := NewAPIError(httpRes)
+	if apiErr.StatusCode != 400 || apiErr.Message != "Bad Request" || apiErr.RequestId != "req-1" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+	if len(apiErr.Violations) != 1 || apiErr.Violations[0].Message != "label must be a string" {
+		t.Errorf("unexpected violations: %+v", apiErr.Violations)
+	}
+	if body, _ := io.ReadAll(httpRes.Body); len(body) == 0 {
+		t.Errorf("expected response body to be preserved")
+	}
+
+	// field violations, error code and request id in the body
+	httpRes = &http.Response{
+		Status:     "422 Unprocessable Entity",
+		StatusCode: 422,
+		Body: io.NopCloser(bytes.NewBufferString(`{"message":"Invalid server type","code":"VALIDATION_FAILED","requestId":"req-2",
+			"errors":[{"field":"serverTypeId","message":"does not exist"},{"property":"label","constraints":{"isNotEmpty":"label should not be empty"}}]}`)),
+	}
+	apiErr = NewAPIError(httpRes)
+	expected := "422 Unprocessable Entity - Invalid server type [VALIDATION_FAILED]\n  serverTypeId: does not exist\n  label: label should not be empty\n  request id: req-2"
+	if apiErr.Error() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, apiErr.Error())
+	}
+
+	// non-JSON body
+	httpRes = &http.Response{
+		StatusCode: 502,
+		Body:       io.NopCloser(bytes.NewBufferString("<html>Bad Gateway</html>")),
+	}
+	apiErr = NewAPIError(httpRes)
+	if apiErr.Error() != "502 Bad Gateway - <html>Bad Gateway</html>" {
+		t.Errorf("unexpected error for non-JSON body: %s", apiErr.Error())
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	cases := map[int]int{
+		401: ExitCodeAuth,
+		403: ExitCodeAuth,
+		404: ExitCodeNotFound,
+		409: ExitCodeConflict,
+		412: ExitCodeConflict,
+		400: ExitCodeValidation,
+		422: ExitCodeValidation,
+		500: ExitCodeServerError,
+		503: ExitCodeServerError,
+		429: ExitCodeError,
+	}
+
+	for statusCode, exitCode := range cases {
+		// wrapped errors keep their exit code
+		err := fmt.Errorf("failed to get server: %w", &APIError{StatusCode: statusCode})
+		if got := ExitCode(err); got != exitCode {
+			t.Errorf("status %d: expected exit code %d, got %d", statusCode, exitCode, got)
+		}
+	}
+
+	if got := ExitCode(errors.New("some error")); got != ExitCodeError {
+		t.Errorf("expected exit code %d for other errors, got %d", ExitCodeError, got)
+	}
+	if got := ExitCode(nil); got != 0 {
+		t.Errorf("expected exit code 0 for nil error, got %d", got)
+	}
+}
+
+func TestInspectResponse_ReturnsAPIError(t *testing.T) {
+	httpRes := &http.Response{
+		Status:     "404 Not Found",
+		StatusCode: 404,
+		Body:       io.NopCloser(bytes.NewBufferString(`{"message":"Server not found"}`)),
+	}
+
+	err := InspectResponse(httpRes, errors.New("404 Not Found"))
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T", err)
+	}
+	if !strings.Contains(err.Error(), "Server not found") {
+		t.Errorf("expected server message in error, got: %v", err)
+	}
+}
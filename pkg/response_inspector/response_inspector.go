@@ -9,18 +9,15 @@ import (
 	"github.com/metalsoft-io/metalcloud-cli/pkg/logger"
 )
 
+// InspectResponse returns an *APIError for error responses, or err when the
+// request failed without a response.
 func InspectResponse(httpRes *http.Response, err error) error {
-	if err != nil {
-		if httpRes != nil && httpRes.StatusCode >= 400 {
-			err := fmt.Errorf("%s - %s", httpRes.Status, httpRes.Body)
-			logger.Get().Error().Err(err).Msg("")
-			return err
-		}
+	if httpRes != nil && httpRes.StatusCode >= 400 {
+		err := NewAPIError(httpRes)
 		logger.Get().Error().Err(err).Msg("")
 		return err
 	}
-	if httpRes.StatusCode >= 400 {
-		err := fmt.Errorf("%s - %s", httpRes.Status, httpRes.Body)
+	if err != nil {
 		logger.Get().Error().Err(err).Msg("")
 		return err
 	}
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the size of the table UnifiedDiff computes the longest
+// common subsequence with; past it the differing middle of the texts is
+// reported as removed and added as a whole.
+const maxDiffCells = 1 << 22
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// UnifiedDiff returns the line differences between a and b in the unified
+// format of `diff -u`, labelled fromName and toName, or "" when they are equal.
+func UnifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	linesA, linesB := splitDiffLines(a), splitDiffLines(b)
+
+	// The common prefix and suffix need no table.
+	prefix := 0
+	for prefix < len(linesA) && prefix < len(linesB) && linesA[prefix] == linesB[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(linesA)-prefix && suffix < len(linesB)-prefix &&
+		linesA[len(linesA)-1-suffix] == linesB[len(linesB)-1-suffix] {
+		suffix++
+	}
+
+	var script []diffLine
+	for _, line := range linesA[:prefix] {
+		script = append(script, diffLine{' ', line})
+	}
+	script = append(script, diffMiddle(linesA[prefix:len(linesA)-suffix], linesB[prefix:len(linesB)-suffix])...)
+	for _, line := range linesA[len(linesA)-suffix:] {
+		script = append(script, diffLine{' ', line})
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	writeDiffHunks(&out, script)
+	return out.String()
+}
+
+// splitDiffLines splits text into lines; a last line without a newline is
+// kept as is.
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffMiddle computes the edit script of a into b from their longest common
+// subsequence.
+func diffMiddle(a, b []string) []diffLine {
+	var script []diffLine
+	if len(a) == 0 || len(b) == 0 || (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			script = append(script, diffLine{'-', line})
+		}
+		for _, line := range b {
+			script = append(script, diffLine{'+', line})
+		}
+		return script
+	}
+
+	// lcs[i*(m+1)+j] is the length of the LCS of a[i:] and b[j:].
+	n, m := len(a), len(b)
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else {
+				lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			script = append(script, diffLine{' ', a[i]})
+			i++
+			j++
+		case i < n && (j == m || lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]):
+			script = append(script, diffLine{'-', a[i]})
+			i++
+		default:
+			script = append(script, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	return script
+}
+
+// writeDiffHunks writes the changes of script with diffContext lines of
+// context, merging changes whose contexts touch into one hunk.
+func writeDiffHunks(out *strings.Builder, script []diffLine) {
+	for start := 0; start < len(script); {
+		first := start
+		for first < len(script) && script[first].op == ' ' {
+			first++
+		}
+		if first == len(script) {
+			return
+		}
+		// Extend the hunk while the next change is within two contexts.
+		last := first
+		for k := first; k < len(script); k++ {
+			if script[k].op != ' ' {
+				last = k
+			} else if k-last > 2*diffContext {
+				break
+			}
+		}
+		from := max(first-diffContext, start)
+		to := min(last+diffContext+1, len(script))
+
+		// Line numbers of the hunk start on each side.
+		lineA, lineB := 1, 1
+		for _, l := range script[:from] {
+			if l.op != '+' {
+				lineA++
+			}
+			if l.op != '-' {
+				lineB++
+			}
+		}
+		countA, countB := 0, 0
+		for _, l := range script[from:to] {
+			if l.op != '+' {
+				countA++
+			}
+			if l.op != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(out, "@@ -%s +%s @@\n", diffRange(lineA, countA), diffRange(lineB, countB))
+		for _, l := range script[from:to] {
+			out.WriteByte(l.op)
+			out.WriteString(l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+}
+
+func diffRange(line, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", line-1)
+	case 1:
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
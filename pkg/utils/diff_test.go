@@ -0,0 +1,59 @@
+package utils
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	if d := UnifiedDiff("a", "b", "x\ny\n", "x\ny\n"); d != "" {
+		t.Errorf("equal texts: diff = %q", d)
+	}
+
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	changed := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n"
+	want := `--- a
++++ b
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+@@ -13,3 +13,4 @@
+ 13
+ 14
+ 15
++16
+`
+	if d := UnifiedDiff("a", "b", old, changed); d != want {
+		t.Errorf("diff =\n%s\nwant\n%s", d, want)
+	}
+
+	// Changes six lines apart share a hunk.
+	want = `--- a
++++ b
+@@ -1,11 +1,11 @@
+-1
++one
+ 2
+ 3
+ 4
+ 5
+ 6
+ 7
+-8
++eight
+ 9
+ 10
+ 11
+`
+	if d := UnifiedDiff("a", "b", old, "one\n2\n3\n4\n5\n6\n7\neight\n9\n10\n11\n12\n13\n14\n15\n"); d != want {
+		t.Errorf("diff =\n%s\nwant\n%s", d, want)
+	}
+
+	want = "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n\\ No newline at end of file\n"
+	if d := UnifiedDiff("a", "b", "", "x\ny"); d != want {
+		t.Errorf("diff from empty = %q, want %q", d, want)
+	}
+}
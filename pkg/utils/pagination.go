@@ -156,12 +156,12 @@ func PrintAll(result interface{}, meta sdk.PaginatedResponseMeta, count int, pri
 	return nil
 }
 
-// PrintAllRaw renders full-fidelity raw API objects for json/yaml output, and the
+// PrintAllRaw renders full-fidelity raw API objects for json/yaml and the template
+// formats built on them (jsonpath, go-template, custom-columns), and the
 // typed records (matching printConfig fields) for table formats. This keeps machine
 // formats lossless while table formats use the safe raw structs.
 func PrintAllRaw(rawItems []json.RawMessage, records interface{}, meta sdk.PaginatedResponseMeta, count int, printConfig *formatter.PrintConfig) error {
-	format := strings.ToLower(viper.GetString(formatter.ConfigFormat))
-	if format == "json" || format == "yaml" {
+	if formatter.IsNativeFormat() {
 		combined := make([]interface{}, 0, len(rawItems))
 		for _, item := range rawItems {
 			var v interface{}
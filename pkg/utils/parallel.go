@@ -0,0 +1,42 @@
+package utils
+
+import "sync"
+
+// ForEachParallel calls task(i) for every i in [0, n) on at most parallelism
+// goroutines (one when parallelism < 1) and returns once all calls are done.
+// Tasks are started in index order. When progress is non-nil it is called
+// after each task with the number of tasks finished so far; calls to it never
+// overlap.
+func ForEachParallel(n, parallelism int, task func(i int), progress func(done int)) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	if parallelism > n {
+		parallelism = n
+	}
+
+	next := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	done := 0
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				task(i)
+				if progress != nil {
+					mu.Lock()
+					done++
+					progress(done)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEachParallel(t *testing.T) {
+	for _, parallelism := range []int{0, 1, 4, 100} {
+		var running, peak, sum int64
+		var progress []int
+		ForEachParallel(20, parallelism, func(i int) {
+			now := atomic.AddInt64(&running, 1)
+			for {
+				old := atomic.LoadInt64(&peak)
+				if now <= old || atomic.CompareAndSwapInt64(&peak, old, now) {
+					break
+				}
+			}
+			atomic.AddInt64(&sum, int64(i))
+			atomic.AddInt64(&running, -1)
+		}, func(done int) {
+			progress = append(progress, done)
+		})
+
+		if sum != 190 {
+			t.Errorf("parallelism %d: sum of indexes = %d, want 190", parallelism, sum)
+		}
+		limit := int64(max(parallelism, 1))
+		if peak > limit {
+			t.Errorf("parallelism %d: %d tasks ran at once", parallelism, peak)
+		}
+		if len(progress) != 20 || progress[19] != 20 {
+			t.Errorf("parallelism %d: progress = %v", parallelism, progress)
+		}
+	}
+
+	ForEachParallel(0, 4, func(int) { t.Error("task called for n = 0") }, nil)
+}